@@ -13,14 +13,20 @@ import (
 	"time"
 
 	"github.com/FlameInTheDark/gochat/cmd/api/config"
+	"github.com/FlameInTheDark/gochat/cmd/api/endpoints/application"
 	"github.com/FlameInTheDark/gochat/cmd/api/endpoints/guild"
 	"github.com/FlameInTheDark/gochat/cmd/api/endpoints/message"
 	"github.com/FlameInTheDark/gochat/cmd/api/endpoints/search"
 	"github.com/FlameInTheDark/gochat/cmd/api/endpoints/user"
 	"github.com/FlameInTheDark/gochat/cmd/api/endpoints/voice"
+	"github.com/FlameInTheDark/gochat/internal/bottoken"
 	"github.com/FlameInTheDark/gochat/internal/cache/kvs"
 	"github.com/FlameInTheDark/gochat/internal/database/db"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/audit"
 	"github.com/FlameInTheDark/gochat/internal/database/pgdb"
+	apprepo "github.com/FlameInTheDark/gochat/internal/database/pgentities/application"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/channel"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/thread"
 	"github.com/FlameInTheDark/gochat/internal/embedmq"
 	"github.com/FlameInTheDark/gochat/internal/helper"
 	"github.com/FlameInTheDark/gochat/internal/idgen"
@@ -151,6 +157,33 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) (*App, error) {
 
 	idgen.New(0)
 
+	if cfg.AuditRetentionDays > 0 && cfg.AuditRetentionInterval > 0 {
+		logger.Info("Starting audit log retention sweep", slog.Int("retention_days", cfg.AuditRetentionDays))
+		retentionCtx, stopRetention := context.WithCancel(context.Background())
+		shut.UpFunc(stopRetention)
+		go runAuditRetention(
+			retentionCtx,
+			audit.New(database),
+			cache,
+			time.Duration(cfg.AuditRetentionDays)*24*time.Hour,
+			time.Duration(cfg.AuditRetentionInterval)*time.Minute,
+			logger)
+	}
+
+	if cfg.ThreadArchiveInterval > 0 {
+		logger.Info("Starting thread auto-archive sweep", slog.Int("interval_minutes", cfg.ThreadArchiveInterval))
+		archiveCtx, stopArchive := context.WithCancel(context.Background())
+		shut.UpFunc(stopArchive)
+		go runThreadArchiver(
+			archiveCtx,
+			thread.New(pg.Conn()),
+			channel.New(pg.Conn()),
+			qt,
+			cache,
+			time.Duration(cfg.ThreadArchiveInterval)*time.Minute,
+			logger)
+	}
+
 	logger.Info("Registering HTTP server")
 	s := server.NewServer()
 	shut.Up(s)
@@ -165,6 +198,7 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) (*App, error) {
 	s.WithCORS()
 	s.WithMetrics("gochat-api")
 	s.WithIdempotency(cache.Client(), cfg.IdempotencyStorageLifetime)
+	s.BotAuthMiddleware(bottoken.NewAuthenticator(apprepo.New(pg.Conn()), cache))
 	s.AuthMiddleware(cfg.AuthSecret)
 	s.RateLimitPipedMiddleware(cfg.RateLimitRequests, cfg.RateLimitTime)
 	s.Use(helper.RequireTokenType("access", "api"))
@@ -180,11 +214,12 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) (*App, error) {
 
 	s.Register(
 		"/api/v1",
-		user.New(database, pg, qt, cache, cfg.AttachmentTTLMinutes*60, contentHosts, logger),
+		user.New(database, pg, qt, imq, cache, cfg.AttachmentTTLMinutes*60, contentHosts, logger),
 		message.New(database, pg, qt, imq, emq, cfg.UploadLimit, cfg.AttachmentTTLMinutes*60, cache, logger),
-		guild.New(database, pg, qt, imq, cache, storage, cfg.AttachmentTTLMinutes*60, cfg.AuthSecret, cfg.VoiceDefaultRegion, disco, extractRegionIDs(cfg.VoiceRegions), logger),
+		guild.New(database, pg, qt, imq, cache, storage, cfg.AttachmentTTLMinutes*60, cfg.AuthSecret, cfg.VoiceDefaultRegion, disco, extractRegionIDs(cfg.VoiceRegions), cfg.VoiceCascade, logger),
 		voice.New(convertRegions(cfg.VoiceRegions), logger),
 		search.New(database, pg, searchService, logger),
+		application.New(pg, qt, cache, logger),
 	)
 
 	return &App{server: s, db: database, logger: logger, addr: cfg.ServerAddress}, nil
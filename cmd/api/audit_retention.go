@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/FlameInTheDark/gochat/internal/database/entities/audit"
+)
+
+const auditRetentionLockKey = "audit:retention:lock"
+
+type auditRetentionLock interface {
+	SetTimedJSONNX(ctx context.Context, key string, val interface{}, ttl int64) (bool, error)
+}
+
+// runAuditRetention periodically removes audit records older than the retention period.
+// A KeyDB lock lets only one API instance sweep per interval.
+func runAuditRetention(ctx context.Context, repo audit.Audit, lock auditRetentionLock, retention, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			acquired, err := lock.SetTimedJSONNX(ctx, auditRetentionLockKey, time.Now().Unix(), int64(interval.Seconds()))
+			if err != nil {
+				logger.Error("unable to acquire audit retention lock", slog.String("error", err.Error()))
+				continue
+			}
+			if !acquired {
+				continue
+			}
+			if err := sweepAuditLog(ctx, repo, time.Now().Add(-retention)); err != nil {
+				logger.Error("audit retention sweep failed", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// sweepAuditLog removes records created before the given time in every guild that has an audit log
+func sweepAuditLog(ctx context.Context, repo audit.Audit, before time.Time) error {
+	guilds, err := repo.GetAuditGuilds(ctx)
+	if err != nil {
+		return err
+	}
+	for _, guildId := range guilds {
+		if err := repo.RemoveRecordsBefore(ctx, guildId, before); err != nil {
+			return fmt.Errorf("guild %d: %w", guildId, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type fakeAuditRepo struct {
+	guilds  []int64
+	removed map[int64]time.Time
+}
+
+func (f *fakeAuditRepo) AddAuditRecord(ctx context.Context, record model.Audit) error { return nil }
+func (f *fakeAuditRepo) RemoveAuditRecordsByGuildId(ctx context.Context, guildID int64) error {
+	return nil
+}
+func (f *fakeAuditRepo) RemoveRecordsBefore(ctx context.Context, guildID int64, before time.Time) error {
+	f.removed[guildID] = before
+	return nil
+}
+func (f *fakeAuditRepo) GetRecordsBefore(ctx context.Context, guildID, before int64, limit int, actorID *int64, action *model.AuditActionType) ([]model.Audit, error) {
+	return nil, nil
+}
+func (f *fakeAuditRepo) GetAuditGuilds(ctx context.Context) ([]int64, error) {
+	return f.guilds, nil
+}
+
+func TestSweepAuditLogRemovesOldRecordsInEveryGuild(t *testing.T) {
+	repo := &fakeAuditRepo{guilds: []int64{1, 2}, removed: map[int64]time.Time{}}
+	before := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := sweepAuditLog(context.Background(), repo, before); err != nil {
+		t.Fatalf("sweepAuditLog returned error: %v", err)
+	}
+	if len(repo.removed) != 2 || !repo.removed[1].Equal(before) || !repo.removed[2].Equal(before) {
+		t.Fatalf("unexpected removals: %#v", repo.removed)
+	}
+}
@@ -40,10 +40,14 @@ type Config struct {
 	OSPassword                 string        `yaml:"os_password" env:"OS_PASSWORD"`
 	VoiceRegions               []VoiceRegion `yaml:"voice_regions"`
 	VoiceDefaultRegion         string        `yaml:"voice_region" env:"VOICE_REGION" env-default:"global"`
+	VoiceCascade               bool          `yaml:"voice_cascade" env:"VOICE_CASCADE" env-default:"false"`
 	EtcdEndpoints              []string      `yaml:"etcd_endpoints" env:"ETCD_ENDPOINTS" env-separator:","`
 	EtcdPrefix                 string        `yaml:"etcd_prefix" env:"ETCD_PREFIX" env-default:"/gochat/sfu"`
 	EtcdUsername               string        `yaml:"etcd_username" env:"ETCD_USERNAME"`
 	EtcdPassword               string        `yaml:"etcd_password" env:"ETCD_PASSWORD"`
+	AuditRetentionDays         int           `yaml:"audit_retention_days" env:"AUDIT_RETENTION_DAYS" env-default:"90"`
+	AuditRetentionInterval     int           `yaml:"audit_retention_interval_minutes" env:"AUDIT_RETENTION_INTERVAL_MINUTES" env-default:"60"`
+	ThreadArchiveInterval      int           `yaml:"thread_archive_interval_minutes" env:"THREAD_ARCHIVE_INTERVAL_MINUTES" env-default:"5"`
 }
 
 type VoiceRegion struct {
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/bottoken"
+	"github.com/FlameInTheDark/gochat/internal/cache"
+	"github.com/FlameInTheDark/gochat/internal/database/pgdb"
+	apprepo "github.com/FlameInTheDark/gochat/internal/database/pgentities/application"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/discriminator"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/member"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/user"
+	"github.com/FlameInTheDark/gochat/internal/mq"
+	"github.com/FlameInTheDark/gochat/internal/server"
+)
+
+const entityName = "application"
+
+// MaxApplicationsPerUser limits the number of bots a single user can own
+const MaxApplicationsPerUser = 25
+
+type tokenCache interface {
+	Forget(ctx context.Context, botId int64) error
+}
+
+func (e *entity) Init(router fiber.Router) {
+	router.Post("", e.Create)
+	router.Get("", e.List)
+	router.Get("/:application_id<int>", e.Get)
+	router.Patch("/:application_id<int>", e.Update)
+	router.Delete("/:application_id<int>", e.Delete)
+	router.Post("/:application_id<int>/token", e.ResetToken)
+	router.Get("/:application_id<int>/authorize", e.GetAuthorization)
+}
+
+type entity struct {
+	name string
+
+	log    *slog.Logger
+	mqt    mq.SendTransporter
+	tokens tokenCache
+
+	app    apprepo.Application
+	user   user.User
+	disc   discriminator.Discriminator
+	member member.Member
+}
+
+func (e *entity) Name() string {
+	return e.name
+}
+
+func New(pg *pgdb.DB, mqt mq.SendTransporter, cache cache.Cache, log *slog.Logger) server.Entity {
+	app := apprepo.New(pg.Conn())
+	return &entity{
+		name:   entityName,
+		log:    log,
+		mqt:    mqt,
+		tokens: bottoken.NewAuthenticator(app, cache),
+		app:    app,
+		user:   user.New(pg.Conn()),
+		disc:   discriminator.New(pg.Conn()),
+		member: member.New(pg.Conn()),
+	}
+}
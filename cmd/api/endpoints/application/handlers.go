@@ -0,0 +1,373 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/bottoken"
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+// Create
+//
+//	@Summary		Create application
+//	@Description	Creates an application with a bot user. The bot token is returned only once, reset it if it is lost.
+//	@Tags			Application
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		CreateApplicationRequest	true	"Application data"
+//	@Success		201		{object}	ApplicationToken			"Application with the bot token"
+//	@failure		400		{string}	string						"Incorrect request body"
+//	@failure		403		{string}	string						"Not allowed for bot accounts"
+//	@failure		406		{string}	string						"Too many applications"
+//	@failure		500		{string}	string						"Something bad happened"
+//	@Router			/application [post]
+func (e *entity) Create(c *fiber.Ctx) error {
+	user, err := getHumanUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateApplicationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	apps, err := e.app.GetOwnerApplications(c.UserContext(), user.Id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetApplications)
+	}
+	if len(apps) >= MaxApplicationsPerUser {
+		return fiber.NewError(fiber.StatusNotAcceptable, ErrTooManyApplications)
+	}
+
+	id := idgen.Next()
+	token, hash, err := bottoken.Generate(id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateToken)
+	}
+	if err := e.user.CreateBotUser(c.UserContext(), id, req.Name); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateApplication)
+	}
+	// Bots do not choose a discriminator, the base36 ID keeps it unique and short
+	disc := "bot-" + strconv.FormatInt(id, 36)
+	if err := e.disc.CreateDiscriminator(c.UserContext(), id, disc); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateDiscriminator)
+	}
+	app := model.Application{
+		Id:          id,
+		OwnerId:     user.Id,
+		Name:        req.Name,
+		Description: req.Description,
+		TokenHash:   hash,
+	}
+	if err := e.app.CreateApplication(c.UserContext(), app); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateApplication)
+	}
+	created, err := e.app.GetApplication(c.UserContext(), id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetApplications)
+	}
+
+	bot := dto.User{Id: id, Name: req.Name, Discriminator: disc, Bot: true}
+	return c.Status(fiber.StatusCreated).JSON(ApplicationToken{Application: buildApplication(created, bot), Token: token})
+}
+
+// List
+//
+//	@Summary	List own applications
+//	@Produce	json
+//	@Tags		Application
+//	@Success	200	{array}		Application	"Applications"
+//	@failure	403	{string}	string		"Not allowed for bot accounts"
+//	@failure	500	{string}	string		"Something bad happened"
+//	@Router		/application [get]
+func (e *entity) List(c *fiber.Ctx) error {
+	user, err := getHumanUser(c)
+	if err != nil {
+		return err
+	}
+	apps, err := e.app.GetOwnerApplications(c.UserContext(), user.Id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetApplications)
+	}
+	ids := make([]int64, len(apps))
+	for i, app := range apps {
+		ids[i] = app.Id
+	}
+	discs, err := e.disc.GetDiscriminatorsByUserIDs(c.UserContext(), ids)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetDiscriminator)
+	}
+	discByID := make(map[int64]string, len(discs))
+	for _, d := range discs {
+		discByID[d.UserId] = d.Discriminator
+	}
+
+	result := make([]Application, len(apps))
+	for i, app := range apps {
+		result[i] = buildApplication(app, dto.User{Id: app.Id, Name: app.Name, Discriminator: discByID[app.Id], Bot: true})
+	}
+	return c.JSON(result)
+}
+
+// Get
+//
+//	@Summary	Get own application
+//	@Produce	json
+//	@Tags		Application
+//	@Param		application_id	path		int64		true	"Application ID"	example(2230469276416868352)
+//	@Success	200				{object}	Application	"Application"
+//	@failure	400				{string}	string		"Incorrect application ID"
+//	@failure	403				{string}	string		"Not allowed for bot accounts"
+//	@failure	404				{string}	string		"Application not found"
+//	@failure	500				{string}	string		"Something bad happened"
+//	@Router		/application/{application_id} [get]
+func (e *entity) Get(c *fiber.Ctx) error {
+	app, err := e.ownApplication(c)
+	if err != nil {
+		return err
+	}
+	bot, err := e.botUser(c.UserContext(), app.Id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(buildApplication(app, bot))
+}
+
+// Update
+//
+//	@Summary	Update own application
+//	@Accept		json
+//	@Produce	json
+//	@Tags		Application
+//	@Param		application_id	path		int64						true	"Application ID"	example(2230469276416868352)
+//	@Param		request			body		UpdateApplicationRequest	true	"Application changes"
+//	@Success	200				{object}	Application					"Application"
+//	@failure	400				{string}	string						"Incorrect request body"
+//	@failure	403				{string}	string						"Not allowed for bot accounts"
+//	@failure	404				{string}	string						"Application not found"
+//	@failure	500				{string}	string						"Something bad happened"
+//	@Router		/application/{application_id} [patch]
+func (e *entity) Update(c *fiber.Ctx) error {
+	app, err := e.ownApplication(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateApplicationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := e.app.UpdateApplication(c.UserContext(), app.Id, req.Name, req.Description); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateApplication)
+	}
+	if req.Name != nil {
+		if err := e.user.ModifyUser(c.UserContext(), app.Id, req.Name, nil); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateApplication)
+		}
+	}
+
+	updated, err := e.app.GetApplication(c.UserContext(), app.Id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetApplications)
+	}
+	bot, err := e.botUser(c.UserContext(), app.Id)
+	if err != nil {
+		return err
+	}
+	if req.Name != nil {
+		go func() {
+			if err := e.mqt.SendUserUpdate(bot.Id, &mqmsg.UpdateUser{User: bot}); err != nil {
+				e.log.Error("unable to send bot update event", slog.String("error", err.Error()))
+			}
+		}()
+	}
+	return c.JSON(buildApplication(updated, bot))
+}
+
+// Delete
+//
+//	@Summary		Delete own application
+//	@Description	Deletes the application, removes its bot from all guilds and revokes the bot token.
+//	@Tags			Application
+//	@Param			application_id	path		int64	true	"Application ID"	example(2230469276416868352)
+//	@Success		204				{string}	string	"No Content"
+//	@failure		400				{string}	string	"Incorrect application ID"
+//	@failure		403				{string}	string	"Not allowed for bot accounts"
+//	@failure		404				{string}	string	"Application not found"
+//	@failure		500				{string}	string	"Something bad happened"
+//	@Router			/application/{application_id} [delete]
+func (e *entity) Delete(c *fiber.Ctx) error {
+	app, err := e.ownApplication(c)
+	if err != nil {
+		return err
+	}
+
+	guilds, err := e.member.GetUserGuilds(c.UserContext(), app.Id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRemoveBotFromGuilds)
+	}
+	for _, g := range guilds {
+		if err := e.member.RemoveMember(c.UserContext(), app.Id, g.GuildId); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRemoveBotFromGuilds)
+		}
+	}
+
+	if err := e.app.DeleteApplication(c.UserContext(), app.Id); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToDeleteApplication)
+	}
+	// The bot user stays for the authorship of its messages
+	if err := e.user.SetUserBlocked(c.UserContext(), app.Id, true); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToDeleteApplication)
+	}
+	if err := e.tokens.Forget(c.UserContext(), app.Id); err != nil {
+		e.log.Error("unable to drop cached bot token", slog.String("error", err.Error()))
+	}
+
+	go func() {
+		for _, g := range guilds {
+			if err := e.mqt.SendGuildUpdate(g.GuildId, &mqmsg.RemoveGuildMember{GuildId: g.GuildId, UserId: app.Id}); err != nil {
+				e.log.Error("unable to send guild member remove event", slog.String("error", err.Error()))
+			}
+		}
+	}()
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ResetToken
+//
+//	@Summary		Reset bot token
+//	@Description	Issues a new bot token. The previous token stops working immediately.
+//	@Tags			Application
+//	@Produce		json
+//	@Param			application_id	path		int64				true	"Application ID"	example(2230469276416868352)
+//	@Success		200				{object}	ApplicationToken	"Application with the new bot token"
+//	@failure		400				{string}	string				"Incorrect application ID"
+//	@failure		403				{string}	string				"Not allowed for bot accounts"
+//	@failure		404				{string}	string				"Application not found"
+//	@failure		500				{string}	string				"Something bad happened"
+//	@Router			/application/{application_id}/token [post]
+func (e *entity) ResetToken(c *fiber.Ctx) error {
+	app, err := e.ownApplication(c)
+	if err != nil {
+		return err
+	}
+	token, hash, err := bottoken.Generate(app.Id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateToken)
+	}
+	if err := e.app.SetTokenHash(c.UserContext(), app.Id, hash); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToResetToken)
+	}
+	if err := e.tokens.Forget(c.UserContext(), app.Id); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToResetToken)
+	}
+	bot, err := e.botUser(c.UserContext(), app.Id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(ApplicationToken{Application: buildApplication(app, bot), Token: token})
+}
+
+// GetAuthorization
+//
+//	@Summary		Get application authorization
+//	@Description	Returns the public data of the application, shown before its bot is added to a guild with POST /guild/{guild_id}/bots.
+//	@Tags			Application
+//	@Produce		json
+//	@Param			application_id	path		int64						true	"Application ID"	example(2230469276416868352)
+//	@Success		200				{object}	ApplicationAuthorization	"Application"
+//	@failure		400				{string}	string						"Incorrect application ID"
+//	@failure		404				{string}	string						"Application not found"
+//	@failure		500				{string}	string						"Something bad happened"
+//	@Router			/application/{application_id}/authorize [get]
+func (e *entity) GetAuthorization(c *fiber.Ctx) error {
+	id, err := parseApplicationID(c)
+	if err != nil {
+		return err
+	}
+	app, err := e.app.GetApplication(c.UserContext(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fiber.NewError(fiber.StatusNotFound, ErrApplicationNotFound)
+	} else if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetApplications)
+	}
+	bot, err := e.botUser(c.UserContext(), app.Id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(ApplicationAuthorization{
+		Id:          app.Id,
+		Name:        app.Name,
+		Description: app.Description,
+		Bot:         bot,
+	})
+}
+
+// getHumanUser returns the user of the request, bots can not manage applications
+func getHumanUser(c *fiber.Ctx) (*helper.JWTUser, error) {
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+	if user.Bot {
+		return nil, fiber.NewError(fiber.StatusForbidden, ErrNotAllowedForBots)
+	}
+	return user, nil
+}
+
+func parseApplicationID(c *fiber.Ctx) (int64, error) {
+	id, err := strconv.ParseInt(c.Params("application_id"), 10, 64)
+	if err != nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, ErrIncorrectApplicationID)
+	}
+	return id, nil
+}
+
+// ownApplication returns the application from the path if it belongs to the user of the request
+func (e *entity) ownApplication(c *fiber.Ctx) (model.Application, error) {
+	user, err := getHumanUser(c)
+	if err != nil {
+		return model.Application{}, err
+	}
+	id, err := parseApplicationID(c)
+	if err != nil {
+		return model.Application{}, err
+	}
+	app, err := e.app.GetApplication(c.UserContext(), id)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && app.OwnerId != user.Id {
+		return model.Application{}, fiber.NewError(fiber.StatusNotFound, ErrApplicationNotFound)
+	} else if err != nil {
+		return model.Application{}, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetApplications)
+	}
+	return app, nil
+}
+
+func (e *entity) botUser(ctx context.Context, id int64) (dto.User, error) {
+	u, err := e.user.GetUserById(ctx, id)
+	if err != nil {
+		return dto.User{}, helper.HttpDbError(err, ErrUnableToGetUser)
+	}
+	disc, err := e.disc.GetDiscriminatorByUserId(ctx, id)
+	if err != nil {
+		return dto.User{}, helper.HttpDbError(err, ErrUnableToGetDiscriminator)
+	}
+	return dto.User{Id: u.Id, Name: u.Name, Discriminator: disc.Discriminator, Bot: u.Bot}, nil
+}
@@ -0,0 +1,108 @@
+package application
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+)
+
+const (
+	ErrUnableToGetUserToken         = "unable to get user token"
+	ErrUnableToParseBody            = "unable to parse body"
+	ErrIncorrectApplicationID       = "incorrect application ID"
+	ErrNotAllowedForBots            = "not allowed for bot accounts"
+	ErrApplicationNotFound          = "application not found"
+	ErrTooManyApplications          = "too many applications"
+	ErrUnableToGetApplications      = "unable to get applications"
+	ErrUnableToCreateApplication    = "unable to create application"
+	ErrUnableToUpdateApplication    = "unable to update application"
+	ErrUnableToDeleteApplication    = "unable to delete application"
+	ErrUnableToResetToken           = "unable to reset bot token"
+	ErrUnableToCreateToken          = "unable to create bot token"
+	ErrUnableToGetUser              = "unable to get user"
+	ErrUnableToGetDiscriminator     = "unable to get discriminator"
+	ErrUnableToCreateDiscriminator  = "unable to create discriminator"
+	ErrUnableToRemoveBotFromGuilds  = "unable to remove bot from guilds"
+	ErrApplicationNameRequired      = "application name is required"
+	ErrApplicationNameTooShort      = "application name must be at least 2 characters"
+	ErrApplicationNameTooLong       = "application name must be 32 characters or fewer"
+	ErrApplicationDescriptionTooBig = "application description must be 400 characters or fewer"
+)
+
+type CreateApplicationRequest struct {
+	Name        string `json:"name" example:"Reminder"`                    // Application name, also used as the bot name
+	Description string `json:"description" example:"Reminds you of tasks"` // Application description shown on the authorization screen
+}
+
+func (r CreateApplicationRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Name,
+			validation.Required.Error(ErrApplicationNameRequired),
+			validation.RuneLength(2, 0).Error(ErrApplicationNameTooShort),
+			validation.RuneLength(0, 32).Error(ErrApplicationNameTooLong),
+		),
+		validation.Field(&r.Description,
+			validation.RuneLength(0, 400).Error(ErrApplicationDescriptionTooBig),
+		),
+	)
+}
+
+type UpdateApplicationRequest struct {
+	Name        *string `json:"name,omitempty" example:"Reminder"`                    // Application name, also used as the bot name
+	Description *string `json:"description,omitempty" example:"Reminds you of tasks"` // Application description
+}
+
+func (r UpdateApplicationRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Name,
+			validation.When(r.Name != nil,
+				validation.Required.Error(ErrApplicationNameRequired),
+				validation.RuneLength(2, 0).Error(ErrApplicationNameTooShort),
+				validation.RuneLength(0, 32).Error(ErrApplicationNameTooLong),
+			),
+		),
+		validation.Field(&r.Description,
+			validation.When(r.Description != nil,
+				validation.RuneLength(0, 400).Error(ErrApplicationDescriptionTooBig),
+			),
+		),
+	)
+}
+
+// Application is a bot owned by the user. The bot user has the same ID as the application.
+type Application struct {
+	Id          int64     `json:"id" example:"2230469276416868352"`       // Application ID, the same as the ID of its bot user
+	OwnerId     int64     `json:"owner_id" example:"2230469276416868352"` // Owner user ID
+	Name        string    `json:"name" example:"Reminder"`                // Application name
+	Description string    `json:"description" example:"Reminds you of tasks"`
+	Bot         dto.User  `json:"bot"`        // Bot user of the application
+	CreatedAt   time.Time `json:"created_at"` // Creation time
+}
+
+// ApplicationToken is returned when the bot token is created or reset. The token is not stored and can not be shown again.
+type ApplicationToken struct {
+	Application
+	Token string `json:"token" example:"2230469276416868352.dGhpcyBpcyBub3QgYSByZWFsIHRva2Vu"` // Bot token, used as "Authorization: Bot <token>"
+}
+
+// ApplicationAuthorization is the public data of the application shown before adding its bot to a guild
+type ApplicationAuthorization struct {
+	Id          int64    `json:"id" example:"2230469276416868352"` // Application ID
+	Name        string   `json:"name" example:"Reminder"`          // Application name
+	Description string   `json:"description" example:"Reminds you of tasks"`
+	Bot         dto.User `json:"bot"` // Bot user of the application
+}
+
+func buildApplication(app model.Application, bot dto.User) Application {
+	return Application{
+		Id:          app.Id,
+		OwnerId:     app.OwnerId,
+		Name:        app.Name,
+		Description: app.Description,
+		Bot:         bot,
+		CreatedAt:   app.CreatedAt,
+	}
+}
@@ -0,0 +1,207 @@
+package guild
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+// GetAuditLog
+//
+//	@Summary		Get guild audit log
+//	@Description	Returns moderation and configuration changes made in the guild, newest first. Requires PermServerViewAuditLog. Use the ID of the last returned record as `before` to get the next page.
+//	@Produce		json
+//	@Tags			Guild
+//	@Param			guild_id	path		int64				true	"Guild ID"	example(2230469276416868352)
+//	@Param			before		query		int64				false	"Return records older than this record ID"
+//	@Param			limit		query		int					false	"Number of records to return (1-100, default 50)"
+//	@Param			actor_id	query		int64				false	"Only records made by this user"
+//	@Param			action		query		int					false	"Only records of this action type"
+//	@Success		200			{array}		dto.AuditLogEntry	"Audit log records"
+//	@failure		400			{string}	string				"Bad request"
+//	@failure		406			{string}	string				"Permissions required"
+//	@failure		500			{string}	string				"Something bad happened"
+//	@Router			/guild/{guild_id}/audit-log [get]
+func (e *entity) GetAuditLog(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+
+	var req GetAuditLogRequest
+	if err := c.QueryParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+	if _, err := e.authorizeGuildPermission(c.UserContext(), guildId, user.Id, permissions.PermServerViewAuditLog); err != nil {
+		return err
+	}
+	if e.audit == nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetAuditLog)
+	}
+
+	limit := DefaultAuditLogLimit
+	if req.Limit != nil {
+		limit = *req.Limit
+	}
+	var before int64
+	if req.Before != nil {
+		before = *req.Before
+	}
+	var action *model.AuditActionType
+	if req.Action != nil {
+		a := model.AuditActionType(*req.Action)
+		action = &a
+	}
+
+	records, err := e.audit.GetRecordsBefore(c.UserContext(), guildId, before, limit, req.ActorId, action)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetAuditLog)
+	}
+
+	result := make([]dto.AuditLogEntry, 0, len(records))
+	for _, r := range records {
+		result = append(result, auditModelToDTO(r))
+	}
+	return c.JSON(result)
+}
+
+// recordAudit writes an audit record for a change that has already been applied.
+// Failures are logged and never fail the request.
+func (e *entity) recordAudit(ctx context.Context, guildId, actorId int64, action model.AuditActionType, targetId int64, changes []model.AuditChange, reason *string) {
+	if e.audit == nil {
+		return
+	}
+	record := model.Audit{
+		GuildId:  guildId,
+		Id:       idgen.Next(),
+		ActorId:  actorId,
+		Action:   action,
+		TargetId: targetId,
+		Changes:  changes,
+		Reason:   reason,
+	}
+	if err := e.audit.AddAuditRecord(ctx, record); err != nil {
+		logger := e.log
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Error("unable to add audit record",
+			slog.Int64("guild_id", guildId),
+			slog.Int("action", int(action)),
+			slog.Int64("target_id", targetId),
+			slog.String("error", err.Error()))
+	}
+}
+
+// auditChange appends a change to the list if the value differs.
+// Nil pointers are stored as missing values, other pointers are dereferenced.
+func auditChange(changes []model.AuditChange, key string, oldValue, newValue any) []model.AuditChange {
+	oldValue, newValue = auditValue(oldValue), auditValue(newValue)
+	if reflect.DeepEqual(oldValue, newValue) {
+		return changes
+	}
+	return append(changes, model.AuditChange{Key: key, Old: oldValue, New: newValue})
+}
+
+func auditValue(v any) any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer {
+		return v
+	}
+	if rv.IsNil() {
+		return nil
+	}
+	return rv.Elem().Interface()
+}
+
+func auditModelToDTO(r model.Audit) dto.AuditLogEntry {
+	entry := dto.AuditLogEntry{
+		Id:        r.Id,
+		GuildId:   r.GuildId,
+		ActorId:   r.ActorId,
+		Action:    int(r.Action),
+		Reason:    r.Reason,
+		CreatedAt: idgen.GetTime(r.Id),
+	}
+	if r.TargetId != 0 {
+		target := r.TargetId
+		entry.TargetId = &target
+	}
+	if len(r.Changes) > 0 {
+		entry.Changes = make([]dto.AuditLogChange, 0, len(r.Changes))
+		for _, ch := range r.Changes {
+			entry.Changes = append(entry.Changes, dto.AuditLogChange{Key: ch.Key, Old: ch.Old, New: ch.New})
+		}
+	}
+	return entry
+}
+
+func roleAuditChanges(old, new model.Role) []model.AuditChange {
+	var changes []model.AuditChange
+	changes = auditChange(changes, "name", old.Name, new.Name)
+	changes = auditChange(changes, "color", old.Color, new.Color)
+	changes = auditChange(changes, "permissions", old.Permissions, new.Permissions)
+	changes = auditChange(changes, "position", old.Position, new.Position)
+	return changes
+}
+
+// overwriteAuditChanges describes a channel role overwrite change, role_id identifies the overwritten role
+func overwriteAuditChanges(roleId int64, old, new model.ChannelRolesPermission) []model.AuditChange {
+	changes := []model.AuditChange{{Key: "role_id", New: roleId}}
+	changes = auditChange(changes, "accept", old.Accept, new.Accept)
+	changes = auditChange(changes, "deny", old.Deny, new.Deny)
+	return changes
+}
+
+func channelAuditChanges(old, new model.Channel) []model.AuditChange {
+	var changes []model.AuditChange
+	changes = auditChange(changes, "name", old.Name, new.Name)
+	changes = auditChange(changes, "type", int(old.Type), int(new.Type))
+	changes = auditChange(changes, "topic", old.Topic, new.Topic)
+	changes = auditChange(changes, "private", old.Private, new.Private)
+	changes = auditChange(changes, "user_limit", old.VoiceUserLimit, new.VoiceUserLimit)
+	changes = auditChange(changes, "bitrate", old.VoiceBitrate, new.VoiceBitrate)
+	changes = auditChange(changes, "stage", old.VoiceStage, new.VoiceStage)
+	return changes
+}
+
+func guildAuditChanges(old, new model.Guild) []model.AuditChange {
+	var changes []model.AuditChange
+	changes = auditChange(changes, "name", old.Name, new.Name)
+	changes = auditChange(changes, "icon", old.Icon, new.Icon)
+	changes = auditChange(changes, "public", old.Public, new.Public)
+	changes = auditChange(changes, "permissions", old.Permissions, new.Permissions)
+	changes = auditChange(changes, "system_messages", old.SystemMessages, new.SystemMessages)
+	changes = auditChange(changes, "mfa_required", old.MFARequired, new.MFARequired)
+	changes = auditChange(changes, "description", old.Description, new.Description)
+	changes = auditChange(changes, "category", int(old.Category), int(new.Category))
+	changes = auditChange(changes, "vanity_code", old.VanityCode, new.VanityCode)
+	changes = auditChange(changes, "verification_level", int(old.VerificationLevel), int(new.VerificationLevel))
+	changes = auditChange(changes, "rules", old.Rules, new.Rules)
+	changes = auditChange(changes, "screening_enabled", old.ScreeningEnabled, new.ScreeningEnabled)
+	return changes
+}
+
+func threadAuditChanges(oldCh, newCh model.Channel, old, new model.Thread) []model.AuditChange {
+	var changes []model.AuditChange
+	changes = auditChange(changes, "name", oldCh.Name, newCh.Name)
+	changes = auditChange(changes, "archived", old.Archived, new.Archived)
+	changes = auditChange(changes, "auto_archive_duration", old.AutoArchiveMinutes, new.AutoArchiveMinutes)
+	return changes
+}
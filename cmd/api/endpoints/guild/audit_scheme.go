@@ -0,0 +1,80 @@
+package guild
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+const (
+	ErrUnableToGetAuditLog = "unable to get audit log"
+	ErrAuditBeforeInvalid  = "before must be a positive audit record ID"
+	ErrAuditLimitInvalid   = "limit must be between 1 and 100"
+	ErrAuditActorInvalid   = "actor ID must be positive"
+	ErrAuditActionInvalid  = "invalid audit action type"
+
+	DefaultAuditLogLimit = 50
+)
+
+var auditActionTypes = []interface{}{
+	int(model.AuditActionGuildUpdate),
+	int(model.AuditActionChannelCreate),
+	int(model.AuditActionChannelUpdate),
+	int(model.AuditActionChannelDelete),
+	int(model.AuditActionChannelOverwriteCreate),
+	int(model.AuditActionChannelOverwriteUpdate),
+	int(model.AuditActionChannelOverwriteDelete),
+	int(model.AuditActionMemberKick),
+	int(model.AuditActionMemberBanAdd),
+	int(model.AuditActionMemberBanRemove),
+	int(model.AuditActionMemberUpdate),
+	int(model.AuditActionMemberRoleUpdate),
+	int(model.AuditActionBotAdd),
+	int(model.AuditActionRoleCreate),
+	int(model.AuditActionRoleUpdate),
+	int(model.AuditActionRoleDelete),
+	int(model.AuditActionInviteCreate),
+	int(model.AuditActionInviteDelete),
+	int(model.AuditActionWebhookCreate),
+	int(model.AuditActionWebhookUpdate),
+	int(model.AuditActionWebhookDelete),
+	int(model.AuditActionEventWebhookCreate),
+	int(model.AuditActionEventWebhookUpdate),
+	int(model.AuditActionEventWebhookDelete),
+	int(model.AuditActionEmojiCreate),
+	int(model.AuditActionEmojiUpdate),
+	int(model.AuditActionEmojiDelete),
+	int(model.AuditActionVoiceRecordingStart),
+	int(model.AuditActionVoiceRecordingStop),
+	int(model.AuditActionThreadCreate),
+	int(model.AuditActionThreadUpdate),
+	int(model.AuditActionThreadDelete),
+}
+
+type GetAuditLogRequest struct {
+	Before  *int64 `query:"before" json:"before" example:"2230469276416868352"`     // Return records older than this record ID
+	Limit   *int   `query:"limit" json:"limit" example:"50"`                        // Number of records to return. Default 50, max 100.
+	ActorId *int64 `query:"actor_id" json:"actor_id" example:"2230469276416868352"` // Only return changes made by this user
+	Action  *int   `query:"action" json:"action" example:"22"`                      // Only return records of this action type
+}
+
+func (r GetAuditLogRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Before,
+			validation.When(r.Before != nil, validation.Required.Error(ErrAuditBeforeInvalid), validation.Min(int64(1)).Error(ErrAuditBeforeInvalid)),
+		),
+		validation.Field(&r.Limit,
+			validation.When(r.Limit != nil,
+				validation.Required.Error(ErrAuditLimitInvalid),
+				validation.Min(1).Error(ErrAuditLimitInvalid),
+				validation.Max(100).Error(ErrAuditLimitInvalid),
+			),
+		),
+		validation.Field(&r.ActorId,
+			validation.When(r.ActorId != nil, validation.Required.Error(ErrAuditActorInvalid), validation.Min(int64(1)).Error(ErrAuditActorInvalid)),
+		),
+		validation.Field(&r.Action,
+			validation.When(r.Action != nil, validation.Required.Error(ErrAuditActionInvalid), validation.In(auditActionTypes...).Error(ErrAuditActionInvalid)),
+		),
+	)
+}
@@ -0,0 +1,177 @@
+package guild
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+type auditQuery struct {
+	guildID int64
+	before  int64
+	limit   int
+	actorID *int64
+	action  *model.AuditActionType
+}
+
+type fakeAuditRepo struct {
+	records []model.Audit
+	queries []auditQuery
+}
+
+func (f *fakeAuditRepo) AddAuditRecord(ctx context.Context, record model.Audit) error {
+	f.records = append(f.records, record)
+	return nil
+}
+
+func (f *fakeAuditRepo) RemoveAuditRecordsByGuildId(ctx context.Context, guildID int64) error {
+	return nil
+}
+
+func (f *fakeAuditRepo) RemoveRecordsBefore(ctx context.Context, guildID int64, before time.Time) error {
+	return nil
+}
+
+func (f *fakeAuditRepo) GetRecordsBefore(ctx context.Context, guildID, before int64, limit int, actorID *int64, action *model.AuditActionType) ([]model.Audit, error) {
+	f.queries = append(f.queries, auditQuery{guildID: guildID, before: before, limit: limit, actorID: actorID, action: action})
+	return f.records, nil
+}
+
+func (f *fakeAuditRepo) GetAuditGuilds(ctx context.Context) ([]int64, error) {
+	return nil, nil
+}
+
+func TestGetAuditLogPassesFiltersAndReturnsEntries(t *testing.T) {
+	reason := "spam"
+	audits := &fakeAuditRepo{records: []model.Audit{{
+		GuildId:  1,
+		Id:       2230469276416868352,
+		ActorId:  10,
+		Action:   model.AuditActionMemberBanAdd,
+		TargetId: 11,
+		Reason:   &reason,
+	}}}
+	members := &fakeMemberRepo{members: map[testMemberKey]bool{{guildID: 1, userID: 10}: true}}
+	perms := &fakePermissionChecker{results: map[testPermKey]bool{{guildID: 1, userID: 10, perm: permissions.PermServerViewAuditLog}: true}}
+	e := &entity{g: &fakeGuildRepo{guild: model.Guild{Id: 1, OwnerId: 99}}, memb: members, perm: perms, audit: audits}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/audit-log", e.GetAuditLog)
+
+	req := httptest.NewRequest("GET", "/guild/1/audit-log?before=500&limit=20&actor_id=10&action=22", nil)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	if len(audits.queries) != 1 {
+		t.Fatalf("expected one query, got %#v", audits.queries)
+	}
+	q := audits.queries[0]
+	if q.guildID != 1 || q.before != 500 || q.limit != 20 || q.actorID == nil || *q.actorID != 10 || q.action == nil || *q.action != model.AuditActionMemberBanAdd {
+		t.Fatalf("unexpected query: %#v", q)
+	}
+
+	var got []dto.AuditLogEntry
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("unable to decode response: %v", err)
+	}
+	if len(got) != 1 || got[0].ActorId != 10 || got[0].Action != int(model.AuditActionMemberBanAdd) ||
+		got[0].TargetId == nil || *got[0].TargetId != 11 || got[0].Reason == nil || *got[0].Reason != reason || got[0].CreatedAt.IsZero() {
+		t.Fatalf("unexpected audit log payload: %#v", got)
+	}
+}
+
+func TestGetAuditLogRequiresPermission(t *testing.T) {
+	audits := &fakeAuditRepo{}
+	members := &fakeMemberRepo{members: map[testMemberKey]bool{{guildID: 1, userID: 10}: true}}
+	perms := &fakePermissionChecker{results: map[testPermKey]bool{}}
+	e := &entity{g: &fakeGuildRepo{guild: model.Guild{Id: 1, OwnerId: 99}}, memb: members, perm: perms, audit: audits}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/audit-log", e.GetAuditLog)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/guild/1/audit-log", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNotAcceptable {
+		t.Fatalf("expected 406, got %d", resp.StatusCode)
+	}
+	if len(audits.queries) != 0 {
+		t.Fatalf("expected no audit queries, got %#v", audits.queries)
+	}
+}
+
+func TestGetAuditLogRejectsInvalidFilters(t *testing.T) {
+	for _, query := range []string{"limit=0", "limit=101", "action=999", "actor_id=-1", "before=0"} {
+		t.Run(query, func(t *testing.T) {
+			e := &entity{audit: &fakeAuditRepo{}}
+			app := newGuildTestApp(t, 10, "/guild/:guild_id/audit-log", e.GetAuditLog)
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/guild/1/audit-log?"+query, nil), -1)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != fiber.StatusBadRequest {
+				t.Fatalf("expected 400, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestKickMemberWritesAuditRecord(t *testing.T) {
+	audits := &fakeAuditRepo{}
+	members := &fakeMemberRepo{members: map[testMemberKey]bool{{guildID: 1, userID: 10}: true, {guildID: 1, userID: 11}: true}}
+	perms := &fakePermissionChecker{results: map[testPermKey]bool{{guildID: 1, userID: 10, perm: permissions.PermMembershipKickMembers}: true}}
+	e := &entity{g: &fakeGuildRepo{guild: model.Guild{Id: 1, OwnerId: 99}}, memb: members, perm: perms, audit: audits}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/member/:user_id/kick", e.KickMember)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/guild/1/member/11/kick", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	if len(audits.records) != 1 {
+		t.Fatalf("expected one audit record, got %#v", audits.records)
+	}
+	r := audits.records[0]
+	if r.GuildId != 1 || r.ActorId != 10 || r.TargetId != 11 || r.Action != model.AuditActionMemberKick || r.Id == 0 {
+		t.Fatalf("unexpected audit record: %#v", r)
+	}
+}
+
+func TestAuditChangeSkipsEqualValuesAndDereferencesPointers(t *testing.T) {
+	oldTopic, newTopic := "old", "new"
+	changes := channelAuditChanges(
+		model.Channel{Name: "general", Type: model.ChannelTypeGuild, Topic: &oldTopic},
+		model.Channel{Name: "general", Type: model.ChannelTypeGuild, Topic: &newTopic, Private: true},
+	)
+	if len(changes) != 2 {
+		t.Fatalf("expected topic and private changes, got %#v", changes)
+	}
+	if changes[0].Key != "topic" || changes[0].Old != "old" || changes[0].New != "new" {
+		t.Fatalf("unexpected topic change: %#v", changes[0])
+	}
+	if changes[1].Key != "private" || changes[1].Old != false || changes[1].New != true {
+		t.Fatalf("unexpected private change: %#v", changes[1])
+	}
+
+	cleared := channelAuditChanges(model.Channel{Topic: &oldTopic}, model.Channel{})
+	if len(cleared) != 1 || cleared[0].Old != "old" || cleared[0].New != nil {
+		t.Fatalf("expected cleared topic change, got %#v", cleared)
+	}
+
+	if changes := roleAuditChanges(model.Role{Name: "mod", Color: 1}, model.Role{Name: "mod", Color: 1}); len(changes) != 0 {
+		t.Fatalf("expected no changes for identical roles, got %#v", changes)
+	}
+}
@@ -0,0 +1,170 @@
+package guild
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+// AddBot
+//
+//	@Summary		Add bot to guild
+//	@Description	Authorizes the bot of an application to join the guild. The bot gets a managed role with the requested permissions, adding it again updates the role. Requires PermServerManage and every requested permission.
+//	@Tags			Guild
+//	@Accept			json
+//	@Produce		json
+//	@Param			guild_id	path		int64				true	"Guild ID"	example(2230469276416868352)
+//	@Param			request		body		AddGuildBotRequest	true	"Bot authorization"
+//	@Success		200			{object}	dto.Role			"Managed role of the bot"
+//	@failure		400			{string}	string				"Incorrect request body"
+//	@failure		403			{string}	string				"Bot is banned or request made by a bot"
+//	@failure		404			{string}	string				"Bot not found"
+//	@failure		406			{string}	string				"Permissions required"
+//	@failure		500			{string}	string				"Something bad happened"
+//	@Router			/guild/{guild_id}/bots [post]
+func (e *entity) AddBot(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+
+	var req AddGuildBotRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	perms := req.Permissions & permissions.AllPermissions
+
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+	if user.Bot {
+		return fiber.NewError(fiber.StatusForbidden, ErrNotAllowedForBots)
+	}
+
+	// Members can not grant permissions they do not have themselves
+	required := append([]permissions.RolePermission{permissions.PermServerManage}, permissions.Split(perms)...)
+	if _, err := e.authorizeGuildPermission(c.UserContext(), guildId, user.Id, required...); err != nil {
+		return err
+	}
+
+	bot, err := e.user.GetUserById(c.UserContext(), req.ApplicationId)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && (!bot.Bot || bot.Blocked) {
+		return fiber.NewError(fiber.StatusNotFound, ErrBotNotFound)
+	} else if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetUser)
+	}
+
+	if banned, err := e.isGuildUserBanned(c.UserContext(), guildId, bot.Id); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCheckGuildBan)
+	} else if banned {
+		return fiber.NewError(fiber.StatusForbidden, ErrUserIsBanned)
+	}
+
+	isMember, err := e.memb.IsGuildMember(c.UserContext(), guildId, bot.Id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildMember)
+	}
+	if !isMember {
+		if err := e.memb.AddMember(c.UserContext(), bot.Id, guildId, false); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToAddBot)
+		}
+	}
+
+	r, created, err := e.upsertManagedRole(c.UserContext(), guildId, bot, perms)
+	if err != nil {
+		return err
+	}
+
+	assigned, err := e.ur.GetUserRoles(c.UserContext(), guildId, bot.Id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetRoles)
+	}
+	hasRole := slices.ContainsFunc(assigned, func(ur model.UserRole) bool { return ur.RoleId == r.Id })
+	if !hasRole {
+		if err := e.ur.AddUserRole(c.UserContext(), guildId, bot.Id, r.Id); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSetUserRole)
+		}
+	}
+
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionBotAdd, bot.Id, []model.AuditChange{{Key: "permissions", New: perms}}, nil)
+
+	role := roleModelToDTO(r)
+	go func() {
+		if !isMember {
+			disc, err := e.disc.GetDiscriminatorByUserId(context.Background(), bot.Id)
+			if err != nil {
+				e.log.Error("unable to get bot discriminator", slog.String("error", err.Error()))
+			}
+			if err := e.mqt.SendGuildUpdate(guildId, &mqmsg.AddGuildMember{
+				GuildId: guildId,
+				UserId:  bot.Id,
+				Member: dto.Member{
+					User:   userToDTO(bot, disc.Discriminator),
+					JoinAt: time.Now(),
+				},
+			}); err != nil {
+				e.log.Error("unable to send add guild member event", slog.String("error", err.Error()))
+			}
+		}
+		var evt mqmsg.EventDataMessage = &mqmsg.UpdateGuildRole{GuildId: guildId, Role: role}
+		if created {
+			evt = &mqmsg.CreateGuildRole{Role: role}
+		}
+		if err := e.mqt.SendGuildUpdate(guildId, evt); err != nil {
+			e.log.Error("unable to send guild event after bot role update", slog.String("error", err.Error()))
+		}
+		if !hasRole {
+			if err := e.mqt.SendGuildUpdate(guildId, &mqmsg.AddGuildMemberRole{GuildId: guildId, RoleId: r.Id, UserId: bot.Id}); err != nil {
+				e.log.Error("unable to send add guild member role event", slog.String("error", err.Error()))
+			}
+		}
+		if err := e.cache.Delete(context.Background(), fmt.Sprintf("guild:%d:roles", guildId)); err != nil {
+			e.log.Error("unable to delete guild roles cache", slog.String("error", err.Error()))
+		}
+	}()
+
+	return c.JSON(role)
+}
+
+// upsertManagedRole creates the managed role of the bot or sets the permissions of the existing one
+func (e *entity) upsertManagedRole(ctx context.Context, guildId int64, bot model.User, perms int64) (model.Role, bool, error) {
+	r, err := e.role.GetManagedRole(ctx, guildId, bot.Id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		roleId := idgen.Next()
+		if err := e.role.CreateManagedRole(ctx, roleId, guildId, bot.Name, perms, bot.Id); err != nil {
+			return r, false, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToAddBot)
+		}
+		r, err = e.role.GetRoleByID(ctx, roleId)
+		if err != nil {
+			return r, false, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetRoles)
+		}
+		return r, true, nil
+	case err != nil:
+		return r, false, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetRoles)
+	}
+	if r.Permissions != perms {
+		if err := e.role.SetRolePermissions(ctx, r.Id, perms); err != nil {
+			return r, false, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToAddBot)
+		}
+		r.Permissions = perms
+	}
+	return r, false, nil
+}
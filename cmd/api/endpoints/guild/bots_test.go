@@ -0,0 +1,178 @@
+package guild
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+type fakeUserRoleRepo struct {
+	assigned map[testMemberKey][]int64
+	addCalls int
+}
+
+func (f *fakeUserRoleRepo) GetUserRoles(ctx context.Context, guildID, userId int64) ([]model.UserRole, error) {
+	ids := f.assigned[testMemberKey{guildID: guildID, userID: userId}]
+	out := make([]model.UserRole, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, model.UserRole{GuildId: guildID, UserId: userId, RoleId: id})
+	}
+	return out, nil
+}
+
+func (f *fakeUserRoleRepo) AddUserRole(ctx context.Context, guildID, userId, roleId int64) error {
+	f.addCalls++
+	key := testMemberKey{guildID: guildID, userID: userId}
+	f.assigned[key] = append(f.assigned[key], roleId)
+	return nil
+}
+
+func (f *fakeUserRoleRepo) RemoveUserRole(ctx context.Context, guildID, userId, roleId int64) error {
+	return nil
+}
+
+func (f *fakeUserRoleRepo) RemoveRoleAssignments(ctx context.Context, guildID, roleId int64) error {
+	return nil
+}
+
+func (f *fakeUserRoleRepo) GetUsersRolesByGuild(ctx context.Context, guildID int64, userIds []int64) ([]model.UserRoles, error) {
+	return nil, nil
+}
+
+func newBotTestEntity() (*entity, *fakeMemberRepo, *fakeRoleRepo, *fakeUserRoleRepo, *fakeRoleTransport) {
+	members := &fakeMemberRepo{members: map[testMemberKey]bool{{guildID: 1, userID: 10}: true}}
+	roles := &fakeRoleRepo{roles: map[int64]model.Role{}}
+	userRoles := &fakeUserRoleRepo{assigned: map[testMemberKey][]int64{}}
+	transport := &fakeRoleTransport{
+		roleCreates: make(chan *mqmsg.CreateGuildRole, 1),
+		roleUpdates: make(chan *mqmsg.UpdateGuildRole, 1),
+	}
+	e := &entity{
+		g:    &fakeGuildRepo{guild: model.Guild{Id: 1, OwnerId: 99}},
+		memb: members,
+		perm: &fakePermissionChecker{results: map[testPermKey]bool{
+			{guildID: 1, userID: 10, perm: permissions.PermServerManage}: true,
+		}},
+		user: &fakeUserRepo{users: map[int64]model.User{
+			20: {Id: 20, Name: "helper", Bot: true},
+			30: {Id: 30, Name: "human"},
+		}},
+		disc:  &fakeDiscriminatorRepo{discriminators: map[int64]string{20: "bot-k"}},
+		ban:   &fakeBanRepo{},
+		role:  roles,
+		ur:    userRoles,
+		mqt:   transport,
+		cache: &fakeCache{},
+	}
+	return e, members, roles, userRoles, transport
+}
+
+func TestAddBotCreatesAndUpdatesManagedRole(t *testing.T) {
+	e, members, roles, userRoles, transport := newBotTestEntity()
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/bots", e.AddBot)
+
+	perms := int64(permissions.PermServerViewChannels | permissions.PermTextSendMessage)
+	body := `{"application_id":20,"permissions":` + strconv.FormatInt(perms, 10) + `}`
+	req := httptest.NewRequest(fiber.MethodPost, "/guild/1/bots", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var role dto.Role
+	if err := json.NewDecoder(resp.Body).Decode(&role); err != nil {
+		t.Fatal(err)
+	}
+	if !role.Managed || role.Permissions != perms {
+		t.Fatalf("unexpected role %+v", role)
+	}
+	if !members.members[testMemberKey{guildID: 1, userID: 20}] {
+		t.Fatal("expected bot to become a guild member")
+	}
+	if got := userRoles.assigned[testMemberKey{guildID: 1, userID: 20}]; len(got) != 1 || got[0] != role.Id {
+		t.Fatalf("expected managed role to be assigned, got %v", got)
+	}
+	select {
+	case <-transport.roleCreates:
+	case <-time.After(time.Second):
+		t.Fatal("expected create role event")
+	}
+
+	// Authorizing the bot again only updates the permissions of the same role
+	perms = int64(permissions.PermServerViewChannels)
+	body = `{"application_id":20,"permissions":` + strconv.FormatInt(perms, 10) + `}`
+	req = httptest.NewRequest(fiber.MethodPost, "/guild/1/bots", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if len(roles.roles) != 1 || roles.roles[role.Id].Permissions != perms {
+		t.Fatalf("expected single managed role with updated permissions, got %+v", roles.roles)
+	}
+	if userRoles.addCalls != 1 {
+		t.Fatalf("expected role to be assigned once, got %d", userRoles.addCalls)
+	}
+	select {
+	case <-transport.roleUpdates:
+	case <-time.After(time.Second):
+		t.Fatal("expected update role event")
+	}
+}
+
+func TestAddBotRejectsRegularUser(t *testing.T) {
+	e, members, _, _, _ := newBotTestEntity()
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/bots", e.AddBot)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/guild/1/bots", strings.NewReader(`{"application_id":30,"permissions":0}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", resp.StatusCode)
+	}
+	if len(members.addCalls) != 0 {
+		t.Fatal("expected no member to be added")
+	}
+}
+
+func TestAddMemberRoleRejectsManagedRole(t *testing.T) {
+	e, members, roles, userRoles, _ := newBotTestEntity()
+	botId := int64(20)
+	members.members[testMemberKey{guildID: 1, userID: botId}] = true
+	roles.roles[5] = model.Role{Id: 5, GuildId: 1, Name: "helper", ManagedBy: &botId}
+	e.perm = &fakePermissionChecker{results: map[testPermKey]bool{
+		{guildID: 1, userID: 10, perm: permissions.PermServerManageRoles}: true,
+	}}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/member/:user_id/roles/:role_id", e.AddMemberRole)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPut, "/guild/1/member/10/roles/5", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", resp.StatusCode)
+	}
+	if userRoles.addCalls != 0 {
+		t.Fatal("expected managed role not to be assigned")
+	}
+}
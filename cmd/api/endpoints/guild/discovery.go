@@ -0,0 +1,218 @@
+package guild
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/guild"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+// GetDiscoverableGuilds
+//
+//	@Summary		List public guilds
+//	@Description	Returns public guilds, the largest first. The online count is approximated from a sample of members and cached for a minute.
+//	@Produce		json
+//	@Tags			Guild Discovery
+//	@Param			query		query		string					false	"Search in guild names and descriptions"
+//	@Param			category	query		int						false	"Only guilds of this category"
+//	@Param			limit		query		int						false	"Number of guilds to return (1-100, default 25)"
+//	@Param			offset		query		int						false	"Number of guilds to skip"
+//	@Success		200			{array}		dto.DiscoverableGuild	"Public guilds"
+//	@failure		400			{string}	string					"Bad request"
+//	@failure		500			{string}	string					"Something bad happened"
+//	@Router			/guild/discovery [get]
+func (e *entity) GetDiscoverableGuilds(c *fiber.Ctx) error {
+	var req GetDiscoverableGuildsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	limit := DefaultDiscoveryLimit
+	if req.Limit != nil {
+		limit = *req.Limit
+	}
+	var offset int
+	if req.Offset != nil {
+		offset = *req.Offset
+	}
+	var category *model.GuildCategory
+	if req.Category != nil {
+		cat := model.GuildCategory(*req.Category)
+		category = &cat
+	}
+
+	guilds, err := e.g.GetDiscoverableGuilds(c.UserContext(), req.Query, category, uint64(limit), uint64(offset))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetDiscovery)
+	}
+
+	result := make([]dto.DiscoverableGuild, 0, len(guilds))
+	for _, g := range guilds {
+		gd := e.dtoGuildWithIcon(c, &g.Guild)
+		result = append(result, dto.DiscoverableGuild{
+			Id:           g.Id,
+			Name:         g.Name,
+			Icon:         gd.Icon,
+			Description:  g.Description,
+			Category:     int(g.Category),
+			VanityCode:   g.VanityCode,
+			MembersCount: g.MembersCount,
+			OnlineCount:  e.approximateOnline(c.UserContext(), g.Id, g.MembersCount),
+		})
+	}
+	return c.JSON(result)
+}
+
+// JoinDiscoverableGuild
+//
+//	@Summary		Join public guild
+//	@Description	Joins a public guild from discovery without an invite.
+//	@Produce		json
+//	@Tags			Guild Discovery
+//	@Param			guild_id	path		int64		true	"Guild ID"	example(2230469276416868352)
+//	@Success		200			{object}	dto.Guild	"Joined guild"
+//	@failure		400			{string}	string		"Bad request"
+//	@failure		403			{string}	string		"Guild is not public, user is banned or a bot"
+//	@failure		404			{string}	string		"Guild not found"
+//	@Router			/guild/discovery/{guild_id}/join [post]
+func (e *entity) JoinDiscoverableGuild(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+	// Bots join guilds only when a member adds them
+	if user.Bot {
+		return fiber.NewError(fiber.StatusForbidden, ErrNotAllowedForBots)
+	}
+
+	g, err := e.g.GetGuildById(c.UserContext(), guildId)
+	if err != nil {
+		return helper.HttpDbError(err, ErrUnableToGetGuildByID)
+	}
+	if !g.Public {
+		return fiber.NewError(fiber.StatusForbidden, ErrGuildNotPublic)
+	}
+
+	return e.joinGuild(c, user, guildId, "", false)
+}
+
+// SetVanityCode
+//
+//	@Summary		Set guild vanity code
+//	@Description	Claims a unique vanity code, it can be used as a permanent invite code. Replaces the previous vanity code of the guild. Requires PermServerManage.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Guild Discovery
+//	@Param			guild_id	path		int64					true	"Guild ID"	example(2230469276416868352)
+//	@Param			request		body		SetVanityCodeRequest	true	"Vanity code"
+//	@Success		200			{object}	dto.Guild				"Guild"
+//	@failure		400			{string}	string					"Bad request"
+//	@failure		406			{string}	string					"Permissions required"
+//	@failure		409			{string}	string					"Vanity code is already taken"
+//	@Router			/guild/{guild_id}/vanity [put]
+func (e *entity) SetVanityCode(c *fiber.Ctx) error {
+	var req SetVanityCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return e.changeVanityCode(c, &req.Code)
+}
+
+// DeleteVanityCode
+//
+//	@Summary		Remove guild vanity code
+//	@Description	Releases the vanity code of the guild. Requires PermServerManage.
+//	@Produce		json
+//	@Tags			Guild Discovery
+//	@Param			guild_id	path		int64		true	"Guild ID"	example(2230469276416868352)
+//	@Success		200			{object}	dto.Guild	"Guild"
+//	@failure		400			{string}	string		"Bad request"
+//	@failure		406			{string}	string		"Permissions required"
+//	@Router			/guild/{guild_id}/vanity [delete]
+func (e *entity) DeleteVanityCode(c *fiber.Ctx) error {
+	return e.changeVanityCode(c, nil)
+}
+
+// changeVanityCode sets or removes the vanity code and announces the guild update
+func (e *entity) changeVanityCode(c *fiber.Ctx, code *string) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+	old, err := e.authorizeGuildPermission(c.UserContext(), guildId, user.Id, permissions.PermServerManage)
+	if err != nil {
+		return err
+	}
+
+	if err := e.g.SetVanityCode(c.UserContext(), guildId, code); err != nil {
+		if errors.Is(err, guild.ErrVanityCodeTaken) {
+			return fiber.NewError(fiber.StatusConflict, ErrVanityCodeTaken)
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSetVanityCode)
+	}
+
+	updated, err := e.g.GetGuildById(c.UserContext(), guildId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildByID)
+	}
+	if changes := guildAuditChanges(*old, updated); len(changes) > 0 {
+		e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionGuildUpdate, guildId, changes, nil)
+	}
+	if err := e.sendGuildUpdateEvent(guildId, &updated); err != nil {
+		return err
+	}
+	return c.JSON(e.dtoGuildWithIcon(c, &updated))
+}
+
+// approximateOnline estimates the online members of the guild from the presence of a random member sample
+func (e *entity) approximateOnline(ctx context.Context, guildId, members int64) int64 {
+	if e.pres == nil || members == 0 {
+		return 0
+	}
+	key := fmt.Sprintf("discovery:online:%d", guildId)
+	if online, err := e.cache.GetInt64(ctx, key); err == nil {
+		return online
+	}
+
+	ids, err := e.memb.SampleGuildMemberIds(ctx, guildId, discoveryOnlineSample)
+	if err != nil || len(ids) == 0 {
+		return 0
+	}
+	sampled, err := e.pres.CountOnline(ctx, ids)
+	if err != nil {
+		e.log.Error("unable to count online members", slog.Int64("guild_id", guildId), slog.String("error", err.Error()))
+		return 0
+	}
+	online := int64(sampled)
+	if int64(len(ids)) < members {
+		online = online * members / int64(len(ids))
+	}
+	if err := e.cache.SetTimedInt64(ctx, key, online, discoveryOnlineTTL); err != nil {
+		e.log.Error("unable to cache online members", slog.Int64("guild_id", guildId), slog.String("error", err.Error()))
+	}
+	return online
+}
@@ -0,0 +1,92 @@
+package guild
+
+import (
+	"regexp"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+const (
+	ErrUnableToGetDiscovery    = "unable to get discoverable guilds"
+	ErrDiscoveryQueryTooLong   = "query must be at most 100 characters"
+	ErrDiscoveryLimitInvalid   = "limit must be between 1 and 100"
+	ErrDiscoveryOffsetInvalid  = "offset must not be negative"
+	ErrGuildCategoryInvalid    = "invalid guild category"
+	ErrGuildDescriptionTooLong = "description must be at most 300 characters"
+	ErrGuildNotPublic          = "guild is not public"
+	ErrVanityCodeInvalid       = "vanity code must be 3 to 32 lowercase letters, digits or dashes"
+	ErrVanityCodeTaken         = "vanity code is already taken"
+	ErrUnableToSetVanityCode   = "unable to set vanity code"
+
+	DefaultDiscoveryLimit = 25
+
+	// Number of members checked for the approximate online count
+	discoveryOnlineSample = 100
+	// Seconds the approximate online count is cached
+	discoveryOnlineTTL = 60
+)
+
+var (
+	// Invite codes are uppercase base36, vanity codes are lowercase
+	inviteCodeRegex = regexp.MustCompile(`^[0-9A-Z]{8}$`)
+	vanityCodeRegex = regexp.MustCompile(`^[a-z0-9-]{3,32}$`)
+)
+
+// isInviteOrVanityCode reports if the code can be an invite code or a vanity code
+func isInviteOrVanityCode(code string) bool {
+	return inviteCodeRegex.MatchString(code) || vanityCodeRegex.MatchString(code)
+}
+
+func validateGuildCategory(v interface{}) error {
+	p, _ := v.(*int)
+	if p == nil {
+		return nil
+	}
+	for _, c := range model.GuildCategories {
+		if model.GuildCategory(*p) == c {
+			return nil
+		}
+	}
+	return validation.NewError("validation", ErrGuildCategoryInvalid)
+}
+
+type GetDiscoverableGuildsRequest struct {
+	Query    string `query:"query" json:"query" example:"gaming"`  // Search in guild names and descriptions
+	Category *int   `query:"category" json:"category" example:"1"` // Only guilds of this category
+	Limit    *int   `query:"limit" json:"limit" example:"25"`      // Number of guilds to return. Default 25, max 100.
+	Offset   *int   `query:"offset" json:"offset" example:"0"`     // Number of guilds to skip
+}
+
+func (r GetDiscoverableGuildsRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Query, validation.RuneLength(0, 100).Error(ErrDiscoveryQueryTooLong)),
+		validation.Field(&r.Category,
+			validation.When(r.Category != nil, validation.By(validateGuildCategory)),
+		),
+		validation.Field(&r.Limit,
+			validation.When(r.Limit != nil,
+				validation.Required.Error(ErrDiscoveryLimitInvalid),
+				validation.Min(1).Error(ErrDiscoveryLimitInvalid),
+				validation.Max(100).Error(ErrDiscoveryLimitInvalid),
+			),
+		),
+		validation.Field(&r.Offset,
+			validation.When(r.Offset != nil, validation.Min(0).Error(ErrDiscoveryOffsetInvalid)),
+		),
+	)
+}
+
+type SetVanityCodeRequest struct {
+	Code string `json:"code" example:"my-guild"` // Lowercase letters, digits and dashes, 3 to 32 characters
+}
+
+func (r SetVanityCodeRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Code,
+			validation.Required.Error(ErrVanityCodeInvalid),
+			validation.Match(vanityCodeRegex).Error(ErrVanityCodeInvalid),
+		),
+	)
+}
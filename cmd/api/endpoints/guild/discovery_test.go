@@ -0,0 +1,113 @@
+package guild
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+func TestAcceptInviteJoinsByVanityCode(t *testing.T) {
+	members := &fakeMemberRepo{members: map[testMemberKey]bool{}}
+	invites := &fakeInviteRepo{}
+	e := &entity{
+		ban:  &fakeBanRepo{},
+		memb: members,
+		inv:  invites,
+		user: &fakeUserRepo{users: map[int64]model.User{10: {Id: 10, Name: "joiner"}}},
+		disc: &fakeDiscriminatorRepo{discriminators: map[int64]string{10: "joiner"}},
+		g:    &fakeGuildRepo{guild: model.Guild{Id: 1}, vanity: map[string]int64{"gophers": 1}},
+		mqt:  &fakeTransport{},
+	}
+
+	app := newGuildTestApp(t, 10, "/guild/invites/accept/:invite_code", e.AcceptInvite)
+	resp, err := app.Test(httptest.NewRequest("POST", "/guild/invites/accept/gophers", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if code := members.invites[testMemberKey{guildID: 1, userID: 10}]; code != "gophers" {
+		t.Fatalf("expected member to be recorded with vanity code, got %q", code)
+	}
+	if invites.invite.Uses != 0 {
+		t.Fatalf("expected vanity code not to count invite uses, got %d", invites.invite.Uses)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/guild/invites/accept/unknown", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestJoinDiscoverableGuildRequiresPublicGuild(t *testing.T) {
+	members := &fakeMemberRepo{members: map[testMemberKey]bool{}}
+	e := &entity{
+		ban:  &fakeBanRepo{},
+		memb: members,
+		user: &fakeUserRepo{users: map[int64]model.User{10: {Id: 10, Name: "joiner"}}},
+		disc: &fakeDiscriminatorRepo{discriminators: map[int64]string{10: "joiner"}},
+		g:    &fakeGuildRepo{guild: model.Guild{Id: 1}},
+		mqt:  &fakeTransport{},
+	}
+	app := newGuildTestApp(t, 10, "/guild/discovery/:guild_id/join", e.JoinDiscoverableGuild)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/guild/discovery/1/join", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+	if len(members.addCalls) != 0 {
+		t.Fatalf("expected no member additions, got %#v", members.addCalls)
+	}
+
+	e.g = &fakeGuildRepo{guild: model.Guild{Id: 1, Public: true}}
+	resp, err = app.Test(httptest.NewRequest("POST", "/guild/discovery/1/join", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if !members.members[testMemberKey{guildID: 1, userID: 10}] {
+		t.Fatal("expected user to join the public guild")
+	}
+}
+
+func TestGetDiscoverableGuildsRequestValidate(t *testing.T) {
+	category, limit := int(model.GuildCategoryMusic), 100
+	if err := (GetDiscoverableGuildsRequest{Query: "go", Category: &category, Limit: &limit}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	badCategory, badLimit, badOffset := 99, 101, -1
+	for _, req := range []GetDiscoverableGuildsRequest{
+		{Category: &badCategory},
+		{Limit: &badLimit},
+		{Offset: &badOffset},
+	} {
+		if err := req.Validate(); err == nil {
+			t.Fatalf("expected error for %#v", req)
+		}
+	}
+}
+
+func TestSetVanityCodeRequestValidate(t *testing.T) {
+	for _, code := range []string{"gophers", "go-chat", "abc"} {
+		if err := (SetVanityCodeRequest{Code: code}).Validate(); err != nil {
+			t.Fatalf("expected %q to be valid, got %v", code, err)
+		}
+	}
+	for _, code := range []string{"", "ab", "Gophers", "go_chat", "ABCDEFGH"} {
+		if err := (SetVanityCodeRequest{Code: code}).Validate(); err == nil {
+			t.Fatalf("expected %q to be invalid", code)
+		}
+	}
+}
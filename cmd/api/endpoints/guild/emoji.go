@@ -60,6 +60,7 @@ func (e *entity) CreateEmoji(c *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionEmojiCreate, uploadMeta.Id, auditChange(nil, "name", nil, uploadMeta.Name), nil)
 	_ = e.invalidateEmojiCache(c.UserContext(), guildId, uploadMeta.Id)
 	return c.JSON(uploadMeta)
 }
@@ -182,6 +183,9 @@ func (e *entity) UpdateEmoji(c *fiber.Ctx) error {
 		return err
 	}
 
+	// Previous name is only needed for the audit diff
+	prev, prevErr := e.emoji.GetGuildEmoji(c.UserContext(), guildId, emojiId)
+
 	updated, err := e.emoji.Rename(c.UserContext(), guildId, emojiId, req.Name, emojiutil.NormalizeName(req.Name))
 	if err != nil {
 		switch {
@@ -194,6 +198,12 @@ func (e *entity) UpdateEmoji(c *fiber.Ctx) error {
 		}
 	}
 
+	if prevErr == nil {
+		if changes := auditChange(nil, "name", prev.Name, updated.Name); len(changes) > 0 {
+			e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionEmojiUpdate, emojiId, changes, nil)
+		}
+	}
+
 	_ = e.invalidateEmojiCache(c.UserContext(), guildId, emojiId)
 	go e.publishEmojiUpdate(guildId, guildEmojiToDTO(updated))
 	return c.JSON(guildEmojiToDTO(updated))
@@ -243,6 +253,7 @@ func (e *entity) DeleteEmoji(c *fiber.Ctx) error {
 	if _, err = e.emoji.Delete(c.UserContext(), guildId, emojiId); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToDeleteEmoji)
 	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionEmojiDelete, emojiId, auditChange(nil, "name", emoji.Name, nil), nil)
 	_ = e.invalidateEmojiCache(c.UserContext(), guildId, emojiId)
 	go e.publishEmojiDelete(guildId, emojiId)
 	return c.SendStatus(fiber.StatusOK)
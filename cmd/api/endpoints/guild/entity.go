@@ -8,26 +8,35 @@ import (
 	"github.com/FlameInTheDark/gochat/internal/cache"
 	"github.com/FlameInTheDark/gochat/internal/database/db"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/attachment"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/audit"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/avatar"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/banned"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/eventdelivery"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/icon"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/message"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/pin"
 	"github.com/FlameInTheDark/gochat/internal/database/pgdb"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/channel"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/channelroleperm"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/channeluserperm"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/discriminator"
 	emojirepo "github.com/FlameInTheDark/gochat/internal/database/pgentities/emoji"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/eventwebhook"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/guild"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/guildchannels"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/invite"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/member"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/role"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/rolecheck"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/thread"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/threadmember"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/user"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/usermfa"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/userrole"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/webhook"
 	"github.com/FlameInTheDark/gochat/internal/indexmq"
 	"github.com/FlameInTheDark/gochat/internal/mq"
+	"github.com/FlameInTheDark/gochat/internal/presence"
 	"github.com/FlameInTheDark/gochat/internal/s3"
 	"github.com/FlameInTheDark/gochat/internal/server"
 	"github.com/FlameInTheDark/gochat/internal/voice/discovery"
@@ -60,15 +69,43 @@ func (e *entity) Init(router fiber.Router) {
 	router.Delete("/:guild_id<int>/channel/:channel_id<int>", e.DeleteChannel)
 	router.Delete("/:guild_id<int>/category/:category_id<int>", e.DeleteCategory)
 
+	router.Post("/:guild_id<int>/channel/:channel_id<int>/threads", e.CreateThread)
+	router.Get("/:guild_id<int>/channel/:channel_id<int>/threads", e.ListThreads)
+	router.Post("/:guild_id<int>/channel/:channel_id<int>/webhooks", e.CreateWebhook)
+	router.Get("/:guild_id<int>/channel/:channel_id<int>/webhooks", e.GetWebhooks)
+	router.Post("/:guild_id<int>/channel/:channel_id<int>/webhooks/:webhook_id<int>/token", e.RotateWebhookToken)
+	router.Delete("/:guild_id<int>/channel/:channel_id<int>/webhooks/:webhook_id<int>", e.DeleteWebhook)
+	router.Patch("/:guild_id<int>/thread/:thread_id<int>", e.UpdateThread)
+	router.Delete("/:guild_id<int>/thread/:thread_id<int>", e.DeleteThread)
+	router.Get("/:guild_id<int>/thread/:thread_id<int>/members", e.GetThreadMembers)
+	router.Put("/:guild_id<int>/thread/:thread_id<int>/members/@me", e.JoinThread)
+	router.Delete("/:guild_id<int>/thread/:thread_id<int>/members/@me", e.LeaveThread)
+
 	router.Post("/:guild_id<int>/voice/:channel_id<int>/join", e.JoinVoice)
 	router.Patch("/:guild_id<int>/voice/:channel_id<int>/region", e.SetVoiceRegion)
 	router.Post("/:guild_id<int>/voice/move", e.MoveMember)
+	router.Post("/:guild_id<int>/voice/:channel_id<int>/recording", e.StartVoiceRecording)
+	router.Delete("/:guild_id<int>/voice/:channel_id<int>/recording", e.StopVoiceRecording)
 
 	router.Get("/:guild_id<int>/members", e.GetMembers)
+	router.Get("/:guild_id<int>/members/pending", e.GetPendingMembers)
+	router.Post("/:guild_id<int>/screening", e.AcceptRules)
+	router.Post("/:guild_id<int>/member/:user_id<int>/approve", e.ApprovePendingMember)
+	router.Post("/:guild_id<int>/member/:user_id<int>/reject", e.RejectPendingMember)
 	router.Get("/:guild_id<int>/bans", e.GetBans)
 	router.Post("/:guild_id<int>/member/:user_id<int>/kick", e.KickMember)
 	router.Post("/:guild_id<int>/member/:user_id<int>/ban", e.BanMember)
 	router.Delete("/:guild_id<int>/member/:user_id<int>/ban", e.UnbanMember)
+	router.Post("/:guild_id<int>/member/:user_id<int>/timeout", e.TimeoutMember)
+	router.Delete("/:guild_id<int>/member/:user_id<int>/timeout", e.RemoveMemberTimeout)
+	router.Get("/:guild_id<int>/audit-log", e.GetAuditLog)
+	router.Post("/:guild_id<int>/bots", e.AddBot)
+	router.Post("/:guild_id<int>/event-webhooks", e.CreateEventWebhook)
+	router.Get("/:guild_id<int>/event-webhooks", e.GetEventWebhooks)
+	router.Patch("/:guild_id<int>/event-webhooks/:webhook_id<int>", e.UpdateEventWebhook)
+	router.Delete("/:guild_id<int>/event-webhooks/:webhook_id<int>", e.DeleteEventWebhook)
+	router.Post("/:guild_id<int>/event-webhooks/:webhook_id<int>/secret", e.RotateEventWebhookSecret)
+	router.Get("/:guild_id<int>/event-webhooks/:webhook_id<int>/deliveries", e.GetEventWebhookDeliveries)
 
 	router.Get("/:guild_id<int>/roles", e.GetGuildRoles)
 	router.Post("/:guild_id<int>/roles", e.CreateGuildRole)
@@ -84,6 +121,11 @@ func (e *entity) Init(router fiber.Router) {
 	router.Patch("/:guild_id<int>/channel/:channel_id<int>/roles/:role_id<int>", e.UpdateChannelRolePermission)
 	router.Delete("/:guild_id<int>/channel/:channel_id<int>/roles/:role_id<int>", e.RemoveChannelRolePermission)
 
+	router.Put("/:guild_id<int>/vanity", e.SetVanityCode)
+	router.Delete("/:guild_id<int>/vanity", e.DeleteVanityCode)
+	router.Get("/discovery", e.GetDiscoverableGuilds)
+	router.Post("/discovery/:guild_id<int>/join", e.JoinDiscoverableGuild)
+
 	router.Get("/invites/receive/:invite_code", e.ReceiveInvite)
 	router.Post("/invites/accept/:invite_code", e.AcceptInvite)
 	router.Get("/invites/:guild_id<int>", e.ListInvites)
@@ -99,24 +141,33 @@ type entity struct {
 	imq   *indexmq.IndexMQ
 	cache cache.Cache
 
-	user  user.User
-	disc  discriminator.Discriminator
-	ch    channel.Channel
-	g     guild.Guild
-	gc    guildchannels.GuildChannels
-	msg   message.Message
-	at    attachment.Attachment
-	perm  permissionChecker
-	uperm channeluserperm.ChannelUserPerm
-	rperm channelroleperm.ChannelRolePerm
-	role  role.Role
-	ur    userrole.UserRole
-	icon  icon.Icon
-	emoji emojirepo.Emoji
-	memb  member.Member
-	ban   banned.Banned
-	inv   invite.Invite
-	av    avatar.Avatar
+	user   user.User
+	disc   discriminator.Discriminator
+	ch     channel.Channel
+	g      guild.Guild
+	gc     guildchannels.GuildChannels
+	msg    message.Message
+	pin    pin.Pin
+	at     attachment.Attachment
+	perm   permissionChecker
+	uperm  channeluserperm.ChannelUserPerm
+	rperm  channelroleperm.ChannelRolePerm
+	role   role.Role
+	ur     userrole.UserRole
+	icon   icon.Icon
+	emoji  emojirepo.Emoji
+	memb   member.Member
+	ban    banned.Banned
+	inv    invite.Invite
+	av     avatar.Avatar
+	audit  audit.Audit
+	thread thread.Thread
+	tmemb  threadmember.ThreadMember
+	mfa    usermfa.UserMFA
+	wh     webhook.Webhook
+	ewh    eventwebhook.EventWebhook
+	edl    eventdelivery.EventDelivery
+	pres   *presence.Store
 
 	storage            *s3.Client
 	attachTTL          int64
@@ -124,13 +175,14 @@ type entity struct {
 	defaultVoiceRegion string
 	disco              discovery.Manager
 	allowedRegions     map[string]struct{}
+	voiceCascade       bool
 }
 
 func (e *entity) Name() string {
 	return e.name
 }
 
-func New(dbcon *db.CQLCon, pg *pgdb.DB, mqt mq.SendTransporter, imq *indexmq.IndexMQ, cache cache.Cache, storage *s3.Client, attachTTLSeconds int64, authSecret string, defaultVoiceRegion string, disco discovery.Manager, allowedRegions []string, log *slog.Logger) server.Entity {
+func New(dbcon *db.CQLCon, pg *pgdb.DB, mqt mq.SendTransporter, imq *indexmq.IndexMQ, cache cache.Cache, storage *s3.Client, attachTTLSeconds int64, authSecret string, defaultVoiceRegion string, disco discovery.Manager, allowedRegions []string, voiceCascade bool, log *slog.Logger) server.Entity {
 	ar := make(map[string]struct{}, len(allowedRegions))
 	for _, r := range allowedRegions {
 		if r == "" {
@@ -162,11 +214,21 @@ func New(dbcon *db.CQLCon, pg *pgdb.DB, mqt mq.SendTransporter, imq *indexmq.Ind
 		ban:                banned.New(dbcon),
 		inv:                invite.New(pg.Conn()),
 		av:                 avatar.New(dbcon),
+		audit:              audit.New(dbcon),
+		pin:                pin.New(dbcon),
+		thread:             thread.New(pg.Conn()),
+		tmemb:              threadmember.New(pg.Conn()),
+		mfa:                usermfa.New(pg.Conn()),
+		wh:                 webhook.New(pg.Conn()),
+		ewh:                eventwebhook.New(pg.Conn()),
+		edl:                eventdelivery.New(dbcon),
+		pres:               presence.NewStore(cache),
 		storage:            storage,
 		attachTTL:          attachTTLSeconds,
 		authSecret:         authSecret,
 		defaultVoiceRegion: defaultVoiceRegion,
 		disco:              disco,
 		allowedRegions:     ar,
+		voiceCascade:       voiceCascade,
 	}
 }
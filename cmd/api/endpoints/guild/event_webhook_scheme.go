@@ -0,0 +1,175 @@
+package guild
+
+import (
+	"errors"
+	"net/url"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/eventhook"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+const (
+	ErrUnableToGetEventWebhooks    = "unable to get event webhooks"
+	ErrUnableToCreateEventWebhook  = "unable to create event webhook"
+	ErrUnableToUpdateEventWebhook  = "unable to update event webhook"
+	ErrUnableToDeleteEventWebhook  = "unable to delete event webhook"
+	ErrUnableToGetDeliveries       = "unable to get event webhook deliveries"
+	ErrEventWebhookNotFound        = "event webhook not found"
+	ErrEventWebhookLimitReached    = "guild event webhook limit reached"
+	ErrEventWebhookURLFormat       = "url must be a valid HTTPS URL"
+	ErrEventWebhookEventsRequired  = "at least one event type is required"
+	ErrEventWebhookEventInvalid    = "event type can not be subscribed to"
+	ErrEventWebhookNoChanges       = "nothing to update"
+	ErrEventWebhookDeliveriesLimit = "limit must be between 1 and 100"
+	ErrEventWebhookDeliveryBefore  = "before must be a positive delivery ID"
+
+	// MaxGuildEventWebhooks is the maximum number of event webhooks in a guild
+	MaxGuildEventWebhooks = 10
+	// DefaultEventWebhookDeliveriesLimit is the number of delivery log records returned by default
+	DefaultEventWebhookDeliveriesLimit = 50
+	// eventWebhookSecretLength is the length of the signing secret
+	eventWebhookSecretLength = 32
+	maxEventWebhookURLLength = 2048
+)
+
+type CreateEventWebhookRequest struct {
+	URL    string  `json:"url" example:"https://example.com/gochat/events"` // HTTPS endpoint receiving the events
+	Events []int64 `json:"events" example:"100,200"`                        // Event types to receive
+}
+
+func (r CreateEventWebhookRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.URL, eventWebhookURLRules()...),
+		validation.Field(&r.Events, validation.Required.Error(ErrEventWebhookEventsRequired), validation.By(validateEventTypes)),
+	)
+}
+
+type UpdateEventWebhookRequest struct {
+	URL     *string  `json:"url,omitempty" example:"https://example.com/gochat/events"` // HTTPS endpoint receiving the events
+	Events  *[]int64 `json:"events,omitempty" example:"100,200"`                        // Event types to receive, replaces the current list
+	Enabled *bool    `json:"enabled,omitempty" example:"true"`                          // Enabling the webhook resets its failure counter
+}
+
+func (r UpdateEventWebhookRequest) Validate() error {
+	if r.URL == nil && r.Events == nil && r.Enabled == nil {
+		return errors.New(ErrEventWebhookNoChanges)
+	}
+	var events []int64
+	if r.Events != nil {
+		events = *r.Events
+	}
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.URL, validation.When(r.URL != nil, eventWebhookURLRules()...)),
+		validation.Field(&r.Events, validation.When(r.Events != nil,
+			validation.By(func(interface{}) error {
+				if len(events) == 0 {
+					return errors.New(ErrEventWebhookEventsRequired)
+				}
+				return validateEventTypes(events)
+			}),
+		)),
+	)
+}
+
+type GetEventWebhookDeliveriesRequest struct {
+	Before *int64 `query:"before" json:"before" example:"2230469276416868352"` // Return deliveries older than this delivery ID
+	Limit  *int   `query:"limit" json:"limit" example:"50"`                    // Number of deliveries to return. Default 50, max 100.
+}
+
+func (r GetEventWebhookDeliveriesRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Before,
+			validation.When(r.Before != nil, validation.Required.Error(ErrEventWebhookDeliveryBefore), validation.Min(int64(1)).Error(ErrEventWebhookDeliveryBefore)),
+		),
+		validation.Field(&r.Limit,
+			validation.When(r.Limit != nil,
+				validation.Required.Error(ErrEventWebhookDeliveriesLimit),
+				validation.Min(1).Error(ErrEventWebhookDeliveriesLimit),
+				validation.Max(100).Error(ErrEventWebhookDeliveriesLimit),
+			),
+		),
+	)
+}
+
+func eventWebhookURLRules() []validation.Rule {
+	return []validation.Rule{
+		validation.Required.Error(ErrEventWebhookURLFormat),
+		validation.Length(1, maxEventWebhookURLLength).Error(ErrEventWebhookURLFormat),
+		is.URL.Error(ErrEventWebhookURLFormat),
+		validation.By(func(value interface{}) error {
+			var raw string
+			switch v := value.(type) {
+			case string:
+				raw = v
+			case *string:
+				raw = *v
+			}
+			u, err := url.Parse(raw)
+			if err != nil || u.Scheme != "https" || u.Host == "" {
+				return errors.New(ErrEventWebhookURLFormat)
+			}
+			return nil
+		}),
+	}
+}
+
+func validateEventTypes(value interface{}) error {
+	events, _ := value.([]int64)
+	for _, t := range events {
+		if !eventhook.Allowed(mqmsg.EventType(t)) {
+			return errors.New(ErrEventWebhookEventInvalid)
+		}
+	}
+	return nil
+}
+
+// uniqueEvents removes repeated event types and keeps the order
+func uniqueEvents(events []int64) []int64 {
+	seen := make(map[int64]struct{}, len(events))
+	result := make([]int64, 0, len(events))
+	for _, t := range events {
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		result = append(result, t)
+	}
+	return result
+}
+
+func eventWebhookModelToDTO(w model.EventWebhook) dto.EventWebhook {
+	events := []int64(w.Events)
+	if events == nil {
+		events = []int64{}
+	}
+	return dto.EventWebhook{
+		Id:           w.Id,
+		GuildId:      w.GuildId,
+		CreatorId:    w.CreatorId,
+		URL:          w.URL,
+		Events:       events,
+		Enabled:      w.Enabled,
+		FailureCount: w.FailureCount,
+		DisabledAt:   w.DisabledAt,
+		CreatedAt:    w.CreatedAt,
+	}
+}
+
+func eventDeliveryModelToDTO(d model.EventWebhookDelivery) dto.EventWebhookDelivery {
+	return dto.EventWebhookDelivery{
+		Id:         d.Id,
+		EventId:    d.EventId,
+		EventType:  d.EventType,
+		Attempt:    d.Attempt,
+		StatusCode: d.StatusCode,
+		Error:      d.Error,
+		DurationMs: d.DurationMs,
+		CreatedAt:  idgen.GetTime(d.Id),
+	}
+}
@@ -0,0 +1,318 @@
+package guild
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+// CreateEventWebhook
+//
+//	@Summary		Create event webhook
+//	@Description	Registers an HTTPS endpoint that receives signed callbacks for the chosen guild events. The signing secret is returned only once. Requires PermServerManage.
+//	@Tags			Guild
+//	@Accept			json
+//	@Produce		json
+//	@Param			guild_id	path		int64						true	"Guild ID"	example(2230469276416868352)
+//	@Param			request		body		CreateEventWebhookRequest	true	"Event webhook data"
+//	@Success		200			{object}	dto.EventWebhook			"Event webhook with secret"
+//	@failure		400			{string}	string						"Incorrect request body"
+//	@failure		406			{string}	string						"Permissions required"
+//	@failure		409			{string}	string						"Event webhook limit reached"
+//	@failure		500			{string}	string						"Something bad happened"
+//	@Router			/guild/{guild_id}/event-webhooks [post]
+func (e *entity) CreateEventWebhook(c *fiber.Ctx) error {
+	guildId, user, err := e.authorizeEventWebhooks(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateEventWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	existing, err := e.ewh.GetGuildEventWebhooks(c.UserContext(), guildId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetEventWebhooks)
+	}
+	if len(existing) >= MaxGuildEventWebhooks {
+		return fiber.NewError(fiber.StatusConflict, ErrEventWebhookLimitReached)
+	}
+
+	secret, err := helper.RandomToken(eventWebhookSecretLength)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateEventWebhook)
+	}
+	webhook := model.EventWebhook{
+		GuildId:   guildId,
+		Id:        idgen.Next(),
+		CreatorId: user.Id,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    uniqueEvents(req.Events),
+	}
+	if err := e.ewh.CreateEventWebhook(c.UserContext(), webhook); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateEventWebhook)
+	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionEventWebhookCreate, webhook.Id, auditChange(nil, "url", nil, webhook.URL), nil)
+
+	created, err := e.ewh.GetEventWebhook(c.UserContext(), guildId, webhook.Id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetEventWebhooks)
+	}
+	result := eventWebhookModelToDTO(created)
+	result.Secret = secret
+	return c.JSON(result)
+}
+
+// GetEventWebhooks
+//
+//	@Summary	List event webhooks
+//	@Produce	json
+//	@Tags		Guild
+//	@Param		guild_id	path		int64				true	"Guild ID"	example(2230469276416868352)
+//	@Success	200			{array}		dto.EventWebhook	"Event webhooks without secrets"
+//	@failure	400			{string}	string				"Incorrect request"
+//	@failure	406			{string}	string				"Permissions required"
+//	@failure	500			{string}	string				"Something bad happened"
+//	@Router		/guild/{guild_id}/event-webhooks [get]
+func (e *entity) GetEventWebhooks(c *fiber.Ctx) error {
+	guildId, _, err := e.authorizeEventWebhooks(c)
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := e.ewh.GetGuildEventWebhooks(c.UserContext(), guildId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetEventWebhooks)
+	}
+	result := make([]dto.EventWebhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		result = append(result, eventWebhookModelToDTO(w))
+	}
+	return c.JSON(result)
+}
+
+// UpdateEventWebhook
+//
+//	@Summary		Update event webhook
+//	@Description	Changes the endpoint URL or the subscribed events, or enables and disables the webhook. Enabling resets the failure counter.
+//	@Tags			Guild
+//	@Accept			json
+//	@Produce		json
+//	@Param			guild_id	path		int64						true	"Guild ID"			example(2230469276416868352)
+//	@Param			webhook_id	path		int64						true	"Event webhook ID"	example(2230469276416868352)
+//	@Param			request		body		UpdateEventWebhookRequest	true	"Changes"
+//	@Success		200			{object}	dto.EventWebhook			"Updated event webhook"
+//	@failure		400			{string}	string						"Incorrect request body"
+//	@failure		404			{string}	string						"Event webhook not found"
+//	@failure		406			{string}	string						"Permissions required"
+//	@failure		500			{string}	string						"Something bad happened"
+//	@Router			/guild/{guild_id}/event-webhooks/{webhook_id} [patch]
+func (e *entity) UpdateEventWebhook(c *fiber.Ctx) error {
+	webhook, user, err := e.getEventWebhook(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateEventWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var events []int64
+	if req.Events != nil {
+		events = uniqueEvents(*req.Events)
+	}
+	if err := e.ewh.UpdateEventWebhook(c.UserContext(), webhook.GuildId, webhook.Id, req.URL, events, req.Enabled); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateEventWebhook)
+	}
+
+	var changes []model.AuditChange
+	if req.URL != nil {
+		changes = auditChange(changes, "url", webhook.URL, *req.URL)
+	}
+	if req.Events != nil {
+		changes = auditChange(changes, "events", []int64(webhook.Events), events)
+	}
+	if req.Enabled != nil {
+		changes = auditChange(changes, "enabled", webhook.Enabled, *req.Enabled)
+	}
+	e.recordAudit(c.UserContext(), webhook.GuildId, user.Id, model.AuditActionEventWebhookUpdate, webhook.Id, changes, nil)
+
+	updated, err := e.ewh.GetEventWebhook(c.UserContext(), webhook.GuildId, webhook.Id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetEventWebhooks)
+	}
+	return c.JSON(eventWebhookModelToDTO(updated))
+}
+
+// RotateEventWebhookSecret
+//
+//	@Summary		Rotate event webhook secret
+//	@Description	Replaces the signing secret. Deliveries made after the rotation, including retries, are signed with the new secret.
+//	@Tags			Guild
+//	@Produce		json
+//	@Param			guild_id	path		int64				true	"Guild ID"			example(2230469276416868352)
+//	@Param			webhook_id	path		int64				true	"Event webhook ID"	example(2230469276416868352)
+//	@Success		200			{object}	dto.EventWebhook	"Event webhook with the new secret"
+//	@failure		400			{string}	string				"Incorrect request"
+//	@failure		404			{string}	string				"Event webhook not found"
+//	@failure		406			{string}	string				"Permissions required"
+//	@failure		500			{string}	string				"Something bad happened"
+//	@Router			/guild/{guild_id}/event-webhooks/{webhook_id}/secret [post]
+func (e *entity) RotateEventWebhookSecret(c *fiber.Ctx) error {
+	webhook, user, err := e.getEventWebhook(c)
+	if err != nil {
+		return err
+	}
+
+	secret, err := helper.RandomToken(eventWebhookSecretLength)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateEventWebhook)
+	}
+	if err := e.ewh.SetSecret(c.UserContext(), webhook.GuildId, webhook.Id, secret); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateEventWebhook)
+	}
+	e.recordAudit(c.UserContext(), webhook.GuildId, user.Id, model.AuditActionEventWebhookUpdate, webhook.Id, []model.AuditChange{{Key: "secret"}}, nil)
+
+	result := eventWebhookModelToDTO(webhook)
+	result.Secret = secret
+	return c.JSON(result)
+}
+
+// DeleteEventWebhook
+//
+//	@Summary	Delete event webhook
+//	@Produce	json
+//	@Tags		Guild
+//	@Param		guild_id	path		int64	true	"Guild ID"			example(2230469276416868352)
+//	@Param		webhook_id	path		int64	true	"Event webhook ID"	example(2230469276416868352)
+//	@Success	200			{string}	string	"Deleted"
+//	@failure	400			{string}	string	"Incorrect request"
+//	@failure	404			{string}	string	"Event webhook not found"
+//	@failure	406			{string}	string	"Permissions required"
+//	@failure	500			{string}	string	"Something bad happened"
+//	@Router		/guild/{guild_id}/event-webhooks/{webhook_id} [delete]
+func (e *entity) DeleteEventWebhook(c *fiber.Ctx) error {
+	webhook, user, err := e.getEventWebhook(c)
+	if err != nil {
+		return err
+	}
+
+	if err := e.ewh.DeleteEventWebhook(c.UserContext(), webhook.GuildId, webhook.Id); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToDeleteEventWebhook)
+	}
+	if e.edl != nil {
+		if err := e.edl.RemoveDeliveries(c.UserContext(), webhook.Id); err != nil {
+			e.log.Error("unable to remove event webhook deliveries", slog.Int64("webhook_id", webhook.Id), slog.String("error", err.Error()))
+		}
+	}
+	e.recordAudit(c.UserContext(), webhook.GuildId, user.Id, model.AuditActionEventWebhookDelete, webhook.Id, auditChange(nil, "url", webhook.URL, nil), nil)
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// GetEventWebhookDeliveries
+//
+//	@Summary		Get event webhook deliveries
+//	@Description	Returns the delivery attempts of the last 7 days, newest first. Use the ID of the last returned attempt as `before` to get the next page.
+//	@Produce		json
+//	@Tags			Guild
+//	@Param			guild_id	path		int64						true	"Guild ID"			example(2230469276416868352)
+//	@Param			webhook_id	path		int64						true	"Event webhook ID"	example(2230469276416868352)
+//	@Param			before		query		int64						false	"Return deliveries older than this delivery ID"
+//	@Param			limit		query		int							false	"Number of deliveries to return (1-100, default 50)"
+//	@Success		200			{array}		dto.EventWebhookDelivery	"Delivery attempts"
+//	@failure		400			{string}	string						"Incorrect request"
+//	@failure		404			{string}	string						"Event webhook not found"
+//	@failure		406			{string}	string						"Permissions required"
+//	@failure		500			{string}	string						"Something bad happened"
+//	@Router			/guild/{guild_id}/event-webhooks/{webhook_id}/deliveries [get]
+func (e *entity) GetEventWebhookDeliveries(c *fiber.Ctx) error {
+	var req GetEventWebhookDeliveriesRequest
+	if err := c.QueryParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	webhook, _, err := e.getEventWebhook(c)
+	if err != nil {
+		return err
+	}
+	if e.edl == nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetDeliveries)
+	}
+
+	limit := DefaultEventWebhookDeliveriesLimit
+	if req.Limit != nil {
+		limit = *req.Limit
+	}
+	var before int64
+	if req.Before != nil {
+		before = *req.Before
+	}
+	deliveries, err := e.edl.GetDeliveriesBefore(c.UserContext(), webhook.Id, before, limit)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetDeliveries)
+	}
+	result := make([]dto.EventWebhookDelivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		result = append(result, eventDeliveryModelToDTO(d))
+	}
+	return c.JSON(result)
+}
+
+// authorizeEventWebhooks checks that the user can manage the event webhooks of the guild from the URL
+func (e *entity) authorizeEventWebhooks(c *fiber.Ctx) (int64, *helper.JWTUser, error) {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return 0, nil, err
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return 0, nil, fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+	if _, err := e.authorizeGuildPermission(c.UserContext(), guildId, user.Id, permissions.PermServerManage); err != nil {
+		return 0, nil, err
+	}
+	return guildId, user, nil
+}
+
+// getEventWebhook returns the event webhook from the URL after checking that the user can manage it
+func (e *entity) getEventWebhook(c *fiber.Ctx) (model.EventWebhook, *helper.JWTUser, error) {
+	guildId, user, err := e.authorizeEventWebhooks(c)
+	if err != nil {
+		return model.EventWebhook{}, nil, err
+	}
+	webhookId, err := strconv.ParseInt(c.Params("webhook_id"), 10, 64)
+	if err != nil {
+		return model.EventWebhook{}, nil, fiber.NewError(fiber.StatusBadRequest, ErrIncorrectWebhookID)
+	}
+
+	webhook, err := e.ewh.GetEventWebhook(c.UserContext(), guildId, webhookId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.EventWebhook{}, nil, fiber.NewError(fiber.StatusNotFound, ErrEventWebhookNotFound)
+	} else if err != nil {
+		return model.EventWebhook{}, nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetEventWebhooks)
+	}
+	return webhook, user, nil
+}
@@ -0,0 +1,189 @@
+package guild
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+type fakeEventWebhookRepo struct {
+	webhooks map[int64]model.EventWebhook
+}
+
+func (f *fakeEventWebhookRepo) CreateEventWebhook(ctx context.Context, webhook model.EventWebhook) error {
+	webhook.Enabled = true
+	f.webhooks[webhook.Id] = webhook
+	return nil
+}
+
+func (f *fakeEventWebhookRepo) GetEventWebhook(ctx context.Context, guildId, id int64) (model.EventWebhook, error) {
+	webhook, ok := f.webhooks[id]
+	if !ok || webhook.GuildId != guildId {
+		return model.EventWebhook{}, sql.ErrNoRows
+	}
+	return webhook, nil
+}
+
+func (f *fakeEventWebhookRepo) GetGuildEventWebhooks(ctx context.Context, guildId int64) ([]model.EventWebhook, error) {
+	var out []model.EventWebhook
+	for _, w := range f.webhooks {
+		if w.GuildId == guildId {
+			out = append(out, w)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeEventWebhookRepo) UpdateEventWebhook(ctx context.Context, guildId, id int64, url *string, events []int64, enabled *bool) error {
+	webhook := f.webhooks[id]
+	if url != nil {
+		webhook.URL = *url
+	}
+	if events != nil {
+		webhook.Events = events
+	}
+	if enabled != nil {
+		webhook.Enabled = *enabled
+		if *enabled {
+			webhook.FailureCount = 0
+		}
+	}
+	f.webhooks[id] = webhook
+	return nil
+}
+
+func (f *fakeEventWebhookRepo) SetSecret(ctx context.Context, guildId, id int64, secret string) error {
+	webhook := f.webhooks[id]
+	webhook.Secret = secret
+	f.webhooks[id] = webhook
+	return nil
+}
+
+func (f *fakeEventWebhookRepo) RecordSuccess(ctx context.Context, guildId, id int64) error {
+	return nil
+}
+
+func (f *fakeEventWebhookRepo) RecordFailure(ctx context.Context, guildId, id int64, disableAfter int) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeEventWebhookRepo) DeleteEventWebhook(ctx context.Context, guildId, id int64) error {
+	delete(f.webhooks, id)
+	return nil
+}
+
+func (f *fakeEventWebhookRepo) DeleteGuildEventWebhooks(ctx context.Context, guildId int64) error {
+	return nil
+}
+
+func newEventWebhookTestEntity(allowed bool) (*entity, *fakeEventWebhookRepo) {
+	repo := &fakeEventWebhookRepo{webhooks: map[int64]model.EventWebhook{}}
+	return &entity{
+		g:    &fakeGuildRepo{guild: model.Guild{Id: 1, OwnerId: 99}},
+		memb: &fakeMemberRepo{members: map[testMemberKey]bool{{guildID: 1, userID: 10}: true}},
+		perm: &fakePermissionChecker{results: map[testPermKey]bool{
+			{guildID: 1, userID: 10, perm: permissions.PermServerManage}: allowed,
+		}},
+		ewh: repo,
+	}, repo
+}
+
+func TestCreateEventWebhookReturnsSecret(t *testing.T) {
+	e, repo := newEventWebhookTestEntity(true)
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/event-webhooks", e.CreateEventWebhook)
+
+	body := `{"url":"https://example.com/events","events":[100,200,100]}`
+	req := httptest.NewRequest(fiber.MethodPost, "/guild/1/event-webhooks", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var created dto.EventWebhook
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Secret == "" || created.Secret != repo.webhooks[created.Id].Secret {
+		t.Fatalf("expected secret of the stored webhook, got %+v", created)
+	}
+	if len(created.Events) != 2 || created.Events[0] != 100 || created.Events[1] != 200 {
+		t.Fatalf("expected unique events, got %v", created.Events)
+	}
+}
+
+func TestCreateEventWebhookValidation(t *testing.T) {
+	e, repo := newEventWebhookTestEntity(true)
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/event-webhooks", e.CreateEventWebhook)
+
+	for _, body := range []string{
+		`{"url":"http://example.com/events","events":[100]}`,
+		`{"url":"https://example.com/events","events":[]}`,
+		`{"url":"https://example.com/events","events":[301]}`,
+	} {
+		req := httptest.NewRequest(fiber.MethodPost, "/guild/1/event-webhooks", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Fatalf("expected status 400 for %s, got %d", body, resp.StatusCode)
+		}
+	}
+	if len(repo.webhooks) != 0 {
+		t.Fatal("expected no webhook to be created")
+	}
+}
+
+func TestCreateEventWebhookRequiresPermission(t *testing.T) {
+	e, repo := newEventWebhookTestEntity(false)
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/event-webhooks", e.CreateEventWebhook)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/guild/1/event-webhooks", strings.NewReader(`{"url":"https://example.com/events","events":[100]}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotAcceptable {
+		t.Fatalf("expected status 406, got %d", resp.StatusCode)
+	}
+	if len(repo.webhooks) != 0 {
+		t.Fatal("expected no webhook to be created")
+	}
+}
+
+func TestUpdateEventWebhookEnablesAndHidesSecret(t *testing.T) {
+	e, repo := newEventWebhookTestEntity(true)
+	repo.webhooks[5] = model.EventWebhook{GuildId: 1, Id: 5, URL: "https://example.com/events", Secret: "secret", Events: []int64{100}, FailureCount: 5}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/event-webhooks/:webhook_id", e.UpdateEventWebhook)
+
+	req := httptest.NewRequest(fiber.MethodPatch, "/guild/1/event-webhooks/5", strings.NewReader(`{"enabled":true}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var updated dto.EventWebhook
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Enabled || updated.FailureCount != 0 || updated.Secret != "" {
+		t.Fatalf("unexpected webhook %+v", updated)
+	}
+}
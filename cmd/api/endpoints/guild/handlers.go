@@ -227,7 +227,6 @@ func (e *entity) GetChannels(c *fiber.Ctx) error {
 // deriveChannelParents assigns ParentId to guild/voice channels based on positional order.
 // After sorting by Position, each guild or voice channel inherits the id of the last
 // category above it. Channels before any category have a nil parent.
-// Thread channels keep their existing ParentId unchanged.
 func deriveChannelParents(channels []dto.Channel) {
 	sort.Slice(channels, func(i, j int) bool {
 		return channels[i].Position < channels[j].Position
@@ -273,12 +272,16 @@ func (e *entity) fetchAndFilterChannels(c *fiber.Ctx, guildCtx *guildContext) er
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
 
-	var channelsData = make([]dto.Channel, len(channels))
+	var channelsData = make([]dto.Channel, 0, len(channels))
 	for i, ch := range channels {
+		// Threads are listed per parent channel
+		if ch.Type == model.ChannelTypeThread {
+			continue
+		}
 		if ch.Permissions == nil {
 			ch.Permissions = &guildCtx.Guild.Permissions
 		}
-		channelsData[i] = channelModelToDTO(&ch, &guildCtx.Guild.Id, guildChannels[i].Position, croles[i].Roles)
+		channelsData = append(channelsData, channelModelToDTO(&ch, &guildCtx.Guild.Id, guildChannels[i].Position, croles[i].Roles))
 	}
 
 	deriveChannelParents(channelsData)
@@ -411,7 +414,7 @@ func (e *entity) createGuildWithDefaults(c *fiber.Ctx, req *CreateGuildRequest,
 	}
 
 	// Add creator as member
-	if err := e.memb.AddMember(c.UserContext(), user.Id, guildId); err != nil {
+	if err := e.memb.AddMember(c.UserContext(), user.Id, guildId, false); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
 
@@ -507,9 +510,25 @@ func (e *entity) Delete(c *fiber.Ctx) error {
 				return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateChannel)
 			}
 		}
+		if e.pin != nil {
+			if delErr := e.pin.RemoveChannelPins(c.UserContext(), ch.Id); delErr != nil {
+				slog.Error("unable to remove channel pins", slog.String("error", delErr.Error()))
+			}
+		}
+		if e.wh != nil {
+			if delErr := e.wh.DeleteChannelWebhooks(c.UserContext(), ch.Id); delErr != nil {
+				slog.Error("unable to remove channel webhooks", slog.String("error", delErr.Error()))
+			}
+		}
 		if remErr := e.gc.RemoveChannel(c.UserContext(), guildId, ch.Id); remErr != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateChannel)
 		}
+		if ch.Type == model.ChannelTypeThread {
+			if delErr := e.thread.DeleteThread(c.UserContext(), guildId, ch.Id); delErr != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToDeleteThread)
+			}
+			_ = e.tmemb.RemoveThreadMembers(c.UserContext(), ch.Id)
+		}
 	}
 
 	// Clean guild channels cache
@@ -536,6 +555,13 @@ func (e *entity) Delete(c *fiber.Ctx) error {
 		_ = e.invalidateEmojiCache(c.UserContext(), guildId, emoji.Id)
 	}
 
+	// Remove event webhooks, their delivery logs expire on their own
+	if e.ewh != nil {
+		if err := e.ewh.DeleteGuildEventWebhooks(c.UserContext(), guildId); err != nil {
+			slog.Error("unable to remove guild event webhooks", slog.String("error", err.Error()))
+		}
+	}
+
 	// 4) Remove all members
 	if err := e.memb.RemoveMembersByGuild(c.UserContext(), guildId); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildMembers)
@@ -546,6 +572,13 @@ func (e *entity) Delete(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToDeleteGuild)
 	}
 
+	// 6) Remove the guild audit log
+	if e.audit != nil {
+		if err := e.audit.RemoveAuditRecordsByGuildId(c.UserContext(), guildId); err != nil {
+			slog.Error("unable to remove guild audit log", slog.Int64("guild_id", guildId), slog.String("error", err.Error()))
+		}
+	}
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
@@ -601,6 +634,7 @@ func (e *entity) setGuildIconIfProvided(c *fiber.Ctx, guildId int64, iconId *int
 //	@Success	200			{object}	dto.Guild			"Guild"
 //	@failure	400			{string}	string				"Incorrect request body"
 //	@failure	401			{string}	string				"Unauthorized"
+//	@failure	406			{string}	string				"Permissions required"
 //	@failure	500			{string}	string				"Something bad happened"
 //	@Router		/guild/{guild_id} [patch]
 func (e *entity) Update(c *fiber.Ctx) error {
@@ -672,6 +706,11 @@ func (e *entity) SetSystemMessagesChannel(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSetSystemMessagesChannel)
 	}
+	updated := *guild
+	updated.SystemMessages = req.ChannelId
+	if changes := guildAuditChanges(*guild, updated); len(changes) > 0 {
+		e.recordAudit(c.UserContext(), guild.Id, user.Id, model.AuditActionGuildUpdate, guild.Id, changes, nil)
+	}
 	return c.SendStatus(fiber.StatusOK)
 }
 
@@ -685,17 +724,44 @@ func (e *entity) updateGuildWithPermissionCheck(c *fiber.Ctx, guildId, userId in
 	if !hasPermission {
 		return fiber.NewError(fiber.StatusNotAcceptable, ErrPermissionsRequired)
 	}
+	if err := validateScreeningRules(guild, req); err != nil {
+		return err
+	}
+	if req.MFARequired != nil && *req.MFARequired != guild.MFARequired {
+		if err := e.setGuildMFARequired(c.UserContext(), guild, userId, *req.MFARequired); err != nil {
+			return err
+		}
+	}
 
 	// Update guild
 	if err := e.g.UpdateGuild(c.UserContext(), guild.Id, req.Name, req.IconId, req.Public, req.Permissions); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateGuild)
 	}
+	var category *model.GuildCategory
+	if req.Category != nil {
+		cat := model.GuildCategory(*req.Category)
+		category = &cat
+	}
+	if err := e.g.UpdateGuildDiscovery(c.UserContext(), guild.Id, req.Description, category); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateGuild)
+	}
+	var level *model.VerificationLevel
+	if req.VerificationLevel != nil {
+		lvl := model.VerificationLevel(*req.VerificationLevel)
+		level = &lvl
+	}
+	if err := e.g.UpdateGuildScreening(c.UserContext(), guild.Id, level, req.Rules, req.ScreeningEnabled); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateGuild)
+	}
 
 	// Get updated guild for response
 	updatedGuild, err := e.g.GetGuildById(c.UserContext(), guild.Id)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateGuild)
 	}
+	if changes := guildAuditChanges(*guild, updatedGuild); len(changes) > 0 {
+		e.recordAudit(c.UserContext(), guild.Id, userId, model.AuditActionGuildUpdate, guild.Id, changes, nil)
+	}
 
 	// Send update event
 	if err := e.sendGuildUpdateEvent(guildId, &updatedGuild); err != nil {
@@ -705,6 +771,27 @@ func (e *entity) updateGuildWithPermissionCheck(c *fiber.Ctx, guildId, userId in
 	return c.SendStatus(fiber.StatusOK)
 }
 
+// setGuildMFARequired changes the two-factor requirement of the guild.
+// Only the owner can change it, and turning it on needs the owner to have two-factor authentication enabled.
+func (e *entity) setGuildMFARequired(ctx context.Context, guild *model.Guild, userId int64, required bool) error {
+	if userId != guild.OwnerId {
+		return fiber.NewError(fiber.StatusNotAcceptable, ErrOnlyOwnerCanChangeMFA)
+	}
+	if required {
+		enabled, err := e.mfa.IsEnabled(ctx, userId)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetMFA)
+		}
+		if !enabled {
+			return fiber.NewError(fiber.StatusNotAcceptable, ErrOwnerMFARequired)
+		}
+	}
+	if err := e.g.SetGuildMFARequired(ctx, guild.Id, required); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateGuild)
+	}
+	return nil
+}
+
 // sendGuildUpdateEvent sends guild update message to message queue
 func (e *entity) sendGuildUpdateEvent(guildId int64, guild *model.Guild) error {
 	if err := e.mqt.SendGuildUpdate(guildId, &mqmsg.UpdateGuild{
@@ -747,11 +834,11 @@ func (e *entity) CreateCategory(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
 	}
 
-	return e.createChannelWithPermissionCheck(c, guildId, user.Id, req.Name, model.ChannelTypeGuildCategory, nil, req.Private, req.Position)
+	return e.createChannelWithPermissionCheck(c, guildId, user.Id, req.Name, model.ChannelTypeGuildCategory, req.Private, req.Position)
 }
 
 // createChannelWithPermissionCheck validates permissions and creates a channel
-func (e *entity) createChannelWithPermissionCheck(c *fiber.Ctx, guildId, userId int64, name string, channelType model.ChannelType, parentId *int64, isPrivate bool, position int) error {
+func (e *entity) createChannelWithPermissionCheck(c *fiber.Ctx, guildId, userId int64, name string, channelType model.ChannelType, isPrivate bool, position int) error {
 	guild, hasPermission, err := e.perm.GuildPerm(c.UserContext(), guildId, userId, permissions.PermServerManageChannels)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
@@ -763,20 +850,16 @@ func (e *entity) createChannelWithPermissionCheck(c *fiber.Ctx, guildId, userId
 
 	channelId := idgen.Next()
 
-	// Only threads use an explicit parent; category membership is derived from position
-	var effectiveParentId *int64
-	if channelType == model.ChannelTypeThread {
-		effectiveParentId = parentId
-	}
-
-	// Add channel to guild
-	if err := e.gc.AddChannel(c.UserContext(), guild.Id, channelId, name, channelType, effectiveParentId, isPrivate, position); err != nil {
+	// Add channel to guild. Category membership is derived from position
+	if err := e.gc.AddChannel(c.UserContext(), guild.Id, channelId, name, channelType, nil, isPrivate, position); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateChannelGroup)
 	}
+	e.recordAudit(c.UserContext(), guild.Id, userId, model.AuditActionChannelCreate, channelId,
+		channelAuditChanges(model.Channel{}, model.Channel{Name: name, Type: channelType, Private: isPrivate}), nil)
 
 	// Send create channel event and clean cached data
 	go func() {
-		if err := e.sendCreateChannelEvent(guildId, guild.Id, channelId, name, channelType, effectiveParentId); err != nil {
+		if err := e.sendCreateChannelEvent(guildId, guild.Id, channelId, name, channelType); err != nil {
 			slog.Error("unable to send create channel event", slog.String("error", err.Error()))
 		}
 		if err := e.cache.Delete(context.Background(), fmt.Sprintf("guild:%d:channels", guildId)); err != nil {
@@ -788,7 +871,7 @@ func (e *entity) createChannelWithPermissionCheck(c *fiber.Ctx, guildId, userId
 }
 
 // sendCreateChannelEvent sends channel creation message to message queue
-func (e *entity) sendCreateChannelEvent(guildId, guildModelId, channelId int64, name string, channelType model.ChannelType, parentId *int64) error {
+func (e *entity) sendCreateChannelEvent(guildId, guildModelId, channelId int64, name string, channelType model.ChannelType) error {
 	if err := e.mqt.SendGuildUpdate(guildId, &mqmsg.CreateChannel{
 		GuildId: &guildModelId,
 		Channel: dto.Channel{
@@ -796,7 +879,6 @@ func (e *entity) sendCreateChannelEvent(guildId, guildModelId, channelId int64,
 			Type:      channelType,
 			GuildId:   &guildModelId,
 			Name:      name,
-			ParentId:  parentId,
 			Position:  0,
 			Topic:     nil,
 			CreatedAt: time.Now(),
@@ -839,7 +921,7 @@ func (e *entity) CreateChannel(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
 	}
 
-	return e.createChannelWithPermissionCheck(c, guildId, user.Id, req.Name, req.Type, req.ParentId, req.Private, req.Position)
+	return e.createChannelWithPermissionCheck(c, guildId, user.Id, req.Name, req.Type, req.Private, req.Position)
 }
 
 // DeleteChannel
@@ -888,6 +970,7 @@ func (e *entity) deleteChannelWithPermissionCheck(c *fiber.Ctx, guildId, channel
 	if err := e.gc.RemoveChannel(c.UserContext(), guildId, channelId); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
+	e.recordAudit(c.UserContext(), guildId, userId, model.AuditActionChannelDelete, channelId, channelAuditChanges(*channel, model.Channel{}), nil)
 
 	// Delete channel messages if any exist
 	if channel.LastMessage != 0 {
@@ -895,6 +978,20 @@ func (e *entity) deleteChannelWithPermissionCheck(c *fiber.Ctx, guildId, channel
 			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 		}
 	}
+	if e.pin != nil {
+		if err := e.pin.RemoveChannelPins(c.UserContext(), channelId); err != nil {
+			slog.Error("unable to remove pins of deleted channel", slog.String("error", err.Error()))
+		}
+	}
+	if e.wh != nil {
+		if err := e.wh.DeleteChannelWebhooks(c.UserContext(), channelId); err != nil {
+			slog.Error("unable to remove webhooks of deleted channel", slog.String("error", err.Error()))
+		}
+	}
+
+	if err := e.removeChannelThreads(c.UserContext(), guildId, channelId); err != nil {
+		slog.Error("unable to remove threads of deleted channel", slog.String("error", err.Error()))
+	}
 
 	// Send delete channel event and clean cached value
 	go func() {
@@ -955,6 +1052,7 @@ func (e *entity) deleteCategoryWithPermissionCheck(c *fiber.Ctx, guildId, catego
 	if err := e.gc.RemoveChannel(c.UserContext(), guildId, channel.Id); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
+	e.recordAudit(c.UserContext(), guildId, userId, model.AuditActionChannelDelete, channel.Id, channelAuditChanges(*channel, model.Channel{}), nil)
 
 	// Send delete channel event and clean cached data
 	go func() {
@@ -1029,9 +1127,9 @@ func (e *entity) PatchChannelOrder(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
-	allowed := make(map[int64]struct{}, len(guildChannels))
+	allowed := make(map[int64]model.GuildChannel, len(guildChannels))
 	for _, gch := range guildChannels {
-		allowed[gch.ChannelId] = struct{}{}
+		allowed[gch.ChannelId] = gch
 	}
 
 	// Build update list
@@ -1058,6 +1156,11 @@ func (e *entity) PatchChannelOrder(c *fiber.Ctx) error {
 	if err := e.gc.SetGuildChannelPosition(c.UserContext(), updates); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
+	for _, upd := range updates {
+		if changes := auditChange(nil, "position", allowed[upd.ChannelId].Position, upd.Position); len(changes) > 0 {
+			e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionChannelUpdate, upd.ChannelId, changes, nil)
+		}
+	}
 
 	// Notify clients about the new order and clean cached data
 	go func() {
@@ -1084,7 +1187,7 @@ func (e *entity) PatchChannelOrder(c *fiber.Ctx) error {
 //	@Param		channel_id	path		int64						true	"Channel ID"	example(2230469276416868352)
 //	@Param		req			body		PatchGuildChannelRequest	true	"Request body"
 //	@Success	200			{object}	dto.Channel					"Ok"
-//	@failure	400			{string}	string						"Incorrect request body"
+//	@failure	400			{string}	string						"Incorrect request body or voice settings on a non-voice channel"
 //	@failure	404			{string}	string						"Member not found"
 //	@failure	401			{string}	string						"Unauthorized"
 //	@failure	406			{string}	string						"Permissions required"
@@ -1129,10 +1232,26 @@ func (e *entity) PatchChannel(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetChannel)
 	}
 
-	upd, err := e.ch.UpdateChannel(c.UserContext(), guildChannel.ChannelId, nil, req.Private, req.Name, req.Topic)
+	// Previous state is needed for the audit diff and the voice settings check
+	prev, prevErr := e.ch.GetChannel(c.UserContext(), guildChannel.ChannelId)
+	if req.hasVoiceSettings() {
+		if prevErr != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetChannel)
+		}
+		if prev.Type != model.ChannelTypeGuildVoice {
+			return fiber.NewError(fiber.StatusBadRequest, ErrNotAVoiceChannel)
+		}
+	}
+
+	upd, err := e.ch.UpdateChannel(c.UserContext(), guildChannel.ChannelId, nil, req.Private, req.Name, req.Topic, req.UserLimit, req.Bitrate, req.Stage)
 	if err != nil {
 		return fiber.NewError(fiber.StatusNotModified, ErrUnableToUpdateChannel)
 	}
+	if prevErr == nil {
+		if changes := channelAuditChanges(prev, upd); len(changes) > 0 {
+			e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionChannelUpdate, upd.Id, changes, nil)
+		}
+	}
 
 	resp := channelModelToDTO(&upd, &guildId, guildChannel.Position, nil)
 
@@ -1186,37 +1305,58 @@ func (e *entity) GetMembers(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildMembers)
 	}
 
+	data, err := e.membersWithProfiles(c.UserContext(), guildId, members)
+	if err != nil {
+		return err
+	}
+
+	// Invite codes are only visible to members who manage invites
+	if _, ok, err := e.perm.GuildPerm(c.UserContext(), guildId, user.Id, permissions.PermMembershipCreateInvite); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildByID)
+	} else if !ok {
+		for i := range data {
+			data[i].InviteCode = nil
+		}
+	}
+	return c.JSON(data)
+}
+
+// membersWithProfiles adds the users, discriminators, roles and avatars to the members
+func (e *entity) membersWithProfiles(ctx context.Context, guildId int64, members []model.Member) ([]dto.Member, error) {
+	if len(members) == 0 {
+		return []dto.Member{}, nil
+	}
 	var memberIds = make([]int64, len(members))
 	for i, m := range members {
 		memberIds[i] = m.UserId
 	}
 
-	dscs, err := e.disc.GetDiscriminatorsByUserIDs(c.UserContext(), memberIds)
+	dscs, err := e.disc.GetDiscriminatorsByUserIDs(ctx, memberIds)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetDiscriminators)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetDiscriminators)
 	}
 
-	users, err := e.user.GetUsersList(c.UserContext(), memberIds)
+	users, err := e.user.GetUsersList(ctx, memberIds)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetUsers)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetUsers)
 	}
 
-	roles, err := e.ur.GetUsersRolesByGuild(c.UserContext(), guildId, memberIds)
+	roles, err := e.ur.GetUsersRolesByGuild(ctx, guildId, memberIds)
 	if err != nil {
 		slog.Error("unable to get users roles", slog.String("error", err.Error()))
-		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetUsersRoles)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetUsersRoles)
 	}
 
 	// Build avatar data map (cached)
 	avData := make(map[int64]*dto.AvatarData, len(users))
 	for _, u := range users {
 		if u.Avatar != nil {
-			if ad, err := e.getAvatarDataCached(c.UserContext(), u.Id, *u.Avatar); err == nil && ad != nil {
+			if ad, err := e.getAvatarDataCached(ctx, u.Id, *u.Avatar); err == nil && ad != nil {
 				avData[u.Id] = ad
 			}
 		}
 	}
-	return c.JSON(membersToDTO(members, users, roles, dscs, avData))
+	return membersToDTO(members, users, roles, dscs, avData), nil
 }
 
 const avatarCacheTTLSeconds = 3600 // 1 hour
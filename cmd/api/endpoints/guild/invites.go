@@ -53,22 +53,23 @@ func generateInviteCodeFromID(inviteID int64) string {
 
 // ReceiveInvite
 //
-//	@Summary	Get invite info by code
-//	@Produce	json
-//	@Tags		Guild Invites
-//	@Param		invite_code	path		string				true	"Invite code"	example(PWBJ124G)
-//	@Success	200			{object}	dto.InvitePreview	"Invite preview"
-//	@failure	404			{string}	string				"invite not found"
-//	@Router		/guild/invites/receive/{invite_code} [get]
+//	@Summary		Get invite info by code
+//	@Description	The code can be an invite code or the vanity code of a guild.
+//	@Produce		json
+//	@Tags			Guild Invites
+//	@Param			invite_code	path		string				true	"Invite code"	example(PWBJ124G)
+//	@Success		200			{object}	dto.InvitePreview	"Invite preview"
+//	@failure		404			{string}	string				"invite not found"
+//	@Router			/guild/invites/receive/{invite_code} [get]
 func (e *entity) ReceiveInvite(c *fiber.Ctx) error {
 	code := c.Params("invite_code")
-	if len(code) != 8 {
+	if !isInviteOrVanityCode(code) {
 		return fiber.NewError(fiber.StatusBadRequest, ErrInviteCodeInvalid)
 	}
 
-	inv, err := e.inv.FetchInvite(c.UserContext(), code)
+	inv, vanity, err := e.resolveInviteCode(c.UserContext(), code)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return fiber.NewError(fiber.StatusNotFound, ErrInviteNotFound)
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetInvites)
@@ -92,22 +93,26 @@ func (e *entity) ReceiveInvite(c *fiber.Ctx) error {
 		CreatedAt:    inv.CreatedAt,
 		ExpiresAt:    inv.ExpiresAt,
 		MembersCount: int(membersCount),
+		Temporary:    inv.Temporary,
+		Vanity:       vanity,
 	})
 }
 
 // AcceptInvite
 //
-//	@Summary	Accept invite and join guild
-//	@Produce	json
-//	@Tags		Guild Invites
-//	@Param		invite_code	path		string		true	"Invite code"	example(PWBJ124G)
-//	@Success	200			{object}	dto.Guild	"Joined guild"
-//	@failure	404			{string}	string		"invite not found"
-//	@failure	401			{string}	string		"unauthorized"
-//	@Router		/guild/invites/accept/{invite_code} [post]
+//	@Summary		Accept invite and join guild
+//	@Description	The code can be an invite code or the vanity code of a guild.
+//	@Produce		json
+//	@Tags			Guild Invites
+//	@Param			invite_code	path		string		true	"Invite code"	example(PWBJ124G)
+//	@Success		200			{object}	dto.Guild	"Joined guild"
+//	@failure		404			{string}	string		"invite not found or used up"
+//	@failure		403			{string}	string		"user is banned or a bot"
+//	@failure		401			{string}	string		"unauthorized"
+//	@Router			/guild/invites/accept/{invite_code} [post]
 func (e *entity) AcceptInvite(c *fiber.Ctx) error {
 	code := c.Params("invite_code")
-	if len(code) != 8 {
+	if !isInviteOrVanityCode(code) {
 		return fiber.NewError(fiber.StatusBadRequest, ErrInviteCodeInvalid)
 	}
 
@@ -115,8 +120,12 @@ func (e *entity) AcceptInvite(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
 	}
+	// Bots join guilds only when a member adds them
+	if user.Bot {
+		return fiber.NewError(fiber.StatusForbidden, ErrNotAllowedForBots)
+	}
 
-	inv, err := e.inv.FetchInvite(c.UserContext(), code)
+	inv, vanity, err := e.resolveInviteCode(c.UserContext(), code)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fiber.NewError(fiber.StatusNotFound, ErrInviteNotFound)
@@ -124,7 +133,28 @@ func (e *entity) AcceptInvite(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetInvites)
 	}
 
-	if banned, err := e.isGuildUserBanned(c.UserContext(), inv.GuildId, user.Id); err != nil {
+	return e.joinGuild(c, user, inv.GuildId, code, vanity)
+}
+
+// resolveInviteCode returns the invite of the code. Vanity codes resolve to an invite of their guild without ID, author and expiration.
+func (e *entity) resolveInviteCode(ctx context.Context, code string) (model.GuildInvite, bool, error) {
+	if inviteCodeRegex.MatchString(code) {
+		inv, err := e.inv.FetchInvite(ctx, code)
+		if err == nil || !errors.Is(err, sql.ErrNoRows) || !vanityCodeRegex.MatchString(code) {
+			return inv, false, err
+		}
+	}
+	guildId, err := e.g.GetGuildIdByVanityCode(ctx, code)
+	if err != nil {
+		return model.GuildInvite{}, false, err
+	}
+	return model.GuildInvite{InviteCode: code, GuildId: guildId}, true, nil
+}
+
+// joinGuild adds the user to the guild and announces the new member.
+// A non-vanity code is used as an invite and counts one use, an empty code joins a public guild from discovery.
+func (e *entity) joinGuild(c *fiber.Ctx, user *helper.JWTUser, guildId int64, code string, vanity bool) error {
+	if banned, err := e.isGuildUserBanned(c.UserContext(), guildId, user.Id); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCheckGuildBan)
 	} else if banned {
 		return fiber.NewError(fiber.StatusForbidden, ErrUserIsBanned)
@@ -135,37 +165,58 @@ func (e *entity) AcceptInvite(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetUser)
 	}
 
+	g, err := e.g.GetGuildById(c.UserContext(), guildId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildByID)
+	}
+
 	// If already a member, just return guild
-	isMember, err := e.memb.IsGuildMember(c.UserContext(), inv.GuildId, user.Id)
+	isMember, err := e.memb.IsGuildMember(c.UserContext(), guildId, user.Id)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildMember)
 	}
+	// New members wait for rules acceptance while the guild screening is on
+	pending := !isMember && g.ScreeningEnabled
+	var temporary bool
 	if !isMember {
-		if err := e.memb.AddMember(c.UserContext(), user.Id, inv.GuildId); err != nil {
+		switch {
+		case code == "":
+			err = e.memb.AddMember(c.UserContext(), user.Id, guildId, pending)
+		case vanity:
+			err = e.memb.AddInvitedMember(c.UserContext(), user.Id, guildId, code, false, pending)
+		default:
+			// Only joins count as uses, the last use could be taken since the invite was fetched
+			used, uerr := e.inv.UseInvite(c.UserContext(), code)
+			if uerr != nil {
+				if errors.Is(uerr, sql.ErrNoRows) {
+					return fiber.NewError(fiber.StatusNotFound, ErrInviteNotFound)
+				}
+				return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetInvites)
+			}
+			temporary = used.Temporary
+			err = e.memb.AddInvitedMember(c.UserContext(), user.Id, guildId, used.InviteCode, temporary, pending)
+		}
+		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildMember)
 		}
 	}
-
 	disc, err := e.disc.GetDiscriminatorByUserId(c.UserContext(), u.Id)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetDiscriminator)
 	}
 
-	g, err := e.g.GetGuildById(c.UserContext(), inv.GuildId)
-	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildByID)
-	}
-
 	go func() {
-		err := e.mqt.SendGuildUpdate(inv.GuildId, &mqmsg.AddGuildMember{
-			GuildId: inv.GuildId,
+		err := e.mqt.SendGuildUpdate(guildId, &mqmsg.AddGuildMember{
+			GuildId: guildId,
 			UserId:  u.Id,
 			Member: dto.Member{
-				User:     userToDTO(u, disc.Discriminator),
-				Username: nil,
-				Avatar:   nil,
-				JoinAt:   time.Now(),
-				Roles:    nil,
+				User:      userToDTO(u, disc.Discriminator),
+				Username:  nil,
+				Avatar:    nil,
+				JoinAt:    time.Now(),
+				Roles:     nil,
+				Temporary: temporary,
+				Pending:   pending,
 			},
 		})
 		if err != nil {
@@ -173,7 +224,7 @@ func (e *entity) AcceptInvite(c *fiber.Ctx) error {
 		}
 		if g.SystemMessages != nil {
 			msgid := idgen.Next()
-			err := e.msg.CreateSystemMessage(context.Background(), msgid, *g.SystemMessages, user.Id, "", model.MessageTypeJoin)
+			err := e.msg.CreateSystemMessage(context.Background(), msgid, *g.SystemMessages, user.Id, 0, "", model.MessageTypeJoin)
 			if err != nil {
 				e.log.Error("unable to send system user join message", slog.String("error", err.Error()))
 				return
@@ -212,13 +263,14 @@ func (e *entity) AcceptInvite(c *fiber.Ctx) error {
 
 // ListInvites
 //
-//	@Summary	List active invites for guild
-//	@Produce	json
-//	@Tags		Guild Invites
-//	@Param		guild_id	path		int64			true	"Guild id"	example(2230469276416868352)
-//	@Success	200			{array}		dto.GuildInvite	"List of invites"
-//	@failure	401			{string}	string			"Unauthorized"
-//	@Router		/guild/invites/{guild_id} [get]
+//	@Summary		List active invites for guild
+//	@Description	Returns invites that are not expired or used up, with their use counts and inviters.
+//	@Produce		json
+//	@Tags			Guild Invites
+//	@Param			guild_id	path		int64			true	"Guild id"	example(2230469276416868352)
+//	@Success		200			{array}		dto.GuildInvite	"List of invites"
+//	@failure		401			{string}	string			"Unauthorized"
+//	@Router			/guild/invites/{guild_id} [get]
 func (e *entity) ListInvites(c *fiber.Ctx) error {
 	guildId, err := e.parseGuildID(c)
 	if err != nil {
@@ -242,21 +294,63 @@ func (e *entity) ListInvites(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetInvites)
 	}
 
+	authors, err := e.inviteAuthors(c.UserContext(), invs)
+	if err != nil {
+		return err
+	}
+
 	// Map to DTOs
 	out := make([]dto.GuildInvite, 0, len(invs))
 	for _, it := range invs {
-		out = append(out, dto.GuildInvite{
-			Id:        it.InviteId,
-			Code:      it.InviteCode,
-			GuildId:   it.GuildId,
-			AuthorId:  it.AuthorId,
-			CreatedAt: it.CreatedAt,
-			ExpiresAt: it.ExpiresAt,
-		})
+		inv := inviteToDTO(it)
+		if a, ok := authors[it.AuthorId]; ok {
+			inv.Author = &a
+		}
+		out = append(out, inv)
 	}
 	return c.JSON(out)
 }
 
+// inviteAuthors returns the users who created the invites by their IDs
+func (e *entity) inviteAuthors(ctx context.Context, invs []model.GuildInvite) (map[int64]dto.User, error) {
+	authors := make(map[int64]dto.User)
+	if len(invs) == 0 {
+		return authors, nil
+	}
+	ids := make([]int64, 0, len(invs))
+	seen := make(map[int64]struct{}, len(invs))
+	for _, it := range invs {
+		if _, ok := seen[it.AuthorId]; ok {
+			continue
+		}
+		seen[it.AuthorId] = struct{}{}
+		ids = append(ids, it.AuthorId)
+	}
+
+	users, err := e.user.GetUsersList(ctx, ids)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetUsers)
+	}
+	dscs, err := e.disc.GetDiscriminatorsByUserIDs(ctx, ids)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetDiscriminators)
+	}
+	dscMap := make(map[int64]string, len(dscs))
+	for _, d := range dscs {
+		dscMap[d.UserId] = d.Discriminator
+	}
+	for _, u := range users {
+		a := userToDTO(u, dscMap[u.Id])
+		if u.Avatar != nil {
+			if ad, err := e.getAvatarDataCached(ctx, u.Id, *u.Avatar); err == nil && ad != nil {
+				a.Avatar = ad
+			}
+		}
+		authors[u.Id] = a
+	}
+	return authors, nil
+}
+
 // DeleteInvite
 //
 //	@Summary	Delete an invite by id
@@ -294,6 +388,7 @@ func (e *entity) DeleteInvite(c *fiber.Ctx) error {
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToDeleteInvite)
 	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionInviteDelete, inviteId, nil, nil)
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -348,23 +443,42 @@ func (e *entity) CreateInvite(c *fiber.Ctx) error {
 	invId := idgen.Next()
 	code := generateInviteCodeFromID(invId)
 
-	inv, ierr := e.inv.CreateInvite(c.UserContext(), code, invId, guildId, user.Id, expiresAt.Unix())
+	inv, ierr := e.inv.CreateInvite(c.UserContext(), code, invId, guildId, user.Id, expiresAt.Unix(), req.MaxUses, req.Temporary)
 	if ierr != nil {
 		// In the unlikely event of collision, regenerate with a new ID and retry once
 		invId = idgen.Next()
 		code = generateInviteCodeFromID(invId)
-		inv, ierr = e.inv.CreateInvite(c.UserContext(), code, invId, guildId, user.Id, expiresAt.Unix())
+		inv, ierr = e.inv.CreateInvite(c.UserContext(), code, invId, guildId, user.Id, expiresAt.Unix(), req.MaxUses, req.Temporary)
 		if ierr != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateInvite)
 		}
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(dto.GuildInvite{
+	changes := []model.AuditChange{
+		{Key: "code", New: inv.InviteCode},
+		{Key: "expires_at", New: inv.ExpiresAt},
+	}
+	if inv.MaxUses > 0 {
+		changes = append(changes, model.AuditChange{Key: "max_uses", New: inv.MaxUses})
+	}
+	if inv.Temporary {
+		changes = append(changes, model.AuditChange{Key: "temporary", New: true})
+	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionInviteCreate, inv.InviteId, changes, nil)
+
+	return c.Status(fiber.StatusCreated).JSON(inviteToDTO(inv))
+}
+
+func inviteToDTO(inv model.GuildInvite) dto.GuildInvite {
+	return dto.GuildInvite{
 		Id:        inv.InviteId,
 		Code:      inv.InviteCode,
 		GuildId:   inv.GuildId,
 		AuthorId:  inv.AuthorId,
 		CreatedAt: inv.CreatedAt,
 		ExpiresAt: inv.ExpiresAt,
-	})
+		MaxUses:   inv.MaxUses,
+		Uses:      inv.Uses,
+		Temporary: inv.Temporary,
+	}
 }
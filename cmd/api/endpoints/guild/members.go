@@ -3,6 +3,7 @@ package guild
 import (
 	"context"
 	"log/slog"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
@@ -17,6 +18,7 @@ type permissionChecker interface {
 	ChannelPerm(ctx context.Context, guildID, channelID, userID int64, perm ...permissions.RolePermission) (*model.Channel, *model.GuildChannel, *model.Guild, bool, error)
 	GuildPerm(ctx context.Context, guildID, userID int64, perm ...permissions.RolePermission) (*model.Guild, bool, error)
 	GetChannelPermissions(ctx context.Context, guildID, channelID, userID int64) (int64, error)
+	MemberPending(ctx context.Context, guildID, userID int64) (bool, error)
 }
 
 // KickMember
@@ -45,6 +47,7 @@ func (e *entity) KickMember(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRemoveMember)
 	}
 
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionMemberKick, memberId, nil, nil)
 	e.sendGuildMemberRemoved(guildId, memberId, user.Id, mqmsg.GuildMemberModerationKick, nil)
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -91,6 +94,7 @@ func (e *entity) BanMember(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRemoveMember)
 	}
 
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionMemberBanAdd, memberId, nil, req.Reason)
 	e.sendGuildMemberRemoved(guildId, memberId, user.Id, mqmsg.GuildMemberModerationBan, req.Reason)
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -122,10 +126,101 @@ func (e *entity) UnbanMember(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUnbanMember)
 	}
 
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionMemberBanRemove, memberId, nil, nil)
 	e.sendGuildModerationEvent(guildId, memberId, user.Id, mqmsg.GuildMemberModerationUnban, nil)
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// TimeoutMember
+//
+//	@Summary		Timeout guild member
+//	@Description	Temporarily stops a member from sending messages, typing, reacting and speaking in voice. Allowed for guild owner, administrators, or members with PermMembershipTimeoutMembers. Cannot target the guild owner. Members with administrator permission can only be moderated by the guild owner. Applying a timeout to a timed out member replaces it.
+//	@Tags			Guild
+//	@Param			guild_id	path		int64					true	"Guild ID"
+//	@Param			user_id		path		int64					true	"User ID"
+//	@Param			request		body		TimeoutMemberRequest	true	"Timeout duration and reason"
+//	@Success		204			{string}	string					"No Content"
+//	@failure		400			{string}	string					"Bad request"
+//	@failure		404			{string}	string					"Member not found"
+//	@failure		406			{string}	string					"Permissions required"
+//	@Router			/guild/{guild_id}/member/{user_id}/timeout [post]
+func (e *entity) TimeoutMember(c *fiber.Ctx) error {
+	guildId, memberId, user, err := e.parseMemberModerationRequest(c)
+	if err != nil {
+		return err
+	}
+
+	var req TimeoutMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if _, err := e.authorizeMemberModeration(c.UserContext(), guildId, user.Id, memberId, permissions.PermMembershipTimeoutMembers, true); err != nil {
+		return err
+	}
+	member, err := e.memb.GetMember(c.UserContext(), memberId, guildId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildMemberToken)
+	}
+
+	until := time.Now().Add(time.Duration(req.Duration) * time.Second).UTC().Truncate(time.Second)
+	if err := e.memb.SetTimeout(c.UserContext(), memberId, guildId, &until); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToTimeoutMember)
+	}
+
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionMemberUpdate, memberId,
+		auditChange(nil, "timeout", memberTimeout(member, time.Now()), &until), req.Reason)
+	e.sendGuildTimeoutEvent(guildId, memberId, user.Id, &until, req.Reason)
+	e.applyVoiceTimeout(guildId, memberId, until)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RemoveMemberTimeout
+//
+//	@Summary		Remove guild member timeout
+//	@Description	Ends an active member timeout. Allowed for guild owner, administrators, or members with PermMembershipTimeoutMembers. Members with administrator permission can only be moderated by the guild owner.
+//	@Tags			Guild
+//	@Param			guild_id	path		int64	true	"Guild ID"
+//	@Param			user_id		path		int64	true	"User ID"
+//	@Success		204			{string}	string	"No Content"
+//	@failure		400			{string}	string	"Bad request"
+//	@failure		404			{string}	string	"Member not found"
+//	@failure		406			{string}	string	"Permissions required"
+//	@Router			/guild/{guild_id}/member/{user_id}/timeout [delete]
+func (e *entity) RemoveMemberTimeout(c *fiber.Ctx) error {
+	guildId, memberId, user, err := e.parseMemberModerationRequest(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := e.authorizeMemberModeration(c.UserContext(), guildId, user.Id, memberId, permissions.PermMembershipTimeoutMembers, true); err != nil {
+		return err
+	}
+	member, err := e.memb.GetMember(c.UserContext(), memberId, guildId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildMemberToken)
+	}
+	now := time.Now()
+	previous := memberTimeout(member, now)
+	if previous == nil {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	// The timeout column is not nullable, a timeout that ends now is no longer active
+	if err := e.memb.SetTimeout(c.UserContext(), memberId, guildId, &now); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRemoveMemberTimeout)
+	}
+
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionMemberUpdate, memberId,
+		auditChange(nil, "timeout", previous, nil), nil)
+	e.sendGuildTimeoutEvent(guildId, memberId, user.Id, nil, nil)
+	e.applyVoiceTimeout(guildId, memberId, time.Time{})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 // GetBans
 //
 //	@Summary		Get guild bans
@@ -224,7 +319,7 @@ func (e *entity) parseMemberModerationRequest(c *fiber.Ctx) (int64, int64, *help
 	return guildId, memberId, user, nil
 }
 
-func (e *entity) authorizeGuildPermission(ctx context.Context, guildId, actorId int64, required permissions.RolePermission) (*model.Guild, error) {
+func (e *entity) authorizeGuildPermission(ctx context.Context, guildId, actorId int64, required ...permissions.RolePermission) (*model.Guild, error) {
 	guild, err := e.g.GetGuildById(ctx, guildId)
 	if err != nil {
 		return nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildByID)
@@ -240,7 +335,7 @@ func (e *entity) authorizeGuildPermission(ctx context.Context, guildId, actorId
 		}
 	}
 
-	_, allowed, err := e.perm.GuildPerm(ctx, guildId, actorId, required)
+	_, allowed, err := e.perm.GuildPerm(ctx, guildId, actorId, required...)
 	if err != nil {
 		return nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetPermission)
 	}
@@ -326,6 +421,72 @@ func (e *entity) sendGuildModerationEvent(guildId, memberId, actorId int64, acti
 	}()
 }
 
+func (e *entity) sendGuildTimeoutEvent(guildId, memberId, actorId int64, until *time.Time, reason *string) {
+	if e.mqt == nil {
+		return
+	}
+	logger := e.log
+	if logger == nil {
+		logger = slog.Default()
+	}
+	go func() {
+		evt := &mqmsg.GuildMemberModeration{GuildId: guildId, UserId: memberId, ActorId: actorId, Action: mqmsg.GuildMemberModerationTimeout, Reason: reason, Until: until}
+		if err := e.mqt.SendGuildUpdate(guildId, evt); err != nil {
+			logger.Error("unable to send guild member moderation event",
+				slog.String("action", string(mqmsg.GuildMemberModerationTimeout)),
+				slog.Int64("guild_id", guildId),
+				slog.Int64("user_id", memberId),
+				slog.String("error", err.Error()))
+		}
+	}()
+}
+
+// applyVoiceTimeout tells the SFU serving each guild voice channel the member is connected to
+// to server-mute them until the timeout ends. Zero until lifts the mute.
+func (e *entity) applyVoiceTimeout(guildId, memberId int64, until time.Time) {
+	if e.cache == nil || e.gc == nil {
+		return
+	}
+	logger := e.log
+	if logger == nil {
+		logger = slog.Default()
+	}
+	go func() {
+		ctx := context.Background()
+		channels, err := e.gc.GetGuildChannels(ctx, guildId)
+		if err != nil {
+			logger.Error("unable to get guild channels for voice timeout",
+				slog.Int64("guild_id", guildId),
+				slog.String("error", err.Error()))
+			return
+		}
+		userField := fmtInt64(memberId)
+		for _, gch := range channels {
+			if v, err := e.cache.HGet(ctx, sessionHashKey(gch.ChannelId), userField); err != nil || v == "" {
+				continue
+			}
+			var route voiceRouteBinding
+			if err := e.cache.GetJSON(ctx, bindingKey(gch.ChannelId), &route); err != nil || route.URL == "" {
+				continue
+			}
+			notifySFUTimeout(route.URL, gch.ChannelId, memberId, until, e.authSecret, logger)
+		}
+	}()
+}
+
+// checkMemberPending returns 403 if the user hasn't passed the guild screening or verification level.
+// Called after a failed permission check to tell pending members why.
+func (e *entity) checkMemberPending(ctx context.Context, guildId, userId int64) error {
+	pending, err := e.perm.MemberPending(ctx, guildId, userId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildMemberToken)
+	}
+	if pending {
+		return fiber.NewError(fiber.StatusForbidden, ErrMemberPending)
+	}
+	return nil
+}
+
 func (e *entity) isGuildUserBanned(ctx context.Context, guildId, userId int64) (bool, error) {
 	if e.ban == nil {
 		return false, nil
@@ -52,6 +52,10 @@ func (f *fakePermissionChecker) GetChannelPermissions(ctx context.Context, guild
 	return 0, nil
 }
 
+func (f *fakePermissionChecker) MemberPending(ctx context.Context, guildID, userID int64) (bool, error) {
+	return false, nil
+}
+
 type testMemberKey struct {
 	guildID int64
 	userID  int64
@@ -59,17 +63,35 @@ type testMemberKey struct {
 
 type fakeMemberRepo struct {
 	members     map[testMemberKey]bool
+	timeouts    map[testMemberKey]time.Time
+	invites     map[testMemberKey]string
+	pending     map[testMemberKey]bool
 	removeCalls []testMemberKey
 	addCalls    []testMemberKey
 }
 
-func (f *fakeMemberRepo) AddMember(ctx context.Context, userID, guildID int64) error {
+func (f *fakeMemberRepo) AddMember(ctx context.Context, userID, guildID int64, pending bool) error {
 	key := testMemberKey{guildID: guildID, userID: userID}
 	f.addCalls = append(f.addCalls, key)
 	f.members[key] = true
+	if pending {
+		if f.pending == nil {
+			f.pending = make(map[testMemberKey]bool)
+		}
+		f.pending[key] = true
+	}
 	return nil
 }
 
+func (f *fakeMemberRepo) AddInvitedMember(ctx context.Context, userID, guildID int64, inviteCode string, temporary, pending bool) error {
+	key := testMemberKey{guildID: guildID, userID: userID}
+	if f.invites == nil {
+		f.invites = make(map[testMemberKey]string)
+	}
+	f.invites[key] = inviteCode
+	return f.AddMember(ctx, userID, guildID, pending)
+}
+
 func (f *fakeMemberRepo) RemoveMember(ctx context.Context, userID, guildID int64) error {
 	key := testMemberKey{guildID: guildID, userID: userID}
 	f.removeCalls = append(f.removeCalls, key)
@@ -83,7 +105,13 @@ func (f *fakeMemberRepo) GetMember(ctx context.Context, userId, guildId int64) (
 	if !f.members[testMemberKey{guildID: guildId, userID: userId}] {
 		return model.Member{}, sql.ErrNoRows
 	}
-	return model.Member{UserId: userId, GuildId: guildId}, nil
+	key := testMemberKey{guildID: guildId, userID: userId}
+	return model.Member{UserId: userId, GuildId: guildId, Timeout: f.timeouts[key], Pending: f.pending[key]}, nil
+}
+
+func (f *fakeMemberRepo) GetMemberTimeout(ctx context.Context, userId, guildId int64) (time.Time, error) {
+	m, err := f.GetMember(ctx, userId, guildId)
+	return m.Timeout, err
 }
 
 func (f *fakeMemberRepo) GetMembersList(ctx context.Context, guildId int64, ids []int64) ([]model.Member, error) {
@@ -108,7 +136,15 @@ func (f *fakeMemberRepo) GetUserGuilds(ctx context.Context, userId int64) ([]mod
 	return nil, nil
 }
 
+func (f *fakeMemberRepo) GetTemporaryGuilds(ctx context.Context, userId int64) ([]model.UserGuild, error) {
+	return nil, nil
+}
+
 func (f *fakeMemberRepo) SetTimeout(ctx context.Context, userId, guildId int64, timeout *time.Time) error {
+	if f.timeouts == nil {
+		f.timeouts = make(map[testMemberKey]time.Time)
+	}
+	f.timeouts[testMemberKey{guildID: guildId, userID: userId}] = *timeout
 	return nil
 }
 
@@ -116,8 +152,25 @@ func (f *fakeMemberRepo) CountGuildMembers(ctx context.Context, guildId int64) (
 	return 0, nil
 }
 
+func (f *fakeMemberRepo) SampleGuildMemberIds(ctx context.Context, guildId int64, limit uint64) ([]int64, error) {
+	return nil, nil
+}
+
+func (f *fakeMemberRepo) GetPendingMembers(ctx context.Context, guildId int64) ([]model.Member, error) {
+	return nil, nil
+}
+
+func (f *fakeMemberRepo) SetPending(ctx context.Context, userId, guildId int64, pending bool) error {
+	if f.pending == nil {
+		f.pending = make(map[testMemberKey]bool)
+	}
+	f.pending[testMemberKey{guildID: guildId, userID: userId}] = pending
+	return nil
+}
+
 type fakeGuildRepo struct {
-	guild model.Guild
+	guild  model.Guild
+	vanity map[string]int64
 }
 
 func (f *fakeGuildRepo) GetGuildById(ctx context.Context, id int64) (model.Guild, error) {
@@ -142,6 +195,29 @@ func (f *fakeGuildRepo) UpdateGuild(ctx context.Context, id int64, name *string,
 func (f *fakeGuildRepo) SetSystemMessagesChannel(ctx context.Context, id int64, channelId *int64) error {
 	return nil
 }
+func (f *fakeGuildRepo) SetGuildMFARequired(ctx context.Context, id int64, required bool) error {
+	return nil
+}
+func (f *fakeGuildRepo) UpdateGuildDiscovery(ctx context.Context, id int64, description *string, category *model.GuildCategory) error {
+	return nil
+}
+func (f *fakeGuildRepo) UpdateGuildScreening(ctx context.Context, id int64, level *model.VerificationLevel, rules *string, enabled *bool) error {
+	return nil
+}
+func (f *fakeGuildRepo) SetVanityCode(ctx context.Context, id int64, code *string) error {
+	f.guild.VanityCode = code
+	return nil
+}
+func (f *fakeGuildRepo) GetGuildIdByVanityCode(ctx context.Context, code string) (int64, error) {
+	id, ok := f.vanity[code]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return id, nil
+}
+func (f *fakeGuildRepo) GetDiscoverableGuilds(ctx context.Context, query string, category *model.GuildCategory, limit, offset uint64) ([]model.DiscoverableGuild, error) {
+	return nil, nil
+}
 
 type fakeBanRepo struct {
 	bans       map[testMemberKey]*string
@@ -231,6 +307,7 @@ func (f *fakeUserRepo) GetUsersList(ctx context.Context, ids []int64) ([]model.U
 	return out, nil
 }
 func (f *fakeUserRepo) CreateUser(ctx context.Context, id int64, name string) error      { return nil }
+func (f *fakeUserRepo) CreateBotUser(ctx context.Context, id int64, name string) error   { return nil }
 func (f *fakeUserRepo) SetUserAvatar(ctx context.Context, id, attachmentId int64) error  { return nil }
 func (f *fakeUserRepo) SetUsername(ctx context.Context, id, name string) error           { return nil }
 func (f *fakeUserRepo) SetUserBlocked(ctx context.Context, id int64, blocked bool) error { return nil }
@@ -263,7 +340,7 @@ type fakeInviteRepo struct {
 	invite model.GuildInvite
 }
 
-func (f *fakeInviteRepo) CreateInvite(ctx context.Context, code string, inviteID, guildID, authorID int64, expiresAt int64) (model.GuildInvite, error) {
+func (f *fakeInviteRepo) CreateInvite(ctx context.Context, code string, inviteID, guildID, authorID int64, expiresAt int64, maxUses int, temporary bool) (model.GuildInvite, error) {
 	return model.GuildInvite{}, nil
 }
 func (f *fakeInviteRepo) GetGuildInvites(ctx context.Context, guildID int64) ([]model.GuildInvite, error) {
@@ -278,6 +355,13 @@ func (f *fakeInviteRepo) DeleteInviteByID(ctx context.Context, guildID, inviteID
 func (f *fakeInviteRepo) FetchInvite(ctx context.Context, code string) (model.GuildInvite, error) {
 	return f.invite, nil
 }
+func (f *fakeInviteRepo) UseInvite(ctx context.Context, code string) (model.GuildInvite, error) {
+	if f.invite.MaxUses > 0 && f.invite.Uses >= f.invite.MaxUses {
+		return model.GuildInvite{}, sql.ErrNoRows
+	}
+	f.invite.Uses++
+	return f.invite, nil
+}
 
 func newGuildTestApp(t *testing.T, userID int64, path string, handler fiber.Handler) *fiber.App {
 	t.Helper()
@@ -513,3 +597,205 @@ func TestAcceptInviteRejectsBannedUser(t *testing.T) {
 		t.Fatalf("expected no member additions, got %#v", members.addCalls)
 	}
 }
+
+func TestAcceptInviteCountsUseAndRecordsInvite(t *testing.T) {
+	members := &fakeMemberRepo{members: map[testMemberKey]bool{}}
+	invites := &fakeInviteRepo{invite: model.GuildInvite{InviteCode: "ABCDEFGH", GuildId: 1, MaxUses: 1, Temporary: true}}
+	e := &entity{
+		ban:  &fakeBanRepo{},
+		memb: members,
+		inv:  invites,
+		user: &fakeUserRepo{users: map[int64]model.User{10: {Id: 10, Name: "joiner"}, 11: {Id: 11, Name: "late"}}},
+		disc: &fakeDiscriminatorRepo{discriminators: map[int64]string{10: "joiner", 11: "late"}},
+		g:    &fakeGuildRepo{guild: model.Guild{Id: 1}},
+		mqt:  &fakeTransport{},
+	}
+
+	app := newGuildTestApp(t, 10, "/guild/invites/accept/:invite_code", e.AcceptInvite)
+	resp, err := app.Test(httptest.NewRequest("POST", "/guild/invites/accept/ABCDEFGH", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if invites.invite.Uses != 1 {
+		t.Fatalf("expected invite to be used once, got %d", invites.invite.Uses)
+	}
+	if code := members.invites[testMemberKey{guildID: 1, userID: 10}]; code != "ABCDEFGH" {
+		t.Fatalf("expected member to be recorded with invite, got %q", code)
+	}
+
+	// The only use is taken
+	app = newGuildTestApp(t, 11, "/guild/invites/accept/:invite_code", e.AcceptInvite)
+	resp, err = app.Test(httptest.NewRequest("POST", "/guild/invites/accept/ABCDEFGH", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+	if members.members[testMemberKey{guildID: 1, userID: 11}] {
+		t.Fatal("expected user not to join with a used up invite")
+	}
+}
+
+func TestCreateInviteRequestValidateMaxUses(t *testing.T) {
+	if err := (CreateInviteRequest{MaxUses: 100}).Validate(); err != nil {
+		t.Fatalf("expected max_uses 100 to be valid, got %v", err)
+	}
+	for _, n := range []int{-1, 101} {
+		if err := (CreateInviteRequest{MaxUses: n}).Validate(); err == nil {
+			t.Fatalf("expected max_uses %d to be invalid", n)
+		}
+	}
+}
+
+func TestTimeoutMemberSetsTimeoutAndSendsEvent(t *testing.T) {
+	transport := &fakeTransport{removed: make(chan *mqmsg.RemoveGuildMember, 1), moderation: make(chan *mqmsg.GuildMemberModeration, 1)}
+	members := &fakeMemberRepo{members: map[testMemberKey]bool{{guildID: 1, userID: 10}: true, {guildID: 1, userID: 11}: true}}
+	perms := &fakePermissionChecker{results: map[testPermKey]bool{
+		{guildID: 1, userID: 10, perm: permissions.PermMembershipTimeoutMembers}: true,
+		{guildID: 1, userID: 11, perm: permissions.PermAdministrator}:            false,
+	}}
+	e := &entity{g: &fakeGuildRepo{guild: model.Guild{Id: 1, OwnerId: 99}}, memb: members, perm: perms, mqt: transport}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/member/:user_id/timeout", e.TimeoutMember)
+
+	before := time.Now()
+	req := httptest.NewRequest("POST", "/guild/1/member/11/timeout", strings.NewReader(`{"duration":600,"reason":"flooding"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+
+	until := members.timeouts[testMemberKey{guildID: 1, userID: 11}]
+	if until.Before(before.Add(599*time.Second)) || until.After(time.Now().Add(601*time.Second)) {
+		t.Fatalf("unexpected timeout end: %v", until)
+	}
+
+	select {
+	case evt := <-transport.moderation:
+		if evt.UserId != 11 || evt.ActorId != 10 || evt.Action != mqmsg.GuildMemberModerationTimeout || evt.Until == nil || !evt.Until.Equal(until) {
+			t.Fatalf("unexpected moderation event: %#v", evt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected guild moderation event")
+	}
+}
+
+func TestTimeoutMemberRequiresPermission(t *testing.T) {
+	members := &fakeMemberRepo{members: map[testMemberKey]bool{{guildID: 1, userID: 10}: true, {guildID: 1, userID: 11}: true}}
+	perms := &fakePermissionChecker{results: map[testPermKey]bool{}}
+	e := &entity{g: &fakeGuildRepo{guild: model.Guild{Id: 1, OwnerId: 99}}, memb: members, perm: perms}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/member/:user_id/timeout", e.TimeoutMember)
+
+	req := httptest.NewRequest("POST", "/guild/1/member/11/timeout", strings.NewReader(`{"duration":600}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNotAcceptable {
+		t.Fatalf("expected 406, got %d", resp.StatusCode)
+	}
+	if len(members.timeouts) != 0 {
+		t.Fatalf("expected no timeouts, got %#v", members.timeouts)
+	}
+}
+
+func TestRemoveMemberTimeoutEndsActiveTimeout(t *testing.T) {
+	transport := &fakeTransport{removed: make(chan *mqmsg.RemoveGuildMember, 1), moderation: make(chan *mqmsg.GuildMemberModeration, 1)}
+	members := &fakeMemberRepo{
+		members:  map[testMemberKey]bool{{guildID: 1, userID: 10}: true, {guildID: 1, userID: 11}: true},
+		timeouts: map[testMemberKey]time.Time{{guildID: 1, userID: 11}: time.Now().Add(time.Hour)},
+	}
+	perms := &fakePermissionChecker{results: map[testPermKey]bool{{guildID: 1, userID: 10, perm: permissions.PermMembershipTimeoutMembers}: true}}
+	e := &entity{g: &fakeGuildRepo{guild: model.Guild{Id: 1, OwnerId: 99}}, memb: members, perm: perms, mqt: transport}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/member/:user_id/timeout", e.RemoveMemberTimeout)
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/guild/1/member/11/timeout", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	if err := helper.CheckMemberTimeout(context.Background(), e.memb, 1, 11); err != nil {
+		t.Fatalf("expected timeout to be lifted, got %v", err)
+	}
+
+	select {
+	case evt := <-transport.moderation:
+		if evt.Action != mqmsg.GuildMemberModerationTimeout || evt.Until != nil {
+			t.Fatalf("unexpected moderation event: %#v", evt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected guild moderation event")
+	}
+}
+
+func TestTimeoutMemberRequestValidate(t *testing.T) {
+	for _, duration := range []int{0, -1, MaxMemberTimeoutSeconds + 1} {
+		if err := (TimeoutMemberRequest{Duration: duration}).Validate(); err == nil {
+			t.Fatalf("expected error for duration %d", duration)
+		}
+	}
+	if err := (TimeoutMemberRequest{Duration: MaxMemberTimeoutSeconds}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type fakeMFARepo struct {
+	enabled map[int64]bool
+}
+
+func (f *fakeMFARepo) GetMFA(ctx context.Context, userId int64) (model.UserMFA, error) {
+	return model.UserMFA{}, sql.ErrNoRows
+}
+func (f *fakeMFARepo) IsEnabled(ctx context.Context, userId int64) (bool, error) {
+	return f.enabled[userId], nil
+}
+func (f *fakeMFARepo) SetPendingSecret(ctx context.Context, userId int64, secret string) (bool, error) {
+	return false, nil
+}
+func (f *fakeMFARepo) EnableMFA(ctx context.Context, userId, step int64, codeHashes []string) (bool, error) {
+	return false, nil
+}
+func (f *fakeMFARepo) UseStep(ctx context.Context, userId, step int64) (bool, error) {
+	return false, nil
+}
+func (f *fakeMFARepo) DisableMFA(ctx context.Context, userId int64) error { return nil }
+func (f *fakeMFARepo) ReplaceRecoveryCodes(ctx context.Context, userId int64, codeHashes []string) error {
+	return nil
+}
+func (f *fakeMFARepo) UseRecoveryCode(ctx context.Context, userId int64, codeHash string) (bool, error) {
+	return false, nil
+}
+func (f *fakeMFARepo) CountRecoveryCodes(ctx context.Context, userId int64) (int, error) {
+	return 0, nil
+}
+
+func TestSetGuildMFARequiredNeedsOwnerWithMFA(t *testing.T) {
+	guild := &model.Guild{Id: 1, OwnerId: 10}
+	mfa := &fakeMFARepo{enabled: map[int64]bool{11: true}}
+	e := &entity{g: &fakeGuildRepo{guild: *guild}, mfa: mfa}
+
+	if err := e.setGuildMFARequired(context.Background(), guild, 11, true); err == nil {
+		t.Fatal("expected non-owner to be rejected")
+	}
+	if err := e.setGuildMFARequired(context.Background(), guild, 10, true); err == nil {
+		t.Fatal("expected owner without two-factor authentication to be rejected")
+	}
+	if err := e.setGuildMFARequired(context.Background(), guild, 10, false); err != nil {
+		t.Fatalf("expected owner to turn the requirement off, got %v", err)
+	}
+
+	mfa.enabled[10] = true
+	if err := e.setGuildMFARequired(context.Background(), guild, 10, true); err != nil {
+		t.Fatalf("expected owner with two-factor authentication to require it, got %v", err)
+	}
+}
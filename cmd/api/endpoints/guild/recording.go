@@ -0,0 +1,190 @@
+package guild
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+// StartVoiceRecording
+//
+//	@Summary		Start voice channel recording
+//	@Description	Records the microphones of the voice channel participants. When the recording stops it is posted as an attachment to the text channel, the guild system messages channel is used by default.
+//	@Tags			Guild
+//	@Accept			json
+//	@Param			guild_id	path		int64						true	"Guild ID"		example(2230469276416868352)
+//	@Param			channel_id	path		int64						true	"Channel ID"	example(2230469276416868352)
+//	@Param			request		body		StartVoiceRecordingRequest	false	"Recording options"
+//	@Success		204			{string}	string						"Recording started"
+//	@failure		400			{string}	string						"Not a voice channel or no text channel to post to"
+//	@failure		406			{string}	string						"Permissions required"
+//	@failure		409			{string}	string						"No active voice session or channel is already recorded"
+//	@failure		503			{string}	string						"Recording is not available"
+//	@Router			/guild/{guild_id}/voice/{channel_id}/recording [post]
+func (e *entity) StartVoiceRecording(c *fiber.Ctx) error {
+	var req StartVoiceRecordingRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+		}
+	}
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+	channelId, err := e.parseChannelID(c)
+	if err != nil {
+		return err
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	guild, err := e.checkVoiceRecordingPerm(c.UserContext(), guildId, channelId, user.Id)
+	if err != nil {
+		return err
+	}
+
+	textChannelId := guild.SystemMessages
+	if req.ChannelId != nil {
+		textChannelId = req.ChannelId
+	}
+	if textChannelId == nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrRecordingTextChannelRequired)
+	}
+	textCh, _, _, ok, err := e.perm.ChannelPerm(c.UserContext(), guildId, *textChannelId, user.Id, permissions.PermTextSendMessage, permissions.PermTextAttachFiles)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, ErrUnableToGetChannel)
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetPermission)
+	}
+	if !ok {
+		return fiber.NewError(fiber.StatusNotAcceptable, ErrPermissionsRequired)
+	}
+	if textCh.Type != model.ChannelTypeGuild {
+		return fiber.NewError(fiber.StatusBadRequest, ErrRecordingTextChannelInvalid)
+	}
+
+	route, err := e.voiceRoute(c.UserContext(), channelId)
+	if err != nil {
+		return err
+	}
+	err = sendSFUAdminRequest(route.URL, "/admin/channel/recording", channelId, voiceRecordingRequest{
+		ChannelID:     channelId,
+		GuildID:       &guildId,
+		UserID:        user.Id,
+		TextChannelID: *textChannelId,
+		AttachmentID:  idgen.Next(),
+		Recording:     true,
+	}, e.authSecret)
+	if err != nil {
+		return e.voiceRecordingError(err, channelId, ErrRecordingAlreadyActive)
+	}
+
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionVoiceRecordingStart, channelId, auditChange(nil, "channel_id", nil, *textChannelId), nil)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// StopVoiceRecording
+//
+//	@Summary		Stop voice channel recording
+//	@Description	Stops the recording, the SFU uploads it and posts it to the text channel chosen on start.
+//	@Tags			Guild
+//	@Param			guild_id	path		int64	true	"Guild ID"		example(2230469276416868352)
+//	@Param			channel_id	path		int64	true	"Channel ID"	example(2230469276416868352)
+//	@Success		204			{string}	string	"Recording stopped"
+//	@failure		400			{string}	string	"Not a voice channel"
+//	@failure		406			{string}	string	"Permissions required"
+//	@failure		409			{string}	string	"No active voice session or channel is not recorded"
+//	@Router			/guild/{guild_id}/voice/{channel_id}/recording [delete]
+func (e *entity) StopVoiceRecording(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+	channelId, err := e.parseChannelID(c)
+	if err != nil {
+		return err
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	if _, err := e.checkVoiceRecordingPerm(c.UserContext(), guildId, channelId, user.Id); err != nil {
+		return err
+	}
+
+	route, err := e.voiceRoute(c.UserContext(), channelId)
+	if err != nil {
+		return err
+	}
+	err = sendSFUAdminRequest(route.URL, "/admin/channel/recording", channelId, voiceRecordingRequest{
+		ChannelID: channelId,
+		GuildID:   &guildId,
+		UserID:    user.Id,
+	}, e.authSecret)
+	if err != nil {
+		return e.voiceRecordingError(err, channelId, ErrRecordingNotActive)
+	}
+
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionVoiceRecordingStop, channelId, nil, nil)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// checkVoiceRecordingPerm checks that the channel is a guild voice channel and the user can record it
+func (e *entity) checkVoiceRecordingPerm(ctx context.Context, guildId, channelId, userId int64) (*model.Guild, error) {
+	ch, _, guild, ok, err := e.perm.ChannelPerm(ctx, guildId, channelId, userId, permissions.PermVoiceConnect, permissions.PermVoiceRecord)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fiber.NewError(fiber.StatusNotFound, ErrUnableToGetChannel)
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetPermission)
+	}
+	if !ok {
+		return nil, fiber.NewError(fiber.StatusNotAcceptable, ErrPermissionsRequired)
+	}
+	if ch.Type != model.ChannelTypeGuildVoice {
+		return nil, fiber.NewError(fiber.StatusBadRequest, ErrNotAVoiceChannel)
+	}
+	return guild, nil
+}
+
+// voiceRoute returns the SFU the channel session runs on
+func (e *entity) voiceRoute(ctx context.Context, channelId int64) (*voiceRouteBinding, error) {
+	if e.cache == nil {
+		return nil, fiber.NewError(fiber.StatusConflict, ErrNoVoiceSession)
+	}
+	var route voiceRouteBinding
+	if err := e.cache.GetJSON(ctx, bindingKey(channelId), &route); err != nil || route.URL == "" {
+		return nil, fiber.NewError(fiber.StatusConflict, ErrNoVoiceSession)
+	}
+	return &route, nil
+}
+
+// voiceRecordingError maps the SFU answer to the API error, conflict is the message for a 409 from the SFU
+func (e *entity) voiceRecordingError(err error, channelId int64, conflict string) error {
+	var adminErr *sfuAdminError
+	if errors.As(err, &adminErr) {
+		switch adminErr.Status {
+		case fiber.StatusNotFound:
+			return fiber.NewError(fiber.StatusConflict, ErrNoVoiceSession)
+		case fiber.StatusConflict:
+			return fiber.NewError(fiber.StatusConflict, conflict)
+		case fiber.StatusServiceUnavailable:
+			return fiber.NewError(fiber.StatusServiceUnavailable, ErrRecordingUnavailable)
+		}
+	}
+	e.log.Error("voice recording: admin request failed", slog.String("error", err.Error()), slog.Int64("channel_id", channelId))
+	return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToChangeRecordingState)
+}
@@ -0,0 +1,25 @@
+package guild
+
+const (
+	ErrNoVoiceSession               = "no active voice session in channel"
+	ErrRecordingTextChannelRequired = "recording requires a text channel or a guild system messages channel"
+	ErrRecordingTextChannelInvalid  = "recording channel must be a guild text channel"
+	ErrRecordingAlreadyActive       = "channel is already being recorded"
+	ErrRecordingNotActive           = "channel is not being recorded"
+	ErrRecordingUnavailable         = "recording is not available on this voice server"
+	ErrUnableToChangeRecordingState = "unable to change recording state"
+)
+
+type StartVoiceRecordingRequest struct {
+	ChannelId *int64 `json:"channel_id" example:"2230469276416868352"` // Text channel to post the recording to, defaults to the guild system messages channel
+}
+
+// voiceRecordingRequest is the body of the SFU admin recording endpoint
+type voiceRecordingRequest struct {
+	ChannelID     int64  `json:"channel_id"`
+	GuildID       *int64 `json:"guild_id"`
+	UserID        int64  `json:"user_id"`
+	TextChannelID int64  `json:"text_channel_id,omitempty"`
+	AttachmentID  int64  `json:"attachment_id,omitempty"`
+	Recording     bool   `json:"recording"`
+}
@@ -0,0 +1,80 @@
+package guild
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+type fakeRecordingPermissionChecker struct {
+	fakePermissionChecker
+	channels map[int64]model.ChannelType
+	system   *int64
+}
+
+func (f *fakeRecordingPermissionChecker) ChannelPerm(ctx context.Context, guildID, channelID, userID int64, perm ...permissions.RolePermission) (*model.Channel, *model.GuildChannel, *model.Guild, bool, error) {
+	return &model.Channel{Id: channelID, Type: f.channels[channelID]}, nil, &model.Guild{Id: guildID, SystemMessages: f.system}, true, nil
+}
+
+func TestStartVoiceRecordingSendsAdminRequest(t *testing.T) {
+	requests := make(chan voiceRecordingRequest, 1)
+	sfu := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req voiceRecordingRequest
+		if r.URL.Path != "/admin/channel/recording" || json.NewDecoder(r.Body).Decode(&req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests <- req
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer sfu.Close()
+
+	cache := &fakeCache{jsonValues: map[string][]byte{}}
+	if err := cache.SetJSON(context.Background(), bindingKey(2), voiceRouteBinding{ID: "sfu-1", URL: "ws" + sfu.URL[len("http"):] + "/signal"}); err != nil {
+		t.Fatal(err)
+	}
+	system := int64(3)
+	audits := &fakeAuditRepo{}
+	e := &entity{
+		perm:       &fakeRecordingPermissionChecker{channels: map[int64]model.ChannelType{2: model.ChannelTypeGuildVoice, 3: model.ChannelTypeGuild}, system: &system},
+		cache:      cache,
+		audit:      audits,
+		authSecret: "secret",
+	}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/voice/:channel_id/recording", e.StartVoiceRecording)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/guild/1/voice/2/recording", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", resp.StatusCode)
+	}
+	req := <-requests
+	if !req.Recording || req.ChannelID != 2 || req.UserID != 10 || req.TextChannelID != 3 || req.AttachmentID == 0 || req.GuildID == nil || *req.GuildID != 1 {
+		t.Fatalf("unexpected admin request %+v", req)
+	}
+	if len(audits.records) != 1 || audits.records[0].Action != model.AuditActionVoiceRecordingStart || audits.records[0].TargetId != 2 {
+		t.Fatalf("unexpected audit records %#v", audits.records)
+	}
+}
+
+func TestStartVoiceRecordingRequiresTextChannel(t *testing.T) {
+	e := &entity{perm: &fakeRecordingPermissionChecker{channels: map[int64]model.ChannelType{2: model.ChannelTypeGuildVoice}}}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/voice/:channel_id/recording", e.StartVoiceRecording)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/guild/1/voice/2/recording", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", resp.StatusCode)
+	}
+}
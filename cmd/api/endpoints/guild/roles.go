@@ -180,6 +180,7 @@ func (e *entity) CreateGuildRole(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetRoles)
 	}
 	created := roleModelToDTO(createdRole)
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionRoleCreate, roleId, roleAuditChanges(model.Role{}, createdRole), nil)
 	go func() {
 		if err := e.mqt.SendGuildUpdate(guildId, &mqmsg.CreateGuildRole{Role: created}); err != nil {
 			slog.Error("unable to send guild update after role creation", slog.String("error", err.Error()))
@@ -267,6 +268,9 @@ func (e *entity) PatchGuildRole(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetRoles)
 	}
+	if changes := roleAuditChanges(r, ur); len(changes) > 0 {
+		e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionRoleUpdate, roleId, changes, nil)
+	}
 	role := roleModelToDTO(ur)
 	go func() {
 		if err := e.mqt.SendGuildUpdate(guildId, &mqmsg.UpdateGuildRole{GuildId: guildId, Role: role}); err != nil {
@@ -324,9 +328,9 @@ func (e *entity) PatchRoleOrder(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetRoles)
 	}
-	allowed := make(map[int64]struct{}, len(roles))
+	allowed := make(map[int64]model.Role, len(roles))
 	for _, role := range roles {
-		allowed[role.Id] = struct{}{}
+		allowed[role.Id] = role
 	}
 
 	updates := make([]model.RoleUpdatePosition, 0, len(req.Roles))
@@ -355,6 +359,11 @@ func (e *entity) PatchRoleOrder(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetRoles)
 	}
+	for _, ur := range updatedRoles {
+		if changes := roleAuditChanges(allowed[ur.Id], ur); len(changes) > 0 {
+			e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionRoleUpdate, ur.Id, changes, nil)
+		}
+	}
 	updated := roleModelToDTOMany(updatedRoles)
 
 	go func() {
@@ -415,6 +424,16 @@ func (e *entity) DeleteGuildRole(c *fiber.Ctx) error {
 	if r.GuildId != guildId {
 		return fiber.NewError(fiber.StatusBadRequest, ErrRoleNotInGuild)
 	}
+	// The role of a bot stays while the bot is in the guild
+	if r.ManagedBy != nil {
+		botIsMember, err := e.memb.IsGuildMember(c.UserContext(), guildId, *r.ManagedBy)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildMemberToken)
+		}
+		if botIsMember {
+			return fiber.NewError(fiber.StatusBadRequest, ErrRoleIsManaged)
+		}
+	}
 
 	if err := e.ur.RemoveRoleAssignments(c.UserContext(), guildId, roleId); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetRoles)
@@ -423,6 +442,7 @@ func (e *entity) DeleteGuildRole(c *fiber.Ctx) error {
 	if err := e.role.RemoveRole(c.UserContext(), roleId); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetRoles)
 	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionRoleDelete, roleId, roleAuditChanges(r, model.Role{}), nil)
 	go func() {
 		if err := e.mqt.SendGuildUpdate(guildId, &mqmsg.DeleteGuildRole{GuildId: guildId, RoleId: roleId}); err != nil {
 			slog.Error("unable to send guild event after role deletion", slog.String("error", err.Error()))
@@ -493,10 +513,14 @@ func (e *entity) AddMemberRole(c *fiber.Ctx) error {
 	if r.GuildId != guildId {
 		return fiber.NewError(fiber.StatusBadRequest, ErrRoleNotInGuild)
 	}
+	if r.ManagedBy != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrRoleIsManaged)
+	}
 
 	if err := e.ur.AddUserRole(c.UserContext(), guildId, memberId, roleId); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSetUserRole)
 	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionMemberRoleUpdate, memberId, []model.AuditChange{{Key: "role_add", New: roleId}}, nil)
 
 	go func() {
 		_ = e.mqt.SendGuildUpdate(guildId, &mqmsg.AddGuildMemberRole{GuildId: guildId, RoleId: roleId, UserId: memberId})
@@ -564,10 +588,14 @@ func (e *entity) RemoveMemberRole(c *fiber.Ctx) error {
 	if r.GuildId != guildId {
 		return fiber.NewError(fiber.StatusBadRequest, ErrRoleNotInGuild)
 	}
+	if r.ManagedBy != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrRoleIsManaged)
+	}
 
 	if err := e.ur.RemoveUserRole(c.UserContext(), guildId, memberId, roleId); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRemoveUserRole)
 	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionMemberRoleUpdate, memberId, []model.AuditChange{{Key: "role_remove", Old: roleId}}, nil)
 
 	go func() {
 		_ = e.mqt.SendGuildUpdate(guildId, &mqmsg.RemoveGuildMemberRole{GuildId: guildId, RoleId: roleId, UserId: memberId})
@@ -748,11 +776,14 @@ func (e *entity) SetChannelRolePermission(c *fiber.Ctx) error {
 	}
 
 	// Upsert behavior: update if exists, else insert
-	if _, err := e.rperm.GetChannelRolePermission(c.UserContext(), channelId, roleId); err != nil {
+	existing, err := e.rperm.GetChannelRolePermission(c.UserContext(), channelId, roleId)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			if err := e.rperm.SetChannelRolePermission(c.UserContext(), channelId, roleId, req.Accept, req.Deny); err != nil {
 				return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSetChannelRolePerm)
 			}
+			e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionChannelOverwriteCreate, channelId,
+				overwriteAuditChanges(roleId, model.ChannelRolesPermission{}, model.ChannelRolesPermission{Accept: req.Accept, Deny: req.Deny}), nil)
 			return c.SendStatus(fiber.StatusOK)
 		}
 		// Other DB error
@@ -766,6 +797,8 @@ func (e *entity) SetChannelRolePermission(c *fiber.Ctx) error {
 	if err := e.rperm.UpdateChannelRolePermission(c.UserContext(), channelId, roleId, req.Accept, req.Deny); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateChannelRole)
 	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionChannelOverwriteUpdate, channelId,
+		overwriteAuditChanges(roleId, existing, model.ChannelRolesPermission{Accept: req.Accept, Deny: req.Deny}), nil)
 	return c.SendStatus(fiber.StatusOK)
 }
 
@@ -781,7 +814,7 @@ func (e *entity) SetChannelRolePermission(c *fiber.Ctx) error {
 //	@Success	200			{string}	string							"Ok"
 //	@failure	400			{string}	string							"Incorrect request"
 //	@failure	401			{string}	string							"Unauthorized"
-//	@failure	404			{string}	string							"Role or channel not found"
+//	@failure	404			{string}	string							"Role, channel or overwrite not found"
 //	@failure	406			{string}	string							"Permissions required"
 //	@failure	500			{string}	string							"Something bad happened"
 //	@Router		/guild/{guild_id}/channel/{channel_id}/roles/{role_id} [patch]
@@ -830,9 +863,16 @@ func (e *entity) UpdateChannelRolePermission(c *fiber.Ctx) error {
 	req.Accept = permissions.SanitizeChannelOverrides(req.Accept)
 	req.Deny = permissions.SanitizeChannelOverrides(req.Deny)
 
+	// Previous mask is needed for the audit diff
+	existing, err := e.rperm.GetChannelRolePermission(c.UserContext(), channelId, roleId)
+	if err != nil {
+		return helper.HttpDbError(err, ErrUnableToGetChannelRolePerms)
+	}
 	if err := e.rperm.UpdateChannelRolePermission(c.UserContext(), channelId, roleId, req.Accept, req.Deny); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateChannelRole)
 	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionChannelOverwriteUpdate, channelId,
+		overwriteAuditChanges(roleId, existing, model.ChannelRolesPermission{Accept: req.Accept, Deny: req.Deny}), nil)
 	return c.SendStatus(fiber.StatusOK)
 }
 
@@ -847,7 +887,7 @@ func (e *entity) UpdateChannelRolePermission(c *fiber.Ctx) error {
 //	@Success	200			{string}	string	"Ok"
 //	@failure	400			{string}	string	"Incorrect request"
 //	@failure	401			{string}	string	"Unauthorized"
-//	@failure	404			{string}	string	"Role or channel not found"
+//	@failure	404			{string}	string	"Role, channel or overwrite not found"
 //	@failure	406			{string}	string	"Permissions required"
 //	@failure	500			{string}	string	"Something bad happened"
 //	@Router		/guild/{guild_id}/channel/{channel_id}/roles/{role_id} [delete]
@@ -883,8 +923,14 @@ func (e *entity) RemoveChannelRolePermission(c *fiber.Ctx) error {
 	if err != nil || r.GuildId != guildId {
 		return fiber.NewError(fiber.StatusNotFound, ErrRoleNotInGuild)
 	}
+	existing, err := e.rperm.GetChannelRolePermission(c.UserContext(), channelId, roleId)
+	if err != nil {
+		return helper.HttpDbError(err, ErrUnableToGetChannelRolePerms)
+	}
 	if err := e.rperm.RemoveChannelRolePermission(c.UserContext(), channelId, roleId); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRemoveChannelRole)
 	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionChannelOverwriteDelete, channelId,
+		overwriteAuditChanges(roleId, existing, model.ChannelRolesPermission{}), nil)
 	return c.SendStatus(fiber.StatusOK)
 }
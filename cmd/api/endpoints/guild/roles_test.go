@@ -2,6 +2,7 @@ package guild
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http/httptest"
@@ -66,6 +67,27 @@ func (f *fakeRoleRepo) CreateRole(ctx context.Context, id, guildId int64, name s
 	return nil
 }
 
+func (f *fakeRoleRepo) CreateManagedRole(ctx context.Context, id, guildId int64, name string, permissions, managedBy int64) error {
+	f.roles[id] = model.Role{
+		Id:          id,
+		GuildId:     guildId,
+		Name:        name,
+		Permissions: permissions,
+		Position:    len(f.rolesForGuild(guildId)),
+		ManagedBy:   &managedBy,
+	}
+	return nil
+}
+
+func (f *fakeRoleRepo) GetManagedRole(ctx context.Context, guildId, managedBy int64) (model.Role, error) {
+	for _, role := range f.roles {
+		if role.GuildId == guildId && role.ManagedBy != nil && *role.ManagedBy == managedBy {
+			return role, nil
+		}
+	}
+	return model.Role{}, sql.ErrNoRows
+}
+
 func (f *fakeRoleRepo) RemoveRole(ctx context.Context, id int64) error {
 	delete(f.roles, id)
 	return nil
@@ -123,6 +145,7 @@ func (f *fakeRoleRepo) rolesForGuild(guildId int64) []model.Role {
 
 type fakeCache struct {
 	jsonValues map[string][]byte
+	hashes     map[string]map[string]string
 	deleted    []string
 	deleteCh   chan string
 }
@@ -148,7 +171,7 @@ func (f *fakeCache) HGet(ctx context.Context, key, field string) (string, error)
 func (f *fakeCache) HSet(ctx context.Context, key, field, value string) error { return nil }
 func (f *fakeCache) HDel(ctx context.Context, key, field string) error        { return nil }
 func (f *fakeCache) HGetAll(ctx context.Context, key string) (map[string]string, error) {
-	return nil, nil
+	return f.hashes[key], nil
 }
 func (f *fakeCache) XAdd(ctx context.Context, stream string, maxLen int64, approx bool, values map[string]interface{}) error {
 	return nil
@@ -249,6 +272,10 @@ func (f *roleOrderPermissionChecker) GetChannelPermissions(ctx context.Context,
 	return 0, nil
 }
 
+func (f *roleOrderPermissionChecker) MemberPending(ctx context.Context, guildID, userID int64) (bool, error) {
+	return false, nil
+}
+
 func TestGetGuildRolesUsesCache(t *testing.T) {
 	cache := &fakeCache{jsonValues: map[string][]byte{}}
 	cachedRoles := []dto.Role{{Id: 15, GuildId: 1, Name: "cached", Position: 3}}
@@ -2,6 +2,7 @@ package guild
 
 import (
 	"regexp"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 
@@ -39,6 +40,9 @@ const (
 	ErrUnableToGetDiscriminator          = "unable to get discriminator"
 	ErrUnableToGetGuildByID              = "unable to get guild by id"
 	ErrUnableToUpdateGuild               = "unable to update guild"
+	ErrUnableToGetMFA                    = "unable to get two-factor authentication"
+	ErrOnlyOwnerCanChangeMFA             = "only the guild owner can change the two-factor requirement"
+	ErrOwnerMFARequired                  = "enable two-factor authentication to require it for moderators"
 	ErrUnableToDeleteGuild               = "unable to delete guild"
 	ErrUnableToGetRoles                  = "unable to get roles"
 	ErrUnableToSetUserRole               = "unable to set user role"
@@ -62,6 +66,9 @@ const (
 	ErrCannotModerateGuildOwner          = "cannot moderate guild owner"
 	ErrOnlyOwnerCanModerateAdministrator = "only guild owner can moderate administrators"
 	ErrUserIsBanned                      = "user is banned"
+	ErrUnableToTimeoutMember             = "unable to timeout member"
+	ErrUnableToRemoveMemberTimeout       = "unable to remove member timeout"
+	ErrVoiceChannelFull                  = "voice channel is full"
 
 	// Channel role permissions
 	ErrUnableToGetChannelRolePerms = "unable to get channel role permissions"
@@ -74,7 +81,14 @@ const (
 	ErrUnableToDeleteInvite = "unable to delete invite"
 	ErrInviteNotFound       = "invite not found"
 	ErrInviteCodeInvalid    = "invalid invite code"
+	ErrInviteMaxUsesInvalid = "max_uses must be between 0 and 100"
 	ErrRoleNotInGuild       = "role does not belong to this guild"
+	ErrRoleIsManaged        = "role is managed by a bot"
+	// Bots
+	ErrBotNotFound         = "bot not found"
+	ErrNotAllowedForBots   = "not allowed for bot accounts"
+	ErrUnableToAddBot      = "unable to add bot"
+	ErrApplicationIdNeeded = "application ID is required"
 
 	// Validation error messages
 	ErrGuildNameRequired   = "guild name is required"
@@ -88,6 +102,8 @@ const (
 	ErrIconIdInvalid       = "icon ID must be positive"
 	ErrParentIdInvalid     = "parent ID must be positive"
 	ErrPermissionsInvalid  = "permissions must be non-negative"
+	ErrUserLimitInvalid    = "user limit must be between 0 and 99"
+	ErrBitrateInvalid      = "bitrate must be 0 or between 8 and 384 kbps"
 	// Roles
 	ErrRoleNameRequired         = "role name is required"
 	ErrRoleNameTooShort         = "role name must be at least 2 characters"
@@ -95,6 +111,11 @@ const (
 	ErrRoleColorInvalid         = "role color must be between 0 and 16777215"
 	ErrUnableToDeleteActiveIcon = "unable to delete active icon"
 	ErrBanReasonTooLong         = "ban reason must be 256 characters or fewer"
+	ErrTimeoutDurationInvalid   = "timeout duration must be between 1 second and 28 days"
+	ErrTimeoutReasonTooLong     = "timeout reason must be 256 characters or fewer"
+
+	// MaxMemberTimeoutSeconds is the longest allowed member timeout, 28 days
+	MaxMemberTimeoutSeconds = 28 * 24 * 60 * 60
 )
 
 var (
@@ -121,10 +142,17 @@ func (r CreateGuildRequest) Validate() error {
 }
 
 type UpdateGuildRequest struct {
-	Name        *string `json:"name" example:"New guild name"`         // Guild name
-	IconId      *int64  `json:"icon_id" example:"2230469276416868352"` // Icon ID
-	Public      *bool   `json:"public" default:"false"`                // Whether the guild is public
-	Permissions *int64  `json:"permissions" default:"7927905"`         // Permissions. Check the permissions documentation for more info.
+	Name        *string `json:"name" example:"New guild name"`             // Guild name
+	IconId      *int64  `json:"icon_id" example:"2230469276416868352"`     // Icon ID
+	Public      *bool   `json:"public" default:"false"`                    // Whether the guild is public
+	Permissions *int64  `json:"permissions" default:"7927905"`             // Permissions. Check the permissions documentation for more info.
+	MFARequired *bool   `json:"mfa_required" default:"false"`              // Require two-factor authentication for moderation permissions. Only the owner can change it and must have two-factor authentication enabled to turn it on.
+	Description *string `json:"description" example:"A place to hang out"` // Description shown in discovery. Empty string removes it.
+	Category    *int    `json:"category" example:"1"`                      // Discovery category
+	// What members without roles need before they can post: 0 none, 1 verified email, 2 account older than 5 minutes, 3 member for 10 minutes
+	VerificationLevel *int    `json:"verification_level" example:"1"`
+	Rules             *string `json:"rules" example:"Be nice"`           // Rules shown to new members. Empty string removes them.
+	ScreeningEnabled  *bool   `json:"screening_enabled" default:"false"` // New members have to accept the rules before they can post. Requires rules.
 }
 
 func (r UpdateGuildRequest) Validate() error {
@@ -141,6 +169,21 @@ func (r UpdateGuildRequest) Validate() error {
 		validation.Field(&r.Permissions,
 			validation.When(r.Permissions != nil, validation.Min(int64(0)).Error(ErrPermissionsInvalid)),
 		),
+		validation.Field(&r.Description,
+			validation.When(r.Description != nil, validation.RuneLength(0, 300).Error(ErrGuildDescriptionTooLong)),
+		),
+		validation.Field(&r.Category,
+			validation.When(r.Category != nil, validation.By(validateGuildCategory)),
+		),
+		validation.Field(&r.VerificationLevel,
+			validation.When(r.VerificationLevel != nil,
+				validation.Min(int(model.VerificationLevelNone)).Error(ErrVerificationLevelInvalid),
+				validation.Max(int(model.VerificationLevelHigh)).Error(ErrVerificationLevelInvalid),
+			),
+		),
+		validation.Field(&r.Rules,
+			validation.When(r.Rules != nil, validation.RuneLength(0, MaxGuildRulesLength).Error(ErrGuildRulesTooLong)),
+		),
 	)
 }
 
@@ -186,7 +229,6 @@ func (r CreateGuildChannelRequest) Validate() error {
 				model.ChannelTypeGuild,
 				model.ChannelTypeGuildVoice,
 				model.ChannelTypeGuildCategory,
-				model.ChannelTypeThread,
 			).Error(ErrChannelTypeInvalid),
 		),
 		validation.Field(&r.ParentId,
@@ -232,6 +274,10 @@ type PatchGuildChannelRequest struct {
 	Name    *string `json:"name,omitempty" example:"new-channel-name"`      // Channel name.
 	Private *bool   `json:"private,omitempty" default:"false"`              // Whether the channel is private. Private channels can only be seen by users with roles assigned to this channel.
 	Topic   *string `json:"topic,omitempty" example:"Just a channel topic"` // Channel topic.
+	// Voice channel settings, rejected for other channel types
+	UserLimit *int  `json:"user_limit,omitempty" example:"10"` // Maximum number of connected users, 0 means unlimited.
+	Bitrate   *int  `json:"bitrate,omitempty" example:"64"`    // Audio bitrate cap in kbps, 0 means the server default.
+	Stage     *bool `json:"stage,omitempty" default:"false"`   // Stage mode, only approved speakers may speak.
 }
 
 func (r PatchGuildChannelRequest) Validate() error {
@@ -243,16 +289,39 @@ func (r PatchGuildChannelRequest) Validate() error {
 				validation.Match(channelNameRegex).Error(ErrChannelNameInvalid),
 			),
 		),
+		validation.Field(&r.UserLimit,
+			validation.When(r.UserLimit != nil,
+				validation.Min(0).Error(ErrUserLimitInvalid),
+				validation.Max(99).Error(ErrUserLimitInvalid),
+			),
+		),
+		validation.Field(&r.Bitrate,
+			validation.When(r.Bitrate != nil && *r.Bitrate != 0,
+				validation.Min(8).Error(ErrBitrateInvalid),
+				validation.Max(384).Error(ErrBitrateInvalid),
+			),
+		),
 	)
 }
 
+// hasVoiceSettings reports whether the request changes voice channel settings
+func (r PatchGuildChannelRequest) hasVoiceSettings() bool {
+	return r.UserLimit != nil || r.Bitrate != nil || r.Stage != nil
+}
+
 // Invites
 type CreateInviteRequest struct {
 	ExpiresInSec *int `json:"expires_in_sec" example:"86400"` // Expiration time in seconds. 0 means unlimited.
+	MaxUses      int  `json:"max_uses" example:"10"`          // Number of times the invite can be used. 0 means unlimited.
+	Temporary    bool `json:"temporary" example:"false"`      // Members are removed when they disconnect unless a role is assigned
 }
 
 func (r CreateInviteRequest) Validate() error {
 	return validation.ValidateStruct(&r,
+		validation.Field(&r.MaxUses,
+			validation.Min(0).Error(ErrInviteMaxUsesInvalid),
+			validation.Max(100).Error(ErrInviteMaxUsesInvalid),
+		),
 		validation.Field(&r.ExpiresInSec,
 			validation.When(r.ExpiresInSec != nil,
 				validation.By(func(v interface{}) error {
@@ -298,6 +367,9 @@ func channelModelToDTO(c *model.Channel, guildId *int64, position int, roles []i
 		Private:       c.Private,
 		Roles:         roles,
 		VoiceRegion:   c.VoiceRegion,
+		UserLimit:     c.VoiceUserLimit,
+		Bitrate:       c.VoiceBitrate,
+		Stage:         c.VoiceStage,
 		LastMessageId: c.LastMessage,
 	}
 }
@@ -305,11 +377,18 @@ func channelModelToDTO(c *model.Channel, guildId *int64, position int, roles []i
 // buildGuildDTO creates a guild DTO from model
 func buildGuildDTO(guild *model.Guild) dto.Guild {
 	return dto.Guild{
-		Id:          guild.Id,
-		Name:        guild.Name,
-		Owner:       guild.OwnerId,
-		Public:      guild.Public,
-		Permissions: guild.Permissions,
+		Id:                guild.Id,
+		Name:              guild.Name,
+		Owner:             guild.OwnerId,
+		Public:            guild.Public,
+		Permissions:       guild.Permissions,
+		MFARequired:       guild.MFARequired,
+		Description:       guild.Description,
+		Category:          int(guild.Category),
+		VanityCode:        guild.VanityCode,
+		VerificationLevel: int(guild.VerificationLevel),
+		Rules:             guild.Rules,
+		ScreeningEnabled:  guild.ScreeningEnabled,
 	}
 }
 
@@ -321,6 +400,7 @@ func roleModelToDTO(r model.Role) dto.Role {
 		Color:       r.Color,
 		Permissions: r.Permissions,
 		Position:    r.Position,
+		Managed:     r.ManagedBy != nil,
 	}
 }
 
@@ -357,6 +437,23 @@ type CreateGuildRoleRequest struct {
 	Permissions int64  `json:"permissions" default:"0"`  // Permissions bitset
 }
 
+type AddGuildBotRequest struct {
+	ApplicationId int64 `json:"application_id" example:"2230469276416868352"` // Application ID, the same as the ID of its bot user
+	Permissions   int64 `json:"permissions" default:"0"`                      // Permissions bitset of the bot role. Unknown bits are dropped.
+}
+
+func (r AddGuildBotRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ApplicationId,
+			validation.Required.Error(ErrApplicationIdNeeded),
+			validation.Min(int64(1)).Error(ErrApplicationIdNeeded),
+		),
+		validation.Field(&r.Permissions,
+			validation.Min(int64(0)).Error(ErrPermissionsInvalid),
+		),
+	)
+}
+
 func (r CreateGuildRoleRequest) Validate() error {
 	return validation.ValidateStruct(&r,
 		validation.Field(&r.Name,
@@ -447,27 +544,53 @@ func (r BanMemberRequest) Validate() error {
 	)
 }
 
+type TimeoutMemberRequest struct {
+	Duration int     `json:"duration" example:"3600"`                   // Timeout duration in seconds, up to 28 days
+	Reason   *string `json:"reason,omitempty" example:"Spamming links"` // Reason shown in the audit log
+}
+
+func (r TimeoutMemberRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Duration,
+			validation.Required.Error(ErrTimeoutDurationInvalid),
+			validation.Min(1).Error(ErrTimeoutDurationInvalid),
+			validation.Max(MaxMemberTimeoutSeconds).Error(ErrTimeoutDurationInvalid),
+		),
+		validation.Field(&r.Reason,
+			validation.When(r.Reason != nil,
+				validation.RuneLength(0, 256).Error(ErrTimeoutReasonTooLong),
+			),
+		),
+	)
+}
+
 func userToDTO(user model.User, dsc string) dto.User {
 	return dto.User{
 		Id:            user.Id,
 		Name:          user.Name,
 		Discriminator: dsc,
+		Bot:           user.Bot,
 	}
 }
 
 func membersToDTO(members []model.Member, users []model.User, roles []model.UserRoles, dscs []model.Discriminator, avData map[int64]*dto.AvatarData) []dto.Member {
 	var data = make([]dto.Member, len(members))
+	now := time.Now()
 	for i, m := range members {
 		u := userToDTO(users[i], dscs[i].Discriminator)
 		if ad, ok := avData[m.UserId]; ok {
 			u.Avatar = ad
 		}
 		data[i] = dto.Member{
-			User:     u,
-			Username: m.Username,
-			Avatar:   m.Avatar,
-			JoinAt:   m.JoinAt,
-			Roles:    roles[i].Roles,
+			User:       u,
+			Username:   m.Username,
+			Avatar:     m.Avatar,
+			JoinAt:     m.JoinAt,
+			Roles:      roles[i].Roles,
+			Timeout:    memberTimeout(m, now),
+			InviteCode: m.InviteCode,
+			Temporary:  m.Temporary,
+			Pending:    m.Pending,
 		}
 	}
 	return data
@@ -528,3 +651,12 @@ type voiceRouteBinding struct {
 	URL    string `json:"url"`
 	Region string `json:"region,omitempty"`
 }
+
+// memberTimeout returns the end of an active member timeout or nil when the member is not timed out
+func memberTimeout(m model.Member, now time.Time) *time.Time {
+	if !m.Timeout.After(now) {
+		return nil
+	}
+	until := m.Timeout
+	return &until
+}
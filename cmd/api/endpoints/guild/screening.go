@@ -0,0 +1,209 @@
+package guild
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+// AcceptRules
+//
+//	@Summary		Accept guild rules
+//	@Description	Accepts the guild rules and lets the pending member post. Only available while the guild screening is enabled.
+//	@Tags			Guild
+//	@Param			guild_id	path		int64	true	"Guild ID"
+//	@Success		204			{string}	string	"No Content"
+//	@failure		400			{string}	string	"Screening is disabled"
+//	@failure		404			{string}	string	"Member not found"
+//	@failure		500			{string}	string	"Something bad happened"
+//	@Router			/guild/{guild_id}/screening [post]
+func (e *entity) AcceptRules(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	guild, err := e.g.GetGuildById(c.UserContext(), guildId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildByID)
+	}
+	if !guild.ScreeningEnabled {
+		return fiber.NewError(fiber.StatusBadRequest, ErrScreeningDisabled)
+	}
+
+	member, err := e.memb.GetMember(c.UserContext(), user.Id, guildId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, ErrNotAMember)
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildMemberToken)
+	}
+	if !member.Pending {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	if err := e.memb.SetPending(c.UserContext(), user.Id, guildId, false); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateMemberScreening)
+	}
+	member.Pending = false
+	e.sendGuildMemberUpdate(guildId, member)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetPendingMembers
+//
+//	@Summary		Get pending guild members
+//	@Description	Returns members who haven't accepted the guild rules yet. Requires PermMembershipKickMembers.
+//	@Produce		json
+//	@Tags			Guild
+//	@Param			guild_id	path		int64		true	"Guild ID"
+//	@Success		200			{array}		dto.Member	"Ok"
+//	@failure		400			{string}	string		"Bad request"
+//	@failure		406			{string}	string		"Permissions required"
+//	@failure		500			{string}	string		"Something bad happened"
+//	@Router			/guild/{guild_id}/members/pending [get]
+func (e *entity) GetPendingMembers(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	if _, err := e.authorizeGuildPermission(c.UserContext(), guildId, user.Id, permissions.PermMembershipKickMembers); err != nil {
+		return err
+	}
+
+	members, err := e.memb.GetPendingMembers(c.UserContext(), guildId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetPendingMembers)
+	}
+	data, err := e.membersWithProfiles(c.UserContext(), guildId, members)
+	if err != nil {
+		return err
+	}
+	return c.JSON(data)
+}
+
+// ApprovePendingMember
+//
+//	@Summary		Approve pending member
+//	@Description	Lets a pending member in without waiting for them to accept the rules. Requires PermMembershipKickMembers.
+//	@Tags			Guild
+//	@Param			guild_id	path		int64	true	"Guild ID"
+//	@Param			user_id		path		int64	true	"User ID"
+//	@Success		204			{string}	string	"No Content"
+//	@failure		400			{string}	string	"Member is not pending"
+//	@failure		404			{string}	string	"Member not found"
+//	@failure		406			{string}	string	"Permissions required"
+//	@Router			/guild/{guild_id}/member/{user_id}/approve [post]
+func (e *entity) ApprovePendingMember(c *fiber.Ctx) error {
+	guildId, memberId, user, err := e.parseMemberModerationRequest(c)
+	if err != nil {
+		return err
+	}
+
+	member, err := e.getPendingMember(c.UserContext(), guildId, user.Id, memberId)
+	if err != nil {
+		return err
+	}
+
+	if err := e.memb.SetPending(c.UserContext(), memberId, guildId, false); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateMemberScreening)
+	}
+	member.Pending = false
+
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionMemberUpdate, memberId,
+		[]model.AuditChange{{Key: "pending", Old: true, New: false}}, nil)
+	e.sendGuildMemberUpdate(guildId, member)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RejectPendingMember
+//
+//	@Summary		Reject pending member
+//	@Description	Removes a pending member from the guild. Requires PermMembershipKickMembers.
+//	@Tags			Guild
+//	@Param			guild_id	path		int64	true	"Guild ID"
+//	@Param			user_id		path		int64	true	"User ID"
+//	@Success		204			{string}	string	"No Content"
+//	@failure		400			{string}	string	"Member is not pending"
+//	@failure		404			{string}	string	"Member not found"
+//	@failure		406			{string}	string	"Permissions required"
+//	@Router			/guild/{guild_id}/member/{user_id}/reject [post]
+func (e *entity) RejectPendingMember(c *fiber.Ctx) error {
+	guildId, memberId, user, err := e.parseMemberModerationRequest(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := e.getPendingMember(c.UserContext(), guildId, user.Id, memberId); err != nil {
+		return err
+	}
+
+	if err := e.memb.RemoveMember(c.UserContext(), memberId, guildId); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRemoveMember)
+	}
+
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionMemberKick, memberId, nil, nil)
+	e.sendGuildMemberRemoved(guildId, memberId, user.Id, mqmsg.GuildMemberModerationKick, nil)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// getPendingMember checks that the actor can moderate the member and that the member is pending
+func (e *entity) getPendingMember(ctx context.Context, guildId, actorId, memberId int64) (model.Member, error) {
+	if _, err := e.authorizeMemberModeration(ctx, guildId, actorId, memberId, permissions.PermMembershipKickMembers, true); err != nil {
+		return model.Member{}, err
+	}
+	member, err := e.memb.GetMember(ctx, memberId, guildId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Member{}, fiber.NewError(fiber.StatusNotFound, ErrNotAMember)
+		}
+		return model.Member{}, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGuildMemberToken)
+	}
+	if !member.Pending {
+		return model.Member{}, fiber.NewError(fiber.StatusBadRequest, ErrMemberNotPending)
+	}
+	return member, nil
+}
+
+// sendGuildMemberUpdate sends the member with the profile to the guild
+func (e *entity) sendGuildMemberUpdate(guildId int64, member model.Member) {
+	if e.mqt == nil {
+		return
+	}
+	logger := e.log
+	if logger == nil {
+		logger = slog.Default()
+	}
+	go func() {
+		data, err := e.membersWithProfiles(context.Background(), guildId, []model.Member{member})
+		if err != nil || len(data) == 0 {
+			logger.Error("unable to get member for guild member update event",
+				slog.Int64("guild_id", guildId),
+				slog.Int64("user_id", member.UserId))
+			return
+		}
+		if err := e.mqt.SendGuildUpdate(guildId, &mqmsg.UpdateGuildMember{GuildId: guildId, Member: data[0]}); err != nil {
+			logger.Error("unable to send guild member update event",
+				slog.Int64("guild_id", guildId),
+				slog.Int64("user_id", member.UserId),
+				slog.String("error", err.Error()))
+		}
+	}()
+}
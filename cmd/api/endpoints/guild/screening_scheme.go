@@ -0,0 +1,39 @@
+package guild
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+const (
+	ErrVerificationLevelInvalid      = "verification level must be between 0 and 3"
+	ErrGuildRulesTooLong             = "rules must be at most 2000 characters"
+	ErrScreeningRulesRequired        = "screening requires guild rules"
+	ErrScreeningDisabled             = "guild screening is disabled"
+	ErrMemberPending                 = "member has not passed the guild screening"
+	ErrMemberNotPending              = "member is not pending"
+	ErrUnableToGetPendingMembers     = "unable to get pending members"
+	ErrUnableToUpdateMemberScreening = "unable to update member screening"
+
+	MaxGuildRulesLength = 2000
+)
+
+// validateScreeningRules checks that the guild will have rules if screening stays on after the update
+func validateScreeningRules(guild *model.Guild, req *UpdateGuildRequest) error {
+	enabled := guild.ScreeningEnabled
+	if req.ScreeningEnabled != nil {
+		enabled = *req.ScreeningEnabled
+	}
+	if !enabled {
+		return nil
+	}
+	rules := guild.Rules
+	if req.Rules != nil {
+		rules = req.Rules
+	}
+	if rules == nil || *rules == "" {
+		return fiber.NewError(fiber.StatusBadRequest, ErrScreeningRulesRequired)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package guild
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+func TestAcceptInviteMarksMemberPendingWithScreening(t *testing.T) {
+	members := &fakeMemberRepo{members: map[testMemberKey]bool{}}
+	rules := "Be nice"
+	e := &entity{
+		ban:  &fakeBanRepo{},
+		memb: members,
+		inv:  &fakeInviteRepo{},
+		user: &fakeUserRepo{users: map[int64]model.User{10: {Id: 10, Name: "joiner"}}},
+		disc: &fakeDiscriminatorRepo{discriminators: map[int64]string{10: "joiner"}},
+		g:    &fakeGuildRepo{guild: model.Guild{Id: 1, Rules: &rules, ScreeningEnabled: true}, vanity: map[string]int64{"gophers": 1}},
+		mqt:  &fakeTransport{},
+	}
+	app := newGuildTestApp(t, 10, "/guild/invites/accept/:invite_code", e.AcceptInvite)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/guild/invites/accept/gophers", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if !members.pending[testMemberKey{guildID: 1, userID: 10}] {
+		t.Fatal("expected new member to be pending")
+	}
+}
+
+func TestAcceptRules(t *testing.T) {
+	key := testMemberKey{guildID: 1, userID: 10}
+	members := &fakeMemberRepo{members: map[testMemberKey]bool{key: true}, pending: map[testMemberKey]bool{key: true}}
+	guilds := &fakeGuildRepo{guild: model.Guild{Id: 1}}
+	e := &entity{g: guilds, memb: members}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/screening", e.AcceptRules)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/guild/1/screening", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected 400 while screening is disabled, got %d", resp.StatusCode)
+	}
+
+	guilds.guild.ScreeningEnabled = true
+	resp, err = app.Test(httptest.NewRequest("POST", "/guild/1/screening", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	if members.pending[key] {
+		t.Fatal("expected member to pass the screening")
+	}
+}
+
+func TestApprovePendingMember(t *testing.T) {
+	key := testMemberKey{guildID: 1, userID: 11}
+	members := &fakeMemberRepo{members: map[testMemberKey]bool{{guildID: 1, userID: 10}: true, key: true}}
+	perms := &fakePermissionChecker{results: map[testPermKey]bool{
+		{guildID: 1, userID: 10, perm: permissions.PermMembershipKickMembers}: true,
+	}}
+	e := &entity{g: &fakeGuildRepo{guild: model.Guild{Id: 1, OwnerId: 99}}, memb: members, perm: perms}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/member/:user_id/approve", e.ApprovePendingMember)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/guild/1/member/11/approve", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected 400 for a member that is not pending, got %d", resp.StatusCode)
+	}
+
+	members.pending = map[testMemberKey]bool{key: true}
+	resp, err = app.Test(httptest.NewRequest("POST", "/guild/1/member/11/approve", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	if members.pending[key] {
+		t.Fatal("expected member to be approved")
+	}
+}
+
+func TestRejectPendingMemberRemovesMember(t *testing.T) {
+	key := testMemberKey{guildID: 1, userID: 11}
+	members := &fakeMemberRepo{
+		members: map[testMemberKey]bool{{guildID: 1, userID: 10}: true, key: true},
+		pending: map[testMemberKey]bool{key: true},
+	}
+	perms := &fakePermissionChecker{results: map[testPermKey]bool{
+		{guildID: 1, userID: 10, perm: permissions.PermMembershipKickMembers}: true,
+	}}
+	e := &entity{g: &fakeGuildRepo{guild: model.Guild{Id: 1, OwnerId: 99}}, memb: members, perm: perms}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/member/:user_id/reject", e.RejectPendingMember)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/guild/1/member/11/reject", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	if len(members.removeCalls) != 1 || members.removeCalls[0] != key {
+		t.Fatalf("unexpected remove calls: %#v", members.removeCalls)
+	}
+}
+
+func TestValidateScreeningRules(t *testing.T) {
+	enabled, empty, rules := true, "", "Be nice"
+	if err := validateScreeningRules(&model.Guild{}, &UpdateGuildRequest{ScreeningEnabled: &enabled}); err == nil {
+		t.Fatal("expected screening without rules to be rejected")
+	}
+	if err := validateScreeningRules(&model.Guild{Rules: &rules, ScreeningEnabled: true}, &UpdateGuildRequest{Rules: &empty}); err == nil {
+		t.Fatal("expected removing rules of a screened guild to be rejected")
+	}
+	if err := validateScreeningRules(&model.Guild{Rules: &rules}, &UpdateGuildRequest{ScreeningEnabled: &enabled}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,572 @@
+package guild
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+// CreateThread
+//
+//	@Summary		Create thread
+//	@Description	Starts a thread under a text channel, either from a channel message or standalone. Requires PermTextCreateThreads in the parent channel, and PermTextReadMessageHistory to start it from a message. The thread inherits the parent channel permission overwrites.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Guild
+//	@Param			guild_id	path		int64				true	"Guild ID"			example(2230469276416868352)
+//	@Param			channel_id	path		int64				true	"Parent channel ID"	example(2230469276416868352)
+//	@Param			request		body		CreateThreadRequest	true	"Thread data"
+//	@Success		201			{object}	dto.Channel			"Thread channel"
+//	@failure		400			{string}	string				"Bad request"
+//	@failure		404			{string}	string				"Message not found"
+//	@failure		406			{string}	string				"Permissions required"
+//	@failure		409			{string}	string				"Thread already exists"
+//	@failure		500			{string}	string				"Something bad happened"
+//	@Router			/guild/{guild_id}/channel/{channel_id}/threads [post]
+func (e *entity) CreateThread(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+	channelId, err := e.parseChannelID(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateThreadRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	perms := []permissions.RolePermission{permissions.PermServerViewChannels, permissions.PermTextCreateThreads}
+	if req.MessageId != nil {
+		// Starting a thread from a message shows it in the thread, so the message must be readable
+		perms = append(perms, permissions.PermTextReadMessageHistory)
+	}
+	parent, _, _, ok, err := e.perm.ChannelPerm(c.UserContext(), guildId, channelId, user.Id, perms...)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetPermission)
+	}
+	if !ok {
+		return fiber.NewError(fiber.StatusNotAcceptable, ErrPermissionsRequired)
+	}
+	if parent.Type != model.ChannelTypeGuild {
+		return fiber.NewError(fiber.StatusBadRequest, ErrThreadParentInvalid)
+	}
+
+	threadId := idgen.Next()
+	if req.MessageId != nil {
+		msg, err := e.msg.GetMessage(c.UserContext(), *req.MessageId, channelId)
+		if err != nil {
+			if errors.Is(err, gocql.ErrNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, ErrThreadMessageNotFound)
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateThread)
+		}
+		if msg.Thread != 0 {
+			return fiber.NewError(fiber.StatusConflict, ErrThreadAlreadyExists)
+		}
+		// Link the message first, so concurrent requests can not start two threads from it
+		claimed, err := e.msg.ClaimMessageThread(c.UserContext(), *req.MessageId, channelId, threadId)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateThread)
+		}
+		if !claimed {
+			return fiber.NewError(fiber.StatusConflict, ErrThreadAlreadyExists)
+		}
+	}
+	// unlinkMessage releases the message when the thread could not be created
+	unlinkMessage := func() {
+		if req.MessageId == nil {
+			return
+		}
+		if err := e.msg.SetMessageThread(c.UserContext(), *req.MessageId, channelId, 0); err != nil {
+			slog.Error("unable to unlink message from thread", slog.String("error", err.Error()))
+		}
+	}
+
+	autoArchive := DefaultThreadAutoArchiveDuration
+	if req.AutoArchiveDuration != nil {
+		autoArchive = *req.AutoArchiveDuration
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if err := e.gc.AddChannel(c.UserContext(), guildId, threadId, name, model.ChannelTypeThread, &channelId, false, 0); err != nil {
+		unlinkMessage()
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateThread)
+	}
+	thread := model.Thread{
+		Id:                 threadId,
+		GuildId:            guildId,
+		ParentId:           channelId,
+		OwnerId:            user.Id,
+		MessageId:          req.MessageId,
+		AutoArchiveMinutes: autoArchive,
+	}
+	if err := e.thread.CreateThread(c.UserContext(), thread); err != nil {
+		_ = e.gc.RemoveChannel(c.UserContext(), guildId, threadId)
+		unlinkMessage()
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateThread)
+	}
+	if err := e.tmemb.AddThreadMember(c.UserContext(), threadId, user.Id); err != nil {
+		slog.Error("unable to add thread owner to thread members", slog.String("error", err.Error()))
+	}
+
+	now := time.Now()
+	thread.LastActivityAt = now
+	thread.CreatedAt = now
+	ch := model.Channel{Id: threadId, Name: name, Type: model.ChannelTypeThread, ParentID: &channelId, CreatedAt: now}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionThreadCreate, threadId,
+		threadAuditChanges(model.Channel{}, ch, model.Thread{}, thread), nil)
+
+	resp := threadModelToDTO(&ch, &thread)
+	go func() {
+		if err := e.mqt.SendGuildUpdate(guildId, &mqmsg.CreateThread{GuildId: guildId, Channel: resp}); err != nil {
+			slog.Error("unable to send thread create event", slog.String("error", err.Error()))
+		}
+	}()
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// ListThreads
+//
+//	@Summary		List channel threads
+//	@Description	Returns threads started in the channel, most recently active first. Active threads are returned unless `archived` is set.
+//	@Produce		json
+//	@Tags			Guild
+//	@Param			guild_id	path		int64		true	"Guild ID"			example(2230469276416868352)
+//	@Param			channel_id	path		int64		true	"Parent channel ID"	example(2230469276416868352)
+//	@Param			archived	query		bool		false	"Return archived threads"
+//	@Success		200			{array}		dto.Channel	"Thread channels"
+//	@failure		400			{string}	string		"Bad request"
+//	@failure		406			{string}	string		"Permissions required"
+//	@failure		500			{string}	string		"Something bad happened"
+//	@Router			/guild/{guild_id}/channel/{channel_id}/threads [get]
+func (e *entity) ListThreads(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+	channelId, err := e.parseChannelID(c)
+	if err != nil {
+		return err
+	}
+
+	var req ListThreadsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	_, _, _, ok, err := e.perm.ChannelPerm(c.UserContext(), guildId, channelId, user.Id, permissions.PermServerViewChannels, permissions.PermTextReadMessageHistory)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetPermission)
+	}
+	if !ok {
+		return fiber.NewError(fiber.StatusNotAcceptable, ErrPermissionsRequired)
+	}
+
+	threads, err := e.thread.GetParentThreads(c.UserContext(), guildId, channelId, &req.Archived)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetThreads)
+	}
+	if len(threads) == 0 {
+		return c.JSON([]dto.Channel{})
+	}
+
+	ids := make([]int64, len(threads))
+	for i, t := range threads {
+		ids[i] = t.Id
+	}
+	channels, err := e.ch.GetChannelsBulk(c.UserContext(), ids)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetThreads)
+	}
+	byId := make(map[int64]model.Channel, len(channels))
+	for _, ch := range channels {
+		byId[ch.Id] = ch
+	}
+
+	result := make([]dto.Channel, 0, len(threads))
+	for i := range threads {
+		ch, ok := byId[threads[i].Id]
+		if !ok {
+			continue
+		}
+		result = append(result, threadModelToDTO(&ch, &threads[i]))
+	}
+	return c.JSON(result)
+}
+
+// UpdateThread
+//
+//	@Summary		Update thread
+//	@Description	Renames, archives, reopens the thread or changes its auto-archive duration. The thread owner can update own thread, other users require PermTextManageThreads.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Guild
+//	@Param			guild_id	path		int64				true	"Guild ID"	example(2230469276416868352)
+//	@Param			thread_id	path		int64				true	"Thread ID"	example(2230469276416868352)
+//	@Param			request		body		UpdateThreadRequest	true	"Thread changes"
+//	@Success		200			{object}	dto.Channel			"Thread channel"
+//	@failure		400			{string}	string				"Bad request"
+//	@failure		404			{string}	string				"Thread not found"
+//	@failure		406			{string}	string				"Permissions required"
+//	@failure		500			{string}	string				"Something bad happened"
+//	@Router			/guild/{guild_id}/thread/{thread_id} [patch]
+func (e *entity) UpdateThread(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+	threadId, err := e.parseThreadID(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateThreadRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	ch, thread, err := e.getGuildThread(c.UserContext(), guildId, threadId, user.Id, permissions.PermServerViewChannels)
+	if err != nil {
+		return err
+	}
+	if thread.OwnerId != user.Id {
+		if err := e.authorizeThreadPermission(c.UserContext(), guildId, thread.ParentId, user.Id, permissions.PermTextManageThreads); err != nil {
+			return err
+		}
+	}
+
+	updCh := *ch
+	if req.Name != nil {
+		updCh.Name = strings.TrimSpace(*req.Name)
+		if err := e.ch.RenameChannel(c.UserContext(), threadId, updCh.Name); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateThread)
+		}
+	}
+	updThread, err := e.thread.UpdateThread(c.UserContext(), guildId, threadId, req.Archived, req.AutoArchiveDuration)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateThread)
+	}
+	if changes := threadAuditChanges(*ch, updCh, *thread, updThread); len(changes) > 0 {
+		e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionThreadUpdate, threadId, changes, nil)
+	}
+
+	resp := threadModelToDTO(&updCh, &updThread)
+	go e.sendThreadUpdateEvent(guildId, resp)
+
+	return c.JSON(resp)
+}
+
+// DeleteThread
+//
+//	@Summary		Delete thread
+//	@Description	Deletes the thread with all its messages. Requires PermTextManageThreads in the parent channel.
+//	@Produce		json
+//	@Tags			Guild
+//	@Param			guild_id	path		int64	true	"Guild ID"	example(2230469276416868352)
+//	@Param			thread_id	path		int64	true	"Thread ID"	example(2230469276416868352)
+//	@Success		204			{string}	string	"Deleted"
+//	@failure		400			{string}	string	"Bad request"
+//	@failure		404			{string}	string	"Thread not found"
+//	@failure		406			{string}	string	"Permissions required"
+//	@failure		500			{string}	string	"Something bad happened"
+//	@Router			/guild/{guild_id}/thread/{thread_id} [delete]
+func (e *entity) DeleteThread(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+	threadId, err := e.parseThreadID(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	ch, thread, err := e.getGuildThread(c.UserContext(), guildId, threadId, user.Id, permissions.PermServerViewChannels, permissions.PermTextManageThreads)
+	if err != nil {
+		return err
+	}
+
+	if err := e.removeThread(c.UserContext(), ch, thread); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToDeleteThread)
+	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionThreadDelete, threadId,
+		threadAuditChanges(*ch, model.Channel{}, *thread, model.Thread{}), nil)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetThreadMembers
+//
+//	@Summary	Get thread members
+//	@Produce	json
+//	@Tags		Guild
+//	@Param		guild_id	path		int64				true	"Guild ID"	example(2230469276416868352)
+//	@Param		thread_id	path		int64				true	"Thread ID"	example(2230469276416868352)
+//	@Success	200			{array}		dto.ThreadMember	"Thread members"
+//	@failure	400			{string}	string				"Bad request"
+//	@failure	404			{string}	string				"Thread not found"
+//	@failure	406			{string}	string				"Permissions required"
+//	@failure	500			{string}	string				"Something bad happened"
+//	@Router		/guild/{guild_id}/thread/{thread_id}/members [get]
+func (e *entity) GetThreadMembers(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+	threadId, err := e.parseThreadID(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	if _, _, err := e.getGuildThread(c.UserContext(), guildId, threadId, user.Id, permissions.PermServerViewChannels); err != nil {
+		return err
+	}
+
+	members, err := e.tmemb.GetThreadMembers(c.UserContext(), threadId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetThreadMembers)
+	}
+	result := make([]dto.ThreadMember, len(members))
+	for i, m := range members {
+		result[i] = threadMemberModelToDTO(m)
+	}
+	return c.JSON(result)
+}
+
+// JoinThread
+//
+//	@Summary	Join thread
+//	@Produce	json
+//	@Tags		Guild
+//	@Param		guild_id	path		int64	true	"Guild ID"	example(2230469276416868352)
+//	@Param		thread_id	path		int64	true	"Thread ID"	example(2230469276416868352)
+//	@Success	204			{string}	string	"Joined"
+//	@failure	400			{string}	string	"Bad request or thread is archived"
+//	@failure	404			{string}	string	"Thread not found"
+//	@failure	406			{string}	string	"Permissions required"
+//	@failure	500			{string}	string	"Something bad happened"
+//	@Router		/guild/{guild_id}/thread/{thread_id}/members/@me [put]
+func (e *entity) JoinThread(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+	threadId, err := e.parseThreadID(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	_, thread, err := e.getGuildThread(c.UserContext(), guildId, threadId, user.Id, permissions.PermServerViewChannels)
+	if err != nil {
+		return err
+	}
+	if thread.Archived {
+		return fiber.NewError(fiber.StatusBadRequest, ErrThreadArchived)
+	}
+
+	if err := e.tmemb.AddThreadMember(c.UserContext(), threadId, user.Id); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToJoinThread)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// LeaveThread
+//
+//	@Summary	Leave thread
+//	@Produce	json
+//	@Tags		Guild
+//	@Param		guild_id	path		int64	true	"Guild ID"	example(2230469276416868352)
+//	@Param		thread_id	path		int64	true	"Thread ID"	example(2230469276416868352)
+//	@Success	204			{string}	string	"Left"
+//	@failure	400			{string}	string	"Bad request"
+//	@failure	404			{string}	string	"Thread not found"
+//	@failure	406			{string}	string	"Permissions required"
+//	@failure	500			{string}	string	"Something bad happened"
+//	@Router		/guild/{guild_id}/thread/{thread_id}/members/@me [delete]
+func (e *entity) LeaveThread(c *fiber.Ctx) error {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return err
+	}
+	threadId, err := e.parseThreadID(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	thread, err := e.thread.GetThread(c.UserContext(), guildId, threadId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, ErrThreadNotFound)
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToLeaveThread)
+	}
+	if err := e.authorizeThreadPermission(c.UserContext(), guildId, thread.ParentId, user.Id, permissions.PermServerViewChannels); err != nil {
+		return err
+	}
+
+	if err := e.tmemb.RemoveThreadMember(c.UserContext(), threadId, user.Id); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToLeaveThread)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// parseThreadID extracts and validates thread ID from URL parameters
+func (e *entity) parseThreadID(c *fiber.Ctx) (int64, error) {
+	threadIdStr := c.Params("thread_id")
+	threadId, err := strconv.ParseInt(threadIdStr, 10, 64)
+	if err != nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, ErrIncorrectThreadID)
+	}
+	return threadId, nil
+}
+
+// getGuildThread loads the thread channel with its metadata and checks permissions on the parent channel,
+// threads have no overwrites of their own
+func (e *entity) getGuildThread(ctx context.Context, guildId, threadId, userId int64, perm ...permissions.RolePermission) (*model.Channel, *model.Thread, error) {
+	thread, err := e.thread.GetThread(ctx, guildId, threadId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, fiber.NewError(fiber.StatusNotFound, ErrThreadNotFound)
+		}
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetThreads)
+	}
+	ch, err := e.ch.GetChannel(ctx, threadId)
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetChannel)
+	}
+	if err := e.authorizeThreadPermission(ctx, guildId, thread.ParentId, userId, perm...); err != nil {
+		return nil, nil, err
+	}
+	return &ch, &thread, nil
+}
+
+func (e *entity) authorizeThreadPermission(ctx context.Context, guildId, parentId, userId int64, perm ...permissions.RolePermission) error {
+	_, _, _, ok, err := e.perm.ChannelPerm(ctx, guildId, parentId, userId, perm...)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetPermission)
+	}
+	if !ok {
+		return fiber.NewError(fiber.StatusNotAcceptable, ErrPermissionsRequired)
+	}
+	return nil
+}
+
+// removeThread deletes the thread channel, its metadata, members and messages, and notifies the guild
+func (e *entity) removeThread(ctx context.Context, ch *model.Channel, thread *model.Thread) error {
+	if err := e.gc.RemoveChannel(ctx, thread.GuildId, thread.Id); err != nil {
+		return err
+	}
+	if err := e.thread.DeleteThread(ctx, thread.GuildId, thread.Id); err != nil {
+		return err
+	}
+	if err := e.tmemb.RemoveThreadMembers(ctx, thread.Id); err != nil {
+		slog.Error("unable to remove thread members", slog.String("error", err.Error()))
+	}
+	if ch.LastMessage != 0 {
+		if err := e.msg.DeleteChannelMessages(ctx, thread.Id, ch.LastMessage); err != nil {
+			slog.Error("unable to remove thread messages", slog.String("error", err.Error()))
+		}
+	}
+	if e.pin != nil {
+		if err := e.pin.RemoveChannelPins(ctx, thread.Id); err != nil {
+			slog.Error("unable to remove thread pins", slog.String("error", err.Error()))
+		}
+	}
+	if thread.MessageId != nil {
+		if err := e.msg.SetMessageThread(ctx, *thread.MessageId, thread.ParentId, 0); err != nil {
+			slog.Error("unable to unlink message from thread", slog.String("error", err.Error()))
+		}
+	}
+
+	guildId := thread.GuildId
+	evt := &mqmsg.DeleteThread{GuildId: guildId, ChannelType: model.ChannelTypeThread, ChannelId: thread.Id, ParentId: thread.ParentId}
+	go func() {
+		if err := e.mqt.SendGuildUpdate(guildId, evt); err != nil {
+			slog.Error("unable to send thread delete event", slog.String("error", err.Error()))
+		}
+	}()
+	return nil
+}
+
+// removeChannelThreads deletes all threads started in a deleted parent channel
+func (e *entity) removeChannelThreads(ctx context.Context, guildId, parentId int64) error {
+	threads, err := e.thread.GetParentThreads(ctx, guildId, parentId, nil)
+	if err != nil {
+		return err
+	}
+	for i := range threads {
+		// A missing channel row still leaves metadata and members to clean up
+		ch, err := e.ch.GetChannel(ctx, threads[i].Id)
+		if err != nil {
+			ch = model.Channel{Id: threads[i].Id}
+		}
+		if err := e.removeThread(ctx, &ch, &threads[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *entity) sendThreadUpdateEvent(guildId int64, thread dto.Channel) {
+	if err := e.mqt.SendGuildUpdate(guildId, &mqmsg.UpdateThread{GuildId: guildId, Channel: thread}); err != nil {
+		slog.Error("unable to send thread update event", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,109 @@
+package guild
+
+import (
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+)
+
+const (
+	ErrIncorrectThreadID          = "incorrect thread ID"
+	ErrUnableToCreateThread       = "unable to create thread"
+	ErrUnableToGetThreads         = "unable to get threads"
+	ErrUnableToUpdateThread       = "unable to update thread"
+	ErrUnableToDeleteThread       = "unable to delete thread"
+	ErrUnableToGetThreadMembers   = "unable to get thread members"
+	ErrUnableToJoinThread         = "unable to join thread"
+	ErrUnableToLeaveThread        = "unable to leave thread"
+	ErrThreadNotFound             = "thread not found"
+	ErrThreadParentInvalid        = "threads can only be started in text channels"
+	ErrThreadMessageNotFound      = "message not found"
+	ErrThreadAlreadyExists        = "a thread was already started from this message"
+	ErrThreadArchived             = "thread is archived"
+	ErrThreadNameRequired         = "thread name is required"
+	ErrThreadNameTooLong          = "thread name must be at most 100 characters"
+	ErrThreadMessageIdInvalid     = "message ID must be positive"
+	ErrThreadAutoArchiveInvalid   = "auto archive duration must be one of 60, 1440, 4320 or 10080 minutes"
+	ErrThreadUpdateFieldsRequired = "at least one field is required"
+
+	DefaultThreadAutoArchiveDuration = 1440
+)
+
+// threadAutoArchiveDurations lists allowed auto-archive durations in minutes: an hour, a day, three days and a week
+var threadAutoArchiveDurations = []interface{}{60, 1440, 4320, 10080}
+
+type CreateThreadRequest struct {
+	Name                string `json:"name" example:"release-discussion"`        // Thread name
+	MessageId           *int64 `json:"message_id" example:"2230469276416868352"` // Parent channel message to start the thread from. Omit to start a standalone thread.
+	AutoArchiveDuration *int   `json:"auto_archive_duration" example:"1440"`     // Minutes of inactivity before the thread is archived: 60, 1440, 4320 or 10080. Default 1440.
+}
+
+func (r CreateThreadRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Name,
+			validation.By(func(v interface{}) error {
+				if strings.TrimSpace(v.(string)) == "" {
+					return validation.NewError("validation", ErrThreadNameRequired)
+				}
+				return nil
+			}),
+			validation.RuneLength(0, 100).Error(ErrThreadNameTooLong),
+		),
+		validation.Field(&r.MessageId,
+			validation.When(r.MessageId != nil, validation.Required.Error(ErrThreadMessageIdInvalid), validation.Min(int64(1)).Error(ErrThreadMessageIdInvalid)),
+		),
+		validation.Field(&r.AutoArchiveDuration,
+			validation.When(r.AutoArchiveDuration != nil, validation.Required.Error(ErrThreadAutoArchiveInvalid), validation.In(threadAutoArchiveDurations...).Error(ErrThreadAutoArchiveInvalid)),
+		),
+	)
+}
+
+type UpdateThreadRequest struct {
+	Name                *string `json:"name" example:"release-discussion"`    // New thread name
+	Archived            *bool   `json:"archived" example:"true"`              // Archive or reopen the thread
+	AutoArchiveDuration *int    `json:"auto_archive_duration" example:"4320"` // Minutes of inactivity before the thread is archived: 60, 1440, 4320 or 10080
+}
+
+func (r UpdateThreadRequest) Validate() error {
+	if r.Name == nil && r.Archived == nil && r.AutoArchiveDuration == nil {
+		return validation.NewError("validation", ErrThreadUpdateFieldsRequired)
+	}
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Name,
+			validation.When(r.Name != nil,
+				validation.By(func(v interface{}) error {
+					if name, _ := v.(*string); name != nil && strings.TrimSpace(*name) == "" {
+						return validation.NewError("validation", ErrThreadNameRequired)
+					}
+					return nil
+				}),
+				validation.RuneLength(0, 100).Error(ErrThreadNameTooLong),
+			),
+		),
+		validation.Field(&r.AutoArchiveDuration,
+			validation.When(r.AutoArchiveDuration != nil, validation.Required.Error(ErrThreadAutoArchiveInvalid), validation.In(threadAutoArchiveDurations...).Error(ErrThreadAutoArchiveInvalid)),
+		),
+	)
+}
+
+type ListThreadsRequest struct {
+	Archived bool `query:"archived" json:"archived" example:"false"` // Return archived threads instead of active ones
+}
+
+// threadModelToDTO builds a thread channel DTO from the channel row and its thread metadata
+func threadModelToDTO(c *model.Channel, t *model.Thread) dto.Channel {
+	ch := channelModelToDTO(c, &t.GuildId, 0, nil)
+	ch.Thread = dto.NewThreadMetadata(t)
+	return ch
+}
+
+func threadMemberModelToDTO(m model.ThreadMember) dto.ThreadMember {
+	return dto.ThreadMember{
+		ThreadId: m.ThreadId,
+		UserId:   m.UserId,
+		JoinedAt: m.JoinedAt,
+	}
+}
@@ -0,0 +1,168 @@
+package guild
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type fakeThreadRepo struct {
+	threads map[int64]model.Thread
+}
+
+func (f *fakeThreadRepo) CreateThread(ctx context.Context, thread model.Thread) error {
+	f.threads[thread.Id] = thread
+	return nil
+}
+func (f *fakeThreadRepo) GetThread(ctx context.Context, guildID, id int64) (model.Thread, error) {
+	t, ok := f.threads[id]
+	if !ok || t.GuildId != guildID {
+		return model.Thread{}, sql.ErrNoRows
+	}
+	return t, nil
+}
+func (f *fakeThreadRepo) GetParentThreads(ctx context.Context, guildID, parentID int64, archived *bool) ([]model.Thread, error) {
+	return nil, nil
+}
+func (f *fakeThreadRepo) UpdateThread(ctx context.Context, guildID, id int64, archived *bool, autoArchiveMinutes *int) (model.Thread, error) {
+	return f.threads[id], nil
+}
+func (f *fakeThreadRepo) SetLastActivity(ctx context.Context, guildID, id int64, at time.Time) error {
+	return nil
+}
+func (f *fakeThreadRepo) GetInactiveThreads(ctx context.Context, now time.Time, limit int) ([]model.Thread, error) {
+	return nil, nil
+}
+func (f *fakeThreadRepo) ArchiveIfInactive(ctx context.Context, guildID, id int64, now time.Time) (bool, error) {
+	return false, nil
+}
+func (f *fakeThreadRepo) DeleteThread(ctx context.Context, guildID, id int64) error {
+	delete(f.threads, id)
+	return nil
+}
+
+type testThreadMemberKey struct {
+	threadID int64
+	userID   int64
+}
+
+type fakeThreadMemberRepo struct {
+	members map[testThreadMemberKey]bool
+}
+
+func (f *fakeThreadMemberRepo) AddThreadMember(ctx context.Context, threadID, userID int64) error {
+	f.members[testThreadMemberKey{threadID: threadID, userID: userID}] = true
+	return nil
+}
+func (f *fakeThreadMemberRepo) RemoveThreadMember(ctx context.Context, threadID, userID int64) error {
+	delete(f.members, testThreadMemberKey{threadID: threadID, userID: userID})
+	return nil
+}
+func (f *fakeThreadMemberRepo) RemoveThreadMembers(ctx context.Context, threadID int64) error {
+	return nil
+}
+func (f *fakeThreadMemberRepo) GetThreadMembers(ctx context.Context, threadID int64) ([]model.ThreadMember, error) {
+	return nil, nil
+}
+
+func TestLeaveThreadRemovesMembership(t *testing.T) {
+	threads := &fakeThreadRepo{threads: map[int64]model.Thread{5: {Id: 5, GuildId: 1, ParentId: 2, OwnerId: 11}}}
+	members := &fakeThreadMemberRepo{members: map[testThreadMemberKey]bool{{threadID: 5, userID: 10}: true}}
+	e := &entity{thread: threads, tmemb: members, perm: &fakeWebhookPermissionChecker{allowed: true}}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/thread/:thread_id/members/@me", e.LeaveThread)
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/guild/1/thread/5/members/@me", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	if members.members[testThreadMemberKey{threadID: 5, userID: 10}] {
+		t.Fatal("expected membership to be removed")
+	}
+}
+
+func TestLeaveThreadRequiresParentChannelAccess(t *testing.T) {
+	threads := &fakeThreadRepo{threads: map[int64]model.Thread{5: {Id: 5, GuildId: 1, ParentId: 2, OwnerId: 11}}}
+	members := &fakeThreadMemberRepo{members: map[testThreadMemberKey]bool{{threadID: 5, userID: 10}: true}}
+	e := &entity{thread: threads, tmemb: members, perm: &fakeWebhookPermissionChecker{allowed: false}}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/thread/:thread_id/members/@me", e.LeaveThread)
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/guild/1/thread/5/members/@me", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNotAcceptable {
+		t.Fatalf("expected 406, got %d", resp.StatusCode)
+	}
+	if !members.members[testThreadMemberKey{threadID: 5, userID: 10}] {
+		t.Fatal("expected membership to stay")
+	}
+}
+
+func TestLeaveThreadReturnsNotFoundForOtherGuild(t *testing.T) {
+	threads := &fakeThreadRepo{threads: map[int64]model.Thread{5: {Id: 5, GuildId: 3, ParentId: 2}}}
+	e := &entity{thread: threads, tmemb: &fakeThreadMemberRepo{members: map[testThreadMemberKey]bool{}}}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/thread/:thread_id/members/@me", e.LeaveThread)
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/guild/1/thread/5/members/@me", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreateThreadRequestValidate(t *testing.T) {
+	msgId := int64(10)
+	badMsgId := int64(0)
+	duration := 4320
+	badDuration := 30
+
+	cases := []struct {
+		name    string
+		req     CreateThreadRequest
+		wantErr bool
+	}{
+		{name: "name only", req: CreateThreadRequest{Name: "release"}},
+		{name: "from message", req: CreateThreadRequest{Name: "release", MessageId: &msgId, AutoArchiveDuration: &duration}},
+		{name: "blank name", req: CreateThreadRequest{Name: "   "}, wantErr: true},
+		{name: "zero message", req: CreateThreadRequest{Name: "release", MessageId: &badMsgId}, wantErr: true},
+		{name: "unsupported duration", req: CreateThreadRequest{Name: "release", AutoArchiveDuration: &badDuration}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.req.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected validation result: %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdateThreadRequestValidate(t *testing.T) {
+	archived := true
+	blank := " "
+	zero := 0
+
+	if err := (UpdateThreadRequest{}).Validate(); err == nil {
+		t.Fatal("expected error for empty update")
+	}
+	if err := (UpdateThreadRequest{Archived: &archived}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := (UpdateThreadRequest{Name: &blank}).Validate(); err == nil {
+		t.Fatal("expected error for blank name")
+	}
+	if err := (UpdateThreadRequest{AutoArchiveDuration: &zero}).Validate(); err == nil {
+		t.Fatal("expected error for zero duration")
+	}
+}
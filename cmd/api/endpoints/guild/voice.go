@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"math/rand"
 	"net/http"
@@ -72,7 +73,7 @@ func sfuAdminBaseURL(wsURL string) string {
 	return u
 }
 
-// issueAdminJWT signs a short-lived admin JWT for API → SFU close-channel calls.
+// issueAdminJWT signs a short-lived admin JWT for API → SFU control calls.
 func issueAdminJWT(channelID int64, authSecret string) (string, error) {
 	now := time.Now()
 	claims := struct {
@@ -98,21 +99,48 @@ func issueAdminJWT(channelID int64, authSecret string) (string, error) {
 // notifyOldSFUClose fires an async HTTP POST to the old SFU's admin endpoint
 // to close all peer connections for the given channel. Errors are logged only.
 func notifyOldSFUClose(oldSFUURL string, channelID int64, authSecret string, log *slog.Logger) {
-	adminToken, err := issueAdminJWT(channelID, authSecret)
-	if err != nil {
-		log.Error("voice region change: failed to issue admin jwt", slog.String("error", err.Error()))
-		return
+	type closeReq struct {
+		ChannelID int64 `json:"channel_id"`
+	}
+	if err := sendSFUAdminRequest(oldSFUURL, "/admin/channel/close", channelID, closeReq{ChannelID: channelID}, authSecret); err != nil {
+		log.Error("voice region change: admin close request failed", slog.String("error", err.Error()), slog.String("sfu", sfuAdminBaseURL(oldSFUURL)))
 	}
+}
 
-	type closeReq struct {
+// notifySFUTimeout asks the SFU to server-mute a timed out user in the channel until the timeout ends.
+// Zero until lifts the timeout mute. Errors are logged only.
+func notifySFUTimeout(sfuURL string, channelID, userID int64, until time.Time, authSecret string, log *slog.Logger) {
+	type timeoutReq struct {
 		ChannelID int64 `json:"channel_id"`
+		UserID    int64 `json:"user_id"`
+		Until     int64 `json:"until"`
 	}
-	body, _ := json.Marshal(closeReq{ChannelID: channelID})
-	baseURL := sfuAdminBaseURL(oldSFUURL)
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, baseURL+"/admin/channel/close", bytes.NewReader(body))
+	req := timeoutReq{ChannelID: channelID, UserID: userID}
+	if !until.IsZero() {
+		req.Until = until.Unix()
+	}
+	if err := sendSFUAdminRequest(sfuURL, "/admin/channel/timeout", channelID, req, authSecret); err != nil {
+		log.Error("member timeout: admin timeout request failed",
+			slog.String("error", err.Error()),
+			slog.String("sfu", sfuAdminBaseURL(sfuURL)),
+			slog.Int64("channel_id", channelID),
+			slog.Int64("user_id", userID))
+	}
+}
+
+// sendSFUAdminRequest posts a JSON payload to an SFU admin endpoint using an admin JWT scoped to the channel
+func sendSFUAdminRequest(sfuURL, path string, channelID int64, payload any, authSecret string) error {
+	adminToken, err := issueAdminJWT(channelID, authSecret)
 	if err != nil {
-		log.Error("voice region change: failed to build admin request", slog.String("error", err.Error()))
-		return
+		return fmt.Errorf("issue admin jwt: %w", err)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal admin request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, sfuAdminBaseURL(sfuURL)+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build admin request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+adminToken)
@@ -120,13 +148,22 @@ func notifyOldSFUClose(oldSFUURL string, channelID int64, authSecret string, log
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Error("voice region change: admin close request failed", slog.String("error", err.Error()), slog.String("sfu", baseURL))
-		return
+		return err
 	}
 	_ = resp.Body.Close()
 	if resp.StatusCode != http.StatusNoContent {
-		log.Warn("voice region change: admin close unexpected status", slog.Int("status", resp.StatusCode), slog.String("sfu", baseURL))
+		return &sfuAdminError{Status: resp.StatusCode}
 	}
+	return nil
+}
+
+// sfuAdminError is returned by sendSFUAdminRequest when the SFU answers with an unexpected status
+type sfuAdminError struct {
+	Status int
+}
+
+func (e *sfuAdminError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.Status)
 }
 
 // JoinVoice
@@ -136,9 +173,11 @@ func notifyOldSFUClose(oldSFUURL string, channelID int64, authSecret string, log
 //	@Tags			Guild
 //	@Param			guild_id	path		int64	true	"Guild ID"
 //	@Param			channel_id	path		int64	true	"Channel ID"
+//	@Param			region		query		string	false	"Voice region nearest to the user, picks an edge SFU when cascading is enabled"
 //	@Success		200			{object}	JoinVoiceResponse
+//	@failure		400			{string}	string	"Unknown region"
 //	@failure		401			{string}	string	"Unauthorized"
-//	@failure		403			{string}	string	"Forbidden"
+//	@failure		403			{string}	string	"Forbidden, member is timed out or pending, or the channel is full"
 //	@failure		503			{string}	string	"No SFU available in region"
 //	@Router			/guild/{guild_id}/voice/{channel_id}/join [post]
 func (e *entity) JoinVoice(c *fiber.Ctx) error {
@@ -162,17 +201,26 @@ func (e *entity) JoinVoice(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
 	if !ok {
+		if err := e.checkMemberPending(c.UserContext(), guildId, user.Id); err != nil {
+			return err
+		}
 		return fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
 	}
 	if ch == nil || ch.Type != model.ChannelTypeGuildVoice {
 		return fiber.NewError(fiber.StatusBadRequest, ErrNotAVoiceChannel)
 	}
+	if err := helper.CheckMemberTimeout(c.UserContext(), e.memb, guildId, user.Id); err != nil {
+		return err
+	}
 
 	// Build voice permission bitmask
 	vperm, err := e.perm.GetChannelPermissions(c.UserContext(), guildId, channelId, user.Id)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
+	if err := e.checkVoiceUserLimit(c.UserContext(), ch, user.Id, vperm); err != nil {
+		return err
+	}
 
 	// Try per-channel route in cache: if exists, reuse its URL.
 	// Key format: voice:route:<channelId> with JSON {"id":"...","url":"...","region":"..."}
@@ -210,6 +258,20 @@ func (e *entity) JoinVoice(c *fiber.Ctx) error {
 		}
 	}
 
+	// With cascading, users from another region join an edge SFU near them that relays the channel from the bound SFU
+	sfuURL, relayURL := chosen.URL, ""
+	if e.voiceCascade {
+		region := strings.TrimSpace(c.Query("region"))
+		if region != "" && len(e.allowedRegions) > 0 {
+			if _, ok := e.allowedRegions[region]; !ok {
+				return fiber.NewError(fiber.StatusBadRequest, "unknown region")
+			}
+		}
+		if edge, ok := e.pickEdgeSFU(c.UserContext(), channelId, region, chosen); ok {
+			sfuURL, relayURL = edge.URL, chosen.URL
+		}
+	}
+
 	// Issue a short-lived SFU token. Extend to 5 minutes during an active region migration.
 	tokenTTL := 2 * time.Minute
 	if e.cache != nil {
@@ -221,9 +283,11 @@ func (e *entity) JoinVoice(c *fiber.Ctx) error {
 	now := time.Now()
 	sfuClaims := struct {
 		helper.Claims
+		voiceChannelSettings
 		ChannelID int64  `json:"channel_id"`
 		GuildID   *int64 `json:"guild_id,omitempty"`
 		Perms     int64  `json:"perms"`
+		Relay     string `json:"relay,omitempty"` // signal URL of the bound SFU the edge SFU relays the channel from
 	}{
 		Claims: helper.Claims{
 			UserID:    user.Id,
@@ -235,9 +299,11 @@ func (e *entity) JoinVoice(c *fiber.Ctx) error {
 				ExpiresAt: jwt.NewNumericDate(now.Add(tokenTTL)),
 			},
 		},
-		ChannelID: channelId,
-		GuildID:   &guildId,
-		Perms:     vperm,
+		voiceChannelSettings: voiceSettingsOf(ch),
+		ChannelID:            channelId,
+		GuildID:              &guildId,
+		Perms:                vperm,
+		Relay:                relayURL,
 	}
 
 	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, sfuClaims)
@@ -246,7 +312,72 @@ func (e *entity) JoinVoice(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToIssueVoiceToken)
 	}
 
-	return c.JSON(JoinVoiceResponse{SFUURL: chosen.URL, SFUToken: signed})
+	return c.JSON(JoinVoiceResponse{SFUURL: sfuURL, SFUToken: signed})
+}
+
+// pickEdgeSFU picks the SFU a user from another region than the bound SFU's joins on a cascaded channel.
+// Users of the same region share one edge per channel, bound via SetNX like the channel binding.
+// Returns false when the user's region is the bound one or has no other SFU.
+func (e *entity) pickEdgeSFU(ctx context.Context, channelId int64, region string, primary voiceRouteBinding) (voiceRouteBinding, bool) {
+	if region == "" || region == primary.Region || e.disco == nil {
+		return voiceRouteBinding{}, false
+	}
+	var edge voiceRouteBinding
+	if e.cache != nil {
+		if err := e.cache.GetJSON(ctx, edgeBindingKey(channelId, region), &edge); err == nil && edge.URL != "" && edge.URL != primary.URL {
+			_ = e.cache.SetTTL(ctx, edgeBindingKey(channelId, region), 60)
+			return edge, true
+		}
+	}
+	list, err := e.disco.List(ctx, region)
+	if err != nil || len(list) == 0 {
+		return voiceRouteBinding{}, false
+	}
+	pickedID, pickedURL := pickSFU(list)
+	if pickedURL == "" || pickedURL == primary.URL {
+		return voiceRouteBinding{}, false
+	}
+	edge = voiceRouteBinding{ID: pickedID, URL: pickedURL, Region: region}
+	if e.cache != nil {
+		set, _ := e.cache.SetTimedJSONNX(ctx, edgeBindingKey(channelId, region), edge, 60)
+		if !set {
+			var winner voiceRouteBinding
+			if err := e.cache.GetJSON(ctx, edgeBindingKey(channelId, region), &winner); err == nil && winner.URL != "" {
+				edge = winner
+			}
+		}
+	}
+	return edge, true
+}
+
+// voiceChannelSettings are the per-channel voice settings the SFU reads from the join token
+type voiceChannelSettings struct {
+	UserLimit int  `json:"user_limit,omitempty"`
+	Bitrate   int  `json:"bitrate,omitempty"`
+	Stage     bool `json:"stage,omitempty"`
+}
+
+func voiceSettingsOf(ch *model.Channel) voiceChannelSettings {
+	return voiceChannelSettings{UserLimit: ch.VoiceUserLimit, Bitrate: ch.VoiceBitrate, Stage: ch.VoiceStage}
+}
+
+// checkVoiceUserLimit rejects the join when the channel is full. Users already connected
+// and members who can move members are let through. The SFU checks the limit again on connect.
+func (e *entity) checkVoiceUserLimit(ctx context.Context, ch *model.Channel, userId, perms int64) error {
+	if ch.VoiceUserLimit <= 0 || e.cache == nil || permissions.CheckPermissions(perms, permissions.PermVoiceMoveMembers) {
+		return nil
+	}
+	sessions, err := e.cache.HGetAll(ctx, sessionHashKey(ch.Id))
+	if err != nil {
+		return nil
+	}
+	if _, ok := sessions[fmtInt64(userId)]; ok {
+		return nil
+	}
+	if len(sessions) >= ch.VoiceUserLimit {
+		return fiber.NewError(fiber.StatusForbidden, ErrVoiceChannelFull)
+	}
+	return nil
 }
 
 func bindingKey(ch int64) string      { return "voice:route:" + fmtInt64(ch) }
@@ -254,6 +385,10 @@ func rebindMarkerKey(ch int64) string { return "voice:rebind:" + fmtInt64(ch) }
 func sessionHashKey(ch int64) string  { return "voice:clients:" + fmtInt64(ch) }
 func fmtInt64(v int64) string         { return strconv.FormatInt(v, 10) }
 
+func edgeBindingKey(ch int64, region string) string {
+	return "voice:edge:" + fmtInt64(ch) + ":" + region
+}
+
 // MoveMember
 //
 //	@Summary		Move member to voice channel
@@ -330,6 +465,7 @@ func (e *entity) MoveMember(c *fiber.Ctx) error {
 	now := time.Now()
 	moveClaims := struct {
 		helper.Claims
+		voiceChannelSettings
 		ChannelID int64 `json:"channel_id"`
 		Perms     int64 `json:"perms"`
 		Moved     bool  `json:"moved"`
@@ -344,9 +480,10 @@ func (e *entity) MoveMember(c *fiber.Ctx) error {
 				ExpiresAt: jwt.NewNumericDate(now.Add(2 * time.Minute)),
 			},
 		},
-		ChannelID: body.ChannelID,
-		Perms:     vperm,
-		Moved:     true,
+		voiceChannelSettings: voiceSettingsOf(&ch),
+		ChannelID:            body.ChannelID,
+		Perms:                vperm,
+		Moved:                true,
 	}
 	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, moveClaims)
 	signed, err := tok.SignedString([]byte(e.authSecret))
@@ -407,6 +544,7 @@ func (e *entity) MoveMember(c *fiber.Ctx) error {
 	now2 := time.Now()
 	adminClaims := struct {
 		helper.Claims
+		voiceChannelSettings
 		ChannelID int64 `json:"channel_id"`
 		Perms     int64 `json:"perms"`
 	}{
@@ -420,8 +558,9 @@ func (e *entity) MoveMember(c *fiber.Ctx) error {
 				ExpiresAt: jwt.NewNumericDate(now2.Add(2 * time.Minute)),
 			},
 		},
-		ChannelID: body.From,
-		Perms:     adminPerms,
+		voiceChannelSettings: voiceSettingsOf(&chFrom),
+		ChannelID:            body.From,
+		Perms:                adminPerms,
 	}
 	atok := jwt.NewWithClaims(jwt.SigningMethodHS256, adminClaims)
 	adminSigned, err := atok.SignedString([]byte(e.authSecret))
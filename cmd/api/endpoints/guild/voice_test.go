@@ -0,0 +1,107 @@
+package guild
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+	"github.com/FlameInTheDark/gochat/internal/voice/discovery"
+)
+
+func TestCheckVoiceUserLimit(t *testing.T) {
+	e := &entity{cache: &fakeCache{hashes: map[string]map[string]string{
+		sessionHashKey(2): {"10": "true", "11": "true"},
+	}}}
+	ch := &model.Channel{Id: 2, Type: model.ChannelTypeGuildVoice, VoiceUserLimit: 2}
+	ctx := context.Background()
+
+	var ferr *fiber.Error
+	if err := e.checkVoiceUserLimit(ctx, ch, 12, 0); !errors.As(err, &ferr) || ferr.Code != fiber.StatusForbidden || ferr.Message != ErrVoiceChannelFull {
+		t.Fatalf("expected the channel to be full, got %v", err)
+	}
+	if err := e.checkVoiceUserLimit(ctx, ch, 10, 0); err != nil {
+		t.Fatalf("expected a connected user to rejoin, got %v", err)
+	}
+	if err := e.checkVoiceUserLimit(ctx, ch, 12, int64(permissions.PermVoiceMoveMembers)); err != nil {
+		t.Fatalf("expected move members to bypass the limit, got %v", err)
+	}
+	ch.VoiceUserLimit = 3
+	if err := e.checkVoiceUserLimit(ctx, ch, 12, 0); err != nil {
+		t.Fatalf("expected a free slot, got %v", err)
+	}
+}
+
+func TestPatchGuildChannelRequestVoiceSettings(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	cases := []struct {
+		name string
+		req  PatchGuildChannelRequest
+		ok   bool
+	}{
+		{"no settings", PatchGuildChannelRequest{}, true},
+		{"unlimited", PatchGuildChannelRequest{UserLimit: intPtr(0), Bitrate: intPtr(0)}, true},
+		{"limits", PatchGuildChannelRequest{UserLimit: intPtr(99), Bitrate: intPtr(384)}, true},
+		{"user limit too high", PatchGuildChannelRequest{UserLimit: intPtr(100)}, false},
+		{"negative user limit", PatchGuildChannelRequest{UserLimit: intPtr(-1)}, false},
+		{"bitrate too low", PatchGuildChannelRequest{Bitrate: intPtr(4)}, false},
+		{"bitrate too high", PatchGuildChannelRequest{Bitrate: intPtr(512)}, false},
+	}
+	for _, tc := range cases {
+		if err := tc.req.Validate(); (err == nil) != tc.ok {
+			t.Errorf("%s: unexpected validation result %v", tc.name, err)
+		}
+	}
+}
+
+type fakeDiscovery struct {
+	instances map[string][]discovery.Instance
+}
+
+func (f *fakeDiscovery) Register(ctx context.Context, region string, inst discovery.Instance) error {
+	return nil
+}
+
+func (f *fakeDiscovery) List(ctx context.Context, region string) ([]discovery.Instance, error) {
+	return f.instances[region], nil
+}
+
+func (f *fakeDiscovery) Regions(ctx context.Context) ([]string, error) { return nil, nil }
+
+func TestPickEdgeSFU(t *testing.T) {
+	cache := &fakeCache{}
+	e := &entity{cache: cache, disco: &fakeDiscovery{instances: map[string][]discovery.Instance{
+		"eu": {{ID: "eu-1", Region: "eu", URL: "wss://eu-1/signal"}},
+		"us": {{ID: "us-1", Region: "us", URL: "wss://us-1/signal"}},
+	}}}
+	primary := voiceRouteBinding{ID: "eu-1", URL: "wss://eu-1/signal", Region: "eu"}
+	ctx := context.Background()
+
+	if _, ok := e.pickEdgeSFU(ctx, 2, "", primary); ok {
+		t.Fatal("expected no edge without a user region")
+	}
+	if _, ok := e.pickEdgeSFU(ctx, 2, "eu", primary); ok {
+		t.Fatal("expected no edge in the bound region")
+	}
+	if _, ok := e.pickEdgeSFU(ctx, 2, "asia", primary); ok {
+		t.Fatal("expected no edge in a region without SFUs")
+	}
+	edge, ok := e.pickEdgeSFU(ctx, 2, "us", primary)
+	if !ok || edge.URL != "wss://us-1/signal" || edge.Region != "us" {
+		t.Fatalf("expected the us edge, got %+v", edge)
+	}
+	if _, bound := cache.jsonValues[edgeBindingKey(2, "us")]; !bound {
+		t.Fatal("expected the edge to be bound to the channel")
+	}
+
+	// Later joins reuse the bound edge
+	e.disco = &fakeDiscovery{instances: map[string][]discovery.Instance{
+		"us": {{ID: "us-2", Region: "us", URL: "wss://us-2/signal"}},
+	}}
+	if edge, ok := e.pickEdgeSFU(ctx, 2, "us", primary); !ok || edge.ID != "us-1" {
+		t.Fatalf("expected the bound edge to be reused, got %+v", edge)
+	}
+}
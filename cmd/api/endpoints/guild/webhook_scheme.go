@@ -0,0 +1,63 @@
+package guild
+
+import (
+	"fmt"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+)
+
+const (
+	ErrUnableToGetWebhooks    = "unable to get webhooks"
+	ErrUnableToCreateWebhook  = "unable to create webhook"
+	ErrUnableToUpdateWebhook  = "unable to update webhook"
+	ErrUnableToDeleteWebhook  = "unable to delete webhook"
+	ErrWebhookNotFound        = "webhook not found"
+	ErrIncorrectWebhookID     = "incorrect webhook ID"
+	ErrWebhookLimitReached    = "channel webhook limit reached"
+	ErrWebhookChannelInvalid  = "webhooks can be created only in text channels"
+	ErrWebhookNameLength      = "webhook name must be between 1 and 32 characters"
+	ErrWebhookAvatarURLFormat = "avatar url must be a valid URL"
+
+	// MaxChannelWebhooks is the maximum number of webhooks in a channel
+	MaxChannelWebhooks = 10
+	// webhookTokenLength is the length of the secret part of the webhook URL
+	webhookTokenLength = 64
+)
+
+type CreateWebhookRequest struct {
+	Name      string  `json:"name" example:"CI"`                                             // Default author name of the messages
+	AvatarURL *string `json:"avatar_url,omitempty" example:"https://example.com/avatar.png"` // Default author avatar of the messages
+}
+
+func (r CreateWebhookRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Name,
+			validation.Required.Error(ErrWebhookNameLength),
+			validation.RuneLength(1, 32).Error(ErrWebhookNameLength),
+		),
+		validation.Field(&r.AvatarURL,
+			validation.When(r.AvatarURL != nil, validation.Required.Error(ErrWebhookAvatarURLFormat), is.RequestURL.Error(ErrWebhookAvatarURLFormat)),
+		),
+	)
+}
+
+func webhookModelToDTO(w model.Webhook) dto.Webhook {
+	return dto.Webhook{
+		Id:        w.Id,
+		GuildId:   w.GuildId,
+		ChannelId: w.ChannelId,
+		CreatorId: w.CreatorId,
+		Name:      w.Name,
+		AvatarURL: w.AvatarURL,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+// webhookURL returns the execution URL of the webhook, the route is served by the message endpoints
+func webhookURL(baseURL string, webhookId int64, token string) string {
+	return fmt.Sprintf("%s/api/v1/message/webhook/%d/%s", baseURL, webhookId, token)
+}
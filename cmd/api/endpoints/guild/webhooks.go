@@ -0,0 +1,242 @@
+package guild
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+// CreateWebhook
+//
+//	@Summary		Create channel webhook
+//	@Description	Creates an incoming webhook that posts messages into the channel. The token and the execution URL are returned only once. Requires PermManageWebhooks in the channel.
+//	@Tags			Guild
+//	@Accept			json
+//	@Produce		json
+//	@Param			guild_id	path		int64					true	"Guild ID"		example(2230469276416868352)
+//	@Param			channel_id	path		int64					true	"Channel ID"	example(2230469276416868352)
+//	@Param			request		body		CreateWebhookRequest	true	"Webhook data"
+//	@Success		200			{object}	dto.Webhook				"Webhook with token"
+//	@failure		400			{string}	string					"Incorrect request body"
+//	@failure		406			{string}	string					"Permissions required"
+//	@failure		409			{string}	string					"Webhook limit reached"
+//	@failure		500			{string}	string					"Something bad happened"
+//	@Router			/guild/{guild_id}/channel/{channel_id}/webhooks [post]
+func (e *entity) CreateWebhook(c *fiber.Ctx) error {
+	guildId, channelId, user, err := e.parseWebhookChannel(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := e.authorizeWebhookChannel(c.UserContext(), guildId, channelId, user.Id); err != nil {
+		return err
+	}
+
+	existing, err := e.wh.GetChannelWebhooks(c.UserContext(), channelId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetWebhooks)
+	}
+	if len(existing) >= MaxChannelWebhooks {
+		return fiber.NewError(fiber.StatusConflict, ErrWebhookLimitReached)
+	}
+
+	token, err := helper.RandomToken(webhookTokenLength)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateWebhook)
+	}
+	webhook := model.Webhook{
+		Id:        idgen.Next(),
+		GuildId:   guildId,
+		ChannelId: channelId,
+		CreatorId: user.Id,
+		Name:      req.Name,
+		AvatarURL: req.AvatarURL,
+		TokenHash: helper.HashToken(token),
+	}
+	if err := e.wh.CreateWebhook(c.UserContext(), webhook); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateWebhook)
+	}
+	e.recordAudit(c.UserContext(), guildId, user.Id, model.AuditActionWebhookCreate, webhook.Id, auditChange(nil, "name", nil, webhook.Name), nil)
+
+	created, err := e.wh.GetWebhook(c.UserContext(), webhook.Id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetWebhooks)
+	}
+	return c.JSON(webhookWithToken(c, created, token))
+}
+
+// GetWebhooks
+//
+//	@Summary	List channel webhooks
+//	@Produce	json
+//	@Tags		Guild
+//	@Param		guild_id	path		int64			true	"Guild ID"		example(2230469276416868352)
+//	@Param		channel_id	path		int64			true	"Channel ID"	example(2230469276416868352)
+//	@Success	200			{array}		dto.Webhook		"Webhooks without tokens"
+//	@failure	400			{string}	string			"Incorrect request"
+//	@failure	406			{string}	string			"Permissions required"
+//	@failure	500			{string}	string			"Something bad happened"
+//	@Router		/guild/{guild_id}/channel/{channel_id}/webhooks [get]
+func (e *entity) GetWebhooks(c *fiber.Ctx) error {
+	guildId, channelId, user, err := e.parseWebhookChannel(c)
+	if err != nil {
+		return err
+	}
+	if err := e.authorizeWebhookChannel(c.UserContext(), guildId, channelId, user.Id); err != nil {
+		return err
+	}
+
+	webhooks, err := e.wh.GetChannelWebhooks(c.UserContext(), channelId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetWebhooks)
+	}
+	result := make([]dto.Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		result = append(result, webhookModelToDTO(w))
+	}
+	return c.JSON(result)
+}
+
+// RotateWebhookToken
+//
+//	@Summary		Rotate webhook token
+//	@Description	Replaces the token of the webhook. The previous execution URL stops working immediately.
+//	@Tags			Guild
+//	@Produce		json
+//	@Param			guild_id	path		int64		true	"Guild ID"		example(2230469276416868352)
+//	@Param			channel_id	path		int64		true	"Channel ID"	example(2230469276416868352)
+//	@Param			webhook_id	path		int64		true	"Webhook ID"	example(2230469276416868352)
+//	@Success		200			{object}	dto.Webhook	"Webhook with the new token"
+//	@failure		400			{string}	string		"Incorrect request"
+//	@failure		404			{string}	string		"Webhook not found"
+//	@failure		406			{string}	string		"Permissions required"
+//	@failure		500			{string}	string		"Something bad happened"
+//	@Router			/guild/{guild_id}/channel/{channel_id}/webhooks/{webhook_id}/token [post]
+func (e *entity) RotateWebhookToken(c *fiber.Ctx) error {
+	webhook, user, err := e.getChannelWebhook(c)
+	if err != nil {
+		return err
+	}
+
+	token, err := helper.RandomToken(webhookTokenLength)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateWebhook)
+	}
+	if err := e.wh.SetTokenHash(c.UserContext(), webhook.Id, helper.HashToken(token)); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUpdateWebhook)
+	}
+	e.recordAudit(c.UserContext(), webhook.GuildId, user.Id, model.AuditActionWebhookUpdate, webhook.Id, []model.AuditChange{{Key: "token"}}, nil)
+
+	return c.JSON(webhookWithToken(c, webhook, token))
+}
+
+// DeleteWebhook
+//
+//	@Summary	Delete webhook
+//	@Produce	json
+//	@Tags		Guild
+//	@Param		guild_id	path		int64	true	"Guild ID"		example(2230469276416868352)
+//	@Param		channel_id	path		int64	true	"Channel ID"	example(2230469276416868352)
+//	@Param		webhook_id	path		int64	true	"Webhook ID"	example(2230469276416868352)
+//	@Success	200			{string}	string	"Deleted"
+//	@failure	400			{string}	string	"Incorrect request"
+//	@failure	404			{string}	string	"Webhook not found"
+//	@failure	406			{string}	string	"Permissions required"
+//	@failure	500			{string}	string	"Something bad happened"
+//	@Router		/guild/{guild_id}/channel/{channel_id}/webhooks/{webhook_id} [delete]
+func (e *entity) DeleteWebhook(c *fiber.Ctx) error {
+	webhook, user, err := e.getChannelWebhook(c)
+	if err != nil {
+		return err
+	}
+
+	if err := e.wh.DeleteWebhook(c.UserContext(), webhook.Id); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToDeleteWebhook)
+	}
+	e.recordAudit(c.UserContext(), webhook.GuildId, user.Id, model.AuditActionWebhookDelete, webhook.Id, auditChange(nil, "name", webhook.Name, nil), nil)
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// parseWebhookChannel extracts the guild and channel IDs and the user of the request
+func (e *entity) parseWebhookChannel(c *fiber.Ctx) (int64, int64, *helper.JWTUser, error) {
+	guildId, err := e.parseGuildID(c)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	channelId, err := e.parseChannelID(c)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return 0, 0, nil, fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+	return guildId, channelId, user, nil
+}
+
+// authorizeWebhookChannel checks that the channel is a text channel of the guild and the user can manage its webhooks
+func (e *entity) authorizeWebhookChannel(ctx context.Context, guildId, channelId, userId int64) error {
+	channel, _, _, ok, err := e.perm.ChannelPerm(ctx, guildId, channelId, userId, permissions.PermManageWebhooks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fiber.NewError(fiber.StatusNotFound, ErrUnableToGetChannel)
+	} else if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetPermission)
+	}
+	if !ok {
+		return fiber.NewError(fiber.StatusNotAcceptable, ErrPermissionsRequired)
+	}
+	if channel.Type != model.ChannelTypeGuild {
+		return fiber.NewError(fiber.StatusBadRequest, ErrWebhookChannelInvalid)
+	}
+	return nil
+}
+
+// getChannelWebhook returns the webhook from the URL after checking that it belongs to the channel and the user can manage it
+func (e *entity) getChannelWebhook(c *fiber.Ctx) (model.Webhook, *helper.JWTUser, error) {
+	guildId, channelId, user, err := e.parseWebhookChannel(c)
+	if err != nil {
+		return model.Webhook{}, nil, err
+	}
+	webhookId, err := strconv.ParseInt(c.Params("webhook_id"), 10, 64)
+	if err != nil {
+		return model.Webhook{}, nil, fiber.NewError(fiber.StatusBadRequest, ErrIncorrectWebhookID)
+	}
+	if err := e.authorizeWebhookChannel(c.UserContext(), guildId, channelId, user.Id); err != nil {
+		return model.Webhook{}, nil, err
+	}
+
+	webhook, err := e.wh.GetWebhook(c.UserContext(), webhookId)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && (webhook.GuildId != guildId || webhook.ChannelId != channelId) {
+		return model.Webhook{}, nil, fiber.NewError(fiber.StatusNotFound, ErrWebhookNotFound)
+	} else if err != nil {
+		return model.Webhook{}, nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetWebhooks)
+	}
+	return webhook, user, nil
+}
+
+// webhookWithToken returns the webhook DTO with the token and the execution URL
+func webhookWithToken(c *fiber.Ctx, webhook model.Webhook, token string) dto.Webhook {
+	result := webhookModelToDTO(webhook)
+	result.Token = token
+	baseURL, _ := c.Locals("base_url").(string)
+	result.URL = webhookURL(baseURL, webhook.Id, token)
+	return result
+}
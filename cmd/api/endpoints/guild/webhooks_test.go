@@ -0,0 +1,179 @@
+package guild
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+type fakeWebhookRepo struct {
+	webhooks map[int64]model.Webhook
+}
+
+func (f *fakeWebhookRepo) CreateWebhook(ctx context.Context, webhook model.Webhook) error {
+	f.webhooks[webhook.Id] = webhook
+	return nil
+}
+
+func (f *fakeWebhookRepo) GetWebhook(ctx context.Context, id int64) (model.Webhook, error) {
+	webhook, ok := f.webhooks[id]
+	if !ok {
+		return model.Webhook{}, sql.ErrNoRows
+	}
+	return webhook, nil
+}
+
+func (f *fakeWebhookRepo) GetChannelWebhooks(ctx context.Context, channelId int64) ([]model.Webhook, error) {
+	var out []model.Webhook
+	for _, w := range f.webhooks {
+		if w.ChannelId == channelId {
+			out = append(out, w)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeWebhookRepo) SetTokenHash(ctx context.Context, id int64, tokenHash string) error {
+	webhook := f.webhooks[id]
+	webhook.TokenHash = tokenHash
+	f.webhooks[id] = webhook
+	return nil
+}
+
+func (f *fakeWebhookRepo) DeleteWebhook(ctx context.Context, id int64) error {
+	delete(f.webhooks, id)
+	return nil
+}
+
+func (f *fakeWebhookRepo) DeleteChannelWebhooks(ctx context.Context, channelId int64) error {
+	for id, w := range f.webhooks {
+		if w.ChannelId == channelId {
+			delete(f.webhooks, id)
+		}
+	}
+	return nil
+}
+
+type fakeWebhookPermissionChecker struct {
+	fakePermissionChecker
+	allowed bool
+}
+
+func (f *fakeWebhookPermissionChecker) ChannelPerm(ctx context.Context, guildID, channelID, userID int64, perm ...permissions.RolePermission) (*model.Channel, *model.GuildChannel, *model.Guild, bool, error) {
+	return &model.Channel{Id: channelID, Type: model.ChannelTypeGuild}, nil, &model.Guild{Id: guildID}, f.allowed, nil
+}
+
+func TestCreateWebhookReturnsTokenOnce(t *testing.T) {
+	repo := &fakeWebhookRepo{webhooks: map[int64]model.Webhook{}}
+	e := &entity{wh: repo, perm: &fakeWebhookPermissionChecker{allowed: true}}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/channel/:channel_id/webhooks", func(c *fiber.Ctx) error {
+		c.Locals("base_url", "https://chat.example")
+		if c.Method() == fiber.MethodGet {
+			return e.GetWebhooks(c)
+		}
+		return e.CreateWebhook(c)
+	})
+
+	req := httptest.NewRequest(fiber.MethodPost, "/guild/1/channel/2/webhooks", strings.NewReader(`{"name":"deploys"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var created dto.Webhook
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Token == "" || !strings.HasSuffix(created.URL, created.Token) || !strings.HasPrefix(created.URL, "https://chat.example/api/v1/message/webhook/") {
+		t.Fatalf("unexpected webhook %+v", created)
+	}
+	if stored := repo.webhooks[created.Id]; stored.TokenHash != helper.HashToken(created.Token) || stored.ChannelId != 2 {
+		t.Fatalf("unexpected stored webhook %+v", stored)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/guild/1/channel/2/webhooks", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []dto.Webhook
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Token != "" || list[0].URL != "" {
+		t.Fatalf("expected webhook list without token, got %+v", list)
+	}
+}
+
+func TestCreateWebhookRequiresPermission(t *testing.T) {
+	repo := &fakeWebhookRepo{webhooks: map[int64]model.Webhook{}}
+	e := &entity{wh: repo, perm: &fakeWebhookPermissionChecker{}}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/channel/:channel_id/webhooks", e.CreateWebhook)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/guild/1/channel/2/webhooks", strings.NewReader(`{"name":"deploys"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotAcceptable {
+		t.Fatalf("expected status 406, got %d", resp.StatusCode)
+	}
+	if len(repo.webhooks) != 0 {
+		t.Fatal("expected no webhook to be created")
+	}
+}
+
+func TestRotateWebhookTokenReplacesHash(t *testing.T) {
+	repo := &fakeWebhookRepo{webhooks: map[int64]model.Webhook{
+		5: {Id: 5, GuildId: 1, ChannelId: 2, Name: "deploys", TokenHash: helper.HashToken("old")},
+	}}
+	e := &entity{wh: repo, perm: &fakeWebhookPermissionChecker{allowed: true}}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/channel/:channel_id/webhooks/:webhook_id/token", e.RotateWebhookToken)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/guild/1/channel/2/webhooks/5/token", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var rotated dto.Webhook
+	if err := json.NewDecoder(resp.Body).Decode(&rotated); err != nil {
+		t.Fatal(err)
+	}
+	if rotated.Token == "" || repo.webhooks[5].TokenHash != helper.HashToken(rotated.Token) {
+		t.Fatalf("expected stored hash to match the new token, got %+v", repo.webhooks[5])
+	}
+}
+
+func TestDeleteWebhookFromOtherChannelNotFound(t *testing.T) {
+	repo := &fakeWebhookRepo{webhooks: map[int64]model.Webhook{
+		5: {Id: 5, GuildId: 1, ChannelId: 3, Name: "deploys"},
+	}}
+	e := &entity{wh: repo, perm: &fakeWebhookPermissionChecker{allowed: true}}
+	app := newGuildTestApp(t, 10, "/guild/:guild_id/channel/:channel_id/webhooks/:webhook_id", e.DeleteWebhook)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodDelete, "/guild/1/channel/2/webhooks/5", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", resp.StatusCode)
+	}
+	if _, ok := repo.webhooks[5]; !ok {
+		t.Fatal("expected webhook of another channel to be kept")
+	}
+}
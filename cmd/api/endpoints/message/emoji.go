@@ -14,6 +14,20 @@ import (
 var customEmojiTagRegex = regexp.MustCompile(`<:([A-Za-z0-9-]+):([0-9]+)>`)
 
 func (e *entity) sanitizeEmojiContent(ctx context.Context, userID int64, content string) (string, error) {
+	return e.sanitizeEmojis(ctx, content, func(guildId int64) (bool, error) {
+		return e.m.IsGuildMember(ctx, guildId, userID)
+	})
+}
+
+// sanitizeWebhookEmojiContent keeps only the custom emoji of the guild the webhook belongs to
+func (e *entity) sanitizeWebhookEmojiContent(ctx context.Context, guildID int64, content string) (string, error) {
+	return e.sanitizeEmojis(ctx, content, func(emojiGuildId int64) (bool, error) {
+		return emojiGuildId == guildID, nil
+	})
+}
+
+// sanitizeEmojis replaces custom emoji tags with their names unless the emoji exists and its guild is allowed
+func (e *entity) sanitizeEmojis(ctx context.Context, content string, allowed func(guildId int64) (bool, error)) (string, error) {
 	if content == "" {
 		return content, nil
 	}
@@ -39,7 +53,7 @@ func (e *entity) sanitizeEmojiContent(ctx context.Context, userID int64, content
 			return ":" + originalName + ":"
 		}
 
-		ok, err := e.m.IsGuildMember(ctx, lookup.GuildId, userID)
+		ok, err := allowed(lookup.GuildId)
 		if err != nil {
 			firstErr = err
 			return match
@@ -13,8 +13,11 @@ import (
 	"github.com/FlameInTheDark/gochat/internal/database/entities/guildchannelmessages"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/mention"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/message"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/pin"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/reaction"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/readstates"
 	"github.com/FlameInTheDark/gochat/internal/database/pgdb"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/blocked"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/channel"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/channelroleperm"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/channeluserperm"
@@ -28,12 +31,16 @@ import (
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/member"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/role"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/rolecheck"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/thread"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/threadmember"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/user"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/userrole"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/webhook"
 	"github.com/FlameInTheDark/gochat/internal/embedmq"
 	"github.com/FlameInTheDark/gochat/internal/indexmq"
 	"github.com/FlameInTheDark/gochat/internal/mq"
 	"github.com/FlameInTheDark/gochat/internal/server"
+	"github.com/FlameInTheDark/gochat/internal/userblock"
 )
 
 const entityName = "message"
@@ -46,6 +53,12 @@ func (e *entity) Init(router fiber.Router) {
 	router.Get("/channel/:channel_id<int>", e.GetMessages)
 	router.Post("/channel/:channel_id<int>/:message_id<int>/ack", e.SetReadState)
 	router.Post("/channel/:channel_id<int>/typing", e.Typing)
+	router.Put("/channel/:channel_id<int>/:message_id<int>/reactions/:emoji", e.AddReaction)
+	router.Delete("/channel/:channel_id<int>/:message_id<int>/reactions/:emoji", e.RemoveReaction)
+	router.Get("/channel/:channel_id<int>/pins", e.GetPins)
+	router.Put("/channel/:channel_id<int>/:message_id<int>/pin", e.PinMessage)
+	router.Delete("/channel/:channel_id<int>/:message_id<int>/pin", e.UnpinMessage)
+	router.Post("/webhook/:webhook_id<int>/:token", e.ExecuteWebhook)
 }
 
 type embedQueue interface {
@@ -72,6 +85,8 @@ type entity struct {
 	dmc     dmchannel.DmChannel
 	gdmc    groupdmchannel.GroupDMChannel
 	msg     message.Message
+	react   reaction.Reaction
+	pin     pin.Pin
 	at      attachment.Attachment
 	perm    rolecheck.RoleCheck
 	uperm   channeluserperm.ChannelUserPerm
@@ -83,8 +98,12 @@ type entity struct {
 	av      avatar.Avatar
 	mention mention.Mention
 	fr      friend.Friend
+	blocks  *userblock.Checker
 	emoji   emojirepo.Emoji
 	ban     banned.Banned
+	thread  thread.Thread
+	tmemb   threadmember.ThreadMember
+	wh      webhook.Webhook
 }
 
 func (e *entity) Name() string {
@@ -111,6 +130,8 @@ func New(cql *db.CQLCon, pg *pgdb.DB, t mq.SendTransporter, imq *indexmq.IndexMQ
 		g:           guild.New(pg.Conn()),
 		gc:          guildchannels.New(pg.Conn()),
 		msg:         message.New(cql),
+		react:       reaction.New(cql),
+		pin:         pin.New(cql),
 		at:          attachment.New(cql),
 		perm:        rolecheck.New(pg),
 		uperm:       channeluserperm.New(pg.Conn()),
@@ -121,7 +142,11 @@ func New(cql *db.CQLCon, pg *pgdb.DB, t mq.SendTransporter, imq *indexmq.IndexMQ
 		gclm:        guildchannelmessages.New(cql),
 		mention:     mention.New(cql),
 		fr:          friend.New(pg.Conn()),
+		blocks:      userblock.NewChecker(blocked.New(pg.Conn()), cache),
 		emoji:       emojirepo.New(pg.Conn()),
 		ban:         banned.New(cql),
+		thread:      thread.New(pg.Conn()),
+		tmemb:       threadmember.New(pg.Conn()),
+		wh:          webhook.New(pg.Conn()),
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 	"github.com/FlameInTheDark/gochat/internal/helper"
 	"github.com/FlameInTheDark/gochat/internal/idgen"
 	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+	"github.com/FlameInTheDark/gochat/internal/msgsearch"
 	"github.com/FlameInTheDark/gochat/internal/permissions"
 )
 
@@ -36,7 +38,7 @@ const (
 //	@Success	200			{object}	dto.Message			"Message"
 //	@failure	400			{string}	string				"Bad request"
 //	@failure	401			{string}	string				"Unauthorized"
-//	@failure	403			{string}	string				"Forbidden"
+//	@failure	403			{string}	string				"Forbidden, member is timed out or user is blocked"
 //	@failure	500			{string}	string				"Internal server error"
 //	@Router		/message/channel/{channel_id} [post]
 func (e *entity) Send(c *fiber.Ctx) error {
@@ -52,6 +54,25 @@ func (e *entity) Send(c *fiber.Ctx) error {
 		return err
 	}
 
+	if channel.Type == model.ChannelTypeThread {
+		if err := e.reopenThread(c.UserContext(), *guildId, channel); err != nil {
+			return err
+		}
+	}
+	if channel.Type == model.ChannelTypeDM {
+		if err := e.validateDMNotBlocked(c.UserContext(), channel.Id, user.Id); err != nil {
+			return err
+		}
+	}
+
+	var reference *model.Message
+	if req.MessageReference != nil {
+		reference, err = e.validateMessageReference(c.UserContext(), channel.Id, guildId, user.Id, *req.MessageReference)
+		if err != nil {
+			return err
+		}
+	}
+
 	validatedAttachments, err := e.validateMessageAttachments(c.UserContext(), channel.Id, user.Id, []int64(req.Attachments))
 	if err != nil {
 		return err
@@ -61,16 +82,26 @@ func (e *entity) Send(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSendMessage)
 	}
+	// Fetch user data concurrently
+	userData, err := e.fetchUserDataForMessage(c, user.Id)
+	if err != nil {
+		return err
+	}
+
 	// Create and send message
-	message, err := e.createAndSendMessage(c, req, user, channel, guildId, validatedAttachments)
+	message, err := e.createAndSendMessage(c, req, userData, channel, guildId, reference, validatedAttachments)
 	if err != nil {
 		return err
 	}
 
-	if HasURL(message.Content) {
+	if msgsearch.HasURL(message.Content) {
 		go e.enqueueMakeEmbed(guildId, message)
 	}
 
+	if channel.Type == model.ChannelTypeThread {
+		go e.recordThreadActivity(*guildId, channel.Id, user.Id)
+	}
+
 	if err := e.rs.SetReadState(c.UserContext(), user.Id, channelId, message.Id); err != nil {
 		e.log.Error("unable to set read state after message sent", slog.String("error", err.Error()))
 	}
@@ -115,6 +146,31 @@ func (e *entity) validateSendPermissions(c *fiber.Ctx, channelId, userId int64)
 		return nil, nil, fiber.NewError(fiber.StatusBadRequest, ErrUnableToSentToThisChannel)
 	}
 
+	// Threads are always guild channels and use their own send permission
+	if channel.Type == model.ChannelTypeThread {
+		guildChannel, err := e.gc.GetGuildByChannel(c.UserContext(), channelId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, nil, fiber.NewError(fiber.StatusNotFound, "channel not found")
+			}
+			return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to get guild channel")
+		}
+		_, _, _, canSend, err := e.perm.ChannelPerm(c.UserContext(), guildChannel.GuildId, guildChannel.ChannelId, userId, permissions.PermTextSendMessageInThreads)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to check permissions")
+		}
+		if !canSend {
+			if err := e.checkMemberPending(c.UserContext(), guildChannel.GuildId, userId); err != nil {
+				return nil, nil, err
+			}
+			return nil, nil, fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
+		}
+		if err := helper.CheckMemberTimeout(c.UserContext(), e.m, guildChannel.GuildId, userId); err != nil {
+			return nil, nil, err
+		}
+		return &channel, &guildChannel.GuildId, nil
+	}
+
 	// Check guild permissions if it's a guild channel
 	if channel.Type == model.ChannelTypeGuild {
 		guildChannel, err := e.gc.GetGuildByChannel(c.UserContext(), channelId)
@@ -128,31 +184,78 @@ func (e *entity) validateSendPermissions(c *fiber.Ctx, channelId, userId int64)
 				return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to check permissions")
 			}
 			if !canSend {
+				if err := e.checkMemberPending(c.UserContext(), guildChannel.GuildId, userId); err != nil {
+					return nil, nil, err
+				}
 				return nil, nil, fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
 			}
+			if err := helper.CheckMemberTimeout(c.UserContext(), e.m, guildChannel.GuildId, userId); err != nil {
+				return nil, nil, err
+			}
 			return &channel, &guildChannel.GuildId, nil
 		}
 	}
 
+	if err := e.validatePrivateChannelAccess(c.UserContext(), &channel, userId); err != nil {
+		return nil, nil, err
+	}
 	return &channel, nil, nil
 }
 
-// createAndSendMessage creates the message and handles all related operations
-func (e *entity) createAndSendMessage(c *fiber.Ctx, req *SendMessageRequest, jwtUser *helper.JWTUser, channel *model.Channel, guildId *int64, validatedAttachments []model.Attachment) (dto.Message, error) {
-	// Fetch user data concurrently
-	userData, err := e.fetchUserDataForMessage(c, jwtUser.Id)
+// validatePrivateChannelAccess checks that the user is a participant of the DM or group DM channel
+func (e *entity) validatePrivateChannelAccess(ctx context.Context, channel *model.Channel, userId int64) error {
+	var ok bool
+	var err error
+	switch channel.Type {
+	case model.ChannelTypeDM:
+		ok, err = e.dmc.IsDmChannelParticipant(ctx, channel.Id, userId)
+	case model.ChannelTypeGroupDM:
+		ok, err = e.gdmc.IsGroupDmParticipant(ctx, channel.Id, userId)
+	default:
+		return nil
+	}
 	if err != nil {
-		return dto.Message{}, err
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to check permissions")
+	}
+	if !ok {
+		return fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
+	}
+	return nil
+}
+
+// validateDMNotBlocked rejects messages between DM participants when one of them blocked the other
+func (e *entity) validateDMNotBlocked(ctx context.Context, channelId, userId int64) error {
+	participants, err := e.dmc.GetDmChannelByChannelId(ctx, channelId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to get dm channel")
 	}
+	for _, p := range participants {
+		for _, id := range []int64{p.UserId, p.ParticipantId} {
+			if id == userId {
+				continue
+			}
+			blocked, err := e.blocks.BlockedEither(ctx, userId, id)
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "failed to check blocked users")
+			}
+			if blocked {
+				return fiber.NewError(fiber.StatusForbidden, ErrUserBlocked)
+			}
+		}
+	}
+	return nil
+}
 
+// createAndSendMessage creates the message and handles all related operations
+func (e *entity) createAndSendMessage(c *fiber.Ctx, req *SendMessageRequest, userData *messageUserData, channel *model.Channel, guildId *int64, reference *model.Message, validatedAttachments []model.Attachment) (dto.Message, error) {
 	// Create message with transaction-like behavior
 	messageId := idgen.Next()
-	if err := e.createMessageWithCleanup(c, messageId, channel.Id, jwtUser.Id, req); err != nil {
+	if err := e.createMessageWithCleanup(c, messageId, channel.Id, userData, req); err != nil {
 		return dto.Message{}, err
 	}
 
 	// Build response message
-	message, err := e.buildMessageResponse(c, messageId, channel, userData, req, validatedAttachments)
+	message, err := e.buildMessageResponse(c, messageId, channel, guildId, userData, req, reference, validatedAttachments)
 	if err != nil {
 		// Cleanup on failure
 		_ = e.msg.DeleteMessage(c.UserContext(), messageId, channel.Id)
@@ -166,16 +269,25 @@ func (e *entity) createAndSendMessage(c *fiber.Ctx, req *SendMessageRequest, jwt
 		}
 	}
 
+	// Mentions, the replied author is mentioned unless the sender opted out
+	users, roles, everyone, here := MentionsExtractor(req.Content)
+	users = replyMentions(users, req, reference, userData.User.Id)
+	req.Mentions = replyMentions(req.Mentions, req, reference, userData.User.Id)
+
 	// Send events (non-blocking)
 	go e.sendMessageEvents(channel.Id, guildId, message, userData, req)
 
-	// Mentions
-	users, roles, everyone, here := MentionsExtractor(req.Content)
 	if users != nil || roles != nil || everyone || here {
 		go func() {
 			for _, u := range users {
+				// Users are not notified about mentions from users they blocked
+				if blocked, err := e.blocks.Blocked(context.Background(), u, message.Author.Id); err != nil {
+					e.log.Error("unable to check blocked users", slog.String("error", err.Error()))
+				} else if blocked {
+					continue
+				}
 				switch channel.Type {
-				case model.ChannelTypeGuild:
+				case model.ChannelTypeGuild, model.ChannelTypeThread:
 					if guildId != nil {
 						if ok, err := e.m.IsGuildMember(context.Background(), *guildId, u); err == nil && ok {
 							if err := e.mention.AddMention(context.Background(), u, channel.Id, messageId, message.Author.Id); err != nil {
@@ -314,10 +426,14 @@ func (e *entity) fetchUserDataForMessage(c *fiber.Ctx, userId int64) (*messageUs
 type messageUserData struct {
 	User          *model.User
 	Discriminator *model.Discriminator
+
+	// Webhook is set for messages posted by a webhook, User then holds the webhook ID and the author name
+	Webhook   bool
+	AvatarURL *string
 }
 
 // createMessageWithCleanup creates message and updates channel with proper error handling
-func (e *entity) createMessageWithCleanup(c *fiber.Ctx, messageId, channelId, userId int64, req *SendMessageRequest) error {
+func (e *entity) createMessageWithCleanup(c *fiber.Ctx, messageId, channelId int64, userData *messageUserData, req *SendMessageRequest) error {
 	manualEmbedsJSON, err := embed.MarshalEmbeds(req.Embeds)
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
@@ -327,8 +443,18 @@ func (e *entity) createMessageWithCleanup(c *fiber.Ctx, messageId, channelId, us
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	var reference int64
+	if req.MessageReference != nil {
+		reference = *req.MessageReference
+	}
+
 	// Create the message
-	if err := e.msg.CreateMessage(c.UserContext(), messageId, channelId, userId, req.Content, []int64(req.Attachments), manualEmbedsJSON, autoEmbedsJSON); err != nil {
+	if userData.Webhook {
+		err = e.msg.CreateWebhookMessage(c.UserContext(), messageId, channelId, userData.User.Id, reference, req.Content, manualEmbedsJSON, autoEmbedsJSON, userData.User.Name, userData.AvatarURL)
+	} else {
+		err = e.msg.CreateMessage(c.UserContext(), messageId, channelId, userData.User.Id, reference, req.Content, []int64(req.Attachments), manualEmbedsJSON, autoEmbedsJSON)
+	}
+	if err != nil {
 		return helper.HttpDbError(err, ErrUnableToSendMessage)
 	}
 
@@ -416,7 +542,7 @@ func (e *entity) buildAttachmentDTOs(attachmentIds []int64, attachments []model.
 }
 
 // buildMessageResponse constructs the message response DTO
-func (e *entity) buildMessageResponse(c *fiber.Ctx, messageId int64, channel *model.Channel, userData *messageUserData, req *SendMessageRequest, validatedAttachments []model.Attachment) (dto.Message, error) {
+func (e *entity) buildMessageResponse(c *fiber.Ctx, messageId int64, channel *model.Channel, guildId *int64, userData *messageUserData, req *SendMessageRequest, reference *model.Message, validatedAttachments []model.Attachment) (dto.Message, error) {
 	attachments := e.buildAttachmentDTOs([]int64(req.Attachments), validatedAttachments)
 
 	// Build author with avatar data if present
@@ -424,23 +550,41 @@ func (e *entity) buildMessageResponse(c *fiber.Ctx, messageId int64, channel *mo
 		Id:            userData.User.Id,
 		Name:          userData.User.Name,
 		Discriminator: userData.Discriminator.Discriminator,
+		Bot:           userData.User.Bot,
 	}
-	if userData.User.Avatar != nil {
+	flags := botAuthorFlag(author)
+	if userData.Webhook {
+		author = webhookAuthor(userData.User.Id, userData.User.Name, userData.AvatarURL)
+		flags = model.MessageFlagWebhook
+	} else if userData.User.Avatar != nil {
 		if ad, err := e.getAvatarDataCached(c.UserContext(), userData.User.Id, *userData.User.Avatar); err == nil && ad != nil {
 			author.Avatar = ad
 		}
 	}
 
-	return dto.Message{
+	message := dto.Message{
 		Id:          messageId,
 		ChannelId:   channel.Id,
 		Author:      author,
 		Content:     req.Content,
 		Attachments: attachments,
 		Embeds:      req.Embeds,
-		Flags:       0,
+		Flags:       flags,
 		Type:        int(model.MessageTypeChat),
-	}, nil
+	}
+	if reference != nil {
+		referenceId := reference.Id
+		message.Type = int(model.MessageTypeReply)
+		message.MessageReference = &referenceId
+		message.ReferencedMessage = e.buildSentReference(c.UserContext(), guildId, reference)
+		if guildId != nil {
+			if err := e.redactBannedReferences(c.UserContext(), *guildId, []dto.Message{message}); err != nil {
+				e.log.Error("unable to apply banned reference visibility", slog.String("error", err.Error()))
+			}
+		}
+	}
+
+	return message, nil
 }
 
 func (e *entity) parseMessageEmbeds(messageId int64, raw *string) []embed.Embed {
@@ -509,12 +653,12 @@ func (e *entity) sendMessageEvents(channelId int64, guildId *int64, message dto.
 
 	// Send indexing event
 	var hasTypes []string
-	if HasURL(req.Content) {
+	if msgsearch.HasURL(req.Content) {
 		hasTypes = append(hasTypes, "url")
 	}
 	for _, attachment := range message.Attachments {
 		if attachment.ContentType != nil {
-			hasTypes = append(hasTypes, GetAttachmentType(*attachment.ContentType))
+			hasTypes = append(hasTypes, msgsearch.GetAttachmentType(*attachment.ContentType))
 		}
 	}
 
@@ -524,8 +668,8 @@ func (e *entity) sendMessageEvents(channelId int64, guildId *int64, message dto.
 		ChannelId: channelId,
 		GuildId:   guildId,
 		Mentions:  req.Mentions,
-		Has:       UniqueAttachmentTypes(hasTypes),
-		Type:      int(model.MessageTypeChat),
+		Has:       msgsearch.UniqueAttachmentTypes(hasTypes),
+		Type:      message.Type,
 		Content:   message.Content,
 	}); err != nil {
 		e.log.Error("failed to send index message event",
@@ -533,36 +677,47 @@ func (e *entity) sendMessageEvents(channelId int64, guildId *int64, message dto.
 			"error", err.Error())
 	}
 
-	// Notify DM recipient via user topic for 1:1 DMs
+	// Notify DM recipients via user topic
 	if guildId == nil {
 		// Determine channel type and DM participants
+		var recipients []int64
 		ch, err := e.ch.GetChannel(context.Background(), channelId)
 		if err == nil && ch.Type == model.ChannelTypeDM {
 			// Fetch both rows for this DM channel and pick the other user
 			rows, rerr := e.dmc.GetDmChannelByChannelId(context.Background(), channelId)
 			if rerr == nil {
-				var recipientId int64
 				for _, r := range rows {
 					if r.UserId != message.Author.Id {
-						recipientId = r.UserId
+						recipients = append(recipients, r.UserId)
 						break
 					}
 				}
-				if recipientId != 0 {
-					var ad *dto.AvatarData
-					if userData.User.Avatar != nil {
-						if v, err := e.getAvatarDataCached(context.Background(), userData.User.Id, *userData.User.Avatar); err == nil {
-							ad = v
-						}
+			}
+		} else if err == nil && ch.Type == model.ChannelTypeGroupDM {
+			rows, rerr := e.gdmc.GetGroupDmParticipants(context.Background(), channelId)
+			if rerr == nil {
+				for _, r := range rows {
+					if r.UserId != message.Author.Id {
+						recipients = append(recipients, r.UserId)
 					}
-					_ = e.mqt.SendUserUpdate(recipientId, &mqmsg.DMMessage{
-						ChannelId: channelId,
-						MessageId: message.Id,
-						From:      mqmsg.UserBrief{Id: userData.User.Id, Name: userData.User.Name, Discriminator: userData.Discriminator.Discriminator, Avatar: userData.User.Avatar, AvatarData: ad},
-					})
 				}
 			}
 		}
+		if len(recipients) > 0 {
+			var ad *dto.AvatarData
+			if userData.User.Avatar != nil {
+				if v, err := e.getAvatarDataCached(context.Background(), userData.User.Id, *userData.User.Avatar); err == nil {
+					ad = v
+				}
+			}
+			for _, recipientId := range recipients {
+				_ = e.mqt.SendUserUpdate(recipientId, &mqmsg.DMMessage{
+					ChannelId: channelId,
+					MessageId: message.Id,
+					From:      mqmsg.UserBrief{Id: userData.User.Id, Name: userData.User.Name, Discriminator: userData.Discriminator.Discriminator, Avatar: userData.User.Avatar, AvatarData: ad},
+				})
+			}
+		}
 	}
 }
 
@@ -630,7 +785,7 @@ func (e *entity) GetMessages(c *fiber.Ctx) error {
 	}
 
 	// Fetch and build messages
-	messages, err := e.fetchAndBuildMessages(c, req, channel, guildId)
+	messages, err := e.fetchAndBuildMessages(c, req, channel, guildId, user.Id)
 	if err != nil {
 		return err
 	}
@@ -690,8 +845,8 @@ func (e *entity) validateReadPermissions(c *fiber.Ctx, channelId, userId int64)
 		return nil, nil, fiber.NewError(fiber.StatusBadRequest, ErrUnableToReadFromThisChannel)
 	}
 
-	// Check guild permissions
-	if channel.Type == model.ChannelTypeGuild {
+	// Check guild permissions, threads inherit them from the parent channel
+	if channel.Type == model.ChannelTypeGuild || channel.Type == model.ChannelTypeThread {
 		guildChannel, err := e.gc.GetGuildByChannel(c.UserContext(), channelId)
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
 			return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to get guild channel")
@@ -716,12 +871,14 @@ func (e *entity) validateReadPermissions(c *fiber.Ctx, channelId, userId int64)
 		}
 	}
 
-	// TODO: Implement DM and GroupDM permission checks
+	if err := e.validatePrivateChannelAccess(c.UserContext(), &channel, userId); err != nil {
+		return nil, nil, err
+	}
 	return &channel, nil, nil
 }
 
 // fetchAndBuildMessages fetches messages and builds DTOs with all related data
-func (e *entity) fetchAndBuildMessages(c *fiber.Ctx, req *GetMessagesRequest, channel *model.Channel, guildId *int64) ([]dto.Message, error) {
+func (e *entity) fetchAndBuildMessages(c *fiber.Ctx, req *GetMessagesRequest, channel *model.Channel, guildId *int64, viewerId int64) ([]dto.Message, error) {
 	// Fetch raw messages
 	rawMessages, userIds, err := e.fetchRawMessages(c, req, channel)
 	if err != nil {
@@ -732,18 +889,44 @@ func (e *entity) fetchAndBuildMessages(c *fiber.Ctx, req *GetMessagesRequest, ch
 		return []dto.Message{}, nil
 	}
 
+	return e.buildMessageList(c, rawMessages, userIds, channel, guildId, viewerId)
+}
+
+// buildMessageList builds DTOs for the raw channel messages with authors, attachments, replies and reactions
+func (e *entity) buildMessageList(c *fiber.Ctx, rawMessages []model.Message, userIds []int64, channel *model.Channel, guildId *int64, viewerId int64) ([]dto.Message, error) {
+	// Referenced messages are loaded first so their authors are fetched with the rest of the users
+	references, referenceAuthors, err := e.fetchReferencedMessages(c.UserContext(), channel.Id, rawMessages)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to fetch referenced messages")
+	}
+	for _, id := range referenceAuthors {
+		if !slices.Contains(userIds, id) {
+			userIds = append(userIds, id)
+		}
+	}
+
 	// Fetch all related data concurrently
 	messageData, err := e.fetchMessageRelatedData(c, rawMessages, userIds, guildId)
 	if err != nil {
 		return nil, err
 	}
+	messageData.References = references
 
 	// Build message DTOs with memory optimization
 	messages := e.buildMessageDTOsOptimized(rawMessages, messageData)
+	if err := e.applyMessageReactions(c.UserContext(), messages, messageData.Reactions, viewerId); err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to fetch reactions")
+	}
+	if err := e.markBlockedAuthors(c.UserContext(), viewerId, messages); err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to apply blocked user visibility")
+	}
 	if guildId != nil {
 		if err := e.redactBannedMessages(c.UserContext(), *guildId, rawMessages, messages); err != nil {
 			return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to apply banned message visibility")
 		}
+		if err := e.redactBannedReferences(c.UserContext(), *guildId, messages); err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to apply banned message visibility")
+		}
 	}
 
 	return messages, nil
@@ -787,9 +970,11 @@ type messageRelatedData struct {
 	Members     map[int64]*model.Member
 	Attachments map[int64]*model.Attachment
 	AvData      map[int64]*dto.AvatarData
+	Reactions   map[int64][]model.ReactionCount
+	References  map[int64]*model.Message
 }
 
-// fetchMessageRelatedData fetches users, members, and attachments concurrently
+// fetchMessageRelatedData fetches users, members, attachments, and reactions concurrently
 func (e *entity) fetchMessageRelatedData(c *fiber.Ctx, messages []model.Message, userIds []int64, guildId *int64) (*messageRelatedData, error) {
 	type usersResult struct {
 		users []model.User
@@ -803,10 +988,15 @@ func (e *entity) fetchMessageRelatedData(c *fiber.Ctx, messages []model.Message,
 		attachments []model.Attachment
 		err         error
 	}
+	type reactionsResult struct {
+		reactions map[int64][]model.ReactionCount
+		err       error
+	}
 
 	usersCh := make(chan usersResult, 1)
 	membersCh := make(chan membersResult, 1)
 	attachmentsCh := make(chan attachmentsResult, 1)
+	reactionsCh := make(chan reactionsResult, 1)
 
 	// Fetch users
 	go func() {
@@ -835,10 +1025,21 @@ func (e *entity) fetchMessageRelatedData(c *fiber.Ctx, messages []model.Message,
 		}
 	}()
 
+	// Fetch reactions
+	go func() {
+		messageIds := make([]int64, len(messages))
+		for i, message := range messages {
+			messageIds[i] = message.Id
+		}
+		reactions, err := e.react.GetReactionCounts(c.UserContext(), messageIds)
+		reactionsCh <- reactionsResult{reactions, err}
+	}()
+
 	// Collect results
 	usersRes := <-usersCh
 	membersRes := <-membersCh
 	attachmentsRes := <-attachmentsCh
+	reactionsRes := <-reactionsCh
 
 	// Check for errors
 	if usersRes.err != nil {
@@ -850,6 +1051,9 @@ func (e *entity) fetchMessageRelatedData(c *fiber.Ctx, messages []model.Message,
 	if attachmentsRes.err != nil {
 		return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to fetch attachments")
 	}
+	if reactionsRes.err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to fetch reactions")
+	}
 
 	// Build maps
 	data := &messageRelatedData{
@@ -857,6 +1061,7 @@ func (e *entity) fetchMessageRelatedData(c *fiber.Ctx, messages []model.Message,
 		Members:     make(map[int64]*model.Member),
 		Attachments: make(map[int64]*model.Attachment),
 		AvData:      make(map[int64]*dto.AvatarData),
+		Reactions:   reactionsRes.reactions,
 	}
 
 	for i := range usersRes.users {
@@ -951,7 +1156,7 @@ func (e *entity) Update(c *fiber.Ctx) error {
 	contentChanged := req.Content != nil && *req.Content != message.Content
 	suppressIsEnabled := model.HasMessageFlag(updatedMessage.Flags, model.MessageFlagSuppressEmbeds)
 	suppressLifted := suppressWasEnabled && !suppressIsEnabled
-	if !suppressIsEnabled && HasURL(updatedMessage.Content) && (contentChanged || suppressLifted) {
+	if !suppressIsEnabled && msgsearch.HasURL(updatedMessage.Content) && (contentChanged || suppressLifted) {
 		go e.enqueueMakeEmbed(guildId, updatedMessage)
 	}
 
@@ -1038,7 +1243,7 @@ func (e *entity) updateMessageAndBuildResponse(c *fiber.Ctx, req *UpdateMessageR
 
 	updatedFlags := model.NormalizeMessageFlags(message.Flags)
 	if req.Flags != nil {
-		updatedFlags = *req.Flags
+		updatedFlags = mergeUpdatedFlags(updatedFlags, *req.Flags)
 	}
 
 	contentChanged := req.Content != nil && *req.Content != message.Content
@@ -1084,6 +1289,7 @@ func (e *entity) updateMessageAndBuildResponse(c *fiber.Ctx, req *UpdateMessageR
 		Id:            userData.User.Id,
 		Name:          userData.DisplayName,
 		Discriminator: userData.Discriminator.Discriminator,
+		Bot:           userData.User.Bot,
 	}
 	if userData.Avatar != nil {
 		if ad, err := e.getAvatarDataCached(c.UserContext(), userData.User.Id, *userData.Avatar); err == nil && ad != nil {
@@ -1107,7 +1313,7 @@ func (e *entity) updateMessageAndBuildResponse(c *fiber.Ctx, req *UpdateMessageR
 		Content:     updatedContent,
 		Attachments: e.buildAttachmentDTOs(message.Attachments, validatedAttachments),
 		Embeds:      responseEmbeds,
-		Flags:       updatedFlags,
+		Flags:       updatedFlags | botAuthorFlag(author),
 		Type:        message.Type,
 		UpdatedAt:   &updatedAt,
 	}, nil
@@ -1212,12 +1418,12 @@ func (e *entity) sendUpdateEvent(channelId int64, guildId *int64, message dto.Me
 	}
 
 	var hasTypes []string
-	if HasURL(message.Content) {
+	if msgsearch.HasURL(message.Content) {
 		hasTypes = append(hasTypes, "url")
 	}
 	for _, attachment := range message.Attachments {
 		if attachment.ContentType != nil {
-			hasTypes = append(hasTypes, GetAttachmentType(*attachment.ContentType))
+			hasTypes = append(hasTypes, msgsearch.GetAttachmentType(*attachment.ContentType))
 		}
 	}
 
@@ -1227,7 +1433,7 @@ func (e *entity) sendUpdateEvent(channelId int64, guildId *int64, message dto.Me
 		ChannelId: channelId,
 		GuildId:   guildId,
 		Mentions:  nil,
-		Has:       UniqueAttachmentTypes(hasTypes),
+		Has:       msgsearch.UniqueAttachmentTypes(hasTypes),
 		Content:   message.Content,
 	}); err != nil {
 		e.log.Error("failed to send update message event",
@@ -1258,13 +1464,13 @@ func (e *entity) Delete(c *fiber.Ctx) error {
 	}
 
 	// Validate message ownership
-	message, err := e.validateDeletePermission(c, messageId, channelId, user.Id)
+	message, guildId, err := e.validateMessageOwnership(c, messageId, channelId, user.Id)
 	if err != nil {
 		return err
 	}
 
 	// Delete message and send event
-	if err := e.deleteMessageAndNotify(c, message); err != nil {
+	if err := e.deleteMessageAndNotify(c, message, guildId); err != nil {
 		return err
 	}
 
@@ -1293,39 +1499,40 @@ func (e *entity) parseDeleteMessageRequest(c *fiber.Ctx) (*helper.JWTUser, int64
 	return user, channelId, messageId, nil
 }
 
-// validateDeletePermission checks if user can delete the message
-func (e *entity) validateDeletePermission(c *fiber.Ctx, messageId, channelId, userId int64) (*model.Message, error) {
-	message, err := e.msg.GetMessage(c.UserContext(), messageId, channelId)
-	if err != nil {
-		if errors.Is(err, gocql.ErrNotFound) {
-			return nil, fiber.NewError(fiber.StatusNotFound, "message not found")
-		}
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to get message")
-	}
-
-	if message.UserId != userId {
-		return nil, fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
-	}
-
-	return &message, nil
-}
-
 // deleteMessageAndNotify deletes the message and sends notification event
-func (e *entity) deleteMessageAndNotify(c *fiber.Ctx, message *model.Message) error {
+func (e *entity) deleteMessageAndNotify(c *fiber.Ctx, message *model.Message, guildId *int64) error {
 	// Delete the message
 	if err := e.msg.DeleteMessage(c.UserContext(), message.Id, message.ChannelId); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to delete message")
 	}
 
+	if err := e.react.RemoveMessageReactions(c.UserContext(), message.Id); err != nil {
+		e.log.Error("failed to remove message reactions",
+			"message_id", message.Id,
+			"error", err.Error())
+	}
+
+	if model.HasMessageFlag(model.NormalizeMessageFlags(message.Flags), model.MessageFlagPinned) && e.pin != nil {
+		removed, err := e.pin.UnpinMessage(c.UserContext(), message.ChannelId, message.Id)
+		if err != nil {
+			e.log.Error("failed to remove message pin",
+				"message_id", message.Id,
+				"error", err.Error())
+		} else if removed {
+			go e.sendPinsUpdate(message.ChannelId, guildId, message.Id, message.UserId, false)
+		}
+	}
+
 	// Send delete event asynchronously
-	go e.sendDeleteEvent(message.ChannelId, message.Id)
+	go e.sendDeleteEvent(message.ChannelId, guildId, message.Id)
 
 	return nil
 }
 
 // sendDeleteEvent sends the message delete event asynchronously
-func (e *entity) sendDeleteEvent(channelId, messageId int64) {
+func (e *entity) sendDeleteEvent(channelId int64, guildId *int64, messageId int64) {
 	if err := e.mqt.SendChannelMessage(channelId, &mqmsg.DeleteMessage{
+		GuildId:   guildId,
 		MessageId: messageId,
 		ChannelId: channelId,
 	}); err != nil {
@@ -1438,7 +1645,7 @@ func (e *entity) validateUploadPermissions(c *fiber.Ctx, channelId, userId int64
 	}
 
 	switch channel.Type {
-	case model.ChannelTypeGuild:
+	case model.ChannelTypeGuild, model.ChannelTypeThread:
 		guildChannel, err := e.gc.GetGuildByChannel(c.UserContext(), channelId)
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
 			return fiber.NewError(fiber.StatusInternalServerError, "failed to get guild channel")
@@ -1453,12 +1660,8 @@ func (e *entity) validateUploadPermissions(c *fiber.Ctx, channelId, userId int64
 				return fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
 			}
 		}
-	case model.ChannelTypeGroupDM:
-		// Check if user is participant in group DM
-		if channel.ParentID != nil && *channel.ParentID != userId {
-			// TODO: Implement proper group DM participant check
-			return fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
-		}
+	case model.ChannelTypeDM, model.ChannelTypeGroupDM:
+		return e.validatePrivateChannelAccess(c.UserContext(), &channel, userId)
 	}
 
 	return nil
@@ -1490,22 +1693,56 @@ func (e *entity) buildMessageDTOsOptimized(messages []model.Message, data *messa
 
 	for i, message := range messages {
 		flags := model.NormalizeMessageFlags(message.Flags)
+		author := e.messageAuthor(&message, data)
 		result[i] = dto.Message{
 			Id:          message.Id,
 			ChannelId:   message.ChannelId,
-			Author:      e.buildAuthorOptimized(message.UserId, data),
+			Author:      author,
 			Content:     message.Content,
 			Attachments: e.buildAttachmentsOptimized(message.Attachments, data),
 			Embeds:      e.mergedMessageEmbeds(message.Id, message.EmbedsJSON, message.AutoEmbedsJSON, flags),
-			Flags:       flags,
+			Flags:       flags | botAuthorFlag(author),
 			UpdatedAt:   message.EditedAt,
 			Type:        message.Type,
 		}
+		if message.Thread != 0 {
+			thread := message.Thread
+			result[i].ThreadId = &thread
+		}
+		if message.Reference != 0 {
+			reference := message.Reference
+			result[i].MessageReference = &reference
+			result[i].ReferencedMessage = e.referencedMessageDTO(reference, message.ChannelId, data)
+		}
 	}
 
 	return result
 }
 
+// messageAuthor constructs the author DTO of the message, webhook authors are taken from the message itself
+func (e *entity) messageAuthor(message *model.Message, data *messageRelatedData) dto.User {
+	if model.HasMessageFlag(model.NormalizeMessageFlags(message.Flags), model.MessageFlagWebhook) {
+		var name string
+		if message.AuthorName != nil {
+			name = *message.AuthorName
+		}
+		return webhookAuthor(message.UserId, name, message.AuthorAvatar)
+	}
+	return e.buildAuthorOptimized(message.UserId, data)
+}
+
+// webhookAuthor constructs the author DTO of a webhook message
+func webhookAuthor(webhookId int64, name string, avatarURL *string) dto.User {
+	author := dto.User{
+		Id:   webhookId,
+		Name: name,
+	}
+	if avatarURL != nil {
+		author.Avatar = &dto.AvatarData{URL: *avatarURL}
+	}
+	return author
+}
+
 // buildAuthorOptimized constructs author DTO with member override if available
 func (e *entity) buildAuthorOptimized(userId int64, data *messageRelatedData) dto.User {
 	user, userExists := data.Users[userId]
@@ -1519,6 +1756,7 @@ func (e *entity) buildAuthorOptimized(userId int64, data *messageRelatedData) dt
 	author := dto.User{
 		Id:   userId,
 		Name: user.Name,
+		Bot:  user.Bot,
 	}
 	if ad, ok := data.AvData[userId]; ok {
 		author.Avatar = ad
@@ -1538,6 +1776,14 @@ func (e *entity) buildAuthorOptimized(userId int64, data *messageRelatedData) dt
 	return author
 }
 
+// botAuthorFlag returns the bot author marker for messages of bot users
+func botAuthorFlag(author dto.User) int {
+	if author.Bot {
+		return model.MessageFlagBotAuthor
+	}
+	return 0
+}
+
 // buildAttachmentsOptimized constructs attachment DTOs efficiently
 func (e *entity) buildAttachmentsOptimized(attachmentIds []int64, data *messageRelatedData) []dto.Attachment {
 	if len(attachmentIds) == 0 {
@@ -1628,6 +1874,7 @@ func (e *entity) SetReadState(c *fiber.Ctx) error {
 //	@Param		channel_id	path		int64	true	"Channel id"
 //	@Success	200			{string}	string	"typing status sent"
 //	@failure	400			{string}	string	"Bad request"
+//	@failure	403			{string}	string	"Forbidden or member is timed out"
 //	@failure	500			{string}	string	"Internal server error"
 //	@Router		/message/channel/{channel_id}/typing [post]
 func (e *entity) Typing(c *fiber.Ctx) error {
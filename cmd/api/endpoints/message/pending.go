@@ -0,0 +1,21 @@
+package message
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// checkMemberPending returns 403 if the member hasn't passed the guild screening:
+// the rules are not accepted or the verification level requirements are not met.
+// The permission checks already limit pending members, it is called only after they fail to explain why.
+func (e *entity) checkMemberPending(ctx context.Context, guildId, userId int64) error {
+	pending, err := e.perm.MemberPending(ctx, guildId, userId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetMember)
+	}
+	if pending {
+		return fiber.NewError(fiber.StatusForbidden, ErrMemberPending)
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+// PinMessage
+//
+//	@Summary		Pin message
+//	@Description	Pins the message in the channel and posts a pin system message. Guild channels require PermTextManageMessages.
+//	@Produce		json
+//	@Tags			Message
+//	@Param			channel_id	path		int64	true	"Channel id"
+//	@Param			message_id	path		int64	true	"Message id"
+//	@Success		200			{string}	string	"OK"
+//	@failure		400			{string}	string	"Bad request or pin limit reached"
+//	@failure		403			{string}	string	"Forbidden or member is timed out"
+//	@failure		404			{string}	string	"Not found"
+//	@failure		500			{string}	string	"Internal server error"
+//	@Router			/message/channel/{channel_id}/{message_id}/pin [put]
+func (e *entity) PinMessage(c *fiber.Ctx) error {
+	user, channelId, messageId, err := e.parseDeleteMessageRequest(c)
+	if err != nil {
+		return err
+	}
+
+	channel, guildId, err := e.validatePinPermissions(c, channelId, user.Id, true)
+	if err != nil {
+		return err
+	}
+
+	message, err := e.msg.GetMessage(c.UserContext(), messageId, channelId)
+	if err != nil {
+		if errors.Is(err, gocql.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "message not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetMessage)
+	}
+
+	flags := model.NormalizeMessageFlags(message.Flags)
+	if model.HasMessageFlag(flags, model.MessageFlagPinned) {
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	count, err := e.pin.CountChannelPins(c.UserContext(), channelId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToPinMessage)
+	}
+	if count >= MaxChannelPins {
+		return fiber.NewError(fiber.StatusBadRequest, ErrPinLimitReached)
+	}
+
+	pinned, err := e.pin.PinMessage(c.UserContext(), channelId, messageId, user.Id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToPinMessage)
+	}
+	if !pinned {
+		return c.SendStatus(fiber.StatusOK)
+	}
+	if err := e.keepPinWithinLimit(c.UserContext(), channelId, messageId); err != nil {
+		return err
+	}
+
+	if err := e.msg.SetMessageFlags(c.UserContext(), messageId, channelId, flags|model.MessageFlagPinned); err != nil {
+		if _, uerr := e.pin.UnpinMessage(c.UserContext(), channelId, messageId); uerr != nil {
+			e.log.Error("unable to roll back message pin", slog.String("error", uerr.Error()))
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToPinMessage)
+	}
+
+	go e.sendPinsUpdate(channelId, guildId, messageId, user.Id, true)
+
+	// The pin is already stored, so failing to post the system message is only logged
+	userData, err := e.fetchUserDataForMessage(c, user.Id)
+	if err != nil {
+		e.log.Error("unable to get user for pin system message", slog.String("error", err.Error()))
+		return c.SendStatus(fiber.StatusOK)
+	}
+	go e.sendPinSystemMessage(channel, guildId, messageId, userData)
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// keepPinWithinLimit recounts the channel pins after the insert and removes the new pin when concurrent
+// requests pushed the channel over the limit. Racing pins may all be rolled back, which only costs a retry.
+func (e *entity) keepPinWithinLimit(ctx context.Context, channelId, messageId int64) error {
+	count, err := e.pin.CountChannelPins(ctx, channelId)
+	if err == nil && count <= MaxChannelPins {
+		return nil
+	}
+	if _, uerr := e.pin.UnpinMessage(ctx, channelId, messageId); uerr != nil {
+		e.log.Error("unable to roll back message pin", slog.String("error", uerr.Error()))
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToPinMessage)
+	}
+	return fiber.NewError(fiber.StatusBadRequest, ErrPinLimitReached)
+}
+
+// UnpinMessage
+//
+//	@Summary		Unpin message
+//	@Description	Removes the message from channel pins. Guild channels require PermTextManageMessages.
+//	@Produce		json
+//	@Tags			Message
+//	@Param			channel_id	path		int64	true	"Channel id"
+//	@Param			message_id	path		int64	true	"Message id"
+//	@Success		200			{string}	string	"OK"
+//	@failure		400			{string}	string	"Bad request"
+//	@failure		403			{string}	string	"Forbidden or member is timed out"
+//	@failure		500			{string}	string	"Internal server error"
+//	@Router			/message/channel/{channel_id}/{message_id}/pin [delete]
+func (e *entity) UnpinMessage(c *fiber.Ctx) error {
+	user, channelId, messageId, err := e.parseDeleteMessageRequest(c)
+	if err != nil {
+		return err
+	}
+
+	_, guildId, err := e.validatePinPermissions(c, channelId, user.Id, true)
+	if err != nil {
+		return err
+	}
+
+	removed, err := e.pin.UnpinMessage(c.UserContext(), channelId, messageId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUnpinMessage)
+	}
+	if !removed {
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	message, err := e.msg.GetMessage(c.UserContext(), messageId, channelId)
+	if err != nil && !errors.Is(err, gocql.ErrNotFound) {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetMessage)
+	}
+	if err == nil {
+		flags := model.NormalizeMessageFlags(message.Flags) &^ model.MessageFlagPinned
+		if err := e.msg.SetMessageFlags(c.UserContext(), messageId, channelId, flags); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToUnpinMessage)
+		}
+	}
+
+	go e.sendPinsUpdate(channelId, guildId, messageId, user.Id, false)
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// GetPins
+//
+//	@Summary	Get pinned messages
+//	@Produce	json
+//	@Tags		Message
+//	@Param		channel_id	path		int64			true	"Channel id"
+//	@Success	200			{array}		dto.Message		"Pinned messages, most recent first"
+//	@failure	400			{string}	string			"Bad request"
+//	@failure	403			{string}	string			"Forbidden"
+//	@failure	404			{string}	string			"Not found"
+//	@failure	500			{string}	string			"Internal server error"
+//	@Router		/message/channel/{channel_id}/pins [get]
+func (e *entity) GetPins(c *fiber.Ctx) error {
+	channelId, err := strconv.ParseInt(c.Params("channel_id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrIncorrectChannelID)
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	channel, guildId, err := e.validatePinPermissions(c, channelId, user.Id, false)
+	if err != nil {
+		return err
+	}
+
+	pins, err := e.pin.GetChannelPins(c.UserContext(), channelId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetPins)
+	}
+	if len(pins) == 0 {
+		return c.JSON([]dto.Message{})
+	}
+
+	ids := make([]int64, len(pins))
+	for i, p := range pins {
+		ids[i] = p.MessageId
+	}
+	rawMessages, err := e.msg.GetChannelMessagesByIDs(c.UserContext(), channelId, ids)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetPins)
+	}
+	if len(rawMessages) == 0 {
+		return c.JSON([]dto.Message{})
+	}
+	rawMessages, userIds := orderPinnedMessages(rawMessages)
+
+	messages, err := e.buildMessageList(c, rawMessages, userIds, channel, guildId, user.Id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(messages)
+}
+
+// orderPinnedMessages sorts pinned messages newest first and returns their unique author IDs
+func orderPinnedMessages(messages []model.Message) ([]model.Message, []int64) {
+	slices.SortFunc(messages, func(a, b model.Message) int {
+		switch {
+		case a.Id > b.Id:
+			return -1
+		case a.Id < b.Id:
+			return 1
+		}
+		return 0
+	})
+
+	var userIds []int64
+	for _, m := range messages {
+		if !slices.Contains(userIds, m.UserId) {
+			userIds = append(userIds, m.UserId)
+		}
+	}
+	return messages, userIds
+}
+
+// mergeUpdatedFlags applies flags from a message edit. The pinned flag is managed by the pin endpoints and kept as is.
+func mergeUpdatedFlags(current, requested int) int {
+	return requested&^model.MessageFlagPinned | current&model.MessageFlagPinned
+}
+
+// validatePinPermissions checks if user can see the channel history and, when managing, change pins in it
+func (e *entity) validatePinPermissions(c *fiber.Ctx, channelId, userId int64, manage bool) (*model.Channel, *int64, error) {
+	channel, err := e.ch.GetChannel(c.UserContext(), channelId)
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusNotFound, "channel not found")
+	}
+
+	switch channel.Type {
+	case model.ChannelTypeGuild, model.ChannelTypeThread:
+		guildChannel, err := e.gc.GetGuildByChannel(c.UserContext(), channelId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, nil, fiber.NewError(fiber.StatusNotFound, "channel not found")
+			}
+			return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to get guild channel")
+		}
+
+		perms := []permissions.RolePermission{permissions.PermServerViewChannels, permissions.PermTextReadMessageHistory}
+		if manage {
+			perms = append(perms, permissions.PermTextManageMessages)
+		}
+		_, _, _, ok, err := e.perm.ChannelPerm(c.UserContext(), guildChannel.GuildId, guildChannel.ChannelId, userId, perms...)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to check permissions")
+		}
+		if !ok {
+			return nil, nil, fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
+		}
+		if manage {
+			if err := helper.CheckMemberTimeout(c.UserContext(), e.m, guildChannel.GuildId, userId); err != nil {
+				return nil, nil, err
+			}
+		}
+		return &channel, &guildChannel.GuildId, nil
+	case model.ChannelTypeDM:
+		ok, err := e.dmc.IsDmChannelParticipant(c.UserContext(), channelId, userId)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to check permissions")
+		}
+		if !ok {
+			return nil, nil, fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
+		}
+		return &channel, nil, nil
+	case model.ChannelTypeGroupDM:
+		ok, err := e.gdmc.IsGroupDmParticipant(c.UserContext(), channelId, userId)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to check permissions")
+		}
+		if !ok {
+			return nil, nil, fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
+		}
+		return &channel, nil, nil
+	default:
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, ErrUnableToPinInThisChannel)
+	}
+}
+
+// sendPinSystemMessage posts a pin system message that references the pinned message
+func (e *entity) sendPinSystemMessage(channel *model.Channel, guildId *int64, messageId int64, userData *messageUserData) {
+	ctx := context.Background()
+	msgId := idgen.Next()
+	if err := e.msg.CreateSystemMessage(ctx, msgId, channel.Id, userData.User.Id, messageId, "", model.MessageTypePin); err != nil {
+		e.log.Error("unable to create pin system message", slog.String("error", err.Error()))
+		return
+	}
+	if err := e.ch.SetLastMessage(ctx, channel.Id, msgId); err != nil {
+		e.log.Error("unable to set last message id", slog.String("error", err.Error()))
+	}
+	if guildId != nil {
+		if err := e.gclm.SetChannelLastMessage(ctx, *guildId, channel.Id, msgId); err != nil {
+			e.log.Error("unable to set guild channel last message id", slog.String("error", err.Error()))
+		}
+	}
+
+	author := dto.User{
+		Id:            userData.User.Id,
+		Name:          userData.User.Name,
+		Discriminator: userData.Discriminator.Discriminator,
+		Bot:           userData.User.Bot,
+	}
+	if userData.User.Avatar != nil {
+		if ad, err := e.getAvatarDataCached(ctx, userData.User.Id, *userData.User.Avatar); err == nil && ad != nil {
+			author.Avatar = ad
+		}
+	}
+	reference := messageId
+	if err := e.mqt.SendChannelMessage(channel.Id, &mqmsg.CreateMessage{
+		GuildId: guildId,
+		Message: dto.Message{
+			Id:               msgId,
+			ChannelId:        channel.Id,
+			Author:           author,
+			Type:             int(model.MessageTypePin),
+			MessageReference: &reference,
+		},
+	}); err != nil {
+		e.log.Error("unable to send pin message event", slog.String("error", err.Error()))
+	}
+	if err := e.imq.IndexMessage(dto.IndexMessage{
+		MessageId: msgId,
+		UserId:    userData.User.Id,
+		ChannelId: channel.Id,
+		GuildId:   guildId,
+		Type:      int(model.MessageTypePin),
+	}); err != nil {
+		e.log.Error("failed to send index message event",
+			"message_id", msgId,
+			"error", err.Error())
+	}
+}
+
+// sendPinsUpdate notifies channel subscribers that the channel pins changed
+func (e *entity) sendPinsUpdate(channelId int64, guildId *int64, messageId, userId int64, pinned bool) {
+	if err := e.mqt.SendChannelMessage(channelId, &mqmsg.ChannelPinsUpdate{
+		GuildId:   guildId,
+		ChannelId: channelId,
+		MessageId: messageId,
+		UserId:    userId,
+		Pinned:    pinned,
+	}); err != nil {
+		e.log.Error("failed to send channel pins update event",
+			slog.Int64("channel_id", channelId),
+			slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,85 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type fakePinRepo struct {
+	count    int
+	unpinned []int64
+}
+
+func (f *fakePinRepo) PinMessage(ctx context.Context, channelId, messageId, userId int64) (bool, error) {
+	return true, nil
+}
+func (f *fakePinRepo) UnpinMessage(ctx context.Context, channelId, messageId int64) (bool, error) {
+	f.unpinned = append(f.unpinned, messageId)
+	return true, nil
+}
+func (f *fakePinRepo) GetChannelPins(ctx context.Context, channelId int64) ([]model.Pin, error) {
+	return nil, nil
+}
+func (f *fakePinRepo) CountChannelPins(ctx context.Context, channelId int64) (int, error) {
+	return f.count, nil
+}
+func (f *fakePinRepo) RemoveChannelPins(ctx context.Context, channelId int64) error {
+	return nil
+}
+
+func TestOrderPinnedMessagesNewestFirst(t *testing.T) {
+	messages, userIds := orderPinnedMessages([]model.Message{
+		{Id: 2, UserId: 10},
+		{Id: 5, UserId: 11},
+		{Id: 3, UserId: 10},
+	})
+
+	var ids []int64
+	for _, m := range messages {
+		ids = append(ids, m.Id)
+	}
+	if !slices.Equal(ids, []int64{5, 3, 2}) {
+		t.Fatalf("expected newest pinned messages first, got %v", ids)
+	}
+	if !slices.Equal(userIds, []int64{11, 10}) {
+		t.Fatalf("expected unique author IDs, got %v", userIds)
+	}
+}
+
+func TestMergeUpdatedFlagsKeepsPinnedFlag(t *testing.T) {
+	pinned := model.MessageFlagPinned
+
+	if got := mergeUpdatedFlags(pinned, model.MessageFlagSuppressEmbeds); got != pinned|model.MessageFlagSuppressEmbeds {
+		t.Fatalf("expected pinned flag to survive the edit, got %d", got)
+	}
+	if got := mergeUpdatedFlags(0, pinned); got != 0 {
+		t.Fatalf("expected edit not to pin the message, got %d", got)
+	}
+}
+
+func TestKeepPinWithinLimit(t *testing.T) {
+	repo := &fakePinRepo{count: MaxChannelPins}
+	e := &entity{pin: repo}
+	if err := e.keepPinWithinLimit(context.Background(), 1, 7); err != nil {
+		t.Fatalf("expected the pin at the limit to be kept, got %v", err)
+	}
+	if len(repo.unpinned) != 0 {
+		t.Fatalf("expected no rollback, got %v", repo.unpinned)
+	}
+
+	repo.count = MaxChannelPins + 1
+	err := e.keepPinWithinLimit(context.Background(), 1, 8)
+	var fiberErr *fiber.Error
+	if !errors.As(err, &fiberErr) || fiberErr.Code != fiber.StatusBadRequest || fiberErr.Message != ErrPinLimitReached {
+		t.Fatalf("expected pin limit error, got %v", err)
+	}
+	if !slices.Equal(repo.unpinned, []int64{8}) {
+		t.Fatalf("expected the pin over the limit to be rolled back, got %v", repo.unpinned)
+	}
+}
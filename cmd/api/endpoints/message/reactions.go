@@ -0,0 +1,371 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+const maxReactionEmojiBytes = 64
+
+// customReactionRegex matches custom guild emoji in the "name:id" or bare "id" form.
+var customReactionRegex = regexp.MustCompile(`^(?:([A-Za-z0-9-]+):)?([0-9]+)$`)
+
+// reactionEmoji is a parsed :emoji route parameter.
+type reactionEmoji struct {
+	Key     string // Value stored in the reactions table
+	EmoteId int64  // Custom guild emoji ID, 0 for unicode emoji
+	Name    string
+}
+
+func (r reactionEmoji) DTO() dto.ReactionEmoji {
+	if r.EmoteId == 0 {
+		return dto.ReactionEmoji{Name: r.Name}
+	}
+	id := r.EmoteId
+	return dto.ReactionEmoji{Id: &id, Name: r.Name}
+}
+
+// parseReactionEmoji accepts a URL-encoded unicode emoji or a custom emoji as "name:id" or "id".
+func parseReactionEmoji(raw string) (reactionEmoji, error) {
+	value, err := url.PathUnescape(raw)
+	if err != nil {
+		return reactionEmoji{}, errors.New(ErrIncorrectEmoji)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" || len(value) > maxReactionEmojiBytes || !utf8.ValidString(value) {
+		return reactionEmoji{}, errors.New(ErrIncorrectEmoji)
+	}
+
+	if parts := customReactionRegex.FindStringSubmatch(value); parts != nil {
+		id, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil || id <= 0 {
+			return reactionEmoji{}, errors.New(ErrIncorrectEmoji)
+		}
+		return reactionEmoji{Key: parts[2], EmoteId: id, Name: parts[1]}, nil
+	}
+
+	// Unicode emoji must contain at least one non-ASCII rune and no separators or markup
+	hasSymbol := false
+	for _, r := range value {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(":<>", r) {
+			return reactionEmoji{}, errors.New(ErrIncorrectEmoji)
+		}
+		if r > unicode.MaxASCII {
+			hasSymbol = true
+		}
+	}
+	if !hasSymbol {
+		return reactionEmoji{}, errors.New(ErrIncorrectEmoji)
+	}
+	return reactionEmoji{Key: value, Name: value}, nil
+}
+
+// AddReaction
+//
+//	@Summary	Add reaction to the message
+//	@Produce	json
+//	@Tags		Message
+//	@Param		channel_id	path		int64	true	"Channel id"
+//	@Param		message_id	path		int64	true	"Message id"
+//	@Param		emoji		path		string	true	"URL-encoded unicode emoji or custom emoji as name:id"
+//	@Success	200			{string}	string	"OK"
+//	@failure	400			{string}	string	"Bad request"
+//	@failure	403			{string}	string	"Forbidden or member is timed out"
+//	@failure	404			{string}	string	"Not found"
+//	@failure	500			{string}	string	"Internal server error"
+//	@Router		/message/channel/{channel_id}/{message_id}/reactions/{emoji} [put]
+func (e *entity) AddReaction(c *fiber.Ctx) error {
+	user, channelId, messageId, emoji, err := e.parseReactionRequest(c)
+	if err != nil {
+		return err
+	}
+
+	_, guildId, err := e.validateReactionPermissions(c, channelId, user.Id, true)
+	if err != nil {
+		return err
+	}
+
+	if err := e.validateReactionMessage(c, messageId, channelId); err != nil {
+		return err
+	}
+
+	if emoji.EmoteId != 0 {
+		lookup, err := e.lookupEmojiCached(c.UserContext(), emoji.EmoteId)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToAddReaction)
+		}
+		if lookup == nil || !lookup.Done {
+			return fiber.NewError(fiber.StatusNotFound, ErrUnknownEmoji)
+		}
+		ok, err := e.m.IsGuildMember(c.UserContext(), lookup.GuildId, user.Id)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToAddReaction)
+		}
+		if !ok {
+			return fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
+		}
+		emoji.Name = lookup.Name
+	}
+
+	added, err := e.react.AddReaction(c.UserContext(), messageId, user.Id, emoji.Key, emoji.EmoteId)
+	if err != nil && !added {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToAddReaction)
+	}
+	if err != nil {
+		// The reaction itself is stored, only the counters lag behind
+		e.log.Error("failed to index added reaction",
+			slog.Int64("message_id", messageId),
+			slog.String("error", err.Error()))
+	}
+
+	if added {
+		go func() {
+			if err := e.mqt.SendChannelMessage(channelId, &mqmsg.MessageReactionAdd{
+				GuildId:   guildId,
+				ChannelId: channelId,
+				MessageId: messageId,
+				UserId:    user.Id,
+				Emoji:     emoji.DTO(),
+			}); err != nil {
+				e.log.Error("failed to send reaction add event",
+					slog.Int64("message_id", messageId),
+					slog.String("error", err.Error()))
+			}
+		}()
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// RemoveReaction
+//
+//	@Summary	Remove own reaction from the message
+//	@Produce	json
+//	@Tags		Message
+//	@Param		channel_id	path		int64	true	"Channel id"
+//	@Param		message_id	path		int64	true	"Message id"
+//	@Param		emoji		path		string	true	"URL-encoded unicode emoji or custom emoji as name:id"
+//	@Success	200			{string}	string	"OK"
+//	@failure	400			{string}	string	"Bad request"
+//	@failure	403			{string}	string	"Forbidden"
+//	@failure	404			{string}	string	"Not found"
+//	@failure	500			{string}	string	"Internal server error"
+//	@Router		/message/channel/{channel_id}/{message_id}/reactions/{emoji} [delete]
+func (e *entity) RemoveReaction(c *fiber.Ctx) error {
+	user, channelId, messageId, emoji, err := e.parseReactionRequest(c)
+	if err != nil {
+		return err
+	}
+
+	_, guildId, err := e.validateReactionPermissions(c, channelId, user.Id, false)
+	if err != nil {
+		return err
+	}
+
+	if err := e.validateReactionMessage(c, messageId, channelId); err != nil {
+		return err
+	}
+
+	// The emoji may have been deleted since the reaction was added, so only use it for the name
+	if emoji.EmoteId != 0 {
+		if lookup, err := e.lookupEmojiCached(c.UserContext(), emoji.EmoteId); err == nil && lookup != nil {
+			emoji.Name = lookup.Name
+		}
+	}
+
+	removed, err := e.react.RemoveReaction(c.UserContext(), messageId, user.Id, emoji.Key, emoji.EmoteId)
+	if err != nil && !removed {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRemoveReaction)
+	}
+	if err != nil {
+		// The reaction itself is removed, only the counters lag behind
+		e.log.Error("failed to index removed reaction",
+			slog.Int64("message_id", messageId),
+			slog.String("error", err.Error()))
+	}
+
+	if removed {
+		go func() {
+			if err := e.mqt.SendChannelMessage(channelId, &mqmsg.MessageReactionRemove{
+				GuildId:   guildId,
+				ChannelId: channelId,
+				MessageId: messageId,
+				UserId:    user.Id,
+				Emoji:     emoji.DTO(),
+			}); err != nil {
+				e.log.Error("failed to send reaction remove event",
+					slog.Int64("message_id", messageId),
+					slog.String("error", err.Error()))
+			}
+		}()
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// parseReactionRequest handles reaction route parameters and user authentication
+func (e *entity) parseReactionRequest(c *fiber.Ctx) (*helper.JWTUser, int64, int64, reactionEmoji, error) {
+	user, channelId, messageId, err := e.parseDeleteMessageRequest(c)
+	if err != nil {
+		return nil, 0, 0, reactionEmoji{}, err
+	}
+
+	emoji, err := parseReactionEmoji(c.Params("emoji"))
+	if err != nil {
+		return nil, 0, 0, reactionEmoji{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return user, channelId, messageId, emoji, nil
+}
+
+// validateReactionPermissions checks if user can see the channel history and, when adding, react in it
+func (e *entity) validateReactionPermissions(c *fiber.Ctx, channelId, userId int64, adding bool) (*model.Channel, *int64, error) {
+	channel, err := e.ch.GetChannel(c.UserContext(), channelId)
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusNotFound, "channel not found")
+	}
+
+	switch channel.Type {
+	case model.ChannelTypeGuild, model.ChannelTypeThread:
+		guildChannel, err := e.gc.GetGuildByChannel(c.UserContext(), channelId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, nil, fiber.NewError(fiber.StatusNotFound, "channel not found")
+			}
+			return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to get guild channel")
+		}
+
+		perms := []permissions.RolePermission{permissions.PermServerViewChannels, permissions.PermTextReadMessageHistory}
+		if adding {
+			perms = append(perms, permissions.PermTextAddReactions)
+		}
+		_, _, _, ok, err := e.perm.ChannelPerm(c.UserContext(), guildChannel.GuildId, guildChannel.ChannelId, userId, perms...)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to check permissions")
+		}
+		if !ok {
+			if adding {
+				if err := e.checkMemberPending(c.UserContext(), guildChannel.GuildId, userId); err != nil {
+					return nil, nil, err
+				}
+			}
+			return nil, nil, fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
+		}
+		// Timed out members can still remove their own reactions
+		if adding {
+			if err := helper.CheckMemberTimeout(c.UserContext(), e.m, guildChannel.GuildId, userId); err != nil {
+				return nil, nil, err
+			}
+		}
+		return &channel, &guildChannel.GuildId, nil
+	case model.ChannelTypeDM:
+		ok, err := e.dmc.IsDmChannelParticipant(c.UserContext(), channelId, userId)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to check permissions")
+		}
+		if !ok {
+			return nil, nil, fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
+		}
+		return &channel, nil, nil
+	case model.ChannelTypeGroupDM:
+		ok, err := e.gdmc.IsGroupDmParticipant(c.UserContext(), channelId, userId)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to check permissions")
+		}
+		if !ok {
+			return nil, nil, fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
+		}
+		return &channel, nil, nil
+	default:
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, ErrUnableToReactInThisChannel)
+	}
+}
+
+// validateReactionMessage checks that the message exists in the channel
+func (e *entity) validateReactionMessage(c *fiber.Ctx, messageId, channelId int64) error {
+	if _, err := e.msg.GetMessage(c.UserContext(), messageId, channelId); err != nil {
+		if errors.Is(err, gocql.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "message not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetMessage)
+	}
+	return nil
+}
+
+// applyMessageReactions fills aggregated reactions for the messages and marks the ones made by the viewer
+func (e *entity) applyMessageReactions(ctx context.Context, messages []dto.Message, counts map[int64][]model.ReactionCount, viewerId int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	names := make(map[int64]string)
+	messageIds := make([]int64, 0, len(counts))
+	for messageId, list := range counts {
+		messageIds = append(messageIds, messageId)
+		for _, r := range list {
+			if r.EmoteId == 0 {
+				continue
+			}
+			if _, ok := names[r.EmoteId]; ok {
+				continue
+			}
+			names[r.EmoteId] = ""
+			if lookup, err := e.lookupEmojiCached(ctx, r.EmoteId); err == nil && lookup != nil {
+				names[r.EmoteId] = lookup.Name
+			}
+		}
+	}
+
+	mine, err := e.react.GetUserReactions(ctx, messageIds, viewerId)
+	if err != nil {
+		return err
+	}
+
+	for i := range messages {
+		messages[i].Reactions = aggregateReactions(counts[messages[i].Id], mine[messages[i].Id], names)
+	}
+	return nil
+}
+
+// aggregateReactions turns the emoji counters of a message into reaction DTOs keeping the storage order
+func aggregateReactions(counts []model.ReactionCount, mine []string, names map[int64]string) []dto.MessageReaction {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	me := make(map[string]bool, len(mine))
+	for _, key := range mine {
+		me[key] = true
+	}
+
+	var result []dto.MessageReaction
+	for _, r := range counts {
+		// Counters of fully removed emoji stay at zero until the message is deleted
+		if r.Count <= 0 {
+			continue
+		}
+		emoji := reactionEmoji{Key: r.Emoji, EmoteId: r.EmoteId, Name: r.Emoji}
+		if r.EmoteId != 0 {
+			emoji.Name = names[r.EmoteId]
+		}
+		result = append(result, dto.MessageReaction{Emoji: emoji.DTO(), Count: int(r.Count), Me: me[r.Emoji]})
+	}
+	return result
+}
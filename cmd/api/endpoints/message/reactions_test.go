@@ -0,0 +1,77 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+func TestParseReactionEmoji(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantKey string
+		wantId  int64
+		wantErr bool
+	}{
+		{name: "encoded unicode", raw: "%F0%9F%91%8D", wantKey: "👍"},
+		{name: "raw unicode", raw: "🔥", wantKey: "🔥"},
+		{name: "keycap sequence", raw: "1%EF%B8%8F%E2%83%A3", wantKey: "1️⃣"},
+		{name: "custom with name", raw: "party-cat:2230469276416868352", wantKey: "2230469276416868352", wantId: 2230469276416868352},
+		{name: "custom bare id", raw: "2230469276416868352", wantKey: "2230469276416868352", wantId: 2230469276416868352},
+		{name: "empty", raw: "", wantErr: true},
+		{name: "plain text", raw: "thumbsup", wantErr: true},
+		{name: "markup", raw: "%3C%3Acat%3A1%3E", wantErr: true},
+		{name: "whitespace", raw: "%F0%9F%91%8D%20%F0%9F%91%8D", wantErr: true},
+		{name: "zero id", raw: "cat:0", wantErr: true},
+		{name: "bad escape", raw: "%ZZ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseReactionEmoji(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %#v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseReactionEmoji returned error: %v", err)
+			}
+			if got.Key != tt.wantKey || got.EmoteId != tt.wantId {
+				t.Fatalf("unexpected emoji: %#v", got)
+			}
+		})
+	}
+}
+
+func TestAggregateReactionsUsesCountsAndMarksViewer(t *testing.T) {
+	counts := []model.ReactionCount{
+		{MessageId: 1, Emoji: "77", EmoteId: 77, Count: 1},
+		{MessageId: 1, Emoji: "🔥", Count: 0},
+		{MessageId: 1, Emoji: "👍", Count: 3},
+	}
+
+	got := aggregateReactions(counts, []string{"👍", "77"}, map[int64]string{77: "party-cat"})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 reactions without the emptied counter, got %#v", got)
+	}
+	if got[0].Emoji.Id == nil || *got[0].Emoji.Id != 77 || got[0].Emoji.Name != "party-cat" || got[0].Count != 1 || !got[0].Me {
+		t.Fatalf("unexpected custom reaction: %#v", got[0])
+	}
+	if got[1].Emoji.Name != "👍" || got[1].Emoji.Id != nil || got[1].Count != 3 || !got[1].Me {
+		t.Fatalf("unexpected unicode reaction: %#v", got[1])
+	}
+
+	other := aggregateReactions(counts, nil, nil)
+	for _, r := range other {
+		if r.Me {
+			t.Fatalf("expected no reactions marked for another viewer, got %#v", other)
+		}
+	}
+
+	if aggregateReactions(nil, []string{"👍"}, nil) != nil {
+		t.Fatal("expected nil reactions for message without reactions")
+	}
+}
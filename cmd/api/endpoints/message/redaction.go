@@ -2,6 +2,7 @@ package message
 
 import (
 	"context"
+	"slices"
 
 	"github.com/FlameInTheDark/gochat/internal/database/model"
 	"github.com/FlameInTheDark/gochat/internal/dto"
@@ -36,3 +37,57 @@ func (e *entity) redactBannedMessages(ctx context.Context, guildId int64, rawMes
 
 	return nil
 }
+
+// redactBannedReferences hides content of replied messages written by authors banned from the guild
+func (e *entity) redactBannedReferences(ctx context.Context, guildId int64, messages []dto.Message) error {
+	if e.ban == nil {
+		return nil
+	}
+
+	bannedAuthors := make(map[int64]bool)
+	for i := range messages {
+		reference := messages[i].ReferencedMessage
+		if reference == nil || reference.Author == nil {
+			continue
+		}
+		banned, seen := bannedAuthors[reference.Author.Id]
+		if !seen {
+			var err error
+			banned, err = e.ban.IsBanned(ctx, guildId, reference.Author.Id)
+			if err != nil {
+				return err
+			}
+			bannedAuthors[reference.Author.Id] = banned
+		}
+		if banned {
+			reference.Content = ""
+			reference.Flags |= model.MessageFlagBannedAuthor
+		}
+	}
+
+	return nil
+}
+
+// markBlockedAuthors flags messages and replied messages written by users the viewer blocked, clients collapse them
+func (e *entity) markBlockedAuthors(ctx context.Context, viewerId int64, messages []dto.Message) error {
+	if e.blocks == nil || len(messages) == 0 {
+		return nil
+	}
+	blocked, err := e.blocks.BlockedIds(ctx, viewerId)
+	if err != nil {
+		return err
+	}
+	if len(blocked) == 0 {
+		return nil
+	}
+
+	for i := range messages {
+		if slices.Contains(blocked, messages[i].Author.Id) {
+			messages[i].Flags |= model.MessageFlagBlockedAuthor
+		}
+		if reference := messages[i].ReferencedMessage; reference != nil && reference.Author != nil && slices.Contains(blocked, reference.Author.Id) {
+			reference.Flags |= model.MessageFlagBlockedAuthor
+		}
+	}
+	return nil
+}
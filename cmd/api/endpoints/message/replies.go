@@ -0,0 +1,137 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+// validateMessageReference checks that the referenced message is in the same channel and the sender can read it
+func (e *entity) validateMessageReference(ctx context.Context, channelId int64, guildId *int64, userId, referenceId int64) (*model.Message, error) {
+	if guildId != nil {
+		_, _, _, canRead, err := e.perm.ChannelPerm(ctx, *guildId, channelId, userId,
+			permissions.PermServerViewChannels,
+			permissions.PermTextReadMessageHistory,
+		)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to check permissions")
+		}
+		if !canRead {
+			return nil, fiber.NewError(fiber.StatusForbidden, ErrPermissionsRequired)
+		}
+	}
+
+	reference, err := e.msg.GetMessage(ctx, referenceId, channelId)
+	if err != nil {
+		if errors.Is(err, gocql.ErrNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, ErrReferencedMessageNotFound)
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetMessage)
+	}
+	return &reference, nil
+}
+
+// replyMentions adds the author of the referenced message to the mentioned users unless the sender opted out
+func replyMentions(users []int64, req *SendMessageRequest, reference *model.Message, senderId int64) []int64 {
+	if reference == nil || !req.mentionRepliedAuthor() || reference.UserId == senderId {
+		return users
+	}
+	if slices.Contains(users, reference.UserId) {
+		return users
+	}
+	return append(users, reference.UserId)
+}
+
+// fetchReferencedMessages loads messages referenced by replies in the batch.
+// Returns referenced messages by ID and IDs of their authors.
+func (e *entity) fetchReferencedMessages(ctx context.Context, channelId int64, messages []model.Message) (map[int64]*model.Message, []int64, error) {
+	var ids []int64
+	for _, message := range messages {
+		if message.Reference != 0 && !slices.Contains(ids, message.Reference) {
+			ids = append(ids, message.Reference)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	referenced, err := e.msg.GetChannelMessagesByIDs(ctx, channelId, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	references := make(map[int64]*model.Message, len(referenced))
+	var authors []int64
+	for i := range referenced {
+		references[referenced[i].Id] = &referenced[i]
+		if !slices.Contains(authors, referenced[i].UserId) {
+			authors = append(authors, referenced[i].UserId)
+		}
+	}
+	return references, authors, nil
+}
+
+// referencedMessageDTO builds a compact copy of the referenced message or a tombstone if it was deleted
+func (e *entity) referencedMessageDTO(referenceId, channelId int64, data *messageRelatedData) *dto.ReferencedMessage {
+	reference, ok := data.References[referenceId]
+	if !ok {
+		return &dto.ReferencedMessage{
+			Id:        referenceId,
+			ChannelId: channelId,
+			Deleted:   true,
+		}
+	}
+
+	author := e.messageAuthor(reference, data)
+	return &dto.ReferencedMessage{
+		Id:        reference.Id,
+		ChannelId: reference.ChannelId,
+		Author:    &author,
+		Content:   reference.Content,
+		Flags:     model.NormalizeMessageFlags(reference.Flags),
+	}
+}
+
+// buildSentReference builds the referenced message for a freshly sent reply.
+// Author lookup failures are logged and fall back to an unknown author.
+func (e *entity) buildSentReference(ctx context.Context, guildId *int64, reference *model.Message) *dto.ReferencedMessage {
+	data := &messageRelatedData{
+		Users:      make(map[int64]*model.User),
+		Members:    make(map[int64]*model.Member),
+		AvData:     make(map[int64]*dto.AvatarData),
+		References: map[int64]*model.Message{reference.Id: reference},
+	}
+
+	users, err := e.user.GetUsersList(ctx, []int64{reference.UserId})
+	if err != nil {
+		e.log.Error("unable to get referenced message author", slog.String("error", err.Error()))
+	}
+	for i := range users {
+		data.Users[users[i].Id] = &users[i]
+		if users[i].Avatar != nil {
+			if ad, err := e.getAvatarDataCached(ctx, users[i].Id, *users[i].Avatar); err == nil && ad != nil {
+				data.AvData[users[i].Id] = ad
+			}
+		}
+	}
+
+	if guildId != nil && e.m != nil {
+		members, err := e.m.GetMembersList(ctx, *guildId, []int64{reference.UserId})
+		if err != nil {
+			e.log.Error("unable to get referenced message member", slog.String("error", err.Error()))
+		}
+		for i := range members {
+			data.Members[members[i].UserId] = &members[i]
+		}
+	}
+
+	return e.referencedMessageDTO(reference.Id, reference.ChannelId, data)
+}
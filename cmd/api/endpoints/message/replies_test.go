@@ -0,0 +1,125 @@
+package message
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+)
+
+func TestReplyMentionsAddsRepliedAuthorByDefault(t *testing.T) {
+	referenceId := int64(5)
+	req := &SendMessageRequest{Content: "reply", MessageReference: &referenceId}
+	reference := &model.Message{Id: referenceId, UserId: 20}
+
+	users := replyMentions([]int64{30}, req, reference, 10)
+	if !slices.Equal(users, []int64{30, 20}) {
+		t.Fatalf("expected replied author to be mentioned, got %v", users)
+	}
+
+	users = replyMentions([]int64{20}, req, reference, 10)
+	if !slices.Equal(users, []int64{20}) {
+		t.Fatalf("expected replied author not to be duplicated, got %v", users)
+	}
+}
+
+func TestReplyMentionsSkipsOptOutAndSelfReplies(t *testing.T) {
+	referenceId := int64(5)
+	mention := false
+	req := &SendMessageRequest{Content: "reply", MessageReference: &referenceId, MentionRepliedAuthor: &mention}
+
+	if users := replyMentions(nil, req, &model.Message{Id: referenceId, UserId: 20}, 10); len(users) != 0 {
+		t.Fatalf("expected no mentions when opted out, got %v", users)
+	}
+
+	req.MentionRepliedAuthor = nil
+	if users := replyMentions(nil, req, &model.Message{Id: referenceId, UserId: 10}, 10); len(users) != 0 {
+		t.Fatalf("expected no mention for a reply to own message, got %v", users)
+	}
+}
+
+func TestBuildMessageDTOsAddsReferencedMessageAndTombstone(t *testing.T) {
+	raw := []model.Message{
+		{Id: 3, ChannelId: 1, UserId: 10, Content: "first reply", Type: int(model.MessageTypeReply), Reference: 2},
+		{Id: 4, ChannelId: 1, UserId: 10, Content: "second reply", Type: int(model.MessageTypeReply), Reference: 1},
+	}
+	data := &messageRelatedData{
+		Users: map[int64]*model.User{
+			10: {Id: 10, Name: "replier"},
+			20: {Id: 20, Name: "author"},
+		},
+		Members:     map[int64]*model.Member{},
+		Attachments: map[int64]*model.Attachment{},
+		AvData:      map[int64]*dto.AvatarData{},
+		References: map[int64]*model.Message{
+			2: {Id: 2, ChannelId: 1, UserId: 20, Content: "original"},
+		},
+	}
+
+	e := &entity{}
+	messages := e.buildMessageDTOsOptimized(raw, data)
+
+	first := messages[0]
+	if first.MessageReference == nil || *first.MessageReference != 2 {
+		t.Fatalf("expected message reference 2, got %v", first.MessageReference)
+	}
+	if first.ReferencedMessage == nil || first.ReferencedMessage.Content != "original" || first.ReferencedMessage.Deleted {
+		t.Fatalf("unexpected referenced message: %#v", first.ReferencedMessage)
+	}
+	if first.ReferencedMessage.Author == nil || first.ReferencedMessage.Author.Name != "author" {
+		t.Fatalf("unexpected referenced author: %#v", first.ReferencedMessage.Author)
+	}
+
+	second := messages[1]
+	if second.ReferencedMessage == nil || !second.ReferencedMessage.Deleted || second.ReferencedMessage.Id != 1 {
+		t.Fatalf("expected tombstone for deleted message, got %#v", second.ReferencedMessage)
+	}
+	if second.ReferencedMessage.Author != nil || second.ReferencedMessage.Content != "" {
+		t.Fatalf("expected tombstone without author and content, got %#v", second.ReferencedMessage)
+	}
+}
+
+func TestRedactBannedReferencesClearsContent(t *testing.T) {
+	messages := []dto.Message{
+		{Id: 3, ReferencedMessage: &dto.ReferencedMessage{Id: 2, Author: &dto.User{Id: 20}, Content: "secret"}},
+		{Id: 4, ReferencedMessage: &dto.ReferencedMessage{Id: 1, Author: &dto.User{Id: 21}, Content: "visible"}},
+	}
+
+	e := &entity{ban: &fakeMessageBanRepo{bans: map[[2]int64]bool{{1, 20}: true}}}
+	if err := e.redactBannedReferences(context.Background(), 1, messages); err != nil {
+		t.Fatalf("redactBannedReferences returned error: %v", err)
+	}
+
+	if messages[0].ReferencedMessage.Content != "" || !model.HasMessageFlag(messages[0].ReferencedMessage.Flags, model.MessageFlagBannedAuthor) {
+		t.Fatalf("expected banned reference to be redacted, got %#v", messages[0].ReferencedMessage)
+	}
+	if messages[1].ReferencedMessage.Content != "visible" {
+		t.Fatalf("expected reference to stay visible, got %#v", messages[1].ReferencedMessage)
+	}
+}
+
+func TestBuildMessageDTOsUsesWebhookAuthor(t *testing.T) {
+	name := "CI"
+	avatar := "https://example.com/ci.png"
+	flags := model.MessageFlagWebhook
+	raw := []model.Message{
+		{Id: 3, ChannelId: 1, UserId: 50, Content: "build passed", Flags: &flags, AuthorName: &name, AuthorAvatar: &avatar},
+	}
+	data := &messageRelatedData{
+		Users:       map[int64]*model.User{},
+		Members:     map[int64]*model.Member{},
+		Attachments: map[int64]*model.Attachment{},
+		AvData:      map[int64]*dto.AvatarData{},
+		References:  map[int64]*model.Message{},
+	}
+
+	e := &entity{}
+	messages := e.buildMessageDTOsOptimized(raw, data)
+
+	author := messages[0].Author
+	if author.Id != 50 || author.Name != "CI" || author.Avatar == nil || author.Avatar.URL != avatar {
+		t.Fatalf("unexpected webhook author: %#v", author)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/FlameInTheDark/gochat/internal/embed"
 	"github.com/FlameInTheDark/gochat/internal/helper"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
 )
 
 const (
@@ -24,11 +25,29 @@ const (
 	ErrUnableToGetUserDiscriminator = "unable to get discriminator"
 	ErrUnableToGetAttachements      = "unable to get attachments"
 	ErrUnableToSentToThisChannel    = "unable to send to this channel"
+	ErrUserBlocked                  = "user is blocked"
 	ErrUnableToReadFromThisChannel  = "unable to read from this channel"
 	ErrUnableToGetMessage           = "unable to get message"
 	ErrUnableToSetReadState         = "unable to set read state"
 	ErrUnableToSendTypingEvent      = "unable to send typing event"
 	ErrInvalidAttachments           = "invalid attachments"
+	ErrIncorrectEmoji               = "incorrect emoji"
+	ErrUnknownEmoji                 = "unknown emoji"
+	ErrUnableToAddReaction          = "unable to add reaction"
+	ErrUnableToRemoveReaction       = "unable to remove reaction"
+	ErrUnableToReactInThisChannel   = "unable to react in this channel"
+	ErrUnableToGetMember            = "unable to get member"
+	ErrMemberPending                = "member has not passed the guild screening"
+	ErrReferencedMessageNotFound    = "referenced message not found"
+	ErrUnableToPinMessage           = "unable to pin message"
+	ErrUnableToUnpinMessage         = "unable to unpin message"
+	ErrUnableToGetPins              = "unable to get pinned messages"
+	ErrUnableToPinInThisChannel     = "unable to pin in this channel"
+	ErrPinLimitReached              = "channel pin limit reached"
+	ErrIncorrectWebhookID           = "incorrect webhook ID"
+	ErrWebhookNotFound              = "webhook not found"
+	ErrInvalidWebhookToken          = "invalid webhook token"
+	ErrUnableToGetWebhook           = "unable to get webhook"
 
 	// Validation error messages
 	ErrMessagePayloadRequired = "message content, attachments, or embeds are required"
@@ -36,6 +55,7 @@ const (
 	ErrMessageContentTooLong  = "message content must be less than 2000 characters"
 	ErrAttachmentIdInvalid    = "attachment ID must be positive"
 	ErrMentionIdInvalid       = "mention ID must be positive"
+	ErrReferenceIdInvalid     = "message reference ID must be positive"
 	ErrFilenameRequired       = "filename is required"
 	ErrFilenameTooLong        = "filename must be less than 255 characters"
 	ErrFileSizeInvalid        = "file size must be positive"
@@ -45,6 +65,9 @@ const (
 	ErrFromIdInvalid          = "from ID must be positive"
 	ErrDirectionInvalid       = "direction must be 'before' or 'after'"
 	ErrFlagsInvalid           = "flags must be non-negative"
+	ErrWebhookAttachments     = "webhooks can not send attachments"
+	ErrWebhookUsernameLength  = "username must be between 1 and 32 characters"
+	ErrWebhookAvatarURL       = "avatar url must be a valid URL"
 )
 
 type SendMessageRequest struct {
@@ -52,6 +75,14 @@ type SendMessageRequest struct {
 	Attachments helper.StringInt64Array `json:"attachments" example:"2230469276416868352"` // IDs of attached files
 	Mentions    helper.StringInt64Array `json:"mentions" example:"2230469276416868352"`    // IDs of mentioned users
 	Embeds      []embed.Embed           `json:"embeds,omitempty"`                          // Manual embeds supplied by the client. These are stored separately from generated URL embeds.
+
+	MessageReference     *int64 `json:"message_reference,omitempty" example:"2230469276416868352"` // ID of the message in the same channel to reply to
+	MentionRepliedAuthor *bool  `json:"mention_replied_author,omitempty" example:"true"`           // Notify the author of the referenced message. Defaults to true.
+}
+
+// mentionRepliedAuthor reports whether the author of the referenced message should be mentioned
+func (r SendMessageRequest) mentionRepliedAuthor() bool {
+	return r.MessageReference != nil && (r.MentionRepliedAuthor == nil || *r.MentionRepliedAuthor)
 }
 
 func (r SendMessageRequest) Validate() error {
@@ -66,6 +97,9 @@ func (r SendMessageRequest) Validate() error {
 		validation.Field(&r.Mentions,
 			validation.Each(validation.Min(int64(1)).Error(ErrMentionIdInvalid)),
 		),
+		validation.Field(&r.MessageReference,
+			validation.When(r.MessageReference != nil, validation.Required.Error(ErrReferenceIdInvalid), validation.Min(int64(1)).Error(ErrReferenceIdInvalid)),
+		),
 	); err != nil {
 		return err
 	}
@@ -73,6 +107,29 @@ func (r SendMessageRequest) Validate() error {
 	return embed.ValidateEmbeds(r.Embeds)
 }
 
+type ExecuteWebhookRequest struct {
+	SendMessageRequest
+	Username  *string `json:"username,omitempty" example:"CI"`                               // Overrides the webhook name for this message
+	AvatarURL *string `json:"avatar_url,omitempty" example:"https://example.com/avatar.png"` // Overrides the webhook avatar for this message
+}
+
+func (r ExecuteWebhookRequest) Validate() error {
+	if len(r.Attachments) > 0 {
+		return errors.New(ErrWebhookAttachments)
+	}
+	if err := validation.ValidateStruct(&r,
+		validation.Field(&r.Username,
+			validation.When(r.Username != nil, validation.Required.Error(ErrWebhookUsernameLength), validation.RuneLength(1, 32).Error(ErrWebhookUsernameLength)),
+		),
+		validation.Field(&r.AvatarURL,
+			validation.When(r.AvatarURL != nil, validation.Required.Error(ErrWebhookAvatarURL), is.RequestURL.Error(ErrWebhookAvatarURL)),
+		),
+	); err != nil {
+		return err
+	}
+	return r.SendMessageRequest.Validate()
+}
+
 type UpdateMessageRequest struct {
 	Content *string        `json:"content,omitempty" example:"Hello world!"` // Message content
 	Embeds  *[]embed.Embed `json:"embeds,omitempty"`                         // Full replacement for the manual embed array. Generated embeds are managed by the embedder service.
@@ -148,7 +205,8 @@ const (
 )
 
 const (
-	DefaultLimit = int(50)
+	DefaultLimit   = int(50)
+	MaxChannelPins = 50 // Maximum number of pinned messages in a channel
 )
 
 type GetMessagesRequest struct {
@@ -56,3 +56,36 @@ func TestUpdateMessageRequestValidateRejectsNegativeFlags(t *testing.T) {
 		t.Fatal("expected validation error")
 	}
 }
+
+func TestSendMessageRequestValidateRejectsInvalidReference(t *testing.T) {
+	for _, ref := range []int64{0, -1} {
+		reference := ref
+		req := SendMessageRequest{Content: "reply", MessageReference: &reference}
+
+		if err := req.Validate(); err == nil {
+			t.Fatalf("expected validation error for reference %d", ref)
+		}
+	}
+}
+
+func TestExecuteWebhookRequestValidate(t *testing.T) {
+	username := "CI"
+	req := ExecuteWebhookRequest{
+		SendMessageRequest: SendMessageRequest{Embeds: []embed.Embed{{Description: "build passed"}}},
+		Username:           &username,
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+
+	req.Attachments = []int64{1}
+	if err := req.Validate(); err == nil {
+		t.Fatal("expected attachments to be rejected")
+	}
+
+	avatar := "not a url"
+	req = ExecuteWebhookRequest{SendMessageRequest: SendMessageRequest{Content: "hi"}, AvatarURL: &avatar}
+	if err := req.Validate(); err == nil {
+		t.Fatal("expected invalid avatar URL to be rejected")
+	}
+}
@@ -0,0 +1,55 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+// reopenThread unarchives an archived thread before a message is sent into it.
+// Threads created before thread metadata existed have no row and are treated as active.
+func (e *entity) reopenThread(ctx context.Context, guildId int64, channel *model.Channel) error {
+	thread, err := e.thread.GetThread(ctx, guildId, channel.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSendMessage)
+	}
+	if !thread.Archived {
+		return nil
+	}
+
+	archived := false
+	thread, err = e.thread.UpdateThread(ctx, guildId, channel.Id, &archived, nil)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSendMessage)
+	}
+
+	evt := &mqmsg.UpdateThread{GuildId: guildId, Channel: dto.NewThreadChannel(channel, &thread)}
+	go func() {
+		if err := e.mqt.SendGuildUpdate(guildId, evt); err != nil {
+			e.log.Error("unable to send thread update event", slog.String("error", err.Error()))
+		}
+	}()
+	return nil
+}
+
+// recordThreadActivity moves the thread auto-archive deadline and adds the author to the thread members
+func (e *entity) recordThreadActivity(guildId, threadId, userId int64) {
+	ctx := context.Background()
+	if err := e.thread.SetLastActivity(ctx, guildId, threadId, time.Now()); err != nil {
+		e.log.Error("unable to set thread last activity", slog.String("error", err.Error()))
+	}
+	if err := e.tmemb.AddThreadMember(ctx, threadId, userId); err != nil {
+		e.log.Error("unable to add thread member", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,104 @@
+package message
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/msgsearch"
+)
+
+// ExecuteWebhook
+//
+//	@Summary		Execute webhook
+//	@Description	Posts a message into the channel of the webhook. The token from the webhook URL authenticates the request, no user token is needed. Attachments are not supported.
+//	@Tags			Message
+//	@Accept			json
+//	@Produce		json
+//	@Param			webhook_id	path		int64					true	"Webhook ID"
+//	@Param			token		path		string					true	"Webhook token"
+//	@Param			request		body		ExecuteWebhookRequest	true	"Message data"
+//	@Success		200			{object}	dto.Message				"Message"
+//	@failure		400			{string}	string					"Bad request"
+//	@failure		401			{string}	string					"Invalid webhook token"
+//	@failure		404			{string}	string					"Webhook not found"
+//	@failure		500			{string}	string					"Internal server error"
+//	@Router			/message/webhook/{webhook_id}/{token} [post]
+func (e *entity) ExecuteWebhook(c *fiber.Ctx) error {
+	webhookId, err := strconv.ParseInt(c.Params("webhook_id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrIncorrectWebhookID)
+	}
+
+	var req ExecuteWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	webhook, err := e.wh.GetWebhook(c.UserContext(), webhookId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fiber.NewError(fiber.StatusNotFound, ErrWebhookNotFound)
+	} else if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetWebhook)
+	}
+	if subtle.ConstantTimeCompare([]byte(helper.HashToken(c.Params("token"))), []byte(webhook.TokenHash)) != 1 {
+		return fiber.NewError(fiber.StatusUnauthorized, ErrInvalidWebhookToken)
+	}
+
+	channel, err := e.ch.GetChannel(c.UserContext(), webhook.ChannelId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "channel not found")
+	}
+	if channel.Type != model.ChannelTypeGuild {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToSentToThisChannel)
+	}
+	guildId := webhook.GuildId
+
+	var reference *model.Message
+	if req.MessageReference != nil {
+		// Webhooks have no permissions of their own, they can reply to any message of their channel
+		reference, err = e.validateMessageReference(c.UserContext(), channel.Id, nil, webhook.Id, *req.MessageReference)
+		if err != nil {
+			return err
+		}
+	}
+
+	req.Content, err = e.sanitizeWebhookEmojiContent(c.UserContext(), guildId, req.Content)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSendMessage)
+	}
+
+	name := webhook.Name
+	if req.Username != nil {
+		name = *req.Username
+	}
+	avatarURL := webhook.AvatarURL
+	if req.AvatarURL != nil {
+		avatarURL = req.AvatarURL
+	}
+	userData := &messageUserData{
+		User:          &model.User{Id: webhook.Id, Name: name},
+		Discriminator: &model.Discriminator{},
+		Webhook:       true,
+		AvatarURL:     avatarURL,
+	}
+
+	message, err := e.createAndSendMessage(c, &req.SendMessageRequest, userData, &channel, &guildId, reference, nil)
+	if err != nil {
+		return err
+	}
+
+	if msgsearch.HasURL(message.Content) {
+		go e.enqueueMakeEmbed(&guildId, message)
+	}
+
+	return c.JSON(message)
+}
@@ -255,7 +255,32 @@ func (e *entity) executeSearch(c *fiber.Ctx, req *MessageSearchRequest, guildID
 			embeds = nil
 		}
 
+		if model.HasMessageFlag(flags, model.MessageFlagWebhook) {
+			author := dto.User{Id: m.UserId}
+			if m.AuthorName != nil {
+				author.Name = *m.AuthorName
+			}
+			if m.AuthorAvatar != nil {
+				author.Avatar = &dto.AvatarData{URL: *m.AuthorAvatar}
+			}
+			resp.Messages = append(resp.Messages, dto.Message{
+				Id:          m.Id,
+				ChannelId:   m.ChannelId,
+				Author:      author,
+				Content:     m.Content,
+				Attachments: dtoAts,
+				Embeds:      embeds,
+				Flags:       flags,
+				Type:        m.Type,
+				UpdatedAt:   m.EditedAt,
+			})
+			continue
+		}
+
 		if u, ok := userMap[m.UserId]; ok {
+			if u.Bot {
+				flags |= model.MessageFlagBotAuthor
+			}
 			resp.Messages = append(resp.Messages, dto.Message{
 				Id:        m.Id,
 				ChannelId: m.ChannelId,
@@ -263,6 +288,7 @@ func (e *entity) executeSearch(c *fiber.Ctx, req *MessageSearchRequest, guildID
 					Id:            u.Id,
 					Name:          u.Name,
 					Discriminator: "",
+					Bot:           u.Bot,
 				},
 				Content:     m.Content,
 				Attachments: dtoAts,
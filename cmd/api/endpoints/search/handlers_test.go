@@ -25,6 +25,9 @@ func (f *fakeChannelRepo) GetChannelsBulk(ctx context.Context, ids []int64) ([]m
 func (f *fakeChannelRepo) GetChannelThreads(ctx context.Context, channelId int64) ([]model.Channel, error) {
 	return nil, nil
 }
+func (f *fakeChannelRepo) GetChannelsAfter(ctx context.Context, afterId int64, limit int) ([]model.Channel, error) {
+	return nil, nil
+}
 func (f *fakeChannelRepo) CreateChannel(ctx context.Context, id int64, name string, channelType model.ChannelType, parent *int64, permissions *int64, private bool) error {
 	return nil
 }
@@ -50,7 +53,7 @@ func (f *fakeChannelRepo) SetChannelParentBulk(ctx context.Context, id []int64,
 func (f *fakeChannelRepo) SetLastMessage(ctx context.Context, id, lastMessage int64) error {
 	return nil
 }
-func (f *fakeChannelRepo) UpdateChannel(ctx context.Context, id int64, parent *int64, private *bool, name, topic *string) (model.Channel, error) {
+func (f *fakeChannelRepo) UpdateChannel(ctx context.Context, id int64, parent *int64, private *bool, name, topic *string, userLimit, bitrate *int, stage *bool) (model.Channel, error) {
 	return model.Channel{}, nil
 }
 func (f *fakeChannelRepo) SetChannelVoiceRegion(ctx context.Context, id int64, region *string) error {
@@ -0,0 +1,202 @@
+package user
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+// GetBlockedUsers
+//
+//	@Summary	Get blocked users
+//	@Produce	json
+//	@Tags		User
+//	@Success	200	{array}		dto.User	"Blocked users"
+//	@failure	400	{string}	string		"Bad request"
+//	@failure	500	{string}	string		"Internal server error"
+//	@Router		/user/me/blocks [get]
+func (e *entity) GetBlockedUsers(c *fiber.Ctx) error {
+	me, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	blocked, err := e.block.GetBlockedUsers(c.UserContext(), me.Id)
+	if err != nil {
+		return helper.HttpDbError(err, ErrUnableToGetBlockedUsers)
+	}
+	if len(blocked) == 0 {
+		return c.JSON([]dto.User{})
+	}
+
+	ids := make([]int64, len(blocked))
+	for i, b := range blocked {
+		ids[i] = b.BlockedUserId
+	}
+	users, err := e.user.GetUsersList(c.UserContext(), ids)
+	if err != nil {
+		return helper.HttpDbError(err, ErrUnableToGetUser)
+	}
+	discs, err := e.disc.GetDiscriminatorsByUserIDs(c.UserContext(), ids)
+	if err != nil {
+		return helper.HttpDbError(err, ErrUnableToGetDiscriminator)
+	}
+	return c.JSON(usersWithDiscriminators(users, discs))
+}
+
+// BlockUser
+//
+//	@Summary		Block user
+//	@Description	Blocked users can't send DMs or friend requests to the user and their mentions, typing and presence are hidden. Blocking removes the friendship and pending friend requests between the users.
+//	@Produce		json
+//	@Tags			User
+//	@Param			user_id	path		int64	true	"User id"	example(2230469276416868352)
+//	@Success		200		{string}	string	"ok"
+//	@failure		400		{string}	string	"Bad request"
+//	@failure		404		{string}	string	"User not found"
+//	@failure		500		{string}	string	"Internal server error"
+//	@Router			/user/me/blocks/{user_id} [put]
+func (e *entity) BlockUser(c *fiber.Ctx) error {
+	me, targetId, err := e.parseBlockRequest(c)
+	if err != nil {
+		return err
+	}
+	if _, err := e.validateRecipient(c, targetId); err != nil {
+		return err
+	}
+
+	if err := e.block.BlockUser(c.UserContext(), me.Id, targetId); err != nil {
+		return helper.HttpDbError(err, ErrUnableToBlockUser)
+	}
+	if err := e.blocks.Forget(c.UserContext(), me.Id); err != nil {
+		e.log.Error("unable to drop cached blocked users", slog.Int64("user_id", me.Id), slog.String("error", err.Error()))
+	}
+
+	friends, err := e.fr.IsFriend(c.UserContext(), me.Id, targetId)
+	if err != nil {
+		return helper.HttpDbError(err, ErrUnableToBlockUser)
+	}
+	if friends {
+		if err := e.fr.RemoveFriend(c.UserContext(), me.Id, targetId); err != nil {
+			return helper.HttpDbError(err, ErrUnableToRemoveFriend)
+		}
+	}
+	// Pending requests in both directions
+	if err := e.fr.RemoveFriendRequest(c.UserContext(), me.Id, targetId); err != nil {
+		return helper.HttpDbError(err, ErrUnableToBlockUser)
+	}
+	if err := e.fr.RemoveFriendRequest(c.UserContext(), targetId, me.Id); err != nil {
+		return helper.HttpDbError(err, ErrUnableToBlockUser)
+	}
+
+	go e.sendBlockEvents(me.Id, targetId, true, friends)
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// UnblockUser
+//
+//	@Summary	Unblock user
+//	@Produce	json
+//	@Tags		User
+//	@Param		user_id	path		int64	true	"User id"	example(2230469276416868352)
+//	@Success	200		{string}	string	"ok"
+//	@failure	400		{string}	string	"Bad request"
+//	@failure	500		{string}	string	"Internal server error"
+//	@Router		/user/me/blocks/{user_id} [delete]
+func (e *entity) UnblockUser(c *fiber.Ctx) error {
+	me, targetId, err := e.parseBlockRequest(c)
+	if err != nil {
+		return err
+	}
+
+	if err := e.block.UnblockUser(c.UserContext(), me.Id, targetId); err != nil {
+		return helper.HttpDbError(err, ErrUnableToUnblockUser)
+	}
+	if err := e.blocks.Forget(c.UserContext(), me.Id); err != nil {
+		e.log.Error("unable to drop cached blocked users", slog.Int64("user_id", me.Id), slog.String("error", err.Error()))
+	}
+
+	go e.sendBlockEvents(me.Id, targetId, false, false)
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// parseBlockRequest returns the user of the request and the user to block or unblock
+func (e *entity) parseBlockRequest(c *fiber.Ctx) (*helper.JWTUser, int64, error) {
+	me, err := helper.GetUser(c)
+	if err != nil {
+		return nil, 0, fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+	targetId, err := strconv.ParseInt(c.Params("user_id"), 10, 64)
+	if err != nil {
+		return nil, 0, fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseID)
+	}
+	if targetId == me.Id {
+		return nil, 0, fiber.NewError(fiber.StatusBadRequest, ErrBadRequest)
+	}
+	return me, targetId, nil
+}
+
+// checkNotBlocked rejects the request if one of the users blocked the other
+func (e *entity) checkNotBlocked(ctx context.Context, userId, targetId int64) error {
+	blocked, err := e.blocks.BlockedEither(ctx, userId, targetId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetBlockedUsers)
+	}
+	if blocked {
+		return fiber.NewError(fiber.StatusForbidden, ErrUserBlocked)
+	}
+	return nil
+}
+
+// sendBlockEvents notifies the user sessions about the block change and both users about the removed friendship.
+// The sessions use the block events to hide typing and presence of blocked users.
+func (e *entity) sendBlockEvents(userId, targetId int64, block, unfriended bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	target, err := e.userBrief(ctx, targetId)
+	if err != nil {
+		e.log.Error("unable to get blocked user", slog.Int64("user_id", targetId), slog.String("error", err.Error()))
+		return
+	}
+	var event mqmsg.EventDataMessage = &mqmsg.UserBlockRemove{User: target}
+	if block {
+		event = &mqmsg.UserBlockAdd{User: target}
+	}
+	if err := e.mqt.SendUserUpdate(userId, event); err != nil {
+		e.log.Error("unable to send block event", slog.Int64("user_id", userId), slog.String("error", err.Error()))
+	}
+	if !unfriended {
+		return
+	}
+
+	if err := e.mqt.SendUserUpdate(userId, &mqmsg.FriendRemoved{Friend: target}); err != nil {
+		e.log.Error("unable to send friend removed event", slog.Int64("user_id", userId), slog.String("error", err.Error()))
+	}
+	user, err := e.userBrief(ctx, userId)
+	if err != nil {
+		e.log.Error("unable to get user", slog.Int64("user_id", userId), slog.String("error", err.Error()))
+		return
+	}
+	if err := e.mqt.SendUserUpdate(targetId, &mqmsg.FriendRemoved{Friend: user}); err != nil {
+		e.log.Error("unable to send friend removed event", slog.Int64("user_id", targetId), slog.String("error", err.Error()))
+	}
+}
+
+// userBrief returns the event payload of the user
+func (e *entity) userBrief(ctx context.Context, userId int64) (mqmsg.UserBrief, error) {
+	u, err := e.fetchUserWithDiscriminatorCtx(ctx, userId)
+	if err != nil {
+		return mqmsg.UserBrief{}, err
+	}
+	return mqmsg.UserBrief{Id: u.Id, Name: u.Name, Discriminator: u.Discriminator, AvatarData: u.Avatar}, nil
+}
@@ -0,0 +1,186 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/cache"
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+	"github.com/FlameInTheDark/gochat/internal/userblock"
+)
+
+type friendPair struct {
+	userId, friendId int64
+}
+
+type fakeFriendRepo struct {
+	friends  map[friendPair]bool
+	requests map[friendPair]bool
+}
+
+func (f *fakeFriendRepo) AddFriend(ctx context.Context, userID, friendID int64) error {
+	f.friends[friendPair{userID, friendID}] = true
+	f.friends[friendPair{friendID, userID}] = true
+	return nil
+}
+func (f *fakeFriendRepo) RemoveFriend(ctx context.Context, userID, friendID int64) error {
+	delete(f.friends, friendPair{userID, friendID})
+	delete(f.friends, friendPair{friendID, userID})
+	return nil
+}
+func (f *fakeFriendRepo) GetFriends(ctx context.Context, userID int64) ([]model.Friend, error) {
+	return nil, nil
+}
+func (f *fakeFriendRepo) CreateFriendRequest(ctx context.Context, userId, friendId int64) error {
+	// Requests are stored by the recipient
+	f.requests[friendPair{friendId, userId}] = true
+	return nil
+}
+func (f *fakeFriendRepo) RemoveFriendRequest(ctx context.Context, userId, friendId int64) error {
+	delete(f.requests, friendPair{userId, friendId})
+	return nil
+}
+func (f *fakeFriendRepo) GetFriendRequests(ctx context.Context, userId int64) ([]model.FriendRequest, error) {
+	return nil, nil
+}
+func (f *fakeFriendRepo) IsFriend(ctx context.Context, userId, friendId int64) (bool, error) {
+	return f.friends[friendPair{userId, friendId}], nil
+}
+
+type fakeBlockRepo struct {
+	blocked map[int64][]int64
+}
+
+func (f *fakeBlockRepo) BlockUser(ctx context.Context, userId, blockedUserId int64) error {
+	if !slices.Contains(f.blocked[userId], blockedUserId) {
+		f.blocked[userId] = append(f.blocked[userId], blockedUserId)
+	}
+	return nil
+}
+func (f *fakeBlockRepo) UnblockUser(ctx context.Context, userId, blockedUserId int64) error {
+	f.blocked[userId] = slices.DeleteFunc(f.blocked[userId], func(id int64) bool { return id == blockedUserId })
+	return nil
+}
+func (f *fakeBlockRepo) IsBlocked(ctx context.Context, userId, blockedUserId int64) (bool, error) {
+	return slices.Contains(f.blocked[userId], blockedUserId), nil
+}
+func (f *fakeBlockRepo) GetBlockedUsers(ctx context.Context, userId int64) ([]model.BlockedUser, error) {
+	out := make([]model.BlockedUser, 0, len(f.blocked[userId]))
+	for _, id := range f.blocked[userId] {
+		out = append(out, model.BlockedUser{UserId: userId, BlockedUserId: id})
+	}
+	return out, nil
+}
+
+// fakeNoCache misses every lookup, so block checks always read the repository
+type fakeNoCache struct {
+	cache.Cache
+}
+
+func (f *fakeNoCache) GetJSON(ctx context.Context, key string, v interface{}) error {
+	return errors.New("not found")
+}
+func (f *fakeNoCache) SetTimedJSON(ctx context.Context, key string, val interface{}, ttl int64) error {
+	return nil
+}
+func (f *fakeNoCache) Delete(ctx context.Context, key string) error {
+	return nil
+}
+
+func newBlockTestEntity() (*entity, *fakeFriendRepo, *fakeBlockRepo, *fakeTransport) {
+	friends := &fakeFriendRepo{
+		friends:  map[friendPair]bool{{10, 20}: true, {20, 10}: true},
+		requests: map[friendPair]bool{},
+	}
+	blocks := &fakeBlockRepo{blocked: map[int64][]int64{}}
+	transport := &fakeTransport{userEvents: make(chan userEvent, 8)}
+	e := &entity{
+		log:    slog.New(slog.DiscardHandler),
+		mqt:    transport,
+		user:   &fakeUserRepo{},
+		disc:   &fakeDiscriminatorRepo{},
+		fr:     friends,
+		block:  blocks,
+		blocks: userblock.NewChecker(blocks, &fakeNoCache{}),
+	}
+	return e, friends, blocks, transport
+}
+
+func TestBlockUserRemovesFriendship(t *testing.T) {
+	e, friends, blocks, transport := newBlockTestEntity()
+	friends.requests[friendPair{10, 20}] = true
+	app := newUserTestApp(10, "/user/me/blocks/:user_id", e.BlockUser)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPut, "/user/me/blocks/20", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if !slices.Contains(blocks.blocked[10], 20) {
+		t.Fatal("expected user to be blocked")
+	}
+	if len(friends.friends) != 0 || len(friends.requests) != 0 {
+		t.Fatalf("expected friendship and requests to be removed, got %v, %v", friends.friends, friends.requests)
+	}
+
+	var blockEvents, removed int
+	for _, ev := range collectUserEvents(t, transport, 3) {
+		switch m := ev.event.(type) {
+		case *mqmsg.UserBlockAdd:
+			if ev.userId != 10 || m.User.Id != 20 {
+				t.Fatalf("unexpected block event for %d: %+v", ev.userId, m)
+			}
+			blockEvents++
+		case *mqmsg.FriendRemoved:
+			removed++
+		}
+	}
+	if blockEvents != 1 || removed != 2 {
+		t.Fatalf("expected block event and friend removal for both users, got %d and %d", blockEvents, removed)
+	}
+}
+
+func TestBlockUserRejectsSelf(t *testing.T) {
+	e, _, blocks, _ := newBlockTestEntity()
+	app := newUserTestApp(10, "/user/me/blocks/:user_id", e.BlockUser)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPut, "/user/me/blocks/10", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", resp.StatusCode)
+	}
+	if len(blocks.blocked) != 0 {
+		t.Fatal("expected no block")
+	}
+}
+
+func TestCreateFriendRequestRejectsBlockedUser(t *testing.T) {
+	e, friends, blocks, _ := newBlockTestEntity()
+	blocks.blocked[30] = []int64{10}
+	app := newUserTestApp(10, "/user/me/friends", e.CreateFriendRequest)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/user/me/friends", strings.NewReader(`{"discriminator":"user-30"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", resp.StatusCode)
+	}
+	if len(friends.requests) != 0 {
+		t.Fatal("expected no friend request")
+	}
+}
@@ -1,8 +1,11 @@
 package user
 
 import (
+	"context"
+
 	"github.com/gofiber/fiber/v2"
 
+	"github.com/FlameInTheDark/gochat/internal/database/model"
 	"github.com/FlameInTheDark/gochat/internal/dto"
 	"github.com/FlameInTheDark/gochat/internal/helper"
 )
@@ -59,6 +62,11 @@ func (e *entity) GetMyDMChannels(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to get dm last messages")
 	}
 
+	groups, recipients, err := e.getGroupDMsData(c.UserContext(), gdms)
+	if err != nil {
+		return helper.HttpDbError(err, ErrUnableToGetGroupDMChannel)
+	}
+
 	result := make([]dto.Channel, len(chs))
 	for i := range chs {
 		var pid *int64
@@ -66,6 +74,40 @@ func (e *entity) GetMyDMChannels(c *fiber.Ctx) error {
 			pid = &v
 		}
 		result[i] = dmChannelModelToDTO(&chs[i], last, pid)
+		if g, ok := groups[chs[i].Id]; ok {
+			result[i].OwnerId = &g.OwnerId
+			result[i].Recipients = recipients[chs[i].Id]
+			if g.Icon != nil {
+				result[i].Icon = e.groupDMIcon(c.UserContext(), g.ChannelId, *g.Icon)
+			}
+		}
 	}
 	return c.JSON(result)
 }
+
+// getGroupDMsData loads the settings and the recipients of the user's group DMs
+func (e *entity) getGroupDMsData(ctx context.Context, gdms []model.GroupDMChannel) (map[int64]model.GroupDM, map[int64][]int64, error) {
+	ids := make([]int64, len(gdms))
+	for i, g := range gdms {
+		ids[i] = g.ChannelId
+	}
+
+	settings, err := e.gdm.GetGroupDms(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	groups := make(map[int64]model.GroupDM, len(settings))
+	for _, g := range settings {
+		groups[g.ChannelId] = g
+	}
+
+	rows, err := e.gdm.GetGroupDmParticipantsMany(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	recipients := make(map[int64][]int64, len(ids))
+	for _, r := range rows {
+		recipients[r.ChannelId] = append(recipients[r.ChannelId], r.UserId)
+	}
+	return groups, recipients, nil
+}
@@ -12,8 +12,10 @@ import (
 	"github.com/FlameInTheDark/gochat/internal/database/entities/guildchannelmessages"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/icon"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/mention"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/message"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/readstates"
 	"github.com/FlameInTheDark/gochat/internal/database/pgdb"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/blocked"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/channel"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/discriminator"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/dmchannel"
@@ -26,8 +28,11 @@ import (
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/user"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/userrole"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/usersettings"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/indexmq"
 	"github.com/FlameInTheDark/gochat/internal/mq"
 	"github.com/FlameInTheDark/gochat/internal/server"
+	"github.com/FlameInTheDark/gochat/internal/userblock"
 )
 
 const entityName = "user"
@@ -40,6 +45,11 @@ func (e *entity) Init(router fiber.Router) {
 	router.Delete("/me/guilds/:guild_id<int>", e.LeaveGuild)
 	router.Get("/me/channels", e.GetMyDMChannels)
 	router.Post("/me/channels", e.CreateDM)
+	router.Post("/me/channels/group", e.CreateGroupDM)
+	router.Patch("/me/channels/:channel_id<int>", e.UpdateGroupDM)
+	router.Post("/me/channels/:channel_id<int>/icon", e.CreateGroupDMIcon)
+	router.Put("/me/channels/:channel_id<int>/recipients/:user_id<int>", e.AddGroupDMRecipient)
+	router.Delete("/me/channels/:channel_id<int>/recipients/:user_id<int>", e.RemoveGroupDMRecipient)
 
 	router.Post("/me/avatar", e.CreateAvatar)
 	router.Get("/me/avatars", e.ListAvatars)
@@ -53,15 +63,25 @@ func (e *entity) Init(router fiber.Router) {
 	router.Post("/me/friends/requests", e.AcceptFriendRequest)
 	router.Delete("/me/friends/requests", e.DeclineFriendRequest)
 
+	router.Get("/me/blocks", e.GetBlockedUsers)
+	router.Put("/me/blocks/:user_id<int>", e.BlockUser)
+	router.Delete("/me/blocks/:user_id<int>", e.UnblockUser)
+
 	router.Get("/me/settings", e.GetUserSettings)
 	router.Post("/me/settings", e.SetUserSettings)
 }
 
+// messageIndexer publishes messages to the search indexer
+type messageIndexer interface {
+	IndexMessage(msg dto.IndexMessage) error
+}
+
 type entity struct {
 	name string
 
 	log   *slog.Logger
 	mqt   mq.SendTransporter
+	imq   messageIndexer
 	cache cache.Cache
 
 	user    user.User
@@ -73,6 +93,8 @@ type entity struct {
 	gdm     groupdmchannel.GroupDMChannel
 	disc    discriminator.Discriminator
 	fr      friend.Friend
+	block   blocked.Blocked
+	blocks  *userblock.Checker
 	uset    usersettings.UserSettings
 	rs      readstates.ReadStates
 	gclm    guildchannelmessages.GuildChannelMessages
@@ -80,6 +102,7 @@ type entity struct {
 	av      avatar.Avatar
 	icon    icon.Icon
 	mention mention.Mention
+	msg     message.Message
 	gc      guildchannels.GuildChannels
 	emoji   emojirepo.Emoji
 
@@ -91,11 +114,12 @@ func (e *entity) Name() string {
 	return e.name
 }
 
-func New(cql *db.CQLCon, pg *pgdb.DB, mqt mq.SendTransporter, cache cache.Cache, attachTTLSeconds int64, contentHosts []string, log *slog.Logger) server.Entity {
+func New(cql *db.CQLCon, pg *pgdb.DB, mqt mq.SendTransporter, imq *indexmq.IndexMQ, cache cache.Cache, attachTTLSeconds int64, contentHosts []string, log *slog.Logger) server.Entity {
 	return &entity{
 		name:         entityName,
 		log:          log,
 		mqt:          mqt,
+		imq:          imq,
 		cache:        cache,
 		attachTTL:    attachTTLSeconds,
 		contentHosts: append([]string(nil), contentHosts...),
@@ -108,6 +132,8 @@ func New(cql *db.CQLCon, pg *pgdb.DB, mqt mq.SendTransporter, cache cache.Cache,
 		gdm:          groupdmchannel.New(pg.Conn()),
 		disc:         discriminator.New(pg.Conn()),
 		fr:           friend.New(pg.Conn()),
+		block:        blocked.New(pg.Conn()),
+		blocks:       userblock.NewChecker(blocked.New(pg.Conn()), cache),
 		uset:         usersettings.New(pg.Conn()),
 		rs:           readstates.New(cql),
 		gclm:         guildchannelmessages.New(cql),
@@ -115,6 +141,7 @@ func New(cql *db.CQLCon, pg *pgdb.DB, mqt mq.SendTransporter, cache cache.Cache,
 		av:           avatar.New(cql),
 		icon:         icon.New(cql),
 		mention:      mention.New(cql),
+		msg:          message.New(cql),
 		gc:           guildchannels.New(pg.Conn()),
 		emoji:        emojirepo.New(pg.Conn()),
 	}
@@ -124,6 +124,7 @@ func (e *entity) GetOrCreateFriendDM(c *fiber.Ctx) error {
 //	@Param		request	body		CreateFriendRequestRequest	true	"Friend request"
 //	@Success	200		{string}	string						"ok"
 //	@failure	400		{string}	string						"Bad request"
+//	@failure	403		{string}	string						"User is blocked"
 //	@failure	500		{string}	string						"Internal server error"
 //	@Router		/user/me/friends [post]
 func (e *entity) CreateFriendRequest(c *fiber.Ctx) error {
@@ -148,6 +149,9 @@ func (e *entity) CreateFriendRequest(c *fiber.Ctx) error {
 	if disc.UserId == me.Id {
 		return fiber.NewError(fiber.StatusBadRequest, ErrBadRequest)
 	}
+	if err := e.checkNotBlocked(c.UserContext(), me.Id, disc.UserId); err != nil {
+		return err
+	}
 
 	if err := e.fr.CreateFriendRequest(c.UserContext(), me.Id, disc.UserId); err != nil {
 		return helper.HttpDbError(err, ErrUnableToCreateFriendRequest)
@@ -302,6 +306,7 @@ func (e *entity) GetFriendRequests(c *fiber.Ctx) error {
 //	@Param		request	body		FriendRequestAction	true	"Accept"
 //	@Success	200		{string}	string				"ok"
 //	@failure	400		{string}	string				"Bad request"
+//	@failure	403		{string}	string				"User is blocked"
 //	@failure	500		{string}	string				"Internal server error"
 //	@Router		/user/me/friends/requests [post]
 func (e *entity) AcceptFriendRequest(c *fiber.Ctx) error {
@@ -320,6 +325,9 @@ func (e *entity) AcceptFriendRequest(c *fiber.Ctx) error {
 	if req.UserId == me.Id {
 		return fiber.NewError(fiber.StatusBadRequest, ErrBadRequest)
 	}
+	if err := e.checkNotBlocked(c.UserContext(), me.Id, req.UserId); err != nil {
+		return err
+	}
 
 	if err := e.fr.AddFriend(c.UserContext(), me.Id, req.UserId); err != nil {
 		return helper.HttpDbError(err, ErrUnableToAcceptFriendRequest)
@@ -0,0 +1,471 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+// CreateGroupDM
+//
+//	@Summary		Create group DM channel
+//	@Description	Creates a group DM with the current user as the owner. A group DM holds at most 10 users including the owner.
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		CreateGroupDMRequest	true	"Group DM data"
+//	@Success		200		{object}	dto.Channel				"Created group DM channel"
+//	@failure		400		{string}	string					"Bad request"
+//	@failure		404		{string}	string					"Recipient not found"
+//	@failure		500		{string}	string					"Internal server error"
+//	@Router			/user/me/channels/group [post]
+func (e *entity) CreateGroupDM(c *fiber.Ctx) error {
+	var req CreateGroupDMRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseRequestBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	// The creator is always a recipient, duplicates are ignored
+	participants := []int64{user.Id}
+	for _, id := range req.RecipientsId {
+		if !slices.Contains(participants, id) {
+			participants = append(participants, id)
+		}
+	}
+	if len(participants) < 2 {
+		return fiber.NewError(fiber.StatusBadRequest, ErrRecipientsRequired)
+	}
+	if err := e.validateRecipients(c, participants[1:]); err != nil {
+		return err
+	}
+
+	var name string
+	if req.Name != nil {
+		name = *req.Name
+	}
+	channelId := idgen.Next()
+	if err := e.ch.CreateChannel(c.UserContext(), channelId, name, model.ChannelTypeGroupDM, nil, nil, false); err != nil {
+		return helper.HttpDbError(err, ErrUnableToCreateChannel)
+	}
+	if err := e.gdm.CreateGroupDm(c.UserContext(), channelId, user.Id, participants); err != nil {
+		// Cleanup: delete the channel if adding participants fails
+		if cleanupErr := e.ch.DeleteChannel(c.UserContext(), channelId); cleanupErr != nil {
+			e.log.Error("failed to cleanup channel after participant join failure",
+				"channel_id", channelId,
+				"cleanup_error", cleanupErr.Error(),
+				"original_error", err.Error())
+		}
+		return helper.HttpDbError(err, ErrUnableToJoingGroupDmChannel)
+	}
+
+	channel := model.Channel{Id: channelId, Name: name, Type: model.ChannelTypeGroupDM, CreatedAt: time.Now()}
+	result := e.groupDMToDTO(c.UserContext(), &channel, model.GroupDM{ChannelId: channelId, OwnerId: user.Id}, participants)
+
+	go e.sendGroupDMEvent(participants, &mqmsg.CreateChannel{Channel: result})
+
+	return c.JSON(result)
+}
+
+// UpdateGroupDM
+//
+//	@Summary		Update group DM channel
+//	@Description	Renames the group DM or sets its icon. Any recipient can do it. Only the owner can transfer the ownership to another recipient.
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			channel_id	path		int64					true	"Channel ID"	example(2230469276416868352)
+//	@Param			request		body		UpdateGroupDMRequest	true	"Group DM data"
+//	@Success		200			{object}	dto.Channel				"Updated group DM channel"
+//	@failure		400			{string}	string					"Bad request"
+//	@failure		403			{string}	string					"Not a recipient or not the owner"
+//	@failure		404			{string}	string					"Channel or icon not found"
+//	@failure		500			{string}	string					"Internal server error"
+//	@Router			/user/me/channels/{channel_id} [patch]
+func (e *entity) UpdateGroupDM(c *fiber.Ctx) error {
+	var req UpdateGroupDMRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseRequestBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+	channel, gdm, participants, err := e.getGroupDM(c)
+	if err != nil {
+		return err
+	}
+
+	if req.OwnerId != nil && *req.OwnerId != gdm.OwnerId {
+		if gdm.OwnerId != user.Id {
+			return fiber.NewError(fiber.StatusForbidden, ErrNotGroupDMOwner)
+		}
+		if !slices.Contains(participants, *req.OwnerId) {
+			return fiber.NewError(fiber.StatusBadRequest, ErrGroupDMRecipientNotFound)
+		}
+	}
+	if req.IconId != nil {
+		ic, err := e.icon.GetIcon(c.UserContext(), *req.IconId, channel.Id)
+		if err != nil {
+			return helper.HttpDbError(err, ErrUnableToGetIcon)
+		}
+		if !ic.Done || ic.URL == nil {
+			return fiber.NewError(fiber.StatusBadRequest, ErrIconNotUploaded)
+		}
+	}
+
+	if req.Name != nil {
+		if err := e.ch.RenameChannel(c.UserContext(), channel.Id, *req.Name); err != nil {
+			return helper.HttpDbError(err, ErrUnableToUpdateGroupDM)
+		}
+		channel.Name = *req.Name
+	}
+	if req.IconId != nil {
+		if err := e.gdm.SetGroupDmIcon(c.UserContext(), channel.Id, req.IconId); err != nil {
+			return helper.HttpDbError(err, ErrUnableToUpdateGroupDM)
+		}
+		gdm.Icon = req.IconId
+	}
+	if req.OwnerId != nil && *req.OwnerId != gdm.OwnerId {
+		if err := e.gdm.SetGroupDmOwner(c.UserContext(), channel.Id, *req.OwnerId); err != nil {
+			return helper.HttpDbError(err, ErrUnableToUpdateGroupDM)
+		}
+		gdm.OwnerId = *req.OwnerId
+	}
+
+	result := e.groupDMToDTO(c.UserContext(), &channel, gdm, participants)
+	go e.sendGroupDMEvent(participants, &mqmsg.UpdateChannel{Channel: result})
+
+	return c.JSON(result)
+}
+
+// CreateGroupDMIcon
+//
+//	@Summary		Create group DM icon metadata
+//	@Description	Creates an icon placeholder for the group DM and returns upload info. Upload the binary to the attachments service, then set it with the update group DM endpoint.
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			channel_id	path		int64						true	"Channel ID"	example(2230469276416868352)
+//	@Param			request		body		CreateGroupDMIconRequest	true	"Icon creation request"
+//	@Success		200			{object}	dto.IconUpload				"Icon upload data"
+//	@failure		400			{string}	string						"Bad request"
+//	@failure		403			{string}	string						"Not a recipient"
+//	@failure		413			{string}	string						"File too large"
+//	@failure		415			{string}	string						"Unsupported Media Type"
+//	@failure		500			{string}	string						"Internal server error"
+//	@Router			/user/me/channels/{channel_id}/icon [post]
+func (e *entity) CreateGroupDMIcon(c *fiber.Ctx) error {
+	var req CreateGroupDMIconRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseRequestBody)
+	}
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	channel, _, _, err := e.getGroupDM(c)
+	if err != nil {
+		return err
+	}
+
+	// Group DM icons are stored with the guild icons, partitioned by the channel ID
+	id := idgen.Next()
+	if err := e.icon.CreateIcon(c.UserContext(), id, channel.Id, e.attachTTL, req.FileSize); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateIcon)
+	}
+
+	return c.JSON(dto.IconUpload{Id: id, ChannelId: channel.Id})
+}
+
+// AddGroupDMRecipient
+//
+//	@Summary		Add group DM recipient
+//	@Description	Adds a user to the group DM. Any recipient can add users until the group DM is full.
+//	@Tags			User
+//	@Produce		json
+//	@Param			channel_id	path		int64		true	"Channel ID"	example(2230469276416868352)
+//	@Param			user_id		path		int64		true	"User ID"		example(2230469276416868352)
+//	@Success		200			{object}	dto.Channel	"Group DM channel"
+//	@failure		400			{string}	string		"Bad request"
+//	@failure		403			{string}	string		"Not a recipient"
+//	@failure		404			{string}	string		"Channel or user not found"
+//	@failure		409			{string}	string		"Group DM is full"
+//	@failure		500			{string}	string		"Internal server error"
+//	@Router			/user/me/channels/{channel_id}/recipients/{user_id} [put]
+func (e *entity) AddGroupDMRecipient(c *fiber.Ctx) error {
+	recipientId, err := e.parseUserIdParam(c, "user_id")
+	if err != nil {
+		return err
+	}
+	channel, gdm, participants, err := e.getGroupDM(c)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(participants, recipientId) {
+		return c.JSON(e.groupDMToDTO(c.UserContext(), &channel, gdm, participants))
+	}
+	if len(participants) >= MaxGroupDMRecipients {
+		return fiber.NewError(fiber.StatusConflict, ErrGroupDMFull)
+	}
+	if _, err := e.validateRecipient(c, recipientId); err != nil {
+		return err
+	}
+
+	if err := e.gdm.JoinGroupDmChannel(c.UserContext(), channel.Id, recipientId); err != nil {
+		return helper.HttpDbError(err, ErrUnableToJoingGroupDmChannel)
+	}
+
+	result := e.groupDMToDTO(c.UserContext(), &channel, gdm, append(participants, recipientId))
+	go func() {
+		e.sendGroupDMEvent([]int64{recipientId}, &mqmsg.CreateChannel{Channel: result})
+		e.sendGroupDMRecipientEvents(&channel, participants, recipientId, model.MessageTypeRecipientAdd)
+	}()
+
+	return c.JSON(result)
+}
+
+// RemoveGroupDMRecipient
+//
+//	@Summary		Remove group DM recipient
+//	@Description	Removes a user from the group DM. Recipients can remove themselves to leave, only the owner can remove others. When the owner leaves the ownership moves to another recipient, when the last recipient leaves the group DM is deleted.
+//	@Tags			User
+//	@Produce		json
+//	@Param			channel_id	path		int64	true	"Channel ID"	example(2230469276416868352)
+//	@Param			user_id		path		int64	true	"User ID"		example(2230469276416868352)
+//	@Success		200			{string}	string	"Removed"
+//	@failure		400			{string}	string	"Bad request"
+//	@failure		403			{string}	string	"Not a recipient or not the owner"
+//	@failure		404			{string}	string	"Channel or recipient not found"
+//	@failure		500			{string}	string	"Internal server error"
+//	@Router			/user/me/channels/{channel_id}/recipients/{user_id} [delete]
+func (e *entity) RemoveGroupDMRecipient(c *fiber.Ctx) error {
+	recipientId, err := e.parseUserIdParam(c, "user_id")
+	if err != nil {
+		return err
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+	channel, gdm, participants, err := e.getGroupDM(c)
+	if err != nil {
+		return err
+	}
+
+	if recipientId != user.Id && gdm.OwnerId != user.Id {
+		return fiber.NewError(fiber.StatusForbidden, ErrNotGroupDMOwner)
+	}
+	if !slices.Contains(participants, recipientId) {
+		return fiber.NewError(fiber.StatusNotFound, ErrGroupDMRecipientNotFound)
+	}
+
+	remaining := slices.DeleteFunc(slices.Clone(participants), func(id int64) bool { return id == recipientId })
+	removed := &mqmsg.DeleteChannel{ChannelType: model.ChannelTypeGroupDM, ChannelId: channel.Id}
+
+	// The last recipient is leaving, nobody can see the group DM anymore
+	if len(remaining) == 0 {
+		if err := e.gdm.DeleteGroupDm(c.UserContext(), channel.Id); err != nil {
+			return helper.HttpDbError(err, ErrUnableToLeaveGroupDM)
+		}
+		if err := e.ch.DeleteChannel(c.UserContext(), channel.Id); err != nil {
+			e.log.Error("unable to delete empty group dm channel", slog.Int64("channel_id", channel.Id), slog.String("error", err.Error()))
+		}
+		go e.sendGroupDMEvent([]int64{recipientId}, removed)
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	if err := e.gdm.LeaveGroupDmChannel(c.UserContext(), channel.Id, recipientId); err != nil {
+		return helper.HttpDbError(err, ErrUnableToLeaveGroupDM)
+	}
+	ownerChanged := false
+	if gdm.OwnerId == recipientId {
+		gdm.OwnerId = slices.Min(remaining)
+		if err := e.gdm.SetGroupDmOwner(c.UserContext(), channel.Id, gdm.OwnerId); err != nil {
+			return helper.HttpDbError(err, ErrUnableToUpdateGroupDM)
+		}
+		ownerChanged = true
+	}
+
+	go func() {
+		e.sendGroupDMEvent([]int64{recipientId}, removed)
+		e.sendGroupDMRecipientEvents(&channel, remaining, recipientId, model.MessageTypeRecipientRemove)
+		if ownerChanged {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+			defer cancel()
+			e.sendGroupDMEvent(remaining, &mqmsg.UpdateChannel{Channel: e.groupDMToDTO(ctx, &channel, gdm, remaining)})
+		}
+	}()
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// getGroupDM loads the group DM from the URL after checking that the current user is a recipient
+func (e *entity) getGroupDM(c *fiber.Ctx) (model.Channel, model.GroupDM, []int64, error) {
+	channelId, err := strconv.ParseInt(c.Params("channel_id"), 10, 64)
+	if err != nil {
+		return model.Channel{}, model.GroupDM{}, nil, fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseID)
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return model.Channel{}, model.GroupDM{}, nil, fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	channel, err := e.ch.GetChannel(c.UserContext(), channelId)
+	if err != nil {
+		return model.Channel{}, model.GroupDM{}, nil, helper.HttpDbError(err, ErrUnableToGetChannel)
+	}
+	if channel.Type != model.ChannelTypeGroupDM {
+		return model.Channel{}, model.GroupDM{}, nil, fiber.NewError(fiber.StatusBadRequest, ErrNotGroupDMChannel)
+	}
+
+	rows, err := e.gdm.GetGroupDmParticipants(c.UserContext(), channelId)
+	if err != nil {
+		return model.Channel{}, model.GroupDM{}, nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGroupDMChannel)
+	}
+	participants := make([]int64, 0, len(rows))
+	for _, r := range rows {
+		participants = append(participants, r.UserId)
+	}
+	if !slices.Contains(participants, user.Id) {
+		return model.Channel{}, model.GroupDM{}, nil, fiber.NewError(fiber.StatusForbidden, ErrNotGroupDMParticipant)
+	}
+
+	gdm, err := e.gdm.GetGroupDm(c.UserContext(), channelId)
+	if err != nil {
+		return model.Channel{}, model.GroupDM{}, nil, helper.HttpDbError(err, ErrUnableToGetGroupDMChannel)
+	}
+	return channel, gdm, participants, nil
+}
+
+// validateRecipients ensures all recipient users exist and are valid
+func (e *entity) validateRecipients(c *fiber.Ctx, recipientIds []int64) error {
+	for _, recipientId := range recipientIds {
+		if _, err := e.user.GetUserById(c.UserContext(), recipientId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fiber.NewError(fiber.StatusNotFound, "recipient user not found")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetUser)
+		}
+	}
+	return nil
+}
+
+// groupDMToDTO converts the group DM channel with its settings and recipients to dto.Channel
+func (e *entity) groupDMToDTO(ctx context.Context, channel *model.Channel, gdm model.GroupDM, recipients []int64) dto.Channel {
+	result := dto.Channel{
+		Id:            channel.Id,
+		Type:          channel.Type,
+		Name:          channel.Name,
+		LastMessageId: channel.LastMessage,
+		CreatedAt:     channel.CreatedAt,
+		OwnerId:       &gdm.OwnerId,
+		Recipients:    recipients,
+	}
+	if gdm.Icon != nil {
+		result.Icon = e.groupDMIcon(ctx, channel.Id, *gdm.Icon)
+	}
+	return result
+}
+
+// groupDMIcon returns the icon metadata of the group DM, the same cache is used for the guild icons
+func (e *entity) groupDMIcon(ctx context.Context, channelId, iconId int64) *dto.Icon {
+	key := fmt.Sprintf("icons:%d:%d", channelId, iconId)
+	var cached dto.Icon
+	if err := e.cache.GetJSON(ctx, key, &cached); err == nil && cached.URL != "" {
+		return &cached
+	}
+
+	ic, err := e.icon.GetIcon(ctx, iconId, channelId)
+	if err != nil || ic.URL == nil {
+		return nil
+	}
+	ico := dto.Icon{Id: iconId, URL: *ic.URL, Filesize: ic.FileSize}
+	if ic.Width != nil {
+		ico.Width = *ic.Width
+	}
+	if ic.Height != nil {
+		ico.Height = *ic.Height
+	}
+	_ = e.cache.SetJSON(ctx, key, ico)
+	return &ico
+}
+
+// sendGroupDMEvent sends the event to the user topic of every recipient
+func (e *entity) sendGroupDMEvent(recipients []int64, event mqmsg.EventDataMessage) {
+	for _, id := range recipients {
+		if err := e.mqt.SendUserUpdate(id, event); err != nil {
+			e.log.Error("unable to send group dm event", slog.Int64("user_id", id), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// sendGroupDMRecipientEvents notifies the recipients about the added or removed user and posts a system message into the channel
+func (e *entity) sendGroupDMRecipientEvents(channel *model.Channel, recipients []int64, userId int64, msgType model.MessageType) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	u, err := e.fetchUserWithDiscriminatorCtx(ctx, userId)
+	if err != nil {
+		e.log.Error("unable to get group dm recipient", slog.Int64("user_id", userId), slog.String("error", err.Error()))
+		return
+	}
+	brief := mqmsg.UserBrief{Id: u.Id, Name: u.Name, Discriminator: u.Discriminator, AvatarData: u.Avatar}
+	if msgType == model.MessageTypeRecipientAdd {
+		e.sendGroupDMEvent(slices.DeleteFunc(slices.Clone(recipients), func(id int64) bool { return id == userId }), &mqmsg.GroupDMRecipientAdd{ChannelId: channel.Id, User: brief})
+	} else {
+		e.sendGroupDMEvent(recipients, &mqmsg.GroupDMRecipientRemove{ChannelId: channel.Id, User: brief})
+	}
+
+	// The system message is authored by the user who joined or left, like guild join messages
+	msgId := idgen.Next()
+	if err := e.msg.CreateSystemMessage(ctx, msgId, channel.Id, userId, 0, "", msgType); err != nil {
+		e.log.Error("unable to create group dm system message", slog.String("error", err.Error()))
+		return
+	}
+	if err := e.ch.SetLastMessage(ctx, channel.Id, msgId); err != nil {
+		e.log.Error("unable to set last message id", slog.String("error", err.Error()))
+	}
+	if err := e.mqt.SendChannelMessage(channel.Id, &mqmsg.CreateMessage{
+		Message: dto.Message{
+			Id:        msgId,
+			ChannelId: channel.Id,
+			Author:    u,
+			Type:      int(msgType),
+		},
+	}); err != nil {
+		e.log.Error("unable to send group dm system message event", slog.String("error", err.Error()))
+	}
+	if err := e.imq.IndexMessage(dto.IndexMessage{
+		MessageId: msgId,
+		UserId:    userId,
+		ChannelId: channel.Id,
+		Type:      int(msgType),
+	}); err != nil {
+		e.log.Error("failed to send index message event",
+			"message_id", msgId,
+			"error", err.Error())
+	}
+}
@@ -0,0 +1,470 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+type fakeChannelRepo struct {
+	channels map[int64]model.Channel
+}
+
+func (f *fakeChannelRepo) GetChannel(ctx context.Context, id int64) (model.Channel, error) {
+	ch, ok := f.channels[id]
+	if !ok {
+		return model.Channel{}, fmt.Errorf("get channel: %w", sql.ErrNoRows)
+	}
+	return ch, nil
+}
+func (f *fakeChannelRepo) GetChannelsBulk(ctx context.Context, ids []int64) ([]model.Channel, error) {
+	return nil, nil
+}
+func (f *fakeChannelRepo) GetChannelThreads(ctx context.Context, channelId int64) ([]model.Channel, error) {
+	return nil, nil
+}
+func (f *fakeChannelRepo) GetChannelsAfter(ctx context.Context, afterId int64, limit int) ([]model.Channel, error) {
+	return nil, nil
+}
+func (f *fakeChannelRepo) CreateChannel(ctx context.Context, id int64, name string, channelType model.ChannelType, parent *int64, permissions *int64, private bool) error {
+	f.channels[id] = model.Channel{Id: id, Name: name, Type: channelType}
+	return nil
+}
+func (f *fakeChannelRepo) DeleteChannel(ctx context.Context, id int64) error {
+	delete(f.channels, id)
+	return nil
+}
+func (f *fakeChannelRepo) RenameChannel(ctx context.Context, id int64, newName string) error {
+	return nil
+}
+func (f *fakeChannelRepo) SetChannelPermissions(ctx context.Context, id int64, permissions int) error {
+	return nil
+}
+func (f *fakeChannelRepo) SetChannelPrivate(ctx context.Context, id int64, private bool) error {
+	return nil
+}
+func (f *fakeChannelRepo) SetChannelTopic(ctx context.Context, id int64, topic *string) error {
+	return nil
+}
+func (f *fakeChannelRepo) SetChannelParent(ctx context.Context, id int64, parent *int64) error {
+	return nil
+}
+func (f *fakeChannelRepo) SetChannelParentBulk(ctx context.Context, id []int64, parent *int64) error {
+	return nil
+}
+func (f *fakeChannelRepo) SetLastMessage(ctx context.Context, id, lastMessage int64) error {
+	return nil
+}
+func (f *fakeChannelRepo) UpdateChannel(ctx context.Context, id int64, parent *int64, private *bool, name, topic *string, userLimit, bitrate *int, stage *bool) (model.Channel, error) {
+	return model.Channel{}, nil
+}
+func (f *fakeChannelRepo) SetChannelVoiceRegion(ctx context.Context, id int64, region *string) error {
+	return nil
+}
+func (f *fakeChannelRepo) GetChannelVoiceRegion(ctx context.Context, id int64) (*string, error) {
+	return nil, nil
+}
+
+type fakeGroupDMRepo struct {
+	groups       map[int64]model.GroupDM
+	participants map[int64][]int64
+}
+
+func (f *fakeGroupDMRepo) JoinGroupDmChannelMany(ctx context.Context, channelId int64, users []int64) error {
+	f.participants[channelId] = append(f.participants[channelId], users...)
+	return nil
+}
+func (f *fakeGroupDMRepo) JoinGroupDmChannel(ctx context.Context, channelId, userId int64) error {
+	f.participants[channelId] = append(f.participants[channelId], userId)
+	return nil
+}
+func (f *fakeGroupDMRepo) GetGroupDmChannel(ctx context.Context, channelId, userId int64) (model.GroupDMChannel, error) {
+	return model.GroupDMChannel{ChannelId: channelId, UserId: userId}, nil
+}
+func (f *fakeGroupDMRepo) LeaveGroupDmChannel(ctx context.Context, channelId, userId int64) error {
+	f.participants[channelId] = slices.DeleteFunc(f.participants[channelId], func(id int64) bool { return id == userId })
+	return nil
+}
+func (f *fakeGroupDMRepo) GetGroupDmParticipants(ctx context.Context, channelId int64) ([]model.GroupDMChannel, error) {
+	out := make([]model.GroupDMChannel, 0, len(f.participants[channelId]))
+	for _, id := range f.participants[channelId] {
+		out = append(out, model.GroupDMChannel{ChannelId: channelId, UserId: id})
+	}
+	return out, nil
+}
+func (f *fakeGroupDMRepo) IsGroupDmParticipant(ctx context.Context, channelId int64, userId int64) (bool, error) {
+	return slices.Contains(f.participants[channelId], userId), nil
+}
+func (f *fakeGroupDMRepo) GetUserGroupDmChannels(ctx context.Context, userId int64) ([]model.GroupDMChannel, error) {
+	return nil, nil
+}
+func (f *fakeGroupDMRepo) GetGroupDmParticipantsMany(ctx context.Context, channelIds []int64) ([]model.GroupDMChannel, error) {
+	return nil, nil
+}
+func (f *fakeGroupDMRepo) CreateGroupDm(ctx context.Context, channelId, ownerId int64, users []int64) error {
+	f.groups[channelId] = model.GroupDM{ChannelId: channelId, OwnerId: ownerId}
+	f.participants[channelId] = append([]int64(nil), users...)
+	return nil
+}
+func (f *fakeGroupDMRepo) GetGroupDm(ctx context.Context, channelId int64) (model.GroupDM, error) {
+	g, ok := f.groups[channelId]
+	if !ok {
+		return model.GroupDM{}, fmt.Errorf("get group dm: %w", sql.ErrNoRows)
+	}
+	return g, nil
+}
+func (f *fakeGroupDMRepo) GetGroupDms(ctx context.Context, channelIds []int64) ([]model.GroupDM, error) {
+	return nil, nil
+}
+func (f *fakeGroupDMRepo) SetGroupDmOwner(ctx context.Context, channelId, ownerId int64) error {
+	g := f.groups[channelId]
+	g.OwnerId = ownerId
+	f.groups[channelId] = g
+	return nil
+}
+func (f *fakeGroupDMRepo) SetGroupDmIcon(ctx context.Context, channelId int64, icon *int64) error {
+	return nil
+}
+func (f *fakeGroupDMRepo) DeleteGroupDm(ctx context.Context, channelId int64) error {
+	delete(f.groups, channelId)
+	delete(f.participants, channelId)
+	return nil
+}
+
+type fakeUserRepo struct{}
+
+func (f *fakeUserRepo) ModifyUser(ctx context.Context, userId int64, name *string, avatar *int64) error {
+	return nil
+}
+func (f *fakeUserRepo) GetUserById(ctx context.Context, id int64) (model.User, error) {
+	if id >= 100 {
+		return model.User{}, fmt.Errorf("get user: %w", sql.ErrNoRows)
+	}
+	return model.User{Id: id, Name: fmt.Sprintf("user%d", id)}, nil
+}
+func (f *fakeUserRepo) GetUsersList(ctx context.Context, ids []int64) ([]model.User, error) {
+	return nil, nil
+}
+func (f *fakeUserRepo) CreateUser(ctx context.Context, id int64, name string) error    { return nil }
+func (f *fakeUserRepo) CreateBotUser(ctx context.Context, id int64, name string) error { return nil }
+func (f *fakeUserRepo) SetUserAvatar(ctx context.Context, id, attachmentId int64) error {
+	return nil
+}
+func (f *fakeUserRepo) SetUsername(ctx context.Context, id, name string) error { return nil }
+func (f *fakeUserRepo) SetUserBlocked(ctx context.Context, id int64, blocked bool) error {
+	return nil
+}
+func (f *fakeUserRepo) SetUploadLimit(ctx context.Context, id int64, uploadLimit int64) error {
+	return nil
+}
+
+type fakeDiscriminatorRepo struct{}
+
+func (f *fakeDiscriminatorRepo) CreateDiscriminator(ctx context.Context, userId int64, discriminator string) error {
+	return nil
+}
+func (f *fakeDiscriminatorRepo) GetDiscriminatorByUserId(ctx context.Context, userId int64) (model.Discriminator, error) {
+	return model.Discriminator{UserId: userId, Discriminator: fmt.Sprintf("user-%d", userId)}, nil
+}
+func (f *fakeDiscriminatorRepo) GetUserIdByDiscriminator(ctx context.Context, discriminator string) (model.Discriminator, error) {
+	var userId int64
+	if _, err := fmt.Sscanf(discriminator, "user-%d", &userId); err != nil {
+		return model.Discriminator{}, fmt.Errorf("get discriminator: %w", sql.ErrNoRows)
+	}
+	return model.Discriminator{UserId: userId, Discriminator: discriminator}, nil
+}
+func (f *fakeDiscriminatorRepo) GetDiscriminatorsByUserIDs(ctx context.Context, userIDs []int64) ([]model.Discriminator, error) {
+	return nil, nil
+}
+
+type fakeMessageRepo struct {
+	system chan model.MessageType
+}
+
+func (f *fakeMessageRepo) CreateMessage(ctx context.Context, id, channelID, userID, reference int64, content string, attachments []int64, embedsJSON, autoEmbedsJSON string) error {
+	return nil
+}
+func (f *fakeMessageRepo) CreateWebhookMessage(ctx context.Context, id, channelID, webhookID, reference int64, content, embedsJSON, autoEmbedsJSON, authorName string, authorAvatar *string) error {
+	return nil
+}
+func (f *fakeMessageRepo) CreateSystemMessage(ctx context.Context, id, channelId, userId, reference int64, content string, msgType model.MessageType) error {
+	f.system <- msgType
+	return nil
+}
+func (f *fakeMessageRepo) CreateSystemMessageWithAttachments(ctx context.Context, id, channelID, userID int64, content string, attachments []int64, msgType model.MessageType) error {
+	return nil
+}
+func (f *fakeMessageRepo) UpdateMessage(ctx context.Context, id, channelID int64, content, embedsJSON, autoEmbedsJSON string, flags int) error {
+	return nil
+}
+func (f *fakeMessageRepo) UpdateGeneratedEmbeds(ctx context.Context, id, channelID int64, autoEmbedsJSON string) error {
+	return nil
+}
+func (f *fakeMessageRepo) SetMessageThread(ctx context.Context, id, channelID, threadID int64) error {
+	return nil
+}
+func (f *fakeMessageRepo) ClaimMessageThread(ctx context.Context, id, channelID, threadID int64) (bool, error) {
+	return true, nil
+}
+func (f *fakeMessageRepo) SetMessageFlags(ctx context.Context, id, channelID int64, flags int) error {
+	return nil
+}
+func (f *fakeMessageRepo) DeleteMessage(ctx context.Context, id, channelId int64) error { return nil }
+func (f *fakeMessageRepo) DeleteChannelMessages(ctx context.Context, channelID, lastId int64) error {
+	return nil
+}
+func (f *fakeMessageRepo) GetMessage(ctx context.Context, id, channelId int64) (model.Message, error) {
+	return model.Message{}, nil
+}
+func (f *fakeMessageRepo) GetMessagesBefore(ctx context.Context, channelId, msgId int64, limit int) ([]model.Message, []int64, error) {
+	return nil, nil, nil
+}
+func (f *fakeMessageRepo) GetMessagesAfter(ctx context.Context, channelId, msgId, lastChannelMessage int64, limit int) ([]model.Message, []int64, error) {
+	return nil, nil, nil
+}
+func (f *fakeMessageRepo) GetMessagesAround(ctx context.Context, channelId, msgId, lastChannelMessage int64, limit int) ([]model.Message, []int64, error) {
+	return nil, nil, nil
+}
+func (f *fakeMessageRepo) GetMessagesList(ctx context.Context, msgIds []int64) ([]model.Message, error) {
+	return nil, nil
+}
+func (f *fakeMessageRepo) GetChannelMessagesByIDs(ctx context.Context, channelId int64, ids []int64) ([]model.Message, error) {
+	return nil, nil
+}
+
+type userEvent struct {
+	userId int64
+	event  mqmsg.EventDataMessage
+}
+
+type fakeTransport struct {
+	userEvents chan userEvent
+}
+
+func (f *fakeTransport) SendChannelMessage(channelId int64, message mqmsg.EventDataMessage) error {
+	return nil
+}
+func (f *fakeTransport) SendGuildUpdate(guildId int64, message mqmsg.EventDataMessage) error {
+	return nil
+}
+func (f *fakeTransport) SendUserUpdate(userId int64, message mqmsg.EventDataMessage) error {
+	f.userEvents <- userEvent{userId: userId, event: message}
+	return nil
+}
+
+type fakeIndexer struct{}
+
+func (f *fakeIndexer) IndexMessage(msg dto.IndexMessage) error { return nil }
+
+func newGroupDMTestEntity() (*entity, *fakeGroupDMRepo, *fakeTransport, *fakeMessageRepo) {
+	gdm := &fakeGroupDMRepo{
+		groups:       map[int64]model.GroupDM{5: {ChannelId: 5, OwnerId: 10}},
+		participants: map[int64][]int64{5: {10, 11, 12}},
+	}
+	transport := &fakeTransport{userEvents: make(chan userEvent, 32)}
+	messages := &fakeMessageRepo{system: make(chan model.MessageType, 4)}
+	e := &entity{
+		log:  slog.New(slog.DiscardHandler),
+		mqt:  transport,
+		imq:  &fakeIndexer{},
+		ch:   &fakeChannelRepo{channels: map[int64]model.Channel{5: {Id: 5, Type: model.ChannelTypeGroupDM}}},
+		gdm:  gdm,
+		user: &fakeUserRepo{},
+		disc: &fakeDiscriminatorRepo{},
+		msg:  messages,
+	}
+	return e, gdm, transport, messages
+}
+
+func newUserTestApp(userID int64, path string, handler fiber.Handler) *fiber.App {
+	app := fiber.New()
+	app.All(path, func(c *fiber.Ctx) error {
+		c.Locals("user", &jwt.Token{Claims: &helper.Claims{UserID: userID}})
+		return handler(c)
+	})
+	return app
+}
+
+// collectUserEvents waits for n user topic events
+func collectUserEvents(t *testing.T, transport *fakeTransport, n int) []userEvent {
+	t.Helper()
+	events := make([]userEvent, 0, n)
+	for len(events) < n {
+		select {
+		case ev := <-transport.userEvents:
+			events = append(events, ev)
+		case <-time.After(time.Second):
+			t.Fatalf("expected %d user events, got %d", n, len(events))
+		}
+	}
+	return events
+}
+
+func TestCreateGroupDMAddsCreatorAsOwner(t *testing.T) {
+	e, gdm, transport, _ := newGroupDMTestEntity()
+	app := newUserTestApp(10, "/user/me/channels/group", e.CreateGroupDM)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/user/me/channels/group", strings.NewReader(`{"recipients_id":[20,21,20,10],"name":"plans"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var channel dto.Channel
+	if err := json.NewDecoder(resp.Body).Decode(&channel); err != nil {
+		t.Fatal(err)
+	}
+	if channel.Type != model.ChannelTypeGroupDM || channel.Name != "plans" || channel.OwnerId == nil || *channel.OwnerId != 10 {
+		t.Fatalf("unexpected channel %+v", channel)
+	}
+	if !slices.Equal(channel.Recipients, []int64{10, 20, 21}) || !slices.Equal(gdm.participants[channel.Id], []int64{10, 20, 21}) {
+		t.Fatalf("expected deduplicated recipients with the creator, got %v", channel.Recipients)
+	}
+
+	notified := map[int64]bool{}
+	for _, ev := range collectUserEvents(t, transport, 3) {
+		if _, ok := ev.event.(*mqmsg.CreateChannel); !ok {
+			t.Fatalf("expected create channel event, got %T", ev.event)
+		}
+		notified[ev.userId] = true
+	}
+	if len(notified) != 3 {
+		t.Fatalf("expected every recipient to be notified, got %v", notified)
+	}
+}
+
+func TestAddGroupDMRecipientRejectsFullGroup(t *testing.T) {
+	e, gdm, _, _ := newGroupDMTestEntity()
+	for id := int64(13); len(gdm.participants[5]) < MaxGroupDMRecipients; id++ {
+		gdm.participants[5] = append(gdm.participants[5], id)
+	}
+	app := newUserTestApp(11, "/user/me/channels/:channel_id/recipients/:user_id", e.AddGroupDMRecipient)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPut, "/user/me/channels/5/recipients/50", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Fatalf("expected status 409, got %d", resp.StatusCode)
+	}
+	if slices.Contains(gdm.participants[5], 50) {
+		t.Fatal("expected recipient not to be added")
+	}
+}
+
+func TestAddGroupDMRecipientPostsSystemMessage(t *testing.T) {
+	e, gdm, transport, messages := newGroupDMTestEntity()
+	app := newUserTestApp(11, "/user/me/channels/:channel_id/recipients/:user_id", e.AddGroupDMRecipient)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPut, "/user/me/channels/5/recipients/13", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if !slices.Contains(gdm.participants[5], 13) {
+		t.Fatal("expected recipient to be added")
+	}
+
+	// The new recipient gets the channel, the others get the recipient add event
+	for _, ev := range collectUserEvents(t, transport, 4) {
+		switch ev.event.(type) {
+		case *mqmsg.CreateChannel:
+			if ev.userId != 13 {
+				t.Fatalf("expected create channel event only for the new recipient, got %d", ev.userId)
+			}
+		case *mqmsg.GroupDMRecipientAdd:
+			if ev.userId == 13 {
+				t.Fatal("expected no recipient add event for the new recipient")
+			}
+		default:
+			t.Fatalf("unexpected event %T", ev.event)
+		}
+	}
+	select {
+	case msgType := <-messages.system:
+		if msgType != model.MessageTypeRecipientAdd {
+			t.Fatalf("expected recipient add message, got %d", msgType)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected system message")
+	}
+}
+
+func TestRemoveGroupDMRecipientRequiresOwner(t *testing.T) {
+	e, gdm, _, _ := newGroupDMTestEntity()
+	app := newUserTestApp(11, "/user/me/channels/:channel_id/recipients/:user_id", e.RemoveGroupDMRecipient)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodDelete, "/user/me/channels/5/recipients/12", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", resp.StatusCode)
+	}
+	if !slices.Contains(gdm.participants[5], 12) {
+		t.Fatal("expected recipient to stay")
+	}
+}
+
+func TestRemoveGroupDMRecipientOwnerLeaveTransfersOwnership(t *testing.T) {
+	e, gdm, transport, messages := newGroupDMTestEntity()
+	app := newUserTestApp(10, "/user/me/channels/:channel_id/recipients/:user_id", e.RemoveGroupDMRecipient)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodDelete, "/user/me/channels/5/recipients/10", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if slices.Contains(gdm.participants[5], 10) {
+		t.Fatal("expected owner to leave")
+	}
+	if gdm.groups[5].OwnerId != 11 {
+		t.Fatalf("expected ownership to move to 11, got %d", gdm.groups[5].OwnerId)
+	}
+
+	// Delete for the leaving owner, recipient remove and channel update for both remaining recipients
+	var deletes, removes, updates int
+	for _, ev := range collectUserEvents(t, transport, 5) {
+		switch ev.event.(type) {
+		case *mqmsg.DeleteChannel:
+			deletes++
+		case *mqmsg.GroupDMRecipientRemove:
+			removes++
+		case *mqmsg.UpdateChannel:
+			updates++
+		}
+	}
+	if deletes != 1 || removes != 2 || updates != 2 {
+		t.Fatalf("unexpected events: %d deletes, %d removes, %d updates", deletes, removes, updates)
+	}
+	select {
+	case msgType := <-messages.system:
+		if msgType != model.MessageTypeRecipientRemove {
+			t.Fatalf("expected recipient remove message, got %d", msgType)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected system message")
+	}
+}
@@ -342,6 +342,7 @@ func (e *entity) fetchGuildMemberData(c *fiber.Ctx, userId, guildId int64) (dto.
 		Id:            userRes.user.Id,
 		Name:          userRes.user.Name,
 		Discriminator: discRes.disc.Discriminator,
+		Bot:           userRes.user.Bot,
 	}
 	// Prefer member avatar over user avatar
 	if memberRes.member.Avatar != nil {
@@ -599,170 +600,6 @@ func (e *entity) createNewDMChannel(c *fiber.Ctx, userId, recipientId int64) (dt
 	}, nil
 }
 
-// CreateGroupDM
-//
-//	@Summary	Create group DM channel
-//	@Produce	json
-//	@Tags		User
-//	@Param		request	body		CreateDMManyRequest	true	"Group DM data"
-//	@Success	200		{object}	dto.Channel			"Created group DM channel"
-//	@failure	400		{string}	string				"Bad request"
-//	@failure	403		{string}	string				"Forbidden"
-//	@failure	404		{string}	string				"Not found"
-//	@failure	500		{string}	string				"Internal server error"
-//	@Router		/user/me/channels/group [post]
-func (e *entity) CreateGroupDM(c *fiber.Ctx) error {
-	// Parse and validate request
-	req, user, err := e.parseGroupDMRequest(c)
-	if err != nil {
-		return err
-	}
-
-	// Validate recipient users exist
-	if err := e.validateRecipients(c, req.RecipientsId); err != nil {
-		return err
-	}
-
-	// Handle existing group DM vs new group DM
-	if req.ChannelId != nil {
-		return e.addUsersToExistingGroupDM(c, *req.ChannelId, user.Id, req.RecipientsId)
-	}
-
-	return e.createNewGroupDM(c, user.Id, req.RecipientsId)
-}
-
-// parseGroupDMRequest handles request parsing and user authentication
-func (e *entity) parseGroupDMRequest(c *fiber.Ctx) (*CreateDMManyRequest, *helper.JWTUser, error) {
-	var req CreateDMManyRequest
-	if err := c.BodyParser(&req); err != nil {
-		return nil, nil, fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseRequestBody)
-	}
-
-	if err := req.Validate(); err != nil {
-		return nil, nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
-	}
-
-	user, err := helper.GetUser(c)
-	if err != nil {
-		return nil, nil, fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
-	}
-
-	return &req, user, nil
-}
-
-// validateRecipients ensures all recipient users exist and are valid
-func (e *entity) validateRecipients(c *fiber.Ctx, recipientIds []int64) error {
-	for _, recipientId := range recipientIds {
-		if _, err := e.user.GetUserById(c.UserContext(), recipientId); err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				return fiber.NewError(fiber.StatusNotFound, "recipient user not found")
-			}
-			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetUser)
-		}
-	}
-	return nil
-}
-
-// addUsersToExistingGroupDM handles adding users to an existing group DM
-func (e *entity) addUsersToExistingGroupDM(c *fiber.Ctx, channelId, userId int64, recipientIds []int64) error {
-	// Verify user permissions and get channel in one operation
-	channel, err := e.validateGroupDMAccess(c, channelId, userId)
-	if err != nil {
-		return err
-	}
-
-	// Add new participants
-	if err := e.gdm.JoinGroupDmChannelMany(c.UserContext(), channelId, recipientIds); err != nil {
-		return helper.HttpDbError(err, ErrUnableToJoingGroupDmChannel)
-	}
-
-	return c.JSON(e.channelToDTO(channel))
-}
-
-// validateGroupDMAccess checks if user can access the group DM and returns the channel
-func (e *entity) validateGroupDMAccess(c *fiber.Ctx, channelId, userId int64) (*model.Channel, error) {
-	// Get channel first to ensure it exists
-	channel, err := e.ch.GetChannel(c.UserContext(), channelId)
-	if err != nil {
-		return nil, fiber.NewError(fiber.StatusNotFound, ErrUnableToGetChannel)
-	}
-
-	// Verify it's actually a group DM
-	if channel.Type != model.ChannelTypeGroupDM {
-		return nil, fiber.NewError(fiber.StatusBadRequest, "channel is not a group DM")
-	}
-
-	// Verify user is participant
-	isParticipant, err := e.gdm.IsGroupDmParticipant(c.UserContext(), channelId, userId)
-	if err != nil {
-		return nil, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGroupDMChannel)
-	}
-
-	if !isParticipant {
-		return nil, fiber.NewError(fiber.StatusForbidden, "not a participant in this group DM")
-	}
-
-	return &channel, nil
-}
-
-// createNewGroupDM handles creating a new group DM channel with transaction-like behavior
-func (e *entity) createNewGroupDM(c *fiber.Ctx, userId int64, recipientIds []int64) error {
-	channelId := idgen.Next()
-	allParticipants := append([]int64{userId}, recipientIds...)
-
-	// Create channel and add participants atomically
-	channel, err := e.createGroupDMWithParticipants(c, channelId, allParticipants)
-	if err != nil {
-		return err
-	}
-
-	return c.JSON(channel)
-}
-
-// createGroupDMWithParticipants creates a group DM channel and adds participants with proper cleanup
-func (e *entity) createGroupDMWithParticipants(c *fiber.Ctx, channelId int64, participants []int64) (dto.Channel, error) {
-	// Create the channel
-	if err := e.ch.CreateChannel(c.UserContext(), channelId, "", model.ChannelTypeGroupDM, nil, nil, false); err != nil {
-		return dto.Channel{}, helper.HttpDbError(err, ErrUnableToCreateChannel)
-	}
-
-	// Add all participants
-	if err := e.gdm.JoinGroupDmChannelMany(c.UserContext(), channelId, participants); err != nil {
-		// Cleanup: delete the channel if adding participants fails
-		if cleanupErr := e.ch.DeleteChannel(c.UserContext(), channelId); cleanupErr != nil {
-			// Log cleanup failure but return original error
-			e.log.Error("failed to cleanup channel after participant join failure",
-				"channel_id", channelId,
-				"cleanup_error", cleanupErr.Error(),
-				"original_error", err.Error())
-		}
-		return dto.Channel{}, helper.HttpDbError(err, ErrUnableToJoingGroupDmChannel)
-	}
-
-	// Return the successfully created channel
-	return dto.Channel{
-		Id:        channelId,
-		Type:      model.ChannelTypeGroupDM,
-		GuildId:   nil,
-		Name:      "",
-		ParentId:  nil,
-		CreatedAt: time.Now(),
-	}, nil
-}
-
-// channelToDTO converts a model.Channel to dto.Channel for group DMs
-func (e *entity) channelToDTO(channel *model.Channel) dto.Channel {
-	return dto.Channel{
-		Id:          channel.Id,
-		Type:        channel.Type,
-		GuildId:     nil, // Group DMs don't belong to guilds
-		Name:        channel.Name,
-		ParentId:    channel.ParentID,
-		Permissions: channel.Permissions,
-		CreatedAt:   channel.CreatedAt,
-	}
-}
-
 // GetUserSettings
 //
 //	@Summary	Get current user settings (optional version gating)
@@ -39,6 +39,20 @@ const (
 	ErrUnableToParseVersion          = "unable to parse version"
 	ErrUnableToGetReadStates         = "unable to get read states"
 	ErrUnableToGetMembership         = "unable to get membership"
+	ErrUnableToUpdateGroupDM         = "unable to update group dm"
+	ErrUnableToLeaveGroupDM          = "unable to leave group dm"
+	ErrUnableToGetIcon               = "unable to get icon"
+	ErrUnableToCreateIcon            = "unable to create icon"
+	ErrNotGroupDMChannel             = "channel is not a group dm"
+	ErrNotGroupDMParticipant         = "not a participant in this group dm"
+	ErrNotGroupDMOwner               = "only the group dm owner can do this"
+	ErrGroupDMRecipientNotFound      = "user is not a recipient of this group dm"
+	ErrGroupDMFull                   = "group dm recipient limit reached"
+	ErrIconNotUploaded               = "icon is not uploaded"
+	ErrUnableToGetBlockedUsers       = "unable to get blocked users"
+	ErrUnableToBlockUser             = "unable to block user"
+	ErrUnableToUnblockUser           = "unable to unblock user"
+	ErrUserBlocked                   = "user is blocked"
 
 	// Validation error messages
 	ErrUserNameTooShort           = "user name must be at least 4 characters"
@@ -49,7 +63,10 @@ const (
 	ErrChannelIdInvalid           = "channel ID must be positive"
 	ErrRecipientsRequired         = "at least one recipient is required"
 	ErrRecipientsInvalid          = "recipient IDs must be positive"
-	ErrTooManyRecipients          = "maximum 10 recipients allowed"
+	ErrTooManyRecipients          = "maximum 9 recipients allowed"
+	ErrGroupDMNameTooLong         = "group dm name must be less than 100 characters"
+	ErrIconIdInvalid              = "icon ID must be positive"
+	ErrOwnerIdInvalid             = "owner ID must be positive"
 	ErrUnableToDeleteActiveAvatar = "unable to delete active avatar"
 	ErrNoFieldsToUpdate           = "at least one field must be provided for update"
 )
@@ -91,25 +108,62 @@ func (r CreateDMRequest) Validate() error {
 	)
 }
 
-type CreateDMManyRequest struct {
-	ChannelId    *int64  `json:"channel_id"`
-	RecipientsId []int64 `json:"recipients_id"`
+// MaxGroupDMRecipients is the maximum number of users in a group DM, including the owner
+const MaxGroupDMRecipients = 10
+
+type CreateGroupDMRequest struct {
+	RecipientsId []int64 `json:"recipients_id" example:"2230469276416868352"` // Users to add, the creator is added automatically
+	Name         *string `json:"name,omitempty" example:"Weekend plans"`      // Group DM name
 }
 
-func (r CreateDMManyRequest) Validate() error {
+func (r CreateGroupDMRequest) Validate() error {
 	return validation.ValidateStruct(&r,
-		validation.Field(&r.ChannelId,
-			validation.When(r.ChannelId != nil, validation.Min(int64(1)).Error(ErrChannelIdInvalid)),
-		),
 		validation.Field(&r.RecipientsId,
 			validation.Required.Error(ErrRecipientsRequired),
 			validation.Length(1, 0).Error(ErrRecipientsRequired),
-			validation.Length(0, 10).Error(ErrTooManyRecipients),
+			validation.Length(0, MaxGroupDMRecipients-1).Error(ErrTooManyRecipients),
 			validation.Each(validation.Min(int64(1)).Error(ErrRecipientsInvalid)),
 		),
+		validation.Field(&r.Name,
+			validation.When(r.Name != nil, validation.RuneLength(0, 100).Error(ErrGroupDMNameTooLong)),
+		),
+	)
+}
+
+type UpdateGroupDMRequest struct {
+	Name    *string `json:"name,omitempty" example:"Weekend plans"`           // New group DM name, empty string clears it
+	IconId  *int64  `json:"icon_id,omitempty" example:"2230469276416868352"`  // ID of an uploaded group DM icon
+	OwnerId *int64  `json:"owner_id,omitempty" example:"2230469276416868352"` // Transfer ownership to this recipient. Owner only.
+}
+
+func (r UpdateGroupDMRequest) Validate() error {
+	if r.Name == nil && r.IconId == nil && r.OwnerId == nil {
+		return validation.NewError("VALIDATION_NO_FIELDS", ErrNoFieldsToUpdate)
+	}
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Name,
+			validation.When(r.Name != nil, validation.RuneLength(0, 100).Error(ErrGroupDMNameTooLong)),
+		),
+		validation.Field(&r.IconId,
+			validation.When(r.IconId != nil, validation.Min(int64(1)).Error(ErrIconIdInvalid)),
+		),
+		validation.Field(&r.OwnerId,
+			validation.When(r.OwnerId != nil, validation.Min(int64(1)).Error(ErrOwnerIdInvalid)),
+		),
 	)
 }
 
+// CreateGroupDMIconRequest is a request to create group DM icon metadata
+type CreateGroupDMIconRequest struct {
+	FileSize    int64  `json:"file_size" example:"120000"`
+	ContentType string `json:"content_type" example:"image/png"`
+}
+
+// Validate constraints are the same as for avatars: <= 250KB and image/*
+func (r CreateGroupDMIconRequest) Validate() error {
+	return CreateAvatarRequest(r).Validate()
+}
+
 type UserSettingsResponse struct {
 	Version            int64                            `json:"version"`
 	Settings           *model.UserSettingsData          `json:"settings"`
@@ -144,6 +198,7 @@ func modelToUser(m model.User) dto.User {
 	return dto.User{
 		Id:   m.Id,
 		Name: m.Name,
+		Bot:  m.Bot,
 	}
 }
 
@@ -252,6 +307,7 @@ func usersWithDiscriminators(users []model.User, discs []model.Discriminator) []
 			Id:            u.Id,
 			Name:          u.Name,
 			Discriminator: discMap[u.Id],
+			Bot:           u.Bot,
 		}
 	}
 	return res
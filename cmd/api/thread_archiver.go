@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/channel"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/thread"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/mq"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+const (
+	threadArchiveLockKey   = "thread:archive:lock"
+	threadArchiveBatchSize = 500
+)
+
+// runThreadArchiver periodically archives threads that passed their auto-archive duration.
+// Uses the same KeyDB lock approach as the audit retention sweep.
+func runThreadArchiver(ctx context.Context, threads thread.Thread, channels channel.Channel, mqt mq.SendTransporter, lock auditRetentionLock, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			acquired, err := lock.SetTimedJSONNX(ctx, threadArchiveLockKey, time.Now().Unix(), int64(interval.Seconds()))
+			if err != nil {
+				logger.Error("unable to acquire thread archive lock", slog.String("error", err.Error()))
+				continue
+			}
+			if !acquired {
+				continue
+			}
+			if err := archiveInactiveThreads(ctx, threads, channels, mqt, time.Now(), logger); err != nil {
+				logger.Error("thread archive sweep failed", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// archiveInactiveThreads archives inactive threads in batches and sends a thread update event for each one
+func archiveInactiveThreads(ctx context.Context, threads thread.Thread, channels channel.Channel, mqt mq.SendTransporter, now time.Time, logger *slog.Logger) error {
+	for {
+		batch, err := threads.GetInactiveThreads(ctx, now, threadArchiveBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, t := range batch {
+			archived, err := threads.ArchiveIfInactive(ctx, t.GuildId, t.Id, now)
+			if err != nil {
+				return fmt.Errorf("thread %d: %w", t.Id, err)
+			}
+			if !archived {
+				continue
+			}
+			ch, err := channels.GetChannel(ctx, t.Id)
+			if err != nil {
+				logger.Error("unable to get archived thread channel", slog.Int64("thread_id", t.Id), slog.String("error", err.Error()))
+				continue
+			}
+			t.Archived = true
+			t.ArchivedAt = &now
+			if err := mqt.SendGuildUpdate(t.GuildId, &mqmsg.UpdateThread{GuildId: t.GuildId, Channel: dto.NewThreadChannel(&ch, &t)}); err != nil {
+				logger.Error("unable to send thread update event", slog.Int64("thread_id", t.Id), slog.String("error", err.Error()))
+			}
+		}
+		if len(batch) < threadArchiveBatchSize {
+			return nil
+		}
+	}
+}
@@ -73,7 +73,8 @@ func (f *fakeUserRepo) GetUserById(ctx context.Context, id int64) (model.User, e
 func (f *fakeUserRepo) GetUsersList(ctx context.Context, ids []int64) ([]model.User, error) {
 	return nil, nil
 }
-func (f *fakeUserRepo) CreateUser(ctx context.Context, id int64, name string) error { return nil }
+func (f *fakeUserRepo) CreateUser(ctx context.Context, id int64, name string) error    { return nil }
+func (f *fakeUserRepo) CreateBotUser(ctx context.Context, id int64, name string) error { return nil }
 func (f *fakeUserRepo) SetUserAvatar(ctx context.Context, id, attachmentId int64) error {
 	select {
 	case f.setCh <- struct{}{}:
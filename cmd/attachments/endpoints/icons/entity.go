@@ -1,6 +1,7 @@
 package icons
 
 import (
+	"context"
 	"log/slog"
 
 	"github.com/gofiber/fiber/v2"
@@ -8,6 +9,7 @@ import (
 	"github.com/FlameInTheDark/gochat/internal/database/db"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/icon"
 	"github.com/FlameInTheDark/gochat/internal/database/pgdb"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/groupdmchannel"
 	pgguild "github.com/FlameInTheDark/gochat/internal/database/pgentities/guild"
 	"github.com/FlameInTheDark/gochat/internal/mq"
 	"github.com/FlameInTheDark/gochat/internal/s3"
@@ -17,10 +19,16 @@ import (
 
 const entityName = "icons"
 
+// groupDMParticipants checks the recipients of group DMs
+type groupDMParticipants interface {
+	IsGroupDmParticipant(ctx context.Context, channelId int64, userId int64) (bool, error)
+}
+
 type entity struct {
 	name     string
 	log      *slog.Logger
 	gld      pgguild.Guild
+	gdm      groupDMParticipants
 	mqt      mq.SendTransporter
 	uploader *upload.IconService
 }
@@ -32,6 +40,7 @@ func New(cql *db.CQLCon, pg *pgdb.DB, storage *s3.Client, externalURL string, mq
 		name:     entityName,
 		log:      log,
 		gld:      pgguild.New(pg.Conn()),
+		gdm:      groupdmchannel.New(pg.Conn()),
 		mqt:      mqt,
 		uploader: upload.NewIconService(icon.New(cql), storage, externalURL, upload.NewFFmpegProcessor(), iconMaxDim, iconMaxSizeBytes),
 	}
@@ -39,4 +48,5 @@ func New(cql *db.CQLCon, pg *pgdb.DB, storage *s3.Client, externalURL string, mq
 
 func (e *entity) Init(router fiber.Router) {
 	router.Post("/:guild_id<int>/:icon_id<int>", e.Upload)
+	router.Post("/group/:channel_id<int>/:icon_id<int>", e.UploadGroupDM)
 }
@@ -77,6 +77,66 @@ func (e *entity) Upload(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusCreated)
 }
 
+// UploadGroupDM
+//
+//	@Summary		Upload group DM icon
+//	@Description	Uploads a group DM icon. Resizes to max 128x128 and converts to WebP <= 250KB. Any recipient can upload. The icon is not applied until it is set with the update group DM endpoint.
+//	@Tags			Upload
+//	@Accept			application/octet-stream
+//	@Produce		json
+//	@Param			channel_id	path		int64	true	"Channel ID"
+//	@Param			icon_id		path		int64	true	"Icon ID"
+//	@Param			file		body		[]byte	true	"Binary image payload"
+//	@Success		201			{string}	string	"Created"
+//	@Success		204			{string}	string	"No Content (already uploaded)"
+//	@failure		400			{string}	string	"Bad request"
+//	@failure		401			{string}	string	"Unauthorized"
+//	@failure		403			{string}	string	"Forbidden"
+//	@failure		404			{string}	string	"Icon not found"
+//	@failure		413			{string}	string	"File too large"
+//	@failure		415			{string}	string	"Unsupported Media Type"
+//	@failure		500			{string}	string	"Internal server error"
+//	@Router			/upload/icons/group/{channel_id}/{icon_id} [post]
+func (e *entity) UploadGroupDM(c *fiber.Ctx) error {
+	channelId, err := strconv.ParseInt(c.Params("channel_id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrIncorrectChannelID)
+	}
+	iconId, err := strconv.ParseInt(c.Params("icon_id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrIncorrectIconID)
+	}
+
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToGetUserToken)
+	}
+
+	ok, err := e.gdm.IsGroupDmParticipant(c.UserContext(), channelId, user.Id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetGroupDM)
+	}
+	if !ok {
+		return fiber.NewError(fiber.StatusForbidden, ErrForbiddenToUpload)
+	}
+
+	body, err := requestBodyReader(c)
+	if err != nil {
+		return err
+	}
+
+	// Group DM icons are stored with the guild icons, partitioned by the channel ID
+	result, err := e.uploader.Upload(c.UserContext(), channelId, iconId, body)
+	if err != nil {
+		return iconUploadError(err)
+	}
+	if result.AlreadyDone {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	return c.SendStatus(fiber.StatusCreated)
+}
+
 func (e *entity) finalizeIconSideEffects(guildId, iconId int64, result *upload.IconResult) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
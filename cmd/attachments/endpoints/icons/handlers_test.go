@@ -92,6 +92,22 @@ func (f *fakeGuildRepo) UpdateGuild(ctx context.Context, id int64, name *string,
 func (f *fakeGuildRepo) SetSystemMessagesChannel(ctx context.Context, id int64, channelId *int64) error {
 	return nil
 }
+func (f *fakeGuildRepo) SetGuildMFARequired(ctx context.Context, id int64, required bool) error {
+	return nil
+}
+func (f *fakeGuildRepo) UpdateGuildDiscovery(ctx context.Context, id int64, description *string, category *model.GuildCategory) error {
+	return nil
+}
+func (f *fakeGuildRepo) UpdateGuildScreening(ctx context.Context, id int64, level *model.VerificationLevel, rules *string, enabled *bool) error {
+	return nil
+}
+func (f *fakeGuildRepo) SetVanityCode(ctx context.Context, id int64, code *string) error { return nil }
+func (f *fakeGuildRepo) GetGuildIdByVanityCode(ctx context.Context, code string) (int64, error) {
+	return 0, nil
+}
+func (f *fakeGuildRepo) GetDiscoverableGuilds(ctx context.Context, query string, category *model.GuildCategory, limit, offset uint64) ([]model.DiscoverableGuild, error) {
+	return nil, nil
+}
 
 type fakeTransport struct {
 	guildUpdateCh chan struct{}
@@ -167,3 +183,42 @@ func makeWebP(width, height int) []byte {
 	data[29] = byte(h >> 16)
 	return data
 }
+
+type fakeGroupDMRepo struct {
+	participants map[int64]bool
+}
+
+func (f *fakeGroupDMRepo) IsGroupDmParticipant(ctx context.Context, channelId int64, userId int64) (bool, error) {
+	return f.participants[userId], nil
+}
+
+func TestUploadGroupDMRequiresRecipient(t *testing.T) {
+	body := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 'd', 'a', 't', 'a'}
+	repo := &fakeIconRepo{placeholder: model.Icon{Id: 4, GuildId: 88, FileSize: int64(len(body))}}
+	e := &entity{
+		gdm:      &fakeGroupDMRepo{participants: map[int64]bool{15: true}},
+		uploader: upload.NewIconService(repo, &fakeStorage{}, "", &fakeProcessor{}, iconMaxDim, iconMaxSizeBytes),
+	}
+
+	for _, tc := range []struct {
+		userID int64
+		status int
+	}{
+		{userID: 16, status: fiber.StatusForbidden},
+		{userID: 15, status: fiber.StatusCreated},
+	} {
+		app := fiber.New()
+		app.Post("/group/:channel_id/:icon_id", func(c *fiber.Ctx) error {
+			c.Locals("user", &jwt.Token{Claims: &helper.Claims{UserID: tc.userID}})
+			return e.UploadGroupDM(c)
+		})
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/group/88/4", bytes.NewReader(body)), -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != tc.status {
+			t.Fatalf("user %d: expected %d, got %d", tc.userID, tc.status, resp.StatusCode)
+		}
+	}
+}
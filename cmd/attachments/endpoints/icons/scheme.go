@@ -3,6 +3,8 @@ package icons
 const (
 	ErrUnableToGetUserToken    = "unable to get user token"
 	ErrIncorrectGuildID        = "incorrect guild ID"
+	ErrIncorrectChannelID      = "incorrect channel ID"
+	ErrUnableToGetGroupDM      = "unable to get group dm"
 	ErrIncorrectIconID         = "incorrect icon ID"
 	ErrUnableToGetGuild        = "unable to get guild"
 	ErrUnableToGetIcon         = "unable to get icon"
@@ -20,6 +20,7 @@ import (
 	"github.com/FlameInTheDark/gochat/internal/mailer/providers/sendpulse"
 	"github.com/FlameInTheDark/gochat/internal/mailer/providers/smtp"
 	"github.com/FlameInTheDark/gochat/internal/server"
+	"github.com/FlameInTheDark/gochat/internal/sessionmq"
 	"github.com/FlameInTheDark/gochat/internal/shutter"
 )
 
@@ -48,6 +49,12 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) (*App, error) {
 	}
 	shut.Up(cache)
 
+	smq, err := sessionmq.New(cfg.NatsConnString)
+	if err != nil {
+		return nil, err
+	}
+	shut.Up(smq)
+
 	// Email notifier
 	tmpl, err := mailer.NewEmailTemplate(cfg.EmailTemplate, cfg.PasswordResetTemplate, cfg.BaseUrl, cfg.AppName, time.Now().Year())
 	if err != nil {
@@ -95,7 +102,7 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) (*App, error) {
 	// HTTP Router
 	s.Register(
 		"/api/v1",
-		auth.New(pg, m, cfg.AuthSecret, logger, helper.RequireTokenType("refresh", "refresh")),
+		auth.New(pg, m, smq, cache, cfg.AuthSecret, cfg.AppName, logger, helper.RequireTokenType("refresh", "refresh"), helper.RequireTokenType("access", "api")),
 	)
 
 	return &App{
@@ -35,6 +35,7 @@ type Config struct {
 	KeyDB                      string `yaml:"keydb" env:"KEYDB" env-default:"127.0.0.1:6379"`
 	PGDSN                      string `yaml:"pg_dsn" env:"PG_DSN" env-default:""`
 	PGRetries                  int    `yaml:"pg_retries" env:"PG_RETRIES" env-default:"5"`
+	NatsConnString             string `yaml:"nats_conn_string" env:"NATS_CONN_STRING" env-default:"nats://nats:4222"`
 }
 
 func LoadConfig(logger *slog.Logger) (*Config, error) {
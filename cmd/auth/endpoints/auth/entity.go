@@ -5,32 +5,50 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 
+	"github.com/FlameInTheDark/gochat/internal/cache"
 	"github.com/FlameInTheDark/gochat/internal/database/pgdb"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/authentication"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/discriminator"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/registration"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/user"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/usermfa"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/usersession"
 	"github.com/FlameInTheDark/gochat/internal/mailer"
 	"github.com/FlameInTheDark/gochat/internal/server"
+	"github.com/FlameInTheDark/gochat/internal/sessionmq"
 )
 
 const entityName = "auth"
 
 func (e *entity) Init(router fiber.Router) {
 	router.Post("/login", e.Login)
+	router.Post("/login/mfa", e.LoginMFA)
 	router.Post("/registration", e.Registration)
 	router.Post("/confirmation", e.Confirmation)
 	router.Post("/recovery", e.PasswordRecovery)
 	router.Post("/reset", e.PasswordReset)
 	router.Group("/refresh", e.middleware).Get("", e.RefreshToken)
+	sessions := router.Group("/sessions", e.access)
+	sessions.Get("", e.GetSessions)
+	sessions.Delete("", e.RevokeAllSessions)
+	sessions.Delete("/:session_id", e.RevokeSession)
+	mfa := router.Group("/mfa", e.access)
+	mfa.Get("", e.GetMFA)
+	mfa.Post("/totp", e.EnrollTOTP)
+	mfa.Post("/totp/confirm", e.ConfirmTOTP)
+	mfa.Post("/disable", e.DisableMFA)
+	mfa.Post("/recovery-codes", e.RegenerateRecoveryCodes)
 }
 
 type entity struct {
 	name   string
 	secret string
+	issuer string
 
 	// Services
-	log *slog.Logger
+	log   *slog.Logger
+	smq   *sessionmq.Queue
+	cache cache.Cache
 
 	// DB entities
 	auth       authentication.Authentication
@@ -38,23 +56,32 @@ type entity struct {
 	reg        registration.Registration
 	mailer     *mailer.Mailer
 	disc       discriminator.Discriminator
+	sess       usersession.UserSession
+	mfa        usermfa.UserMFA
 	middleware fiber.Handler
+	access     fiber.Handler
 }
 
 func (e *entity) Name() string {
 	return e.name
 }
 
-func New(pg *pgdb.DB, m *mailer.Mailer, secret string, log *slog.Logger, middlewares fiber.Handler) server.Entity {
+func New(pg *pgdb.DB, m *mailer.Mailer, smq *sessionmq.Queue, cache cache.Cache, secret, issuer string, log *slog.Logger, middlewares, access fiber.Handler) server.Entity {
 	return &entity{
 		name:       entityName,
 		secret:     secret,
+		issuer:     issuer,
 		log:        log,
+		smq:        smq,
+		cache:      cache,
 		auth:       authentication.New(pg.Conn()),
 		user:       user.New(pg.Conn()),
 		reg:        registration.New(pg.Conn()),
 		disc:       discriminator.New(pg.Conn()),
+		sess:       usersession.New(pg.Conn()),
+		mfa:        usermfa.New(pg.Conn()),
 		mailer:     m,
 		middleware: middlewares,
+		access:     access,
 	}
 }
@@ -14,15 +14,17 @@ import (
 
 // Login
 //
-//	@Summary	Authentication
-//	@Produce	json
-//	@Tags		Auth
-//	@Param		request	body		LoginRequest	true	"Login data"
-//	@Success	200		{object}	LoginResponse
-//	@failure	400		{string}	string	"Incorrect request body"
-//	@failure	401		{string}	string	"Unauthorized"
-//	@failure	500		{string}	string	"Something bad happened"
-//	@Router		/auth/login [post]
+//	@Summary		Authentication
+//	@Description	Users with two-factor authentication get a ticket instead of tokens, the login is finished with /auth/login/mfa.
+//	@Produce		json
+//	@Tags			Auth
+//	@Param			request	body		LoginRequest	true	"Login data"
+//	@Success		200		{object}	LoginResponse
+//	@failure		400		{string}	string	"Incorrect request body"
+//	@failure		401		{string}	string	"Unauthorized"
+//	@failure		429		{string}	string	"Two-factor login is locked after too many invalid codes"
+//	@failure		500		{string}	string	"Something bad happened"
+//	@Router			/auth/login [post]
 func (e *entity) Login(c *fiber.Ctx) error {
 	var req LoginRequest
 	err := c.BodyParser(&req)
@@ -50,7 +52,20 @@ func (e *entity) Login(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusUnauthorized, ErrUserIsBanned)
 	}
 
-	t, rt, err := helper.IssueTokens(user.Id, e.secret)
+	mfa, err := e.mfa.IsEnabled(c.UserContext(), user.Id)
+	if err != nil {
+		e.log.Error("unable to get mfa", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetMFA)
+	}
+	if mfa {
+		ticket, err := e.mfaLoginTicket(c, user.Id)
+		if err != nil {
+			return err
+		}
+		return c.JSON(LoginResponse{MFA: true, Ticket: ticket})
+	}
+
+	t, rt, err := e.startSession(c, user.Id)
 	if err != nil {
 		return err
 	}
@@ -60,22 +75,42 @@ func (e *entity) Login(c *fiber.Ctx) error {
 
 // RefreshToken
 //
-//	@Summary	Refresh authentication token
-//	@Produce	json
-//	@Tags		Auth
-//	@Param		Authorization	header		string	true	"Refresh token instead of auth"
-//	@Success	200				{object}	RefreshTokenResponse
-//	@failure	400				{string}	string	"Incorrect request body"
-//	@failure	401				{string}	string	"Unauthorized"
-//	@failure	500				{string}	string	"Something bad happened"
-//	@Router		/auth/refresh [get]
+//	@Summary		Refresh authentication token
+//	@Description	Rotates the refresh token: the used token becomes invalid. Reusing an already rotated refresh token revokes the whole session.
+//	@Produce		json
+//	@Tags			Auth
+//	@Param			Authorization	header		string	true	"Refresh token instead of auth"
+//	@Success		200				{object}	RefreshTokenResponse
+//	@failure		400				{string}	string	"Incorrect request body"
+//	@failure		401				{string}	string	"Unauthorized, session revoked or refresh token reused"
+//	@failure		500				{string}	string	"Something bad happened"
+//	@Router			/auth/refresh [get]
 func (e *entity) RefreshToken(c *fiber.Ctx) error {
-	tu, err := helper.GetUser(c)
+	claims, err := helper.GetClaims(c)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetUserFromToken)
 	}
+	if claims.SessionID == 0 {
+		// Issued before sessions were tracked, the user has to log in again
+		return fiber.NewError(fiber.StatusUnauthorized, ErrSessionRevoked)
+	}
+
+	sess, err := e.sess.GetSession(c.UserContext(), claims.UserID, claims.SessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fiber.NewError(fiber.StatusUnauthorized, ErrSessionRevoked)
+	} else if err != nil {
+		e.log.Error("unable to get session", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetSession)
+	}
+	if !sess.Active(time.Now()) {
+		return fiber.NewError(fiber.StatusUnauthorized, ErrSessionRevoked)
+	}
+	if sess.RefreshJti != claims.ID {
+		// An old refresh token means it was copied, revoke the session so neither copy works
+		return e.refreshTokenReused(c, sess.UserId, sess.Id)
+	}
 
-	u, err := e.user.GetUserById(c.UserContext(), tu.Id)
+	u, err := e.user.GetUserById(c.UserContext(), claims.UserID)
 	if err != nil {
 		return fiber.NewError(fiber.StatusUnauthorized, ErrUnableToGetUserById)
 	}
@@ -83,7 +118,18 @@ func (e *entity) RefreshToken(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusUnauthorized, ErrUserIsBanned)
 	}
 
-	t, rt, err := helper.IssueTokens(u.Id, e.secret)
+	jti := helper.NewJTI()
+	ok, err := e.sess.RotateSession(c.UserContext(), u.Id, sess.Id, claims.ID, jti, clientDevice(c), c.IP(), time.Now().Add(helper.RefreshTokenLifetime))
+	if err != nil {
+		e.log.Error("unable to rotate session", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRotateSession)
+	}
+	if !ok {
+		// Another refresh with the same token won the race
+		return e.refreshTokenReused(c, sess.UserId, sess.Id)
+	}
+
+	t, rt, err := helper.IssueTokens(u.Id, sess.Id, jti, e.secret)
 	if err != nil {
 		return err
 	}
@@ -346,5 +392,10 @@ func (e *entity) PasswordReset(c *fiber.Ctx) error {
 		return err
 	}
 
+	// Log out everywhere, the old password could be used to get the sessions
+	if err := e.revokeAll(c, req.Id); err != nil {
+		return err
+	}
+
 	return c.SendStatus(fiber.StatusOK)
 }
@@ -0,0 +1,431 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/totp"
+)
+
+const (
+	// Lifetime of the ticket returned by the first login step, in seconds
+	mfaTicketTTL    = 300
+	mfaTicketLength = 40
+	// Wrong codes a user can enter in the second login step before it is locked, over all tickets
+	mfaAttempts = 5
+	// Lockout window in seconds, counted from the first wrong code
+	mfaLockoutTTL = 900
+
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+)
+
+var errInvalidMFACode = errors.New("invalid mfa code")
+
+type mfaTicket struct {
+	UserId int64 `json:"user_id"`
+}
+
+// LoginMFA
+//
+//	@Summary		Finish two-factor login
+//	@Description	Exchanges the ticket returned by the login of a user with two-factor authentication for tokens. The code is a TOTP code or an unused recovery code.
+//	@Produce		json
+//	@Tags			Auth
+//	@Param			request	body		LoginMFARequest	true	"Ticket and code"
+//	@Success		200		{object}	LoginResponse
+//	@failure		400		{string}	string	"Incorrect request body"
+//	@failure		401		{string}	string	"Ticket expired or invalid code"
+//	@failure		429		{string}	string	"Too many invalid codes"
+//	@failure		500		{string}	string	"Something bad happened"
+//	@Router			/auth/login/mfa [post]
+func (e *entity) LoginMFA(c *fiber.Ctx) error {
+	var req LoginMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	key := mfaTicketKey(req.Ticket)
+	var ticket mfaTicket
+	if err := e.cache.GetJSON(c.UserContext(), key, &ticket); err != nil || ticket.UserId == 0 {
+		return fiber.NewError(fiber.StatusUnauthorized, ErrMFATicketExpired)
+	}
+	// Tickets issued before the lockout are dropped too
+	if e.mfaLocked(c.UserContext(), ticket.UserId) {
+		_ = e.cache.Delete(c.UserContext(), key)
+		return fiber.NewError(fiber.StatusTooManyRequests, ErrMFALocked)
+	}
+
+	if err := e.verifySecondFactor(c.UserContext(), ticket.UserId, req.Code); errors.Is(err, errInvalidMFACode) {
+		return e.mfaAttemptFailed(c, req.Ticket, ticket.UserId)
+	} else if err != nil {
+		e.log.Error("unable to verify mfa code", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToVerifyMFACode)
+	}
+	if err := e.cache.Delete(c.UserContext(), key); err != nil {
+		e.log.Warn("unable to delete mfa ticket", slog.String("error", err.Error()))
+	}
+	if err := e.cache.Delete(c.UserContext(), mfaAttemptsKey(ticket.UserId)); err != nil {
+		e.log.Warn("unable to reset mfa attempts", slog.String("error", err.Error()))
+	}
+
+	user, err := e.user.GetUserById(c.UserContext(), ticket.UserId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, ErrUnableToGetUserById)
+	}
+	if user.Blocked {
+		return fiber.NewError(fiber.StatusUnauthorized, ErrUserIsBanned)
+	}
+
+	t, rt, err := e.startSession(c, user.Id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(LoginResponse{Token: t, RefreshToken: rt})
+}
+
+// GetMFA
+//
+//	@Summary	Get two-factor authentication status
+//	@Produce	json
+//	@Tags		Auth
+//	@Success	200	{object}	MFAStatus
+//	@failure	401	{string}	string	"Unauthorized"
+//	@failure	500	{string}	string	"Something bad happened"
+//	@Router		/auth/mfa [get]
+func (e *entity) GetMFA(c *fiber.Ctx) error {
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, ErrUnableToGetUserFromToken)
+	}
+	enabled, err := e.mfa.IsEnabled(c.UserContext(), user.Id)
+	if err != nil {
+		e.log.Error("unable to get mfa", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetMFA)
+	}
+	status := MFAStatus{Enabled: enabled}
+	if enabled {
+		status.RecoveryCodesLeft, err = e.mfa.CountRecoveryCodes(c.UserContext(), user.Id)
+		if err != nil {
+			e.log.Error("unable to count recovery codes", slog.String("error", err.Error()))
+			return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetMFA)
+		}
+	}
+	return c.JSON(status)
+}
+
+// EnrollTOTP
+//
+//	@Summary		Start TOTP enrollment
+//	@Description	Generates a new TOTP secret. Two-factor authentication is enabled after the secret is confirmed with a code, calling this again replaces an unconfirmed secret.
+//	@Produce		json
+//	@Tags			Auth
+//	@Success		200	{object}	TOTPEnrollment
+//	@failure		401	{string}	string	"Unauthorized"
+//	@failure		409	{string}	string	"Two-factor authentication is already enabled"
+//	@failure		500	{string}	string	"Something bad happened"
+//	@Router			/auth/mfa/totp [post]
+func (e *entity) EnrollTOTP(c *fiber.Ctx) error {
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, ErrUnableToGetUserFromToken)
+	}
+	auth, err := e.auth.GetAuthenticationByUserId(c.UserContext(), user.Id)
+	if err := helper.HttpDbError(err, ErrUnableToGetAuthentication); err != nil {
+		return err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGenerateToken)
+	}
+	ok, err := e.mfa.SetPendingSecret(c.UserContext(), user.Id, secret)
+	if err != nil {
+		e.log.Error("unable to set pending mfa secret", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSetMFA)
+	}
+	if !ok {
+		return fiber.NewError(fiber.StatusConflict, ErrMFAAlreadyEnabled)
+	}
+	return c.JSON(TOTPEnrollment{Secret: secret, URI: totp.URI(e.issuer, auth.Email, secret)})
+}
+
+// ConfirmTOTP
+//
+//	@Summary		Confirm TOTP enrollment
+//	@Description	Enables two-factor authentication with a code of the enrolled secret. Returns recovery codes, they are shown only once.
+//	@Produce		json
+//	@Tags			Auth
+//	@Param			request	body		MFACodeRequest	true	"Code from the authenticator app"
+//	@Success		200		{object}	RecoveryCodes
+//	@failure		400		{string}	string	"Incorrect request body or code"
+//	@failure		401		{string}	string	"Unauthorized"
+//	@failure		404		{string}	string	"No pending enrollment"
+//	@failure		409		{string}	string	"Two-factor authentication is already enabled"
+//	@failure		500		{string}	string	"Something bad happened"
+//	@Router			/auth/mfa/totp/confirm [post]
+func (e *entity) ConfirmTOTP(c *fiber.Ctx) error {
+	var req MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, ErrUnableToGetUserFromToken)
+	}
+
+	m, err := e.mfa.GetMFA(c.UserContext(), user.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fiber.NewError(fiber.StatusNotFound, ErrMFANotEnrolled)
+	} else if err != nil {
+		e.log.Error("unable to get mfa", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetMFA)
+	}
+	if m.Enabled() {
+		return fiber.NewError(fiber.StatusConflict, ErrMFAAlreadyEnabled)
+	}
+	step, ok := totp.Validate(m.TOTPSecret, req.Code, time.Now())
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, ErrInvalidMFACode)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGenerateToken)
+	}
+	ok, err = e.mfa.EnableMFA(c.UserContext(), user.Id, step, hashes)
+	if err != nil {
+		e.log.Error("unable to enable mfa", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSetMFA)
+	}
+	if !ok {
+		// Confirmed concurrently or the code was already used
+		return fiber.NewError(fiber.StatusBadRequest, ErrInvalidMFACode)
+	}
+	return c.JSON(RecoveryCodes{Codes: codes})
+}
+
+// DisableMFA
+//
+//	@Summary		Disable two-factor authentication
+//	@Description	Requires the password and a TOTP or recovery code. Removes the secret and all recovery codes.
+//	@Produce		json
+//	@Tags			Auth
+//	@Param			request	body		MFAReauthRequest	true	"Password and code"
+//	@Success		200		{string}	string	"Two-factor authentication disabled"
+//	@failure		400		{string}	string	"Incorrect request body"
+//	@failure		401		{string}	string	"Unauthorized, wrong password or code"
+//	@failure		404		{string}	string	"Two-factor authentication is not enabled"
+//	@failure		500		{string}	string	"Something bad happened"
+//	@Router			/auth/mfa/disable [post]
+func (e *entity) DisableMFA(c *fiber.Ctx) error {
+	userId, err := e.reauthenticate(c)
+	if err != nil {
+		return err
+	}
+	if err := e.mfa.DisableMFA(c.UserContext(), userId); err != nil {
+		e.log.Error("unable to disable mfa", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSetMFA)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// RegenerateRecoveryCodes
+//
+//	@Summary		Regenerate recovery codes
+//	@Description	Requires the password and a TOTP or recovery code. Previous recovery codes stop working.
+//	@Produce		json
+//	@Tags			Auth
+//	@Param			request	body		MFAReauthRequest	true	"Password and code"
+//	@Success		200		{object}	RecoveryCodes
+//	@failure		400		{string}	string	"Incorrect request body"
+//	@failure		401		{string}	string	"Unauthorized, wrong password or code"
+//	@failure		404		{string}	string	"Two-factor authentication is not enabled"
+//	@failure		500		{string}	string	"Something bad happened"
+//	@Router			/auth/mfa/recovery-codes [post]
+func (e *entity) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userId, err := e.reauthenticate(c)
+	if err != nil {
+		return err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGenerateToken)
+	}
+	if err := e.mfa.ReplaceRecoveryCodes(c.UserContext(), userId, hashes); err != nil {
+		e.log.Error("unable to replace recovery codes", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToSetMFA)
+	}
+	return c.JSON(RecoveryCodes{Codes: codes})
+}
+
+// mfaLoginTicket returns the ticket for the second login step
+func (e *entity) mfaLoginTicket(c *fiber.Ctx, userId int64) (string, error) {
+	if e.mfaLocked(c.UserContext(), userId) {
+		return "", fiber.NewError(fiber.StatusTooManyRequests, ErrMFALocked)
+	}
+	ticket, err := helper.RandomToken(mfaTicketLength)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGenerateToken)
+	}
+	if err := e.cache.SetTimedJSON(c.UserContext(), mfaTicketKey(ticket), mfaTicket{UserId: userId}, mfaTicketTTL); err != nil {
+		e.log.Error("unable to store mfa ticket", slog.String("error", err.Error()))
+		return "", fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateMFATicket)
+	}
+	return ticket, nil
+}
+
+// mfaAttemptFailed counts a wrong code of the user and drops the ticket when the attempts run out.
+// The count is kept per user, so a new login does not give more guesses.
+func (e *entity) mfaAttemptFailed(c *fiber.Ctx, ticket string, userId int64) error {
+	key := mfaAttemptsKey(userId)
+	n, err := e.cache.Incr(c.UserContext(), key)
+	if err == nil && n == 1 {
+		err = e.cache.SetTTL(c.UserContext(), key, mfaLockoutTTL)
+	}
+	if err != nil || n >= mfaAttempts {
+		_ = e.cache.Delete(c.UserContext(), mfaTicketKey(ticket))
+	}
+	return fiber.NewError(fiber.StatusUnauthorized, ErrInvalidMFACode)
+}
+
+// mfaLocked reports whether the user entered too many wrong codes in the lockout window
+func (e *entity) mfaLocked(ctx context.Context, userId int64) bool {
+	n, err := e.cache.GetInt64(ctx, mfaAttemptsKey(userId))
+	return err == nil && n >= mfaAttempts
+}
+
+// reauthenticate checks the password and the second factor of the current user
+func (e *entity) reauthenticate(c *fiber.Ctx) (int64, error) {
+	var req MFAReauthRequest
+	if err := c.BodyParser(&req); err != nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, ErrUnableToParseBody)
+	}
+	if err := req.Validate(); err != nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return 0, fiber.NewError(fiber.StatusUnauthorized, ErrUnableToGetUserFromToken)
+	}
+
+	auth, err := e.auth.GetAuthenticationByUserId(c.UserContext(), user.Id)
+	if err := helper.HttpDbError(err, ErrUnableToGetAuthentication); err != nil {
+		return 0, err
+	}
+	if err := CompareHashAndPassword(auth.PasswordHash, req.Password); err != nil {
+		return 0, fiber.NewError(fiber.StatusUnauthorized, ErrUnableToCompareHash)
+	}
+
+	enabled, err := e.mfa.IsEnabled(c.UserContext(), user.Id)
+	if err != nil {
+		e.log.Error("unable to get mfa", slog.String("error", err.Error()))
+		return 0, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetMFA)
+	}
+	if !enabled {
+		return 0, fiber.NewError(fiber.StatusNotFound, ErrMFANotEnabled)
+	}
+	if err := e.verifySecondFactor(c.UserContext(), user.Id, req.Code); errors.Is(err, errInvalidMFACode) {
+		return 0, fiber.NewError(fiber.StatusUnauthorized, ErrInvalidMFACode)
+	} else if err != nil {
+		e.log.Error("unable to verify mfa code", slog.String("error", err.Error()))
+		return 0, fiber.NewError(fiber.StatusInternalServerError, ErrUnableToVerifyMFACode)
+	}
+	return user.Id, nil
+}
+
+// verifySecondFactor accepts a TOTP code of a step that was not used yet or an unused recovery code
+func (e *entity) verifySecondFactor(ctx context.Context, userId int64, code string) error {
+	m, err := e.mfa.GetMFA(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errInvalidMFACode
+	} else if err != nil {
+		return err
+	}
+	if !m.Enabled() {
+		return errInvalidMFACode
+	}
+
+	code = normalizeCode(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(m.TOTPSecret, code, time.Now())
+		if !ok {
+			return errInvalidMFACode
+		}
+		used, err := e.mfa.UseStep(ctx, userId, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := e.mfa.UseRecoveryCode(ctx, userId, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errInvalidMFACode
+	}
+	return nil
+}
+
+// newRecoveryCodes returns codes to show to the user and their hashes to store
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	buf := make([]byte, recoveryCodeLength)
+	for range recoveryCodeCount {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		var sb strings.Builder
+		for i, b := range buf {
+			if i == recoveryCodeLength/2 {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte(recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+		}
+		code := sb.String()
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(normalizeCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+// Recovery codes are random, so a fast hash is enough
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeCode drops separators users type or copy along with the code
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
+
+func mfaTicketKey(ticket string) string {
+	return fmt.Sprintf("mfa_ticket:%s", ticket)
+}
+
+func mfaAttemptsKey(userId int64) string {
+	return fmt.Sprintf("mfa_attempts:%d", userId)
+}
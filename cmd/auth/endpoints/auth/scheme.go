@@ -2,6 +2,7 @@ package auth
 
 import (
 	"regexp"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
@@ -34,6 +35,25 @@ const (
 	ErrEmailNotFound                    = "email not found"
 	ErrUnableToSetPasswordHash          = "unable to set password hash"
 	ErrRecoveryEmailAlreadySent         = "recovery email already sent"
+	ErrUnableToCreateSession            = "unable to create session"
+	ErrUnableToGetSession               = "unable to get session"
+	ErrUnableToRotateSession            = "unable to rotate session"
+	ErrUnableToRevokeSession            = "unable to revoke session"
+	ErrSessionRevoked                   = "session is revoked"
+	ErrSessionNotFound                  = "session not found"
+	ErrRefreshTokenReused               = "refresh token was already used, session is revoked"
+	ErrIncorrectSessionId               = "incorrect session id"
+	ErrUnableToGetAuthentication        = "unable to get authentication"
+	ErrUnableToGetMFA                   = "unable to get two-factor authentication"
+	ErrUnableToSetMFA                   = "unable to update two-factor authentication"
+	ErrUnableToVerifyMFACode            = "unable to verify two-factor code"
+	ErrUnableToCreateMFATicket          = "unable to create two-factor login ticket"
+	ErrMFAAlreadyEnabled                = "two-factor authentication is already enabled"
+	ErrMFANotEnabled                    = "two-factor authentication is not enabled"
+	ErrMFANotEnrolled                   = "no pending two-factor enrollment"
+	ErrMFATicketExpired                 = "two-factor login ticket is expired"
+	ErrInvalidMFACode                   = "invalid two-factor code"
+	ErrMFALocked                        = "too many invalid two-factor codes, try again later"
 
 	// Validation error messages
 	ErrNameRequired               = "name is required"
@@ -51,6 +71,9 @@ const (
 	ErrEmailRequired              = "email is required"
 	ErrEmailInvalidFormat         = "email format is invalid"
 	ErrPasswordRequired           = "password is required"
+	ErrTicketRequired             = "ticket is required"
+	ErrCodeRequired               = "code is required"
+	ErrCodeTooLong                = "code must be less than 32 characters"
 )
 
 var (
@@ -76,8 +99,27 @@ func (r LoginRequest) Validate() error {
 }
 
 type LoginResponse struct {
-	Token        string `json:"token"`         // Authentication token
-	RefreshToken string `json:"refresh_token"` // Refresh token. Used to refresh authentication token.
+	Token        string `json:"token"`                                                       // Authentication token
+	RefreshToken string `json:"refresh_token"`                                               // Refresh token. Used to refresh authentication token.
+	MFA          bool   `json:"mfa,omitempty"`                                               // Two-factor authentication is required, tokens are empty. Finish the login with the ticket.
+	Ticket       string `json:"ticket,omitempty" example:"just_a_randomly_generated_ticket"` // Ticket for the second login step, valid for 5 minutes
+}
+
+type LoginMFARequest struct {
+	Ticket string `json:"ticket" example:"just_a_randomly_generated_ticket"` // Ticket from the login response
+	Code   string `json:"code" example:"123456"`                             // TOTP code or recovery code
+}
+
+func (r LoginMFARequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Ticket,
+			validation.Required.Error(ErrTicketRequired),
+		),
+		validation.Field(&r.Code,
+			validation.Required.Error(ErrCodeRequired),
+			validation.RuneLength(0, 32).Error(ErrCodeTooLong),
+		),
+	)
 }
 
 type RefreshTokenResponse struct {
@@ -172,3 +214,56 @@ func (r PasswordResetRequest) Validate() error {
 		),
 	)
 }
+
+type Session struct {
+	Id         int64     `json:"id" example:"2230469276416868352"`                 // Session ID
+	Device     string    `json:"device" example:"Mozilla/5.0 (X11; Linux x86_64)"` // User agent of the last login or refresh
+	IP         string    `json:"ip" example:"203.0.113.7"`                         // IP address of the last login or refresh
+	CreatedAt  time.Time `json:"created_at"`                                       // Login time
+	LastUsedAt time.Time `json:"last_used_at"`                                     // Time of the last token refresh
+	Current    bool      `json:"current"`                                          // Session of the token used for this request
+}
+
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`                         // Whether two-factor authentication is enabled
+	RecoveryCodesLeft int  `json:"recovery_codes_left" example:"8"` // Number of unused recovery codes
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`                            // Base32 encoded secret for manual entry
+	URI    string `json:"uri" example:"otpauth://totp/GoChat:user@example.com?secret=JBSWY3DPEHPK3PXP"` // otpauth URI to show as a QR code
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" example:"123456"` // TOTP code from the authenticator app
+}
+
+func (r MFACodeRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Code,
+			validation.Required.Error(ErrCodeRequired),
+			validation.RuneLength(0, 32).Error(ErrCodeTooLong),
+		),
+	)
+}
+
+type MFAReauthRequest struct {
+	Password string `json:"password" example:"VerYstR0NgP@66WoR6"` // User password
+	Code     string `json:"code" example:"123456"`                 // TOTP code or recovery code
+}
+
+func (r MFAReauthRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Password,
+			validation.Required.Error(ErrPasswordRequired),
+		),
+		validation.Field(&r.Code,
+			validation.Required.Error(ErrCodeRequired),
+			validation.RuneLength(0, 32).Error(ErrCodeTooLong),
+		),
+	)
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"codes" example:"abcde-fgh23"` // One-time recovery codes, shown only once
+}
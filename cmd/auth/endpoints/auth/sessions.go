@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/helper"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+)
+
+// Max length of the stored user agent
+const maxDeviceLength = 256
+
+// GetSessions
+//
+//	@Summary	Get login sessions
+//	@Produce	json
+//	@Tags		Auth
+//	@Success	200	{array}		Session
+//	@failure	401	{string}	string	"Unauthorized"
+//	@failure	500	{string}	string	"Something bad happened"
+//	@Router		/auth/sessions [get]
+func (e *entity) GetSessions(c *fiber.Ctx) error {
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, ErrUnableToGetUserFromToken)
+	}
+	sessions, err := e.sess.GetActiveSessions(c.UserContext(), user.Id)
+	if err != nil {
+		e.log.Error("unable to get sessions", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetSession)
+	}
+	resp := make([]Session, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, Session{
+			Id:         s.Id,
+			Device:     s.Device,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			Current:    s.Id == user.SessionId,
+		})
+	}
+	return c.JSON(resp)
+}
+
+// RevokeSession
+//
+//	@Summary		Revoke login session
+//	@Description	Logs out the session: its refresh token stops working and gateway connections of the session are closed.
+//	@Produce		json
+//	@Tags			Auth
+//	@Param			session_id	path		int64	true	"Session ID"
+//	@Success		200			{string}	string	"Session revoked"
+//	@failure		400			{string}	string	"Incorrect session id"
+//	@failure		401			{string}	string	"Unauthorized"
+//	@failure		404			{string}	string	"Session not found"
+//	@failure		500			{string}	string	"Something bad happened"
+//	@Router			/auth/sessions/{session_id} [delete]
+func (e *entity) RevokeSession(c *fiber.Ctx) error {
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, ErrUnableToGetUserFromToken)
+	}
+	sessionId, err := strconv.ParseInt(c.Params("session_id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, ErrIncorrectSessionId)
+	}
+	ok, err := e.sess.RevokeSession(c.UserContext(), user.Id, sessionId)
+	if err != nil {
+		e.log.Error("unable to revoke session", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRevokeSession)
+	}
+	if !ok {
+		return fiber.NewError(fiber.StatusNotFound, ErrSessionNotFound)
+	}
+	e.notifyRevoked(user.Id, sessionId)
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// RevokeAllSessions
+//
+//	@Summary		Log out everywhere
+//	@Description	Revokes all login sessions of the user, including the current one.
+//	@Produce		json
+//	@Tags			Auth
+//	@Success		200	{string}	string	"Sessions revoked"
+//	@failure		401	{string}	string	"Unauthorized"
+//	@failure		500	{string}	string	"Something bad happened"
+//	@Router			/auth/sessions [delete]
+func (e *entity) RevokeAllSessions(c *fiber.Ctx) error {
+	user, err := helper.GetUser(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, ErrUnableToGetUserFromToken)
+	}
+	if err := e.revokeAll(c, user.Id); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// startSession creates a login session and issues its tokens
+func (e *entity) startSession(c *fiber.Ctx, userId int64) (token, refresh string, err error) {
+	sess := model.UserSession{
+		Id:         idgen.Next(),
+		UserId:     userId,
+		RefreshJti: helper.NewJTI(),
+		Device:     clientDevice(c),
+		IP:         c.IP(),
+		ExpiresAt:  time.Now().Add(helper.RefreshTokenLifetime),
+	}
+	if err := e.sess.CreateSession(c.UserContext(), sess); err != nil {
+		e.log.Error("unable to create session", slog.String("error", err.Error()))
+		return "", "", fiber.NewError(fiber.StatusInternalServerError, ErrUnableToCreateSession)
+	}
+	return helper.IssueTokens(userId, sess.Id, sess.RefreshJti, e.secret)
+}
+
+// refreshTokenReused revokes the session after an already rotated refresh token was presented
+func (e *entity) refreshTokenReused(c *fiber.Ctx, userId, sessionId int64) error {
+	e.log.Warn("refresh token reuse detected, revoking session",
+		slog.Int64("user_id", userId),
+		slog.Int64("session_id", sessionId),
+		slog.String("ip", c.IP()))
+	if _, err := e.sess.RevokeSession(c.UserContext(), userId, sessionId); err != nil {
+		e.log.Error("unable to revoke session", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRevokeSession)
+	}
+	e.notifyRevoked(userId, sessionId)
+	return fiber.NewError(fiber.StatusUnauthorized, ErrRefreshTokenReused)
+}
+
+// revokeAll revokes every session of the user
+func (e *entity) revokeAll(c *fiber.Ctx, userId int64) error {
+	if _, err := e.sess.RevokeUserSessions(c.UserContext(), userId); err != nil {
+		e.log.Error("unable to revoke sessions", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToRevokeSession)
+	}
+	// Without session IDs the gateway also drops connections authorized by tokens issued before sessions were tracked
+	e.notifyRevoked(userId)
+	return nil
+}
+
+// notifyRevoked asks gateways to close connections of the revoked sessions, all sessions of the user if none are given
+func (e *entity) notifyRevoked(userId int64, sessions ...int64) {
+	if e.smq == nil {
+		return
+	}
+	if err := e.smq.Revoke(userId, sessions...); err != nil {
+		e.log.Error("unable to send session revoke event", slog.Int64("user_id", userId), slog.String("error", err.Error()))
+	}
+}
+
+func clientDevice(c *fiber.Ctx) string {
+	ua := c.Get(fiber.HeaderUserAgent)
+	if len(ua) > maxDeviceLength {
+		ua = ua[:maxDeviceLength]
+	}
+	return ua
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"reflect"
 	"time"
 
 	"github.com/gocql/gocql"
 	nq "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 
 	"github.com/FlameInTheDark/gochat/cmd/embedder/config"
 	"github.com/FlameInTheDark/gochat/internal/cache/kvs"
@@ -20,11 +22,13 @@ import (
 	"github.com/FlameInTheDark/gochat/internal/embed"
 	"github.com/FlameInTheDark/gochat/internal/embedgen"
 	"github.com/FlameInTheDark/gochat/internal/embedmq"
+	"github.com/FlameInTheDark/gochat/internal/mq/durable"
 	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
 	mqnats "github.com/FlameInTheDark/gochat/internal/mq/nats"
 )
 
-const embedQueueGroup = "embedder"
+// embedDurable is the consumer shared by all embedder replicas
+const embedDurable = "embedder"
 
 type App struct {
 	log   *slog.Logger
@@ -33,9 +37,10 @@ type App struct {
 	gen   *embedgen.Generator
 	cache *kvs.Cache
 	conn  *nq.Conn
+	js    jetstream.JetStream
 	mqt   *mqnats.NatsQueue
 
-	subs []*nq.Subscription
+	consumer *durable.Consumer
 }
 
 func NewApp(logger *slog.Logger) (*App, error) {
@@ -57,6 +62,13 @@ func NewApp(logger *slog.Logger) (*App, error) {
 		return nil, err
 	}
 
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		_ = database.Close()
+		return nil, err
+	}
+
 	logger.Info("Connecting to NATS publisher")
 	transport, err := mqnats.New(cfg.NatsConnString)
 	if err != nil {
@@ -100,36 +112,46 @@ func NewApp(logger *slog.Logger) (*App, error) {
 		gen:   generator,
 		cache: embedCache,
 		conn:  conn,
+		js:    js,
 		mqt:   transport,
 	}, nil
 }
 
 func (a *App) Start() error {
 	a.log.Info("Starting service")
-	sub, err := a.conn.QueueSubscribe(embedmq.MakeEmbedSubject, embedQueueGroup, func(msg *nq.Msg) {
-		var request embedmq.MakeEmbedRequest
-		if err := json.Unmarshal(msg.Data, &request); err != nil {
-			a.log.Error("failed to decode embed request", slog.String("error", err.Error()))
-			return
-		}
-		if err := a.processRequest(request); err != nil {
-			a.log.Error("failed to process embed request",
-				slog.Int64("message_id", request.Message.Id),
-				slog.Int64("channel_id", request.Message.ChannelId),
-				slog.String("error", err.Error()))
-		}
-	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	if err := durable.EnsureStreams(ctx, a.js, embedmq.Stream); err != nil {
+		return err
+	}
+	consumer, err := durable.Consume(ctx, a.js, durable.ConsumerConfig{
+		Stream:  embedmq.Stream.Name,
+		Durable: embedDurable,
+		Handlers: map[string]durable.Handler{
+			embedmq.MakeEmbedSubject: a.handleRequest,
+		},
+		HandleTimeout: 15 * time.Second,
+	}, a.log)
 	if err != nil {
 		return err
 	}
-	a.subs = append(a.subs, sub)
+	a.consumer = consumer
 	return nil
 }
 
-func (a *App) processRequest(request embedmq.MakeEmbedRequest) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+func (a *App) handleRequest(ctx context.Context, data []byte) error {
+	var request embedmq.MakeEmbedRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return durable.Permanent(fmt.Errorf("failed to decode embed request: %w", err))
+	}
+	if err := a.processRequest(ctx, request); err != nil {
+		return fmt.Errorf("failed to process embed request for message %d: %w", request.Message.Id, err)
+	}
+	return nil
+}
 
+func (a *App) processRequest(ctx context.Context, request embedmq.MakeEmbedRequest) error {
 	currentMessage, err := a.msg.GetMessage(ctx, request.Message.Id, request.Message.ChannelId)
 	if err != nil {
 		if errors.Is(err, gocql.ErrNotFound) {
@@ -201,10 +223,8 @@ func (a *App) persistAndPublish(ctx context.Context, request embedmq.MakeEmbedRe
 }
 
 func (a *App) Close() error {
-	for _, sub := range a.subs {
-		if err := sub.Unsubscribe(); err != nil {
-			a.log.Error("unable to unsubscribe", slog.String("error", err.Error()))
-		}
+	if a.consumer != nil {
+		a.consumer.Stop()
 	}
 	if a.conn != nil {
 		a.conn.Close()
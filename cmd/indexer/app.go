@@ -3,28 +3,31 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"time"
 
+	nq "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
 	"github.com/FlameInTheDark/gochat/cmd/indexer/config"
 	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/indexmq"
+	"github.com/FlameInTheDark/gochat/internal/mq/durable"
 	"github.com/FlameInTheDark/gochat/internal/msgsearch"
-	nq "github.com/nats-io/nats.go"
 )
 
-const (
-	indexQueue       = "indexer.message"
-	indexDeleteQueue = "indexer.delete"
-	indexUpdateQueue = "indexer.update"
-)
+// indexerDurable is the consumer shared by all indexer replicas
+const indexerDurable = "indexer"
 
 type App struct {
 	logger *slog.Logger
 
 	search *msgsearch.Search
 	conn   *nq.Conn
+	js     jetstream.JetStream
 
-	subs []*nq.Subscription
+	consumer *durable.Consumer
 }
 
 func NewApp(logger *slog.Logger) (*App, error) {
@@ -43,116 +46,115 @@ func NewApp(logger *slog.Logger) (*App, error) {
 		return nil, err
 	}
 
+	js, err := jetstream.New(c)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
 	return &App{
 		logger: logger,
 		search: search,
 		conn:   c,
+		js:     js,
 	}, nil
 }
 
 func (a *App) Start() error {
 	a.logger.Info("Starting service")
-	sub, err := a.conn.Subscribe(indexQueue, func(msg *nq.Msg) {
-		a.logger.Info("Received message", slog.String("body", string(msg.Data)))
-		var indexMsg dto.IndexMessage
-		err := json.Unmarshal(msg.Data, &indexMsg)
-		if err != nil {
-			a.logger.Error(err.Error())
-			a.logger.Debug("Error unmarshalling message", slog.String("data", string(msg.Data)))
-			return
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-		defer cancel()
-		err = a.search.IndexMessage(ctx, msgsearch.Message{
-			GuildId:   indexMsg.GuildId,
-			ChannelId: indexMsg.ChannelId,
-			UserId:    indexMsg.UserId,
-			MessageId: indexMsg.MessageId,
-			Has:       indexMsg.Has,
-			Mentions:  indexMsg.Mentions,
-			Content:   indexMsg.Content,
-		})
-		if err != nil {
-			a.logger.Error("Error indexing message", slog.String("error", err.Error()))
-			return
-		}
-	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	err := durable.EnsureStreams(ctx, a.js, indexmq.Stream)
 	if err != nil {
 		a.logger.Error(err.Error())
 		return err
 	}
-	a.subs = append(a.subs, sub)
-
-	delsub, err := a.conn.Subscribe(indexDeleteQueue, func(msg *nq.Msg) {
-		a.logger.Info("Received delete message", slog.String("body", string(msg.Data)))
-		var indexMsg dto.IndexDeleteMessage
-		err := json.Unmarshal(msg.Data, &indexMsg)
-		if err != nil {
-			a.logger.Error(err.Error())
-			a.logger.Debug("Error unmarshalling message", slog.String("data", string(msg.Data)))
-			return
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-		defer cancel()
-		err = a.search.DeleteMessage(ctx, msgsearch.DeleteMessage{
-			ChannelId: indexMsg.ChannelId,
-			MessageId: indexMsg.MessageId,
-		})
-		if err != nil {
-			a.logger.Error("Error deleting message", slog.String("error", err.Error()))
-			return
-		}
-	})
+
+	a.consumer, err = durable.Consume(ctx, a.js, durable.ConsumerConfig{
+		Stream:  indexmq.Stream.Name,
+		Durable: indexerDurable,
+		Handlers: map[string]durable.Handler{
+			indexmq.IndexSubject:  a.indexMessage,
+			indexmq.DeleteSubject: a.deleteMessage,
+			indexmq.UpdateSubject: a.updateMessage,
+		},
+	}, a.logger)
 	if err != nil {
 		a.logger.Error(err.Error())
 		return err
 	}
-	a.subs = append(a.subs, delsub)
-
-	updsub, err := a.conn.Subscribe(indexUpdateQueue, func(msg *nq.Msg) {
-		a.logger.Info("Received update message", slog.String("body", string(msg.Data)))
-		var indexMsg dto.IndexMessage
-		err := json.Unmarshal(msg.Data, &indexMsg)
-		if err != nil {
-			a.logger.Error(err.Error())
-			a.logger.Debug("Error unmarshalling message", slog.String("data", string(msg.Data)))
-			return
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-		defer cancel()
-		err = a.search.UpdateMessage(ctx, msgsearch.Message{
-			GuildId:   indexMsg.GuildId,
-			ChannelId: indexMsg.ChannelId,
-			UserId:    indexMsg.UserId,
-			MessageId: indexMsg.MessageId,
-			Has:       indexMsg.Has,
-			Mentions:  indexMsg.Mentions,
-			Content:   indexMsg.Content,
-		})
-		if err != nil {
-			a.logger.Error("Error updating message", slog.String("error", err.Error()))
-			return
-		}
+	return nil
+}
+
+func (a *App) indexMessage(ctx context.Context, data []byte) error {
+	a.logger.Debug("Received message", slog.String("body", string(data)))
+	var indexMsg dto.IndexMessage
+	err := json.Unmarshal(data, &indexMsg)
+	if err != nil {
+		return durable.Permanent(fmt.Errorf("unable to unmarshal index message: %w", err))
+	}
+
+	err = a.search.IndexMessage(ctx, msgsearch.Message{
+		GuildId:   indexMsg.GuildId,
+		ChannelId: indexMsg.ChannelId,
+		UserId:    indexMsg.UserId,
+		MessageId: indexMsg.MessageId,
+		Has:       indexMsg.Has,
+		Mentions:  indexMsg.Mentions,
+		Content:   indexMsg.Content,
 	})
 	if err != nil {
-		a.logger.Error(err.Error())
-		return err
+		return fmt.Errorf("unable to index message: %w", err)
+	}
+	return nil
+}
+
+func (a *App) deleteMessage(ctx context.Context, data []byte) error {
+	a.logger.Debug("Received delete message", slog.String("body", string(data)))
+	var indexMsg dto.IndexDeleteMessage
+	err := json.Unmarshal(data, &indexMsg)
+	if err != nil {
+		return durable.Permanent(fmt.Errorf("unable to unmarshal delete message: %w", err))
+	}
+
+	err = a.search.DeleteMessage(ctx, msgsearch.DeleteMessage{
+		ChannelId: indexMsg.ChannelId,
+		MessageId: indexMsg.MessageId,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete message: %w", err)
 	}
-	a.subs = append(a.subs, updsub)
+	return nil
+}
 
+func (a *App) updateMessage(ctx context.Context, data []byte) error {
+	a.logger.Debug("Received update message", slog.String("body", string(data)))
+	var indexMsg dto.IndexMessage
+	err := json.Unmarshal(data, &indexMsg)
+	if err != nil {
+		return durable.Permanent(fmt.Errorf("unable to unmarshal update message: %w", err))
+	}
+
+	err = a.search.UpdateMessage(ctx, msgsearch.Message{
+		GuildId:   indexMsg.GuildId,
+		ChannelId: indexMsg.ChannelId,
+		UserId:    indexMsg.UserId,
+		MessageId: indexMsg.MessageId,
+		Has:       indexMsg.Has,
+		Mentions:  indexMsg.Mentions,
+		Content:   indexMsg.Content,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to update message: %w", err)
+	}
 	return nil
 }
 
 func (a *App) Close() error {
 	a.logger.Debug("Closing app")
-	for _, sub := range a.subs {
-		err := sub.Unsubscribe()
-		if err != nil {
-			a.logger.Error("Unable to unsubscribe", slog.String("error", err.Error()))
-		}
+	if a.consumer != nil {
+		a.consumer.Stop()
 	}
 	a.conn.Close()
 	return nil
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -16,7 +17,11 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	recm "github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/interceptor/pkg/gcc"
 	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v4"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -25,6 +30,7 @@ import (
 	"github.com/FlameInTheDark/gochat/cmd/sfu/config"
 	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
 	"github.com/FlameInTheDark/gochat/internal/permissions"
+	"github.com/FlameInTheDark/gochat/internal/s3"
 	"github.com/FlameInTheDark/gochat/internal/shutter"
 )
 
@@ -38,8 +44,11 @@ type App struct {
 
 	iceConfig webrtc.Configuration
 	webrtcAPI *webrtc.API // Custom API with restricted MediaEngine
+	// Bandwidth estimators of new peer connections by connection ID, taken right after creation
+	estimators sync.Map
 
 	instID      string
+	signalURL   string // public signal URL of this node, join tokens name the origin node by it
 	totalPeers  atomic.Int64
 	discoverLog sync.Once
 }
@@ -59,7 +68,6 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) *App {
 	}
 
 	iceCfg := buildICEConfig(cfg.STUNServers)
-	api := buildWebRTCAPI(logger)
 
 	fiberApp := fiber.New(fiber.Config{DisableStartupMessage: true})
 	lm := slogfiber.NewWithFilters(logger, slogfiber.IgnorePath("/metrics"))
@@ -82,7 +90,17 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) *App {
 	} else if marginPct > 100 {
 		marginPct = 100
 	}
-	sfu := NewSFU(cfg.WebhookURL, cfg.WebhookToken, logger, maxAudioBps, cfg.EnforceAudioBitrate, marginPct)
+	sfu := NewSFU(cfg.WebhookURL, cfg.WebhookToken, logger, maxAudioBps, cfg.EnforceAudioBitrate, marginPct, cfg.Simulcast)
+	if cfg.S3Endpoint != "" {
+		storage, err := s3.NewClient(cfg.S3Endpoint, cfg.S3AccessKeyID, cfg.S3SecretAccessKey, cfg.S3Region, cfg.S3Bucket, cfg.S3UseSSL)
+		if err != nil {
+			logger.Error("unable to create recording storage", slog.String("error", err.Error()))
+			panic(err)
+		}
+		sfu.EnableRecording(storage, recordingPublicBase(cfg), cfg.RecordingDir, time.Duration(cfg.RecordingMaxMinutes)*time.Minute)
+	} else {
+		logger.Info("channel recording disabled", slog.String("reason", "s3 endpoint missing"))
+	}
 
 	a := &App{
 		app:       fiberApp,
@@ -91,13 +109,20 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) *App {
 		shut:      shut,
 		sfu:       sfu,
 		instID:    cfg.ServiceID,
+		signalURL: buildSignalURL(cfg.PublicBaseURL),
 		iceConfig: iceCfg,
-		webrtcAPI: api,
 	}
+	a.webrtcAPI = buildWebRTCAPI(logger, func(id string, est cc.BandwidthEstimator) {
+		a.estimators.Store(id, est)
+	})
 
 	fiberApp.Get("/signal", websocket.New(a.handleSignalWS, websocket.Config{}))
+	fiberApp.Get("/relay", websocket.New(a.handleRelayWS, websocket.Config{}))
 	fiberApp.Post("/admin/channel/close", a.handleAdminCloseChannel)
+	fiberApp.Post("/admin/channel/timeout", a.handleAdminTimeoutUser)
+	fiberApp.Post("/admin/channel/recording", a.handleAdminRecording)
 	go sfu.RunKeyFrameTicker()
+	go sfu.RunLayerSelection()
 
 	return a
 }
@@ -147,10 +172,21 @@ func buildICEConfig(stunServers []string) webrtc.Configuration {
 
 // buildWebRTCAPI creates a webrtc.API with a restricted MediaEngine (Opus + VP8 + VP9 only)
 // and TWCC header extensions registered for bandwidth estimation.
-// Uses minimal interceptors to avoid crashes in the RTCP receiver report interceptor.
-func buildWebRTCAPI(logger *slog.Logger) *webrtc.API {
+// Uses minimal interceptors to avoid crashes in the RTCP receiver report interceptor:
+// only TWCC sequence numbers on outbound packets and the congestion controller that
+// estimates the bandwidth of every subscriber. onEstimator receives the estimator of
+// each new peer connection by its ID.
+func buildWebRTCAPI(logger *slog.Logger, onEstimator cc.NewPeerConnectionCallback) *webrtc.API {
 	me := &webrtc.MediaEngine{}
 
+	// Video feedback: bandwidth estimates for layer selection, keyframe requests for layer switches
+	videoFeedback := []webrtc.RTCPFeedback{
+		{Type: webrtc.TypeRTCPFBGoogREMB},
+		{Type: webrtc.TypeRTCPFBTransportCC},
+		{Type: webrtc.TypeRTCPFBCCM, Parameter: "fir"},
+		{Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"},
+	}
+
 	// Audio: Opus only (48kHz, 2ch)
 	if err := me.RegisterCodec(webrtc.RTPCodecParameters{
 		RTPCodecCapability: webrtc.RTPCodecCapability{
@@ -167,8 +203,9 @@ func buildWebRTCAPI(logger *slog.Logger) *webrtc.API {
 	// Video: VP8 (widely supported, low complexity)
 	if err := me.RegisterCodec(webrtc.RTPCodecParameters{
 		RTPCodecCapability: webrtc.RTPCodecCapability{
-			MimeType:  webrtc.MimeTypeVP8,
-			ClockRate: 90000,
+			MimeType:     webrtc.MimeTypeVP8,
+			ClockRate:    90000,
+			RTCPFeedback: videoFeedback,
 		},
 		PayloadType: 96,
 	}, webrtc.RTPCodecTypeVideo); err != nil {
@@ -178,8 +215,9 @@ func buildWebRTCAPI(logger *slog.Logger) *webrtc.API {
 	// Video: VP9 (better quality at same bitrate, optional)
 	if err := me.RegisterCodec(webrtc.RTPCodecParameters{
 		RTPCodecCapability: webrtc.RTPCodecCapability{
-			MimeType:  webrtc.MimeTypeVP9,
-			ClockRate: 90000,
+			MimeType:     webrtc.MimeTypeVP9,
+			ClockRate:    90000,
+			RTCPFeedback: videoFeedback,
 		},
 		PayloadType: 98,
 	}, webrtc.RTPCodecTypeVideo); err != nil {
@@ -195,7 +233,32 @@ func buildWebRTCAPI(logger *slog.Logger) *webrtc.API {
 		logger.Warn("failed to register TWCC extension for audio", slog.String("error", err.Error()))
 	}
 
-	return webrtc.NewAPI(webrtc.WithMediaEngine(me))
+	// MID and RID header extensions identify simulcast layers that are not declared in the SDP
+	for _, uri := range []string{sdp.SDESMidURI, sdp.SDESRTPStreamIDURI, sdp.SDESRepairRTPStreamIDURI} {
+		if err := me.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: uri}, webrtc.RTPCodecTypeVideo); err != nil {
+			logger.Warn("failed to register simulcast extension", slog.String("uri", uri), slog.String("error", err.Error()))
+		}
+	}
+
+	registry := &interceptor.Registry{}
+	if err := webrtc.ConfigureTWCCHeaderExtensionSender(me, registry); err != nil {
+		logger.Warn("failed to configure TWCC sender", slog.String("error", err.Error()))
+	}
+	congestion, err := cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
+		// No pacing: packets are forwarded as they arrive, the estimate only drives layer selection
+		return gcc.NewSendSideBWE(
+			gcc.SendSideBWEInitialBitrate(initialBitrateEstimate),
+			gcc.SendSideBWEPacer(gcc.NewNoOpPacer()),
+		)
+	})
+	if err != nil {
+		logger.Warn("failed to create congestion controller", slog.String("error", err.Error()))
+	} else {
+		congestion.OnNewPeerConnection(onEstimator)
+		registry.Add(congestion)
+	}
+
+	return webrtc.NewAPI(webrtc.WithMediaEngine(me), webrtc.WithInterceptorRegistry(registry))
 }
 
 // ---------------------------------------------------------------------------
@@ -233,6 +296,22 @@ func (a *App) notifyUserLeave(uid, channelID int64, guildID *int64) {
 	}
 }
 
+// notifyUserStream sends an async webhook notification for a user starting or stopping a screen share.
+func (a *App) notifyUserStream(uid, channelID int64, guildID *int64, streaming bool) {
+	go func() {
+		resp, err := a.sfu.httpClient.R().
+			SetHeader("Content-Type", "application/json").
+			SetHeader("X-Webhook-Token", a.cfg.WebhookToken).
+			SetBody(UserStreamNotify{UserId: uid, ChannelId: channelID, GuildId: guildID, Streaming: streaming}).
+			Post(a.cfg.WebhookURL + "/api/v1/webhook/sfu/voice/stream")
+		if err != nil {
+			a.log.Error("user stream notify failed", slog.String("error", err.Error()))
+		} else if resp.StatusCode() != 200 {
+			a.log.Warn("user stream notify unexpected status", slog.Int("status", resp.StatusCode()))
+		}
+	}()
+}
+
 // ---------------------------------------------------------------------------
 // WebSocket signal handler
 // ---------------------------------------------------------------------------
@@ -247,12 +326,13 @@ func (a *App) handleSignalWS(c *websocket.Conn) {
 		_ = (&threadSafeWriter{conn: c.Conn}).SendEnvelope(OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCJoin), D: ErrorResponse{Error: "invalid message"}})
 		return
 	}
-	uid, channelID, guildID, perms, _, err := a.authorizeJoin(joinEnv)
+	claims, err := a.authorizeJoin(joinEnv)
 	if err != nil {
 		a.log.Warn("join unauthorized", slog.String("error", err.Error()))
 		_ = (&threadSafeWriter{conn: c.Conn}).SendEnvelope(OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCJoin), D: ErrorResponse{Error: err.Error()}})
 		return
 	}
+	uid, channelID, guildID, perms := claims.UserID, claims.ChannelID, claims.GuildID, claims.Perms
 
 	if a.sfu.IsBlocked(channelID, uid) {
 		a.log.Warn("blocked user tried to join", slog.Int64("user", uid), slog.Int64("channel", channelID))
@@ -260,6 +340,13 @@ func (a *App) handleSignalWS(c *websocket.Conn) {
 		return
 	}
 
+	// Moved users and members who can move members are let into a full channel
+	if !claims.Moved && !hasPerm(perms, permissions.PermVoiceMoveMembers) && a.sfu.IsFull(channelID, uid, claims.UserLimit) {
+		a.log.Info("user tried to join a full channel", slog.Int64("user", uid), slog.Int64("channel", channelID))
+		_ = (&threadSafeWriter{conn: c.Conn}).SendEnvelope(OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCJoin), D: ErrorResponse{Error: "channel full"}})
+		return
+	}
+
 	// Phase 2: Setup вЂ” create PeerConnection and register it.
 	a.notifyUserJoin(uid, channelID, guildID)
 	defer a.notifyUserLeave(uid, channelID, guildID)
@@ -272,9 +359,14 @@ func (a *App) handleSignalWS(c *websocket.Conn) {
 	defer func() { _ = pc.Close() }()
 
 	writer := &threadSafeWriter{conn: c.Conn}
-	state := &peerConnectionState{peerConnection: pc, websocket: writer, userID: uid, perms: perms}
+	state := &peerConnectionState{peerConnection: pc, websocket: writer, userID: uid, guildID: guildID, perms: perms}
+	// Stage moderators can always speak, other users wait for approval while the channel is a stage
+	state.stageSpeaker.Store(hasPerm(perms, permissions.PermVoiceMuteMembers))
+	if est, ok := a.estimators.LoadAndDelete(pc.ID()); ok {
+		state.bwe = est.(cc.BandwidthEstimator)
+	}
 
-	if err := a.setupTransceivers(pc); err != nil {
+	if err := a.setupTransceivers(pc, state); err != nil {
 		a.log.Error("failed to setup transceivers", slog.String("error", err.Error()))
 		return
 	}
@@ -288,11 +380,16 @@ func (a *App) handleSignalWS(c *websocket.Conn) {
 
 	a.log.Info("client joined", slog.Int64("user", uid), slog.Int64("channel", channelID))
 
-	a.sfu.AddPeer(channelID, state)
+	ch := a.sfu.AddPeer(channelID, state, claims.settings())
+	// Clients of a cascaded channel joined on an edge node, the channel is relayed from the origin node
+	if claims.Relay != "" && claims.Relay != a.signalURL {
+		a.ensureUplink(ch, claims.Relay, claims.settings())
+	}
 	a.totalPeers.Add(1)
 	defer func() {
 		writer.Close()
 		a.sfu.RemovePeer(channelID, pc)
+		a.releaseUplink(channelID)
 		a.totalPeers.Add(-1)
 		a.log.Info("client left", slog.Int64("user", uid), slog.Int64("channel", channelID))
 	}()
@@ -303,14 +400,40 @@ func (a *App) handleSignalWS(c *websocket.Conn) {
 	a.messageLoop(c, pc, writer, uid, perms, channelID)
 }
 
-// setupTransceivers adds audio and video sendrecv transceivers to the peer connection.
-func (a *App) setupTransceivers(pc *webrtc.PeerConnection) error {
+// handleRelayWS accepts the relay link of an edge node of a cascaded channel bound to this node.
+func (a *App) handleRelayWS(c *websocket.Conn) {
+	defer func() { _ = c.Close() }()
+	a.serveRelay(c.Conn)
+}
+
+// setupTransceivers adds audio and video sendrecv transceivers for the camera and microphone,
+// then a second pair the client publishes a screen share on.
+// Simulcast is offered on the camera video transceiver only. The screen pair is sendrecv as well,
+// so pion keeps a placeholder sender on it and never reuses it for forwarded tracks.
+func (a *App) setupTransceivers(pc *webrtc.PeerConnection, state *peerConnectionState) error {
 	for _, typ := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeVideo, webrtc.RTPCodecTypeAudio} {
-		if _, err := pc.AddTransceiverFromKind(typ, webrtc.RTPTransceiverInit{
+		tr, err := pc.AddTransceiverFromKind(typ, webrtc.RTPTransceiverInit{
 			Direction: webrtc.RTPTransceiverDirectionSendrecv,
-		}); err != nil {
+		})
+		if err != nil {
 			return fmt.Errorf("add transceiver %s: %w", typ, err)
 		}
+		if typ == webrtc.RTPCodecTypeVideo {
+			state.videoTransceiver = tr
+		}
+	}
+	for _, typ := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeVideo, webrtc.RTPCodecTypeAudio} {
+		tr, err := pc.AddTransceiverFromKind(typ, webrtc.RTPTransceiverInit{
+			Direction: webrtc.RTPTransceiverDirectionSendrecv,
+		})
+		if err != nil {
+			return fmt.Errorf("add screen transceiver %s: %w", typ, err)
+		}
+		if typ == webrtc.RTPCodecTypeVideo {
+			state.screenVideoTransceiver = tr
+		} else {
+			state.screenAudioTransceiver = tr
+		}
 	}
 	return nil
 }
@@ -340,17 +463,20 @@ func (a *App) registerPeerCallbacks(
 		}
 	})
 
-	pc.OnTrack(func(t *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
-		a.handleInboundTrack(pc, state, t, uid, channelID, perms)
+	pc.OnTrack(func(t *webrtc.TrackRemote, r *webrtc.RTPReceiver) {
+		a.handleInboundTrack(pc, state, t, state.isScreenReceiver(r), uid, channelID, perms)
 	})
 }
 
 // handleInboundTrack processes a single inbound track, forwarding RTP packets to
 // a local track while enforcing permissions and bitrate limits.
+// Simulcast layers arrive as separate tracks with a RID, each one feeds the same simulcast source.
+// Screen share tracks need PermVoiceStream instead of the speak and video permissions.
 func (a *App) handleInboundTrack(
 	pc *webrtc.PeerConnection,
 	state *peerConnectionState,
 	t *webrtc.TrackRemote,
+	screen bool,
 	uid, channelID, perms int64,
 ) {
 	// Recover from panics in the track read loop. Pion's internal buffers
@@ -366,14 +492,18 @@ func (a *App) handleInboundTrack(
 		}
 	}()
 
-	a.log.Info("inbound track", slog.String("kind", t.Kind().String()), slog.String("id", t.ID()))
+	a.log.Info("inbound track", slog.String("kind", t.Kind().String()), slog.String("id", t.ID()), slog.String("rid", t.RID()), slog.Bool("screen", screen))
 
 	// Permission enforcement
-	if t.Kind() == webrtc.RTPCodecTypeAudio && !hasPerm(perms, permissions.PermVoiceSpeak) {
+	if screen && !hasPerm(perms, permissions.PermVoiceStream) {
+		a.log.Warn("rejecting screen share track: no PermVoiceStream", slog.Int64("user", uid))
+		return
+	}
+	if !screen && t.Kind() == webrtc.RTPCodecTypeAudio && !hasPerm(perms, permissions.PermVoiceSpeak) {
 		a.log.Warn("rejecting audio track: no PermVoiceSpeak", slog.Int64("user", uid))
 		return
 	}
-	if t.Kind() == webrtc.RTPCodecTypeVideo && !hasPerm(perms, permissions.PermVoiceVideo) {
+	if !screen && t.Kind() == webrtc.RTPCodecTypeVideo && !hasPerm(perms, permissions.PermVoiceVideo) {
 		a.log.Warn("rejecting video track: no PermVoiceVideo", slog.Int64("user", uid))
 		return
 	}
@@ -384,23 +514,42 @@ func (a *App) handleInboundTrack(
 		return
 	}
 
-	trackLocal := a.sfu.AddTrack(channelID, uid, t)
+	trackLocal := a.sfu.AddTrack(channelID, uid, pc, t, screen)
 	if trackLocal == nil {
 		a.log.Warn("failed to create forwarding track", slog.Int64("user", uid), slog.Int64("channel", channelID), slog.String("track", t.ID()), slog.String("kind", t.Kind().String()))
 		return
 	}
 	defer a.sfu.RemoveTrack(channelID, trackLocal)
 
-	a.forwardRTP(pc, t, trackLocal, uid, channelID)
+	// The screen share video decides whether the user is live, screen audio is optional
+	if screen && t.Kind() == webrtc.RTPCodecTypeVideo {
+		if a.sfu.StartStream(channelID, state) {
+			a.notifyUserStream(uid, channelID, state.guildID, true)
+		}
+		defer func() {
+			if a.sfu.StopStream(channelID, state) {
+				a.notifyUserStream(uid, channelID, state.guildID, false)
+			}
+		}()
+	}
+
+	// Microphone tracks are recorded while the channel recording runs and held back from stage listeners
+	var target rtpWriter = trackLocal
+	if !screen && t.Kind() == webrtc.RTPCodecTypeAudio {
+		target = a.sfu.StageGate(channelID, state, a.sfu.RecordingTap(channelID, state, trackLocal))
+	}
+
+	a.forwardRTP(pc, t, target, uid, channelID, a.sfu.AudioBitrateLimit(channelID))
 }
 
-// forwardRTP reads RTP packets from the remote track and writes them to the local track.
-// Handles audio bitrate enforcement when configured.
+// forwardRTP reads RTP packets from the remote track and writes them to the local track or simulcast layer.
+// Handles audio bitrate enforcement when configured, limit is the audio bitrate limit in bps and 0 disables it.
 func (a *App) forwardRTP(
 	pc *webrtc.PeerConnection,
 	remote *webrtc.TrackRemote,
-	local *webrtc.TrackLocalStaticRTP,
+	local rtpWriter,
 	uid, channelID int64,
+	limit uint64,
 ) {
 	// Recover from panics in pion's interceptor chain. The RTCP receiver report
 	// interceptor can crash with a nil pointer dereference when the PeerConnection
@@ -419,12 +568,12 @@ func (a *App) forwardRTP(
 	buf := make([]byte, 1500)
 	rtpPacket := &rtp.Packet{}
 
-	// Bitrate enforcement for audio if configured
+	// Bitrate enforcement for audio if configured, the channel bitrate lowers the global limit
 	enforce := a.sfu.enforceAudioBitrate &&
-		a.sfu.maxAudioBitrateBps > 0 &&
+		limit > 0 &&
 		remote.Kind() == webrtc.RTPCodecTypeAudio
 
-	limitWithMargin := float64(a.sfu.maxAudioBitrateBps)
+	limitWithMargin := float64(limit)
 	if a.sfu.audioBitrateMarginPct > 0 {
 		limitWithMargin *= 1.0 + float64(a.sfu.audioBitrateMarginPct)/100.0
 	}
@@ -704,6 +853,37 @@ func (a *App) handleLegacyRTCEvent(env envelope, pc *webrtc.PeerConnection, writ
 			return false
 		}
 		a.sfu.BlockUser(channelID, data.UserId, data.Block)
+
+	case int(mqmsg.EventTypeRTCVideoQuality):
+		var data videoQualityData
+		if err := json.Unmarshal(env.D, &data); err != nil {
+			return false
+		}
+		q, ok := parseVideoQuality(data.Quality)
+		if !ok {
+			a.log.Warn("unknown video quality", slog.Int64("user", uid), slog.String("quality", data.Quality))
+			return false
+		}
+		a.sfu.SetVideoQuality(channelID, uid, data.User, q)
+
+	case int(mqmsg.EventTypeRTCRaiseHand):
+		var data raiseHandData
+		if err := json.Unmarshal(env.D, &data); err != nil {
+			return false
+		}
+		a.sfu.RaiseHand(channelID, uid, data.Raised)
+
+	case int(mqmsg.EventTypeRTCStageSpeaker):
+		var data stageSpeakerData
+		if err := json.Unmarshal(env.D, &data); err != nil {
+			return false
+		}
+		// Anyone can step down, approving speakers is up to the stage moderators
+		if !hasPerm(perms, permissions.PermVoiceMuteMembers) && (data.User != uid || data.Speaker) {
+			a.log.Warn("stage speaker denied: insufficient permissions", slog.Int64("user", uid))
+			return false
+		}
+		a.sfu.SetStageSpeaker(channelID, data.User, data.Speaker)
 	}
 	return false
 }
@@ -746,6 +926,63 @@ func (a *App) handleAdminCloseChannel(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// handleAdminTimeoutUser server-mutes a timed out guild member until the timeout ends.
+// Requires a valid admin JWT in the Authorization header.
+func (a *App) handleAdminTimeoutUser(c *fiber.Ctx) error {
+	token := c.Get("Authorization")
+	channelID, err := a.validateAdminToken(token)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var req TimeoutUserRequest
+	if err := c.BodyParser(&req); err != nil || req.ChannelID == 0 || req.UserID == 0 || req.Until < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+	if channelID != 0 && channelID != req.ChannelID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "channel mismatch"})
+	}
+	var until time.Time
+	if req.Until > 0 {
+		until = time.Unix(req.Until, 0)
+	}
+	a.sfu.TimeoutUser(req.ChannelID, req.UserID, until)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// handleAdminRecording starts or stops the recording of a channel.
+func (a *App) handleAdminRecording(c *fiber.Ctx) error {
+	token := c.Get("Authorization")
+	channelID, err := a.validateAdminToken(token)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var req RecordingRequest
+	if err := c.BodyParser(&req); err != nil || req.ChannelID == 0 || (req.Recording && (req.TextChannelID == 0 || req.AttachmentID == 0)) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+	if channelID != 0 && channelID != req.ChannelID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "channel mismatch"})
+	}
+	if req.Recording {
+		err = a.sfu.StartRecording(req.ChannelID, req.GuildID, req.UserID, req.TextChannelID, req.AttachmentID)
+	} else {
+		err = a.sfu.StopRecording(req.ChannelID, req.UserID)
+	}
+	switch {
+	case err == nil:
+		return c.SendStatus(fiber.StatusNoContent)
+	case errors.Is(err, errRecordingDisabled):
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errChannelNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errRecordingActive), errors.Is(err, errNotRecording):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		a.log.Error("recording request failed", slog.Int64("channel", req.ChannelID), slog.String("error", err.Error()))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "recording failed"})
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Discovery heartbeat
 // ---------------------------------------------------------------------------
@@ -756,8 +993,6 @@ func (a *App) discoveryHeartbeat() {
 		return
 	}
 
-	url := buildSignalURL(a.cfg.PublicBaseURL)
-
 	client := a.sfu.httpClient
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
@@ -775,7 +1010,7 @@ func (a *App) discoveryHeartbeat() {
 			payload := heartbeatPayload{
 				ID:     a.instID,
 				Region: a.cfg.Region,
-				URL:    url,
+				URL:    a.signalURL,
 				Load:   a.totalPeers.Load(),
 			}
 			resp, err := client.R().
@@ -820,6 +1055,23 @@ func buildSignalURL(publicBaseURL string) string {
 	return url
 }
 
+// recordingPublicBase returns the public URL prefix of the recording bucket, the same way the attachments service builds it.
+func recordingPublicBase(cfg *config.Config) string {
+	if base := strings.TrimRight(cfg.S3ExternalURL, "/"); base != "" {
+		return base
+	}
+	endp := cfg.S3Endpoint
+	low := strings.ToLower(endp)
+	if !strings.HasPrefix(low, "http://") && !strings.HasPrefix(low, "https://") {
+		if cfg.S3UseSSL {
+			endp = "https://" + endp
+		} else {
+			endp = "http://" + endp
+		}
+	}
+	return strings.TrimRight(endp, "/") + "/" + strings.Trim(cfg.S3Bucket, "/")
+}
+
 // ---------------------------------------------------------------------------
 // Handshake helpers
 // ---------------------------------------------------------------------------
@@ -838,16 +1090,18 @@ func (a *App) readJoinEnvelope(c *websocket.Conn) (rtcJoinEnvelope, error) {
 	return env, nil
 }
 
-func (a *App) authorizeJoin(env rtcJoinEnvelope) (int64, int64, *int64, int64, bool, error) {
+// authorizeJoin validates the join envelope and returns the token claims scoped to the requested channel.
+func (a *App) authorizeJoin(env rtcJoinEnvelope) (*sfuClaims, error) {
 	if env.OP != int(mqmsg.OPCodeRTC) || env.T != int(mqmsg.EventTypeRTCJoin) || env.D.Token == "" || env.D.Channel == 0 {
-		return 0, 0, nil, 0, false, fmt.Errorf("expected join")
+		return nil, fmt.Errorf("expected join")
 	}
-	uid, tokChannel, tokGuild, perms, moved, err := a.validateJoinToken(env.D.Token)
+	claims, err := a.validateJoinToken(env.D.Token)
 	if err != nil {
-		return 0, 0, nil, 0, false, fmt.Errorf("unauthorized")
+		return nil, fmt.Errorf("unauthorized")
 	}
-	if tokChannel != 0 && tokChannel != env.D.Channel {
-		return 0, 0, nil, 0, false, fmt.Errorf("unauthorized")
+	if claims.ChannelID != 0 && claims.ChannelID != env.D.Channel {
+		return nil, fmt.Errorf("unauthorized")
 	}
-	return uid, env.D.Channel, tokGuild, perms, moved, nil
+	claims.ChannelID = env.D.Channel
+	return claims, nil
 }
@@ -19,6 +19,17 @@ type sfuClaims struct {
 	// Moved indicates the user was force-moved to this channel by an admin
 	// or a user with PermVoiceMoveMembers; bypasses channel-level blocks.
 	Moved bool `json:"moved,omitempty"`
+	// Voice channel settings, zero values keep the SFU defaults
+	UserLimit int  `json:"user_limit,omitempty"`
+	Bitrate   int  `json:"bitrate,omitempty"` // audio bitrate cap in kbps
+	Stage     bool `json:"stage,omitempty"`
+	// Relay is the signal URL of the origin node of a cascaded channel, set when the user joins an edge node
+	Relay string `json:"relay,omitempty"`
+}
+
+// settings returns the channel settings carried by the token.
+func (c *sfuClaims) settings() channelSettings {
+	return channelSettings{userLimit: c.UserLimit, bitrateKbps: c.Bitrate, stage: c.Stage}
 }
 
 // stripBearerPrefix removes an optional "Bearer " prefix.
@@ -74,8 +85,7 @@ func (a *App) validateAdminToken(token string) (int64, error) {
 }
 
 // validateJoinToken parses and validates the SFU join token.
-// Returns (userID, channelID, perms, error).
-func (a *App) validateJoinToken(token string) (int64, int64, *int64, int64, bool, error) {
+func (a *App) validateJoinToken(token string) (*sfuClaims, error) {
 	var claims sfuClaims
 
 	tok := stripBearerPrefix(token)
@@ -95,13 +105,62 @@ func (a *App) validateJoinToken(token string) (int64, int64, *int64, int64, bool
 		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
 	)
 	if err != nil {
-		return 0, 0, nil, 0, false, err
+		return nil, err
 	}
 
 	// Ensure the token type and audience are correct.
 	if claims.TokenType != "sfu" || !containsString(claims.Audience, "sfu") {
-		return 0, 0, nil, 0, false, fmt.Errorf("aud/typ mismatch")
+		return nil, fmt.Errorf("aud/typ mismatch")
 	}
 
-	return claims.UserID, claims.ChannelID, claims.GuildID, claims.Perms, claims.Moved, nil
+	return &claims, nil
+}
+
+// relayClaims defines the relay JWT contents, SFU nodes of a cascaded channel authenticate relay links with it.
+type relayClaims struct {
+	helper.Claims
+	ChannelID int64 `json:"channel_id"`
+}
+
+// issueRelayToken signs a short-lived relay JWT (typ=relay, aud=sfu) for the channel with the shared secret.
+func (a *App) issueRelayToken(channelID int64) (string, error) {
+	now := time.Now()
+	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, relayClaims{
+		Claims: helper.Claims{
+			TokenType: "relay",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "gochat",
+				Audience:  []string{"sfu"},
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			},
+		},
+		ChannelID: channelID,
+	})
+	return tok.SignedString([]byte(a.cfg.AuthSecret))
+}
+
+// validateRelayToken parses and validates a relay JWT and returns the channel ID it is scoped to.
+func (a *App) validateRelayToken(token string) (int64, error) {
+	var claims relayClaims
+	_, err := jwt.ParseWithClaims(
+		stripBearerPrefix(token),
+		&claims,
+		func(t *jwt.Token) (any, error) {
+			if t.Method != jwt.SigningMethodHS256 {
+				return nil, fmt.Errorf("unexpected alg: %s", t.Method.Alg())
+			}
+			return []byte(a.cfg.AuthSecret), nil
+		},
+		jwt.WithIssuer("gochat"),
+		jwt.WithLeeway(2*time.Second),
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+	)
+	if err != nil {
+		return 0, err
+	}
+	if claims.TokenType != "relay" || !containsString(claims.Audience, "sfu") || claims.ChannelID == 0 {
+		return 0, fmt.Errorf("aud/typ mismatch")
+	}
+	return claims.ChannelID, nil
 }
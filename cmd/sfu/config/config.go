@@ -29,6 +29,24 @@ type Config struct {
 	// during enforcement to account for headers/Jitter/overhead. E.g. 15 means
 	// 15% over the configured cap is tolerated before disconnect. Range 0..100.
 	AudioBitrateMarginPercent int `yaml:"audio_bitrate_margin_percent" env:"SFU_AUDIO_BITRATE_MARGIN_PERCENT" env-default:"15"`
+
+	// Simulcast offers publishers to send their camera in several layers (RIDs q, h, f).
+	// Every subscriber then receives the layer that fits its bandwidth.
+	Simulcast bool `yaml:"simulcast" env:"SFU_SIMULCAST" env-default:"true"`
+
+	// Recording storage, channel recording is disabled when S3Endpoint is empty.
+	S3Endpoint        string `yaml:"s3_endpoint" env:"S3_ENDPOINT" env-default:""`
+	S3AccessKeyID     string `yaml:"s3_access_key_id" env:"S3_ACCESS_KEY_ID" env-default:""`
+	S3SecretAccessKey string `yaml:"s3_secret_access_key" env:"S3_SECRET_ACCESS_KEY" env-default:""`
+	S3UseSSL          bool   `yaml:"s3_use_ssl" env:"S3_USE_SSL" env-default:"false"`
+	S3Bucket          string `yaml:"s3_bucket" env:"S3_BUCKET" env-default:"gochat"`
+	S3Region          string `yaml:"s3_region" env:"S3_REGION"`
+	S3ExternalURL     string `yaml:"s3_external_url" env:"S3_EXTERNAL_URL"`
+
+	// RecordingDir keeps the track files until the recording is uploaded, empty means the system temp directory.
+	RecordingDir string `yaml:"recording_dir" env:"SFU_RECORDING_DIR" env-default:""`
+	// RecordingMaxMinutes stops recordings after the given time, 0 disables the limit.
+	RecordingMaxMinutes int `yaml:"recording_max_minutes" env:"SFU_RECORDING_MAX_MINUTES" env-default:"240"`
 }
 
 func LoadConfig() (*Config, error) {
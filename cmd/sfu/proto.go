@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/pion/webrtc/v4"
 )
 
 const joinHandshakeTimeout = 5 * time.Second
@@ -76,6 +78,14 @@ type UserLeaveNotify struct {
 	GuildId   *int64 `json:"guild_id"`
 }
 
+// UserStreamNotify tells the webhook service that a user started or stopped sharing their screen.
+type UserStreamNotify struct {
+	UserId    int64  `json:"user_id"`
+	ChannelId int64  `json:"channel_id"`
+	GuildId   *int64 `json:"guild_id"`
+	Streaming bool   `json:"streaming"`
+}
+
 type ChannelAliveNotify struct {
 	GuildId   *int64 `json:"guild_id"`
 	ChannelId int64  `json:"channel_id"`
@@ -83,9 +93,11 @@ type ChannelAliveNotify struct {
 
 // speakingEvent is sent to clients in the same channel to indicate
 // that a user started or stopped speaking. Speaking is 1 (active) or 0 (inactive).
+// Priority is set while a priority speaker talks, clients lower the volume of the others.
 type speakingEvent struct {
 	UserId   int64 `json:"user_id"`
 	Speaking int   `json:"speaking"`
+	Priority bool  `json:"priority,omitempty"`
 }
 
 // muteEvent is broadcast when a user is server-muted or unmuted.
@@ -100,6 +112,46 @@ type deafenEvent struct {
 	Deafened bool  `json:"deafened"`
 }
 
+// RecordingNotify tells the webhook service that a channel recording was uploaded
+// and should be posted to the text channel.
+type RecordingNotify struct {
+	ChannelId     int64  `json:"channel_id"`
+	GuildId       *int64 `json:"guild_id"`
+	UserId        int64  `json:"user_id"`
+	TextChannelId int64  `json:"text_channel_id"`
+	AttachmentId  int64  `json:"attachment_id"`
+	Name          string `json:"name"`
+	URL           string `json:"url"`
+	ContentType   string `json:"content_type"`
+	Size          int64  `json:"size"`
+	DurationMs    int64  `json:"duration_ms"`
+}
+
+// streamEvent is broadcast when a user goes live with a screen share or stops sharing.
+type streamEvent struct {
+	UserId    int64 `json:"user_id"`
+	Streaming bool  `json:"streaming"`
+}
+
+// recordingEvent is broadcast when a moderator starts or stops recording the channel.
+// UserId is 0 when the recording stopped on its own.
+type recordingEvent struct {
+	Recording bool  `json:"recording"`
+	UserId    int64 `json:"user_id,omitempty"`
+}
+
+// raiseHandEvent is broadcast when a stage listener raises or lowers the hand.
+type raiseHandEvent struct {
+	UserId int64 `json:"user_id"`
+	Raised bool  `json:"raised"`
+}
+
+// stageSpeakerEvent is broadcast when a user becomes a stage speaker or goes back to the audience.
+type stageSpeakerEvent struct {
+	UserId  int64 `json:"user_id"`
+	Speaker bool  `json:"speaker"`
+}
+
 // kickEvent is sent to a user being kicked from the channel.
 type kickEvent struct {
 	UserId int64 `json:"user_id"`
@@ -116,6 +168,25 @@ type CloseChannelRequest struct {
 	ChannelID int64 `json:"channel_id"`
 }
 
+// TimeoutUserRequest is the body for the admin /admin/channel/timeout endpoint.
+// Until is the unix time the member timeout ends, 0 lifts the timeout.
+type TimeoutUserRequest struct {
+	ChannelID int64 `json:"channel_id"`
+	UserID    int64 `json:"user_id"`
+	Until     int64 `json:"until"`
+}
+
+// RecordingRequest is the body for the admin /admin/channel/recording endpoint.
+// TextChannelID and AttachmentID are required to start a recording.
+type RecordingRequest struct {
+	ChannelID     int64  `json:"channel_id"`
+	GuildID       *int64 `json:"guild_id"`
+	UserID        int64  `json:"user_id"`
+	TextChannelID int64  `json:"text_channel_id"`
+	AttachmentID  int64  `json:"attachment_id"`
+	Recording     bool   `json:"recording"`
+}
+
 // muteUserData payload for local/server mute of another user.
 type muteUserData struct {
 	User  int64 `json:"user"`
@@ -132,3 +203,84 @@ type deafenUserData struct {
 type kickUserData struct {
 	User int64 `json:"user"`
 }
+
+// raiseHandData payload for raising or lowering the own hand in a stage channel.
+type raiseHandData struct {
+	Raised bool `json:"raised"`
+}
+
+// stageSpeakerData payload for approving or revoking a stage speaker.
+type stageSpeakerData struct {
+	User    int64 `json:"user"`
+	Speaker bool  `json:"speaker"`
+}
+
+// videoQualityData payload for the preferred simulcast quality of a subscriber.
+// User 0 applies the quality to every publisher.
+type videoQualityData struct {
+	User    int64  `json:"user,omitempty"`
+	Quality string `json:"quality"`
+}
+
+// Relay message types exchanged between the SFU nodes of a cascaded channel over the /relay WebSocket.
+const (
+	relayMsgJoin      = "join"      // first message of the dialing node, answered with a join once accepted
+	relayMsgOffer     = "offer"     // offer of the sender's outbound peer connection
+	relayMsgAnswer    = "answer"    // answer to an offer, applied to the receiver's outbound peer connection
+	relayMsgCandidate = "candidate" // ICE candidate, Outbound tells which peer connection of the sender it belongs to
+	relayMsgEvent     = "event"     // RTC event envelope for the clients of the other node
+	relayMsgCommand   = "command"   // moderation command for the node the target user is connected to
+	relayMsgLeave     = "leave"     // the last connection of a user left the cascade
+)
+
+// relayMessage is the message format of the relay link between two SFU nodes.
+type relayMessage struct {
+	Type      string                   `json:"type"`
+	Channel   int64                    `json:"channel,omitempty"`
+	Token     string                   `json:"token,omitempty"`
+	Settings  *relaySettings           `json:"settings,omitempty"`
+	SDP       string                   `json:"sdp,omitempty"`
+	Candidate *webrtc.ICECandidateInit `json:"candidate,omitempty"`
+	Outbound  bool                     `json:"outbound,omitempty"`
+	Event     json.RawMessage          `json:"event,omitempty"`
+	Command   *relayCommand            `json:"command,omitempty"`
+	UserID    int64                    `json:"user_id,omitempty"`
+}
+
+// relaySettings carries the voice channel settings of the dialing node's join token.
+type relaySettings struct {
+	UserLimit int  `json:"user_limit,omitempty"`
+	Bitrate   int  `json:"bitrate,omitempty"`
+	Stage     bool `json:"stage,omitempty"`
+}
+
+// Relay command names
+const (
+	relayCmdMute         = "mute"
+	relayCmdDeafen       = "deafen"
+	relayCmdKick         = "kick"
+	relayCmdKickAll      = "kick_all"
+	relayCmdBlock        = "block"
+	relayCmdTimeout      = "timeout"
+	relayCmdStageSpeaker = "stage_speaker"
+)
+
+// relayCommand is a moderation action, it runs on the node the target user is connected to.
+// Until is the unix time a timeout ends, 0 lifts it.
+type relayCommand struct {
+	Name  string `json:"name"`
+	User  int64  `json:"user,omitempty"`
+	Value bool   `json:"value,omitempty"`
+	Until int64  `json:"until,omitempty"`
+}
+
+// relayState is the part of a relayed event that tells whose state it is and whether the state is active.
+type relayState struct {
+	UserId    int64 `json:"user_id"`
+	Muted     bool  `json:"muted"`
+	Deafened  bool  `json:"deafened"`
+	Streaming bool  `json:"streaming"`
+	Recording bool  `json:"recording"`
+	Raised    bool  `json:"raised"`
+	Speaker   bool  `json:"speaker"`
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4/pkg/media/oggwriter"
+
+	"github.com/FlameInTheDark/gochat/internal/upload"
+)
+
+const (
+	recordingSampleRate    = 48000
+	recordingChannels      = 2
+	recordingManifestName  = "recording.json"
+	recordingContentType   = "application/zip"
+	recordingUploadTimeout = 30 * time.Minute
+)
+
+var (
+	errRecordingDisabled = errors.New("recording is not configured")
+	errRecordingActive   = errors.New("channel is already being recorded")
+	errNotRecording      = errors.New("channel is not being recorded")
+	errChannelNotFound   = errors.New("no voice session in channel")
+)
+
+// recordingStorage uploads finished recordings, implemented by the S3 client.
+type recordingStorage interface {
+	UploadObject(ctx context.Context, key string, body io.Reader, contentType string) error
+}
+
+// recording collects the microphone tracks of a voice channel into Ogg/Opus files, one file per track.
+// The files are packaged into a zip archive with a manifest when the recording stops.
+type recording struct {
+	channelID     int64
+	guildID       *int64
+	startedBy     int64
+	textChannelID int64
+	attachmentID  int64
+	startedAt     time.Time
+	dir           string
+	timer         *time.Timer
+	log           *slog.Logger
+
+	mu      sync.Mutex
+	tracks  map[*recordingTap]*recordedTrack
+	files   []*recordedTrack
+	perUser map[int64]int
+	stopped bool
+}
+
+// recordedTrack is the Ogg file of one inbound track.
+// Offset is the time from the recording start to the first packet of the track.
+type recordedTrack struct {
+	userID  int64
+	name    string
+	offset  time.Duration
+	ogg     *oggwriter.OggWriter
+	lastSeq uint16
+	started bool
+	failed  bool
+}
+
+// recordingManifest describes the files of a recording archive.
+type recordingManifest struct {
+	ChannelId  int64                    `json:"channel_id"`
+	GuildId    *int64                   `json:"guild_id,omitempty"`
+	StartedBy  int64                    `json:"started_by"`
+	StartedAt  time.Time                `json:"started_at"`
+	DurationMs int64                    `json:"duration_ms"`
+	Tracks     []recordingManifestTrack `json:"tracks"`
+}
+
+type recordingManifestTrack struct {
+	UserId   int64  `json:"user_id"`
+	File     string `json:"file"`
+	OffsetMs int64  `json:"offset_ms"`
+}
+
+func newRecording(dir string, channelID int64, guildID *int64, startedBy, textChannelID, attachmentID int64, log *slog.Logger) (*recording, error) {
+	tmp, err := os.MkdirTemp(dir, fmt.Sprintf("recording-%d-", channelID))
+	if err != nil {
+		return nil, fmt.Errorf("create recording directory: %w", err)
+	}
+	return &recording{
+		channelID:     channelID,
+		guildID:       guildID,
+		startedBy:     startedBy,
+		textChannelID: textChannelID,
+		attachmentID:  attachmentID,
+		startedAt:     time.Now(),
+		dir:           tmp,
+		log:           log,
+		tracks:        make(map[*recordingTap]*recordedTrack),
+		perUser:       make(map[int64]int),
+	}, nil
+}
+
+// write appends the packet to the file of the track, the file is created on the first packet.
+// Late and duplicate packets are dropped, the Ogg granule position follows the RTP timestamps.
+func (r *recording) write(tap *recordingTap, p *rtp.Packet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return
+	}
+	tr, ok := r.tracks[tap]
+	if !ok {
+		userID := tap.state.userID
+		r.perUser[userID]++
+		tr = &recordedTrack{
+			userID: userID,
+			name:   fmt.Sprintf("%d-%d.ogg", userID, r.perUser[userID]),
+			offset: time.Since(r.startedAt),
+		}
+		ogg, err := oggwriter.New(filepath.Join(r.dir, tr.name), recordingSampleRate, recordingChannels)
+		if err != nil {
+			r.log.Warn("failed to create recording file", slog.Int64("channel", r.channelID), slog.Int64("user", userID), slog.String("error", err.Error()))
+			tr.failed = true
+		} else {
+			tr.ogg = ogg
+			r.files = append(r.files, tr)
+		}
+		r.tracks[tap] = tr
+	}
+	if tr.failed {
+		return
+	}
+	if tr.started && int16(p.SequenceNumber-tr.lastSeq) <= 0 {
+		return
+	}
+	tr.started = true
+	tr.lastSeq = p.SequenceNumber
+	if err := tr.ogg.WriteRTP(p); err != nil {
+		r.log.Warn("failed to write recording packet", slog.Int64("channel", r.channelID), slog.Int64("user", tr.userID), slog.String("error", err.Error()))
+		tr.failed = true
+	}
+}
+
+// stop closes the track files and returns them, later packets are ignored.
+func (r *recording) stop() []*recordedTrack {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	for _, tr := range r.files {
+		if err := tr.ogg.Close(); err != nil {
+			r.log.Warn("failed to close recording file", slog.Int64("channel", r.channelID), slog.String("file", tr.name), slog.String("error", err.Error()))
+		}
+	}
+	return r.files
+}
+
+// writeArchive writes the zip archive with the track files and the manifest.
+// Opus is already compressed, so the files are stored without compression.
+func (r *recording) writeArchive(w io.Writer, files []*recordedTrack, endedAt time.Time) error {
+	zw := zip.NewWriter(w)
+	manifest := recordingManifest{
+		ChannelId:  r.channelID,
+		GuildId:    r.guildID,
+		StartedBy:  r.startedBy,
+		StartedAt:  r.startedAt.UTC(),
+		DurationMs: endedAt.Sub(r.startedAt).Milliseconds(),
+		Tracks:     make([]recordingManifestTrack, 0, len(files)),
+	}
+	for _, tr := range files {
+		if err := addArchiveFile(zw, filepath.Join(r.dir, tr.name), tr.name, endedAt); err != nil {
+			return err
+		}
+		manifest.Tracks = append(manifest.Tracks, recordingManifestTrack{
+			UserId:   tr.userID,
+			File:     tr.name,
+			OffsetMs: tr.offset.Milliseconds(),
+		})
+	}
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: recordingManifestName, Method: zip.Deflate, Modified: endedAt})
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(mw).Encode(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func addArchiveFile(zw *zip.Writer, path, name string, modified time.Time) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// archiveName returns the file name of the recording attachment.
+func (r *recording) archiveName() string {
+	return fmt.Sprintf("recording-%d-%s.zip", r.channelID, r.startedAt.UTC().Format("20060102-150405"))
+}
+
+// recordingTap passes the packets of a microphone track to its forwarding target
+// and to the channel recording while one is running. Server-muted users are not recorded.
+type recordingTap struct {
+	rtpWriter
+	ch    *channelState
+	state *peerConnectionState
+}
+
+func (t *recordingTap) WriteRTP(p *rtp.Packet) error {
+	if rec := t.ch.recording.Load(); rec != nil && !t.ch.isServerMuted(t.state) {
+		rec.write(t, p)
+	}
+	return t.rtpWriter.WriteRTP(p)
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// EnableRecording lets moderators record channels, finished recordings are uploaded to storage.
+// Track files are kept in dir until the upload, the system temp directory is used when empty.
+// Recordings are stopped after limit, zero means no limit.
+func (s *SFU) EnableRecording(storage recordingStorage, publicBase, dir string, limit time.Duration) {
+	s.recordingStorage = storage
+	s.recordingPublicBase = publicBase
+	s.recordingDir = dir
+	s.recordingLimit = limit
+}
+
+// StartRecording starts recording the channel, the result is posted to the text channel as the given attachment.
+func (s *SFU) StartRecording(channelID int64, guildID *int64, userID, textChannelID, attachmentID int64) error {
+	if s.recordingStorage == nil {
+		return errRecordingDisabled
+	}
+	s.mu.RLock()
+	ch, ok := s.channels[channelID]
+	s.mu.RUnlock()
+	if !ok {
+		return errChannelNotFound
+	}
+	if ch.recording.Load() != nil {
+		return errRecordingActive
+	}
+	rec, err := newRecording(s.recordingDir, channelID, guildID, userID, textChannelID, attachmentID, s.log)
+	if err != nil {
+		return err
+	}
+	if !ch.recording.CompareAndSwap(nil, rec) {
+		_ = os.RemoveAll(rec.dir)
+		return errRecordingActive
+	}
+	if s.recordingLimit > 0 {
+		rec.timer = time.AfterFunc(s.recordingLimit, func() {
+			if ch.recording.CompareAndSwap(rec, nil) {
+				s.log.Info("recording limit reached", slog.Int64("channel", channelID))
+				ch.broadcastRecordingState(0, false)
+				s.finishRecording(rec)
+			}
+		})
+	}
+	s.log.Info("recording started", slog.Int64("channel", channelID), slog.Int64("user", userID))
+	ch.broadcastRecordingState(userID, true)
+	return nil
+}
+
+// StopRecording stops the recording of the channel and uploads it in the background.
+func (s *SFU) StopRecording(channelID int64, userID int64) error {
+	s.mu.RLock()
+	ch, ok := s.channels[channelID]
+	s.mu.RUnlock()
+	if !ok {
+		return errChannelNotFound
+	}
+	rec := ch.recording.Swap(nil)
+	if rec == nil {
+		return errNotRecording
+	}
+	s.log.Info("recording stopped", slog.Int64("channel", channelID), slog.Int64("user", userID))
+	ch.broadcastRecordingState(userID, false)
+	go s.finishRecording(rec)
+	return nil
+}
+
+// RecordingTap wraps the forwarding target of a microphone track, so the track is recorded while the channel is.
+func (s *SFU) RecordingTap(channelID int64, state *peerConnectionState, w rtpWriter) rtpWriter {
+	s.mu.RLock()
+	ch, ok := s.channels[channelID]
+	s.mu.RUnlock()
+	if !ok {
+		return w
+	}
+	return &recordingTap{rtpWriter: w, ch: ch, state: state}
+}
+
+// finishRecording packages the recorded tracks, uploads the archive and tells the webhook service
+// to post it to the text channel. Recordings without audio are dropped.
+func (s *SFU) finishRecording(rec *recording) {
+	if rec.timer != nil {
+		rec.timer.Stop()
+	}
+	files := rec.stop()
+	defer func() { _ = os.RemoveAll(rec.dir) }()
+	if len(files) == 0 {
+		s.log.Info("recording has no audio, skipping upload", slog.Int64("channel", rec.channelID))
+		return
+	}
+	endedAt := time.Now()
+
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(rec.writeArchive(pw, files, endedAt))
+	}()
+	body := &countingReader{r: pr}
+	key := upload.AttachmentOriginalKey(rec.textChannelID, rec.attachmentID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), recordingUploadTimeout)
+	defer cancel()
+	if err := s.recordingStorage.UploadObject(ctx, key, body, recordingContentType); err != nil {
+		_ = pr.CloseWithError(err)
+		s.log.Error("recording upload failed", slog.Int64("channel", rec.channelID), slog.String("error", err.Error()))
+		return
+	}
+
+	resp, err := s.httpClient.R().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("X-Webhook-Token", s.webhookToken).
+		SetBody(RecordingNotify{
+			ChannelId:     rec.channelID,
+			GuildId:       rec.guildID,
+			UserId:        rec.startedBy,
+			TextChannelId: rec.textChannelID,
+			AttachmentId:  rec.attachmentID,
+			Name:          rec.archiveName(),
+			URL:           upload.PublicURL(s.recordingPublicBase, key),
+			ContentType:   recordingContentType,
+			Size:          body.n,
+			DurationMs:    endedAt.Sub(rec.startedAt).Milliseconds(),
+		}).
+		Post(s.webhookUrl + "/api/v1/webhook/sfu/voice/recording")
+	if err != nil {
+		s.log.Error("recording notify failed", slog.String("error", err.Error()))
+	} else if resp.StatusCode() != 200 {
+		s.log.Warn("recording notify unexpected status", slog.Int("status", resp.StatusCode()))
+	}
+}
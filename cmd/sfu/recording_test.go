@@ -0,0 +1,103 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/pion/rtp"
+)
+
+type fakeRecordingStorage struct {
+	key         string
+	contentType string
+	body        []byte
+}
+
+func (f *fakeRecordingStorage) UploadObject(_ context.Context, key string, body io.Reader, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	f.key, f.contentType, f.body = key, contentType, data
+	return nil
+}
+
+type discardWriter struct{}
+
+func (discardWriter) WriteRTP(*rtp.Packet) error { return nil }
+
+func TestRecordingArchive(t *testing.T) {
+	s := NewSFU("", "", slog.New(slog.NewTextHandler(io.Discard, nil)), 0, false, 0, false)
+	storage := &fakeRecordingStorage{}
+	s.EnableRecording(storage, "http://s3/gochat", t.TempDir(), 0)
+
+	if err := s.StartRecording(1, nil, 5, 10, 20); err != errChannelNotFound {
+		t.Fatalf("expected no channel error, got %v", err)
+	}
+	ch := s.getOrCreateChannel(1)
+	defer ch.stop()
+
+	speaker := s.RecordingTap(1, &peerConnectionState{userID: 5}, discardWriter{})
+	other := s.RecordingTap(1, &peerConnectionState{userID: 6}, discardWriter{})
+	muted := s.RecordingTap(1, &peerConnectionState{userID: 7, serverMuted: true}, discardWriter{})
+
+	// Packets sent before the recording starts are not recorded
+	_ = speaker.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1}, Payload: []byte{1}})
+
+	if err := s.StartRecording(1, nil, 5, 10, 20); err != nil {
+		t.Fatalf("failed to start recording: %v", err)
+	}
+	if err := s.StartRecording(1, nil, 5, 10, 20); err != errRecordingActive {
+		t.Fatalf("expected already recording error, got %v", err)
+	}
+	for _, seq := range []uint16{2, 3, 3, 2, 4} {
+		_ = speaker.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: seq, Timestamp: uint32(seq) * 960}, Payload: []byte{1, 2, 3}})
+	}
+	_ = other.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 100}, Payload: []byte{1}})
+	_ = muted.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1}, Payload: []byte{1}})
+
+	rec := ch.recording.Swap(nil)
+	if tr := rec.tracks[speaker.(*recordingTap)]; tr == nil || tr.lastSeq != 4 {
+		t.Fatal("expected late and duplicate packets to be dropped")
+	}
+	s.finishRecording(rec)
+
+	if storage.key != "media/10/20/original" || storage.contentType != recordingContentType {
+		t.Fatalf("unexpected upload %q %q", storage.key, storage.contentType)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(storage.body), int64(len(storage.body)))
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	names := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		names[f.Name] = f
+	}
+	if len(names) != 3 || names["5-1.ogg"] == nil || names["6-1.ogg"] == nil || names[recordingManifestName] == nil {
+		t.Fatalf("unexpected archive files %v", names)
+	}
+	mr, err := names[recordingManifestName].Open()
+	if err != nil {
+		t.Fatalf("failed to open manifest: %v", err)
+	}
+	defer func() { _ = mr.Close() }()
+	var manifest recordingManifest
+	if err := json.NewDecoder(mr).Decode(&manifest); err != nil {
+		t.Fatalf("failed to decode manifest: %v", err)
+	}
+	if manifest.ChannelId != 1 || manifest.StartedBy != 5 || len(manifest.Tracks) != 2 {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+}
+
+func TestRecordingDisabled(t *testing.T) {
+	s := NewSFU("", "", slog.New(slog.NewTextHandler(io.Discard, nil)), 0, false, 0, false)
+	if err := s.StartRecording(1, nil, 5, 10, 20); err != errRecordingDisabled {
+		t.Fatalf("expected disabled error, got %v", err)
+	}
+}
@@ -0,0 +1,652 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/webrtc/v4"
+
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+// ---------------------------------------------------------------------------
+// relayLink connects a channel to the same channel on another SFU node of a cascade.
+// Every client connects to the node nearest to it, the nodes forward tracks to each other.
+// The origin node is the one the channel is bound to, edge nodes in other regions dial its /relay endpoint.
+//
+// Both ends of a link work the same way: tracks go out on a peer connection registered as a channel peer,
+// so the regular sync pass offers them, and come in on a second peer connection that answers the other
+// node's offers. Events, moderation commands and leaves go over the WebSocket.
+// ---------------------------------------------------------------------------
+
+const (
+	// An edge node dials its origin again after a failure, the delay doubles up to the max
+	relayRedialMin = time.Second
+	relayRedialMax = 30 * time.Second
+)
+
+type relayLink struct {
+	ch     *channelState
+	conn   *websocket.Conn
+	writer *threadSafeWriter
+	out    *peerConnectionState
+	in     *webrtc.PeerConnection
+	remote string // address of the other node, for logs
+
+	closeOnce sync.Once
+}
+
+// remoteUser holds the sticky events of a user connected to another node, via is the link they came in on.
+type remoteUser struct {
+	via    *relayLink
+	events map[int]json.RawMessage
+}
+
+func (l *relayLink) send(msg relayMessage) error {
+	return l.writer.WriteJSON(msg)
+}
+
+// settings converts the relayed channel settings.
+func (s *relaySettings) settings() channelSettings {
+	return channelSettings{userLimit: s.UserLimit, bitrateKbps: s.Bitrate, stage: s.Stage}
+}
+
+// relayURL converts the signal URL of a node to its relay endpoint.
+func relayURL(signalURL string) string {
+	return strings.TrimSuffix(strings.TrimRight(signalURL, "/"), "/signal") + "/relay"
+}
+
+// parseStreamOwner returns the publisher of a relayed track from the "u:<id>[:screen]" stream ID.
+func parseStreamOwner(streamID string) (owner int64, screen bool, ok bool) {
+	id, found := strings.CutPrefix(streamID, "u:")
+	if !found {
+		return 0, false, false
+	}
+	id, screen = strings.CutSuffix(id, screenStreamSuffix)
+	owner, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || owner == 0 {
+		return 0, false, false
+	}
+	return owner, screen, true
+}
+
+// ---------------------------------------------------------------------------
+// Channel side of the cascade
+// ---------------------------------------------------------------------------
+
+// hasUserLocked reports whether the user has a client connection on this node, caller must hold the lock.
+func (c *channelState) hasUserLocked(userID int64) bool {
+	for _, p := range c.peers {
+		if p.relay == nil && p.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// clientCount returns the number of client connections, relay links excluded.
+func (c *channelState) clientCount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.clientCountLocked()
+}
+
+func (c *channelState) clientCountLocked() int {
+	n := 0
+	for _, p := range c.peers {
+		if p.relay == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// stopDialing clears the dialing flag once no clients are left, a client joining meanwhile keeps the uplink going.
+func (c *channelState) stopDialing() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clientCountLocked() > 0 && !c.stopped.Load() {
+		return false
+	}
+	c.dialing = false
+	return true
+}
+
+func (c *channelState) snapshotLinks() []*relayLink {
+	c.mu.RLock()
+	links := make([]*relayLink, len(c.links))
+	copy(links, c.links)
+	c.mu.RUnlock()
+	return links
+}
+
+// addLink registers the link and its outbound peer connection in the channel.
+func (c *channelState) addLink(l *relayLink) {
+	c.mu.Lock()
+	c.links = append(c.links, l)
+	c.peers = append(c.peers, l.out)
+	n := len(c.links)
+	c.mu.Unlock()
+	c.log.Info("relay link added", slog.Int64("channel", c.id), slog.String("remote", l.remote), slog.Int("links", n))
+}
+
+// removeLink drops the link together with the tracks and remote users that came in on it.
+// Returns the users the other links have to forget.
+func (c *channelState) removeLink(l *relayLink) (dropped []int64, empty bool) {
+	c.mu.Lock()
+	for i, link := range c.links {
+		if link == l {
+			c.links = append(c.links[:i], c.links[i+1:]...)
+			break
+		}
+	}
+	if c.uplink == l {
+		c.uplink = nil
+	}
+	for i, p := range c.peers {
+		if p == l.out {
+			c.removeForwarders(p)
+			c.peers = append(c.peers[:i], c.peers[i+1:]...)
+			break
+		}
+	}
+	for id, entry := range c.trackLocals {
+		if entry.via == l {
+			delete(c.trackLocals, id)
+		}
+	}
+	for userID, u := range c.remoteStates {
+		if u.via != l {
+			continue
+		}
+		delete(c.remoteStates, userID)
+		if userID != 0 {
+			dropped = append(dropped, userID)
+		}
+	}
+	empty = c.emptyLocked()
+	c.mu.Unlock()
+	c.log.Info("relay link removed", slog.Int64("channel", c.id), slog.String("remote", l.remote))
+	return dropped, empty
+}
+
+// addRelayTrack creates the forwarding target of a track relayed by another node.
+// The track and stream IDs of the publisher's node are kept, so clients map the track to the same user.
+func (c *channelState) addRelayTrack(l *relayLink, owner int64, t *webrtc.TrackRemote) *meteredTrack {
+	trackLocal, err := webrtc.NewTrackLocalStaticRTP(t.Codec().RTPCodecCapability, t.ID(), t.StreamID())
+	if err != nil {
+		c.log.Warn("failed to create relayed track", slog.Int64("channel", c.id), slog.Int64("user", owner), slog.String("track", t.ID()), slog.String("error", err.Error()))
+		return nil
+	}
+	track := &meteredTrack{TrackLocalStaticRTP: trackLocal}
+
+	c.mu.Lock()
+	c.trackLocals[t.ID()] = trackLocalEntry{track: track, owner: owner, via: l}
+	c.mu.Unlock()
+	c.log.Debug("relayed track added", slog.Int64("channel", c.id), slog.Int64("user", owner), slog.String("track", t.ID()), slog.String("kind", t.Kind().String()))
+	return track
+}
+
+// forwardEvent passes an event from another node to the local clients and the other links.
+func (c *channelState) forwardEvent(raw json.RawMessage, from *relayLink) {
+	c.rememberEvent(raw, from)
+	for _, p := range c.snapshotPeers() {
+		if from != nil && p.relay == from {
+			continue
+		}
+		_ = p.websocket.SendEvent(raw)
+	}
+}
+
+// rememberEvent keeps the latest state event of a remote user, so peers joining later learn about it.
+// Recording state is kept under user 0. Inactive states are dropped.
+func (c *channelState) rememberEvent(raw json.RawMessage, from *relayLink) {
+	var env struct {
+		T int        `json:"t"`
+		D relayState `json:"d"`
+	}
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return
+	}
+	userID := env.D.UserId
+	switch mqmsg.EventType(env.T) {
+	case mqmsg.EventTypeRTCServerMuteUser, mqmsg.EventTypeRTCServerDeafenUser, mqmsg.EventTypeRTCStream,
+		mqmsg.EventTypeRTCRaiseHand, mqmsg.EventTypeRTCStageSpeaker:
+	case mqmsg.EventTypeRTCRecording:
+		userID = 0
+	default:
+		return
+	}
+	active := env.D.Muted || env.D.Deafened || env.D.Streaming || env.D.Recording || env.D.Raised || env.D.Speaker
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	u, ok := c.remoteStates[userID]
+	if !active {
+		if ok {
+			delete(u.events, env.T)
+			if len(u.events) == 0 {
+				delete(c.remoteStates, userID)
+			}
+		}
+		return
+	}
+	if !ok {
+		u = &remoteUser{events: make(map[int]json.RawMessage)}
+		c.remoteStates[userID] = u
+	}
+	u.via = from
+	u.events[env.T] = raw
+}
+
+// sendRemoteStates replays the state of users on other nodes to a joining peer or a new link.
+func (c *channelState) sendRemoteStates(to *peerConnectionState) {
+	c.mu.RLock()
+	var events []json.RawMessage
+	for _, u := range c.remoteStates {
+		if to.relay != nil && u.via == to.relay {
+			continue
+		}
+		for _, raw := range u.events {
+			events = append(events, raw)
+		}
+	}
+	c.mu.RUnlock()
+	for _, raw := range events {
+		_ = to.websocket.SendEvent(raw)
+	}
+}
+
+// sendLeave tells the other nodes to forget a user, except the node the leave came from.
+func (c *channelState) sendLeave(userID int64, from *relayLink) {
+	for _, l := range c.snapshotLinks() {
+		if l == from {
+			continue
+		}
+		_ = l.send(relayMessage{Type: relayMsgLeave, UserID: userID})
+	}
+}
+
+// forgetUser drops the state of a user that left another node and passes the leave on.
+func (c *channelState) forgetUser(userID int64, from *relayLink) {
+	c.mu.Lock()
+	delete(c.remoteStates, userID)
+	c.mu.Unlock()
+	c.sendLeave(userID, from)
+}
+
+// sendBlockList passes the block list to a new link, so blocks made before the link was up apply on the other node.
+func (c *channelState) sendBlockList(l *relayLink) {
+	c.mu.RLock()
+	users := make([]int64, 0, len(c.blockedUsers))
+	for userID := range c.blockedUsers {
+		users = append(users, userID)
+	}
+	c.mu.RUnlock()
+	for _, userID := range users {
+		_ = l.send(relayMessage{Type: relayMsgCommand, Command: &relayCommand{Name: relayCmdBlock, User: userID, Value: true}})
+	}
+}
+
+// runCommand applies a moderation command when the target user is connected to this node and passes it on
+// to the other nodes, except the one it came from. Without relay links it always applies, as a single node did.
+// The block list is kept on every node, so a blocked user can't join any node of the cascade.
+func (c *channelState) runCommand(cmd relayCommand, from *relayLink) {
+	c.mu.RLock()
+	local := len(c.links) == 0 || c.hasUserLocked(cmd.User)
+	c.mu.RUnlock()
+
+	switch cmd.Name {
+	case relayCmdMute:
+		if local {
+			c.serverMuteUser(cmd.User, cmd.Value)
+		}
+	case relayCmdDeafen:
+		if local {
+			c.serverDeafenUser(cmd.User, cmd.Value)
+		}
+	case relayCmdKick:
+		if local {
+			c.kickUser(cmd.User)
+		}
+	case relayCmdKickAll:
+		c.kickAll()
+	case relayCmdBlock:
+		if local {
+			c.blockUser(cmd.User, cmd.Value)
+		} else {
+			c.setBlocked(cmd.User, cmd.Value)
+		}
+	case relayCmdTimeout:
+		if local {
+			var until time.Time
+			if cmd.Until > 0 {
+				until = time.Unix(cmd.Until, 0)
+			}
+			c.timeoutUser(cmd.User, until)
+		}
+	case relayCmdStageSpeaker:
+		if local && !c.setStageSpeaker(cmd.User, cmd.Value) {
+			c.log.Warn("stage speaker target not found", slog.Int64("channel", c.id), slog.Int64("user", cmd.User))
+		}
+	default:
+		c.log.Warn("unknown relay command", slog.Int64("channel", c.id), slog.String("command", cmd.Name))
+		return
+	}
+
+	for _, l := range c.snapshotLinks() {
+		if l == from {
+			continue
+		}
+		if err := l.send(relayMessage{Type: relayMsgCommand, Command: &cmd}); err != nil {
+			c.log.Warn("failed to relay command", slog.Int64("channel", c.id), slog.String("remote", l.remote), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Link setup and message loop
+// ---------------------------------------------------------------------------
+
+// serveRelay accepts a relay link from an edge node serving clients of a channel bound to this node.
+func (a *App) serveRelay(conn *websocket.Conn) {
+	var join relayMessage
+	_ = conn.SetReadDeadline(time.Now().Add(joinHandshakeTimeout))
+	if err := conn.ReadJSON(&join); err != nil || join.Type != relayMsgJoin || join.Channel == 0 {
+		a.log.Warn("invalid relay join", slog.String("remote", conn.RemoteAddr().String()))
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	channelID, err := a.validateRelayToken(join.Token)
+	if err != nil || channelID != join.Channel {
+		a.log.Warn("relay join unauthorized", slog.String("remote", conn.RemoteAddr().String()))
+		return
+	}
+
+	ch := a.sfu.getOrCreateChannel(channelID)
+	if join.Settings != nil {
+		ch.applySettings(join.Settings.settings())
+	}
+	l, err := a.newRelayLink(ch, conn, conn.RemoteAddr().String())
+	if err != nil {
+		a.log.Error("failed to create relay link", slog.Int64("channel", channelID), slog.String("error", err.Error()))
+		a.sfu.cleanupChannel(channelID, ch)
+		return
+	}
+	if err := l.send(relayMessage{Type: relayMsgJoin, Channel: channelID}); err != nil {
+		a.closeRelay(l)
+		return
+	}
+	a.startRelay(l)
+	a.runRelay(l)
+}
+
+// ensureUplink connects the channel to its origin node unless it is already kept connected.
+func (a *App) ensureUplink(ch *channelState, origin string, settings channelSettings) {
+	ch.mu.Lock()
+	if ch.dialing {
+		ch.mu.Unlock()
+		return
+	}
+	ch.dialing = true
+	ch.mu.Unlock()
+	go a.keepUplink(ch, origin, settings)
+}
+
+// keepUplink dials the origin node and dials it again with a backoff when the dial fails or the link drops,
+// as long as clients are connected to this node. Otherwise they would be cut off from the rest of the channel.
+func (a *App) keepUplink(ch *channelState, origin string, settings channelSettings) {
+	backoff := relayRedialMin
+	for !ch.stopDialing() {
+		l, err := a.connectUplink(ch, origin, settings)
+		if err != nil {
+			a.log.Error("relay uplink failed", slog.Int64("channel", ch.id), slog.String("origin", origin), slog.Duration("retry", backoff), slog.String("error", err.Error()))
+			time.Sleep(backoff)
+			backoff = min(backoff*2, relayRedialMax)
+			continue
+		}
+		ch.mu.Lock()
+		ch.uplink = l
+		ch.mu.Unlock()
+		a.startRelay(l)
+		// Every client may have left while the link was dialed
+		if ch.clientCount() == 0 {
+			a.closeRelay(l)
+			continue
+		}
+		started := time.Now()
+		a.runRelay(l)
+		if ch.clientCount() == 0 {
+			continue
+		}
+		// A link that stayed up for a while is dialed again right away
+		if time.Since(started) > relayRedialMax {
+			backoff = relayRedialMin
+		}
+		a.log.Warn("relay uplink lost", slog.Int64("channel", ch.id), slog.String("origin", origin), slog.Duration("retry", backoff))
+		time.Sleep(backoff)
+		backoff = min(backoff*2, relayRedialMax)
+	}
+}
+
+func (a *App) connectUplink(ch *channelState, origin string, settings channelSettings) (*relayLink, error) {
+	token, err := a.issueRelayToken(ch.id)
+	if err != nil {
+		return nil, fmt.Errorf("issue relay token: %w", err)
+	}
+	dialer := &websocket.Dialer{HandshakeTimeout: joinHandshakeTimeout}
+	conn, _, err := dialer.Dial(relayURL(origin), nil)
+	if err != nil {
+		return nil, fmt.Errorf("dial origin: %w", err)
+	}
+	join := relayMessage{
+		Type:     relayMsgJoin,
+		Channel:  ch.id,
+		Token:    token,
+		Settings: &relaySettings{UserLimit: settings.userLimit, Bitrate: settings.bitrateKbps, Stage: settings.stage},
+	}
+	if err := conn.WriteJSON(join); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("send join: %w", err)
+	}
+	var ack relayMessage
+	_ = conn.SetReadDeadline(time.Now().Add(joinHandshakeTimeout))
+	if err := conn.ReadJSON(&ack); err != nil || ack.Type != relayMsgJoin {
+		_ = conn.Close()
+		return nil, fmt.Errorf("relay join rejected")
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	l, err := a.newRelayLink(ch, conn, origin)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// newRelayLink creates the peer connections of a link. The link is not registered in the channel yet.
+func (a *App) newRelayLink(ch *channelState, conn *websocket.Conn, remote string) (*relayLink, error) {
+	out, err := a.webrtcAPI.NewPeerConnection(a.iceConfig)
+	if err != nil {
+		return nil, fmt.Errorf("create outbound peer connection: %w", err)
+	}
+	in, err := a.webrtcAPI.NewPeerConnection(a.iceConfig)
+	if err != nil {
+		_ = out.Close()
+		return nil, fmt.Errorf("create inbound peer connection: %w", err)
+	}
+	writer := &threadSafeWriter{conn: conn, relay: true}
+	l := &relayLink{ch: ch, conn: conn, writer: writer, in: in, remote: remote}
+	l.out = &peerConnectionState{peerConnection: out, websocket: writer, relay: l}
+	// The outbound side estimates the bandwidth to the other node for simulcast layer selection
+	if est, ok := a.estimators.LoadAndDelete(out.ID()); ok {
+		l.out.bwe = est.(cc.BandwidthEstimator)
+	}
+	a.estimators.Delete(in.ID())
+
+	for _, pc := range []*webrtc.PeerConnection{out, in} {
+		outbound := pc == out
+		pc.OnICECandidate(func(i *webrtc.ICECandidate) {
+			if i == nil {
+				return
+			}
+			cand := i.ToJSON()
+			_ = l.send(relayMessage{Type: relayMsgCandidate, Candidate: &cand, Outbound: outbound})
+		})
+		pc.OnConnectionStateChange(func(st webrtc.PeerConnectionState) {
+			a.log.Debug("relay connection state change", slog.Int64("channel", ch.id), slog.String("remote", remote), slog.Bool("outbound", outbound), slog.String("state", st.String()))
+			if st == webrtc.PeerConnectionStateFailed {
+				a.closeRelay(l)
+			}
+		})
+	}
+	in.OnTrack(func(t *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		a.handleRelayTrack(l, t)
+	})
+	return l, nil
+}
+
+// startRelay registers the link, sends the current state of the channel to the other node and starts negotiating.
+func (a *App) startRelay(l *relayLink) {
+	ch := l.ch
+	ch.addLink(l)
+	ch.sendStreamStates(l.out)
+	ch.sendRecordingState(l.out)
+	ch.sendStageState(l.out)
+	ch.sendRemoteStates(l.out)
+	ch.sendBlockList(l)
+	ch.signalPeerConnections()
+}
+
+// runRelay reads relay messages until the WebSocket closes, then closes the link.
+func (a *App) runRelay(l *relayLink) {
+	defer a.closeRelay(l)
+	for {
+		var msg relayMessage
+		if err := l.conn.ReadJSON(&msg); err != nil {
+			a.log.Info("relay link read stopped", slog.Int64("channel", l.ch.id), slog.String("remote", l.remote), slog.String("error", err.Error()))
+			return
+		}
+		if err := a.handleRelayMessage(l, msg); err != nil {
+			a.log.Warn("relay message failed", slog.Int64("channel", l.ch.id), slog.String("remote", l.remote), slog.String("type", msg.Type), slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (a *App) handleRelayMessage(l *relayLink, msg relayMessage) error {
+	switch msg.Type {
+	case relayMsgOffer:
+		if err := l.in.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: msg.SDP}); err != nil {
+			return err
+		}
+		answer, err := l.in.CreateAnswer(nil)
+		if err != nil {
+			return err
+		}
+		if err := l.in.SetLocalDescription(answer); err != nil {
+			return err
+		}
+		return l.send(relayMessage{Type: relayMsgAnswer, SDP: answer.SDP})
+
+	case relayMsgAnswer:
+		return l.out.peerConnection.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: msg.SDP})
+
+	case relayMsgCandidate:
+		if msg.Candidate == nil {
+			return nil
+		}
+		// Candidates of the other node's outbound connection belong to our inbound one and the other way around
+		if msg.Outbound {
+			return l.in.AddICECandidate(*msg.Candidate)
+		}
+		return l.out.peerConnection.AddICECandidate(*msg.Candidate)
+
+	case relayMsgEvent:
+		if len(msg.Event) > 0 {
+			l.ch.forwardEvent(msg.Event, l)
+		}
+
+	case relayMsgCommand:
+		if msg.Command != nil {
+			l.ch.runCommand(*msg.Command, l)
+		}
+
+	case relayMsgLeave:
+		l.ch.forgetUser(msg.UserID, l)
+
+	default:
+		return fmt.Errorf("unknown relay message")
+	}
+	return nil
+}
+
+// handleRelayTrack forwards a track relayed by another node to the clients and links of this node.
+// Permissions, mutes and bitrate limits were already enforced on the publisher's node.
+func (a *App) handleRelayTrack(l *relayLink, t *webrtc.TrackRemote) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.log.Error("recovered panic in relay track goroutine", slog.Any("panic", r), slog.Int64("channel", l.ch.id), slog.String("track", t.ID()))
+		}
+	}()
+
+	owner, screen, ok := parseStreamOwner(t.StreamID())
+	if !ok {
+		a.log.Warn("rejecting relayed track: unknown stream", slog.Int64("channel", l.ch.id), slog.String("stream", t.StreamID()))
+		return
+	}
+	track := l.ch.addRelayTrack(l, owner, t)
+	if track == nil {
+		return
+	}
+	l.ch.signalPeerConnections()
+	defer a.sfu.RemoveTrack(l.ch.id, track)
+
+	// The node recording the channel records relayed microphones too
+	var target rtpWriter = track
+	if !screen && t.Kind() == webrtc.RTPCodecTypeAudio {
+		target = a.sfu.RecordingTap(l.ch.id, &peerConnectionState{userID: owner}, track)
+	}
+	a.forwardRTP(l.in, t, target, owner, l.ch.id, 0)
+}
+
+// closeRelay tears the link down once. The tracks relayed over it are removed and
+// the other links forget the users that were connected through it.
+func (a *App) closeRelay(l *relayLink) {
+	l.closeOnce.Do(func() {
+		l.writer.Close()
+		_ = l.conn.Close()
+		_ = l.out.peerConnection.Close()
+		_ = l.in.Close()
+		dropped, empty := l.ch.removeLink(l)
+		for _, userID := range dropped {
+			l.ch.sendLeave(userID, l)
+		}
+		l.ch.signalPeerConnections()
+		if empty {
+			a.sfu.cleanupChannel(l.ch.id, l.ch)
+		}
+	})
+}
+
+// releaseUplink closes the link to the origin node once no clients are left on this node.
+func (a *App) releaseUplink(channelID int64) {
+	a.sfu.mu.RLock()
+	ch, ok := a.sfu.channels[channelID]
+	a.sfu.mu.RUnlock()
+	if !ok || ch.clientCount() > 0 {
+		return
+	}
+	ch.mu.RLock()
+	l := ch.uplink
+	ch.mu.RUnlock()
+	if l != nil {
+		a.closeRelay(l)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/pion/webrtc/v4"
+	"resty.dev/v3"
+)
+
+// newTestRelayChannel returns a stopped channel, so state changes never start a sync pass.
+func newTestRelayChannel(t *testing.T) *channelState {
+	t.Helper()
+	ch := newChannelState(1, resty.New(), "", "", slog.New(slog.NewTextHandler(io.Discard, nil)), 0, false)
+	ch.stop()
+	return ch
+}
+
+func newTestRelayLink(ch *channelState) *relayLink {
+	l := &relayLink{ch: ch, writer: &threadSafeWriter{relay: true}, remote: "test"}
+	l.out = &peerConnectionState{websocket: l.writer, relay: l}
+	return l
+}
+
+func newTestTrack(t *testing.T, id, streamID string) *meteredTrack {
+	t.Helper()
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus}, id, streamID)
+	if err != nil {
+		t.Fatalf("failed to create track: %v", err)
+	}
+	return &meteredTrack{TrackLocalStaticRTP: track}
+}
+
+func TestParseStreamOwner(t *testing.T) {
+	if owner, screen, ok := parseStreamOwner("u:42"); !ok || owner != 42 || screen {
+		t.Fatalf("unexpected owner %d screen %v ok %v", owner, screen, ok)
+	}
+	if owner, screen, ok := parseStreamOwner("u:42:screen"); !ok || owner != 42 || !screen {
+		t.Fatalf("unexpected owner %d screen %v ok %v", owner, screen, ok)
+	}
+	for _, id := range []string{"", "42", "u:", "u:abc", "u:0"} {
+		if _, _, ok := parseStreamOwner(id); ok {
+			t.Fatalf("expected %q to be rejected", id)
+		}
+	}
+	if got := relayURL("wss://sfu.example.com/signal"); got != "wss://sfu.example.com/relay" {
+		t.Fatalf("unexpected relay url %s", got)
+	}
+}
+
+func TestRelayRememberEvent(t *testing.T) {
+	ch := newTestRelayChannel(t)
+	l := newTestRelayLink(ch)
+
+	ch.forwardEvent(json.RawMessage(`{"op":7,"t":516,"d":{"user_id":2,"streaming":true}}`), l)
+	ch.forwardEvent(json.RawMessage(`{"op":7,"t":518,"d":{"user_id":2,"raised":true}}`), l)
+	ch.forwardEvent(json.RawMessage(`{"op":7,"t":517,"d":{"recording":true,"user_id":3}}`), l)
+	ch.forwardEvent(json.RawMessage(`{"op":7,"t":514,"d":{"user_id":2,"speaking":1}}`), l)
+	if u := ch.remoteStates[2]; u == nil || len(u.events) != 2 || u.via != l {
+		t.Fatal("expected the stream and hand state of the remote user to be kept")
+	}
+	if ch.remoteStates[0] == nil || ch.remoteStates[3] != nil {
+		t.Fatal("expected the recording state to be kept for the whole channel")
+	}
+
+	ch.forwardEvent(json.RawMessage(`{"op":7,"t":518,"d":{"user_id":2,"raised":false}}`), l)
+	if u := ch.remoteStates[2]; u == nil || len(u.events) != 1 {
+		t.Fatal("expected a lowered hand to be forgotten")
+	}
+	ch.forgetUser(2, l)
+	if ch.remoteStates[2] != nil {
+		t.Fatal("expected a user that left to be forgotten")
+	}
+}
+
+func TestRelayRunCommand(t *testing.T) {
+	ch := newTestRelayChannel(t)
+	local := &peerConnectionState{userID: 1, websocket: &threadSafeWriter{}}
+	ch.addPeer(local)
+
+	// A single node applies commands even to users it does not know, as before cascading
+	ch.trackLocals["3-audio"] = trackLocalEntry{track: newTestTrack(t, "3-audio", "u:3"), owner: 3}
+	ch.runCommand(relayCommand{Name: relayCmdMute, User: 3, Value: true}, nil)
+	if _, ok := ch.trackLocals["3-audio"]; ok {
+		t.Fatal("expected the command to apply without relay links")
+	}
+
+	l := newTestRelayLink(ch)
+	ch.addLink(l)
+	ch.trackLocals["2-audio"] = trackLocalEntry{track: newTestTrack(t, "2-audio", "u:2"), owner: 2, via: l}
+
+	ch.runCommand(relayCommand{Name: relayCmdMute, User: 2, Value: true}, nil)
+	if _, ok := ch.trackLocals["2-audio"]; !ok {
+		t.Fatal("expected the node of the remote user to apply the mute")
+	}
+	ch.runCommand(relayCommand{Name: relayCmdMute, User: 1, Value: true}, l)
+	if !ch.isServerMuted(local) {
+		t.Fatal("expected a relayed mute to apply to the local user")
+	}
+	ch.runCommand(relayCommand{Name: relayCmdBlock, User: 2, Value: true}, nil)
+	if !ch.isBlocked(2) {
+		t.Fatal("expected the block list to be kept on every node")
+	}
+	if n := ch.clientCount(); n != 1 {
+		t.Fatalf("expected relay links not to count as clients, got %d", n)
+	}
+}
+
+func TestRelayRemoveLink(t *testing.T) {
+	ch := newTestRelayChannel(t)
+	l := newTestRelayLink(ch)
+	other := newTestRelayLink(ch)
+	ch.addLink(l)
+	ch.addLink(other)
+	ch.uplink = l
+	ch.trackLocals["2-audio"] = trackLocalEntry{track: newTestTrack(t, "2-audio", "u:2"), owner: 2, via: l}
+	ch.trackLocals["4-audio"] = trackLocalEntry{track: newTestTrack(t, "4-audio", "u:4"), owner: 4, via: other}
+	ch.rememberEvent(json.RawMessage(`{"op":7,"t":516,"d":{"user_id":2,"streaming":true}}`), l)
+	ch.rememberEvent(json.RawMessage(`{"op":7,"t":516,"d":{"user_id":4,"streaming":true}}`), other)
+
+	dropped, empty := ch.removeLink(l)
+	if empty {
+		t.Fatal("expected the channel to be kept for the other link")
+	}
+	if len(dropped) != 1 || dropped[0] != 2 {
+		t.Fatalf("expected the user of the link to be dropped, got %v", dropped)
+	}
+	if _, ok := ch.trackLocals["2-audio"]; ok {
+		t.Fatal("expected the relayed track of the link to be removed")
+	}
+	if _, ok := ch.trackLocals["4-audio"]; !ok || ch.remoteStates[4] == nil {
+		t.Fatal("expected the other link to be kept")
+	}
+	if ch.uplink != nil || len(ch.links) != 1 || len(ch.peers) != 1 {
+		t.Fatal("expected the link to be unregistered")
+	}
+
+	if _, empty := ch.removeLink(other); !empty {
+		t.Fatal("expected the channel to be empty without links")
+	}
+}
+
+func TestRelaySignalSkipsOwnLink(t *testing.T) {
+	ch := newTestRelayChannel(t)
+	l := newTestRelayLink(ch)
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatalf("failed to create peer connection: %v", err)
+	}
+	defer func() { _ = pc.Close() }()
+	l.out.peerConnection = pc
+	ch.addLink(l)
+	ch.trackLocals["2-audio"] = trackLocalEntry{track: newTestTrack(t, "2-audio", "u:2"), owner: 2, via: l}
+
+	// Nothing to send back over the link, the sync pass does not offer
+	ch.doSignalPeerConnections()
+	if n := len(pc.GetTransceivers()); n != 0 {
+		t.Fatalf("expected the relayed track not to be sent back, got %d transceivers", n)
+	}
+
+	ch.trackLocals["1-audio"] = trackLocalEntry{track: newTestTrack(t, "1-audio", "u:1"), owner: 1}
+	ch.doSignalPeerConnections()
+	senders := pc.GetSenders()
+	if len(senders) != 1 || senders[0].Track().ID() != "1-audio" {
+		t.Fatal("expected only the local track to be sent over the link")
+	}
+}
+
+func TestRelayStopDialing(t *testing.T) {
+	ch := newChannelState(1, resty.New(), "", "", slog.New(slog.NewTextHandler(io.Discard, nil)), 0, false)
+	defer ch.stop()
+	client := &peerConnectionState{userID: 2}
+	ch.peers = append(ch.peers, client, newTestRelayLink(ch).out)
+	ch.dialing = true
+
+	if ch.stopDialing() || !ch.dialing {
+		t.Fatal("expected the uplink to be kept while a client is connected")
+	}
+	ch.peers = ch.peers[1:]
+	if !ch.stopDialing() || ch.dialing {
+		t.Fatal("expected dialing to stop once only relay peers are left")
+	}
+}
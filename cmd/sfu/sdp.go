@@ -129,3 +129,44 @@ func limitAudioBitrateInSDP(sdpIn string, maxBps uint64) string {
 	}
 	return sdpIn
 }
+
+// enableSimulcastInSDP asks the client to publish simulcast on the video media section
+// with the given mid by adding recv RIDs and the simulcast attribute. Sections that already
+// declare RIDs are left alone. If parsing fails, returns the original SDP.
+func enableSimulcastInSDP(sdpIn string, mid string, rids []string) string {
+	if mid == "" || len(rids) == 0 {
+		return sdpIn
+	}
+
+	var desc sdp.SessionDescription
+	if err := desc.UnmarshalString(sdpIn); err != nil {
+		return sdpIn
+	}
+
+	changed := false
+	for _, md := range desc.MediaDescriptions {
+		if md == nil || !strings.EqualFold(md.MediaName.Media, "video") {
+			continue
+		}
+		if v, ok := md.Attribute(sdp.AttrKeyMID); !ok || v != mid {
+			continue
+		}
+		if _, ok := md.Attribute("rid"); ok {
+			break
+		}
+		for _, rid := range rids {
+			md.Attributes = append(md.Attributes, sdp.NewAttribute("rid", rid+" recv"))
+		}
+		md.Attributes = append(md.Attributes, sdp.NewAttribute("simulcast", "recv "+strings.Join(rids, ";")))
+		changed = true
+		break
+	}
+	if !changed {
+		return sdpIn
+	}
+
+	if out, err := desc.Marshal(); err == nil {
+		return string(out)
+	}
+	return sdpIn
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -8,7 +9,9 @@ import (
 	"time"
 
 	"github.com/fasthttp/websocket"
+	"github.com/pion/interceptor/pkg/cc"
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v4"
 	"resty.dev/v3"
 
@@ -24,6 +27,8 @@ type threadSafeWriter struct {
 	conn   *websocket.Conn
 	mu     sync.Mutex
 	closed atomic.Bool
+	// Relay writers wrap envelopes and offers into relay messages for another SFU node
+	relay bool
 }
 
 func (t *threadSafeWriter) WriteJSON(v any) error {
@@ -39,10 +44,28 @@ func (t *threadSafeWriter) WriteJSON(v any) error {
 }
 
 func (t *threadSafeWriter) SendEnvelope(env OutEnvelope) error {
+	if t.relay {
+		raw, err := json.Marshal(env)
+		if err != nil {
+			return err
+		}
+		return t.SendEvent(raw)
+	}
 	return t.WriteJSON(env)
 }
 
+// SendEvent writes an already encoded event envelope, relayed from another SFU node.
+func (t *threadSafeWriter) SendEvent(raw json.RawMessage) error {
+	if t.relay {
+		return t.WriteJSON(relayMessage{Type: relayMsgEvent, Event: raw})
+	}
+	return t.WriteJSON(raw)
+}
+
 func (t *threadSafeWriter) SendRTCOffer(desc webrtc.SessionDescription) error {
+	if t.relay {
+		return t.WriteJSON(relayMessage{Type: relayMsgOffer, SDP: desc.SDP})
+	}
 	payload := rtcOffer{SDP: desc.SDP, Type: desc.Type.String()}
 	env := OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCOffer), D: payload}
 	return t.SendEnvelope(env)
@@ -71,18 +94,92 @@ type peerConnectionState struct {
 	peerConnection *webrtc.PeerConnection
 	websocket      *threadSafeWriter
 	userID         int64
-	perms          int64 // voice permission bitmask from JWT
-	serverMuted    bool  // server-wide mute (admin action)
-	serverDeafened bool  // server-wide deafen (admin action)
+	guildID        *int64    // nil for DM and group calls
+	perms          int64     // voice permission bitmask from JWT
+	serverMuted    bool      // server-wide mute (admin action)
+	serverDeafened bool      // server-wide deafen (admin action)
+	timeoutUntil   time.Time // member timeout end, the user stays server-muted until then
+	timeoutMuted   bool      // the server mute was applied by the timeout, not by a moderator
+	// Set for the outbound peer connection of a relay link to another SFU node, nil for clients
+	relay *relayLink
+
+	// Video transceiver the client publishes its camera on, offered simulcast RIDs
+	videoTransceiver *webrtc.RTPTransceiver
+	// Preferred simulcast quality per publisher, 0 holds the default for all publishers
+	videoQuality map[int64]videoQuality
+
+	// Screen share transceivers, tracks received on them are published under the screen stream ID
+	screenVideoTransceiver *webrtc.RTPTransceiver
+	screenAudioTransceiver *webrtc.RTPTransceiver
+	// Live screen share video tracks, the user is streaming while above zero
+	screenTracks atomic.Int32
+
+	// Stage state, listeners need to be approved as speakers to be heard
+	stageSpeaker atomic.Bool
+	handRaised   atomic.Bool
+
+	// Bandwidth feedback of the peer as a subscriber
+	bwe      cc.BandwidthEstimator // TWCC based estimate, used once TWCC feedback arrives
+	twccSeen atomic.Bool
+	rembBps  atomic.Uint64
+
+	// Layer selection state, only touched by the selection pass
+	lastEstimate uint64
+	nextProbe    time.Time
+	probeBackoff time.Duration
+}
+
+// estimatedBitrate returns the bandwidth estimate of the peer as a subscriber in bps, 0 if unknown.
+// The lower of the TWCC and REMB estimates is used when both are present.
+func (p *peerConnectionState) estimatedBitrate() uint64 {
+	var est uint64
+	if p.bwe != nil && p.twccSeen.Load() {
+		est = uint64(max(p.bwe.GetTargetBitrate(), 0))
+	}
+	if remb := p.rembBps.Load(); remb > 0 && (est == 0 || remb < est) {
+		est = remb
+	}
+	return est
+}
+
+// preferredQuality returns the quality the peer wants for the publisher, caller must hold the channel lock.
+func (p *peerConnectionState) preferredQuality(publisher int64) videoQuality {
+	if q, ok := p.videoQuality[publisher]; ok {
+		return q
+	}
+	if q, ok := p.videoQuality[0]; ok {
+		return q
+	}
+	return videoQualityAuto
+}
+
+// isScreenReceiver reports if the receiver belongs to one of the screen share transceivers.
+func (p *peerConnectionState) isScreenReceiver(r *webrtc.RTPReceiver) bool {
+	for _, tr := range []*webrtc.RTPTransceiver{p.screenVideoTransceiver, p.screenAudioTransceiver} {
+		if tr != nil && r != nil && tr.Receiver() == r {
+			return true
+		}
+	}
+	return false
 }
 
 // ---------------------------------------------------------------------------
 // trackLocalEntry associates a local track with its owner.
 // ---------------------------------------------------------------------------
 
+// screenStreamSuffix is appended to the stream ID of screen share tracks.
+const screenStreamSuffix = ":screen"
+
 type trackLocalEntry struct {
-	track *webrtc.TrackLocalStaticRTP
+	track *meteredTrack
 	owner int64
+	// Relay link the track came in on, nil for tracks published on this node
+	via *relayLink
+}
+
+// rtpWriter receives the packets of an inbound track: a regular local track or a simulcast layer.
+type rtpWriter interface {
+	WriteRTP(p *rtp.Packet) error
 }
 
 // ---------------------------------------------------------------------------
@@ -97,6 +194,8 @@ type channelState struct {
 	mu          sync.RWMutex
 	peers       []*peerConnectionState
 	trackLocals map[string]trackLocalEntry
+	// Simulcast video tracks, every subscriber has its own local track for them
+	simulcast map[string]*simulcastSource
 
 	ttlTicker   *time.Ticker
 	ttlStopChan chan struct{}
@@ -110,11 +209,46 @@ type channelState struct {
 	// Per-channel blocked users set
 	blockedUsers map[int64]bool
 
-	// Configured limits
-	maxAudioBitrateBps uint64
+	// Configured limits, maxAudioBitrateBps is the global limit lowered by the channel bitrate
+	baseAudioBitrateBps uint64
+	maxAudioBitrateBps  uint64
+	// Offer simulcast RIDs to publishers
+	simulcastEnabled bool
+
+	// Active recording of the channel, nil when not recording
+	recording atomic.Pointer[recording]
+	// Only approved speakers are heard while the channel is a stage
+	stage atomic.Bool
+
+	// Relay links to the other SFU nodes of a cascaded channel, uplink is the one this node dialed.
+	// dialing is set while keepUplink keeps the uplink connected.
+	links   []*relayLink
+	uplink  *relayLink
+	dialing bool
+	// Sticky events of users connected to other nodes by user and event type, replayed to joining peers
+	remoteStates map[int64]*remoteUser
 }
 
-func newChannelState(id int64, httpClient *resty.Client, webhookUrl, webhookToken string, log *slog.Logger, maxAudioBitrateBps uint64) *channelState {
+// channelSettings are the voice channel settings carried by the join token, zero values keep the defaults.
+type channelSettings struct {
+	userLimit   int
+	bitrateKbps int
+	stage       bool
+}
+
+// effectiveAudioBitrate returns the lower of the global limit and the channel bitrate, 0 means no limit.
+func effectiveAudioBitrate(globalBps uint64, channelKbps int) uint64 {
+	if channelKbps <= 0 {
+		return globalBps
+	}
+	channelBps := uint64(channelKbps) * 1000
+	if globalBps == 0 || channelBps < globalBps {
+		return channelBps
+	}
+	return globalBps
+}
+
+func newChannelState(id int64, httpClient *resty.Client, webhookUrl, webhookToken string, log *slog.Logger, maxAudioBitrateBps uint64, simulcastEnabled bool) *channelState {
 	t := time.NewTicker(time.Minute)
 	stop := make(chan struct{})
 	go func(channelId int64, ch chan struct{}) {
@@ -145,15 +279,19 @@ func newChannelState(id int64, httpClient *resty.Client, webhookUrl, webhookToke
 	sigCh := make(chan struct{}, 1)
 	sigStop := make(chan struct{})
 	cs := &channelState{
-		id:                 id,
-		log:                log,
-		trackLocals:        make(map[string]trackLocalEntry),
-		blockedUsers:       make(map[int64]bool),
-		ttlTicker:          t,
-		ttlStopChan:        stop,
-		signalCh:           sigCh,
-		signalStop:         sigStop,
-		maxAudioBitrateBps: maxAudioBitrateBps,
+		id:                  id,
+		log:                 log,
+		trackLocals:         make(map[string]trackLocalEntry),
+		simulcast:           make(map[string]*simulcastSource),
+		blockedUsers:        make(map[int64]bool),
+		remoteStates:        make(map[int64]*remoteUser),
+		ttlTicker:           t,
+		ttlStopChan:         stop,
+		signalCh:            sigCh,
+		signalStop:          sigStop,
+		baseAudioBitrateBps: maxAudioBitrateBps,
+		maxAudioBitrateBps:  maxAudioBitrateBps,
+		simulcastEnabled:    simulcastEnabled,
 	}
 
 	// Dedicated goroutine for debounced signaling.
@@ -196,11 +334,16 @@ func (c *channelState) addPeer(state *peerConnectionState) {
 }
 
 func (c *channelState) removePeer(pc *webrtc.PeerConnection) (removed bool, empty bool) {
-	var removedUser int64
+	var (
+		removedUser int64
+		client      bool
+	)
 	c.mu.Lock()
 	for i := range c.peers {
 		if c.peers[i].peerConnection == pc {
 			removedUser = c.peers[i].userID
+			client = c.peers[i].relay == nil
+			c.removeForwarders(c.peers[i])
 			// Swap with last element and truncate (order doesn't matter)
 			last := len(c.peers) - 1
 			c.peers[i] = c.peers[last]
@@ -210,54 +353,119 @@ func (c *channelState) removePeer(pc *webrtc.PeerConnection) (removed bool, empt
 			break
 		}
 	}
-	if removed && len(c.peers) == 0 && len(c.trackLocals) > 0 {
-		c.trackLocals = make(map[string]trackLocalEntry)
+	if removed && len(c.peers) == 0 {
+		if len(c.trackLocals) > 0 {
+			c.trackLocals = make(map[string]trackLocalEntry)
+		}
+		if len(c.simulcast) > 0 {
+			c.simulcast = make(map[string]*simulcastSource)
+		}
 	}
-	empty = len(c.peers) == 0 && len(c.trackLocals) == 0
+	empty = c.emptyLocked()
 	n := len(c.peers)
+	// The other nodes forget the user's state once the last connection of the user is gone
+	left := removed && client && !c.hasUserLocked(removedUser)
 	c.mu.Unlock()
 	if removed {
 		c.log.Debug("peer removed", slog.Int64("channel", c.id), slog.Int64("user", removedUser), slog.Int("total_peers", n))
 	}
+	if left {
+		c.sendLeave(removedUser, nil)
+	}
 	return removed, empty
 }
 
-func (c *channelState) addTrack(userID int64, t *webrtc.TrackRemote) *webrtc.TrackLocalStaticRTP {
+// addTrack creates the forwarding target of an inbound track. Simulcast layers (tracks with a RID)
+// of the same track are grouped into one simulcastSource, pc is the publisher used for keyframe requests.
+// Screen share tracks are labeled with the "u:<id>:screen" stream ID so clients can render them apart.
+func (c *channelState) addTrack(userID int64, pc *webrtc.PeerConnection, t *webrtc.TrackRemote, screen bool) rtpWriter {
 	// Use streamID to carry the sender's user ID so receivers can map tracks to users.
 	// Keep the original track ID for uniqueness.
 	streamID := fmt.Sprintf("u:%d", userID)
+	if screen {
+		streamID += screenStreamSuffix
+	}
 	// Ensure unique Track ID per user to avoid collisions across peers (e.g. "video")
 	trackID := fmt.Sprintf("%d-%s", userID, t.ID())
+	if t.RID() != "" {
+		return c.addSimulcastLayer(userID, pc, t, trackID, streamID)
+	}
 	trackLocal, err := webrtc.NewTrackLocalStaticRTP(t.Codec().RTPCodecCapability, trackID, streamID)
 	if err != nil {
 		c.log.Warn("failed to create local track", slog.Int64("channel", c.id), slog.Int64("user", userID), slog.String("track", trackID), slog.String("error", err.Error()))
 		return nil
 	}
+	track := &meteredTrack{TrackLocalStaticRTP: trackLocal}
 
 	c.mu.Lock()
-	c.trackLocals[trackID] = trackLocalEntry{track: trackLocal, owner: userID}
+	c.trackLocals[trackID] = trackLocalEntry{track: track, owner: userID}
 	c.mu.Unlock()
 	c.log.Debug("track added", slog.Int64("channel", c.id), slog.Int64("user", userID), slog.String("track", trackID), slog.String("kind", t.Kind().String()))
-	return trackLocal
+	return track
 }
 
-func (c *channelState) removeTrack(track *webrtc.TrackLocalStaticRTP) (removed bool, empty bool) {
-	if track == nil {
-		return false, false
+func (c *channelState) addSimulcastLayer(userID int64, pc *webrtc.PeerConnection, t *webrtc.TrackRemote, trackID, streamID string) *simulcastLayer {
+	c.mu.Lock()
+	src, ok := c.simulcast[trackID]
+	if !ok {
+		src = newSimulcastSource(trackID, streamID, userID, t.Codec().RTPCodecCapability, pc)
+		c.simulcast[trackID] = src
 	}
+	c.mu.Unlock()
+	layer := src.addLayer(t.RID(), uint32(t.SSRC()))
+	c.log.Debug("simulcast layer added", slog.Int64("channel", c.id), slog.Int64("user", userID), slog.String("track", trackID), slog.String("rid", t.RID()))
+	return layer
+}
+
+// removeTrack removes a regular track, or a simulcast layer together with its source once no layers are left.
+func (c *channelState) removeTrack(track rtpWriter) (removed bool, empty bool) {
+	var id string
 	c.mu.Lock()
-	if _, ok := c.trackLocals[track.ID()]; ok {
-		delete(c.trackLocals, track.ID())
-		removed = true
+	switch t := track.(type) {
+	case *meteredTrack:
+		id = t.ID()
+		if entry, ok := c.trackLocals[id]; ok && entry.track == t {
+			delete(c.trackLocals, id)
+			removed = true
+		}
+	case *simulcastLayer:
+		id = t.source.id
+		if _, last := t.source.removeLayer(t); last && c.simulcast[id] == t.source {
+			delete(c.simulcast, id)
+			removed = true
+		}
 	}
-	empty = len(c.peers) == 0 && len(c.trackLocals) == 0
+	empty = c.emptyLocked()
 	c.mu.Unlock()
 	if removed {
-		c.log.Debug("track removed", slog.Int64("channel", c.id), slog.String("track", track.ID()))
+		c.log.Debug("track removed", slog.Int64("channel", c.id), slog.String("track", id))
 	}
 	return removed, empty
 }
 
+// trackOwner returns the publisher of a regular or simulcast track, caller must hold the lock.
+func (c *channelState) trackOwner(trackID string) (int64, bool) {
+	if entry, ok := c.trackLocals[trackID]; ok {
+		return entry.owner, true
+	}
+	if src, ok := c.simulcast[trackID]; ok {
+		return src.owner, true
+	}
+	return 0, false
+}
+
+// removeForwarders drops the simulcast forwarders of a subscriber, caller must hold the lock.
+func (c *channelState) removeForwarders(p *peerConnectionState) {
+	for _, src := range c.simulcast {
+		src.removeForwarder(p)
+	}
+}
+
+// emptyLocked reports if the channel has no peers and tracks, caller must hold the lock.
+func (c *channelState) emptyLocked() bool {
+	return len(c.peers) == 0 && len(c.trackLocals) == 0 && len(c.simulcast) == 0
+}
+
 // signalPeerConnections enqueues a signal request to the dedicated goroutine.
 // Non-blocking: if a signal is already pending it is coalesced.
 func (c *channelState) signalPeerConnections() {
@@ -289,6 +497,8 @@ func (c *channelState) doSignalPeerConnections() {
 		if st != webrtc.PeerConnectionStateClosed && st != webrtc.PeerConnectionStateFailed {
 			c.peers[n] = p
 			n++
+		} else {
+			c.removeForwarders(p)
 		}
 	}
 	// Nil out removed tail entries to help GC.
@@ -303,7 +513,7 @@ func (c *channelState) doSignalPeerConnections() {
 		offer webrtc.SessionDescription
 	}
 	work := make([]peerWork, 0, len(c.peers))
-	c.log.Debug("signaling peers", slog.Int64("channel", c.id), slog.Int("peers", len(c.peers)), slog.Int("tracks", len(c.trackLocals)))
+	c.log.Debug("signaling peers", slog.Int64("channel", c.id), slog.Int("peers", len(c.peers)), slog.Int("tracks", len(c.trackLocals)), slog.Int("simulcast", len(c.simulcast)))
 
 	for _, state := range c.peers {
 		if state.peerConnection.SignalingState() != webrtc.SignalingStateStable {
@@ -317,13 +527,24 @@ func (c *channelState) doSignalPeerConnections() {
 				continue
 			}
 			trackID := sender.Track().ID()
-			entry, exists := c.trackLocals[trackID]
+			owner, exists := c.trackOwner(trackID)
+			src, simulcast := c.simulcast[trackID]
+			// A simulcast sender must carry the subscriber's own forwarder track,
+			// it goes stale when the source is published again.
+			if simulcast {
+				if f := src.forwarder(state); f == nil || sender.Track() != f.track {
+					exists = false
+				}
+			}
 			// Remove if: track no longer exists, belongs to the same user,
 			// or the receiver is server-deafened (should receive nothing).
-			if !exists || entry.owner == state.userID || state.serverDeafened {
+			if !exists || owner == state.userID || state.serverDeafened {
 				if err := state.peerConnection.RemoveTrack(sender); err != nil {
 					c.log.Warn("failed to remove sender", slog.Int64("channel", c.id), slog.String("error", err.Error()))
 				}
+				if simulcast && exists {
+					src.removeForwarder(state)
+				}
 				continue
 			}
 			existingSenders[trackID] = true
@@ -332,30 +553,63 @@ func (c *channelState) doSignalPeerConnections() {
 		// Add missing tracks for other users (skip if receiver is deafened)
 		if !state.serverDeafened {
 			for id, entry := range c.trackLocals {
-				if entry.owner == state.userID {
+				// Relayed tracks are not sent back over the link they came in on
+				if entry.owner == state.userID || (state.relay != nil && entry.via == state.relay) {
 					continue
 				}
 				if existingSenders[id] {
 					continue
 				}
-				if _, err := state.peerConnection.AddTrack(entry.track); err != nil {
+				sender, err := state.peerConnection.AddTrack(entry.track.TrackLocalStaticRTP)
+				if err != nil {
 					c.log.Warn("failed to add track to peer", slog.Int64("channel", c.id), slog.String("error", err.Error()))
+					continue
+				}
+				go c.readSenderRTCP(state, sender, nil)
+			}
+			for id, src := range c.simulcast {
+				if src.owner == state.userID {
+					continue
+				}
+				if existingSenders[id] {
+					continue
+				}
+				f, err := src.addForwarder(state)
+				if err != nil {
+					c.log.Warn("failed to create simulcast forwarder", slog.Int64("channel", c.id), slog.String("track", id), slog.String("error", err.Error()))
+					continue
+				}
+				sender, err := state.peerConnection.AddTrack(f.track)
+				if err != nil {
+					src.removeForwarder(state)
+					c.log.Warn("failed to add track to peer", slog.Int64("channel", c.id), slog.String("error", err.Error()))
+					continue
 				}
+				go c.readSenderRTCP(state, sender, f)
 			}
 		}
 
+		// A relay link has nothing to negotiate until there are tracks to send
+		if state.relay != nil && len(state.peerConnection.GetTransceivers()) == 0 {
+			continue
+		}
+
 		offer, err := state.peerConnection.CreateOffer(nil)
 		if err != nil {
 			c.log.Warn("failed to create offer", slog.Int64("channel", c.id), slog.String("error", err.Error()))
 			continue
 		}
-		if c.maxAudioBitrateBps > 0 {
-			offer.SDP = limitAudioBitrateInSDP(offer.SDP, c.maxAudioBitrateBps)
-		}
 		if err = state.peerConnection.SetLocalDescription(offer); err != nil {
 			c.log.Warn("failed to set local description", slog.Int64("channel", c.id), slog.String("error", err.Error()))
 			continue
 		}
+		// SDP changes only go to the client, pion rejects a local offer that differs from the one it created
+		if c.maxAudioBitrateBps > 0 {
+			offer.SDP = limitAudioBitrateInSDP(offer.SDP, c.maxAudioBitrateBps)
+		}
+		if c.simulcastEnabled && state.videoTransceiver != nil {
+			offer.SDP = enableSimulcastInSDP(offer.SDP, state.videoTransceiver.Mid(), simulcastRIDs)
+		}
 
 		work = append(work, peerWork{state: state, offer: offer})
 	}
@@ -369,6 +623,7 @@ func (c *channelState) doSignalPeerConnections() {
 	}
 }
 
+// dispatchKeyFrame requests a keyframe for every inbound track, including all simulcast layers.
 func (c *channelState) dispatchKeyFrame() {
 	c.mu.RLock()
 	peers := make([]*peerConnectionState, len(c.peers))
@@ -377,20 +632,163 @@ func (c *channelState) dispatchKeyFrame() {
 
 	for _, p := range peers {
 		for _, receiver := range p.peerConnection.GetReceivers() {
-			if receiver.Track() == nil {
+			for _, track := range receiver.Tracks() {
+				_ = p.peerConnection.WriteRTCP([]rtcp.Packet{
+					&rtcp.PictureLossIndication{MediaSSRC: uint32(track.SSRC())},
+				})
+			}
+		}
+	}
+}
+
+// readSenderRTCP reads the feedback of a subscriber for one sender until the sender is stopped.
+// Reading also lets the congestion control interceptor see TWCC feedback.
+// PLI and FIR for a simulcast forwarder are passed to the publisher of the forwarded layer.
+func (c *channelState) readSenderRTCP(p *peerConnectionState, sender *webrtc.RTPSender, f *layerForwarder) {
+	for {
+		pkts, _, err := sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+		for _, pkt := range pkts {
+			switch pkt := pkt.(type) {
+			case *rtcp.ReceiverEstimatedMaximumBitrate:
+				p.rembBps.Store(uint64(pkt.Bitrate))
+			case *rtcp.TransportLayerCC:
+				p.twccSeen.Store(true)
+			case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+				if f != nil {
+					f.requestKeyFrame()
+				}
+			}
+		}
+	}
+}
+
+// selectLayers measures stream bitrates and picks the simulcast layer every subscriber receives.
+// The budget is the subscriber's bandwidth estimate minus the regular tracks it receives.
+// Called by the layer selection loop only.
+func (c *channelState) selectLayers(now time.Time) {
+	type subscriber struct {
+		peer  *peerConnectionState
+		prefs map[*simulcastSource]videoQuality
+	}
+	c.mu.RLock()
+	regular := make([]trackLocalEntry, 0, len(c.trackLocals))
+	for _, entry := range c.trackLocals {
+		regular = append(regular, entry)
+	}
+	sources := make([]*simulcastSource, 0, len(c.simulcast))
+	for _, src := range c.simulcast {
+		sources = append(sources, src)
+	}
+	subs := make([]subscriber, 0, len(c.peers))
+	for _, p := range c.peers {
+		if p.serverDeafened {
+			continue
+		}
+		s := subscriber{peer: p, prefs: make(map[*simulcastSource]videoQuality, len(sources))}
+		for _, src := range sources {
+			s.prefs[src] = p.preferredQuality(src.owner)
+		}
+		subs = append(subs, s)
+	}
+	c.mu.RUnlock()
+
+	for _, entry := range regular {
+		entry.track.meter.sample(now)
+	}
+	if len(sources) == 0 {
+		return
+	}
+	ranked := make(map[*simulcastSource][]*simulcastLayer, len(sources))
+	for _, src := range sources {
+		src.sample(now)
+		ranked[src] = src.rankedLayers()
+	}
+
+	for _, s := range subs {
+		p := s.peer
+		var (
+			forwarders []*layerForwarder
+			layers     [][]*simulcastLayer
+			demands    []layerDemand
+		)
+		for _, src := range sources {
+			f := src.forwarder(p)
+			if f == nil || len(ranked[src]) == 0 {
 				continue
 			}
-			_ = p.peerConnection.WriteRTCP([]rtcp.Packet{
-				&rtcp.PictureLossIndication{MediaSSRC: uint32(receiver.Track().SSRC())},
-			})
+			d := layerDemand{
+				bitrates: make([]uint64, len(ranked[src])),
+				max:      s.prefs[src].maxLayer(len(ranked[src])),
+				current:  -1,
+			}
+			target := f.targetLayer()
+			for i, l := range ranked[src] {
+				d.bitrates[i] = l.meter.bps.Load()
+				if l.rid == target {
+					d.current = i
+				}
+			}
+			forwarders = append(forwarders, f)
+			layers = append(layers, ranked[src])
+			demands = append(demands, d)
+		}
+		if len(demands) == 0 {
+			continue
+		}
+
+		estimate := p.estimatedBitrate()
+		var budget uint64
+		if estimate > 0 {
+			var reserved uint64
+			for _, entry := range regular {
+				if entry.owner != p.userID {
+					reserved += entry.track.meter.bps.Load()
+				}
+			}
+			// Non-zero budget means the estimate is known, even when regular tracks take all of it
+			budget = max(uint64(float64(estimate)*layerBandwidthHeadroom), reserved+1) - reserved
+		}
+		congested := estimate > 0 && p.lastEstimate > 0 && float64(estimate) < float64(p.lastEstimate)*layerCongestionDrop
+		p.lastEstimate = estimate
+		if congested {
+			p.probeBackoff = min(max(p.probeBackoff*2, layerProbeBackoffMin), layerProbeBackoffMax)
+			p.nextProbe = now.Add(p.probeBackoff)
+		}
+
+		plan, probed := planLayers(demands, budget, congested, budget > 0 && !now.Before(p.nextProbe))
+		if probed {
+			p.probeBackoff = max(p.probeBackoff/2, layerProbeBackoffMin)
+			p.nextProbe = now.Add(p.probeBackoff)
+		}
+		for i, f := range forwarders {
+			f.setTarget(layers[i][plan[i]].rid)
 		}
 	}
 }
 
+// setVideoQuality stores the preferred simulcast quality of a subscriber for a publisher.
+// Publisher 0 sets the default and drops the per-publisher preferences.
+func (c *channelState) setVideoQuality(userID, publisher int64, q videoQuality) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.peers {
+		if p.userID != userID {
+			continue
+		}
+		if publisher == 0 || p.videoQuality == nil {
+			p.videoQuality = make(map[int64]videoQuality)
+		}
+		p.videoQuality[publisher] = q
+	}
+}
+
 func (c *channelState) isEmpty() bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return len(c.peers) == 0 && len(c.trackLocals) == 0
+	return c.emptyLocked()
 }
 
 // isBlocked checks if a user is in this channel's block list.
@@ -411,9 +809,20 @@ func (c *channelState) snapshotPeers() []*peerConnectionState {
 }
 
 // broadcastSpeaking relays speaking state to all peers in the channel except the origin.
+// Stage listeners are not announced as speaking, priority speakers are flagged.
 func (c *channelState) broadcastSpeaking(fromUser int64, speaking int) {
 	peers := c.snapshotPeers()
 	payload := speakingEvent{UserId: fromUser, Speaking: speaking}
+	for _, p := range peers {
+		if p.userID != fromUser || speaking == 0 {
+			continue
+		}
+		if !c.canSpeak(p) {
+			return
+		}
+		payload.Priority = hasPerm(p.perms, permissions.PermVoicePrioritySpeaker)
+		break
+	}
 	env := OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCSpeaking), D: payload}
 
 	for _, p := range peers {
@@ -424,6 +833,48 @@ func (c *channelState) broadcastSpeaking(fromUser int64, speaking int) {
 	}
 }
 
+func (c *channelState) broadcastStreamState(userID int64, streaming bool) {
+	peers := c.snapshotPeers()
+	env := OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCStream), D: streamEvent{UserId: userID, Streaming: streaming}}
+	for _, p := range peers {
+		_ = p.websocket.SendEnvelope(env)
+	}
+}
+
+// sendStreamStates tells a joining peer which users in the channel are already streaming.
+func (c *channelState) sendStreamStates(to *peerConnectionState) {
+	for _, p := range c.snapshotPeers() {
+		if p == to || p.screenTracks.Load() <= 0 {
+			continue
+		}
+		_ = to.websocket.SendEnvelope(OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCStream), D: streamEvent{UserId: p.userID, Streaming: true}})
+	}
+}
+
+func (c *channelState) broadcastRecordingState(userID int64, recording bool) {
+	peers := c.snapshotPeers()
+	env := OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCRecording), D: recordingEvent{Recording: recording, UserId: userID}}
+	for _, p := range peers {
+		_ = p.websocket.SendEnvelope(env)
+	}
+}
+
+// sendRecordingState tells a joining peer that the channel is being recorded.
+func (c *channelState) sendRecordingState(to *peerConnectionState) {
+	rec := c.recording.Load()
+	if rec == nil {
+		return
+	}
+	_ = to.websocket.SendEnvelope(OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCRecording), D: recordingEvent{Recording: true, UserId: rec.startedBy}})
+}
+
+// isServerMuted reports whether the peer is muted by a moderator.
+func (c *channelState) isServerMuted(p *peerConnectionState) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return p.serverMuted
+}
+
 func (c *channelState) broadcastMuteState(userID int64, muted bool) {
 	peers := c.snapshotPeers()
 	env := OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCServerMuteUser), D: muteEvent{UserId: userID, Muted: muted}}
@@ -443,9 +894,16 @@ func (c *channelState) broadcastDeafenState(userID int64, deafened bool) {
 // serverMuteUser sets/unsets server-wide mute on a target user.
 // When muted, the user's audio tracks are removed so no one receives them.
 func (c *channelState) serverMuteUser(targetUserID int64, muted bool) {
+	c.setServerMute(targetUserID, muted, false)
+}
+
+// setServerMute applies the mute, byTimeout marks a mute the end of the timeout may lift.
+// A moderator mute takes the mute over, a timeout doesn't take over a moderator mute.
+func (c *channelState) setServerMute(targetUserID int64, muted, byTimeout bool) {
 	c.mu.Lock()
 	for _, p := range c.peers {
 		if p.userID == targetUserID {
+			p.timeoutMuted = muted && byTimeout && (p.timeoutMuted || !p.serverMuted)
 			p.serverMuted = muted
 			break
 		}
@@ -457,6 +915,11 @@ func (c *channelState) serverMuteUser(targetUserID int64, muted bool) {
 				delete(c.trackLocals, id)
 			}
 		}
+		for id, src := range c.simulcast {
+			if src.owner == targetUserID {
+				delete(c.simulcast, id)
+			}
+		}
 	}
 	c.mu.Unlock()
 	c.log.Info("server mute user", slog.Int64("channel", c.id), slog.Int64("user", targetUserID), slog.Bool("muted", muted))
@@ -465,6 +928,49 @@ func (c *channelState) serverMuteUser(targetUserID int64, muted bool) {
 	c.signalPeerConnections()
 }
 
+// timeoutUser server-mutes a timed out user and schedules the unmute for when the timeout ends.
+// Zero until lifts an active timeout mute right away. Mutes applied by moderators are left alone.
+func (c *channelState) timeoutUser(targetUserID int64, until time.Time) {
+	c.mu.Lock()
+	found, hadTimeout := false, false
+	for _, p := range c.peers {
+		if p.userID == targetUserID {
+			found = true
+			hadTimeout = hadTimeout || !p.timeoutUntil.IsZero()
+			p.timeoutUntil = until
+		}
+	}
+	c.mu.Unlock()
+	if !found {
+		return
+	}
+	if until.IsZero() || !until.After(time.Now()) {
+		if hadTimeout {
+			c.endTimeout(targetUserID, until)
+		}
+		return
+	}
+	c.setServerMute(targetUserID, true, true)
+	time.AfterFunc(time.Until(until), func() { c.endTimeout(targetUserID, until) })
+}
+
+// endTimeout lifts the mute of the timeout if the timeout that ends at until is still the active one.
+// A mute a moderator applied before or during the timeout stays.
+func (c *channelState) endTimeout(targetUserID int64, until time.Time) {
+	c.mu.Lock()
+	unmute := false
+	for _, p := range c.peers {
+		if p.userID == targetUserID && p.timeoutUntil.Equal(until) {
+			p.timeoutUntil = time.Time{}
+			unmute = unmute || p.timeoutMuted
+		}
+	}
+	c.mu.Unlock()
+	if unmute && !c.stopped.Load() {
+		c.serverMuteUser(targetUserID, false)
+	}
+}
+
 // serverDeafenUser sets/unsets server-wide deafen on a target user.
 // When deafened, the user receives no audio/video from anyone.
 func (c *channelState) serverDeafenUser(targetUserID int64, deafened bool) {
@@ -507,6 +1013,15 @@ func (c *channelState) kickUser(targetUserID int64) {
 
 // blockUser adds or removes a user from the channel's block list.
 func (c *channelState) blockUser(targetUserID int64, block bool) {
+	c.setBlocked(targetUserID, block)
+	// If blocking, also kick them out
+	if block {
+		c.kickUser(targetUserID)
+	}
+}
+
+// setBlocked updates the block list without kicking, used for users connected to other nodes.
+func (c *channelState) setBlocked(targetUserID int64, block bool) {
 	c.mu.Lock()
 	if block {
 		c.blockedUsers[targetUserID] = true
@@ -514,9 +1029,16 @@ func (c *channelState) blockUser(targetUserID int64, block bool) {
 		delete(c.blockedUsers, targetUserID)
 	}
 	c.mu.Unlock()
-	// If blocking, also kick them out
-	if block {
-		c.kickUser(targetUserID)
+}
+
+// kickAll kicks every client in the channel. Relay links are closed by the other nodes once their clients are gone.
+func (c *channelState) kickAll() {
+	for _, p := range c.snapshotPeers() {
+		if p.relay != nil {
+			continue
+		}
+		_ = p.websocket.SendEnvelope(OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCServerKickUser), D: kickEvent{UserId: p.userID}})
+		_ = p.peerConnection.Close()
 	}
 }
 
@@ -536,12 +1058,19 @@ type SFU struct {
 	maxAudioBitrateBps    uint64
 	enforceAudioBitrate   bool
 	audioBitrateMarginPct int
+	simulcast             bool
+
+	// Channel recording, disabled while recordingStorage is nil
+	recordingStorage    recordingStorage
+	recordingPublicBase string
+	recordingDir        string
+	recordingLimit      time.Duration
 
 	// Graceful shutdown
 	done chan struct{}
 }
 
-func NewSFU(webhookUrl, webhookToken string, log *slog.Logger, maxAudioBitrateBps uint64, enforceAudioBitrate bool, audioBitrateMarginPct int) *SFU {
+func NewSFU(webhookUrl, webhookToken string, log *slog.Logger, maxAudioBitrateBps uint64, enforceAudioBitrate bool, audioBitrateMarginPct int, simulcast bool) *SFU {
 	return &SFU{
 		log:                   log,
 		channels:              make(map[int64]*channelState),
@@ -551,6 +1080,7 @@ func NewSFU(webhookUrl, webhookToken string, log *slog.Logger, maxAudioBitrateBp
 		maxAudioBitrateBps:    maxAudioBitrateBps,
 		enforceAudioBitrate:   enforceAudioBitrate,
 		audioBitrateMarginPct: audioBitrateMarginPct,
+		simulcast:             simulcast,
 		done:                  make(chan struct{}),
 	}
 }
@@ -564,12 +1094,20 @@ func (s *SFU) Close() {
 		close(s.done)
 	}
 
+	var recordings []*recording
 	s.mu.Lock()
 	for id, ch := range s.channels {
+		if rec := ch.recording.Swap(nil); rec != nil {
+			recordings = append(recordings, rec)
+		}
 		ch.stop()
 		delete(s.channels, id)
 	}
 	s.mu.Unlock()
+
+	for _, rec := range recordings {
+		s.finishRecording(rec)
+	}
 }
 
 func (s *SFU) getOrCreateChannel(channelID int64) *channelState {
@@ -585,19 +1123,63 @@ func (s *SFU) getOrCreateChannel(channelID int64) *channelState {
 	s.mu.Lock()
 	ch, ok = s.channels[channelID]
 	if !ok {
-		ch = newChannelState(channelID, s.httpClient, s.webhookUrl, s.webhookToken, s.log, s.maxAudioBitrateBps)
+		ch = newChannelState(channelID, s.httpClient, s.webhookUrl, s.webhookToken, s.log, s.maxAudioBitrateBps, s.simulcast)
 		s.channels[channelID] = ch
 	}
 	s.mu.Unlock()
 	return ch
 }
 
-func (s *SFU) AddPeer(channelID int64, state *peerConnectionState) *channelState {
+// AddPeer registers the peer in the channel. The settings of the latest join apply to the whole channel,
+// every token carries the current channel settings.
+func (s *SFU) AddPeer(channelID int64, state *peerConnectionState, settings channelSettings) *channelState {
 	ch := s.getOrCreateChannel(channelID)
+	ch.applySettings(settings)
 	ch.addPeer(state)
+	ch.sendStreamStates(state)
+	ch.sendRecordingState(state)
+	ch.sendStageState(state)
+	ch.sendRemoteStates(state)
+	if ch.stage.Load() && state.stageSpeaker.Load() {
+		ch.broadcastStageSpeaker(state.userID, true)
+	}
 	return ch
 }
 
+// IsFull reports whether the channel has reached the user limit without the user, 0 means unlimited.
+// Several connections of the same user count once.
+func (s *SFU) IsFull(channelID int64, userID int64, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+	s.mu.RLock()
+	ch, ok := s.channels[channelID]
+	s.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	users := make(map[int64]struct{})
+	for _, p := range ch.snapshotPeers() {
+		if p.relay == nil && p.userID != userID {
+			users[p.userID] = struct{}{}
+		}
+	}
+	return len(users) >= limit
+}
+
+// AudioBitrateLimit returns the audio bitrate limit of the channel in bps, 0 means no limit.
+func (s *SFU) AudioBitrateLimit(channelID int64) uint64 {
+	s.mu.RLock()
+	ch, ok := s.channels[channelID]
+	s.mu.RUnlock()
+	if !ok {
+		return s.maxAudioBitrateBps
+	}
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return ch.maxAudioBitrateBps
+}
+
 func (s *SFU) RemovePeer(channelID int64, pc *webrtc.PeerConnection) {
 	s.mu.RLock()
 	ch, ok := s.channels[channelID]
@@ -616,16 +1198,16 @@ func (s *SFU) RemovePeer(channelID int64, pc *webrtc.PeerConnection) {
 	}
 }
 
-func (s *SFU) AddTrack(channelID int64, userID int64, t *webrtc.TrackRemote) *webrtc.TrackLocalStaticRTP {
+func (s *SFU) AddTrack(channelID int64, userID int64, pc *webrtc.PeerConnection, t *webrtc.TrackRemote, screen bool) rtpWriter {
 	ch := s.getOrCreateChannel(channelID)
-	track := ch.addTrack(userID, t)
+	track := ch.addTrack(userID, pc, t, screen)
 	if track != nil {
 		ch.signalPeerConnections()
 	}
 	return track
 }
 
-func (s *SFU) RemoveTrack(channelID int64, track *webrtc.TrackLocalStaticRTP) {
+func (s *SFU) RemoveTrack(channelID int64, track rtpWriter) {
 	s.mu.RLock()
 	ch, ok := s.channels[channelID]
 	s.mu.RUnlock()
@@ -650,6 +1232,9 @@ func (s *SFU) cleanupChannel(channelID int64, ch *channelState) {
 	if current, ok := s.channels[channelID]; ok && current == ch && ch.isEmpty() {
 		ch.stop()
 		delete(s.channels, channelID)
+		if rec := ch.recording.Swap(nil); rec != nil {
+			go s.finishRecording(rec)
+		}
 	}
 	s.mu.Unlock()
 }
@@ -677,6 +1262,36 @@ func (s *SFU) dispatchKeyFrameAll() {
 	}
 }
 
+// StartStream counts a live screen share video track of the peer.
+// Returns true when the user just went live, the channel peers are notified then.
+func (s *SFU) StartStream(channelID int64, state *peerConnectionState) bool {
+	if state.screenTracks.Add(1) != 1 {
+		return false
+	}
+	s.broadcastStreamState(channelID, state.userID, true)
+	return true
+}
+
+// StopStream releases a screen share video track counted by StartStream.
+// Returns true when the last one ended and the user stopped streaming.
+func (s *SFU) StopStream(channelID int64, state *peerConnectionState) bool {
+	if state.screenTracks.Add(-1) != 0 {
+		return false
+	}
+	s.broadcastStreamState(channelID, state.userID, false)
+	return true
+}
+
+func (s *SFU) broadcastStreamState(channelID int64, userID int64, streaming bool) {
+	s.mu.RLock()
+	ch, ok := s.channels[channelID]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	ch.broadcastStreamState(userID, streaming)
+}
+
 // BroadcastSpeaking relays speaking state to all peers in the channel except the origin.
 func (s *SFU) BroadcastSpeaking(channelID int64, fromUser int64, speaking int) {
 	s.mu.RLock()
@@ -703,6 +1318,40 @@ func (s *SFU) RunKeyFrameTicker() {
 	}
 }
 
+// RunLayerSelection periodically picks the simulcast layers of all subscribers.
+// Stops when the SFU's done channel is closed.
+func (s *SFU) RunLayerSelection() {
+	ticker := time.NewTicker(layerSelectInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.mu.RLock()
+			channels := make([]*channelState, 0, len(s.channels))
+			for _, ch := range s.channels {
+				channels = append(channels, ch)
+			}
+			s.mu.RUnlock()
+			for _, ch := range channels {
+				ch.selectLayers(now)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// SetVideoQuality stores the preferred simulcast quality of a user for a publisher, 0 for all publishers.
+func (s *SFU) SetVideoQuality(channelID int64, userID, publisher int64, q videoQuality) {
+	s.mu.RLock()
+	ch, ok := s.channels[channelID]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	ch.setVideoQuality(userID, publisher, q)
+}
+
 // hasPerm checks if a permission bitmask includes a specific voice permission.
 // PermAdministrator overrides all checks.
 func hasPerm(perms int64, required permissions.RolePermission) bool {
@@ -720,7 +1369,22 @@ func (s *SFU) ServerMuteUser(channelID int64, targetUserID int64, muted bool) {
 	if !ok {
 		return
 	}
-	ch.serverMuteUser(targetUserID, muted)
+	ch.runCommand(relayCommand{Name: relayCmdMute, User: targetUserID, Value: muted}, nil)
+}
+
+// TimeoutUser applies or lifts a member timeout mute on a target user in a channel.
+func (s *SFU) TimeoutUser(channelID int64, targetUserID int64, until time.Time) {
+	s.mu.RLock()
+	ch, ok := s.channels[channelID]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	cmd := relayCommand{Name: relayCmdTimeout, User: targetUserID}
+	if !until.IsZero() {
+		cmd.Until = until.Unix()
+	}
+	ch.runCommand(cmd, nil)
 }
 
 // ServerDeafenUser sets/unsets server-wide deafen on a target user.
@@ -731,7 +1395,7 @@ func (s *SFU) ServerDeafenUser(channelID int64, targetUserID int64, deafened boo
 	if !ok {
 		return
 	}
-	ch.serverDeafenUser(targetUserID, deafened)
+	ch.runCommand(relayCommand{Name: relayCmdDeafen, User: targetUserID, Value: deafened}, nil)
 }
 
 // KickUser closes the peer connection of the target user, removing them from the channel.
@@ -742,7 +1406,7 @@ func (s *SFU) KickUser(channelID int64, targetUserID int64) {
 	if !ok {
 		return
 	}
-	ch.kickUser(targetUserID)
+	ch.runCommand(relayCommand{Name: relayCmdKick, User: targetUserID}, nil)
 }
 
 // BlockUser adds or removes a user from the channel's block list.
@@ -753,11 +1417,12 @@ func (s *SFU) BlockUser(channelID int64, targetUserID int64, block bool) {
 	if !ok {
 		return
 	}
-	ch.blockUser(targetUserID, block)
+	ch.runCommand(relayCommand{Name: relayCmdBlock, User: targetUserID, Value: block}, nil)
 }
 
 // KickAll sends a kick envelope to every peer in the channel and closes their peer connections.
 // Used when the channel's SFU region changes and this instance is the old SFU.
+// The clients of the other nodes of a cascaded channel are kicked as well.
 func (s *SFU) KickAll(channelID int64) {
 	s.mu.RLock()
 	ch, ok := s.channels[channelID]
@@ -765,11 +1430,7 @@ func (s *SFU) KickAll(channelID int64) {
 	if !ok {
 		return
 	}
-	peers := ch.snapshotPeers()
-	for _, p := range peers {
-		_ = p.websocket.SendEnvelope(OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCServerKickUser), D: kickEvent{UserId: p.userID}})
-		_ = p.peerConnection.Close()
-	}
+	ch.runCommand(relayCommand{Name: relayCmdKickAll}, nil)
 }
 
 // IsBlocked checks if a user is blocked from a channel.
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"resty.dev/v3"
+)
+
+func TestSetupTransceiversScreenShare(t *testing.T) {
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatalf("failed to create peer connection: %v", err)
+	}
+	defer func() { _ = pc.Close() }()
+
+	state := &peerConnectionState{peerConnection: pc}
+	if err := (&App{}).setupTransceivers(pc, state); err != nil {
+		t.Fatalf("failed to setup transceivers: %v", err)
+	}
+	if n := len(pc.GetTransceivers()); n != 4 {
+		t.Fatalf("expected 4 transceivers, got %d", n)
+	}
+	if state.screenVideoTransceiver.Kind() != webrtc.RTPCodecTypeVideo || state.screenAudioTransceiver.Kind() != webrtc.RTPCodecTypeAudio {
+		t.Fatal("unexpected screen transceiver kinds")
+	}
+	if state.isScreenReceiver(state.videoTransceiver.Receiver()) {
+		t.Fatal("expected the camera receiver not to be a screen receiver")
+	}
+	if !state.isScreenReceiver(state.screenVideoTransceiver.Receiver()) || !state.isScreenReceiver(state.screenAudioTransceiver.Receiver()) {
+		t.Fatal("expected the screen receivers to be detected")
+	}
+
+	// Forwarded tracks must get their own transceivers instead of taking over the screen ones
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "2-video", "u:2")
+	if err != nil {
+		t.Fatalf("failed to create track: %v", err)
+	}
+	sender, err := pc.AddTrack(track)
+	if err != nil {
+		t.Fatalf("failed to add track: %v", err)
+	}
+	if sender == state.screenVideoTransceiver.Sender() || len(pc.GetTransceivers()) != 5 {
+		t.Fatal("expected the screen transceiver to be kept for the publisher")
+	}
+}
+
+func TestStreamStateCounting(t *testing.T) {
+	s := NewSFU("", "", slog.New(slog.NewTextHandler(io.Discard, nil)), 0, false, 0, false)
+	state := &peerConnectionState{userID: 1}
+
+	if !s.StartStream(1, state) {
+		t.Fatal("expected the first screen track to start the stream")
+	}
+	if s.StartStream(1, state) {
+		t.Fatal("expected a second screen track to keep the stream")
+	}
+	if s.StopStream(1, state) {
+		t.Fatal("expected the stream to continue while a screen track is live")
+	}
+	if !s.StopStream(1, state) {
+		t.Fatal("expected the last screen track to stop the stream")
+	}
+}
+
+func TestTimeoutKeepsModeratorMute(t *testing.T) {
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatalf("failed to create peer connection: %v", err)
+	}
+	defer func() { _ = pc.Close() }()
+	ch := newChannelState(1, resty.New(), "", "", slog.New(slog.NewTextHandler(io.Discard, nil)), 0, false)
+	defer ch.stop()
+	p := &peerConnectionState{peerConnection: pc, websocket: &threadSafeWriter{}, userID: 2}
+	ch.peers = append(ch.peers, p)
+
+	// Muted by a moderator before the timeout
+	ch.serverMuteUser(2, true)
+	until := time.Now().Add(time.Hour)
+	ch.timeoutUser(2, until)
+	ch.endTimeout(2, until)
+	if !ch.isServerMuted(p) {
+		t.Fatal("expected the moderator mute to stay after the timeout")
+	}
+
+	// Muted by a moderator during the timeout
+	ch.serverMuteUser(2, false)
+	until = until.Add(time.Minute)
+	ch.timeoutUser(2, until)
+	ch.serverMuteUser(2, true)
+	ch.endTimeout(2, until)
+	if !ch.isServerMuted(p) {
+		t.Fatal("expected the mute applied during the timeout to stay")
+	}
+
+	ch.serverMuteUser(2, false)
+	until = until.Add(time.Minute)
+	ch.timeoutUser(2, until)
+	ch.endTimeout(2, until)
+	if ch.isServerMuted(p) {
+		t.Fatal("expected the timeout mute to be lifted")
+	}
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v4"
+)
+
+const (
+	// Interval of the layer selection pass
+	layerSelectInterval = time.Second
+	// Min interval between keyframe requests for one layer
+	layerKeyFrameInterval = 500 * time.Millisecond
+	// Timestamp step inserted on a layer switch, one frame at 30 fps on the 90 kHz video clock
+	layerSwitchTimestampGap = 90000 / 30
+	// Share of the estimated bandwidth that layers may take
+	layerBandwidthHeadroom = 0.9
+	// Estimate drop between two passes that is treated as congestion
+	layerCongestionDrop = 0.95
+	// Probing a higher layer without bandwidth for it is retried with an exponential backoff
+	layerProbeBackoffMin = 10 * time.Second
+	layerProbeBackoffMax = 2 * time.Minute
+	// Start value of the TWCC based estimate, before the first feedback arrives
+	initialBitrateEstimate = 1_000_000
+)
+
+// simulcastRIDs are offered to publishers in ascending quality: quarter, half and full resolution.
+var simulcastRIDs = []string{"q", "h", "f"}
+
+// videoQuality is the max simulcast layer a subscriber wants to receive.
+type videoQuality string
+
+const (
+	videoQualityAuto   videoQuality = "auto"
+	videoQualityLow    videoQuality = "low"
+	videoQualityMedium videoQuality = "medium"
+	videoQualityHigh   videoQuality = "high"
+)
+
+// parseVideoQuality returns false for unknown values, empty is auto.
+func parseVideoQuality(s string) (videoQuality, bool) {
+	switch q := videoQuality(strings.ToLower(s)); q {
+	case "", videoQualityAuto:
+		return videoQualityAuto, true
+	case videoQualityLow, videoQualityMedium, videoQualityHigh:
+		return q, true
+	}
+	return "", false
+}
+
+// maxLayer returns the highest layer index allowed by the quality when the source has n layers.
+func (q videoQuality) maxLayer(n int) int {
+	switch q {
+	case videoQualityLow:
+		return 0
+	case videoQualityMedium:
+		return (n - 1) / 2
+	}
+	return n - 1
+}
+
+// ---------------------------------------------------------------------------
+// rateMeter measures the bitrate of a forwarded stream.
+// ---------------------------------------------------------------------------
+
+type rateMeter struct {
+	bytes   atomic.Uint64
+	bps     atomic.Uint64
+	active  atomic.Bool
+	sampled time.Time // only touched by the layer selection pass
+}
+
+func (m *rateMeter) add(n int) {
+	m.bytes.Add(uint64(n))
+}
+
+// sample updates the bitrate from the bytes received since the last sample.
+// The stream is inactive when nothing was received in between.
+func (m *rateMeter) sample(now time.Time) {
+	n := m.bytes.Swap(0)
+	if m.sampled.IsZero() {
+		m.sampled = now
+		return
+	}
+	elapsed := now.Sub(m.sampled).Seconds()
+	m.sampled = now
+	if elapsed <= 0 {
+		return
+	}
+	m.active.Store(n > 0)
+	cur := uint64(float64(n) * 8 / elapsed)
+	if old := m.bps.Load(); old > 0 && n > 0 {
+		cur = (old + cur) / 2
+	}
+	m.bps.Store(cur)
+}
+
+// meteredTrack is a regular forwarded track, shared by all subscribers.
+type meteredTrack struct {
+	*webrtc.TrackLocalStaticRTP
+	meter rateMeter
+}
+
+func (t *meteredTrack) WriteRTP(p *rtp.Packet) error {
+	t.meter.add(p.MarshalSize())
+	return t.TrackLocalStaticRTP.WriteRTP(p)
+}
+
+// ---------------------------------------------------------------------------
+// simulcastSource is a published video track with several RID layers.
+// Every subscriber gets its own local track fed from one of the layers.
+// ---------------------------------------------------------------------------
+
+type simulcastSource struct {
+	id        string
+	streamID  string
+	owner     int64
+	codec     webrtc.RTPCodecCapability
+	publisher *webrtc.PeerConnection
+
+	mu         sync.RWMutex
+	layers     map[string]*simulcastLayer
+	forwarders map[*peerConnectionState]*layerForwarder
+}
+
+func newSimulcastSource(id, streamID string, owner int64, codec webrtc.RTPCodecCapability, publisher *webrtc.PeerConnection) *simulcastSource {
+	return &simulcastSource{
+		id:         id,
+		streamID:   streamID,
+		owner:      owner,
+		codec:      codec,
+		publisher:  publisher,
+		layers:     make(map[string]*simulcastLayer),
+		forwarders: make(map[*peerConnectionState]*layerForwarder),
+	}
+}
+
+// addLayer registers an inbound RID stream. A layer that is published again replaces the old one.
+func (s *simulcastSource) addLayer(rid string, ssrc uint32) *simulcastLayer {
+	l := &simulcastLayer{source: s, rid: rid, ssrc: ssrc}
+	s.mu.Lock()
+	s.layers[rid] = l
+	s.mu.Unlock()
+	return l
+}
+
+// removeLayer returns true when the source has no layers left.
+func (s *simulcastSource) removeLayer(l *simulcastLayer) (removed bool, empty bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.layers[l.rid] == l {
+		delete(s.layers, l.rid)
+		removed = true
+	}
+	return removed, len(s.layers) == 0
+}
+
+func (s *simulcastSource) layer(rid string) *simulcastLayer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.layers[rid]
+}
+
+// rankedLayers returns active layers ordered by bitrate, lowest first.
+func (s *simulcastSource) rankedLayers() []*simulcastLayer {
+	s.mu.RLock()
+	layers := make([]*simulcastLayer, 0, len(s.layers))
+	for _, l := range s.layers {
+		if l.meter.active.Load() {
+			layers = append(layers, l)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(layers, func(i, j int) bool {
+		a, b := layers[i].meter.bps.Load(), layers[j].meter.bps.Load()
+		if a != b {
+			return a < b
+		}
+		return ridRank(layers[i].rid) < ridRank(layers[j].rid)
+	})
+	return layers
+}
+
+// defaultLayer is the layer a new subscriber starts with until the first selection pass
+func (s *simulcastSource) defaultLayer() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	best := ""
+	for rid := range s.layers {
+		if best == "" || ridRank(rid) < ridRank(best) {
+			best = rid
+		}
+	}
+	return best
+}
+
+func (s *simulcastSource) sample(now time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, l := range s.layers {
+		l.meter.sample(now)
+	}
+}
+
+// addForwarder creates the local track of a subscriber.
+func (s *simulcastSource) addForwarder(p *peerConnectionState) (*layerForwarder, error) {
+	track, err := webrtc.NewTrackLocalStaticRTP(s.codec, s.id, s.streamID)
+	if err != nil {
+		return nil, err
+	}
+	f := &layerForwarder{source: s, track: track, target: s.defaultLayer()}
+	s.mu.Lock()
+	s.forwarders[p] = f
+	s.mu.Unlock()
+	if l := s.layer(f.target); l != nil {
+		l.requestKeyFrame()
+	}
+	return f, nil
+}
+
+func (s *simulcastSource) forwarder(p *peerConnectionState) *layerForwarder {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.forwarders[p]
+}
+
+func (s *simulcastSource) removeForwarder(p *peerConnectionState) {
+	s.mu.Lock()
+	delete(s.forwarders, p)
+	s.mu.Unlock()
+}
+
+// forward passes a packet of the layer to every subscriber that receives the layer or waits to switch to it.
+func (s *simulcastSource) forward(l *simulcastLayer, p *rtp.Packet) {
+	keyFrame := isKeyFrameStart(s.codec.MimeType, p.Payload)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, f := range s.forwarders {
+		f.write(l.rid, p, keyFrame)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// simulcastLayer is one inbound RID stream of a simulcast source.
+// ---------------------------------------------------------------------------
+
+type simulcastLayer struct {
+	source *simulcastSource
+	rid    string
+	ssrc   uint32
+	meter  rateMeter
+
+	lastKeyFrameRequest atomic.Int64
+}
+
+func (l *simulcastLayer) WriteRTP(p *rtp.Packet) error {
+	l.meter.add(p.MarshalSize())
+	l.source.forward(l, p)
+	return nil
+}
+
+// requestKeyFrame sends a PLI for the layer to the publisher, at most once per layerKeyFrameInterval.
+func (l *simulcastLayer) requestKeyFrame() {
+	now := time.Now().UnixNano()
+	last := l.lastKeyFrameRequest.Load()
+	if now-last < int64(layerKeyFrameInterval) || !l.lastKeyFrameRequest.CompareAndSwap(last, now) {
+		return
+	}
+	_ = l.source.publisher.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: l.ssrc}})
+}
+
+// ---------------------------------------------------------------------------
+// layerForwarder writes one layer of a source to the local track of a subscriber.
+// Switching to the target layer happens on its next keyframe. Sequence numbers
+// and timestamps are rewritten so the subscriber sees one continuous stream.
+// ---------------------------------------------------------------------------
+
+type layerForwarder struct {
+	source *simulcastSource
+	track  *webrtc.TrackLocalStaticRTP
+
+	mu        sync.Mutex
+	current   string // forwarded layer, empty until the first keyframe
+	target    string
+	started   bool
+	lastSeq   uint16
+	lastTS    uint32
+	seqOffset uint16
+	tsOffset  uint32
+}
+
+func (f *layerForwarder) write(rid string, p *rtp.Packet, keyFrame bool) {
+	f.mu.Lock()
+	if rid != f.current {
+		if rid != f.target || !keyFrame {
+			f.mu.Unlock()
+			return
+		}
+		if f.started {
+			f.seqOffset = p.SequenceNumber - f.lastSeq - 1
+			f.tsOffset = p.Timestamp - f.lastTS - layerSwitchTimestampGap
+		}
+		f.current = rid
+	}
+	out := *p
+	out.SequenceNumber = p.SequenceNumber - f.seqOffset
+	out.Timestamp = p.Timestamp - f.tsOffset
+	if !f.started || int16(out.SequenceNumber-f.lastSeq) > 0 {
+		f.lastSeq = out.SequenceNumber
+		f.lastTS = out.Timestamp
+	}
+	f.started = true
+	f.mu.Unlock()
+
+	_ = f.track.WriteRTP(&out)
+}
+
+// setTarget selects the layer to switch to and requests a keyframe for it.
+func (f *layerForwarder) setTarget(rid string) {
+	f.mu.Lock()
+	changed := f.target != rid
+	f.target = rid
+	switching := f.current != rid
+	f.mu.Unlock()
+	if changed && switching {
+		if l := f.source.layer(rid); l != nil {
+			l.requestKeyFrame()
+		}
+	}
+}
+
+func (f *layerForwarder) targetLayer() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.target
+}
+
+// requestKeyFrame asks for a keyframe of the forwarded layer, used when the subscriber sends a PLI or FIR.
+func (f *layerForwarder) requestKeyFrame() {
+	f.mu.Lock()
+	rid := f.current
+	if rid == "" {
+		rid = f.target
+	}
+	f.mu.Unlock()
+	if l := f.source.layer(rid); l != nil {
+		l.requestKeyFrame()
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Layer selection
+// ---------------------------------------------------------------------------
+
+// layerDemand describes a simulcast source received by a subscriber.
+type layerDemand struct {
+	bitrates []uint64 // bitrate of each layer, lowest first
+	max      int      // highest layer allowed by the subscriber's preferred quality
+	current  int      // current target layer, -1 if none
+}
+
+// planLayers picks a layer for every demand within the budget in bps.
+// Without a bandwidth estimate (budget 0) everyone gets the highest allowed layer.
+// Layers only go down on congestion, so a higher layer that is being probed is not
+// dropped while the estimate is still growing. Upgrades that fit the budget are shared
+// round-robin, lowest layers first. With probe set and no upgrade fitting the budget,
+// the cheapest single step up is taken anyway so the estimate can grow into it.
+// Returns the chosen layers and whether a probe upgrade was made.
+func planLayers(demands []layerDemand, budget uint64, congested, probe bool) ([]int, bool) {
+	plan := make([]int, len(demands))
+	if budget == 0 {
+		for i, d := range demands {
+			plan[i] = d.max
+		}
+		return plan, false
+	}
+
+	var cost uint64
+	for i, d := range demands {
+		plan[i] = min(max(d.current, 0), d.max)
+		cost += d.bitrates[plan[i]]
+	}
+
+	if congested {
+		for cost > budget {
+			worst := -1
+			for i, d := range demands {
+				if plan[i] == 0 {
+					continue
+				}
+				if worst == -1 || d.bitrates[plan[i]] > demands[worst].bitrates[plan[worst]] {
+					worst = i
+				}
+			}
+			if worst == -1 {
+				break
+			}
+			cost -= demands[worst].bitrates[plan[worst]] - demands[worst].bitrates[plan[worst]-1]
+			plan[worst]--
+		}
+		return plan, false
+	}
+
+	upgraded := false
+	for {
+		step := false
+		for i, d := range demands {
+			if plan[i] >= d.max {
+				continue
+			}
+			delta := d.bitrates[plan[i]+1] - d.bitrates[plan[i]]
+			if cost+delta > budget {
+				continue
+			}
+			cost += delta
+			plan[i]++
+			step, upgraded = true, true
+		}
+		if !step {
+			break
+		}
+	}
+	if upgraded || !probe {
+		return plan, false
+	}
+
+	cheapest := -1
+	var cheapestDelta uint64
+	for i, d := range demands {
+		if plan[i] >= d.max {
+			continue
+		}
+		delta := d.bitrates[plan[i]+1] - d.bitrates[plan[i]]
+		if cheapest == -1 || delta < cheapestDelta {
+			cheapest, cheapestDelta = i, delta
+		}
+	}
+	if cheapest == -1 {
+		return plan, false
+	}
+	plan[cheapest]++
+	return plan, true
+}
+
+// isKeyFrameStart reports if the payload is the first packet of a VP8 or VP9 keyframe.
+func isKeyFrameStart(mimeType string, payload []byte) bool {
+	switch {
+	case strings.EqualFold(mimeType, webrtc.MimeTypeVP8):
+		var vp8 codecs.VP8Packet
+		if _, err := vp8.Unmarshal(payload); err != nil {
+			return false
+		}
+		return vp8.S == 1 && vp8.PID == 0 && len(vp8.Payload) > 0 && vp8.Payload[0]&0x01 == 0
+	case strings.EqualFold(mimeType, webrtc.MimeTypeVP9):
+		var vp9 codecs.VP9Packet
+		if _, err := vp9.Unmarshal(payload); err != nil {
+			return false
+		}
+		return vp9.B && !vp9.P && vp9.SID == 0
+	}
+	return false
+}
+
+// ridRank orders layers with the same bitrate by their RID.
+func ridRank(rid string) int {
+	for i, r := range simulcastRIDs {
+		if r == rid {
+			return i
+		}
+	}
+	return len(simulcastRIDs)
+}
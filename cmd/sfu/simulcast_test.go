@@ -0,0 +1,161 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4"
+)
+
+func TestPlanLayers(t *testing.T) {
+	layers := []uint64{150_000, 500_000, 1_500_000}
+	demand := func(current int) layerDemand {
+		return layerDemand{bitrates: layers, max: 2, current: current}
+	}
+
+	// No estimate: highest allowed layer
+	plan, _ := planLayers([]layerDemand{demand(-1), {bitrates: layers, max: 1, current: -1}}, 0, false, false)
+	if !reflect.DeepEqual(plan, []int{2, 1}) {
+		t.Fatalf("unexpected plan without estimate %v", plan)
+	}
+
+	// Upgrades are shared round-robin within the budget
+	plan, probed := planLayers([]layerDemand{demand(0), demand(0)}, 1_200_000, false, false)
+	if !reflect.DeepEqual(plan, []int{1, 1}) || probed {
+		t.Fatalf("unexpected plan %v, probed %v", plan, probed)
+	}
+
+	// Without congestion the current layers are kept even above the budget
+	plan, _ = planLayers([]layerDemand{demand(2)}, 300_000, false, false)
+	if !reflect.DeepEqual(plan, []int{2}) {
+		t.Fatalf("expected layer to be kept, got %v", plan)
+	}
+
+	// Congestion drops the most expensive layers first
+	plan, _ = planLayers([]layerDemand{demand(2), demand(1)}, 1_000_000, true, false)
+	if !reflect.DeepEqual(plan, []int{1, 1}) {
+		t.Fatalf("unexpected plan on congestion %v", plan)
+	}
+	plan, _ = planLayers([]layerDemand{demand(2)}, 1, true, false)
+	if !reflect.DeepEqual(plan, []int{0}) {
+		t.Fatalf("expected lowest layer on heavy congestion, got %v", plan)
+	}
+
+	// Probe takes one step up when nothing fits
+	plan, probed = planLayers([]layerDemand{demand(0)}, 200_000, false, true)
+	if !reflect.DeepEqual(plan, []int{1}) || !probed {
+		t.Fatalf("unexpected probe plan %v, probed %v", plan, probed)
+	}
+
+	// Preferred quality caps the layer
+	plan, _ = planLayers([]layerDemand{{bitrates: layers, max: 0, current: 2}}, 10_000_000, false, true)
+	if !reflect.DeepEqual(plan, []int{0}) {
+		t.Fatalf("expected the preferred quality to cap the layer, got %v", plan)
+	}
+}
+
+func TestVideoQualityMaxLayer(t *testing.T) {
+	cases := []struct {
+		quality string
+		layers  int
+		want    int
+	}{
+		{"", 3, 2},
+		{"HIGH", 3, 2},
+		{"medium", 3, 1},
+		{"medium", 2, 0},
+		{"low", 3, 0},
+	}
+	for _, c := range cases {
+		q, ok := parseVideoQuality(c.quality)
+		if !ok {
+			t.Fatalf("unexpected invalid quality %q", c.quality)
+		}
+		if got := q.maxLayer(c.layers); got != c.want {
+			t.Fatalf("quality %q with %d layers: got %d, want %d", c.quality, c.layers, got, c.want)
+		}
+	}
+	if _, ok := parseVideoQuality("ultra"); ok {
+		t.Fatal("expected unknown quality to be rejected")
+	}
+}
+
+func TestIsKeyFrameStart(t *testing.T) {
+	// VP8 descriptor with S=1 and PID=0, then a payload header with the inverse keyframe bit
+	if !isKeyFrameStart(webrtc.MimeTypeVP8, []byte{0x10, 0x00, 0x00}) {
+		t.Fatal("expected VP8 keyframe start")
+	}
+	if isKeyFrameStart(webrtc.MimeTypeVP8, []byte{0x10, 0x01, 0x00}) {
+		t.Fatal("expected VP8 interframe")
+	}
+	if isKeyFrameStart(webrtc.MimeTypeVP8, []byte{0x00, 0x00, 0x00}) {
+		t.Fatal("expected VP8 continuation packet to be rejected")
+	}
+	// VP9 descriptor with B=1 and P=0
+	if !isKeyFrameStart(webrtc.MimeTypeVP9, []byte{0x08, 0x00}) {
+		t.Fatal("expected VP9 keyframe start")
+	}
+	if isKeyFrameStart(webrtc.MimeTypeVP9, []byte{0x48, 0x00}) {
+		t.Fatal("expected VP9 inter-predicted frame")
+	}
+}
+
+func TestLayerForwarderSwitchesOnKeyFrame(t *testing.T) {
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "1-video", "u:1")
+	if err != nil {
+		t.Fatalf("failed to create track: %v", err)
+	}
+	f := &layerForwarder{source: newSimulcastSource("1-video", "u:1", 1, track.Codec(), nil), track: track, target: "q"}
+	packet := func(seq uint16, ts uint32) *rtp.Packet {
+		return &rtp.Packet{Header: rtp.Header{SequenceNumber: seq, Timestamp: ts}}
+	}
+
+	f.write("q", packet(100, 1000), false)
+	if f.started {
+		t.Fatal("expected forwarding to wait for a keyframe")
+	}
+	f.write("q", packet(101, 1000), true)
+	f.write("q", packet(102, 4000), false)
+	f.write("h", packet(5000, 90000), true)
+	if f.current != "q" || f.lastSeq != 102 || f.lastTS != 4000 {
+		t.Fatalf("unexpected state %q %d %d", f.current, f.lastSeq, f.lastTS)
+	}
+
+	f.target = "h"
+	f.write("h", packet(5001, 93000), false)
+	if f.current != "q" {
+		t.Fatal("expected the switch to wait for a keyframe")
+	}
+	f.write("h", packet(5002, 96000), true)
+	f.write("q", packet(103, 7000), false)
+	if f.current != "h" || f.lastSeq != 103 || f.lastTS != 4000+layerSwitchTimestampGap {
+		t.Fatalf("unexpected state after switch %q %d %d", f.current, f.lastSeq, f.lastTS)
+	}
+	f.write("h", packet(5003, 99000), false)
+	if f.lastSeq != 104 || f.lastTS != 7000+layerSwitchTimestampGap {
+		t.Fatalf("expected continuous rewriting, got %d %d", f.lastSeq, f.lastTS)
+	}
+}
+
+func TestEnableSimulcastInSDP(t *testing.T) {
+	offer := "v=0\r\no=- 1 1 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\n" +
+		"m=video 9 UDP/TLS/RTP/SAVPF 96\r\na=mid:0\r\na=rtpmap:96 VP8/90000\r\na=sendrecv\r\n" +
+		"m=video 9 UDP/TLS/RTP/SAVPF 96\r\na=mid:2\r\na=rtpmap:96 VP8/90000\r\na=sendrecv\r\n"
+
+	out := enableSimulcastInSDP(offer, "0", simulcastRIDs)
+	first, second, _ := strings.Cut(out, "a=mid:2")
+	if !strings.Contains(first, "a=rid:q recv") || !strings.Contains(first, "a=simulcast:recv q;h;f") {
+		t.Fatalf("expected simulcast on the first video section:\n%s", out)
+	}
+	if strings.Contains(second, "a=rid") {
+		t.Fatalf("expected other sections to stay unchanged:\n%s", out)
+	}
+	if again := enableSimulcastInSDP(out, "0", simulcastRIDs); strings.Count(again, "a=simulcast") != 1 {
+		t.Fatalf("expected RIDs to be added once:\n%s", again)
+	}
+	if got := enableSimulcastInSDP(offer, "5", simulcastRIDs); got != offer {
+		t.Fatal("expected unknown mid to keep the SDP")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/pion/rtp"
+
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+// stageGate drops the microphone packets of a peer while the channel is a stage and the peer is not an approved speaker.
+// The track stays negotiated, so approving or revoking a speaker takes effect without renegotiation.
+type stageGate struct {
+	rtpWriter
+	ch    *channelState
+	state *peerConnectionState
+}
+
+func (g *stageGate) WriteRTP(p *rtp.Packet) error {
+	if !g.ch.canSpeak(g.state) {
+		return nil
+	}
+	return g.rtpWriter.WriteRTP(p)
+}
+
+// applySettings applies the voice channel settings from a join token.
+// Hands are lowered when the channel stops being a stage.
+func (c *channelState) applySettings(s channelSettings) {
+	c.mu.Lock()
+	c.maxAudioBitrateBps = effectiveAudioBitrate(c.baseAudioBitrateBps, s.bitrateKbps)
+	if c.stage.Swap(s.stage) && !s.stage {
+		for _, p := range c.peers {
+			p.handRaised.Store(false)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// canSpeak reports whether the peer is heard, everyone is outside of stage mode.
+func (c *channelState) canSpeak(p *peerConnectionState) bool {
+	return !c.stage.Load() || p.stageSpeaker.Load()
+}
+
+func (c *channelState) broadcastRaiseHand(userID int64, raised bool) {
+	peers := c.snapshotPeers()
+	env := OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCRaiseHand), D: raiseHandEvent{UserId: userID, Raised: raised}}
+	for _, p := range peers {
+		_ = p.websocket.SendEnvelope(env)
+	}
+}
+
+func (c *channelState) broadcastStageSpeaker(userID int64, speaker bool) {
+	peers := c.snapshotPeers()
+	env := OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCStageSpeaker), D: stageSpeakerEvent{UserId: userID, Speaker: speaker}}
+	for _, p := range peers {
+		_ = p.websocket.SendEnvelope(env)
+	}
+}
+
+// sendStageState tells a joining peer who speaks on the stage and whose hand is raised.
+func (c *channelState) sendStageState(to *peerConnectionState) {
+	if !c.stage.Load() {
+		return
+	}
+	for _, p := range c.snapshotPeers() {
+		if p == to {
+			continue
+		}
+		if p.stageSpeaker.Load() {
+			_ = to.websocket.SendEnvelope(OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCStageSpeaker), D: stageSpeakerEvent{UserId: p.userID, Speaker: true}})
+		} else if p.handRaised.Load() {
+			_ = to.websocket.SendEnvelope(OutEnvelope{OP: int(mqmsg.OPCodeRTC), T: int(mqmsg.EventTypeRTCRaiseHand), D: raiseHandEvent{UserId: p.userID, Raised: true}})
+		}
+	}
+}
+
+// raiseHand raises or lowers the hand of a stage listener. Returns false if nothing changed,
+// speakers and users outside of a stage have no hand to raise.
+func (c *channelState) raiseHand(userID int64, raised bool) bool {
+	if !c.stage.Load() {
+		return false
+	}
+	changed := false
+	for _, p := range c.snapshotPeers() {
+		if p.userID != userID || (raised && p.stageSpeaker.Load()) {
+			continue
+		}
+		if p.handRaised.Swap(raised) != raised {
+			changed = true
+		}
+	}
+	if changed {
+		c.log.Debug("stage hand", slog.Int64("channel", c.id), slog.Int64("user", userID), slog.Bool("raised", raised))
+		c.broadcastRaiseHand(userID, raised)
+	}
+	return changed
+}
+
+// setStageSpeaker approves a user as a stage speaker or moves them back to the audience.
+// An approved speaker's hand is lowered. Returns false if the user is not in the channel.
+func (c *channelState) setStageSpeaker(userID int64, speaker bool) bool {
+	found := false
+	for _, p := range c.snapshotPeers() {
+		if p.userID != userID {
+			continue
+		}
+		found = true
+		p.stageSpeaker.Store(speaker)
+		if speaker {
+			p.handRaised.Store(false)
+		}
+	}
+	if !found {
+		return false
+	}
+	c.log.Info("stage speaker", slog.Int64("channel", c.id), slog.Int64("user", userID), slog.Bool("speaker", speaker))
+	c.broadcastStageSpeaker(userID, speaker)
+	if !speaker && c.stage.Load() {
+		c.broadcastSpeaking(userID, 0)
+	}
+	return true
+}
+
+// StageGate wraps the forwarding target of a microphone track, so stage listeners are not heard.
+func (s *SFU) StageGate(channelID int64, state *peerConnectionState, w rtpWriter) rtpWriter {
+	s.mu.RLock()
+	ch, ok := s.channels[channelID]
+	s.mu.RUnlock()
+	if !ok {
+		return w
+	}
+	return &stageGate{rtpWriter: w, ch: ch, state: state}
+}
+
+// RaiseHand raises or lowers the hand of a user in a stage channel.
+func (s *SFU) RaiseHand(channelID int64, userID int64, raised bool) {
+	s.mu.RLock()
+	ch, ok := s.channels[channelID]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	ch.raiseHand(userID, raised)
+}
+
+// SetStageSpeaker approves or revokes a stage speaker in a channel.
+func (s *SFU) SetStageSpeaker(channelID int64, userID int64, speaker bool) {
+	s.mu.RLock()
+	ch, ok := s.channels[channelID]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	ch.runCommand(relayCommand{Name: relayCmdStageSpeaker, User: userID, Value: speaker}, nil)
+}
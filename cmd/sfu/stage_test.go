@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/pion/rtp"
+
+	"github.com/FlameInTheDark/gochat/internal/permissions"
+)
+
+type countingWriter struct{ n int }
+
+func (w *countingWriter) WriteRTP(*rtp.Packet) error {
+	w.n++
+	return nil
+}
+
+func TestStageGate(t *testing.T) {
+	s := NewSFU("", "", slog.New(slog.NewTextHandler(io.Discard, nil)), 0, false, 0, false)
+	listener := &peerConnectionState{userID: 1, websocket: &threadSafeWriter{}}
+	moderator := &peerConnectionState{userID: 2, websocket: &threadSafeWriter{}, perms: int64(permissions.PermVoiceMuteMembers)}
+	moderator.stageSpeaker.Store(true)
+
+	ch := s.AddPeer(1, listener, channelSettings{stage: true})
+	defer ch.stop()
+	s.AddPeer(1, moderator, channelSettings{stage: true})
+
+	out := &countingWriter{}
+	gate := s.StageGate(1, listener, out)
+	_ = gate.WriteRTP(&rtp.Packet{})
+	if out.n != 0 {
+		t.Fatal("expected the listener to be muted on the stage")
+	}
+
+	if !ch.raiseHand(1, true) || !listener.handRaised.Load() {
+		t.Fatal("expected the listener to raise the hand")
+	}
+	if ch.raiseHand(2, true) {
+		t.Fatal("expected speakers to have no hand to raise")
+	}
+
+	s.SetStageSpeaker(1, 1, true)
+	if listener.handRaised.Load() {
+		t.Fatal("expected the hand to be lowered on approval")
+	}
+	_ = gate.WriteRTP(&rtp.Packet{})
+	if out.n != 1 {
+		t.Fatal("expected an approved speaker to be heard")
+	}
+
+	s.SetStageSpeaker(1, 1, false)
+	_ = gate.WriteRTP(&rtp.Packet{})
+	if out.n != 1 {
+		t.Fatal("expected a revoked speaker to be muted")
+	}
+
+	// Everyone speaks once the channel is no longer a stage
+	ch.applySettings(channelSettings{})
+	_ = gate.WriteRTP(&rtp.Packet{})
+	if out.n != 2 {
+		t.Fatal("expected everyone to be heard outside of stage mode")
+	}
+}
+
+func TestChannelUserLimit(t *testing.T) {
+	s := NewSFU("", "", slog.New(slog.NewTextHandler(io.Discard, nil)), 0, false, 0, false)
+	ch := s.AddPeer(1, &peerConnectionState{userID: 1, websocket: &threadSafeWriter{}}, channelSettings{userLimit: 2})
+	defer ch.stop()
+	// A second connection of the same user counts once
+	s.AddPeer(1, &peerConnectionState{userID: 1, websocket: &threadSafeWriter{}}, channelSettings{userLimit: 2})
+
+	if s.IsFull(1, 2, 2) {
+		t.Fatal("expected a free slot for the second user")
+	}
+	s.AddPeer(1, &peerConnectionState{userID: 2, websocket: &threadSafeWriter{}}, channelSettings{userLimit: 2})
+	if !s.IsFull(1, 3, 2) {
+		t.Fatal("expected the channel to be full")
+	}
+	if s.IsFull(1, 2, 2) {
+		t.Fatal("expected a connected user to be let back in")
+	}
+	if s.IsFull(1, 3, 0) {
+		t.Fatal("expected no limit to never be full")
+	}
+}
+
+func TestChannelAudioBitrate(t *testing.T) {
+	s := NewSFU("", "", slog.New(slog.NewTextHandler(io.Discard, nil)), 128000, false, 0, false)
+	if got := s.AudioBitrateLimit(1); got != 128000 {
+		t.Fatalf("expected the global limit without a channel, got %d", got)
+	}
+	ch := s.AddPeer(1, &peerConnectionState{userID: 1, websocket: &threadSafeWriter{}}, channelSettings{bitrateKbps: 64})
+	defer ch.stop()
+	if got := s.AudioBitrateLimit(1); got != 64000 {
+		t.Fatalf("expected the channel bitrate, got %d", got)
+	}
+	ch.applySettings(channelSettings{bitrateKbps: 256})
+	if got := s.AudioBitrateLimit(1); got != 128000 {
+		t.Fatalf("expected the global limit to cap the channel bitrate, got %d", got)
+	}
+	if got := effectiveAudioBitrate(0, 96); got != 96000 {
+		t.Fatalf("expected the channel bitrate without a global limit, got %d", got)
+	}
+}
@@ -15,6 +15,7 @@ func main() {
 		Commands: []*cli.Command{
 			permissions(),
 			tokens(),
+			search(),
 		},
 	}
 	if err := app.Run(context.Background(), os.Args); err != nil {
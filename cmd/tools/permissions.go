@@ -130,6 +130,10 @@ func allPerms() []permDef {
 		{"PermAdministrator", perm.PermAdministrator},
 		{"PermCreateExpressions", perm.PermCreateExpressions},
 		{"PermManageExpressions", perm.PermManageExpressions},
+		{"PermManageWebhooks", perm.PermManageWebhooks},
+		{"PermVoiceStream", perm.PermVoiceStream},
+		{"PermVoiceRecord", perm.PermVoiceRecord},
+		{"PermVoicePrioritySpeaker", perm.PermVoicePrioritySpeaker},
 	}
 }
 
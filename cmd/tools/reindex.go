@@ -0,0 +1,511 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/FlameInTheDark/gochat/internal/database/db"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/attachment"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/message"
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/database/pgdb"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/channel"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/guildchannels"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/msgsearch"
+)
+
+const reindexChannelPage = 100
+
+func search() *cli.Command {
+	return &cli.Command{
+		Name:  "search",
+		Usage: "Message search index operations",
+		Commands: []*cli.Command{
+			searchReindex(),
+		},
+	}
+}
+
+func searchReindex() *cli.Command {
+	return &cli.Command{
+		Name:  "reindex",
+		Usage: "Rebuild the messages index from ScyllaDB into a new versioned index and switch the alias to it",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "pg-dsn", Usage: "PostgreSQL DSN", Required: true},
+			&cli.StringSliceFlag{Name: "cluster", Usage: "ScyllaDB hosts", Required: true},
+			&cli.StringFlag{Name: "keyspace", Value: "gochat", Usage: "ScyllaDB keyspace"},
+			&cli.StringSliceFlag{Name: "os-address", Usage: "OpenSearch addresses", Required: true},
+			&cli.BoolFlag{Name: "os-insecure", Usage: "skip OpenSearch TLS verification"},
+			&cli.StringFlag{Name: "os-username", Usage: "OpenSearch username"},
+			&cli.StringFlag{Name: "os-password", Usage: "OpenSearch password"},
+			&cli.StringFlag{Name: "index", Usage: "target index name. If empty, the next messages_v<N> version is used."},
+			&cli.StringFlag{Name: "state", Value: "reindex_state.json", Usage: "progress file, the rebuild continues from it when restarted"},
+			&cli.IntFlag{Name: "batch", Value: 500, Usage: "messages per bulk request"},
+			&cli.IntFlag{Name: "rate", Value: 2000, Usage: "max indexed messages per second, 0 for unlimited"},
+			&cli.BoolFlag{Name: "no-switch", Usage: "build the index without switching the alias"},
+			&cli.BoolFlag{Name: "delete-old", Usage: "delete indices the alias pointed to after the switch"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			logger := slog.Default()
+			cql, err := db.NewCQLCon(cmd.String("keyspace"), db.NewDBLogger(logger), cmd.StringSlice("cluster")...)
+			if err != nil {
+				return fmt.Errorf("unable to connect to ScyllaDB: %w", err)
+			}
+			defer cql.Close()
+			pg := pgdb.NewDB(logger)
+			if err := pg.Connect(cmd.String("pg-dsn"), 3); err != nil {
+				return fmt.Errorf("unable to connect to PostgreSQL: %w", err)
+			}
+			defer pg.Close()
+			s, err := msgsearch.NewSearch(cmd.StringSlice("os-address"), cmd.Bool("os-insecure"), cmd.String("os-username"), cmd.String("os-password"))
+			if err != nil {
+				return fmt.Errorf("unable to connect to OpenSearch: %w", err)
+			}
+
+			r := &reindexer{
+				search:    s,
+				msg:       message.New(cql),
+				at:        attachment.New(cql),
+				ch:        channel.New(pg.Conn()),
+				gc:        guildchannels.New(pg.Conn()),
+				statePath: cmd.String("state"),
+				batch:     max(cmd.Int("batch"), 1),
+				throttle:  throttle{rate: cmd.Int("rate")},
+			}
+			return r.run(ctx, cmd.String("index"), !cmd.Bool("no-switch"), cmd.Bool("delete-old"))
+		},
+	}
+}
+
+// reindexState is the progress of the rebuild. Channels are walked in ID order, each from the newest message to the oldest.
+type reindexState struct {
+	Index string `json:"index"`
+	// Messages created after the rebuild started are indexed by the catch-up pass
+	Start int64 `json:"start"`
+	// Last fully indexed channel
+	Channel int64 `json:"channel"`
+	// Channel in progress and the message ID to continue from
+	Current int64 `json:"current,omitempty"`
+	Cursor  int64 `json:"cursor,omitempty"`
+	// All channels were walked, only the catch-up and the switch are left
+	Walked  bool  `json:"walked"`
+	Indexed int64 `json:"indexed"`
+}
+
+type reindexer struct {
+	search    *msgsearch.Search
+	msg       message.Message
+	at        attachment.Attachment
+	ch        channel.Channel
+	gc        guildchannels.GuildChannels
+	statePath string
+	batch     int
+	throttle  throttle
+	state     reindexState
+}
+
+func (r *reindexer) run(ctx context.Context, index string, switchAlias, deleteOld bool) error {
+	if err := r.prepare(ctx, index); err != nil {
+		return err
+	}
+
+	if !r.state.Walked {
+		fmt.Printf("indexing into %s, continuing after channel %d\n", r.state.Index, r.state.Channel)
+		if err := r.walk(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Messages sent during the rebuild went to the old index, index them before and after the switch.
+	// Edits and deletions went there too, they are applied from the change log the same way.
+	catchUp := idgen.FromTime(time.Now())
+	if err := r.catchUp(ctx, r.state.Start); err != nil {
+		return err
+	}
+	if err := r.applyChanges(ctx); err != nil {
+		return err
+	}
+	if !switchAlias {
+		fmt.Printf("index %s is built, %d messages indexed. Run again without --no-switch to switch the alias.\n", r.state.Index, r.state.Indexed)
+		return nil
+	}
+	old, err := r.search.SwitchAlias(ctx, r.state.Index)
+	if err != nil {
+		return fmt.Errorf("unable to switch alias: %w", err)
+	}
+	fmt.Printf("alias %s switched to %s\n", msgsearch.MessagesAlias, r.state.Index)
+	if err := r.catchUp(ctx, catchUp); err != nil {
+		return err
+	}
+	if err := r.applyChanges(ctx); err != nil {
+		return err
+	}
+	// Changes are written through the alias to the new index from now on
+	if err := r.search.DeleteIndices(ctx, []string{msgsearch.ChangesIndexName(r.state.Index)}); err != nil {
+		return fmt.Errorf("unable to delete the change log: %w", err)
+	}
+	if deleteOld {
+		if err := r.search.DeleteIndices(ctx, old); err != nil {
+			return fmt.Errorf("unable to delete old indices: %w", err)
+		}
+		for _, name := range old {
+			fmt.Printf("deleted index %s\n", name)
+		}
+	}
+	if err := os.Remove(r.statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to remove state file: %w", err)
+	}
+	fmt.Printf("done, %d messages indexed\n", r.state.Indexed)
+	return nil
+}
+
+// prepare loads the saved progress or creates the target index for a new rebuild
+func (r *reindexer) prepare(ctx context.Context, index string) error {
+	data, err := os.ReadFile(r.statePath)
+	if err == nil {
+		if err := json.Unmarshal(data, &r.state); err != nil {
+			return fmt.Errorf("unable to read state file: %w", err)
+		}
+		if index != "" && index != r.state.Index {
+			return fmt.Errorf("state file belongs to index %s, remove it to rebuild into %s", r.state.Index, index)
+		}
+		exists, err := r.search.IndexExists(ctx, r.state.Index)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("index %s from the state file does not exist, remove the state file to start over", r.state.Index)
+		}
+		exists, err = r.search.IndexExists(ctx, msgsearch.ChangesIndexName(r.state.Index))
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("change log of index %s does not exist, remove the state file to start over", r.state.Index)
+		}
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to read state file: %w", err)
+	}
+
+	if index == "" {
+		current, _, err := r.search.AliasIndices(ctx)
+		if err != nil {
+			return err
+		}
+		index = msgsearch.NextIndexName(current)
+	}
+	exists, err := r.search.IndexExists(ctx, index)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("index %s already exists", index)
+	}
+	// Only one rebuild can record changes, a leftover log belongs to an abandoned one
+	exists, err = r.search.IndexExists(ctx, msgsearch.ChangesAlias)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("alias %s exists, another rebuild is running or was abandoned. Delete the index it points to to start over", msgsearch.ChangesAlias)
+	}
+	if err := r.search.CreateIndex(ctx, index); err != nil {
+		return fmt.Errorf("unable to create index %s: %w", index, err)
+	}
+	if err := r.search.CreateChangesIndex(ctx, index); err != nil {
+		return fmt.Errorf("unable to create the change log of index %s: %w", index, err)
+	}
+	r.state = reindexState{Index: index, Start: idgen.FromTime(time.Now())}
+	return r.save()
+}
+
+// walk indexes all messages of every channel, saving the progress after each batch
+func (r *reindexer) walk(ctx context.Context) error {
+	for {
+		chs, err := r.ch.GetChannelsAfter(ctx, r.state.Channel, reindexChannelPage)
+		if err != nil {
+			return err
+		}
+		for _, ch := range chs {
+			from := ch.LastMessage
+			if r.state.Current == ch.Id {
+				from = r.state.Cursor
+			}
+			if ch.LastMessage != 0 {
+				if err := r.channel(ctx, ch, from, 0); err != nil {
+					return err
+				}
+			}
+			r.state.Channel = ch.Id
+			r.state.Current, r.state.Cursor = 0, 0
+			if err := r.save(); err != nil {
+				return err
+			}
+		}
+		if len(chs) < reindexChannelPage {
+			break
+		}
+	}
+	r.state.Walked = true
+	return r.save()
+}
+
+// catchUp indexes messages with ID not less than since in all channels
+func (r *reindexer) catchUp(ctx context.Context, since int64) error {
+	var after int64
+	for {
+		chs, err := r.ch.GetChannelsAfter(ctx, after, reindexChannelPage)
+		if err != nil {
+			return err
+		}
+		for _, ch := range chs {
+			after = ch.Id
+			if ch.LastMessage < since {
+				continue
+			}
+			if err := r.channel(ctx, ch, ch.LastMessage, since); err != nil {
+				return err
+			}
+		}
+		if len(chs) < reindexChannelPage {
+			return r.save()
+		}
+	}
+}
+
+// applyChanges re-reads messages edited or deleted during the rebuild from ScyllaDB.
+// Existing ones are indexed again, missing ones are removed from the new index.
+func (r *reindexer) applyChanges(ctx context.Context) error {
+	if err := r.search.RefreshChanges(ctx, r.state.Index); err != nil {
+		return fmt.Errorf("unable to refresh the change log: %w", err)
+	}
+	var after int64
+	var applied int
+	for {
+		changes, err := r.search.Changes(ctx, r.state.Index, after, r.batch)
+		if err != nil {
+			return err
+		}
+		byChannel := make(map[int64][]int64)
+		for _, c := range changes {
+			byChannel[c.ChannelId] = append(byChannel[c.ChannelId], c.MessageId)
+			after = c.MessageId
+		}
+		for channelId, ids := range byChannel {
+			if err := r.applyChannelChanges(ctx, channelId, ids); err != nil {
+				return err
+			}
+		}
+		applied += len(changes)
+		if err := r.throttle.wait(ctx, len(changes)); err != nil {
+			return err
+		}
+		if len(changes) < r.batch {
+			break
+		}
+	}
+	if applied > 0 {
+		fmt.Printf("applied %d edited or deleted messages\n", applied)
+	}
+	return nil
+}
+
+func (r *reindexer) applyChannelChanges(ctx context.Context, channelId int64, ids []int64) error {
+	msgs, err := r.msg.GetChannelMessagesByIDs(ctx, channelId, ids)
+	if err != nil {
+		return err
+	}
+	found := make(map[int64]bool, len(msgs))
+	var attachments []int64
+	for _, m := range msgs {
+		found[m.Id] = true
+		attachments = append(attachments, m.Attachments...)
+	}
+	var deleted []msgsearch.ChangedMessage
+	for _, id := range ids {
+		if !found[id] {
+			deleted = append(deleted, msgsearch.ChangedMessage{ChannelId: channelId, MessageId: id})
+		}
+	}
+	if err := r.search.BulkDelete(ctx, r.state.Index, deleted); err != nil {
+		return err
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	guildId, err := r.guild(ctx, channelId)
+	if err != nil {
+		return err
+	}
+	types, err := r.attachmentTypes(ctx, channelId, attachments)
+	if err != nil {
+		return err
+	}
+	docs := make([]msgsearch.Message, 0, len(msgs))
+	for _, m := range msgs {
+		docs = append(docs, document(guildId, m, types))
+	}
+	return r.search.BulkIndex(ctx, r.state.Index, docs)
+}
+
+// guild returns the guild of the channel, nil for DM and group DM channels
+func (r *reindexer) guild(ctx context.Context, channelId int64) (*int64, error) {
+	gc, err := r.gc.GetGuildByChannel(ctx, channelId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &gc.GuildId, nil
+}
+
+// channel indexes messages of the channel from the message ID down to the stop ID
+func (r *reindexer) channel(ctx context.Context, ch model.Channel, from, stop int64) error {
+	guildId, err := r.guild(ctx, ch.Id)
+	if err != nil {
+		return err
+	}
+
+	cursor := from
+	for cursor >= stop && cursor > ch.Id {
+		msgs, _, err := r.msg.GetMessagesBefore(ctx, ch.Id, cursor, r.batch)
+		if err != nil {
+			return err
+		}
+		docs := make([]msgsearch.Message, 0, len(msgs))
+		var attachments []int64
+		for _, m := range msgs {
+			if m.Id < stop {
+				break
+			}
+			attachments = append(attachments, m.Attachments...)
+		}
+		types, err := r.attachmentTypes(ctx, ch.Id, attachments)
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if m.Id < stop {
+				break
+			}
+			docs = append(docs, document(guildId, m, types))
+		}
+		if err := r.search.BulkIndex(ctx, r.state.Index, docs); err != nil {
+			return err
+		}
+		r.state.Indexed += int64(len(docs))
+		if err := r.throttle.wait(ctx, len(docs)); err != nil {
+			return err
+		}
+		if len(msgs) < r.batch || len(docs) < len(msgs) {
+			return nil
+		}
+
+		cursor = msgs[len(msgs)-1].Id - 1
+		if stop == 0 {
+			r.state.Current, r.state.Cursor = ch.Id, cursor
+			if err := r.save(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// attachmentTypes returns content types of the attachments by ID
+func (r *reindexer) attachmentTypes(ctx context.Context, channelId int64, ids []int64) (map[int64]string, error) {
+	types := make(map[int64]string, len(ids))
+	if len(ids) == 0 {
+		return types, nil
+	}
+	ats, err := r.at.SelectAttachmentsByChannel(ctx, channelId, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range ats {
+		if a.ContentType != nil {
+			types[a.Id] = *a.ContentType
+		}
+	}
+	return types, nil
+}
+
+// document builds the search document the same way the API does when a message is sent.
+// Mentions are taken from the content, because the mentions sent along with the message are not stored.
+func document(guildId *int64, m model.Message, types map[int64]string) msgsearch.Message {
+	var contentTypes []string
+	for _, id := range m.Attachments {
+		if ct, ok := types[id]; ok {
+			contentTypes = append(contentTypes, ct)
+		}
+	}
+	return msgsearch.Message{
+		GuildId:   guildId,
+		ChannelId: m.ChannelId,
+		UserId:    m.UserId,
+		MessageId: m.Id,
+		Has:       msgsearch.HasTypes(m.Content, contentTypes),
+		Mentions:  msgsearch.MentionedUsers(m.Content),
+		Content:   m.Content,
+	}
+}
+
+// save writes the progress to a temporary file and renames it, so an interrupted write does not corrupt the state
+func (r *reindexer) save() error {
+	data, err := json.MarshalIndent(r.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := r.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, r.statePath); err != nil {
+		return fmt.Errorf("unable to write state file: %w", err)
+	}
+	return nil
+}
+
+// throttle limits the indexing rate to spare ScyllaDB and OpenSearch
+type throttle struct {
+	rate int
+	next time.Time
+}
+
+// wait blocks until n more messages fit into the rate
+func (t *throttle) wait(ctx context.Context, n int) error {
+	if t.rate <= 0 {
+		return nil
+	}
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	t.next = t.next.Add(time.Duration(n) * time.Second / time.Duration(t.rate))
+	timer := time.NewTimer(t.next.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -1,26 +1,52 @@
 package main
 
 import (
+	"context"
 	"log/slog"
+	"time"
+
+	nq "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 
 	"github.com/FlameInTheDark/gochat/cmd/webhook/auth"
 	cfgpkg "github.com/FlameInTheDark/gochat/cmd/webhook/config"
 	attentity "github.com/FlameInTheDark/gochat/cmd/webhook/endpoints/attachments"
 	sfuentity "github.com/FlameInTheDark/gochat/cmd/webhook/endpoints/sfu"
+	"github.com/FlameInTheDark/gochat/cmd/webhook/eventhooks"
 	"github.com/FlameInTheDark/gochat/internal/cache/kvs"
 	"github.com/FlameInTheDark/gochat/internal/database/db"
 	"github.com/FlameInTheDark/gochat/internal/database/entities/attachment"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/eventdelivery"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/guildchannelmessages"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/message"
+	"github.com/FlameInTheDark/gochat/internal/database/pgdb"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/channel"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/discriminator"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/eventwebhook"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/user"
+	"github.com/FlameInTheDark/gochat/internal/eventhook"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
 	"github.com/FlameInTheDark/gochat/internal/mq"
+	"github.com/FlameInTheDark/gochat/internal/mq/durable"
 	"github.com/FlameInTheDark/gochat/internal/mq/nats"
 	"github.com/FlameInTheDark/gochat/internal/server"
 	"github.com/FlameInTheDark/gochat/internal/shutter"
 	"github.com/FlameInTheDark/gochat/internal/voice/discovery"
 )
 
+// eventDeliveryDurable is the delivery consumer shared by all webhook replicas
+const eventDeliveryDurable = "event-webhooks"
+
 type App struct {
 	server *server.Server
 	cfg    *cfgpkg.Config
 	log    *slog.Logger
+
+	// Event webhooks, nil when PostgreSQL is not configured
+	nc         *nq.Conn
+	pub        *durable.Publisher
+	dispatcher *eventhooks.Dispatcher
+	consumer   *durable.Consumer
 }
 
 func NewApp(shut *shutter.Shut, logger *slog.Logger) (*App, error) {
@@ -36,8 +62,13 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) (*App, error) {
 		// If discovery is core to this service, fail fast
 		return nil, err
 	}
+	idgen.New(0)
 
-	var att attachment.Attachment
+	var (
+		att        attachment.Attachment
+		deliveries eventdelivery.EventDelivery
+		rec        sfuentity.Recordings
+	)
 	if len(cfg.Cluster) > 0 {
 		cql, err := db.NewCQLCon(cfg.ClusterKeyspace, db.NewDBLogger(logger), cfg.Cluster...)
 		if err != nil {
@@ -45,6 +76,22 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) (*App, error) {
 		}
 		shut.Up(cql)
 		att = attachment.New(cql)
+		deliveries = eventdelivery.New(cql)
+		rec.Att = att
+		rec.Msg = message.New(cql)
+		rec.Gclm = guildchannelmessages.New(cql)
+	}
+
+	var pg *pgdb.DB
+	if cfg.PGDSN != "" {
+		pg = pgdb.NewDB(logger)
+		if err := pg.Connect(cfg.PGDSN, cfg.PGRetries); err != nil {
+			return nil, err
+		}
+		shut.Up(pg)
+		rec.Ch = channel.New(pg.Conn())
+		rec.User = user.New(pg.Conn())
+		rec.Disc = discriminator.New(pg.Conn())
 	}
 
 	var qt mq.SendTransporter
@@ -77,11 +124,72 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) (*App, error) {
 	// Register endpoints under /webhook
 	s.Register(
 		"/api/v1/webhook",
-		sfuentity.New(logger, disco, tokens, cache, qt),
+		sfuentity.New(logger, disco, tokens, cache, qt, rec),
 		attentity.New(logger, att, tokens),
 	)
 
-	return &App{server: s, cfg: cfg, log: logger}, nil
+	app := &App{server: s, cfg: cfg, log: logger}
+	if pg != nil {
+		if err := app.startEventWebhooks(eventwebhook.New(pg.Conn()), deliveries); err != nil {
+			app.stopEventWebhooks()
+			return nil, err
+		}
+	}
+	return app, nil
+}
+
+// startEventWebhooks starts dispatching guild events to the event webhooks and delivering them
+func (a *App) startEventWebhooks(hooks eventwebhook.EventWebhook, deliveries eventdelivery.EventDelivery) error {
+	pub, err := durable.NewPublisher(a.cfg.NatsConnString, eventhook.Stream)
+	if err != nil {
+		return err
+	}
+	a.pub = pub
+	nc, err := nq.Connect(a.cfg.NatsConnString, nq.Compression(true))
+	if err != nil {
+		return err
+	}
+	a.nc = nc
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	// The consumer waits twice the handle timeout for an ack, so a slow endpoint is not sent the same event
+	// again until the running attempt has timed out and asked for a retry
+	timeout := time.Duration(a.cfg.EventWebhookTimeout) * time.Second
+	deliverer := eventhooks.NewDeliverer(a.log, hooks, deliveries, timeout, a.cfg.EventWebhookDisableAfter, a.cfg.EventWebhookAllowPrivate)
+	a.consumer, err = durable.Consume(ctx, js, durable.ConsumerConfig{
+		Stream:        eventhook.Stream.Name,
+		Durable:       eventDeliveryDurable,
+		Handlers:      map[string]durable.Handler{eventhook.DeliverySubject: deliverer.Deliver},
+		Backoff:       eventhook.Backoff,
+		HandleTimeout: timeout + time.Second*5,
+	}, a.log)
+	if err != nil {
+		return err
+	}
+
+	a.dispatcher = eventhooks.NewDispatcher(a.log, pub, hooks, time.Duration(a.cfg.EventWebhookCacheTTL)*time.Second)
+	return a.dispatcher.Start(nc)
+}
+
+func (a *App) stopEventWebhooks() {
+	if a.dispatcher != nil {
+		a.dispatcher.Stop()
+	}
+	if a.consumer != nil {
+		a.consumer.Stop()
+	}
+	if a.nc != nil {
+		a.nc.Close()
+	}
+	if a.pub != nil {
+		_ = a.pub.Close()
+	}
 }
 
 func (a *App) Start() {
@@ -93,4 +201,7 @@ func (a *App) Start() {
 	}()
 }
 
-func (a *App) Close() error { return a.server.Close() }
+func (a *App) Close() error {
+	a.stopEventWebhooks()
+	return a.server.Close()
+}
@@ -26,6 +26,14 @@ type Config struct {
 	KeyDB string `yaml:"keydb" env:"KEYDB" env-default:"127.0.0.1:6379"`
 	// NATS
 	NatsConnString string `yaml:"nats_conn_string" env:"NATS_CONN_STRING" env-default:"nats://nats:4222"`
+	// PostgreSQL for event webhooks, the delivery worker is disabled without it
+	PGDSN     string `yaml:"pg_dsn" env:"PG_DSN"`
+	PGRetries int    `yaml:"pg_retries" env:"PG_RETRIES" env-default:"5"`
+	// Event webhooks delivery
+	EventWebhookTimeout      int  `yaml:"event_webhook_timeout" env:"EVENT_WEBHOOK_TIMEOUT" env-default:"10"`            // Seconds to wait for the endpoint
+	EventWebhookDisableAfter int  `yaml:"event_webhook_disable_after" env:"EVENT_WEBHOOK_DISABLE_AFTER" env-default:"5"` // Failed deliveries in a row that disable the webhook
+	EventWebhookCacheTTL     int  `yaml:"event_webhook_cache_ttl" env:"EVENT_WEBHOOK_CACHE_TTL" env-default:"30"`        // Seconds to cache the webhooks of a guild
+	EventWebhookAllowPrivate bool `yaml:"event_webhook_allow_private" env:"EVENT_WEBHOOK_ALLOW_PRIVATE"`                 // Allow endpoints on private networks, for development
 }
 
 func LoadConfig(logger *slog.Logger) (*Config, error) {
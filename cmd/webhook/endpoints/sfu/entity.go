@@ -5,6 +5,12 @@ import (
 
 	"github.com/FlameInTheDark/gochat/cmd/webhook/auth"
 	"github.com/FlameInTheDark/gochat/internal/cache"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/attachment"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/guildchannelmessages"
+	"github.com/FlameInTheDark/gochat/internal/database/entities/message"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/channel"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/discriminator"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/user"
 	"github.com/FlameInTheDark/gochat/internal/mq"
 	"github.com/FlameInTheDark/gochat/internal/server"
 	"github.com/FlameInTheDark/gochat/internal/voice/discovery"
@@ -20,9 +26,25 @@ type entity struct {
 	tokens *auth.TokenManager
 	cache  cache.Cache
 	mqt    mq.SendTransporter
+	rec    Recordings
 }
 
-func New(log *slog.Logger, disco discovery.Manager, tokens *auth.TokenManager, cache cache.Cache, mqt mq.SendTransporter) server.Entity {
+// Recordings are the stores used to post channel recordings to text channels.
+// Recordings are rejected while any of them is nil.
+type Recordings struct {
+	Att  attachment.Attachment
+	Msg  message.Message
+	Gclm guildchannelmessages.GuildChannelMessages
+	Ch   channel.Channel
+	User user.User
+	Disc discriminator.Discriminator
+}
+
+func (r Recordings) configured() bool {
+	return r.Att != nil && r.Msg != nil && r.Gclm != nil && r.Ch != nil && r.User != nil && r.Disc != nil
+}
+
+func New(log *slog.Logger, disco discovery.Manager, tokens *auth.TokenManager, cache cache.Cache, mqt mq.SendTransporter, rec Recordings) server.Entity {
 	return &entity{
 		name:   entityName,
 		log:    log,
@@ -30,6 +52,7 @@ func New(log *slog.Logger, disco discovery.Manager, tokens *auth.TokenManager, c
 		tokens: tokens,
 		cache:  cache,
 		mqt:    mqt,
+		rec:    rec,
 	}
 }
 
@@ -39,6 +62,8 @@ func (e *entity) Init(router fiber.Router) {
 	router.Post("/heartbeat", e.Heartbeat)
 	router.Post("/voice/join", e.ChannelUserJoin)
 	router.Post("/voice/leave", e.ChannelUserLeave)
+	router.Post("/voice/stream", e.ChannelUserStream)
+	router.Post("/voice/recording", e.ChannelRecording)
 	router.Post("/channel/alive", e.ChannelAlive)
 	// Prometheus HTTP SD endpoint that lists SFU metrics targets discovered via etcd
 	router.Get("/prom_sd", e.PromSD)
@@ -172,6 +172,53 @@ func (e *entity) ChannelUserLeave(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusOK)
 }
 
+// ChannelUserStream
+//
+//	@Summary		SFU voice stream
+//	@Description	Notify guild members that a client started or stopped sharing their screen
+//	@Tags			Webhook
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Webhook-Token	header	string				true	"JWT token"
+//	@Param			request			body	ChannelUserStream	true	"Client stream data"
+//	@Success		200
+//	@Failure		400	{string}	string	"Bad request"
+//	@Failure		401	{string}	string	"Unauthorized"
+//	@Router			/webhook/sfu/voice/stream [post]
+func (e *entity) ChannelUserStream(c *fiber.Ctx) error {
+	var req ChannelUserStream
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if !e.tokens.Validate("sfu", "", c.Get(hdrToken)) {
+		return fiber.ErrUnauthorized
+	}
+	if req.GuildId != nil {
+		go func() {
+			var msg mqmsg.EventDataMessage = &mqmsg.GuildMemberStreamStop{
+				GuildId:   *req.GuildId,
+				UserId:    req.UserId,
+				ChannelId: req.ChannelId,
+			}
+			if req.Streaming {
+				msg = &mqmsg.GuildMemberStreamStart{
+					GuildId:   *req.GuildId,
+					UserId:    req.UserId,
+					ChannelId: req.ChannelId,
+				}
+			}
+			if err := e.mqt.SendGuildUpdate(*req.GuildId, msg); err != nil {
+				slog.Error("unable to send guild update", slog.String("error", err.Error()))
+			}
+		}()
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
 // Heartbeat
 //
 //	@Summary		SFU update channel TTL
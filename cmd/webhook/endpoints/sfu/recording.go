@@ -0,0 +1,97 @@
+package sfu
+
+import (
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+// ChannelRecording
+//
+//	@Summary		SFU voice recording
+//	@Description	Post an uploaded voice channel recording to the text channel as a system message with the recording attachment
+//	@Tags			Webhook
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Webhook-Token	header	string				true	"JWT token"
+//	@Param			request			body	ChannelRecording	true	"Recording data"
+//	@Success		200
+//	@Failure		400	{string}	string	"Bad request"
+//	@Failure		401	{string}	string	"Unauthorized"
+//	@Failure		500	{string}	string	"Unable to post recording"
+//	@Failure		503	{string}	string	"Service unavailable"
+//	@Router			/webhook/sfu/voice/recording [post]
+func (e *entity) ChannelRecording(c *fiber.Ctx) error {
+	var req ChannelRecording
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if !e.tokens.Validate("sfu", "", c.Get(hdrToken)) {
+		return fiber.ErrUnauthorized
+	}
+	if !e.rec.configured() {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "recording stores not configured")
+	}
+
+	ctx := c.UserContext()
+	if err := e.rec.Att.CreateAttachment(ctx, req.AttachmentId, req.TextChannelId, req.UserId, 0, req.Size, req.Name); err != nil {
+		e.log.Error("unable to create recording attachment", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to create attachment")
+	}
+	if err := e.rec.Att.DoneAttachment(ctx, req.AttachmentId, req.TextChannelId, &req.ContentType, &req.URL, nil, nil, nil, &req.Size, &req.Name, &req.UserId); err != nil {
+		e.log.Error("unable to finalize recording attachment", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to create attachment")
+	}
+
+	msgId := idgen.Next()
+	if err := e.rec.Msg.CreateSystemMessageWithAttachments(ctx, msgId, req.TextChannelId, req.UserId, "", []int64{req.AttachmentId}, model.MessageTypeVoiceRecording); err != nil {
+		e.log.Error("unable to create recording message", slog.String("error", err.Error()))
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to create message")
+	}
+	if err := e.rec.Ch.SetLastMessage(ctx, req.TextChannelId, msgId); err != nil {
+		e.log.Error("unable to set last message id", slog.String("error", err.Error()))
+	}
+	if req.GuildId != nil {
+		if err := e.rec.Gclm.SetChannelLastMessage(ctx, *req.GuildId, req.TextChannelId, msgId); err != nil {
+			e.log.Error("unable to set guild channel last message", slog.String("error", err.Error()))
+		}
+	}
+
+	author := dto.User{Id: req.UserId}
+	if u, err := e.rec.User.GetUserById(ctx, req.UserId); err == nil {
+		author.Name = u.Name
+		author.Bot = u.Bot
+	}
+	if d, err := e.rec.Disc.GetDiscriminatorByUserId(ctx, req.UserId); err == nil {
+		author.Discriminator = d.Discriminator
+	}
+	go func() {
+		if err := e.mqt.SendChannelMessage(req.TextChannelId, &mqmsg.CreateMessage{
+			GuildId: req.GuildId,
+			Message: dto.Message{
+				Id:        msgId,
+				ChannelId: req.TextChannelId,
+				Author:    author,
+				Type:      int(model.MessageTypeVoiceRecording),
+				Attachments: []dto.Attachment{{
+					ContentType: &req.ContentType,
+					Filename:    req.Name,
+					URL:         req.URL,
+					Size:        req.Size,
+				}},
+			},
+		}); err != nil {
+			slog.Error("unable to send recording message event", slog.String("error", err.Error()))
+		}
+	}()
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -45,6 +45,46 @@ func (r ChannelUserLeave) Validate() error {
 	)
 }
 
+type ChannelUserStream struct {
+	ChannelId int64  `json:"channel_id"`
+	UserId    int64  `json:"user_id"`
+	GuildId   *int64 `json:"guild_id,omitempty"`
+	Streaming bool   `json:"streaming"`
+}
+
+func (r ChannelUserStream) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ChannelId, validation.Required),
+		validation.Field(&r.UserId, validation.Required),
+	)
+}
+
+type ChannelRecording struct {
+	ChannelId     int64  `json:"channel_id"`
+	GuildId       *int64 `json:"guild_id,omitempty"`
+	UserId        int64  `json:"user_id"`
+	TextChannelId int64  `json:"text_channel_id"`
+	AttachmentId  int64  `json:"attachment_id"`
+	Name          string `json:"name"`
+	URL           string `json:"url"`
+	ContentType   string `json:"content_type"`
+	Size          int64  `json:"size"`
+	DurationMs    int64  `json:"duration_ms"`
+}
+
+func (r ChannelRecording) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ChannelId, validation.Required),
+		validation.Field(&r.UserId, validation.Required),
+		validation.Field(&r.TextChannelId, validation.Required),
+		validation.Field(&r.AttachmentId, validation.Required),
+		validation.Field(&r.Name, validation.Required),
+		validation.Field(&r.URL, validation.Required),
+		validation.Field(&r.ContentType, validation.Required),
+		validation.Field(&r.Size, validation.Min(int64(0))),
+	)
+}
+
 type ChannelAlive struct {
 	ChannelId int64  `json:"channel_id"`
 	GuildId   *int64 `json:"guild_id,omitempty"`
@@ -0,0 +1,195 @@
+package eventhooks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/FlameInTheDark/gochat/internal/database/entities/eventdelivery"
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/eventwebhook"
+	"github.com/FlameInTheDark/gochat/internal/eventhook"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/mq/durable"
+)
+
+const (
+	userAgent        = "gochat-webhook"
+	maxResponseBody  = 64 * 1024
+	maxDeliveryError = 512
+)
+
+var errPrivateAddress = errors.New("endpoint resolves to a private address")
+
+// Deliverer posts delivery jobs to the webhook endpoints and keeps the delivery log
+type Deliverer struct {
+	log          *slog.Logger
+	hooks        eventwebhook.EventWebhook
+	deliveries   eventdelivery.EventDelivery
+	client       *http.Client
+	disableAfter int
+}
+
+// NewDeliverer creates the deliverer. A webhook is disabled after disableAfter deliveries in a row failed all retries.
+// Endpoints on private networks are refused unless allowPrivate is set. The delivery log is skipped when deliveries is nil.
+func NewDeliverer(log *slog.Logger, hooks eventwebhook.EventWebhook, deliveries eventdelivery.EventDelivery, timeout time.Duration, disableAfter int, allowPrivate bool) *Deliverer {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = denyPrivate
+	}
+	return &Deliverer{
+		log:          log,
+		hooks:        hooks,
+		deliveries:   deliveries,
+		disableAfter: disableAfter,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			// A redirect is a failed delivery, following it could reach addresses the URL check did not see
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Deliver is the durable handler of delivery jobs
+func (d *Deliverer) Deliver(ctx context.Context, data []byte) error {
+	var job eventhook.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return durable.Permanent(fmt.Errorf("unable to unmarshal delivery job: %w", err))
+	}
+
+	// The webhook is read on every attempt, so deleted, disabled and changed webhooks apply to pending deliveries
+	hook, err := d.hooks.GetEventWebhook(ctx, job.GuildId, job.WebhookId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !hook.Enabled || !hook.Subscribed(int64(job.EventType)) {
+		return nil
+	}
+
+	attempt, last := durable.Attempt(ctx)
+	started := time.Now()
+	status, err := d.post(ctx, hook, job)
+	d.record(hook, job, int(attempt), status, time.Since(started), err)
+
+	if err == nil {
+		if hook.FailureCount > 0 {
+			if rerr := d.hooks.RecordSuccess(ctx, hook.GuildId, hook.Id); rerr != nil {
+				d.log.Warn("unable to reset webhook failures", slog.Int64("webhook_id", hook.Id), slog.String("error", rerr.Error()))
+			}
+		}
+		return nil
+	}
+
+	if last || durable.IsPermanent(err) {
+		disabled, rerr := d.hooks.RecordFailure(ctx, hook.GuildId, hook.Id, d.disableAfter)
+		if rerr != nil {
+			d.log.Warn("unable to record webhook failure", slog.Int64("webhook_id", hook.Id), slog.String("error", rerr.Error()))
+		} else if disabled {
+			d.log.Info("event webhook disabled after failed deliveries", slog.Int64("webhook_id", hook.Id), slog.Int64("guild_id", hook.GuildId))
+		}
+	}
+	return err
+}
+
+// post sends the signed event and returns the response status. Errors that a retry can not fix are permanent.
+func (d *Deliverer) post(ctx context.Context, hook model.EventWebhook, job eventhook.Job) (int, error) {
+	body, err := json.Marshal(eventhook.Payload{
+		Id:        job.EventId,
+		WebhookId: hook.Id,
+		GuildId:   hook.GuildId,
+		EventType: job.EventType,
+		Data:      job.Data,
+	})
+	if err != nil {
+		return 0, durable.Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, durable.Permanent(err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(eventhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(eventhook.HeaderSignature, eventhook.Sign(hook.Secret, timestamp, body))
+	req.Header.Set(eventhook.HeaderEvent, strconv.Itoa(int(job.EventType)))
+	req.Header.Set(eventhook.HeaderDelivery, strconv.FormatInt(job.EventId, 10))
+
+	resp, err := d.client.Do(req)
+	if errors.Is(err, errPrivateAddress) {
+		return 0, durable.Permanent(err)
+	} else if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp.StatusCode, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	default:
+		return resp.StatusCode, durable.Permanent(fmt.Errorf("endpoint responded with status %d", resp.StatusCode))
+	}
+}
+
+// record adds the attempt to the delivery log
+func (d *Deliverer) record(hook model.EventWebhook, job eventhook.Job, attempt, status int, duration time.Duration, cause error) {
+	if d.deliveries == nil {
+		return
+	}
+	var errText *string
+	if cause != nil {
+		text := cause.Error()
+		if len(text) > maxDeliveryError {
+			text = text[:maxDeliveryError]
+		}
+		errText = &text
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	err := d.deliveries.AddDelivery(ctx, model.EventWebhookDelivery{
+		WebhookId:  hook.Id,
+		Id:         idgen.Next(),
+		EventId:    job.EventId,
+		EventType:  int(job.EventType),
+		Attempt:    attempt,
+		StatusCode: status,
+		Error:      errText,
+		DurationMs: int(duration.Milliseconds()),
+	})
+	if err != nil {
+		d.log.Warn("unable to add delivery log record", slog.Int64("webhook_id", hook.Id), slog.String("error", err.Error()))
+	}
+}
+
+// denyPrivate refuses connections to loopback, private and link-local addresses
+func denyPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return errPrivateAddress
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Package eventhooks delivers guild events published on NATS to the event webhooks registered by guild admins.
+// The dispatcher turns events into delivery jobs on a JetStream stream and the deliverer posts them to the endpoints.
+package eventhooks
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	nq "github.com/nats-io/nats.go"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/eventwebhook"
+	"github.com/FlameInTheDark/gochat/internal/eventhook"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+const (
+	// dispatcherQueue is the queue group shared by all replicas, each event is dispatched once
+	dispatcherQueue = "event-webhooks"
+	guildPrefix     = "guild."
+)
+
+// dispatchSubjects are the NATS subjects guild events are published to
+var dispatchSubjects = []string{"guild.*", "channel.*"}
+
+type publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+type cachedHooks struct {
+	hooks   []model.EventWebhook
+	expires time.Time
+}
+
+// Dispatcher creates a delivery job for every event webhook subscribed to an event
+type Dispatcher struct {
+	log   *slog.Logger
+	pub   publisher
+	hooks eventwebhook.EventWebhook
+	ttl   time.Duration
+
+	mu        sync.Mutex
+	cache     map[int64]cachedHooks
+	nextSweep time.Time
+	subs      []*nq.Subscription
+}
+
+// NewDispatcher creates the dispatcher. Webhooks of a guild are cached for ttl, so changes apply after it passes.
+func NewDispatcher(log *slog.Logger, pub publisher, hooks eventwebhook.EventWebhook, ttl time.Duration) *Dispatcher {
+	return &Dispatcher{
+		log:   log,
+		pub:   pub,
+		hooks: hooks,
+		ttl:   ttl,
+		cache: make(map[int64]cachedHooks),
+	}
+}
+
+// Start subscribes to the guild and channel events
+func (d *Dispatcher) Start(nc *nq.Conn) error {
+	for _, subject := range dispatchSubjects {
+		sub, err := nc.QueueSubscribe(subject, dispatcherQueue, d.onMessage)
+		if err != nil {
+			d.Stop()
+			return err
+		}
+		d.subs = append(d.subs, sub)
+	}
+	return nil
+}
+
+// Stop unsubscribes from the events
+func (d *Dispatcher) Stop() {
+	for _, sub := range d.subs {
+		if err := sub.Unsubscribe(); err != nil {
+			d.log.Warn("unable to unsubscribe", slog.String("subject", sub.Subject), slog.String("error", err.Error()))
+		}
+	}
+	d.subs = nil
+}
+
+func (d *Dispatcher) onMessage(msg *nq.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := d.dispatch(ctx, msg.Subject, msg.Data); err != nil {
+		d.log.Error("unable to dispatch event", slog.String("subject", msg.Subject), slog.String("error", err.Error()))
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, subject string, data []byte) error {
+	var msg mqmsg.Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	if msg.Operation != mqmsg.OpCodeDispatch || msg.EventType == nil || !eventhook.Allowed(*msg.EventType) {
+		return nil
+	}
+
+	// Guild events carry the guild in the subject, channel events in the data. DM events have no guild.
+	var guildId int64
+	if id, ok := strings.CutPrefix(subject, guildPrefix); ok {
+		var err error
+		if guildId, err = strconv.ParseInt(id, 10, 64); err != nil {
+			return nil
+		}
+	} else if id, ok := eventhook.GuildID(msg.Data); ok {
+		guildId = id
+	} else {
+		return nil
+	}
+
+	hooks, err := d.guildHooks(ctx, guildId)
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if !hook.Enabled || !hook.Subscribed(int64(*msg.EventType)) {
+			continue
+		}
+		job, err := json.Marshal(eventhook.Job{
+			GuildId:   guildId,
+			WebhookId: hook.Id,
+			EventId:   idgen.Next(),
+			EventType: *msg.EventType,
+			Data:      msg.Data,
+		})
+		if err != nil {
+			return err
+		}
+		if err := d.pub.Publish(eventhook.DeliverySubject, job); err != nil {
+			d.log.Error("unable to publish delivery job",
+				slog.Int64("webhook_id", hook.Id),
+				slog.String("error", err.Error()))
+		}
+	}
+	return nil
+}
+
+// guildHooks returns the cached webhooks of the guild, most guilds have none
+func (d *Dispatcher) guildHooks(ctx context.Context, guildId int64) ([]model.EventWebhook, error) {
+	now := time.Now()
+	d.mu.Lock()
+	cached, ok := d.cache[guildId]
+	d.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.hooks, nil
+	}
+
+	hooks, err := d.hooks.GetGuildEventWebhooks(ctx, guildId)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	if now.After(d.nextSweep) {
+		for id, c := range d.cache {
+			if now.After(c.expires) {
+				delete(d.cache, id)
+			}
+		}
+		d.nextSweep = now.Add(d.ttl)
+	}
+	d.cache[guildId] = cachedHooks{hooks: hooks, expires: now.Add(d.ttl)}
+	d.mu.Unlock()
+	return hooks, nil
+}
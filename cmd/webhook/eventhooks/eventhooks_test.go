@@ -0,0 +1,238 @@
+package eventhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/eventhook"
+	"github.com/FlameInTheDark/gochat/internal/mq/durable"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+type fakeHooks struct {
+	hooks    map[int64]model.EventWebhook
+	failures int
+}
+
+func (f *fakeHooks) CreateEventWebhook(ctx context.Context, webhook model.EventWebhook) error {
+	return nil
+}
+
+func (f *fakeHooks) GetEventWebhook(ctx context.Context, guildId, id int64) (model.EventWebhook, error) {
+	hook, ok := f.hooks[id]
+	if !ok || hook.GuildId != guildId {
+		return model.EventWebhook{}, sql.ErrNoRows
+	}
+	return hook, nil
+}
+
+func (f *fakeHooks) GetGuildEventWebhooks(ctx context.Context, guildId int64) ([]model.EventWebhook, error) {
+	var out []model.EventWebhook
+	for _, hook := range f.hooks {
+		if hook.GuildId == guildId {
+			out = append(out, hook)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeHooks) UpdateEventWebhook(ctx context.Context, guildId, id int64, url *string, events []int64, enabled *bool) error {
+	return nil
+}
+
+func (f *fakeHooks) SetSecret(ctx context.Context, guildId, id int64, secret string) error {
+	return nil
+}
+
+func (f *fakeHooks) RecordSuccess(ctx context.Context, guildId, id int64) error {
+	hook := f.hooks[id]
+	hook.FailureCount = 0
+	f.hooks[id] = hook
+	return nil
+}
+
+func (f *fakeHooks) RecordFailure(ctx context.Context, guildId, id int64, disableAfter int) (bool, error) {
+	f.failures++
+	hook := f.hooks[id]
+	hook.FailureCount++
+	if hook.FailureCount >= disableAfter {
+		hook.Enabled = false
+	}
+	f.hooks[id] = hook
+	return !hook.Enabled, nil
+}
+
+func (f *fakeHooks) DeleteEventWebhook(ctx context.Context, guildId, id int64) error {
+	return nil
+}
+
+func (f *fakeHooks) DeleteGuildEventWebhooks(ctx context.Context, guildId int64) error {
+	return nil
+}
+
+type fakeDeliveries struct {
+	records []model.EventWebhookDelivery
+}
+
+func (f *fakeDeliveries) AddDelivery(ctx context.Context, delivery model.EventWebhookDelivery) error {
+	f.records = append(f.records, delivery)
+	return nil
+}
+
+func (f *fakeDeliveries) GetDeliveriesBefore(ctx context.Context, webhookId, before int64, limit int) ([]model.EventWebhookDelivery, error) {
+	return nil, nil
+}
+
+func (f *fakeDeliveries) RemoveDeliveries(ctx context.Context, webhookId int64) error {
+	return nil
+}
+
+type fakePublisher struct {
+	jobs []eventhook.Job
+}
+
+func (f *fakePublisher) Publish(subject string, data []byte) error {
+	var job eventhook.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return err
+	}
+	f.jobs = append(f.jobs, job)
+	return nil
+}
+
+func testJob(t *testing.T) []byte {
+	t.Helper()
+	data, err := json.Marshal(eventhook.Job{
+		GuildId:   1,
+		WebhookId: 5,
+		EventId:   77,
+		EventType: mqmsg.EventTypeMessageCreate,
+		Data:      json.RawMessage(`{"guild_id":1}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDeliverSignsRequest(t *testing.T) {
+	var payload eventhook.Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(eventhook.HeaderTimestamp), 10, 64)
+		if !eventhook.Verify("secret", timestamp, body, r.Header.Get(eventhook.HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.Unmarshal(body, &payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	hooks := &fakeHooks{hooks: map[int64]model.EventWebhook{
+		5: {GuildId: 1, Id: 5, URL: srv.URL, Secret: "secret", Events: pq.Int64Array{int64(mqmsg.EventTypeMessageCreate)}, Enabled: true, FailureCount: 2},
+	}}
+	deliveries := &fakeDeliveries{}
+	d := NewDeliverer(slog.Default(), hooks, deliveries, time.Second, 5, true)
+
+	if err := d.Deliver(context.Background(), testJob(t)); err != nil {
+		t.Fatalf("Deliver returned error: %v", err)
+	}
+	if payload.Id != 77 || payload.EventType != mqmsg.EventTypeMessageCreate || string(payload.Data) != `{"guild_id":1}` {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+	if len(deliveries.records) != 1 || deliveries.records[0].StatusCode != http.StatusNoContent || deliveries.records[0].Error != nil {
+		t.Fatalf("unexpected delivery log %+v", deliveries.records)
+	}
+	if hooks.hooks[5].FailureCount != 0 {
+		t.Fatal("expected successful delivery to reset failures")
+	}
+}
+
+func TestDeliverDisablesFailingWebhook(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer srv.Close()
+
+	hooks := &fakeHooks{hooks: map[int64]model.EventWebhook{
+		5: {GuildId: 1, Id: 5, URL: srv.URL, Secret: "secret", Events: pq.Int64Array{int64(mqmsg.EventTypeMessageCreate)}, Enabled: true},
+	}}
+	d := NewDeliverer(slog.Default(), hooks, nil, time.Second, 1, true)
+
+	err := d.Deliver(context.Background(), testJob(t))
+	if err == nil || !durable.IsPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+	if hooks.hooks[5].Enabled {
+		t.Fatal("expected webhook to be disabled")
+	}
+
+	// Pending jobs of a disabled webhook are dropped without a request
+	if err := d.Deliver(context.Background(), testJob(t)); err != nil {
+		t.Fatalf("expected job of disabled webhook to be dropped, got %v", err)
+	}
+	if hooks.failures != 1 {
+		t.Fatalf("expected one recorded failure, got %d", hooks.failures)
+	}
+}
+
+func TestDeliverRefusesPrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not reach the private endpoint")
+	}))
+	defer srv.Close()
+
+	hooks := &fakeHooks{hooks: map[int64]model.EventWebhook{
+		5: {GuildId: 1, Id: 5, URL: srv.URL, Secret: "secret", Events: pq.Int64Array{int64(mqmsg.EventTypeMessageCreate)}, Enabled: true},
+	}}
+	d := NewDeliverer(slog.Default(), hooks, nil, time.Second, 5, false)
+
+	if err := d.Deliver(context.Background(), testJob(t)); err == nil || !durable.IsPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+}
+
+func TestDispatchCreatesJobsForSubscribedWebhooks(t *testing.T) {
+	hooks := &fakeHooks{hooks: map[int64]model.EventWebhook{
+		5: {GuildId: 1, Id: 5, Events: pq.Int64Array{int64(mqmsg.EventTypeMessageCreate)}, Enabled: true},
+		6: {GuildId: 1, Id: 6, Events: pq.Int64Array{int64(mqmsg.EventTypeGuildMemberAdd)}, Enabled: true},
+		7: {GuildId: 1, Id: 7, Events: pq.Int64Array{int64(mqmsg.EventTypeMessageCreate)}, Enabled: false},
+	}}
+	pub := &fakePublisher{}
+	d := NewDispatcher(slog.Default(), pub, hooks, time.Minute)
+
+	event := `{"op":0,"t":100,"d":{"guild_id":1,"message":{"id":3}}}`
+	if err := d.dispatch(context.Background(), "channel.2", []byte(event)); err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.jobs) != 1 || pub.jobs[0].WebhookId != 5 || pub.jobs[0].GuildId != 1 || pub.jobs[0].EventId == 0 {
+		t.Fatalf("unexpected jobs %+v", pub.jobs)
+	}
+
+	// Guild events take the guild from the subject, DM and typing events are skipped
+	pub.jobs = nil
+	if err := d.dispatch(context.Background(), "guild.1", []byte(`{"op":0,"t":200,"d":{"user_id":3}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.dispatch(context.Background(), "channel.9", []byte(`{"op":0,"t":100,"d":{"guild_id":null}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.dispatch(context.Background(), "channel.2", []byte(`{"op":0,"t":301,"d":{"guild_id":1}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.jobs) != 1 || pub.jobs[0].WebhookId != 6 {
+		t.Fatalf("unexpected jobs %+v", pub.jobs)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/FlameInTheDark/gochat/cmd/ws/auth"
 	"github.com/FlameInTheDark/gochat/cmd/ws/config"
 	"github.com/FlameInTheDark/gochat/cmd/ws/hub"
+	"github.com/FlameInTheDark/gochat/cmd/ws/session"
 	"github.com/FlameInTheDark/gochat/internal/cache/kvs"
 	"github.com/FlameInTheDark/gochat/internal/database/db"
 	"github.com/FlameInTheDark/gochat/internal/database/pgdb"
@@ -29,6 +30,7 @@ type App struct {
 	jwt      *auth.Auth
 	natsConn *nats.Conn
 	hub      *hub.Hub
+	sessions *session.Registry
 	app      *fiber.App
 	cdb      *db.CQLCon
 	pg       *pgdb.DB
@@ -40,6 +42,7 @@ type App struct {
 
 	// Metrics
 	wsActive prometheus.Gauge
+	metrics  *wsMetrics
 }
 
 func NewApp(shut *shutter.Shut, logger *slog.Logger) *App {
@@ -103,11 +106,27 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) *App {
 	})
 
 	wsHub := hub.New(natsCon)
+	metrics := newWSMetrics()
+
+	sessions, err := session.NewRegistry(wsHub, natsCon, kv, session.Config{
+		ResumeWindow: time.Duration(cfg.ResumeWindow) * time.Second,
+		BufferSize:   cfg.ResumeBufferSize,
+		QueueSize:    cfg.OutBufferSize,
+		OnDrop: func() {
+			metrics.dropped.WithLabelValues(dropStageSession).Inc()
+		},
+	}, logger)
+	if err != nil {
+		logger.Error("unable to create session registry", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	shut.Up(sessions)
 
 	a := &App{
 		jwt:      jwtauth,
 		natsConn: natsCon,
 		hub:      wsHub,
+		sessions: sessions,
 		app:      app,
 		cdb:      dbcon,
 		pg:       pg,
@@ -115,6 +134,7 @@ func NewApp(shut *shutter.Shut, logger *slog.Logger) *App {
 		cfg:      cfg,
 		log:      logger,
 		shut:     shut,
+		metrics:  metrics,
 	}
 
 	// Metrics setup
@@ -9,15 +9,19 @@ import (
 )
 
 type Config struct {
-	Host              string   `yaml:"host" env:"HOST" envDefault:":3100"`
-	AuthSecret        string   `yaml:"auth_secret" env:"AUTH_SECRET" env-default:"change_me_before_use_it_in_production"`
-	Cluster           []string `yaml:"cluster" env:"CLUSTER" env-default:""`
-	ClusterKeyspace   string   `yaml:"cluster_keyspace" env:"CLUSTER_KEYSPACE" env-default:"gochat"`
-	HearthBeatTimeout int64    `yaml:"hearth_beat_timeout" env:"HEARTH_BEAT_TIME" env-default:"35000"`
-	NatsConnString    string   `yaml:"nats_conn_string" env:"NATS_CONN_STRING" env-default:"nats://nats:4222"`
-	PGDSN             string   `yaml:"pg_dsn" env:"PG_DSN"`
-	PGRetries         int      `yaml:"pg_retries" env:"PG_RETRIES" env-default:"5"`
-	CacheAddr         string   `yaml:"cache_addr" env:"CACHE_ADDR" env-default:"keydb:6379"`
+	Host                string   `yaml:"host" env:"HOST" envDefault:":3100"`
+	AuthSecret          string   `yaml:"auth_secret" env:"AUTH_SECRET" env-default:"change_me_before_use_it_in_production"`
+	Cluster             []string `yaml:"cluster" env:"CLUSTER" env-default:""`
+	ClusterKeyspace     string   `yaml:"cluster_keyspace" env:"CLUSTER_KEYSPACE" env-default:"gochat"`
+	HearthBeatTimeout   int64    `yaml:"hearth_beat_timeout" env:"HEARTH_BEAT_TIME" env-default:"35000"`
+	NatsConnString      string   `yaml:"nats_conn_string" env:"NATS_CONN_STRING" env-default:"nats://nats:4222"`
+	PGDSN               string   `yaml:"pg_dsn" env:"PG_DSN"`
+	PGRetries           int      `yaml:"pg_retries" env:"PG_RETRIES" env-default:"5"`
+	CacheAddr           string   `yaml:"cache_addr" env:"CACHE_ADDR" env-default:"keydb:6379"`
+	ResumeWindow        int64    `yaml:"resume_window" env:"RESUME_WINDOW" env-default:"60"`                   // Seconds a dropped session can be resumed
+	ResumeBufferSize    int64    `yaml:"resume_buffer_size" env:"RESUME_BUFFER_SIZE" env-default:"500"`        // Events kept per session for replay
+	OutBufferSize       int      `yaml:"out_buffer_size" env:"OUT_BUFFER_SIZE" env-default:"256"`              // Outbound events queued per connection
+	SlowConsumerTimeout int64    `yaml:"slow_consumer_timeout" env:"SLOW_CONSUMER_TIMEOUT" env-default:"2000"` // Milliseconds an event that can not be dropped waits for a slow client
 }
 
 func LoadConfig(logger *slog.Logger) (*Config, error) {
@@ -13,30 +13,67 @@ import (
 	"github.com/gofiber/contrib/websocket"
 
 	"github.com/FlameInTheDark/gochat/cmd/ws/handler"
-	"github.com/FlameInTheDark/gochat/cmd/ws/subscriber"
+	"github.com/FlameInTheDark/gochat/cmd/ws/session"
 	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
 	"github.com/FlameInTheDark/gochat/internal/presence"
 )
 
-// wsConn implements hub.Conn for a single WebSocket connection.
-// It wraps the writer pump's outbound channel so the hub can deliver
-// messages without blocking.
+var errWriterClosed = errors.New("ws writer closed")
+
+// wsConn implements session.Conn for a single WebSocket connection.
+// It wraps the writer pump's outbound channel so the session can deliver
+// messages without blocking the other connections.
 type wsConn struct {
-	id  string
-	out chan<- outMsg
+	id     string
+	out    chan<- outMsg
+	closed <-chan struct{}
+	close  func(code int, reason string)
+	// How long an event that can not be dropped waits for space in the buffer
+	timeout time.Duration
+	metrics *wsMetrics
+	// Low priority events dropped on this connection
+	dropped atomic.Int64
 }
 
-func (w *wsConn) Send(data []byte) {
-	// Copy the data so the hub's buffer can be reused.
-	cp := make([]byte, len(data))
-	copy(cp, data)
-	// Non-blocking: drop the message if the connection's buffer is full.
+func (w *wsConn) Send(data []byte, p session.Priority) {
+	m := outMsg{kind: 1, data: data}
 	select {
-	case w.out <- outMsg{kind: 1, data: cp}:
+	case w.out <- m:
+		return
 	default:
-		// Slow consumer вЂ” message dropped. The ping/heartbeat timeout
-		// will eventually evict this connection.
 	}
+
+	if p == session.PriorityLow {
+		// Typing and presence are refreshed by the next update, drop them
+		w.dropped.Add(1)
+		w.metrics.dropped.WithLabelValues(dropStageConnection).Inc()
+		return
+	}
+
+	t := time.NewTimer(w.timeout)
+	defer t.Stop()
+	select {
+	case w.out <- m:
+	case <-w.closed:
+	case <-t.C:
+		// The client stays behind. The event is already recorded in the session, so it gets it after resume.
+		w.metrics.slowConsumers.Inc()
+		w.close(session.CloseResume, "Slow consumer, resume the session")
+	}
+}
+
+// Replay waits for space in the outbound buffer, replayed events must not be dropped.
+func (w *wsConn) Replay(data []byte) error {
+	select {
+	case w.out <- outMsg{kind: 1, data: data}:
+		return nil
+	case <-w.closed:
+		return errWriterClosed
+	}
+}
+
+func (w *wsConn) Close(code int, reason string) {
+	w.close(code, reason)
 }
 
 // outMsg is an internal message sent through the writer pump channel.
@@ -53,8 +90,12 @@ func (a *App) wsHandler(c *websocket.Conn) {
 		a.wsActive.Inc()
 		defer a.wsActive.Dec()
 	}
-	out := make(chan outMsg, 256)
+	out := make(chan outMsg, a.cfg.OutBufferSize)
+	// Closed to make the writer pump send the close frame and stop
 	writerClosed := make(chan struct{})
+	writerDone := make(chan struct{})
+	var closeCode int
+	var closeReason string
 
 	compressMode := strings.EqualFold(c.Query("compress"), "zlib-stream")
 	var zbuf bytes.Buffer
@@ -64,7 +105,34 @@ func (a *App) wsHandler(c *websocket.Conn) {
 	}
 
 	go func() {
-		for m := range out {
+		defer close(writerDone)
+		writeClose := func() {
+			_ = c.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(closeCode, closeReason),
+				time.Now().Add(1*time.Second),
+			)
+			_ = c.Close()
+			if compressMode && zw != nil {
+				_ = zw.Close()
+			}
+		}
+		for {
+			// Close takes priority over the queued messages
+			select {
+			case <-writerClosed:
+				writeClose()
+				return
+			default:
+			}
+			var m outMsg
+			select {
+			case <-writerClosed:
+				writeClose()
+				return
+			case m = <-out:
+			}
+
 			var err error
 			switch m.kind {
 			case 1:
@@ -108,13 +176,6 @@ func (a *App) wsHandler(c *websocket.Conn) {
 					}
 					err = c.WriteJSON(m.v)
 				}
-			case 3:
-				err = c.WriteControl(
-					websocket.CloseMessage,
-					websocket.FormatCloseMessage(websocket.CloseNormalClosure, string(m.data)),
-					time.Now().Add(1*time.Second),
-				)
-				_ = c.Close()
 			case 4:
 				if c.Conn != nil {
 					_ = c.SetWriteDeadline(time.Now().Add(5 * time.Second))
@@ -124,18 +185,11 @@ func (a *App) wsHandler(c *websocket.Conn) {
 			if m.done != nil {
 				m.done <- err
 			}
-			if m.kind == 3 {
-				if compressMode && zw != nil {
-					_ = zw.Close()
-				}
-				return
-			}
 		}
 	}()
 
 	var closed int32
 	var closeOnce sync.Once
-	errWriterClosed := errors.New("ws writer closed")
 	sendJSON := func(v any) error {
 		if atomic.LoadInt32(&closed) == 1 {
 			return errWriterClosed
@@ -143,19 +197,23 @@ func (a *App) wsHandler(c *websocket.Conn) {
 		done := make(chan error, 1)
 		select {
 		case out <- outMsg{kind: 2, v: v, done: done}:
-			return <-done
 		case <-writerClosed:
 			atomic.StoreInt32(&closed, 1)
 			return errWriterClosed
 		}
+		select {
+		case err := <-done:
+			return err
+		case <-writerDone:
+			return errWriterClosed
+		}
 	}
-	sendClose := func(reason string) {
+	sendClose := func(code int, reason string) {
 		closeOnce.Do(func() {
 			atomic.StoreInt32(&closed, 1)
+			closeCode, closeReason = code, reason
 			close(writerClosed)
-			done := make(chan error, 1)
-			out <- outMsg{kind: 3, data: []byte(reason), done: done}
-			<-done
+			<-writerDone
 		})
 	}
 
@@ -168,18 +226,25 @@ func (a *App) wsHandler(c *websocket.Conn) {
 		}
 	}()
 
-	conn := &wsConn{id: c.RemoteAddr().String(), out: out}
-	subs := subscriber.New(a.hub, conn)
+	conn := &wsConn{
+		id:      c.RemoteAddr().String(),
+		out:     out,
+		closed:  writerClosed,
+		close:   sendClose,
+		timeout: time.Duration(a.cfg.SlowConsumerTimeout) * time.Millisecond,
+		metrics: a.metrics,
+	}
 	defer func() {
-		cerr := subs.Close()
-		if cerr != nil {
-			a.log.Error("Error closing subscriber", "error", cerr)
+		dropped := conn.dropped.Load()
+		a.metrics.connectionDrops.Observe(float64(dropped))
+		if dropped > 0 {
+			a.log.Info("Dropped low priority events on slow connection", "conn", conn.id, "dropped", dropped)
 		}
 	}()
 	pstore := presence.NewStore(a.cache)
 
-	h := handler.New(a.cdb, a.pg, subs, sendJSON, a.jwt, a.cfg.HearthBeatTimeout, func() {
-		sendClose("Closed")
+	h := handler.New(a.cdb, a.pg, a.sessions, conn, sendJSON, a.jwt, a.cfg.HearthBeatTimeout, func() {
+		sendClose(session.CloseNormal, "Closed")
 	}, a.log, a.natsConn, pstore, a.cache)
 
 	defer func() { _ = h.Close() }()
@@ -205,10 +270,14 @@ func (a *App) wsHandler(c *websocket.Conn) {
 				done := make(chan error, 1)
 				select {
 				case out <- outMsg{kind: 4, done: done}:
-					<-done
 				case <-writerClosed:
 					return
 				}
+				select {
+				case <-done:
+				case <-writerDone:
+					return
+				}
 			}
 		}
 	}()
@@ -226,6 +295,10 @@ func (a *App) wsHandler(c *websocket.Conn) {
 			) {
 				return
 			}
+			if atomic.LoadInt32(&closed) == 1 {
+				// Closed by the server
+				return
+			}
 			a.log.Error("Read WS message error", "error", err)
 			continue
 		}
@@ -12,16 +12,22 @@ import (
 	"github.com/nats-io/nats.go"
 
 	"github.com/FlameInTheDark/gochat/cmd/ws/auth"
+	"github.com/FlameInTheDark/gochat/cmd/ws/session"
 	"github.com/FlameInTheDark/gochat/cmd/ws/subscriber"
+	"github.com/FlameInTheDark/gochat/internal/bottoken"
 	"github.com/FlameInTheDark/gochat/internal/cache/kvs"
 	"github.com/FlameInTheDark/gochat/internal/database/db"
 	"github.com/FlameInTheDark/gochat/internal/database/pgdb"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/application"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/blocked"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/dmchannel"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/groupdmchannel"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/guild"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/guildchannels"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/member"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/user"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/userrole"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/usersession"
 	"github.com/FlameInTheDark/gochat/internal/dto"
 	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
 	"github.com/FlameInTheDark/gochat/internal/permissions"
@@ -39,16 +45,24 @@ type heartbeatMessage struct {
 }
 
 type Handler struct {
-	user     *dto.User
+	user *dto.User
+	// Subscriptions of the gateway session, set after hello or resume
 	sub      *subscriber.Subscriber
+	reg      *session.Registry
+	sess     *session.Session
+	conn     session.Conn
 	g        guild.Guild
 	m        member.Member
+	ur       userrole.UserRole
 	dm       dmchannel.DmChannel
 	gdm      groupdmchannel.GroupDMChannel
+	blk      blocked.Blocked
 	u        user.User
+	us       usersession.UserSession
 	gc       guildchannels.GuildChannels
 	perm     rolecheck.RoleCheck
 	jwt      *auth.Auth
+	bots     *bottoken.Authenticator
 	sendJSON func(v any) error
 	nats     *nats.Conn
 	pstore   *presence.Store
@@ -72,18 +86,23 @@ type Handler struct {
 	lastPresenceTouch time.Time
 }
 
-func New(c *db.CQLCon, pg *pgdb.DB, sub *subscriber.Subscriber, sendJSON func(v any) error, jwt *auth.Auth, hbTimeout int64, closer func(), logger *slog.Logger, nats *nats.Conn, pstore *presence.Store, cache *kvs.Cache) *Handler {
+func New(c *db.CQLCon, pg *pgdb.DB, reg *session.Registry, conn session.Conn, sendJSON func(v any) error, jwt *auth.Auth, hbTimeout int64, closer func(), logger *slog.Logger, nats *nats.Conn, pstore *presence.Store, cache *kvs.Cache) *Handler {
 	initTimer := time.AfterFunc(time.Second*5, closer)
 	return &Handler{
-		sub:      sub,
+		reg:      reg,
+		conn:     conn,
 		g:        guild.New(pg.Conn()),
 		m:        member.New(pg.Conn()),
+		ur:       userrole.New(pg.Conn()),
 		dm:       dmchannel.New(pg.Conn()),
 		gdm:      groupdmchannel.New(pg.Conn()),
+		blk:      blocked.New(pg.Conn()),
 		u:        user.New(pg.Conn()),
+		us:       usersession.New(pg.Conn()),
 		gc:       guildchannels.New(pg.Conn()),
 		perm:     rolecheck.New(pg),
 		jwt:      jwt,
+		bots:     bottoken.NewAuthenticator(application.New(pg.Conn()), cache),
 		sendJSON: sendJSON,
 		nats:     nats,
 		pstore:   pstore,
@@ -98,12 +117,14 @@ func New(c *db.CQLCon, pg *pgdb.DB, sub *subscriber.Subscriber, sendJSON func(v
 }
 
 func (h *Handler) HandleMessage(e mqmsg.Message) {
-	if e.Operation != mqmsg.OPCodeHello && h.user == nil {
+	if e.Operation != mqmsg.OPCodeHello && e.Operation != mqmsg.OPCodeResume && h.user == nil {
 		return
 	}
 	switch e.Operation {
 	case mqmsg.OPCodeHello:
 		h.hello(&e)
+	case mqmsg.OPCodeResume:
+		h.resume(&e)
 	case mqmsg.OPCodeHeartBeat:
 		if len(e.Data) == 0 || string(bytes.TrimSpace(e.Data)) == "null" {
 			return
@@ -368,8 +389,29 @@ func (h *Handler) HandleMessage(e mqmsg.Message) {
 	}
 }
 
+// loadBlocked hands the users blocked by the user to the session, it hides their typing, presence and mentions
+func (h *Handler) loadBlocked(ctx context.Context) {
+	blocked, err := h.blk.GetBlockedUsers(ctx, h.user.Id)
+	if err != nil {
+		h.log.Warn("Error getting blocked users", "error", err)
+		return
+	}
+	ids := make([]int64, 0, len(blocked))
+	for _, b := range blocked {
+		ids = append(ids, b.BlockedUserId)
+	}
+	h.sess.SetBlocked(ids)
+}
+
 func (h *Handler) Close() error {
 	h.OnWSClosed()
+	if h.sess != nil {
+		// Keep the session for resume, it is dropped when the resume window ends
+		// and the temporary memberships are removed then
+		h.reg.Detach(h.sess, h.conn)
+	} else {
+		h.removeTemporaryMemberships()
+	}
 	h.closer()
 	return nil
 }
@@ -398,9 +440,42 @@ func (h *Handler) OnWSClosed() {
 	}
 }
 
+// removeTemporaryMemberships removes the user from guilds joined with a temporary invite when the last session ends.
+// Members that got a role keep their membership.
+func (h *Handler) removeTemporaryMemberships() {
+	if h.user == nil || h.pstore == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if online, err := h.pstore.HasSessions(ctx, h.user.Id, time.Now().Unix()); err != nil || online {
+		return
+	}
+	guilds, err := h.m.GetTemporaryGuilds(ctx, h.user.Id)
+	if err != nil {
+		h.log.Warn("Error getting temporary memberships", "error", err)
+		return
+	}
+	for _, g := range guilds {
+		roles, err := h.ur.GetUserRoles(ctx, g.GuildId, h.user.Id)
+		if err != nil {
+			h.log.Warn("Error getting member roles", "guild_id", g.GuildId, "error", err)
+			continue
+		}
+		if len(roles) > 0 {
+			continue
+		}
+		if err := h.m.RemoveMember(ctx, h.user.Id, g.GuildId); err != nil {
+			h.log.Warn("Error removing temporary member", "guild_id", g.GuildId, "error", err)
+			continue
+		}
+		h.publishGuildEvent(g.GuildId, &mqmsg.RemoveGuildMember{GuildId: g.GuildId, UserId: h.user.Id})
+	}
+}
+
 func (h *Handler) sendPresenceSnapshot(userID int64) {
 	// Read presence from cache and send to this connection only
-	if h.pstore == nil {
+	if h.pstore == nil || h.sess.Blocked(userID) {
 		return
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
@@ -441,3 +516,18 @@ func (h *Handler) publishPresence(agg presence.Presence) {
 	}
 	_ = h.nats.Publish(fmt.Sprintf("presence.user.%d", agg.UserID), b)
 }
+
+func (h *Handler) publishGuildEvent(guildID int64, event mqmsg.EventDataMessage) {
+	if h.nats == nil {
+		return
+	}
+	msg, err := mqmsg.BuildEventMessage(event)
+	if err != nil {
+		return
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	_ = h.nats.Publish(fmt.Sprintf("guild.%d", guildID), b)
+}
@@ -2,19 +2,28 @@ package handler
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	crand "crypto/rand"
 
+	"github.com/FlameInTheDark/gochat/cmd/ws/session"
 	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/helper"
 	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
 
 	pgmodel "github.com/FlameInTheDark/gochat/internal/database/model"
 )
 
 func (h *Handler) hello(msg *mqmsg.Message) {
+	if h.sess != nil {
+		// Session is already established on this connection
+		return
+	}
 	var m helloMessage
 	err := json.Unmarshal(msg.Data, &m)
 	if err != nil {
@@ -23,7 +32,7 @@ func (h *Handler) hello(msg *mqmsg.Message) {
 		h.log.Error("Error unmarshalling hello message", "error", err)
 		return
 	}
-	token, err := h.jwt.ParseAccess(m.Token)
+	token, err := h.parseToken(m.Token)
 	if err != nil {
 		h.initTimer.Stop()
 		h.closer()
@@ -35,6 +44,12 @@ func (h *Handler) hello(msg *mqmsg.Message) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(h.hbTimeout))
 	defer cancel()
 
+	if !h.authSessionActive(ctx, token.UserID, token.SessionID) {
+		h.initTimer.Stop()
+		h.conn.Close(session.CloseSessionRevoked, "Session revoked")
+		return
+	}
+
 	type userResult struct {
 		user pgmodel.User
 		err  error
@@ -76,6 +91,7 @@ func (h *Handler) hello(msg *mqmsg.Message) {
 	h.user = &dto.User{
 		Id:   ur.user.Id,
 		Name: ur.user.Name,
+		Bot:  ur.user.Bot,
 	}
 
 	// Establish or reuse session ID (UUID v4 style). Presence will be set only after client PresenceUpdate.
@@ -84,6 +100,20 @@ func (h *Handler) hello(msg *mqmsg.Message) {
 	} else {
 		h.sessionID = newSessionID()
 	}
+	sess, err := h.reg.Create(h.sessionID, ur.user.Id, token.SessionID, h.conn)
+	if errors.Is(err, session.ErrSessionTaken) {
+		h.sessionID = newSessionID()
+		sess, err = h.reg.Create(h.sessionID, ur.user.Id, token.SessionID, h.conn)
+	}
+	if err != nil {
+		h.closer()
+		h.log.Error("Error creating session", "error", err)
+		return
+	}
+	h.sess = sess
+	h.sess.OnEnd(h.removeTemporaryMemberships)
+	h.sub = sess.Subscriber()
+	h.loadBlocked(ctx)
 
 	// Do not auto-set presence here. Presence is set only after client sends PresenceUpdate.
 	hellomsg, err := mqmsg.BuildEventMessage(&mqmsg.HeartbeatInterval{HeartbeatInterval: h.hbTimeout, SessionID: h.sessionID})
@@ -99,6 +129,27 @@ func (h *Handler) hello(msg *mqmsg.Message) {
 		h.log.Error("Error sending hello message", "error", err)
 		return
 	}
+	h.startHeartbeatTimer()
+
+	// Subscribe to personal user topic
+	err = h.sub.Subscribe("user", fmt.Sprintf("user.%d", token.UserID))
+	if err != nil {
+		h.initTimer.Stop()
+		h.closer()
+		h.log.Error("Error subscribing to user", "error", err)
+		return
+	}
+
+	// Subscribe to all guilds (hub registrations are fast in-memory ops)
+	for _, g := range gr.guilds {
+		if err := h.sub.Subscribe(fmt.Sprintf("guild.%d", g.GuildId), fmt.Sprintf("guild.%d", g.UserId)); err != nil {
+			h.log.Warn("Error subscribing to guild", "error", err, "guild_id", g.GuildId)
+		}
+	}
+}
+
+// startHeartbeatTimer closes the connection if the client stops sending heartbeats
+func (h *Handler) startHeartbeatTimer() {
 	h.hTimer = time.AfterFunc(time.Millisecond*time.Duration(h.hbTimeout+10000), func() {
 		h.log.Warn("Heartbeat timeout; closing WS", "user_id", func() any {
 			if h.user != nil {
@@ -111,22 +162,37 @@ func (h *Handler) hello(msg *mqmsg.Message) {
 			h.log.Error("Error closing WS connection after timeout", "error", err)
 		}
 	})
+}
 
-	// Subscribe to personal user topic
-	err = h.sub.Subscribe("user", fmt.Sprintf("user.%d", token.UserID))
+// parseToken accepts a user access token or a bot token with the "Bot " prefix
+func (h *Handler) parseToken(token string) (*helper.Claims, error) {
+	bot, ok := strings.CutPrefix(strings.TrimSpace(token), "Bot ")
+	if !ok {
+		return h.jwt.ParseAccess(token)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	botId, err := h.bots.Authenticate(ctx, strings.TrimSpace(bot))
 	if err != nil {
-		h.initTimer.Stop()
-		h.closer()
-		h.log.Error("Error subscribing to user", "error", err)
-		return
+		return nil, err
 	}
+	return &helper.Claims{UserID: botId, TokenType: "access", Bot: true}, nil
+}
 
-	// Subscribe to all guilds (hub registrations are fast in-memory ops)
-	for _, g := range gr.guilds {
-		if err := h.sub.Subscribe(fmt.Sprintf("guild.%d", g.GuildId), fmt.Sprintf("guild.%d", g.UserId)); err != nil {
-			h.log.Warn("Error subscribing to guild", "error", err, "guild_id", g.GuildId)
+// authSessionActive checks that the login session of the token is not revoked.
+// Tokens issued before login sessions were tracked have no session, they expire on their own.
+func (h *Handler) authSessionActive(ctx context.Context, userId, sessionId int64) bool {
+	if sessionId == 0 {
+		return true
+	}
+	s, err := h.us.GetSession(ctx, userId, sessionId)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.log.Error("Error getting login session", "error", err)
 		}
+		return false
 	}
+	return s.Active(time.Now())
 }
 
 // newSessionID generates a random UUIDv4-like string without external deps.
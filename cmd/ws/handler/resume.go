@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FlameInTheDark/gochat/cmd/ws/session"
+	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+func (h *Handler) resume(msg *mqmsg.Message) {
+	if h.sess != nil {
+		// Session is already established on this connection
+		return
+	}
+	var m mqmsg.Resume
+	err := json.Unmarshal(msg.Data, &m)
+	if err != nil {
+		h.initTimer.Stop()
+		h.closer()
+		h.log.Error("Error unmarshalling resume message", "error", err)
+		return
+	}
+	token, err := h.parseToken(m.Token)
+	if err != nil {
+		h.initTimer.Stop()
+		h.closer()
+		h.log.Error("Error parsing token", "error", err)
+		return
+	}
+	if m.SessionID == "" {
+		h.invalidSession()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if !h.authSessionActive(ctx, token.UserID, token.SessionID) {
+		h.initTimer.Stop()
+		h.conn.Close(session.CloseSessionRevoked, "Session revoked")
+		return
+	}
+	u, err := h.u.GetUserById(ctx, token.UserID)
+	if err != nil {
+		h.initTimer.Stop()
+		h.closer()
+		h.log.Error("Error getting user", "error", err)
+		return
+	}
+
+	sess, replayed, err := h.reg.Resume(m.SessionID, token.UserID, token.SessionID, m.Seq, h.conn)
+	if err != nil {
+		if !errors.Is(err, session.ErrInvalidSession) {
+			h.log.Error("Error resuming session", "error", err, "session_id", m.SessionID)
+		}
+		h.invalidSession()
+		return
+	}
+	h.initTimer.Stop()
+
+	h.user = &dto.User{
+		Id:   u.Id,
+		Name: u.Name,
+		Bot:  u.Bot,
+	}
+	h.sess = sess
+	h.sess.OnEnd(h.removeTemporaryMemberships)
+	h.sub = sess.Subscriber()
+	h.sessionID = m.SessionID
+	h.loadBlocked(ctx)
+	// Restore watched presences from the session subscriptions
+	for key := range h.sub.Topics() {
+		uid, ok := strings.CutPrefix(key, "presence.")
+		if !ok {
+			continue
+		}
+		if id, err := strconv.ParseInt(uid, 10, 64); err == nil {
+			h.psubs[id] = struct{}{}
+		}
+	}
+
+	resumed, err := mqmsg.BuildEventMessage(&mqmsg.Resumed{HeartbeatInterval: h.hbTimeout, SessionID: h.sessionID, Replayed: replayed})
+	if err != nil {
+		h.closer()
+		return
+	}
+	if err := h.sendJSON(resumed); err != nil {
+		h.closer()
+		h.log.Error("Error sending resumed message", "error", err)
+		return
+	}
+	h.startHeartbeatTimer()
+}
+
+// invalidSession tells the client to start over with hello and gives it time to do so
+func (h *Handler) invalidSession() {
+	msg, err := mqmsg.BuildEventMessage(&mqmsg.InvalidSession{Resumable: false})
+	if err != nil {
+		h.closer()
+		return
+	}
+	if err := h.sendJSON(msg); err != nil {
+		h.closer()
+		return
+	}
+	h.initTimer.Reset(time.Second * 5)
+}
@@ -0,0 +1,44 @@
+package main
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	dropStageSession    = "session"
+	dropStageConnection = "connection"
+)
+
+// wsMetrics tracks events lost on slow connections
+type wsMetrics struct {
+	// Events dropped because the session queue or the connection buffer was full
+	dropped *prometheus.CounterVec
+	// Connections closed because they stayed behind on events that can not be dropped
+	slowConsumers prometheus.Counter
+	// Dropped events per closed connection
+	connectionDrops prometheus.Histogram
+}
+
+func newWSMetrics() *wsMetrics {
+	m := &wsMetrics{
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gochat",
+			Subsystem: "ws",
+			Name:      "dropped_events_total",
+			Help:      "Number of events dropped for slow WebSocket connections",
+		}, []string{"stage"}),
+		slowConsumers: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "gochat",
+			Subsystem: "ws",
+			Name:      "slow_consumer_closes_total",
+			Help:      "Number of WebSocket connections closed for falling behind",
+		}),
+		connectionDrops: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "gochat",
+			Subsystem: "ws",
+			Name:      "connection_dropped_events",
+			Help:      "Number of low priority events dropped per WebSocket connection",
+			Buckets:   []float64{0, 1, 10, 100, 1000},
+		}),
+	}
+	prometheus.MustRegister(m.dropped, m.slowConsumers, m.connectionDrops)
+	return m
+}
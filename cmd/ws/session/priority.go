@@ -0,0 +1,92 @@
+package session
+
+import (
+	"encoding/json"
+
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+// WebSocket close codes the gateway uses to tell the client how to reconnect
+const (
+	CloseNormal = 1000
+	// CloseResume means the client fell behind on events and should reconnect with resume
+	CloseResume = 4000
+	// CloseInvalidSession means the session lost events and the client should reconnect with a fresh hello
+	CloseInvalidSession = 4001
+	// CloseSessionRevoked means the login session was revoked and the client has to log in again
+	CloseSessionRevoked = 4002
+)
+
+type Priority int
+
+const (
+	// PriorityLow events are transient state that is refreshed by the next event, they can be dropped
+	PriorityLow Priority = iota
+	// PriorityHigh events must be delivered or the connection has to be recovered
+	PriorityHigh
+)
+
+// EventPriority returns delivery priority of a serialized event. Typing and presence updates can be dropped.
+func EventPriority(data []byte) Priority {
+	return parseEvent(data).priority
+}
+
+// parseEvent reads the priority of the event and the users the session filters it by
+func parseEvent(data []byte) event {
+	e := event{data: data, priority: PriorityHigh}
+	var m struct {
+		Operation mqmsg.OPCodeType `json:"op"`
+		EventType *mqmsg.EventType `json:"t"`
+		Data      json.RawMessage  `json:"d"`
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return e
+	}
+	if m.Operation == mqmsg.OPCodePresenceUpdate {
+		e.priority = PriorityLow
+		e.from = eventUserId(m.Data)
+		return e
+	}
+	if m.EventType == nil {
+		return e
+	}
+	switch *m.EventType {
+	case mqmsg.EventTypeChannelUserTyping:
+		e.priority = PriorityLow
+		e.from = eventUserId(m.Data)
+	case mqmsg.EventTypeMention:
+		var d struct {
+			AuthorId int64 `json:"author_id"`
+		}
+		if json.Unmarshal(m.Data, &d) == nil {
+			e.from = d.AuthorId
+		}
+	case mqmsg.EventTypeUserBlockAdd:
+		e.blocked = eventBlockUserId(m.Data)
+	case mqmsg.EventTypeUserBlockRemove:
+		e.unblocked = eventBlockUserId(m.Data)
+	}
+	return e
+}
+
+func eventUserId(data json.RawMessage) int64 {
+	var d struct {
+		UserId int64 `json:"user_id"`
+	}
+	if json.Unmarshal(data, &d) != nil {
+		return 0
+	}
+	return d.UserId
+}
+
+func eventBlockUserId(data json.RawMessage) int64 {
+	var d struct {
+		User struct {
+			Id int64 `json:"id"`
+		} `json:"user"`
+	}
+	if json.Unmarshal(data, &d) != nil {
+		return 0
+	}
+	return d.User.Id
+}
@@ -0,0 +1,331 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/FlameInTheDark/gochat/cmd/ws/hub"
+	"github.com/FlameInTheDark/gochat/internal/sessionmq"
+)
+
+// takeoverSubject is used to move a session from the gateway instance that owns it to the instance the client resumed on
+const takeoverSubject = "ws.session.takeover"
+
+// ErrSessionTaken is returned when the session ID already belongs to another user
+var ErrSessionTaken = errors.New("session id is used by another user")
+
+type streamCache interface {
+	XAdd(ctx context.Context, stream string, maxLen int64, approx bool, values map[string]interface{}) error
+	XRange(ctx context.Context, stream, start, stop string) ([]map[string]interface{}, error)
+	SetTTL(ctx context.Context, key string, ttl int64) error
+	Delete(ctx context.Context, key string) error
+}
+
+type Config struct {
+	// How long a session waits for resume after the connection is dropped
+	ResumeWindow time.Duration
+	// Max number of events kept for replay
+	BufferSize int64
+	// Size of the queue between the hub and the session
+	QueueSize int
+	// Called for every event dropped because the queue is full
+	OnDrop func()
+}
+
+type takeoverRequest struct {
+	SessionID string `json:"session_id"`
+	UserId    int64  `json:"user_id"`
+}
+
+// Registry keeps gateway sessions of this instance
+type Registry struct {
+	hub   *hub.Hub
+	nc    *nats.Conn
+	cache streamCache
+	cfg   Config
+	log   *slog.Logger
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+	takeover *nats.Subscription
+	revoke   *nats.Subscription
+}
+
+func NewRegistry(h *hub.Hub, nc *nats.Conn, cache streamCache, cfg Config, logger *slog.Logger) (*Registry, error) {
+	r := &Registry{
+		hub:      h,
+		nc:       nc,
+		cache:    cache,
+		cfg:      cfg,
+		log:      logger,
+		sessions: make(map[string]*Session),
+	}
+	sub, err := nc.Subscribe(takeoverSubject, r.handleTakeover)
+	if err != nil {
+		return nil, fmt.Errorf("unable to subscribe to session takeover: %w", err)
+	}
+	r.takeover = sub
+	sub, err = nc.Subscribe(sessionmq.RevokeSubject, r.handleRevoke)
+	if err != nil {
+		_ = r.takeover.Unsubscribe()
+		return nil, fmt.Errorf("unable to subscribe to session revoke: %w", err)
+	}
+	r.revoke = sub
+	return r, nil
+}
+
+// Create starts a new session for a connection that sent hello with a token of the login session authSession.
+// A detached session with the same ID of the same user is replaced.
+func (r *Registry) Create(id string, userId, authSession int64, conn Conn) (*Session, error) {
+	r.mu.Lock()
+	old, ok := r.sessions[id]
+	if ok && old.userId != userId {
+		r.mu.Unlock()
+		return nil, ErrSessionTaken
+	}
+	s := newSession(r, id, userId, fmt.Sprintf("ws:events:%s:%d", id, time.Now().UnixNano()), 0)
+	s.conn = conn
+	s.authSession = authSession
+	r.sessions[id] = s
+	r.mu.Unlock()
+
+	if ok {
+		r.drop(old, CloseNormal, "Session replaced")
+	}
+	return s, nil
+}
+
+// Resume attaches the connection to an existing session and replays events after seq.
+// The session is looked up locally first and then taken over from other gateway instances.
+// Returns ErrInvalidSession if the session is unknown, belongs to another user or the missed events are no longer buffered.
+func (r *Registry) Resume(id string, userId, authSession, seq int64, conn Conn) (*Session, int, error) {
+	r.mu.Lock()
+	s, ok := r.sessions[id]
+	r.mu.Unlock()
+
+	if !ok {
+		var err error
+		s, err = r.takeoverRemote(id, userId)
+		if err != nil {
+			return nil, 0, err
+		}
+		// takeoverRemote returns the session locked to keep new events after the replay
+	} else {
+		s.mu.Lock()
+		if s.closed || s.userId != userId {
+			s.mu.Unlock()
+			return nil, 0, ErrInvalidSession
+		}
+		if s.expire != nil {
+			s.expire.Stop()
+			s.expire = nil
+		}
+		// Only one connection can use the session
+		if s.conn != nil {
+			go s.conn.Close(CloseNormal, "Session resumed on another connection")
+		}
+		s.conn = nil
+	}
+
+	replayed, err := s.replay(conn, seq)
+	if err != nil {
+		s.mu.Unlock()
+		r.drop(s, CloseNormal, "")
+		return nil, 0, err
+	}
+	s.conn = conn
+	s.authSession = authSession
+	s.mu.Unlock()
+	return s, replayed, nil
+}
+
+// Detach is called when the connection is closed. The session keeps recording events until it is resumed or expired.
+func (r *Registry) Detach(s *Session, conn Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.conn != conn {
+		// The session was already taken by another connection
+		return
+	}
+	s.conn = nil
+	var t *time.Timer
+	t = time.AfterFunc(r.cfg.ResumeWindow, func() {
+		s.mu.Lock()
+		if s.expire != t {
+			// Resumed before the window ended
+			s.mu.Unlock()
+			return
+		}
+		s.stop()
+		s.mu.Unlock()
+		r.release(s)
+		s.ended()
+	})
+	s.expire = t
+}
+
+// Close stops all sessions of this instance
+func (r *Registry) Close() error {
+	if r.takeover != nil {
+		_ = r.takeover.Unsubscribe()
+	}
+	if r.revoke != nil {
+		_ = r.revoke.Unsubscribe()
+	}
+	r.mu.Lock()
+	sessions := make([]*Session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		sessions = append(sessions, s)
+	}
+	r.mu.Unlock()
+	for _, s := range sessions {
+		r.drop(s, CloseNormal, "Server shutting down")
+	}
+	return nil
+}
+
+// drop stops the session, closes the attached connection with the code and removes the replay buffer
+func (r *Registry) drop(s *Session, code int, reason string) {
+	s.mu.Lock()
+	conn := s.stop()
+	s.mu.Unlock()
+	if conn != nil {
+		go conn.Close(code, reason)
+	}
+	r.release(s)
+}
+
+// release removes the stopped session from the registry and deletes its replay buffer
+func (r *Registry) release(s *Session) {
+	r.mu.Lock()
+	if r.sessions[s.id] == s {
+		delete(r.sessions, s.id)
+	}
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.cache.Delete(ctx, s.stream); err != nil {
+		r.log.Warn("unable to delete session buffer", "session_id", s.id, "error", err)
+	}
+}
+
+// takeoverRemote asks other instances to hand over the session and recreates it here.
+// The returned session is locked.
+func (r *Registry) takeoverRemote(id string, userId int64) (*Session, error) {
+	req, err := json.Marshal(takeoverRequest{SessionID: id, UserId: userId})
+	if err != nil {
+		return nil, err
+	}
+	msg, err := r.nc.Request(takeoverSubject, req, time.Second)
+	if err != nil {
+		if errors.Is(err, nats.ErrTimeout) || errors.Is(err, nats.ErrNoResponders) {
+			return nil, ErrInvalidSession
+		}
+		return nil, fmt.Errorf("unable to request session takeover: %w", err)
+	}
+	var st State
+	if err := json.Unmarshal(msg.Data, &st); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal session state: %w", err)
+	}
+	if st.UserId != userId {
+		return nil, ErrInvalidSession
+	}
+
+	s := newSession(r, id, userId, st.Stream, st.Seq)
+	s.mu.Lock()
+	r.mu.Lock()
+	old, ok := r.sessions[id]
+	r.sessions[id] = s
+	r.mu.Unlock()
+	if ok {
+		// Raced with a hello using the same ID
+		r.drop(old, CloseNormal, "Session replaced")
+	}
+	for key, topic := range st.Topics {
+		if err := s.sub.Subscribe(key, topic); err != nil {
+			r.log.Warn("unable to restore session subscription", "session_id", id, "topic", topic, "error", err)
+		}
+	}
+	return s, nil
+}
+
+// handleTakeover hands over the session if this instance owns it. Instances without the session do not reply.
+func (r *Registry) handleTakeover(msg *nats.Msg) {
+	var req takeoverRequest
+	if err := json.Unmarshal(msg.Data, &req); err != nil {
+		return
+	}
+	r.mu.Lock()
+	s, ok := r.sessions[req.SessionID]
+	if !ok || s.userId != req.UserId {
+		r.mu.Unlock()
+		return
+	}
+	delete(r.sessions, req.SessionID)
+	r.mu.Unlock()
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	st := s.state()
+	conn := s.stop()
+	s.mu.Unlock()
+	if conn != nil {
+		go conn.Close(CloseNormal, "Session resumed on another connection")
+	}
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		r.log.Error("unable to marshal session state", "session_id", s.id, "error", err)
+		return
+	}
+	if err := msg.Respond(data); err != nil {
+		r.log.Warn("unable to respond to session takeover", "session_id", s.id, "error", err)
+	}
+}
+
+// Revoke drops sessions authorized with the revoked login sessions. They are closed with CloseSessionRevoked and can not be resumed.
+func (r *Registry) Revoke(rev sessionmq.Revoke) {
+	r.mu.Lock()
+	var sessions []*Session
+	for _, s := range r.sessions {
+		if s.userId == rev.UserId {
+			sessions = append(sessions, s)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, s := range sessions {
+		s.mu.Lock()
+		revoked := rev.Matches(s.userId, s.authSession)
+		s.mu.Unlock()
+		if revoked {
+			r.drop(s, CloseSessionRevoked, "Session revoked")
+			go s.ended()
+		}
+	}
+}
+
+func (r *Registry) handleRevoke(msg *nats.Msg) {
+	var rev sessionmq.Revoke
+	if err := json.Unmarshal(msg.Data, &rev); err != nil {
+		r.log.Warn("unable to unmarshal session revoke", "error", err)
+		return
+	}
+	r.Revoke(rev)
+}
+
+func (r *Registry) streamTTL() int64 {
+	// Buffer outlives the resume window a bit, so the replay does not race with the expiry
+	return int64(r.cfg.ResumeWindow/time.Second) + 30
+}
@@ -0,0 +1,361 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/FlameInTheDark/gochat/cmd/ws/hub"
+	"github.com/FlameInTheDark/gochat/cmd/ws/subscriber"
+)
+
+// ErrInvalidSession is returned when a session can not be resumed and the client must send a fresh hello
+var ErrInvalidSession = errors.New("invalid session")
+
+// Conn is the WebSocket connection attached to a session.
+type Conn interface {
+	// Send delivers a live event. Low priority events may be dropped if the connection falls behind,
+	// other events may wait for a short time before the connection is closed.
+	Send(data []byte, p Priority)
+	// Replay delivers a buffered event and blocks until it is written.
+	Replay(data []byte) error
+	// Close sends the close frame with the code and closes the connection.
+	Close(code int, reason string)
+}
+
+// State is the part of a session handed over to another gateway instance on resume
+type State struct {
+	UserId int64             `json:"user_id"`
+	Seq    int64             `json:"seq"`
+	Stream string            `json:"stream"`
+	Topics map[string]string `json:"topics"`
+}
+
+// Session numbers the events dispatched to a gateway connection and keeps the latest of them
+// in a bounded KeyDB stream. When the connection drops, the session stays subscribed for the
+// resume window and keeps recording, so a new connection can resume it and get the missed events.
+type Session struct {
+	id     string
+	userId int64
+	stream string
+	reg    *Registry
+	sub    *subscriber.Subscriber
+
+	in   chan event
+	done chan struct{}
+	// Set when the queue overflowed with an event that can not be dropped
+	invalid atomic.Bool
+
+	mu     sync.Mutex
+	seq    int64
+	conn   Conn
+	expire *time.Timer
+	closed bool
+	// Counts the numbered event the run goroutine writes to the replay buffer. It is added to with mu held,
+	// so with mu held and after waitRecorded every event up to seq is in the buffer.
+	recording sync.WaitGroup
+	// Accessed only by the run goroutine
+	lastTouch time.Time
+	// Login session of the token the connection was authorized with
+	authSession int64
+	// Users blocked by the session user, their typing, presence and mentions are not delivered
+	blocked map[int64]struct{}
+	// Called once when the session expires or is revoked, not when it is resumed, replaced or handed over
+	onEnd func()
+}
+
+var _ hub.Conn = (*Session)(nil)
+
+type event struct {
+	data     []byte
+	priority Priority
+	// User the typing, presence or mention comes from
+	from int64
+	// User blocked or unblocked by the session user
+	blocked   int64
+	unblocked int64
+}
+
+func newSession(reg *Registry, id string, userId int64, stream string, seq int64) *Session {
+	s := &Session{
+		id:     id,
+		userId: userId,
+		stream: stream,
+		reg:    reg,
+		seq:    seq,
+		in:     make(chan event, reg.cfg.QueueSize),
+		done:   make(chan struct{}),
+	}
+	s.sub = subscriber.New(reg.hub, s)
+	go s.run()
+	return s
+}
+
+// OnEnd sets the function called when the session ends without being resumed
+func (s *Session) OnEnd(f func()) {
+	s.mu.Lock()
+	s.onEnd = f
+	s.mu.Unlock()
+}
+
+// ended calls the OnEnd function of the stopped session, at most once
+func (s *Session) ended() {
+	s.mu.Lock()
+	f := s.onEnd
+	s.onEnd = nil
+	s.mu.Unlock()
+	if f != nil {
+		f()
+	}
+}
+
+// ID returns the session ID the client uses to resume
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Subscriber returns topic subscriptions of the session
+func (s *Session) Subscriber() *subscriber.Subscriber {
+	return s.sub
+}
+
+// Send queues an event from the hub. It never blocks the hub: if the queue is full, low priority events are dropped
+// and losing any other event invalidates the session, since it can not be replayed anymore.
+func (s *Session) Send(data []byte) {
+	cp := make([]byte, len(data))
+	copy(cp, data)
+	e := parseEvent(cp)
+	select {
+	case s.in <- e:
+	default:
+		if s.reg.cfg.OnDrop != nil {
+			s.reg.cfg.OnDrop()
+		}
+		if e.priority == PriorityHigh && s.invalid.CompareAndSwap(false, true) {
+			s.reg.log.Warn("session queue overflow, invalidating session", "session_id", s.id)
+			go s.reg.drop(s, CloseInvalidSession, "Session lost events")
+		}
+	}
+}
+
+func (s *Session) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case e := <-s.in:
+			s.dispatch(e)
+		}
+	}
+}
+
+// dispatch numbers the event, records it for replay and delivers it to the attached connection.
+// Low priority events are not numbered nor replayed, so clients can rely on "s" having no gaps.
+// The sequence is assigned under the lock, the KeyDB write and the delivery happen outside of it,
+// so a slow KeyDB delays only this session's events and not the block list or resume.
+func (s *Session) dispatch(e event) {
+	s.mu.Lock()
+	if s.closed || !s.filter(e) {
+		s.mu.Unlock()
+		return
+	}
+	if e.priority == PriorityLow {
+		if s.conn != nil {
+			s.conn.Send(e.data, e.priority)
+		}
+		s.mu.Unlock()
+		return
+	}
+	s.seq++
+	seq, conn := s.seq, s.conn
+	s.recording.Add(1)
+	s.mu.Unlock()
+
+	framed := withSequence(e.data, seq)
+	s.record(seq, framed)
+	s.recording.Done()
+	// A connection that resumed meanwhile got the event from the buffer, the old one is closed
+	if conn != nil {
+		conn.Send(framed, e.priority)
+	}
+}
+
+// waitRecorded waits until the event being written to the replay buffer is written. Must be called with s.mu held.
+func (s *Session) waitRecorded() {
+	s.recording.Wait()
+}
+
+// SetBlocked replaces the users blocked by the session user
+func (s *Session) SetBlocked(ids []int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocked = make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		s.blocked[id] = struct{}{}
+	}
+}
+
+// Blocked reports whether the session user blocked the user
+func (s *Session) Blocked(userId int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.blocked[userId]
+	return ok
+}
+
+// filter applies block changes of the event and reports whether the event should be delivered.
+// Must be called with s.mu held.
+func (s *Session) filter(e event) bool {
+	if e.blocked != 0 {
+		if s.blocked == nil {
+			s.blocked = make(map[int64]struct{})
+		}
+		s.blocked[e.blocked] = struct{}{}
+	}
+	if e.unblocked != 0 {
+		delete(s.blocked, e.unblocked)
+	}
+	if e.from == 0 {
+		return true
+	}
+	_, blocked := s.blocked[e.from]
+	return !blocked
+}
+
+// record appends the event to the replay buffer. Failed appends leave a gap that makes replay fail.
+func (s *Session) record(seq int64, data []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := s.reg.cache.XAdd(ctx, s.stream, s.reg.cfg.BufferSize, false, map[string]interface{}{
+		"s": seq,
+		"e": string(data),
+	})
+	if err != nil {
+		s.reg.log.Warn("unable to record session event", "session_id", s.id, "error", err)
+		return
+	}
+	// Keep the buffer alive while the session is in use, the TTL cleans up after crashed instances
+	if time.Since(s.lastTouch) > 10*time.Second {
+		if err := s.reg.cache.SetTTL(ctx, s.stream, s.reg.streamTTL()); err != nil {
+			s.reg.log.Warn("unable to refresh session buffer ttl", "session_id", s.id, "error", err)
+		}
+		s.lastTouch = time.Now()
+	}
+}
+
+// replay sends buffered events with sequence greater than seq to conn. Must be called with s.mu held.
+func (s *Session) replay(conn Conn, seq int64) (int, error) {
+	if seq < 0 || seq > s.seq {
+		return 0, ErrInvalidSession
+	}
+	if seq == s.seq {
+		return 0, nil
+	}
+	s.waitRecorded()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	entries, err := s.reg.cache.XRange(ctx, s.stream, "-", "+")
+	if err != nil {
+		return 0, fmt.Errorf("unable to read session buffer: %w", err)
+	}
+	events, err := missedEvents(entries, seq, s.seq)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range events {
+		if err := conn.Replay(e); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
+
+// state returns the handover state, once the events up to its sequence are in the buffer.
+// Must be called with s.mu held.
+func (s *Session) state() State {
+	s.waitRecorded()
+	return State{
+		UserId: s.userId,
+		Seq:    s.seq,
+		Stream: s.stream,
+		Topics: s.sub.Topics(),
+	}
+}
+
+// stop unsubscribes the session and stops recording. Returns the connection that was attached.
+// Must be called with s.mu held.
+func (s *Session) stop() Conn {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	if s.expire != nil {
+		s.expire.Stop()
+	}
+	_ = s.sub.Close()
+	conn := s.conn
+	s.conn = nil
+	return conn
+}
+
+// missedEvents picks events after seq from the buffer entries.
+// The events must continue seq without gaps up to last, otherwise the buffer was trimmed or lost events.
+func missedEvents(entries []map[string]interface{}, seq, last int64) ([][]byte, error) {
+	var events [][]byte
+	next := seq + 1
+	for _, entry := range entries {
+		s, data, ok := parseEntry(entry)
+		if !ok {
+			return nil, ErrInvalidSession
+		}
+		if s <= seq {
+			continue
+		}
+		if s != next {
+			return nil, ErrInvalidSession
+		}
+		events = append(events, data)
+		next++
+	}
+	if next != last+1 {
+		return nil, ErrInvalidSession
+	}
+	return events, nil
+}
+
+func parseEntry(entry map[string]interface{}) (int64, []byte, bool) {
+	rawSeq, ok := entry["s"].(string)
+	if !ok {
+		return 0, nil, false
+	}
+	seq, err := strconv.ParseInt(rawSeq, 10, 64)
+	if err != nil {
+		return 0, nil, false
+	}
+	data, ok := entry["e"].(string)
+	if !ok {
+		return 0, nil, false
+	}
+	return seq, []byte(data), true
+}
+
+// withSequence adds the "s" field to a JSON object event.
+// Events that are not JSON objects are returned unchanged.
+func withSequence(data []byte, seq int64) []byte {
+	if len(data) < 2 || data[0] != '{' {
+		return data
+	}
+	out := make([]byte, 0, len(data)+24)
+	out = append(out, `{"s":`...)
+	out = strconv.AppendInt(out, seq, 10)
+	if data[1] != '}' {
+		out = append(out, ',')
+	}
+	return append(out, data[1:]...)
+}
@@ -0,0 +1,421 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FlameInTheDark/gochat/cmd/ws/hub"
+	"github.com/FlameInTheDark/gochat/internal/sessionmq"
+)
+
+type fakeStreams struct {
+	mu      sync.Mutex
+	streams map[string][]map[string]interface{}
+	// Blocks XAdd until closed, simulates a slow KeyDB
+	gate chan struct{}
+}
+
+func (f *fakeStreams) XAdd(_ context.Context, stream string, maxLen int64, _ bool, values map[string]interface{}) error {
+	if f.gate != nil {
+		<-f.gate
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// KeyDB returns all stream values as strings
+	entry := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		entry[k] = fmt.Sprint(v)
+	}
+	entries := append(f.streams[stream], entry)
+	if int64(len(entries)) > maxLen {
+		entries = entries[int64(len(entries))-maxLen:]
+	}
+	f.streams[stream] = entries
+	return nil
+}
+
+func (f *fakeStreams) XRange(_ context.Context, stream, _, _ string) ([]map[string]interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]map[string]interface{}(nil), f.streams[stream]...), nil
+}
+
+func (f *fakeStreams) SetTTL(context.Context, string, int64) error {
+	return nil
+}
+
+func (f *fakeStreams) Delete(_ context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.streams, key)
+	return nil
+}
+
+type fakeConn struct {
+	mu     sync.Mutex
+	events []string
+	code   int
+}
+
+func (c *fakeConn) Send(data []byte, _ Priority) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, string(data))
+}
+
+func (c *fakeConn) Replay(data []byte) error {
+	c.Send(data, PriorityHigh)
+	return nil
+}
+
+func (c *fakeConn) Close(code int, _ string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.code = code
+}
+
+func (c *fakeConn) received() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.events...)
+}
+
+func newTestRegistry(bufferSize int64) *Registry {
+	return &Registry{
+		hub:      hub.New(nil),
+		cache:    &fakeStreams{streams: make(map[string][]map[string]interface{})},
+		cfg:      Config{ResumeWindow: time.Minute, BufferSize: bufferSize, QueueSize: 1},
+		log:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		sessions: make(map[string]*Session),
+	}
+}
+
+func TestWithSequence(t *testing.T) {
+	cases := map[string]string{
+		`{"op":0,"t":100,"d":{}}`: `{"s":7,"op":0,"t":100,"d":{}}`,
+		`{}`:                      `{"s":7}`,
+		`[1]`:                     `[1]`,
+	}
+	for in, want := range cases {
+		if got := string(withSequence([]byte(in), 7)); got != want {
+			t.Fatalf("withSequence(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestMissedEventsDetectsTrimmedBuffer(t *testing.T) {
+	entries := []map[string]interface{}{
+		{"s": "4", "e": "d"},
+		{"s": "5", "e": "e"},
+	}
+
+	events, err := missedEvents(entries, 3, 5)
+	if err != nil || len(events) != 2 || string(events[0]) != "d" {
+		t.Fatalf("expected events 4 and 5, got %q, %v", events, err)
+	}
+	if _, err := missedEvents(entries, 2, 5); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("expected invalid session for trimmed event 3, got %v", err)
+	}
+	if _, err := missedEvents(entries, 3, 6); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("expected invalid session for unrecorded event 6, got %v", err)
+	}
+}
+
+func TestResumeReplaysMissedEvents(t *testing.T) {
+	r := newTestRegistry(10)
+	first := &fakeConn{}
+	s, err := r.Create("sess", 1, 0, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.dispatch(event{data: []byte(`{"op":0}`), priority: PriorityHigh})
+	s.dispatch(event{data: []byte(`{"op":0}`), priority: PriorityHigh})
+	r.Detach(s, first)
+	s.dispatch(event{data: []byte(`{"op":0,"t":1}`), priority: PriorityHigh})
+
+	if _, _, err := r.Resume("sess", 2, 0, 2, &fakeConn{}); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("expected another user to be rejected, got %v", err)
+	}
+
+	second := &fakeConn{}
+	resumed, replayed, err := r.Resume("sess", 1, 0, 2, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed != s || replayed != 1 {
+		t.Fatalf("expected one replayed event on the same session, got %d", replayed)
+	}
+	s.dispatch(event{data: []byte(`{"op":0,"t":301}`), priority: PriorityLow})
+	s.dispatch(event{data: []byte(`{"op":0,"t":2}`), priority: PriorityHigh})
+
+	got := second.received()
+	want := []string{`{"s":3,"op":0,"t":1}`, `{"op":0,"t":301}`, `{"s":4,"op":0,"t":2}`}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if len(first.received()) != 2 {
+		t.Fatalf("expected detached connection to get no events, got %v", first.received())
+	}
+}
+
+func TestResumeFailsWhenBufferTrimmed(t *testing.T) {
+	r := newTestRegistry(2)
+	conn := &fakeConn{}
+	s, err := r.Create("sess", 1, 0, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 4 {
+		s.dispatch(event{data: []byte(`{"op":0}`), priority: PriorityHigh})
+	}
+	r.Detach(s, conn)
+
+	if _, _, err := r.Resume("sess", 1, 0, 1, &fakeConn{}); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("expected invalid session, got %v", err)
+	}
+	if _, ok := r.sessions["sess"]; ok {
+		t.Fatal("expected invalid session to be dropped")
+	}
+}
+
+func TestEventPriority(t *testing.T) {
+	cases := map[string]Priority{
+		`{"op":0,"t":100,"d":{}}`:    PriorityHigh,
+		`{"op":0,"t":301,"d":{}}`:    PriorityLow,
+		`{"op":3,"d":{"user_id":1}}`: PriorityLow,
+		`not json`:                   PriorityHigh,
+	}
+	for in, want := range cases {
+		if got := EventPriority([]byte(in)); got != want {
+			t.Fatalf("EventPriority(%s) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestBlockedUserEventsAreFiltered(t *testing.T) {
+	r := newTestRegistry(10)
+	conn := &fakeConn{}
+	s, err := r.Create("sess", 1, 0, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetBlocked([]int64{2})
+
+	events := []string{
+		`{"op":0,"t":301,"d":{"channel_id":5,"user_id":2}}`,
+		`{"op":3,"d":{"user_id":2,"status":"online"}}`,
+		`{"op":0,"t":302,"d":{"channel_id":5,"author_id":2}}`,
+		`{"op":0,"t":301,"d":{"channel_id":5,"user_id":3}}`,
+		// Blocking user 3 hides the events that follow
+		`{"op":0,"t":409,"d":{"user":{"id":3}}}`,
+		`{"op":0,"t":301,"d":{"channel_id":5,"user_id":3}}`,
+		// Unblocking user 2 shows their events again
+		`{"op":0,"t":410,"d":{"user":{"id":2}}}`,
+		`{"op":3,"d":{"user_id":2,"status":"idle"}}`,
+	}
+	for _, e := range events {
+		s.dispatch(parseEvent([]byte(e)))
+	}
+
+	got := conn.received()
+	want := []string{
+		`{"op":0,"t":301,"d":{"channel_id":5,"user_id":3}}`,
+		`{"s":1,"op":0,"t":409,"d":{"user":{"id":3}}}`,
+		`{"s":2,"op":0,"t":410,"d":{"user":{"id":2}}}`,
+		`{"op":3,"d":{"user_id":2,"status":"idle"}}`,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if !s.Blocked(3) || s.Blocked(2) {
+		t.Fatal("expected block changes to be applied")
+	}
+}
+
+func TestQueueOverflowInvalidatesSession(t *testing.T) {
+	r := newTestRegistry(10)
+	typing := &fakeConn{}
+	s, err := r.Create("typing", 1, 0, typing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Dispatcher blocks on the lock, so the queue holds at most two events
+	s.mu.Lock()
+	for range 3 {
+		s.Send([]byte(`{"op":0,"t":301}`))
+	}
+	s.mu.Unlock()
+
+	conn := &fakeConn{}
+	s, err = r.Create("messages", 1, 0, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	for range 3 {
+		s.Send([]byte(`{"op":0,"t":100}`))
+	}
+	s.mu.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		conn.mu.Lock()
+		code := conn.code
+		conn.mu.Unlock()
+		if code == CloseInvalidSession {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected connection to be closed with %d, got %d", CloseInvalidSession, code)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if typing.code != 0 {
+		t.Fatalf("expected dropped typing events to keep the session, got close %d", typing.code)
+	}
+}
+
+func TestRevokeDropsSessionsOfRevokedLogin(t *testing.T) {
+	r := newTestRegistry(10)
+	revoked, kept, other := &fakeConn{}, &fakeConn{}, &fakeConn{}
+	if _, err := r.Create("revoked", 1, 10, revoked); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Create("kept", 1, 11, kept); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Create("other", 2, 10, other); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Revoke(sessionmq.Revoke{UserId: 1, Sessions: []int64{10}})
+	if _, ok := r.sessions["revoked"]; ok {
+		t.Fatal("expected session of the revoked login to be dropped")
+	}
+	if code := waitClosed(revoked); code != CloseSessionRevoked {
+		t.Fatalf("expected connection to be closed with %d, got %d", CloseSessionRevoked, code)
+	}
+	if len(r.sessions) != 2 {
+		t.Fatalf("expected other sessions to stay, got %d", len(r.sessions))
+	}
+
+	r.Revoke(sessionmq.Revoke{UserId: 1})
+	if _, ok := r.sessions["kept"]; ok {
+		t.Fatal("expected all sessions of the user to be dropped")
+	}
+	if _, ok := r.sessions["other"]; !ok {
+		t.Fatal("expected session of another user to stay")
+	}
+}
+
+// waitClosed waits for the asynchronous close of the connection and returns the close code
+func waitClosed(c *fakeConn) int {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		code := c.code
+		c.mu.Unlock()
+		if code != 0 {
+			return code
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return 0
+}
+
+func TestSlowBufferDoesNotHoldSessionLock(t *testing.T) {
+	r := newTestRegistry(10)
+	streams := r.cache.(*fakeStreams)
+	first := &fakeConn{}
+	s, err := r.Create("sess", 1, 0, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Detach(s, first)
+
+	streams.gate = make(chan struct{})
+	dispatched := make(chan struct{})
+	go func() {
+		s.dispatch(event{data: []byte(`{"op":0}`), priority: PriorityHigh})
+		close(dispatched)
+	}()
+	// Wait for the event to be numbered, its write is stuck in the gate
+	for {
+		s.mu.Lock()
+		seq := s.seq
+		s.mu.Unlock()
+		if seq == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	blocked := make(chan struct{})
+	go func() {
+		s.SetBlocked([]int64{5})
+		_ = s.Blocked(5)
+		close(blocked)
+	}()
+	select {
+	case <-blocked:
+	case <-time.After(time.Second):
+		t.Fatal("expected the block list to be usable while the event is written")
+	}
+
+	// Resume waits for the write, so the event is replayed instead of lost
+	resumed := make(chan int)
+	go func() {
+		_, replayed, err := r.Resume("sess", 1, 0, 0, &fakeConn{})
+		if err != nil {
+			t.Error(err)
+		}
+		resumed <- replayed
+	}()
+	close(streams.gate)
+	if replayed := <-resumed; replayed != 1 {
+		t.Fatalf("expected the written event to be replayed, got %d", replayed)
+	}
+	<-dispatched
+}
+
+func TestSessionEndsOnlyWhenNotResumed(t *testing.T) {
+	r := newTestRegistry(10)
+	r.cfg.ResumeWindow = time.Millisecond * 20
+	conn := &fakeConn{}
+	s, err := r.Create("s", 1, 10, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ends := make(chan struct{}, 2)
+	s.OnEnd(func() { ends <- struct{}{} })
+
+	r.Detach(s, conn)
+	resumed := &fakeConn{}
+	if _, _, err := r.Resume("s", 1, 10, 0, resumed); err != nil {
+		t.Fatalf("resume returned error: %v", err)
+	}
+	time.Sleep(time.Millisecond * 50)
+	select {
+	case <-ends:
+		t.Fatal("expected resumed session not to end")
+	default:
+	}
+
+	r.Detach(s, resumed)
+	select {
+	case <-ends:
+	case <-time.After(time.Second):
+		t.Fatal("expected session to end after the resume window")
+	}
+	select {
+	case <-ends:
+		t.Fatal("expected session to end once")
+	case <-time.After(time.Millisecond * 50):
+	}
+}
@@ -67,3 +67,14 @@ func (s *Subscriber) Close() error {
 	s.topics = make(map[string]string)
 	return nil
 }
+
+// Topics returns a copy of the current subscriptions by key.
+func (s *Subscriber) Topics() map[string]string {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	topics := make(map[string]string, len(s.topics))
+	for k, t := range s.topics {
+		topics[k] = t
+	}
+	return topics
+}
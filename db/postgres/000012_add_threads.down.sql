@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS thread_members;
+DROP TABLE IF EXISTS threads;
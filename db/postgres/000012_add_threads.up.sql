@@ -0,0 +1,27 @@
+CREATE TABLE threads
+(
+    id                   BIGINT      NOT NULL,
+    guild_id             BIGINT      NOT NULL,
+    parent_id            BIGINT      NOT NULL,
+    owner_id             BIGINT      NOT NULL,
+    message_id           BIGINT,
+    archived             BOOL        NOT NULL DEFAULT false,
+    auto_archive_minutes INTEGER     NOT NULL DEFAULT 1440,
+    last_activity_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    archived_at          TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (guild_id, id)
+);
+CREATE INDEX idx_threads_id ON threads (id);
+CREATE INDEX idx_threads_parent ON threads (parent_id, archived);
+CREATE INDEX idx_threads_activity ON threads (last_activity_at) WHERE NOT archived;
+SELECT create_distributed_table('threads', 'guild_id', colocate_with => 'guilds');
+
+CREATE TABLE thread_members
+(
+    thread_id BIGINT      NOT NULL,
+    user_id   BIGINT      NOT NULL,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (thread_id, user_id)
+);
+SELECT create_distributed_table('thread_members', 'thread_id');
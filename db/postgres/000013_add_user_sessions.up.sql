@@ -0,0 +1,14 @@
+CREATE TABLE user_sessions
+(
+    id           BIGINT      NOT NULL,
+    user_id      BIGINT      NOT NULL,
+    refresh_jti  TEXT        NOT NULL,
+    device       TEXT        NOT NULL DEFAULT '',
+    ip           TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    revoked_at   TIMESTAMPTZ,
+    PRIMARY KEY (user_id, id)
+);
+SELECT create_distributed_table('user_sessions', 'user_id');
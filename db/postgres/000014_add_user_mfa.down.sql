@@ -0,0 +1,4 @@
+ALTER TABLE guilds
+    DROP COLUMN IF EXISTS mfa_required;
+DROP TABLE IF EXISTS user_recovery_codes;
+DROP TABLE IF EXISTS user_mfa;
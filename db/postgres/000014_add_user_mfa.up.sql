@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS user_mfa
+(
+    user_id     BIGINT      NOT NULL,
+    totp_secret TEXT        NOT NULL,
+    last_step   BIGINT      NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    enabled_at  TIMESTAMPTZ,
+    PRIMARY KEY (user_id)
+);
+SELECT create_distributed_table('user_mfa', 'user_id');
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes
+(
+    user_id   BIGINT      NOT NULL,
+    code_hash TEXT        NOT NULL,
+    used_at   TIMESTAMPTZ,
+    PRIMARY KEY (user_id, code_hash)
+);
+SELECT create_distributed_table('user_recovery_codes', 'user_id', colocate_with => 'user_mfa');
+
+ALTER TABLE guilds
+    ADD COLUMN IF NOT EXISTS mfa_required BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+ALTER TABLE roles
+    DROP COLUMN IF EXISTS managed_by;
+DROP TABLE IF EXISTS applications;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS bot;
@@ -0,0 +1,18 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS applications
+(
+    id          BIGINT      NOT NULL,
+    owner_id    BIGINT      NOT NULL,
+    name        TEXT        NOT NULL,
+    description TEXT        NOT NULL DEFAULT '',
+    token_hash  TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_applications_owner_id ON applications (owner_id);
+SELECT create_distributed_table('applications', 'id');
+
+ALTER TABLE roles
+    ADD COLUMN IF NOT EXISTS managed_by BIGINT;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    id         BIGINT      NOT NULL,
+    guild_id   BIGINT      NOT NULL,
+    channel_id BIGINT      NOT NULL,
+    creator_id BIGINT      NOT NULL,
+    name       TEXT        NOT NULL,
+    avatar_url TEXT,
+    token_hash TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_channel_id ON webhooks (channel_id);
+SELECT create_distributed_table('webhooks', 'id');
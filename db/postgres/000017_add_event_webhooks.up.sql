@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS event_webhooks
+(
+    guild_id      BIGINT      NOT NULL,
+    id            BIGINT      NOT NULL,
+    creator_id    BIGINT      NOT NULL,
+    url           TEXT        NOT NULL,
+    secret        TEXT        NOT NULL,
+    events        BIGINT[]    NOT NULL,
+    enabled       BOOLEAN     NOT NULL DEFAULT true,
+    failure_count INTEGER     NOT NULL DEFAULT 0,
+    disabled_at   TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (guild_id, id)
+);
+SELECT create_distributed_table('event_webhooks', 'guild_id');
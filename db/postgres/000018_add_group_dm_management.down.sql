@@ -0,0 +1,3 @@
+-- The composite primary key of group_dm_channels is kept, the original
+-- single column key can't hold more than one participant per channel.
+DROP TABLE IF EXISTS group_dms;
@@ -0,0 +1,11 @@
+ALTER TABLE group_dm_channels DROP CONSTRAINT IF EXISTS group_dm_channels_pkey;
+ALTER TABLE group_dm_channels ADD PRIMARY KEY (channel_id, user_id);
+
+CREATE TABLE IF NOT EXISTS group_dms
+(
+    channel_id BIGINT NOT NULL,
+    owner_id   BIGINT NOT NULL,
+    icon       BIGINT,
+    PRIMARY KEY (channel_id)
+);
+SELECT create_distributed_table('group_dms', 'channel_id', colocate_with => 'group_dm_channels');
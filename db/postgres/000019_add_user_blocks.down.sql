@@ -0,0 +1,3 @@
+-- The composite primary key of blocked_users is kept, the original
+-- single column key can't hold more than one blocked user per user.
+ALTER TABLE blocked_users DROP COLUMN IF EXISTS created_at;
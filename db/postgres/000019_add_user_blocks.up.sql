@@ -0,0 +1,3 @@
+ALTER TABLE blocked_users DROP CONSTRAINT IF EXISTS blocked_users_pkey;
+ALTER TABLE blocked_users ADD PRIMARY KEY (user_id, blocked_user_id);
+ALTER TABLE blocked_users ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
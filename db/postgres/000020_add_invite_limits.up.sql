@@ -0,0 +1,119 @@
+ALTER TABLE guild_invites
+    ADD COLUMN IF NOT EXISTS max_uses  INT     NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS uses      INT     NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS temporary BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE members
+    ADD COLUMN IF NOT EXISTS invite_code VARCHAR(8),
+    ADD COLUMN IF NOT EXISTS temporary   BOOLEAN NOT NULL DEFAULT false;
+
+-- Active (non-expired and not used up) invites view, max_uses = 0 means unlimited
+CREATE OR REPLACE VIEW active_guild_invites AS
+SELECT *
+FROM guild_invites
+WHERE expires_at > now()
+  AND (max_uses = 0 OR uses < max_uses);
+
+-- The result columns changed, the function has to be recreated
+DROP FUNCTION IF EXISTS fetch_guild_invite(varchar);
+
+-- Lookup function: resolve code, purge if expired or used up, return if valid
+CREATE OR REPLACE FUNCTION fetch_guild_invite(p_code varchar)
+    RETURNS TABLE (
+        invite_code varchar(8),
+        invite_id   bigint,
+        guild_id    bigint,
+        author_id   bigint,
+        created_at  timestamptz,
+        expires_at  timestamptz,
+        max_uses    int,
+        uses        int,
+        temporary   boolean
+        )
+    LANGUAGE plpgsql
+        SECURITY DEFINER
+SET search_path = public, pg_temp
+    AS $$
+DECLARE
+  v_invite_id bigint;
+  v_guild_id  bigint;
+  v_deleted   integer;
+BEGIN
+  -- Resolve code (single-shard on mapper)
+  SELECT ic.invite_id, ic.guild_id
+    INTO v_invite_id, v_guild_id
+    FROM guild_invite_codes ic
+   WHERE ic.invite_code = p_code;
+
+  IF NOT FOUND THEN
+    RETURN; -- no such code
+  END IF;
+
+  -- TTL and max uses delete on the guild shard
+  DELETE FROM guild_invites gi
+   WHERE gi.guild_id  = v_guild_id
+     AND gi.invite_id = v_invite_id
+     AND (gi.expires_at <= now() OR (gi.max_uses > 0 AND gi.uses >= gi.max_uses));
+  GET DIAGNOSTICS v_deleted = ROW_COUNT;
+
+  IF v_deleted > 0 THEN
+    -- Remove orphaned mapper row
+    DELETE FROM guild_invite_codes
+     WHERE invite_code = p_code;
+    RETURN; -- expired or used up and removed
+  END IF;
+
+  -- Return valid invite
+  RETURN QUERY
+  SELECT p_code, gi.invite_id, gi.guild_id, gi.author_id, gi.created_at, gi.expires_at, gi.max_uses, gi.uses, gi.temporary
+    FROM guild_invites gi
+   WHERE gi.guild_id  = v_guild_id
+     AND gi.invite_id = v_invite_id
+     AND gi.expires_at > now();
+END;
+$$;
+
+-- Use function: resolve code and count one use if the invite is still valid.
+-- The update locks the invite row, concurrent uses of the last slot can't both succeed.
+CREATE OR REPLACE FUNCTION use_guild_invite(p_code varchar)
+    RETURNS TABLE (
+        invite_code varchar(8),
+        invite_id   bigint,
+        guild_id    bigint,
+        author_id   bigint,
+        created_at  timestamptz,
+        expires_at  timestamptz,
+        max_uses    int,
+        uses        int,
+        temporary   boolean
+        )
+    LANGUAGE plpgsql
+        SECURITY DEFINER
+SET search_path = public, pg_temp
+    AS $$
+DECLARE
+  v_invite_id bigint;
+  v_guild_id  bigint;
+BEGIN
+  SELECT ic.invite_id, ic.guild_id
+    INTO v_invite_id, v_guild_id
+    FROM guild_invite_codes ic
+   WHERE ic.invite_code = p_code;
+
+  IF NOT FOUND THEN
+    RETURN; -- no such code
+  END IF;
+
+  RETURN QUERY
+  UPDATE guild_invites gi
+     SET uses = gi.uses + 1
+   WHERE gi.guild_id  = v_guild_id
+     AND gi.invite_id = v_invite_id
+     AND gi.expires_at > now()
+     AND (gi.max_uses = 0 OR gi.uses < gi.max_uses)
+  RETURNING p_code, gi.invite_id, gi.guild_id, gi.author_id, gi.created_at, gi.expires_at, gi.max_uses, gi.uses, gi.temporary;
+END;
+$$;
+
+-- Optional: grant execute to your app role
+-- GRANT EXECUTE ON FUNCTION use_guild_invite(varchar) TO your_app_role;
@@ -0,0 +1,12 @@
+-- Vanity codes don't fit the invite code column anymore
+UPDATE members SET invite_code = NULL WHERE length(invite_code) > 8;
+ALTER TABLE members ALTER COLUMN invite_code TYPE VARCHAR(8);
+
+DROP TABLE IF EXISTS guild_vanity_codes;
+
+DROP INDEX IF EXISTS idx_guilds_public_category;
+
+ALTER TABLE guilds
+    DROP COLUMN IF EXISTS vanity_code,
+    DROP COLUMN IF EXISTS category,
+    DROP COLUMN IF EXISTS description;
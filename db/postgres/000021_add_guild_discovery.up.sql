@@ -0,0 +1,19 @@
+ALTER TABLE guilds
+    ADD COLUMN IF NOT EXISTS description TEXT,
+    ADD COLUMN IF NOT EXISTS category    SMALLINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS vanity_code VARCHAR(32);
+
+CREATE INDEX IF NOT EXISTS idx_guilds_public_category ON guilds (public, category);
+
+-- Vanity codes are unique across guilds, the mapper resolves a code to its guild
+CREATE TABLE IF NOT EXISTS guild_vanity_codes
+(
+    vanity_code VARCHAR(32) NOT NULL,
+    guild_id    BIGINT      NOT NULL,
+    PRIMARY KEY (vanity_code)
+);
+CREATE INDEX IF NOT EXISTS idx_guild_vanity_codes_guild ON guild_vanity_codes (guild_id);
+SELECT create_distributed_table('guild_vanity_codes', 'vanity_code');
+
+-- Members joined with a vanity code store it as their invite code
+ALTER TABLE members ALTER COLUMN invite_code TYPE VARCHAR(32);
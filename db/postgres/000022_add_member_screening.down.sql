@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_members_pending;
+
+ALTER TABLE members DROP COLUMN IF EXISTS pending;
+
+ALTER TABLE guilds
+    DROP COLUMN IF EXISTS screening_enabled,
+    DROP COLUMN IF EXISTS rules,
+    DROP COLUMN IF EXISTS verification_level;
@@ -0,0 +1,8 @@
+ALTER TABLE guilds
+    ADD COLUMN IF NOT EXISTS verification_level SMALLINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rules              TEXT,
+    ADD COLUMN IF NOT EXISTS screening_enabled  BOOLEAN  NOT NULL DEFAULT false;
+
+-- Members who haven't accepted the rules of a guild with screening enabled
+ALTER TABLE members ADD COLUMN IF NOT EXISTS pending BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS idx_members_pending ON members (guild_id, user_id) WHERE pending;
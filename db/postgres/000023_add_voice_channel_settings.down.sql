@@ -0,0 +1,4 @@
+ALTER TABLE channels
+    DROP COLUMN IF EXISTS voice_stage,
+    DROP COLUMN IF EXISTS voice_bitrate,
+    DROP COLUMN IF EXISTS voice_user_limit;
@@ -0,0 +1,5 @@
+-- Voice channel settings, 0 means the SFU default for the limit and the bitrate
+ALTER TABLE channels
+    ADD COLUMN IF NOT EXISTS voice_user_limit INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS voice_bitrate    INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS voice_stage      BOOLEAN NOT NULL DEFAULT false;
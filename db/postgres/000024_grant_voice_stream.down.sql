@@ -0,0 +1,2 @@
+-- The granted PermVoiceStream bits can not be told apart from the ones set later, they are kept.
+SELECT 1;
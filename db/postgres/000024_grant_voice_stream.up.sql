@@ -0,0 +1,24 @@
+-- PermVoiceStream (1 << 30) was split from PermVoiceVideo (1 << 22) for screen sharing.
+-- Grant and deny it wherever video is granted or denied, so existing guilds keep screen sharing as before.
+UPDATE guilds
+SET permissions = permissions | 1073741824
+WHERE permissions & 4194304 <> 0;
+
+UPDATE roles
+SET permissions = permissions | 1073741824
+WHERE permissions & 4194304 <> 0;
+
+UPDATE channels
+SET permissions = permissions | 1073741824
+WHERE permissions IS NOT NULL
+  AND permissions & 4194304 <> 0;
+
+UPDATE channel_roles_permissions
+SET accept = CASE WHEN accept & 4194304 <> 0 THEN accept | 1073741824 ELSE accept END,
+    deny   = CASE WHEN deny & 4194304 <> 0 THEN deny | 1073741824 ELSE deny END
+WHERE (accept | deny) & 4194304 <> 0;
+
+UPDATE channel_user_permissions
+SET accept = CASE WHEN accept & 4194304 <> 0 THEN accept | 1073741824 ELSE accept END,
+    deny   = CASE WHEN deny & 4194304 <> 0 THEN deny | 1073741824 ELSE deny END
+WHERE (accept | deny) & 4194304 <> 0;
@@ -0,0 +1,9 @@
+-- Time the email of the account was confirmed, checked by the guild verification levels
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+-- Accounts with a password were created by a confirmed registration
+UPDATE users u
+SET email_verified_at = a.created_at
+FROM authentications a
+WHERE a.user_id = u.id
+  AND u.email_verified_at IS NULL;
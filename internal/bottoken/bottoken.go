@@ -0,0 +1,110 @@
+// Package bottoken issues and checks long-lived bot tokens. A token is the bot user ID and a random secret
+// separated by a dot, only the SHA-256 hash of the token is stored.
+package bottoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/FlameInTheDark/gochat/internal/cache"
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+const (
+	secretSize = 32
+	// Seconds the token hash of the bot stays in the cache
+	cacheTTL = 300
+)
+
+var ErrInvalidToken = errors.New("invalid bot token")
+
+// Generate returns a new token of the bot and its hash
+func Generate(botId int64) (token, hash string, err error) {
+	secret := make([]byte, secretSize)
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	token = strconv.FormatInt(botId, 10) + "." + base64.RawURLEncoding.EncodeToString(secret)
+	return token, Hash(token), nil
+}
+
+// Hash returns the hex encoded SHA-256 hash of the token
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Parse returns the bot user ID of the token without checking the secret
+func Parse(token string) (int64, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return 0, ErrInvalidToken
+	}
+	botId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || botId <= 0 {
+		return 0, ErrInvalidToken
+	}
+	return botId, nil
+}
+
+type applications interface {
+	GetApplication(ctx context.Context, id int64) (model.Application, error)
+}
+
+// Authenticator checks bot tokens against the stored hashes of the applications
+type Authenticator struct {
+	apps  applications
+	cache cache.Cache
+}
+
+func NewAuthenticator(apps applications, cache cache.Cache) *Authenticator {
+	return &Authenticator{apps: apps, cache: cache}
+}
+
+// Authenticate returns the bot user ID of a valid token
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (int64, error) {
+	botId, err := Parse(token)
+	if err != nil {
+		return 0, err
+	}
+	hash, err := a.tokenHash(ctx, botId)
+	if err != nil {
+		return 0, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(Hash(token))) != 1 {
+		return 0, ErrInvalidToken
+	}
+	return botId, nil
+}
+
+// Forget drops the cached token hash, must be called after the token was reset or the application removed
+func (a *Authenticator) Forget(ctx context.Context, botId int64) error {
+	return a.cache.Delete(ctx, cacheKey(botId))
+}
+
+func (a *Authenticator) tokenHash(ctx context.Context, botId int64) (string, error) {
+	if hash, err := a.cache.Get(ctx, cacheKey(botId)); err == nil && hash != "" {
+		return hash, nil
+	}
+	app, err := a.apps.GetApplication(ctx, botId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvalidToken
+	} else if err != nil {
+		return "", err
+	}
+	_ = a.cache.SetTimed(ctx, cacheKey(botId), app.TokenHash, cacheTTL)
+	return app.TokenHash, nil
+}
+
+func cacheKey(botId int64) string {
+	return fmt.Sprintf("bot:%d:token", botId)
+}
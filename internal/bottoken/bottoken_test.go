@@ -0,0 +1,100 @@
+package bottoken
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/FlameInTheDark/gochat/internal/cache"
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type fakeApps struct {
+	apps  map[int64]model.Application
+	calls int
+}
+
+func (f *fakeApps) GetApplication(_ context.Context, id int64) (model.Application, error) {
+	f.calls++
+	app, ok := f.apps[id]
+	if !ok {
+		return app, sql.ErrNoRows
+	}
+	return app, nil
+}
+
+type fakeCache struct {
+	cache.Cache
+	values map[string]string
+}
+
+func (f *fakeCache) Get(_ context.Context, key string) (string, error) {
+	v, ok := f.values[key]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return v, nil
+}
+
+func (f *fakeCache) SetTimed(_ context.Context, key, val string, _ int64) error {
+	f.values[key] = val
+	return nil
+}
+
+func (f *fakeCache) Delete(_ context.Context, key string) error {
+	delete(f.values, key)
+	return nil
+}
+
+func TestParse(t *testing.T) {
+	token, hash, err := Generate(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != Hash(token) {
+		t.Fatal("expected hash of the generated token")
+	}
+	if id, err := Parse(token); err != nil || id != 42 {
+		t.Fatalf("expected bot 42, got %d, %v", id, err)
+	}
+	for _, bad := range []string{"", "42", "42.", "abc.def", "-1.def"} {
+		if _, err := Parse(bad); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("expected %q to be rejected, got %v", bad, err)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	token, hash, _ := Generate(42)
+	apps := &fakeApps{apps: map[int64]model.Application{42: {Id: 42, TokenHash: hash}}}
+	a := NewAuthenticator(apps, &fakeCache{values: map[string]string{}})
+	ctx := context.Background()
+
+	for range 2 {
+		if id, err := a.Authenticate(ctx, token); err != nil || id != 42 {
+			t.Fatalf("expected bot 42, got %d, %v", id, err)
+		}
+	}
+	if apps.calls != 1 {
+		t.Fatalf("expected token hash to be cached, got %d lookups", apps.calls)
+	}
+	if _, err := a.Authenticate(ctx, "42.wrong"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected wrong secret to be rejected, got %v", err)
+	}
+	if _, err := a.Authenticate(ctx, "7.secret"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected unknown bot to be rejected, got %v", err)
+	}
+
+	reset, resetHash, _ := Generate(42)
+	apps.apps[42] = model.Application{Id: 42, TokenHash: resetHash}
+	if err := a.Forget(ctx, 42); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected old token to be rejected after reset, got %v", err)
+	}
+	if _, err := a.Authenticate(ctx, reset); err != nil {
+		t.Fatal(err)
+	}
+}
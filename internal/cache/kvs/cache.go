@@ -207,3 +207,17 @@ func (c *Cache) XAdd(ctx context.Context, stream string, maxLen int64, approx bo
 	}
 	return nil
 }
+
+// XRange returns values of the stream entries between start and stop IDs in insertion order.
+// Use "-" and "+" to read the whole stream.
+func (c *Cache) XRange(ctx context.Context, stream, start, stop string) ([]map[string]interface{}, error) {
+	h := c.c.XRange(ctx, stream, start, stop)
+	if h.Err() != nil {
+		return nil, h.Err()
+	}
+	entries := make([]map[string]interface{}, 0, len(h.Val()))
+	for _, m := range h.Val() {
+		entries = append(entries, m.Values)
+	}
+	return entries, nil
+}
@@ -9,11 +9,11 @@ import (
 )
 
 type Audit interface {
-	AddAuditRecord(ctx context.Context, guildID int64, changes map[string]string) error
+	AddAuditRecord(ctx context.Context, record model.Audit) error
 	RemoveAuditRecordsByGuildId(ctx context.Context, guildID int64) error
-	RemoveRecordsBefore(ctx context.Context, before time.Time) error
-	GetLastRecords(ctx context.Context, guild int64) ([]model.Audit, error)
-	GetRecordsBefore(ctx context.Context, guild int64, before time.Time) ([]model.Audit, error)
+	RemoveRecordsBefore(ctx context.Context, guildID int64, before time.Time) error
+	GetRecordsBefore(ctx context.Context, guildID, before int64, limit int, actorID *int64, action *model.AuditActionType) ([]model.Audit, error)
+	GetAuditGuilds(ctx context.Context) ([]int64, error)
 }
 
 type Entity struct {
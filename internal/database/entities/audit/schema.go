@@ -2,25 +2,33 @@ package audit
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/FlameInTheDark/gochat/internal/database/model"
+	"github.com/FlameInTheDark/gochat/internal/idgen"
 )
 
 const (
-	addAuditRecord         = `INSERT INTO gochat.audit_log (guild_id, created_at, changes) VALUES (?, toTimestamp(now()), ?)`
+	addAuditRecord         = `INSERT INTO gochat.audit_log (guild_id, id, actor_id, action, target_id, changes, reason) VALUES (?, ?, ?, ?, ?, ?, ?)`
 	removeRecordsByGuildId = `DELETE FROM gochat.audit_log WHERE guild_id = ?`
-	removeRecordsBefore    = `DELETE FROM gochat.audit_log WHERE created_at < ?`
-	getLastRecords         = `SELECT guild_id, created_at, changes FROM gochat.audit_log WHERE guild_id = ? ORDER BY created_at DESC LIMIT 10`
-	getRecordsBefore       = `SELECT guild_id, created_at, changes FROM gochat.audit_log WHERE guild_id = ? AND created_at < ? ORDER BY created_at DESC LIMIT 10`
+	removeRecordsBefore    = `DELETE FROM gochat.audit_log WHERE guild_id = ? AND id < ?`
+	getRecordsBefore       = `SELECT guild_id, id, actor_id, action, target_id, changes, reason FROM gochat.audit_log WHERE guild_id = ? AND id < ?`
+	getAuditGuilds         = `SELECT DISTINCT guild_id FROM gochat.audit_log`
 )
 
-func (e *Entity) AddAuditRecord(ctx context.Context, guildID int64, changes map[string]string) error {
-	err := e.c.Session().
+func (e *Entity) AddAuditRecord(ctx context.Context, record model.Audit) error {
+	changes, err := json.Marshal(record.Changes)
+	if err != nil {
+		return fmt.Errorf("unable to marshal audit changes: %w", err)
+	}
+	err = e.c.Session().
 		Query(addAuditRecord).
 		WithContext(ctx).
-		Bind(guildID, time.Now(), changes).
+		Bind(record.GuildId, record.Id, record.ActorId, int(record.Action), record.TargetId, string(changes), record.Reason).
 		Exec()
 	if err != nil {
 		return fmt.Errorf("unable to add audit record: %w", err)
@@ -40,11 +48,13 @@ func (e *Entity) RemoveAuditRecordsByGuildId(ctx context.Context, guildID int64)
 	return nil
 }
 
-func (e *Entity) RemoveRecordsBefore(ctx context.Context, before time.Time) error {
+// RemoveRecordsBefore deletes guild records created before the given time.
+// Record IDs are snowflakes, so the time is converted to the smallest ID generated at that moment.
+func (e *Entity) RemoveRecordsBefore(ctx context.Context, guildID int64, before time.Time) error {
 	err := e.c.Session().
 		Query(removeRecordsBefore).
 		WithContext(ctx).
-		Bind(before).
+		Bind(guildID, idgen.FromTime(before)).
 		Exec()
 	if err != nil {
 		return fmt.Errorf("unable to remove records: %w", err)
@@ -52,38 +62,78 @@ func (e *Entity) RemoveRecordsBefore(ctx context.Context, before time.Time) erro
 	return nil
 }
 
-func (e *Entity) GetLastRecords(ctx context.Context, guild int64) ([]model.Audit, error) {
-	var audits []model.Audit
+// GetRecordsBefore returns up to limit newest guild records with ID lower than before.
+// Zero before starts from the latest record. Actor and action filters are applied inside the guild partition.
+func (e *Entity) GetRecordsBefore(ctx context.Context, guildID, before int64, limit int, actorID *int64, action *model.AuditActionType) ([]model.Audit, error) {
+	if before <= 0 {
+		before = math.MaxInt64
+	}
+
+	var sb strings.Builder
+	sb.WriteString(getRecordsBefore)
+	args := []interface{}{guildID, before}
+	if actorID != nil {
+		sb.WriteString(" AND actor_id = ?")
+		args = append(args, *actorID)
+	}
+	if action != nil {
+		sb.WriteString(" AND action = ?")
+		args = append(args, int(*action))
+	}
+	sb.WriteString(" LIMIT ?")
+	args = append(args, limit)
+	if actorID != nil || action != nil {
+		sb.WriteString(" ALLOW FILTERING")
+	}
+
 	iter := e.c.Session().
-		Query(getLastRecords).
+		Query(sb.String()).
 		WithContext(ctx).
-		Bind(guild).
+		Bind(args...).
 		Iter()
-	var a model.Audit
-	for iter.Scan(&a.GuildId, &a.CreatedAt, &a.Changes) {
+
+	var (
+		audits     []model.Audit
+		a          model.Audit
+		actionType int
+		changes    string
+	)
+	for iter.Scan(&a.GuildId, &a.Id, &a.ActorId, &actionType, &a.TargetId, &changes, &a.Reason) {
+		a.Action = model.AuditActionType(actionType)
+		if changes != "" {
+			// Keep numbers as json.Number so snowflake IDs do not lose precision
+			dec := json.NewDecoder(strings.NewReader(changes))
+			dec.UseNumber()
+			if err := dec.Decode(&a.Changes); err != nil {
+				_ = iter.Close()
+				return nil, fmt.Errorf("unable to unmarshal audit changes: %w", err)
+			}
+		}
 		audits = append(audits, a)
+		a = model.Audit{}
 	}
-	err := iter.Close()
-	if err != nil {
+	if err := iter.Close(); err != nil {
 		return nil, fmt.Errorf("unable to get audit records: %w", err)
 	}
 	return audits, nil
 }
 
-func (e *Entity) GetRecordsBefore(ctx context.Context, guild int64, before time.Time) ([]model.Audit, error) {
-	var audits []model.Audit
+// GetAuditGuilds returns IDs of all guilds that have audit records
+func (e *Entity) GetAuditGuilds(ctx context.Context) ([]int64, error) {
 	iter := e.c.Session().
-		Query(getRecordsBefore).
+		Query(getAuditGuilds).
 		WithContext(ctx).
-		Bind(guild).
 		Iter()
-	var a model.Audit
-	for iter.Scan(&a.GuildId, &a.CreatedAt, &a.Changes) {
-		audits = append(audits, a)
+
+	var (
+		guilds []int64
+		id     int64
+	)
+	for iter.Scan(&id) {
+		guilds = append(guilds, id)
 	}
-	err := iter.Close()
-	if err != nil {
-		return nil, fmt.Errorf("unable to get audit records: %w", err)
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("unable to get audit guilds: %w", err)
 	}
-	return audits, nil
+	return guilds, nil
 }
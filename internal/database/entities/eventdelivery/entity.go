@@ -0,0 +1,22 @@
+package eventdelivery
+
+import (
+	"context"
+
+	"github.com/FlameInTheDark/gochat/internal/database/db"
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type EventDelivery interface {
+	AddDelivery(ctx context.Context, delivery model.EventWebhookDelivery) error
+	GetDeliveriesBefore(ctx context.Context, webhookId, before int64, limit int) ([]model.EventWebhookDelivery, error)
+	RemoveDeliveries(ctx context.Context, webhookId int64) error
+}
+
+type Entity struct {
+	c *db.CQLCon
+}
+
+func New(c *db.CQLCon) *Entity {
+	return &Entity{c: c}
+}
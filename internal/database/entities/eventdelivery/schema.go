@@ -0,0 +1,67 @@
+package eventdelivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gocql/gocql"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+// Records expire with the default TTL of the table
+const (
+	addDelivery         = `INSERT INTO gochat.event_webhook_deliveries (webhook_id, id, event_id, event_type, attempt, status_code, error, duration_ms) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	getDeliveriesBefore = `SELECT webhook_id, id, event_id, event_type, attempt, status_code, error, duration_ms FROM gochat.event_webhook_deliveries WHERE webhook_id = ? AND id < ? LIMIT ?`
+	removeDeliveries    = `DELETE FROM gochat.event_webhook_deliveries WHERE webhook_id = ?`
+)
+
+func (e *Entity) AddDelivery(ctx context.Context, d model.EventWebhookDelivery) error {
+	err := e.c.Session().
+		Query(addDelivery).
+		WithContext(ctx).
+		Bind(d.WebhookId, d.Id, d.EventId, d.EventType, d.Attempt, d.StatusCode, d.Error, d.DurationMs).
+		Exec()
+	if err != nil {
+		return fmt.Errorf("unable to add event webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// GetDeliveriesBefore returns up to limit newest deliveries with ID lower than before.
+// Zero before starts from the latest delivery.
+func (e *Entity) GetDeliveriesBefore(ctx context.Context, webhookId, before int64, limit int) ([]model.EventWebhookDelivery, error) {
+	if before <= 0 {
+		before = math.MaxInt64
+	}
+	var deliveries []model.EventWebhookDelivery
+	iter := e.c.Session().
+		Query(getDeliveriesBefore).
+		WithContext(ctx).
+		Bind(webhookId, before, limit).
+		Iter()
+	var d model.EventWebhookDelivery
+	for iter.Scan(&d.WebhookId, &d.Id, &d.EventId, &d.EventType, &d.Attempt, &d.StatusCode, &d.Error, &d.DurationMs) {
+		deliveries = append(deliveries, d)
+		d = model.EventWebhookDelivery{}
+	}
+	err := iter.Close()
+	if err != nil && !errors.Is(err, gocql.ErrNotFound) {
+		return nil, fmt.Errorf("unable to get event webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (e *Entity) RemoveDeliveries(ctx context.Context, webhookId int64) error {
+	err := e.c.Session().
+		Query(removeDeliveries).
+		WithContext(ctx).
+		Bind(webhookId).
+		Exec()
+	if err != nil {
+		return fmt.Errorf("unable to remove event webhook deliveries: %w", err)
+	}
+	return nil
+}
@@ -8,10 +8,15 @@ import (
 )
 
 type Message interface {
-	CreateMessage(ctx context.Context, id, channelID, userID int64, content string, attachments []int64, embedsJSON, autoEmbedsJSON string) error
-	CreateSystemMessage(ctx context.Context, id, channelId, userId int64, content string, msgType model.MessageType) error
+	CreateMessage(ctx context.Context, id, channelID, userID, reference int64, content string, attachments []int64, embedsJSON, autoEmbedsJSON string) error
+	CreateWebhookMessage(ctx context.Context, id, channelID, webhookID, reference int64, content, embedsJSON, autoEmbedsJSON, authorName string, authorAvatar *string) error
+	CreateSystemMessage(ctx context.Context, id, channelId, userId, reference int64, content string, msgType model.MessageType) error
+	CreateSystemMessageWithAttachments(ctx context.Context, id, channelID, userID int64, content string, attachments []int64, msgType model.MessageType) error
 	UpdateMessage(ctx context.Context, id, channelID int64, content, embedsJSON, autoEmbedsJSON string, flags int) error
 	UpdateGeneratedEmbeds(ctx context.Context, id, channelID int64, autoEmbedsJSON string) error
+	SetMessageThread(ctx context.Context, id, channelID, threadID int64) error
+	ClaimMessageThread(ctx context.Context, id, channelID, threadID int64) (bool, error)
+	SetMessageFlags(ctx context.Context, id, channelID int64, flags int) error
 	DeleteMessage(ctx context.Context, id, channelId int64) error
 	DeleteChannelMessages(ctx context.Context, channelID, lastId int64) error
 	GetMessage(ctx context.Context, id, channelId int64) (model.Message, error)
@@ -11,25 +11,53 @@ import (
 )
 
 const (
-	createMessage         = `INSERT INTO gochat.messages (channel_id, bucket, id, user_id, content, attachments, embeds, auto_embeds, flags, type) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0)`
-	createSystemMessage   = `INSERT INTO gochat.messages (channel_id, bucket, id, user_id, content, flags, type) VALUES (?, ?, ?, ?, ?, 0, ?)`
+	createMessage         = `INSERT INTO gochat.messages (channel_id, bucket, id, user_id, content, attachments, embeds, auto_embeds, flags, type, reference) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	createWebhookMessage  = `INSERT INTO gochat.messages (channel_id, bucket, id, user_id, content, embeds, auto_embeds, flags, type, reference, author_name, author_avatar) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	createSystemMessage   = `INSERT INTO gochat.messages (channel_id, bucket, id, user_id, content, flags, type, reference) VALUES (?, ?, ?, ?, ?, 0, ?, ?)`
+	createSystemFiles     = `INSERT INTO gochat.messages (channel_id, bucket, id, user_id, content, attachments, flags, type, reference) VALUES (?, ?, ?, ?, ?, ?, 0, ?, 0)`
 	updateMessage         = `UPDATE gochat.messages SET content = ?, embeds = ?, auto_embeds = ?, flags = ?, edited_at = toTimestamp(now()) WHERE channel_id = ? AND id = ? AND bucket = ?`
 	updateGeneratedEmbeds = `UPDATE gochat.messages SET auto_embeds = ? WHERE channel_id = ? AND id = ? AND bucket = ?`
+	setMessageThread      = `UPDATE gochat.messages SET thread = ? WHERE channel_id = ? AND id = ? AND bucket = ?`
+	claimMessageThread    = `UPDATE gochat.messages SET thread = ? WHERE channel_id = ? AND id = ? AND bucket = ? IF thread = null`
+	setMessageFlags       = `UPDATE gochat.messages SET flags = ? WHERE channel_id = ? AND id = ? AND bucket = ?`
 	deleteMessage         = `DELETE FROM gochat.messages WHERE channel_id = ? AND bucket = ? AND id = ?`
 	deleteChannelMessages = `DELETE FROM gochat.messages WHERE channel_id = ? AND bucket IN ?`
-	getMessage            = `SELECT id, channel_id, user_id, content, attachments, embeds, auto_embeds, flags, edited_at, type FROM gochat.messages WHERE id = ? AND channel_id = ? AND bucket = ?`
-	getMessagesBefore     = `SELECT id, channel_id, user_id, content, attachments, embeds, auto_embeds, flags, edited_at, type FROM gochat.messages WHERE channel_id = ? AND id <= ? AND bucket = ? ORDER BY id DESC LIMIT ?`
-	getMessagesAfter      = `SELECT id, channel_id, user_id, content, attachments, embeds, auto_embeds, flags, edited_at, type FROM gochat.messages WHERE channel_id = ? AND id >= ? AND bucket = ? ORDER BY id LIMIT ?`
-	getMessagesList       = `SELECT id, channel_id, user_id, content, attachments, embeds, auto_embeds, flags, edited_at, type FROM gochat.messages WHERE id IN ?`
-	getMessagesByIds      = `SELECT id, channel_id, user_id, content, attachments, embeds, auto_embeds, flags, edited_at, type FROM gochat.messages WHERE channel_id = ? AND bucket = ? AND id IN ?;
+	getMessage            = `SELECT id, channel_id, user_id, content, attachments, embeds, auto_embeds, flags, edited_at, type, thread, reference, author_name, author_avatar FROM gochat.messages WHERE id = ? AND channel_id = ? AND bucket = ?`
+	getMessagesBefore     = `SELECT id, channel_id, user_id, content, attachments, embeds, auto_embeds, flags, edited_at, type, thread, reference, author_name, author_avatar FROM gochat.messages WHERE channel_id = ? AND id <= ? AND bucket = ? ORDER BY id DESC LIMIT ?`
+	getMessagesAfter      = `SELECT id, channel_id, user_id, content, attachments, embeds, auto_embeds, flags, edited_at, type, thread, reference, author_name, author_avatar FROM gochat.messages WHERE channel_id = ? AND id >= ? AND bucket = ? ORDER BY id LIMIT ?`
+	getMessagesList       = `SELECT id, channel_id, user_id, content, attachments, embeds, auto_embeds, flags, edited_at, type, thread, reference, author_name, author_avatar FROM gochat.messages WHERE id IN ?`
+	getMessagesByIds      = `SELECT id, channel_id, user_id, content, attachments, embeds, auto_embeds, flags, edited_at, type, thread, reference, author_name, author_avatar FROM gochat.messages WHERE channel_id = ? AND bucket = ? AND id IN ?;
 `
 )
 
-func (e *Entity) CreateMessage(ctx context.Context, id, channelID, userID int64, content string, attachments []int64, embedsJSON, autoEmbedsJSON string) error {
+// CreateMessage stores a user message. Non-zero reference makes the message a reply to that message.
+func (e *Entity) CreateMessage(ctx context.Context, id, channelID, userID, reference int64, content string, attachments []int64, embedsJSON, autoEmbedsJSON string) error {
+	msgType := model.MessageTypeChat
+	if reference != 0 {
+		msgType = model.MessageTypeReply
+	}
 	err := e.c.Session().
 		Query(createMessage).
 		WithContext(ctx).
-		Bind(channelID, idgen.GetBucket(id), id, userID, content, attachments, embedsJSON, autoEmbedsJSON, 0).
+		Bind(channelID, idgen.GetBucket(id), id, userID, content, attachments, embedsJSON, autoEmbedsJSON, 0, int(msgType), reference).
+		Exec()
+	if err != nil {
+		return fmt.Errorf("unable to create message: %w", err)
+	}
+	return nil
+}
+
+// CreateWebhookMessage stores a message posted by a webhook. The author name and avatar are kept with the message,
+// so later changes of the webhook do not affect already posted messages.
+func (e *Entity) CreateWebhookMessage(ctx context.Context, id, channelID, webhookID, reference int64, content, embedsJSON, autoEmbedsJSON, authorName string, authorAvatar *string) error {
+	msgType := model.MessageTypeChat
+	if reference != 0 {
+		msgType = model.MessageTypeReply
+	}
+	err := e.c.Session().
+		Query(createWebhookMessage).
+		WithContext(ctx).
+		Bind(channelID, idgen.GetBucket(id), id, webhookID, content, embedsJSON, autoEmbedsJSON, model.MessageFlagWebhook, int(msgType), reference, authorName, authorAvatar).
 		Exec()
 	if err != nil {
 		return fmt.Errorf("unable to create message: %w", err)
@@ -37,11 +65,25 @@ func (e *Entity) CreateMessage(ctx context.Context, id, channelID, userID int64,
 	return nil
 }
 
-func (e *Entity) CreateSystemMessage(ctx context.Context, id, channelID, userID int64, content string, msgType model.MessageType) error {
+// CreateSystemMessage stores a system message. Non-zero reference points to the message the event is about.
+func (e *Entity) CreateSystemMessage(ctx context.Context, id, channelID, userID, reference int64, content string, msgType model.MessageType) error {
 	err := e.c.Session().
 		Query(createSystemMessage).
 		WithContext(ctx).
-		Bind(channelID, idgen.GetBucket(id), id, userID, content, int(msgType)).
+		Bind(channelID, idgen.GetBucket(id), id, userID, content, int(msgType), reference).
+		Exec()
+	if err != nil {
+		return fmt.Errorf("unable to create message: %w", err)
+	}
+	return nil
+}
+
+// CreateSystemMessageWithAttachments stores a system message that carries files, like a voice channel recording.
+func (e *Entity) CreateSystemMessageWithAttachments(ctx context.Context, id, channelID, userID int64, content string, attachments []int64, msgType model.MessageType) error {
+	err := e.c.Session().
+		Query(createSystemFiles).
+		WithContext(ctx).
+		Bind(channelID, idgen.GetBucket(id), id, userID, content, attachments, int(msgType)).
 		Exec()
 	if err != nil {
 		return fmt.Errorf("unable to create message: %w", err)
@@ -73,6 +115,51 @@ func (e *Entity) UpdateGeneratedEmbeds(ctx context.Context, id, channelID int64,
 	return nil
 }
 
+// SetMessageThread links the message to the thread started from it, zero thread removes the link
+func (e *Entity) SetMessageThread(ctx context.Context, id, channelID, threadID int64) error {
+	// The link is removed with null, so ClaimMessageThread can start a new thread from the message
+	var thread any = threadID
+	if threadID == 0 {
+		thread = nil
+	}
+	err := e.c.Session().
+		Query(setMessageThread).
+		WithContext(ctx).
+		Bind(thread, channelID, id, idgen.GetBucket(id)).
+		Exec()
+	if err != nil {
+		return fmt.Errorf("unable to set message thread: %w", err)
+	}
+	return nil
+}
+
+// ClaimMessageThread links the message to the thread only if no thread was started from it yet.
+// Reports whether the link was set.
+func (e *Entity) ClaimMessageThread(ctx context.Context, id, channelID, threadID int64) (bool, error) {
+	applied, err := e.c.Session().
+		Query(claimMessageThread).
+		WithContext(ctx).
+		Bind(threadID, channelID, id, idgen.GetBucket(id)).
+		MapScanCAS(make(map[string]interface{}))
+	if err != nil {
+		return false, fmt.Errorf("unable to claim message thread: %w", err)
+	}
+	return applied, nil
+}
+
+// SetMessageFlags replaces message flags without marking the message as edited
+func (e *Entity) SetMessageFlags(ctx context.Context, id, channelID int64, flags int) error {
+	err := e.c.Session().
+		Query(setMessageFlags).
+		WithContext(ctx).
+		Bind(flags, channelID, id, idgen.GetBucket(id)).
+		Exec()
+	if err != nil {
+		return fmt.Errorf("unable to set message flags: %w", err)
+	}
+	return nil
+}
+
 func (e *Entity) DeleteMessage(ctx context.Context, id, channelID int64) error {
 	err := e.c.Session().
 		Query(deleteMessage).
@@ -109,7 +196,7 @@ func (e *Entity) GetMessage(ctx context.Context, id, channelID int64) (model.Mes
 		Query(getMessage).
 		WithContext(ctx).
 		Bind(id, channelID, idgen.GetBucket(id)).
-		Scan(&m.Id, &m.ChannelId, &m.UserId, &m.Content, &m.Attachments, &m.EmbedsJSON, &m.AutoEmbedsJSON, &m.Flags, &m.EditedAt, &m.Type)
+		Scan(&m.Id, &m.ChannelId, &m.UserId, &m.Content, &m.Attachments, &m.EmbedsJSON, &m.AutoEmbedsJSON, &m.Flags, &m.EditedAt, &m.Type, &m.Thread, &m.Reference, &m.AuthorName, &m.AuthorAvatar)
 	if err != nil {
 		return m, fmt.Errorf("unable to get message: %w", err)
 	}
@@ -131,7 +218,7 @@ func (e *Entity) GetMessagesBefore(ctx context.Context, channelID, msgID int64,
 			Bind(channelID, msgID, lastBucket, limit-len(msgs)).
 			Iter()
 		var m model.Message
-		for iter.Scan(&m.Id, &m.ChannelId, &m.UserId, &m.Content, &m.Attachments, &m.EmbedsJSON, &m.AutoEmbedsJSON, &m.Flags, &m.EditedAt, &m.Type) {
+		for iter.Scan(&m.Id, &m.ChannelId, &m.UserId, &m.Content, &m.Attachments, &m.EmbedsJSON, &m.AutoEmbedsJSON, &m.Flags, &m.EditedAt, &m.Type, &m.Thread, &m.Reference, &m.AuthorName, &m.AuthorAvatar) {
 			msgs = append(msgs, cloneMessageRow(m))
 			users[m.UserId] = true
 		}
@@ -165,7 +252,7 @@ func (e *Entity) GetMessagesAfter(ctx context.Context, channelID, msgID, lastCha
 			Bind(channelID, msgID, lastBucket, limit-len(msgs)).
 			Iter()
 		var m model.Message
-		for iter.Scan(&m.Id, &m.ChannelId, &m.UserId, &m.Content, &m.Attachments, &m.EmbedsJSON, &m.AutoEmbedsJSON, &m.Flags, &m.EditedAt, &m.Type) {
+		for iter.Scan(&m.Id, &m.ChannelId, &m.UserId, &m.Content, &m.Attachments, &m.EmbedsJSON, &m.AutoEmbedsJSON, &m.Flags, &m.EditedAt, &m.Type, &m.Thread, &m.Reference, &m.AuthorName, &m.AuthorAvatar) {
 			msgs = append(msgs, cloneMessageRow(m))
 			users[m.UserId] = true
 		}
@@ -213,7 +300,7 @@ func (e *Entity) GetMessagesList(ctx context.Context, msgIDs []int64) ([]model.M
 		Bind(msgIDs).
 		Iter()
 	var m model.Message
-	for iter.Scan(&m.Id, &m.ChannelId, &m.UserId, &m.Content, &m.Attachments, &m.EmbedsJSON, &m.AutoEmbedsJSON, &m.Flags, &m.EditedAt, &m.Type) {
+	for iter.Scan(&m.Id, &m.ChannelId, &m.UserId, &m.Content, &m.Attachments, &m.EmbedsJSON, &m.AutoEmbedsJSON, &m.Flags, &m.EditedAt, &m.Type, &m.Thread, &m.Reference, &m.AuthorName, &m.AuthorAvatar) {
 		msgs = append(msgs, cloneMessageRow(m))
 	}
 	if err := iter.Close(); err != nil {
@@ -256,7 +343,7 @@ func (e *Entity) GetChannelMessagesByIDs(ctx context.Context, channelID int64, i
 				Iter()
 
 			var m model.Message
-			for iter.Scan(&m.Id, &m.ChannelId, &m.UserId, &m.Content, &m.Attachments, &m.EmbedsJSON, &m.AutoEmbedsJSON, &m.Flags, &m.EditedAt, &m.Type) {
+			for iter.Scan(&m.Id, &m.ChannelId, &m.UserId, &m.Content, &m.Attachments, &m.EmbedsJSON, &m.AutoEmbedsJSON, &m.Flags, &m.EditedAt, &m.Type, &m.Thread, &m.Reference, &m.AuthorName, &m.AuthorAvatar) {
 				results = append(results, cloneMessageRow(m))
 			}
 			if err := iter.Close(); err != nil {
@@ -0,0 +1,24 @@
+package pin
+
+import (
+	"context"
+
+	"github.com/FlameInTheDark/gochat/internal/database/db"
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type Pin interface {
+	PinMessage(ctx context.Context, channelId, messageId, userId int64) (bool, error)
+	UnpinMessage(ctx context.Context, channelId, messageId int64) (bool, error)
+	GetChannelPins(ctx context.Context, channelId int64) ([]model.Pin, error)
+	CountChannelPins(ctx context.Context, channelId int64) (int, error)
+	RemoveChannelPins(ctx context.Context, channelId int64) error
+}
+
+type Entity struct {
+	c *db.CQLCon
+}
+
+func New(c *db.CQLCon) *Entity {
+	return &Entity{c: c}
+}
@@ -0,0 +1,93 @@
+package pin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+const (
+	pinMessage        = `INSERT INTO gochat.channel_pins (channel_id, message_id, pinned_by, pinned_at) VALUES (?, ?, ?, ?) IF NOT EXISTS`
+	unpinMessage      = `DELETE FROM gochat.channel_pins WHERE channel_id = ? AND message_id = ? IF EXISTS`
+	getChannelPins    = `SELECT channel_id, message_id, pinned_by, pinned_at FROM gochat.channel_pins WHERE channel_id = ?`
+	countChannelPins  = `SELECT COUNT(*) FROM gochat.channel_pins WHERE channel_id = ?`
+	removeChannelPins = `DELETE FROM gochat.channel_pins WHERE channel_id = ?`
+)
+
+// PinMessage stores the pin and reports whether the message was newly pinned.
+func (e *Entity) PinMessage(ctx context.Context, channelId, messageId, userId int64) (bool, error) {
+	applied, err := e.c.Session().
+		Query(pinMessage).
+		WithContext(ctx).
+		Bind(channelId, messageId, userId, time.Now()).
+		MapScanCAS(make(map[string]interface{}))
+	if err != nil {
+		return false, fmt.Errorf("unable to pin message: %w", err)
+	}
+	return applied, nil
+}
+
+// UnpinMessage deletes the pin and reports whether the message was pinned.
+func (e *Entity) UnpinMessage(ctx context.Context, channelId, messageId int64) (bool, error) {
+	applied, err := e.c.Session().
+		Query(unpinMessage).
+		WithContext(ctx).
+		Bind(channelId, messageId).
+		MapScanCAS(make(map[string]interface{}))
+	if err != nil {
+		return false, fmt.Errorf("unable to unpin message: %w", err)
+	}
+	return applied, nil
+}
+
+// GetChannelPins returns channel pins ordered by message ID, newest first.
+func (e *Entity) GetChannelPins(ctx context.Context, channelId int64) ([]model.Pin, error) {
+	var pins []model.Pin
+	iter := e.c.Session().
+		Query(getChannelPins).
+		WithContext(ctx).
+		Bind(channelId).
+		Iter()
+	var p model.Pin
+	for iter.Scan(&p.ChannelId, &p.MessageId, &p.PinnedBy, &p.PinnedAt) {
+		pins = append(pins, p)
+	}
+	err := iter.Close()
+	if err != nil && !errors.Is(err, gocql.ErrNotFound) {
+		return nil, fmt.Errorf("unable to get channel pins: %w", err)
+	}
+	return pins, nil
+}
+
+func (e *Entity) CountChannelPins(ctx context.Context, channelId int64) (int, error) {
+	var count int
+	err := e.c.Session().
+		Query(countChannelPins).
+		WithContext(ctx).
+		Bind(channelId).
+		Scan(&count)
+	if err != nil {
+		if errors.Is(err, gocql.ErrNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("unable to count channel pins: %w", err)
+	}
+	return count, nil
+}
+
+func (e *Entity) RemoveChannelPins(ctx context.Context, channelId int64) error {
+	err := e.c.Session().
+		Query(removeChannelPins).
+		WithContext(ctx).
+		Bind(channelId).
+		Exec()
+	if err != nil {
+		return fmt.Errorf("unable to remove channel pins: %w", err)
+	}
+	return nil
+}
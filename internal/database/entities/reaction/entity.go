@@ -9,9 +9,12 @@ import (
 
 type Reaction interface {
 	GetReactions(ctx context.Context, messageId int64) ([]model.Reaction, error)
-	GetReactionsAfter(ctx context.Context, messageId, userId int64) ([]model.Reaction, error)
-	AddReaction(ctx context.Context, messageId, userId, emoteId int64) error
-	RemoveReaction(ctx context.Context, messageId, userId int64) error
+	GetReactionCounts(ctx context.Context, messageIds []int64) (map[int64][]model.ReactionCount, error)
+	GetUserReactions(ctx context.Context, messageIds []int64, userId int64) (map[int64][]string, error)
+	GetReactionsAfter(ctx context.Context, messageId int64, emoji string, userId int64, limit int) ([]model.Reaction, error)
+	AddReaction(ctx context.Context, messageId, userId int64, emoji string, emoteId int64) (bool, error)
+	RemoveReaction(ctx context.Context, messageId, userId int64, emoji string, emoteId int64) (bool, error)
+	RemoveMessageReactions(ctx context.Context, messageId int64) error
 }
 
 type Entity struct {
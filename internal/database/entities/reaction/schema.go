@@ -2,16 +2,27 @@ package reaction
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/gocql/gocql"
+
 	"github.com/FlameInTheDark/gochat/internal/database/model"
 )
 
 const (
-	getReactions      = `SELECT message_id, user_id, emote_id FROM gochat.reactions WHERE message_id = ? ORDER BY user_id LIMIT 10`
-	getReactionsAfter = `SELECT message_id, user_id, emote_id FROM gochat.reactions WHERE message_id = ? AND token(user_id) > token(?) ORDER BY user_id LIMIT 10`
-	addReaction       = `INSERT INTO gochat.reactions (message_id, user_id, emote_id) VALUES (?, ?, ?)`
-	removeReaction    = `DELETE FROM gochat.reactions WHERE message_id = ? AND user_id = ?`
+	getReactions           = `SELECT message_id, emoji, user_id, emote_id FROM gochat.reactions WHERE message_id = ?`
+	getReactionCounts      = `SELECT message_id, emoji, emote_id, count FROM gochat.reaction_counts WHERE message_id IN ?`
+	getUserReactions       = `SELECT message_id, emoji FROM gochat.reactions_by_user WHERE message_id IN ? AND user_id = ?`
+	getReactionsAfter      = `SELECT message_id, emoji, user_id, emote_id FROM gochat.reactions WHERE message_id = ? AND emoji = ? AND user_id > ? LIMIT ?`
+	addReaction            = `INSERT INTO gochat.reactions (message_id, emoji, user_id, emote_id) VALUES (?, ?, ?, ?) IF NOT EXISTS`
+	removeReaction         = `DELETE FROM gochat.reactions WHERE message_id = ? AND emoji = ? AND user_id = ? IF EXISTS`
+	removeMessageReactions = `DELETE FROM gochat.reactions WHERE message_id = ?`
+	addUserReaction        = `INSERT INTO gochat.reactions_by_user (message_id, user_id, emoji) VALUES (?, ?, ?)`
+	removeUserReaction     = `DELETE FROM gochat.reactions_by_user WHERE message_id = ? AND user_id = ? AND emoji = ?`
+	removeUserReactions    = `DELETE FROM gochat.reactions_by_user WHERE message_id = ?`
+	updateReactionCount    = `UPDATE gochat.reaction_counts SET count = count + ? WHERE message_id = ? AND emoji = ? AND emote_id = ?`
+	removeReactionCounts   = `DELETE FROM gochat.reaction_counts WHERE message_id = ?`
 )
 
 func (e *Entity) GetReactions(ctx context.Context, messageId int64) ([]model.Reaction, error) {
@@ -22,54 +33,167 @@ func (e *Entity) GetReactions(ctx context.Context, messageId int64) ([]model.Rea
 		Bind(messageId).
 		Iter()
 	var r model.Reaction
-	for iter.Scan(&r.MessageId, &r.UserId, &r.EmoteId) {
+	for iter.Scan(&r.MessageId, &r.Emoji, &r.UserId, &r.EmoteId) {
 		reactions = append(reactions, r)
 	}
 	err := iter.Close()
-	if err != nil {
+	if err != nil && !errors.Is(err, gocql.ErrNotFound) {
 		return nil, fmt.Errorf("unable to get reactions: %w", err)
 	}
 	return reactions, nil
 }
 
-func (e *Entity) GetReactionsAfter(ctx context.Context, messageId, userId int64) ([]model.Reaction, error) {
+// GetReactionCounts returns the per-emoji reaction counters of the given messages.
+func (e *Entity) GetReactionCounts(ctx context.Context, messageIds []int64) (map[int64][]model.ReactionCount, error) {
+	counts := make(map[int64][]model.ReactionCount)
+	if len(messageIds) == 0 {
+		return counts, nil
+	}
+	iter := e.c.Session().
+		Query(getReactionCounts).
+		WithContext(ctx).
+		Bind(messageIds).
+		Iter()
+	var r model.ReactionCount
+	for iter.Scan(&r.MessageId, &r.Emoji, &r.EmoteId, &r.Count) {
+		counts[r.MessageId] = append(counts[r.MessageId], r)
+	}
+	err := iter.Close()
+	if err != nil && !errors.Is(err, gocql.ErrNotFound) {
+		return nil, fmt.Errorf("unable to get reaction counts: %w", err)
+	}
+	return counts, nil
+}
+
+// GetUserReactions returns the emoji keys the user reacted with on each of the given messages.
+func (e *Entity) GetUserReactions(ctx context.Context, messageIds []int64, userId int64) (map[int64][]string, error) {
+	reactions := make(map[int64][]string)
+	if len(messageIds) == 0 {
+		return reactions, nil
+	}
+	iter := e.c.Session().
+		Query(getUserReactions).
+		WithContext(ctx).
+		Bind(messageIds, userId).
+		Iter()
+	var (
+		messageId int64
+		emoji     string
+	)
+	for iter.Scan(&messageId, &emoji) {
+		reactions[messageId] = append(reactions[messageId], emoji)
+	}
+	err := iter.Close()
+	if err != nil && !errors.Is(err, gocql.ErrNotFound) {
+		return nil, fmt.Errorf("unable to get user reactions: %w", err)
+	}
+	return reactions, nil
+}
+
+func (e *Entity) GetReactionsAfter(ctx context.Context, messageId int64, emoji string, userId int64, limit int) ([]model.Reaction, error) {
 	var reactions []model.Reaction
 	iter := e.c.Session().
 		Query(getReactionsAfter).
 		WithContext(ctx).
-		Bind(messageId, userId).
+		Bind(messageId, emoji, userId, limit).
 		Iter()
 	var r model.Reaction
-	for iter.Scan(&r.MessageId, &r.UserId, &r.EmoteId) {
+	for iter.Scan(&r.MessageId, &r.Emoji, &r.UserId, &r.EmoteId) {
 		reactions = append(reactions, r)
 	}
 	err := iter.Close()
-	if err != nil {
+	if err != nil && !errors.Is(err, gocql.ErrNotFound) {
 		return nil, fmt.Errorf("unable to get reactions: %w", err)
 	}
 	return reactions, nil
 }
 
-func (e *Entity) AddReaction(ctx context.Context, messageId, userId, emoteId int64) error {
-	err := e.c.Session().
+// AddReaction stores the user's reaction and reports whether it was newly added.
+func (e *Entity) AddReaction(ctx context.Context, messageId, userId int64, emoji string, emoteId int64) (bool, error) {
+	applied, err := e.c.Session().
 		Query(addReaction).
 		WithContext(ctx).
-		Bind(messageId, userId, emoteId).
+		Bind(messageId, emoji, userId, emoteId).
+		MapScanCAS(make(map[string]interface{}))
+	if err != nil {
+		return false, fmt.Errorf("unable to add reaction: %w", err)
+	}
+	if !applied {
+		return false, nil
+	}
+	err = e.c.Session().
+		Query(addUserReaction).
+		WithContext(ctx).
+		Bind(messageId, userId, emoji).
 		Exec()
 	if err != nil {
-		return fmt.Errorf("unable to add reaction: %w", err)
+		return true, fmt.Errorf("unable to add user reaction: %w", err)
+	}
+	if err := e.updateReactionCount(ctx, messageId, emoji, emoteId, 1); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// RemoveReaction deletes the user's reaction and reports whether it existed.
+func (e *Entity) RemoveReaction(ctx context.Context, messageId, userId int64, emoji string, emoteId int64) (bool, error) {
+	applied, err := e.c.Session().
+		Query(removeReaction).
+		WithContext(ctx).
+		Bind(messageId, emoji, userId).
+		MapScanCAS(make(map[string]interface{}))
+	if err != nil {
+		return false, fmt.Errorf("unable to remove reaction: %w", err)
+	}
+	if !applied {
+		return false, nil
+	}
+	err = e.c.Session().
+		Query(removeUserReaction).
+		WithContext(ctx).
+		Bind(messageId, userId, emoji).
+		Exec()
+	if err != nil {
+		return true, fmt.Errorf("unable to remove user reaction: %w", err)
+	}
+	if err := e.updateReactionCount(ctx, messageId, emoji, emoteId, -1); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// updateReactionCount moves the emoji counter of the message by delta.
+// Only called after the reaction row itself was changed, so the counter follows the LWT result.
+func (e *Entity) updateReactionCount(ctx context.Context, messageId int64, emoji string, emoteId int64, delta int64) error {
+	err := e.c.Session().
+		Query(updateReactionCount).
+		WithContext(ctx).
+		Bind(delta, messageId, emoji, emoteId).
+		Exec()
+	if err != nil {
+		return fmt.Errorf("unable to update reaction count: %w", err)
 	}
 	return nil
 }
 
-func (e *Entity) RemoveReaction(ctx context.Context, messageId, userId int64) error {
+func (e *Entity) RemoveMessageReactions(ctx context.Context, messageId int64) error {
 	err := e.c.Session().
-		Query(removeReaction).
+		Query(removeMessageReactions).
 		WithContext(ctx).
-		Bind(messageId, userId).
+		Bind(messageId).
 		Exec()
 	if err != nil {
-		return fmt.Errorf("unable to remove reaction: %w", err)
+		return fmt.Errorf("unable to remove message reactions: %w", err)
+	}
+	for _, query := range []string{removeUserReactions, removeReactionCounts} {
+		err = e.c.Session().
+			Query(query).
+			WithContext(ctx).
+			Bind(messageId).
+			Exec()
+		if err != nil {
+			return fmt.Errorf("unable to remove message reactions: %w", err)
+		}
 	}
 	return nil
 }
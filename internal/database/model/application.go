@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Application owns a bot user with the same ID. Only the hash of the bot token is stored.
+type Application struct {
+	Id          int64     `db:"id"`
+	OwnerId     int64     `db:"owner_id"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	TokenHash   string    `db:"token_hash"`
+	CreatedAt   time.Time `db:"created_at"`
+}
@@ -1,9 +1,64 @@
 package model
 
-import "time"
+type AuditActionType int
+
+const (
+	AuditActionGuildUpdate AuditActionType = 1
+
+	AuditActionChannelCreate          AuditActionType = 10
+	AuditActionChannelUpdate          AuditActionType = 11
+	AuditActionChannelDelete          AuditActionType = 12
+	AuditActionChannelOverwriteCreate AuditActionType = 13
+	AuditActionChannelOverwriteUpdate AuditActionType = 14
+	AuditActionChannelOverwriteDelete AuditActionType = 15
+
+	AuditActionMemberKick       AuditActionType = 20
+	AuditActionMemberBanAdd     AuditActionType = 22
+	AuditActionMemberBanRemove  AuditActionType = 23
+	AuditActionMemberUpdate     AuditActionType = 24
+	AuditActionMemberRoleUpdate AuditActionType = 25
+	AuditActionBotAdd           AuditActionType = 28
+
+	AuditActionRoleCreate AuditActionType = 30
+	AuditActionRoleUpdate AuditActionType = 31
+	AuditActionRoleDelete AuditActionType = 32
+
+	AuditActionInviteCreate AuditActionType = 40
+	AuditActionInviteDelete AuditActionType = 42
+
+	AuditActionWebhookCreate AuditActionType = 50
+	AuditActionWebhookUpdate AuditActionType = 51
+	AuditActionWebhookDelete AuditActionType = 52
+
+	AuditActionEventWebhookCreate AuditActionType = 53
+	AuditActionEventWebhookUpdate AuditActionType = 54
+	AuditActionEventWebhookDelete AuditActionType = 55
+
+	AuditActionEmojiCreate AuditActionType = 60
+	AuditActionEmojiUpdate AuditActionType = 61
+	AuditActionEmojiDelete AuditActionType = 62
+
+	AuditActionVoiceRecordingStart AuditActionType = 70
+	AuditActionVoiceRecordingStop  AuditActionType = 71
+
+	AuditActionThreadCreate AuditActionType = 110
+	AuditActionThreadUpdate AuditActionType = 111
+	AuditActionThreadDelete AuditActionType = 112
+)
+
+// AuditChange is a single changed property of the audit target
+type AuditChange struct {
+	Key string `json:"key"`
+	Old any    `json:"old,omitempty"`
+	New any    `json:"new,omitempty"`
+}
 
 type Audit struct {
-	GuildId   int64             `db:"guild_id"`
-	CreatedAt time.Time         `db:"created_at"`
-	Changes   map[string]string `db:"changes"`
+	GuildId  int64           `db:"guild_id"`
+	Id       int64           `db:"id"`
+	ActorId  int64           `db:"actor_id"`
+	Action   AuditActionType `db:"action"`
+	TargetId int64           `db:"target_id"`
+	Changes  []AuditChange   `db:"changes"`
+	Reason   *string         `db:"reason"`
 }
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+type BlockedUser struct {
+	UserId        int64     `db:"user_id"`
+	BlockedUserId int64     `db:"blocked_user_id"`
+	CreatedAt     time.Time `db:"created_at"`
+}
@@ -13,6 +13,10 @@ type Channel struct {
 	Private     bool        `db:"private"`
 	LastMessage int64       `db:"last_message"`
 	CreatedAt   time.Time   `db:"created_at"`
+	// Voice channel settings
+	VoiceUserLimit int  `db:"voice_user_limit"` // 0 means unlimited
+	VoiceBitrate   int  `db:"voice_bitrate"`    // Audio bitrate cap in kbps, 0 means the SFU default
+	VoiceStage     bool `db:"voice_stage"`      // Only approved speakers may speak
 }
 
 type ChannelType int
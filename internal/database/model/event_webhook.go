@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// EventWebhook receives signed HTTP callbacks for the subscribed guild events.
+// The secret is stored as is because every delivery is signed with it.
+type EventWebhook struct {
+	GuildId      int64         `db:"guild_id"`
+	Id           int64         `db:"id"`
+	CreatorId    int64         `db:"creator_id"`
+	URL          string        `db:"url"`
+	Secret       string        `db:"secret"`
+	Events       pq.Int64Array `db:"events"`
+	Enabled      bool          `db:"enabled"`
+	FailureCount int           `db:"failure_count"`
+	DisabledAt   *time.Time    `db:"disabled_at"`
+	CreatedAt    time.Time     `db:"created_at"`
+}
+
+// Subscribed reports whether the webhook receives the event type
+func (w EventWebhook) Subscribed(eventType int64) bool {
+	for _, t := range w.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// EventWebhookDelivery is a single attempt to deliver an event to the webhook endpoint
+type EventWebhookDelivery struct {
+	WebhookId  int64
+	Id         int64
+	EventId    int64
+	EventType  int
+	Attempt    int
+	StatusCode int
+	Error      *string
+	DurationMs int
+}
@@ -4,3 +4,10 @@ type GroupDMChannel struct {
 	ChannelId int64 `db:"channel_id"`
 	UserId    int64 `db:"user_id"`
 }
+
+// GroupDM holds the settings of a group DM channel, the name is stored on the channel itself
+type GroupDM struct {
+	ChannelId int64  `db:"channel_id"`
+	OwnerId   int64  `db:"owner_id"`
+	Icon      *int64 `db:"icon"`
+}
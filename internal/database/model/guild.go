@@ -3,12 +3,70 @@ package model
 import "time"
 
 type Guild struct {
-	Id             int64     `db:"id"`
-	Name           string    `db:"name"`
-	OwnerId        int64     `db:"owner_id"`
-	Icon           *int64    `db:"icon"`
-	Public         bool      `db:"public"`
-	Permissions    int64     `db:"permissions"`
-	CreatedAt      time.Time `db:"created_at"`
-	SystemMessages *int64    `db:"system_messages"`
+	Id                int64             `db:"id"`
+	Name              string            `db:"name"`
+	OwnerId           int64             `db:"owner_id"`
+	Icon              *int64            `db:"icon"`
+	Public            bool              `db:"public"`
+	Permissions       int64             `db:"permissions"`
+	CreatedAt         time.Time         `db:"created_at"`
+	SystemMessages    *int64            `db:"system_messages"`
+	MFARequired       bool              `db:"mfa_required"`
+	Description       *string           `db:"description"`
+	Category          GuildCategory     `db:"category"`
+	VanityCode        *string           `db:"vanity_code"`
+	VerificationLevel VerificationLevel `db:"verification_level"`
+	Rules             *string           `db:"rules"`
+	ScreeningEnabled  bool              `db:"screening_enabled"`
+}
+
+// VerificationLevel is what members without roles need before they can post in the guild.
+// Every level includes the requirements of the lower levels.
+type VerificationLevel int
+
+const (
+	VerificationLevelNone VerificationLevel = iota
+	// The account has a verified email
+	VerificationLevelLow
+	// The account is registered for at least VerificationAccountAge
+	VerificationLevelMedium
+	// The user is a member of the guild for at least VerificationMembershipAge
+	VerificationLevelHigh
+)
+
+const (
+	VerificationAccountAge    = 5 * time.Minute
+	VerificationMembershipAge = 10 * time.Minute
+)
+
+// DiscoverableGuild is a public guild listed in the discovery directory
+type DiscoverableGuild struct {
+	Guild
+	MembersCount int64 `db:"members_count"`
+}
+
+// GuildCategory is the topic public guilds are filtered by in discovery
+type GuildCategory int
+
+const (
+	GuildCategoryNone GuildCategory = iota
+	GuildCategoryGaming
+	GuildCategoryMusic
+	GuildCategoryEducation
+	GuildCategoryScience
+	GuildCategoryEntertainment
+	GuildCategoryArt
+	GuildCategoryCommunity
+)
+
+// GuildCategories lists the known categories
+var GuildCategories = []GuildCategory{
+	GuildCategoryNone,
+	GuildCategoryGaming,
+	GuildCategoryMusic,
+	GuildCategoryEducation,
+	GuildCategoryScience,
+	GuildCategoryEntertainment,
+	GuildCategoryArt,
+	GuildCategoryCommunity,
 }
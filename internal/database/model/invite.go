@@ -10,4 +10,9 @@ type GuildInvite struct {
 	AuthorId   int64     `db:"author_id"`
 	CreatedAt  time.Time `db:"created_at"`
 	ExpiresAt  time.Time `db:"expires_at"`
+	// Zero means unlimited uses
+	MaxUses int `db:"max_uses"`
+	Uses    int `db:"uses"`
+	// Members joined with a temporary invite are removed when they disconnect
+	Temporary bool `db:"temporary"`
 }
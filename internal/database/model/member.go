@@ -9,6 +9,12 @@ type Member struct {
 	Avatar   *int64    `db:"avatar"`
 	JoinAt   time.Time `db:"join_at"`
 	Timeout  time.Time `db:"timeout"`
+	// Code of the invite the member joined with
+	InviteCode *string `db:"invite_code"`
+	// Joined with a temporary invite and removed on disconnect unless a role was assigned
+	Temporary bool `db:"temporary"`
+	// Has not accepted the rules of a guild with member screening yet
+	Pending bool `db:"pending"`
 }
 
 type UserGuild struct {
@@ -15,6 +15,9 @@ type Message struct {
 	Reference      int64
 	Thread         int64
 	EditedAt       *time.Time
+	// Name and avatar URL of the webhook author, set only for webhook messages
+	AuthorName   *string
+	AuthorAvatar *string
 }
 
 type MessageType int
@@ -23,11 +26,22 @@ const (
 	MessageTypeChat MessageType = iota
 	MessageTypeReply
 	MessageTypeJoin
+	MessageTypePin
+	MessageTypeRecipientAdd
+	MessageTypeRecipientRemove
+	MessageTypeVoiceRecording
 )
 
 const (
 	MessageFlagSuppressEmbeds = 1 << 2
 	MessageFlagBannedAuthor   = 1 << 3
+	MessageFlagPinned         = 1 << 4
+	// Set in API responses for messages of bot users, not stored
+	MessageFlagBotAuthor = 1 << 5
+	// Message was posted by a webhook, the author is not a user
+	MessageFlagWebhook = 1 << 6
+	// Set in API responses for messages of users blocked by the viewer, not stored
+	MessageFlagBlockedAuthor = 1 << 7
 )
 
 func NormalizeMessageFlags(flags *int) int {
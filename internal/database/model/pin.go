@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type Pin struct {
+	ChannelId int64
+	MessageId int64
+	PinnedBy  int64
+	PinnedAt  time.Time
+}
@@ -3,5 +3,13 @@ package model
 type Reaction struct {
 	MessageId int64
 	UserId    int64
+	Emoji     string // Unicode emoji or the decimal ID of a custom guild emoji
+	EmoteId   int64  // Custom guild emoji ID, 0 for unicode emoji
+}
+
+type ReactionCount struct {
+	MessageId int64
+	Emoji     string
 	EmoteId   int64
+	Count     int64
 }
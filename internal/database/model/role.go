@@ -7,6 +7,8 @@ type Role struct {
 	Color       int    `db:"color"`
 	Permissions int64  `db:"permissions"`
 	Position    int    `db:"position"`
+	// Application of the bot the role was created for, managed roles can not be assigned by members
+	ManagedBy *int64 `db:"managed_by"`
 }
 
 type RoleUpdatePosition struct {
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+type Thread struct {
+	Id                 int64      `db:"id"`
+	GuildId            int64      `db:"guild_id"`
+	ParentId           int64      `db:"parent_id"`
+	OwnerId            int64      `db:"owner_id"`
+	MessageId          *int64     `db:"message_id"`
+	Archived           bool       `db:"archived"`
+	AutoArchiveMinutes int        `db:"auto_archive_minutes"`
+	LastActivityAt     time.Time  `db:"last_activity_at"`
+	ArchivedAt         *time.Time `db:"archived_at"`
+	CreatedAt          time.Time  `db:"created_at"`
+}
+
+type ThreadMember struct {
+	ThreadId int64     `db:"thread_id"`
+	UserId   int64     `db:"user_id"`
+	JoinedAt time.Time `db:"joined_at"`
+}
@@ -8,5 +8,8 @@ type User struct {
 	Avatar      *int64    `json:"avatar" db:"avatar"`
 	Blocked     bool      `json:"blocked" db:"blocked"`
 	UploadLimit *int64    `json:"upload_limit" db:"upload_limit"`
+	Bot         bool      `json:"bot" db:"bot"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	// Nil for bots and accounts without a confirmed email
+	EmailVerifiedAt *time.Time `json:"-" db:"email_verified_at"`
 }
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// UserMFA is the TOTP second factor of the user. The secret is pending until EnabledAt is set by a confirmed code.
+// LastStep is the last accepted time step, codes of this or earlier steps are rejected.
+type UserMFA struct {
+	UserId     int64      `db:"user_id"`
+	TOTPSecret string     `db:"totp_secret"`
+	LastStep   int64      `db:"last_step"`
+	CreatedAt  time.Time  `db:"created_at"`
+	EnabledAt  *time.Time `db:"enabled_at"`
+}
+
+// Enabled returns true if the enrollment was confirmed
+func (m UserMFA) Enabled() bool {
+	return m.EnabledAt != nil
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// UserSession is a login of the user on a device. RefreshJti is the ID of the only refresh token that is still valid for the session.
+type UserSession struct {
+	Id         int64      `db:"id"`
+	UserId     int64      `db:"user_id"`
+	RefreshJti string     `db:"refresh_jti"`
+	Device     string     `db:"device"`
+	IP         string     `db:"ip"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt time.Time  `db:"last_used_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
+
+// Active returns true if the session is not revoked and not expired
+func (s UserSession) Active(now time.Time) bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(now)
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Webhook posts messages into a guild channel. Only the hash of the secret token is stored.
+type Webhook struct {
+	Id        int64     `db:"id"`
+	GuildId   int64     `db:"guild_id"`
+	ChannelId int64     `db:"channel_id"`
+	CreatorId int64     `db:"creator_id"`
+	Name      string    `db:"name"`
+	AvatarURL *string   `db:"avatar_url"`
+	TokenHash string    `db:"token_hash"`
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -0,0 +1,26 @@
+package application
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type Application interface {
+	CreateApplication(ctx context.Context, app model.Application) error
+	GetApplication(ctx context.Context, id int64) (model.Application, error)
+	GetOwnerApplications(ctx context.Context, ownerId int64) ([]model.Application, error)
+	UpdateApplication(ctx context.Context, id int64, name, description *string) error
+	SetTokenHash(ctx context.Context, id int64, tokenHash string) error
+	DeleteApplication(ctx context.Context, id int64) error
+}
+
+type Entity struct {
+	c *sqlx.DB
+}
+
+func New(c *sqlx.DB) Application {
+	return &Entity{c: c}
+}
@@ -0,0 +1,121 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+func (e *Entity) CreateApplication(ctx context.Context, app model.Application) error {
+	q := squirrel.Insert("applications").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("id", "owner_id", "name", "description", "token_hash").
+		Values(app.Id, app.OwnerId, app.Name, app.Description, app.TokenHash)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to create application: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) GetApplication(ctx context.Context, id int64) (model.Application, error) {
+	var app model.Application
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("applications").
+		Where(squirrel.Eq{"id": id})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return app, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.GetContext(ctx, &app, raw, args...)
+	if err != nil {
+		return app, fmt.Errorf("unable to get application: %w", err)
+	}
+	return app, nil
+}
+
+func (e *Entity) GetOwnerApplications(ctx context.Context, ownerId int64) ([]model.Application, error) {
+	var apps []model.Application
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("applications").
+		Where(squirrel.Eq{"owner_id": ownerId}).
+		OrderBy("id ASC")
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &apps, raw, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apps, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get owner applications: %w", err)
+	}
+	return apps, nil
+}
+
+func (e *Entity) UpdateApplication(ctx context.Context, id int64, name, description *string) error {
+	if name == nil && description == nil {
+		return nil
+	}
+	q := squirrel.Update("applications").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"id": id})
+	if name != nil {
+		q = q.Set("name", *name)
+	}
+	if description != nil {
+		q = q.Set("description", *description)
+	}
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to update application: %w", err)
+	}
+	return nil
+}
+
+// SetTokenHash replaces the bot token, the previous token stops working
+func (e *Entity) SetTokenHash(ctx context.Context, id int64, tokenHash string) error {
+	q := squirrel.Update("applications").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("token_hash", tokenHash).
+		Where(squirrel.Eq{"id": id})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to set application token: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) DeleteApplication(ctx context.Context, id int64) error {
+	q := squirrel.Delete("applications").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"id": id})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to delete application: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package blocked
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type Blocked interface {
+	BlockUser(ctx context.Context, userId, blockedUserId int64) error
+	UnblockUser(ctx context.Context, userId, blockedUserId int64) error
+	IsBlocked(ctx context.Context, userId, blockedUserId int64) (bool, error)
+	GetBlockedUsers(ctx context.Context, userId int64) ([]model.BlockedUser, error)
+}
+
+type Entity struct {
+	c *sqlx.DB
+}
+
+func New(c *sqlx.DB) *Entity {
+	return &Entity{c: c}
+}
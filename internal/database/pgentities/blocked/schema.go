@@ -0,0 +1,81 @@
+package blocked
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+func (e *Entity) BlockUser(ctx context.Context, userId, blockedUserId int64) error {
+	q := squirrel.Insert("blocked_users").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("user_id", "blocked_user_id").
+		Values(userId, blockedUserId).
+		Suffix("ON CONFLICT (user_id, blocked_user_id) DO NOTHING")
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to block user: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) UnblockUser(ctx context.Context, userId, blockedUserId int64) error {
+	q := squirrel.Delete("blocked_users").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"user_id": userId, "blocked_user_id": blockedUserId})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to unblock user: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) IsBlocked(ctx context.Context, userId, blockedUserId int64) (bool, error) {
+	q := squirrel.Select("1").
+		PlaceholderFormat(squirrel.Dollar).
+		From("blocked_users").
+		Where(squirrel.Eq{"user_id": userId, "blocked_user_id": blockedUserId})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	var blocked bool
+	err = e.c.QueryRowContext(ctx, fmt.Sprintf("SELECT EXISTS (%s)", raw), args...).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("unable to check if user is blocked: %w", err)
+	}
+	return blocked, nil
+}
+
+func (e *Entity) GetBlockedUsers(ctx context.Context, userId int64) ([]model.BlockedUser, error) {
+	var blocked []model.BlockedUser
+	q := squirrel.Select("user_id", "blocked_user_id", "created_at").
+		PlaceholderFormat(squirrel.Dollar).
+		From("blocked_users").
+		Where(squirrel.Eq{"user_id": userId}).
+		OrderBy("created_at DESC")
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &blocked, raw, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get blocked users: %w", err)
+	}
+	return blocked, nil
+}
@@ -11,6 +11,7 @@ type Channel interface {
 	GetChannel(ctx context.Context, id int64) (model.Channel, error)
 	GetChannelsBulk(ctx context.Context, ids []int64) ([]model.Channel, error)
 	GetChannelThreads(ctx context.Context, channelId int64) ([]model.Channel, error)
+	GetChannelsAfter(ctx context.Context, afterId int64, limit int) ([]model.Channel, error)
 	CreateChannel(ctx context.Context, id int64, name string, channelType model.ChannelType, parent *int64, permissions *int64, private bool) error
 	DeleteChannel(ctx context.Context, id int64) error
 	RenameChannel(ctx context.Context, id int64, newName string) error
@@ -20,7 +21,7 @@ type Channel interface {
 	SetChannelParent(ctx context.Context, id int64, parent *int64) error
 	SetChannelParentBulk(ctx context.Context, id []int64, parent *int64) error
 	SetLastMessage(ctx context.Context, id, lastMessage int64) error
-	UpdateChannel(ctx context.Context, id int64, parent *int64, private *bool, name, topic *string) (model.Channel, error)
+	UpdateChannel(ctx context.Context, id int64, parent *int64, private *bool, name, topic *string, userLimit, bitrate *int, stage *bool) (model.Channel, error)
 	SetChannelVoiceRegion(ctx context.Context, id int64, region *string) error
 	GetChannelVoiceRegion(ctx context.Context, id int64) (*string, error)
 }
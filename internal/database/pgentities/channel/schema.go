@@ -72,6 +72,28 @@ func (e *Entity) GetChannelThreads(ctx context.Context, channelId int64) ([]mode
 	return channels, nil
 }
 
+// GetChannelsAfter returns channels with ID greater than afterId ordered by ID, used to walk all channels in pages
+func (e *Entity) GetChannelsAfter(ctx context.Context, afterId int64, limit int) ([]model.Channel, error) {
+	var chs []model.Channel
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("channels").
+		Where(squirrel.Gt{"id": afterId}).
+		OrderBy("id asc").
+		Limit(uint64(limit))
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return chs, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &chs, raw, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return chs, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get channels: %w", err)
+	}
+	return chs, nil
+}
+
 func (e *Entity) CreateChannel(ctx context.Context, id int64, name string, channelType model.ChannelType, parent *int64, permissions *int64, private bool) error {
 	q := squirrel.Insert("channels").
 		PlaceholderFormat(squirrel.Dollar).
@@ -255,7 +277,7 @@ func (e *Entity) SetLastMessage(ctx context.Context, id, lastMessage int64) erro
 	return nil
 }
 
-func (e *Entity) UpdateChannel(ctx context.Context, id int64, parent *int64, private *bool, name, topic *string) (model.Channel, error) {
+func (e *Entity) UpdateChannel(ctx context.Context, id int64, parent *int64, private *bool, name, topic *string, userLimit, bitrate *int, stage *bool) (model.Channel, error) {
 	q := squirrel.Update("channels").
 		PlaceholderFormat(squirrel.Dollar).
 		Where(squirrel.Eq{"id": id}).
@@ -276,6 +298,15 @@ func (e *Entity) UpdateChannel(ctx context.Context, id int64, parent *int64, pri
 	if topic != nil {
 		q = q.Set("topic", *topic)
 	}
+	if userLimit != nil {
+		q = q.Set("voice_user_limit", *userLimit)
+	}
+	if bitrate != nil {
+		q = q.Set("voice_bitrate", *bitrate)
+	}
+	if stage != nil {
+		q = q.Set("voice_stage", *stage)
+	}
 	raw, args, err := q.ToSql()
 	if err != nil {
 		return model.Channel{}, fmt.Errorf("unable to create SQL query: %w", err)
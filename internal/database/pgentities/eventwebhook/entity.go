@@ -0,0 +1,29 @@
+package eventwebhook
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type EventWebhook interface {
+	CreateEventWebhook(ctx context.Context, webhook model.EventWebhook) error
+	GetEventWebhook(ctx context.Context, guildId, id int64) (model.EventWebhook, error)
+	GetGuildEventWebhooks(ctx context.Context, guildId int64) ([]model.EventWebhook, error)
+	UpdateEventWebhook(ctx context.Context, guildId, id int64, url *string, events []int64, enabled *bool) error
+	SetSecret(ctx context.Context, guildId, id int64, secret string) error
+	RecordSuccess(ctx context.Context, guildId, id int64) error
+	RecordFailure(ctx context.Context, guildId, id int64, disableAfter int) (bool, error)
+	DeleteEventWebhook(ctx context.Context, guildId, id int64) error
+	DeleteGuildEventWebhooks(ctx context.Context, guildId int64) error
+}
+
+type Entity struct {
+	c *sqlx.DB
+}
+
+func New(c *sqlx.DB) EventWebhook {
+	return &Entity{c: c}
+}
@@ -0,0 +1,202 @@
+package eventwebhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+func (e *Entity) CreateEventWebhook(ctx context.Context, webhook model.EventWebhook) error {
+	q := squirrel.Insert("event_webhooks").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("guild_id", "id", "creator_id", "url", "secret", "events").
+		Values(webhook.GuildId, webhook.Id, webhook.CreatorId, webhook.URL, webhook.Secret, webhook.Events)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to create event webhook: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) GetEventWebhook(ctx context.Context, guildId, id int64) (model.EventWebhook, error) {
+	var webhook model.EventWebhook
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("event_webhooks").
+		Where(squirrel.And{
+			squirrel.Eq{"guild_id": guildId},
+			squirrel.Eq{"id": id},
+		})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return webhook, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.GetContext(ctx, &webhook, raw, args...)
+	if err != nil {
+		return webhook, fmt.Errorf("unable to get event webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (e *Entity) GetGuildEventWebhooks(ctx context.Context, guildId int64) ([]model.EventWebhook, error) {
+	var webhooks []model.EventWebhook
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("event_webhooks").
+		Where(squirrel.Eq{"guild_id": guildId}).
+		OrderBy("id ASC")
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &webhooks, raw, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhooks, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get guild event webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// UpdateEventWebhook changes the provided fields. Enabling the webhook resets its failure counter.
+func (e *Entity) UpdateEventWebhook(ctx context.Context, guildId, id int64, url *string, events []int64, enabled *bool) error {
+	q := squirrel.Update("event_webhooks").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.And{
+			squirrel.Eq{"guild_id": guildId},
+			squirrel.Eq{"id": id},
+		})
+	if url != nil {
+		q = q.Set("url", *url)
+	}
+	if events != nil {
+		q = q.Set("events", pq.Int64Array(events))
+	}
+	if enabled != nil {
+		q = q.Set("enabled", *enabled)
+		if *enabled {
+			q = q.Set("failure_count", 0).Set("disabled_at", nil)
+		} else {
+			q = q.Set("disabled_at", squirrel.Expr("COALESCE(disabled_at, now())"))
+		}
+	}
+
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to update event webhook: %w", err)
+	}
+	return nil
+}
+
+// SetSecret replaces the signing secret of the webhook
+func (e *Entity) SetSecret(ctx context.Context, guildId, id int64, secret string) error {
+	q := squirrel.Update("event_webhooks").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("secret", secret).
+		Where(squirrel.And{
+			squirrel.Eq{"guild_id": guildId},
+			squirrel.Eq{"id": id},
+		})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to set event webhook secret: %w", err)
+	}
+	return nil
+}
+
+// RecordSuccess resets the counter of failed deliveries in a row
+func (e *Entity) RecordSuccess(ctx context.Context, guildId, id int64) error {
+	q := squirrel.Update("event_webhooks").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("failure_count", 0).
+		Where(squirrel.And{
+			squirrel.Eq{"guild_id": guildId},
+			squirrel.Eq{"id": id},
+			squirrel.NotEq{"failure_count": 0},
+		})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to reset event webhook failures: %w", err)
+	}
+	return nil
+}
+
+// RecordFailure counts a failed delivery and disables the webhook once disableAfter deliveries in a row have failed.
+// It reports whether the webhook is disabled.
+func (e *Entity) RecordFailure(ctx context.Context, guildId, id int64, disableAfter int) (bool, error) {
+	q := squirrel.Update("event_webhooks").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("failure_count", squirrel.Expr("failure_count + 1")).
+		Set("enabled", squirrel.Expr("enabled AND failure_count + 1 < ?", disableAfter)).
+		Set("disabled_at", squirrel.Expr("CASE WHEN enabled AND failure_count + 1 >= ? THEN now() ELSE disabled_at END", disableAfter)).
+		Where(squirrel.And{
+			squirrel.Eq{"guild_id": guildId},
+			squirrel.Eq{"id": id},
+		}).
+		Suffix("RETURNING enabled")
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	var enabled bool
+	err = e.c.GetContext(ctx, &enabled, raw, args...)
+	if err != nil {
+		return false, fmt.Errorf("unable to record event webhook failure: %w", err)
+	}
+	return !enabled, nil
+}
+
+func (e *Entity) DeleteEventWebhook(ctx context.Context, guildId, id int64) error {
+	q := squirrel.Delete("event_webhooks").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.And{
+			squirrel.Eq{"guild_id": guildId},
+			squirrel.Eq{"id": id},
+		})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to delete event webhook: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) DeleteGuildEventWebhooks(ctx context.Context, guildId int64) error {
+	q := squirrel.Delete("event_webhooks").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"guild_id": guildId})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to delete guild event webhooks: %w", err)
+	}
+	return nil
+}
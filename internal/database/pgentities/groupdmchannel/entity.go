@@ -15,6 +15,13 @@ type GroupDMChannel interface {
 	GetGroupDmParticipants(ctx context.Context, channelId int64) ([]model.GroupDMChannel, error)
 	IsGroupDmParticipant(ctx context.Context, channelId int64, userId int64) (bool, error)
 	GetUserGroupDmChannels(ctx context.Context, userId int64) ([]model.GroupDMChannel, error)
+	GetGroupDmParticipantsMany(ctx context.Context, channelIds []int64) ([]model.GroupDMChannel, error)
+	CreateGroupDm(ctx context.Context, channelId, ownerId int64, users []int64) error
+	GetGroupDm(ctx context.Context, channelId int64) (model.GroupDM, error)
+	GetGroupDms(ctx context.Context, channelIds []int64) ([]model.GroupDM, error)
+	SetGroupDmOwner(ctx context.Context, channelId, ownerId int64) error
+	SetGroupDmIcon(ctx context.Context, channelId int64, icon *int64) error
+	DeleteGroupDm(ctx context.Context, channelId int64) error
 }
 
 type Entity struct {
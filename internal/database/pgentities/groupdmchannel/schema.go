@@ -150,3 +150,162 @@ func (e *Entity) GetUserGroupDmChannels(ctx context.Context, userId int64) ([]mo
 	}
 	return items, nil
 }
+
+func (e *Entity) GetGroupDmParticipantsMany(ctx context.Context, channelIds []int64) ([]model.GroupDMChannel, error) {
+	if len(channelIds) == 0 {
+		return []model.GroupDMChannel{}, nil
+	}
+	var items []model.GroupDMChannel
+	q := squirrel.Select("channel_id", "user_id").
+		PlaceholderFormat(squirrel.Dollar).
+		From("group_dm_channels").
+		Where(squirrel.Eq{"channel_id": channelIds})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &items, raw, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return []model.GroupDMChannel{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("get group dm participants many error: %w", err)
+	}
+	return items, nil
+}
+
+// CreateGroupDm stores the group DM settings and adds all the users to it in one transaction
+func (e *Entity) CreateGroupDm(ctx context.Context, channelId, ownerId int64, users []int64) error {
+	tx, err := e.c.Beginx()
+	if err != nil {
+		return err
+	}
+
+	raw, args, err := squirrel.Insert("group_dms").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("channel_id", "owner_id").
+		Values(channelId, ownerId).
+		ToSql()
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, raw, args...); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("unable to create group dm: %w", err)
+	}
+
+	q := squirrel.Insert("group_dm_channels").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("channel_id", "user_id")
+	for _, user := range users {
+		q = q.Values(channelId, user)
+	}
+	raw, args, err = q.ToSql()
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, raw, args...); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("unable to add users to dm channel: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to create group dm: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) GetGroupDm(ctx context.Context, channelId int64) (model.GroupDM, error) {
+	var gdm model.GroupDM
+	raw, args, err := squirrel.Select("channel_id", "owner_id", "icon").
+		PlaceholderFormat(squirrel.Dollar).
+		From("group_dms").
+		Where(squirrel.Eq{"channel_id": channelId}).
+		ToSql()
+	if err != nil {
+		return gdm, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	if err := e.c.GetContext(ctx, &gdm, raw, args...); err != nil {
+		return model.GroupDM{}, fmt.Errorf("unable to get group dm: %w", err)
+	}
+	return gdm, nil
+}
+
+func (e *Entity) GetGroupDms(ctx context.Context, channelIds []int64) ([]model.GroupDM, error) {
+	if len(channelIds) == 0 {
+		return []model.GroupDM{}, nil
+	}
+	var items []model.GroupDM
+	raw, args, err := squirrel.Select("channel_id", "owner_id", "icon").
+		PlaceholderFormat(squirrel.Dollar).
+		From("group_dms").
+		Where(squirrel.Eq{"channel_id": channelIds}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &items, raw, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return []model.GroupDM{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get group dms: %w", err)
+	}
+	return items, nil
+}
+
+func (e *Entity) SetGroupDmOwner(ctx context.Context, channelId, ownerId int64) error {
+	raw, args, err := squirrel.Update("group_dms").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("owner_id", ownerId).
+		Where(squirrel.Eq{"channel_id": channelId}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	if _, err := e.c.ExecContext(ctx, raw, args...); err != nil {
+		return fmt.Errorf("unable to set group dm owner: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) SetGroupDmIcon(ctx context.Context, channelId int64, icon *int64) error {
+	raw, args, err := squirrel.Update("group_dms").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("icon", icon).
+		Where(squirrel.Eq{"channel_id": channelId}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	if _, err := e.c.ExecContext(ctx, raw, args...); err != nil {
+		return fmt.Errorf("unable to set group dm icon: %w", err)
+	}
+	return nil
+}
+
+// DeleteGroupDm removes the group DM settings and all remaining participants
+func (e *Entity) DeleteGroupDm(ctx context.Context, channelId int64) error {
+	tx, err := e.c.Beginx()
+	if err != nil {
+		return err
+	}
+	for _, table := range []string{"group_dm_channels", "group_dms"} {
+		raw, args, err := squirrel.Delete(table).
+			PlaceholderFormat(squirrel.Dollar).
+			Where(squirrel.Eq{"channel_id": channelId}).
+			ToSql()
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("unable to create SQL query: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, raw, args...); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("unable to delete group dm: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to delete group dm: %w", err)
+	}
+	return nil
+}
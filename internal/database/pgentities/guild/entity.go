@@ -18,6 +18,12 @@ type Guild interface {
 	SetGuildPermissions(ctx context.Context, id int64, permissions int64) error
 	UpdateGuild(ctx context.Context, id int64, name *string, icon *int64, public *bool, permissions *int64) error
 	SetSystemMessagesChannel(ctx context.Context, id int64, channelId *int64) error
+	SetGuildMFARequired(ctx context.Context, id int64, required bool) error
+	UpdateGuildDiscovery(ctx context.Context, id int64, description *string, category *model.GuildCategory) error
+	UpdateGuildScreening(ctx context.Context, id int64, level *model.VerificationLevel, rules *string, enabled *bool) error
+	SetVanityCode(ctx context.Context, id int64, code *string) error
+	GetGuildIdByVanityCode(ctx context.Context, code string) (int64, error)
+	GetDiscoverableGuilds(ctx context.Context, query string, category *model.GuildCategory, limit, offset uint64) ([]model.DiscoverableGuild, error)
 }
 
 type Entity struct {
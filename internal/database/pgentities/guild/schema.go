@@ -2,13 +2,19 @@ package guild
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/FlameInTheDark/gochat/internal/database/model"
 	"github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
 )
 
+// ErrVanityCodeTaken is returned when another guild already uses the vanity code
+var ErrVanityCodeTaken = errors.New("vanity code already taken")
+
 func (e *Entity) GetGuildById(ctx context.Context, id int64) (model.Guild, error) {
 	var g model.Guild
 
@@ -200,3 +206,197 @@ func (e *Entity) SetSystemMessagesChannel(ctx context.Context, id int64, channel
 	}
 	return nil
 }
+
+func (e *Entity) SetGuildMFARequired(ctx context.Context, id int64, required bool) error {
+	q := squirrel.Update("guilds").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("mfa_required", required).
+		Where(squirrel.Eq{"id": id})
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("unable to set mfa required: %w", err)
+	}
+	return nil
+}
+
+// UpdateGuildDiscovery sets the description and category shown in discovery, an empty description removes it
+func (e *Entity) UpdateGuildDiscovery(ctx context.Context, id int64, description *string, category *model.GuildCategory) error {
+	if description == nil && category == nil {
+		return nil
+	}
+	q := squirrel.Update("guilds").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"id": id})
+	if description != nil {
+		if *description == "" {
+			q = q.Set("description", nil)
+		} else {
+			q = q.Set("description", *description)
+		}
+	}
+	if category != nil {
+		q = q.Set("category", *category)
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("unable to update guild discovery: %w", err)
+	}
+	return nil
+}
+
+// UpdateGuildScreening sets the verification level, the rules and whether members have to accept them, empty rules remove them
+func (e *Entity) UpdateGuildScreening(ctx context.Context, id int64, level *model.VerificationLevel, rules *string, enabled *bool) error {
+	if level == nil && rules == nil && enabled == nil {
+		return nil
+	}
+	q := squirrel.Update("guilds").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"id": id})
+	if level != nil {
+		q = q.Set("verification_level", *level)
+	}
+	if rules != nil {
+		if *rules == "" {
+			q = q.Set("rules", nil)
+		} else {
+			q = q.Set("rules", *rules)
+		}
+	}
+	if enabled != nil {
+		q = q.Set("screening_enabled", *enabled)
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("unable to update guild screening: %w", err)
+	}
+	return nil
+}
+
+// SetVanityCode replaces the vanity code of the guild in guild_vanity_codes and guilds in one transaction.
+// A nil code removes it. Returns ErrVanityCodeTaken if another guild uses the code.
+func (e *Entity) SetVanityCode(ctx context.Context, id int64, code *string) error {
+	tx, err := e.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	q1 := squirrel.Delete("guild_vanity_codes").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"guild_id": id})
+	sql1, args1, err := q1.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query for vanity codes: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, sql1, args1...); err != nil {
+		return fmt.Errorf("unable to remove vanity code: %w", err)
+	}
+
+	if code != nil {
+		q2 := squirrel.Insert("guild_vanity_codes").
+			PlaceholderFormat(squirrel.Dollar).
+			Columns("vanity_code", "guild_id").
+			Values(*code, id)
+		sql2, args2, qerr := q2.ToSql()
+		if qerr != nil {
+			err = qerr
+			return fmt.Errorf("unable to create SQL query for vanity codes: %w", err)
+		}
+		if _, err = tx.ExecContext(ctx, sql2, args2...); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				return ErrVanityCodeTaken
+			}
+			return fmt.Errorf("unable to add vanity code: %w", err)
+		}
+	}
+
+	q3 := squirrel.Update("guilds").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("vanity_code", code).
+		Where(squirrel.Eq{"id": id})
+	sql3, args3, err := q3.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, sql3, args3...); err != nil {
+		return fmt.Errorf("unable to set vanity code: %w", err)
+	}
+	return nil
+}
+
+// GetGuildIdByVanityCode resolves the vanity code to the guild ID
+func (e *Entity) GetGuildIdByVanityCode(ctx context.Context, code string) (int64, error) {
+	var id int64
+	q := squirrel.Select("guild_id").
+		PlaceholderFormat(squirrel.Dollar).
+		From("guild_vanity_codes").
+		Where(squirrel.Eq{"vanity_code": code})
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	if err = e.c.GetContext(ctx, &id, sql, args...); err != nil {
+		return 0, fmt.Errorf("unable to get guild by vanity code: %w", err)
+	}
+	return id, nil
+}
+
+// GetDiscoverableGuilds returns public guilds with their member count, the largest first.
+// The query matches the name or description, case-insensitive.
+func (e *Entity) GetDiscoverableGuilds(ctx context.Context, query string, category *model.GuildCategory, limit, offset uint64) ([]model.DiscoverableGuild, error) {
+	var gs []model.DiscoverableGuild
+	q := squirrel.Select("g.*", "count(m.user_id) AS members_count").
+		PlaceholderFormat(squirrel.Dollar).
+		From("guilds g").
+		LeftJoin("members m ON m.guild_id = g.id").
+		Where(squirrel.Eq{"g.public": true}).
+		GroupBy("g.id").
+		OrderBy("members_count DESC", "g.id ASC").
+		Limit(limit).
+		Offset(offset)
+	if query != "" {
+		pattern := "%" + likeEscaper.Replace(query) + "%"
+		q = q.Where(squirrel.Or{
+			squirrel.ILike{"g.name": pattern},
+			squirrel.ILike{"g.description": pattern},
+		})
+	}
+	if category != nil {
+		q = q.Where(squirrel.Eq{"g.category": *category})
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	if err = e.c.SelectContext(ctx, &gs, sql, args...); err != nil {
+		return nil, fmt.Errorf("unable to get discoverable guilds: %w", err)
+	}
+	return gs, nil
+}
+
+// likeEscaper escapes the LIKE wildcards of user input
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
@@ -9,7 +9,7 @@ import (
 
 type Invite interface {
 	// CreateInvite inserts both guild_invites and guild_invite_codes in one transaction
-	CreateInvite(ctx context.Context, code string, inviteID, guildID, authorID int64, expiresAt int64) (model.GuildInvite, error)
+	CreateInvite(ctx context.Context, code string, inviteID, guildID, authorID int64, expiresAt int64, maxUses int, temporary bool) (model.GuildInvite, error)
 	// GetGuildInvites returns active invites for a guild joined with codes
 	GetGuildInvites(ctx context.Context, guildID int64) ([]model.GuildInvite, error)
 	// DeleteInviteByCode deletes invite by guild and code (removes mapping too)
@@ -18,6 +18,8 @@ type Invite interface {
 	DeleteInviteByID(ctx context.Context, guildID, inviteID int64) error
 	// FetchInvite uses DB function fetch_guild_invite to resolve code and return a valid invite (or no rows)
 	FetchInvite(ctx context.Context, code string) (model.GuildInvite, error)
+	// UseInvite uses DB function use_guild_invite to count one use of a valid invite (or no rows if it is expired or used up)
+	UseInvite(ctx context.Context, code string) (model.GuildInvite, error)
 }
 
 type Entity struct {
@@ -12,7 +12,8 @@ import (
 
 // CreateInvite inserts records into guild_invites and guild_invite_codes in a transaction.
 // expiresAt must be a unix seconds timestamp, will be converted to timestamptz.
-func (e *Entity) CreateInvite(ctx context.Context, code string, inviteID, guildID, authorID int64, expiresAt int64) (model.GuildInvite, error) {
+// maxUses of zero means the invite can be used unlimited times.
+func (e *Entity) CreateInvite(ctx context.Context, code string, inviteID, guildID, authorID int64, expiresAt int64, maxUses int, temporary bool) (model.GuildInvite, error) {
 	tx, err := e.c.BeginTxx(ctx, nil)
 	if err != nil {
 		return model.GuildInvite{}, err
@@ -29,8 +30,8 @@ func (e *Entity) CreateInvite(ctx context.Context, code string, inviteID, guildI
 	// Using squirrel to avoid SQL injection and keep consistency
 	q1 := squirrel.Insert("guild_invites").
 		PlaceholderFormat(squirrel.Dollar).
-		Columns("guild_id", "invite_id", "author_id", "created_at", "expires_at").
-		Values(guildID, inviteID, authorID, time.Now(), time.Unix(expiresAt, 0))
+		Columns("guild_id", "invite_id", "author_id", "created_at", "expires_at", "max_uses", "temporary").
+		Values(guildID, inviteID, authorID, time.Now(), time.Unix(expiresAt, 0), maxUses, temporary)
 	sql1, args1, err := q1.ToSql()
 	if err != nil {
 		return model.GuildInvite{}, fmt.Errorf("unable to create SQL query for invites: %w", err)
@@ -59,6 +60,8 @@ func (e *Entity) CreateInvite(ctx context.Context, code string, inviteID, guildI
 		AuthorId:   authorID,
 		CreatedAt:  time.Now(),
 		ExpiresAt:  time.Unix(expiresAt, 0),
+		MaxUses:    maxUses,
+		Temporary:  temporary,
 	}, nil
 }
 
@@ -74,6 +77,9 @@ func (e *Entity) GetGuildInvites(ctx context.Context, guildID int64) ([]model.Gu
 		"gi.author_id",
 		"gi.created_at",
 		"gi.expires_at",
+		"gi.max_uses",
+		"gi.uses",
+		"gi.temporary",
 	).
 		PlaceholderFormat(squirrel.Dollar).
 		From("active_guild_invites gi").
@@ -130,7 +136,7 @@ func (e *Entity) DeleteInviteByCode(ctx context.Context, guildID int64, code str
 // FetchInvite calls function fetch_guild_invite to return a valid invite (or 0 rows)
 func (e *Entity) FetchInvite(ctx context.Context, code string) (model.GuildInvite, error) {
 	var inv model.GuildInvite
-	// The function returns: invite_code, invite_id, guild_id, author_id, created_at, expires_at
+	// The function returns: invite_code, invite_id, guild_id, author_id, created_at, expires_at, max_uses, uses, temporary
 	err := e.c.GetContext(ctx, &inv, "SELECT * FROM fetch_guild_invite($1)", code)
 	if err != nil {
 		return model.GuildInvite{}, err
@@ -138,6 +144,17 @@ func (e *Entity) FetchInvite(ctx context.Context, code string) (model.GuildInvit
 	return inv, nil
 }
 
+// UseInvite calls function use_guild_invite to count one use of a valid invite (or 0 rows).
+// The invite is not returned when it is expired or all of its uses are taken.
+func (e *Entity) UseInvite(ctx context.Context, code string) (model.GuildInvite, error) {
+	var inv model.GuildInvite
+	err := e.c.GetContext(ctx, &inv, "SELECT * FROM use_guild_invite($1)", code)
+	if err != nil {
+		return model.GuildInvite{}, err
+	}
+	return inv, nil
+}
+
 // DeleteInviteByID removes the invite by composite key using the helper function
 func (e *Entity) DeleteInviteByID(ctx context.Context, guildID, inviteID int64) error {
 	if _, err := e.c.ExecContext(ctx, "SELECT delete_guild_invite($1, $2)", guildID, inviteID); err != nil {
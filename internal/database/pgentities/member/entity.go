@@ -9,16 +9,22 @@ import (
 )
 
 type Member interface {
-	AddMember(ctx context.Context, userID, guildID int64) error
+	AddMember(ctx context.Context, userID, guildID int64, pending bool) error
+	AddInvitedMember(ctx context.Context, userID, guildID int64, inviteCode string, temporary, pending bool) error
 	RemoveMember(ctx context.Context, userID, guildID int64) error
 	RemoveMembersByGuild(ctx context.Context, guildID int64) error
 	GetMember(ctx context.Context, userId, guildId int64) (model.Member, error)
+	GetMemberTimeout(ctx context.Context, userId, guildId int64) (time.Time, error)
 	GetMembersList(ctx context.Context, guildId int64, ids []int64) ([]model.Member, error)
 	GetGuildMembers(ctx context.Context, guildId int64) ([]model.Member, error)
+	GetPendingMembers(ctx context.Context, guildId int64) ([]model.Member, error)
+	SetPending(ctx context.Context, userId, guildId int64, pending bool) error
 	IsGuildMember(ctx context.Context, guildId, userId int64) (bool, error)
 	GetUserGuilds(ctx context.Context, userId int64) ([]model.UserGuild, error)
+	GetTemporaryGuilds(ctx context.Context, userId int64) ([]model.UserGuild, error)
 	SetTimeout(ctx context.Context, userId, guildId int64, timeout *time.Time) error
 	CountGuildMembers(ctx context.Context, guildId int64) (int64, error)
+	SampleGuildMemberIds(ctx context.Context, guildId int64, limit uint64) ([]int64, error)
 }
 
 type Entity struct {
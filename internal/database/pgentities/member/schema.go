@@ -9,11 +9,30 @@ import (
 	"github.com/Masterminds/squirrel"
 )
 
-func (e *Entity) AddMember(ctx context.Context, userID, guildID int64) error {
+// AddMember adds the member, pending members wait for the rules acceptance
+func (e *Entity) AddMember(ctx context.Context, userID, guildID int64, pending bool) error {
 	q := squirrel.Insert("members").
 		PlaceholderFormat(squirrel.Dollar).
-		Columns("user_id", "guild_id").
-		Values(userID, guildID)
+		Columns("user_id", "guild_id", "pending").
+		Values(userID, guildID, pending)
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("unable to add member: %w", err)
+	}
+	return nil
+}
+
+// AddInvitedMember adds the member with the code of the invite used to join
+func (e *Entity) AddInvitedMember(ctx context.Context, userID, guildID int64, inviteCode string, temporary, pending bool) error {
+	q := squirrel.Insert("members").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("user_id", "guild_id", "invite_code", "temporary", "pending").
+		Values(userID, guildID, inviteCode, temporary, pending)
 
 	sql, args, err := q.ToSql()
 	if err != nil {
@@ -81,6 +100,25 @@ func (e *Entity) GetMember(ctx context.Context, userId, guildId int64) (model.Me
 	return m, nil
 }
 
+// GetMemberTimeout returns the end of the member's timeout, it is in the past when the member is not timed out
+func (e *Entity) GetMemberTimeout(ctx context.Context, userId, guildId int64) (time.Time, error) {
+	var timeout time.Time
+	q := squirrel.Select("timeout").
+		PlaceholderFormat(squirrel.Dollar).
+		From("members").
+		Where(squirrel.And{squirrel.Eq{"user_id": userId}, squirrel.Eq{"guild_id": guildId}})
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return timeout, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.GetContext(ctx, &timeout, sql, args...)
+	if err != nil {
+		return timeout, fmt.Errorf("unable to get member timeout: %w", err)
+	}
+	return timeout, nil
+}
+
 func (e *Entity) GetMembersList(ctx context.Context, guildId int64, ids []int64) ([]model.Member, error) {
 	var members []model.Member
 	q := squirrel.Select("*").
@@ -123,6 +161,44 @@ func (e *Entity) GetGuildMembers(ctx context.Context, guildId int64) ([]model.Me
 	return members, nil
 }
 
+// GetPendingMembers returns the members who haven't accepted the guild rules yet, ordered by user ID
+func (e *Entity) GetPendingMembers(ctx context.Context, guildId int64) ([]model.Member, error) {
+	var members []model.Member
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("members").
+		Where(squirrel.And{squirrel.Eq{"guild_id": guildId}, squirrel.Eq{"pending": true}}).
+		OrderBy("user_id ASC")
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return members, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &members, sql, args...)
+	if err != nil {
+		return members, fmt.Errorf("unable to get pending members: %w", err)
+	}
+	return members, nil
+}
+
+// SetPending marks the member as waiting for rules acceptance or lets them in
+func (e *Entity) SetPending(ctx context.Context, userId, guildId int64, pending bool) error {
+	q := squirrel.Update("members").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.And{squirrel.Eq{"user_id": userId}, squirrel.Eq{"guild_id": guildId}}).
+		Set("pending", pending)
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("unable to set member pending: %w", err)
+	}
+	return nil
+}
+
 func (e *Entity) IsGuildMember(ctx context.Context, guildId, userId int64) (bool, error) {
 	var exists bool
 	raw := "SELECT EXISTS(SELECT 1 FROM members WHERE user_id = $1 AND guild_id = $2)"
@@ -151,6 +227,25 @@ func (e *Entity) GetUserGuilds(ctx context.Context, userId int64) ([]model.UserG
 	return guilds, nil
 }
 
+// GetTemporaryGuilds returns the guilds the user joined with a temporary invite
+func (e *Entity) GetTemporaryGuilds(ctx context.Context, userId int64) ([]model.UserGuild, error) {
+	var guilds []model.UserGuild
+	q := squirrel.Select("user_id", "guild_id").
+		PlaceholderFormat(squirrel.Dollar).
+		From("members").
+		Where(squirrel.And{squirrel.Eq{"user_id": userId}, squirrel.Eq{"temporary": true}})
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return guilds, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &guilds, sql, args...)
+	if err != nil {
+		return guilds, fmt.Errorf("unable to get temporary user guilds: %w", err)
+	}
+	return guilds, nil
+}
+
 func (e *Entity) SetTimeout(ctx context.Context, userId, guildId int64, timeout *time.Time) error {
 	q := squirrel.Update("members").
 		PlaceholderFormat(squirrel.Dollar).
@@ -184,3 +279,23 @@ func (e *Entity) CountGuildMembers(ctx context.Context, guildId int64) (int64, e
 	}
 	return count, nil
 }
+
+// SampleGuildMemberIds returns up to limit random member IDs of the guild
+func (e *Entity) SampleGuildMemberIds(ctx context.Context, guildId int64, limit uint64) ([]int64, error) {
+	var ids []int64
+	q := squirrel.Select("user_id").
+		PlaceholderFormat(squirrel.Dollar).
+		From("members").
+		Where(squirrel.Eq{"guild_id": guildId}).
+		OrderBy("random()").
+		Limit(limit)
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	if err = e.c.SelectContext(ctx, &ids, sql, args...); err != nil {
+		return nil, fmt.Errorf("unable to sample guild members: %w", err)
+	}
+	return ids, nil
+}
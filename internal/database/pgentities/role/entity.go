@@ -12,6 +12,8 @@ type Role interface {
 	GetGuildRoles(ctx context.Context, guildId int64) ([]model.Role, error)
 	GetRolesBulk(ctx context.Context, guildID int64, ids []int64) ([]model.Role, error)
 	CreateRole(ctx context.Context, id, guildId int64, name string, color int, permissions int64) error
+	CreateManagedRole(ctx context.Context, id, guildId int64, name string, permissions, managedBy int64) error
+	GetManagedRole(ctx context.Context, guildId, managedBy int64) (model.Role, error)
 	RemoveRole(ctx context.Context, id int64) error
 	SetRoleColor(ctx context.Context, id int64, color int) error
 	SetRoleName(ctx context.Context, id int64, name string) error
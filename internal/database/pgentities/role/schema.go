@@ -86,6 +86,43 @@ WHERE guild_id = $2
 	return nil
 }
 
+// CreateManagedRole creates the role of the bot application, it is placed after the other roles of the guild
+func (e *Entity) CreateManagedRole(ctx context.Context, id, guildId int64, name string, permissions, managedBy int64) error {
+	const query = `
+INSERT INTO roles (id, guild_id, name, color, permissions, position, managed_by)
+SELECT $1, $2, $3, 0, $4, COALESCE(MAX(position), -1) + 1, $5
+FROM roles
+WHERE guild_id = $2
+`
+	_, err := e.c.ExecContext(ctx, query, id, guildId, name, permissions, managedBy)
+	if err != nil {
+		return fmt.Errorf("unable to create managed role for guild %d: %w", guildId, err)
+	}
+	return nil
+}
+
+// GetManagedRole returns the role created for the bot application in the guild
+func (e *Entity) GetManagedRole(ctx context.Context, guildId, managedBy int64) (model.Role, error) {
+	var r model.Role
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("roles").
+		Where(squirrel.And{
+			squirrel.Eq{"guild_id": guildId},
+			squirrel.Eq{"managed_by": managedBy},
+		}).
+		Limit(1)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return r, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.GetContext(ctx, &r, raw, args...)
+	if err != nil {
+		return r, fmt.Errorf("unable to get managed role: %w", err)
+	}
+	return r, nil
+}
+
 func (e *Entity) RemoveRole(ctx context.Context, id int64) error {
 	q := squirrel.Delete("roles").
 		PlaceholderFormat(squirrel.Dollar).
@@ -0,0 +1,75 @@
+package rolecheck
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// accountTTL is how long the account facts are kept, they change only when the email is confirmed
+	accountTTL = time.Minute
+	// accountCacheSize limits the cached accounts, expired ones are dropped when it is reached
+	accountCacheSize = 10000
+)
+
+// account holds the facts the verification levels check
+type account struct {
+	bot           bool
+	emailVerified bool
+	createdAt     time.Time
+	expires       time.Time
+}
+
+// accountCache keeps the accounts of members without roles, so permission checks in guilds
+// with a verification level don't load the user on every message, reaction and typing event.
+type accountCache struct {
+	mu      sync.Mutex
+	entries map[int64]account
+}
+
+func newAccountCache() *accountCache {
+	return &accountCache{entries: make(map[int64]account)}
+}
+
+func (c *accountCache) get(userID int64, now time.Time) (account, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a, ok := c.entries[userID]
+	if !ok || now.After(a.expires) {
+		return account{}, false
+	}
+	return a, true
+}
+
+func (c *accountCache) put(userID int64, a account, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= accountCacheSize {
+		for id, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= accountCacheSize {
+			clear(c.entries)
+		}
+	}
+	a.expires = now.Add(accountTTL)
+	c.entries[userID] = a
+}
+
+// account returns the account facts of the user from the cache or the database
+func (e *Entity) account(ctx context.Context, userID int64) (account, error) {
+	now := time.Now()
+	if a, ok := e.accounts.get(userID, now); ok {
+		return a, nil
+	}
+	u, err := e.u.GetUserById(ctx, userID)
+	if err != nil {
+		return account{}, err
+	}
+	a := account{bot: u.Bot, emailVerified: u.EmailVerifiedAt != nil, createdAt: u.CreatedAt}
+	e.accounts.put(userID, a, now)
+	return a, nil
+}
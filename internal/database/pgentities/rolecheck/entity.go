@@ -14,6 +14,8 @@ import (
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/guildchannels"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/member"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/role"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/user"
+	"github.com/FlameInTheDark/gochat/internal/database/pgentities/usermfa"
 	"github.com/FlameInTheDark/gochat/internal/database/pgentities/userrole"
 	"github.com/FlameInTheDark/gochat/internal/permissions"
 )
@@ -23,6 +25,7 @@ type RoleCheck interface {
 	ChannelPerm(ctx context.Context, guildID, channelID, userID int64, perm ...permissions.RolePermission) (*model.Channel, *model.GuildChannel, *model.Guild, bool, error)
 	GuildPerm(ctx context.Context, guildID, userID int64, perm ...permissions.RolePermission) (*model.Guild, bool, error)
 	GetChannelPermissions(ctx context.Context, guildID, channelID, userID int64) (int64, error)
+	MemberPending(ctx context.Context, guildID, userID int64) (bool, error)
 }
 
 type Entity struct {
@@ -36,6 +39,10 @@ type Entity struct {
 	m    member.Member
 	dm   dmchannel.DmChannel
 	gdm  groupdmchannel.GroupDMChannel
+	mfa  usermfa.UserMFA
+	u    user.User
+
+	accounts *accountCache
 }
 
 func New(pg *pgdb.DB) RoleCheck {
@@ -50,5 +57,9 @@ func New(pg *pgdb.DB) RoleCheck {
 		m:    member.New(pg.Conn()),
 		dm:   dmchannel.New(pg.Conn()),
 		gdm:  groupdmchannel.New(pg.Conn()),
+		mfa:  usermfa.New(pg.Conn()),
+		u:    user.New(pg.Conn()),
+
+		accounts: newAccountCache(),
 	}
 }
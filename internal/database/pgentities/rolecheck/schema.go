@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/FlameInTheDark/gochat/internal/database/model"
 	"github.com/FlameInTheDark/gochat/internal/permissions"
@@ -149,6 +150,18 @@ func (e *Entity) ChannelPerm(ctx context.Context, guildID, channelID, userID int
 			return nil, nil, nil, false, nil
 		}
 
+		permAll, err = e.withoutPendingMember(ctx, &guild, userID, roleIDs, permAll)
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+
+		if permissions.RequiresMFA(perm...) {
+			permAll, err = e.withoutUnverifiedModeration(ctx, &guild, userID, permAll)
+			if err != nil {
+				return nil, nil, nil, false, err
+			}
+		}
+
 		// Check if user has all required permissions
 		if permissions.CheckPermissions(permAll, perm...) {
 			return &channel, &gc, &guild, true, nil
@@ -253,6 +266,15 @@ func (e *Entity) GetChannelPermissions(ctx context.Context, guildID, channelID,
 		return 0, err
 	}
 
+	permAll, err = e.withoutPendingMember(ctx, &guild, userID, roleIDs, permAll)
+	if err != nil {
+		return 0, err
+	}
+	permAll, err = e.withoutUnverifiedModeration(ctx, &guild, userID, permAll)
+	if err != nil {
+		return 0, err
+	}
+
 	// If channel is private and user has no role overrides, access may still be restricted in higher layers.
 	return permAll, nil
 }
@@ -291,9 +313,113 @@ func (e *Entity) GuildPerm(ctx context.Context, guildID, userID int64, perm ...p
 		permAll = permissions.AddRoles(permAll, role.Permissions)
 	}
 
+	permAll, err = e.withoutPendingMember(ctx, &guild, userID, roleIDs, permAll)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if permissions.RequiresMFA(perm...) {
+		permAll, err = e.withoutUnverifiedModeration(ctx, &guild, userID, permAll)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
 	// Check if user has all required permissions
 	if permissions.CheckPermissions(permAll, perm...) {
 		return &guild, true, nil
 	}
 	return nil, false, nil
 }
+
+// withoutUnverifiedModeration removes moderation permissions of a user without two-factor authentication
+// when the guild requires it. The guild owner is not restricted.
+func (e *Entity) withoutUnverifiedModeration(ctx context.Context, guild *model.Guild, userID, permAll int64) (int64, error) {
+	if !guild.MFARequired || userID == guild.OwnerId || !permissions.HasOverlap(permAll, permissions.ModerationPermissions) {
+		return permAll, nil
+	}
+	enabled, err := e.mfa.IsEnabled(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if enabled {
+		return permAll, nil
+	}
+	return permissions.SubtractRoles(permAll, permissions.ModerationPermissions), nil
+}
+
+// MemberPending reports whether the member hasn't passed the screening of the guild yet:
+// the rules are not accepted or the verification level requirements are not met.
+func (e *Entity) MemberPending(ctx context.Context, guildID, userID int64) (bool, error) {
+	guild, err := e.g.GetGuildById(ctx, guildID)
+	if err != nil {
+		return false, err
+	}
+	return e.memberPending(ctx, &guild, userID, nil)
+}
+
+// withoutPendingMember leaves only PendingPermissions to members who haven't passed the guild screening
+func (e *Entity) withoutPendingMember(ctx context.Context, guild *model.Guild, userID int64, roleIDs []int64, permAll int64) (int64, error) {
+	pending, err := e.memberPending(ctx, guild, userID, roleIDs)
+	if err != nil {
+		return 0, err
+	}
+	if pending {
+		return permAll & permissions.PendingPermissions, nil
+	}
+	return permAll, nil
+}
+
+// memberPending checks the screening of the member, the role IDs of the member are loaded when nil.
+// Members with a role, bots and the guild owner are exempt from the verification level.
+// Users without a member record are left to the permission checks.
+// Only guilds with screening or a verification level load the member row, the account is cached.
+func (e *Entity) memberPending(ctx context.Context, guild *model.Guild, userID int64, roleIDs []int64) (bool, error) {
+	if userID == guild.OwnerId || (!guild.ScreeningEnabled && guild.VerificationLevel == model.VerificationLevelNone) {
+		return false, nil
+	}
+	member, err := e.m.GetMember(ctx, userID, guild.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if guild.ScreeningEnabled && member.Pending {
+		return true, nil
+	}
+	if guild.VerificationLevel == model.VerificationLevelNone {
+		return false, nil
+	}
+
+	if roleIDs == nil {
+		roleIDs, err = e.getUserRoleIDs(ctx, guild.Id, userID)
+		if err != nil {
+			return false, err
+		}
+	}
+	if len(roleIDs) > 0 {
+		return false, nil
+	}
+	acc, err := e.account(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if acc.bot {
+		return false, nil
+	}
+	return !verificationPassed(guild.VerificationLevel, acc.emailVerified, acc.createdAt, member.JoinAt, time.Now()), nil
+}
+
+// verificationPassed checks the requirements of the verification level
+func verificationPassed(level model.VerificationLevel, emailVerified bool, createdAt, joinedAt, now time.Time) bool {
+	if level >= model.VerificationLevelLow && !emailVerified {
+		return false
+	}
+	if level >= model.VerificationLevelMedium && now.Sub(createdAt) < model.VerificationAccountAge {
+		return false
+	}
+	if level >= model.VerificationLevelHigh && now.Sub(joinedAt) < model.VerificationMembershipAge {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package rolecheck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+func TestVerificationPassed(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	fresh := now.Add(-time.Minute)
+
+	cases := []struct {
+		name          string
+		level         model.VerificationLevel
+		emailVerified bool
+		createdAt     time.Time
+		joinedAt      time.Time
+		want          bool
+	}{
+		{"none", model.VerificationLevelNone, false, fresh, fresh, true},
+		{"low without email", model.VerificationLevelLow, false, old, old, false},
+		{"low", model.VerificationLevelLow, true, fresh, fresh, true},
+		{"medium new account", model.VerificationLevelMedium, true, fresh, old, false},
+		{"medium", model.VerificationLevelMedium, true, old, fresh, true},
+		{"high new member", model.VerificationLevelHigh, true, old, fresh, false},
+		{"high", model.VerificationLevelHigh, true, old, old, true},
+	}
+	for _, c := range cases {
+		if got := verificationPassed(c.level, c.emailVerified, c.createdAt, c.joinedAt, now); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestAccountCacheExpires(t *testing.T) {
+	c := newAccountCache()
+	now := time.Now()
+	c.put(1, account{emailVerified: true}, now)
+
+	if a, ok := c.get(1, now.Add(accountTTL/2)); !ok || !a.emailVerified {
+		t.Fatalf("expected cached account, got %+v %v", a, ok)
+	}
+	if _, ok := c.get(1, now.Add(accountTTL+time.Second)); ok {
+		t.Fatal("expected expired account to be reloaded")
+	}
+}
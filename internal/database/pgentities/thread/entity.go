@@ -0,0 +1,29 @@
+package thread
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type Thread interface {
+	CreateThread(ctx context.Context, thread model.Thread) error
+	GetThread(ctx context.Context, guildID, id int64) (model.Thread, error)
+	GetParentThreads(ctx context.Context, guildID, parentID int64, archived *bool) ([]model.Thread, error)
+	UpdateThread(ctx context.Context, guildID, id int64, archived *bool, autoArchiveMinutes *int) (model.Thread, error)
+	SetLastActivity(ctx context.Context, guildID, id int64, at time.Time) error
+	GetInactiveThreads(ctx context.Context, now time.Time, limit int) ([]model.Thread, error)
+	ArchiveIfInactive(ctx context.Context, guildID, id int64, now time.Time) (bool, error)
+	DeleteThread(ctx context.Context, guildID, id int64) error
+}
+
+type Entity struct {
+	c *sqlx.DB
+}
+
+func New(c *sqlx.DB) Thread {
+	return &Entity{c: c}
+}
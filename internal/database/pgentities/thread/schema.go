@@ -0,0 +1,214 @@
+package thread
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+// inactiveCondition matches threads whose last activity is older than their auto-archive duration
+const inactiveCondition = "NOT archived AND last_activity_at + make_interval(mins => auto_archive_minutes) <= ?"
+
+func (e *Entity) CreateThread(ctx context.Context, thread model.Thread) error {
+	q := squirrel.Insert("threads").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("id", "guild_id", "parent_id", "owner_id", "message_id", "auto_archive_minutes").
+		Values(thread.Id, thread.GuildId, thread.ParentId, thread.OwnerId, thread.MessageId, thread.AutoArchiveMinutes)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to create thread: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) GetThread(ctx context.Context, guildID, id int64) (model.Thread, error) {
+	var t model.Thread
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("threads").
+		Where(
+			squirrel.And{
+				squirrel.Eq{"guild_id": guildID},
+				squirrel.Eq{"id": id},
+			},
+		)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return t, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.GetContext(ctx, &t, raw, args...)
+	if err != nil {
+		return t, fmt.Errorf("unable to get thread: %w", err)
+	}
+	return t, nil
+}
+
+// GetParentThreads returns threads started in the parent channel, most recently active first.
+// Nil archived returns both active and archived threads.
+func (e *Entity) GetParentThreads(ctx context.Context, guildID, parentID int64, archived *bool) ([]model.Thread, error) {
+	var threads []model.Thread
+	where := squirrel.And{
+		squirrel.Eq{"guild_id": guildID},
+		squirrel.Eq{"parent_id": parentID},
+	}
+	if archived != nil {
+		where = append(where, squirrel.Eq{"archived": *archived})
+	}
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("threads").
+		Where(where).
+		OrderBy("last_activity_at DESC")
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return threads, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &threads, raw, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return threads, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get parent threads: %w", err)
+	}
+	return threads, nil
+}
+
+// UpdateThread changes archive state and auto-archive duration.
+// Unarchiving resets the activity timestamp so the thread is not archived again by the next sweep.
+func (e *Entity) UpdateThread(ctx context.Context, guildID, id int64, archived *bool, autoArchiveMinutes *int) (model.Thread, error) {
+	var t model.Thread
+	q := squirrel.Update("threads").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(
+			squirrel.And{
+				squirrel.Eq{"guild_id": guildID},
+				squirrel.Eq{"id": id},
+			},
+		).
+		Suffix("RETURNING *")
+	if archived != nil {
+		q = q.Set("archived", *archived)
+		if *archived {
+			q = q.Set("archived_at", squirrel.Expr("now()"))
+		} else {
+			q = q.Set("archived_at", nil).
+				Set("last_activity_at", squirrel.Expr("now()"))
+		}
+	}
+	if autoArchiveMinutes != nil {
+		q = q.Set("auto_archive_minutes", *autoArchiveMinutes)
+	}
+	if archived == nil && autoArchiveMinutes == nil {
+		return e.GetThread(ctx, guildID, id)
+	}
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return t, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.GetContext(ctx, &t, raw, args...)
+	if err != nil {
+		return t, fmt.Errorf("unable to update thread: %w", err)
+	}
+	return t, nil
+}
+
+func (e *Entity) SetLastActivity(ctx context.Context, guildID, id int64, at time.Time) error {
+	q := squirrel.Update("threads").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("last_activity_at", at).
+		Where(
+			squirrel.And{
+				squirrel.Eq{"guild_id": guildID},
+				squirrel.Eq{"id": id},
+			},
+		)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to set thread last activity: %w", err)
+	}
+	return nil
+}
+
+// GetInactiveThreads returns up to limit active threads that passed their auto-archive duration
+func (e *Entity) GetInactiveThreads(ctx context.Context, now time.Time, limit int) ([]model.Thread, error) {
+	var threads []model.Thread
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("threads").
+		Where(inactiveCondition, now).
+		OrderBy("last_activity_at ASC").
+		Limit(uint64(limit))
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return threads, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &threads, raw, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return threads, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get inactive threads: %w", err)
+	}
+	return threads, nil
+}
+
+// ArchiveIfInactive archives the thread only if it is still inactive,
+// so a message sent after the sweep selected the thread keeps it open.
+func (e *Entity) ArchiveIfInactive(ctx context.Context, guildID, id int64, now time.Time) (bool, error) {
+	q := squirrel.Update("threads").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("archived", true).
+		Set("archived_at", now).
+		Where(
+			squirrel.And{
+				squirrel.Eq{"guild_id": guildID},
+				squirrel.Eq{"id": id},
+				squirrel.Expr(inactiveCondition, now),
+			},
+		)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	res, err := e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return false, fmt.Errorf("unable to archive thread: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("unable to archive thread: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (e *Entity) DeleteThread(ctx context.Context, guildID, id int64) error {
+	q := squirrel.Delete("threads").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(
+			squirrel.And{
+				squirrel.Eq{"guild_id": guildID},
+				squirrel.Eq{"id": id},
+			},
+		)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to delete thread: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package threadmember
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type ThreadMember interface {
+	AddThreadMember(ctx context.Context, threadID, userID int64) error
+	RemoveThreadMember(ctx context.Context, threadID, userID int64) error
+	RemoveThreadMembers(ctx context.Context, threadID int64) error
+	GetThreadMembers(ctx context.Context, threadID int64) ([]model.ThreadMember, error)
+}
+
+type Entity struct {
+	c *sqlx.DB
+}
+
+func New(c *sqlx.DB) ThreadMember {
+	return &Entity{c: c}
+}
@@ -0,0 +1,85 @@
+package threadmember
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+// AddThreadMember adds the user to the thread, adding an existing member is a no-op
+func (e *Entity) AddThreadMember(ctx context.Context, threadID, userID int64) error {
+	q := squirrel.Insert("thread_members").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("thread_id", "user_id").
+		Values(threadID, userID).
+		Suffix("ON CONFLICT DO NOTHING")
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to add thread member: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) RemoveThreadMember(ctx context.Context, threadID, userID int64) error {
+	q := squirrel.Delete("thread_members").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(
+			squirrel.And{
+				squirrel.Eq{"thread_id": threadID},
+				squirrel.Eq{"user_id": userID},
+			},
+		)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to remove thread member: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) RemoveThreadMembers(ctx context.Context, threadID int64) error {
+	q := squirrel.Delete("thread_members").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"thread_id": threadID})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to remove thread members: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) GetThreadMembers(ctx context.Context, threadID int64) ([]model.ThreadMember, error) {
+	var members []model.ThreadMember
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("thread_members").
+		Where(squirrel.Eq{"thread_id": threadID}).
+		OrderBy("joined_at ASC")
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return members, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &members, raw, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return members, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get thread members: %w", err)
+	}
+	return members, nil
+}
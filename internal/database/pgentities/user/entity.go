@@ -12,6 +12,7 @@ type User interface {
 	GetUserById(ctx context.Context, id int64) (model.User, error)
 	GetUsersList(ctx context.Context, ids []int64) ([]model.User, error)
 	CreateUser(ctx context.Context, id int64, name string) error
+	CreateBotUser(ctx context.Context, id int64, name string) error
 	SetUserAvatar(ctx context.Context, id, attachmentId int64) error
 	SetUsername(ctx context.Context, id, name string) error
 	SetUserBlocked(ctx context.Context, id int64, blocked bool) error
@@ -71,11 +71,12 @@ func (e *Entity) GetUsersList(ctx context.Context, ids []int64) ([]model.User, e
 	return users, nil
 }
 
+// CreateUser creates the account of a registration, it is called once the email is confirmed
 func (e *Entity) CreateUser(ctx context.Context, id int64, name string) error {
 	q := squirrel.Insert("users").
 		PlaceholderFormat(squirrel.Dollar).
-		Columns("id", "name", "blocked").
-		Values(id, name, false)
+		Columns("id", "name", "blocked", "email_verified_at").
+		Values(id, name, false, squirrel.Expr("now()"))
 	raw, args, err := q.ToSql()
 	if err != nil {
 		return fmt.Errorf("unable to create SQL query: %w", err)
@@ -87,6 +88,22 @@ func (e *Entity) CreateUser(ctx context.Context, id int64, name string) error {
 	return nil
 }
 
+func (e *Entity) CreateBotUser(ctx context.Context, id int64, name string) error {
+	q := squirrel.Insert("users").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("id", "name", "blocked", "bot").
+		Values(id, name, false, true)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to create bot user: %w", err)
+	}
+	return nil
+}
+
 func (e *Entity) SetUserAvatar(ctx context.Context, id, attachmentId int64) error {
 	q := squirrel.Update("users").
 		PlaceholderFormat(squirrel.Dollar).
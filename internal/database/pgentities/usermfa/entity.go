@@ -0,0 +1,29 @@
+package usermfa
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type UserMFA interface {
+	GetMFA(ctx context.Context, userId int64) (model.UserMFA, error)
+	IsEnabled(ctx context.Context, userId int64) (bool, error)
+	SetPendingSecret(ctx context.Context, userId int64, secret string) (bool, error)
+	EnableMFA(ctx context.Context, userId, step int64, codeHashes []string) (bool, error)
+	UseStep(ctx context.Context, userId, step int64) (bool, error)
+	DisableMFA(ctx context.Context, userId int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userId int64, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userId int64, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userId int64) (int, error)
+}
+
+type Entity struct {
+	c *sqlx.DB
+}
+
+func New(c *sqlx.DB) UserMFA {
+	return &Entity{c: c}
+}
@@ -0,0 +1,269 @@
+package usermfa
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+func (e *Entity) GetMFA(ctx context.Context, userId int64) (model.UserMFA, error) {
+	var m model.UserMFA
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("user_mfa").
+		Where(squirrel.Eq{"user_id": userId})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return m, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.GetContext(ctx, &m, raw, args...)
+	if err != nil {
+		return m, fmt.Errorf("unable to get user mfa: %w", err)
+	}
+	return m, nil
+}
+
+// IsEnabled returns true if the user confirmed the TOTP enrollment
+func (e *Entity) IsEnabled(ctx context.Context, userId int64) (bool, error) {
+	m, err := e.GetMFA(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return m.Enabled(), nil
+}
+
+// SetPendingSecret stores a new secret waiting for confirmation, returns false if the user already has 2FA enabled
+func (e *Entity) SetPendingSecret(ctx context.Context, userId int64, secret string) (bool, error) {
+	q := squirrel.Insert("user_mfa").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("user_id", "totp_secret").
+		Values(userId, secret).
+		Suffix("ON CONFLICT (user_id) DO UPDATE SET totp_secret = EXCLUDED.totp_secret, last_step = 0, created_at = now() WHERE user_mfa.enabled_at IS NULL")
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	res, err := e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return false, fmt.Errorf("unable to set pending mfa secret: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("unable to set pending mfa secret: %w", err)
+	}
+	return n > 0, nil
+}
+
+// EnableMFA confirms the pending secret with the step of the verified code and stores the recovery codes.
+// Returns false if there is no pending secret or the step was already used.
+func (e *Entity) EnableMFA(ctx context.Context, userId, step int64, codeHashes []string) (_ bool, err error) {
+	tx, err := e.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	q := squirrel.Update("user_mfa").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("enabled_at", squirrel.Expr("now()")).
+		Set("last_step", step).
+		Where(
+			squirrel.And{
+				squirrel.Eq{"user_id": userId},
+				squirrel.Eq{"enabled_at": nil},
+				squirrel.Lt{"last_step": step},
+			},
+		)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return false, fmt.Errorf("unable to enable user mfa: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("unable to enable user mfa: %w", err)
+	}
+	if n == 0 {
+		_ = tx.Rollback()
+		return false, nil
+	}
+	if err = replaceCodes(ctx, tx, userId, codeHashes); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// UseStep records the step of an accepted code, returns false if this or a later step was already used
+func (e *Entity) UseStep(ctx context.Context, userId, step int64) (bool, error) {
+	q := squirrel.Update("user_mfa").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("last_step", step).
+		Where(
+			squirrel.And{
+				squirrel.Eq{"user_id": userId},
+				squirrel.Lt{"last_step": step},
+			},
+		)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	res, err := e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return false, fmt.Errorf("unable to use mfa step: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("unable to use mfa step: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DisableMFA removes the secret and the recovery codes
+func (e *Entity) DisableMFA(ctx context.Context, userId int64) (err error) {
+	tx, err := e.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	for _, table := range []string{"user_recovery_codes", "user_mfa"} {
+		raw, args, qerr := squirrel.Delete(table).
+			PlaceholderFormat(squirrel.Dollar).
+			Where(squirrel.Eq{"user_id": userId}).
+			ToSql()
+		if qerr != nil {
+			return fmt.Errorf("unable to create SQL query: %w", qerr)
+		}
+		if _, err = tx.ExecContext(ctx, raw, args...); err != nil {
+			return fmt.Errorf("unable to disable user mfa: %w", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes invalidates all recovery codes of the user and stores the new ones
+func (e *Entity) ReplaceRecoveryCodes(ctx context.Context, userId int64, codeHashes []string) (err error) {
+	tx, err := e.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = replaceCodes(ctx, tx, userId, codeHashes); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// UseRecoveryCode marks the code as used, returns false if there is no such unused code
+func (e *Entity) UseRecoveryCode(ctx context.Context, userId int64, codeHash string) (bool, error) {
+	q := squirrel.Update("user_recovery_codes").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("used_at", squirrel.Expr("now()")).
+		Where(
+			squirrel.And{
+				squirrel.Eq{"user_id": userId},
+				squirrel.Eq{"code_hash": codeHash},
+				squirrel.Eq{"used_at": nil},
+			},
+		)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	res, err := e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return false, fmt.Errorf("unable to use recovery code: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("unable to use recovery code: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CountRecoveryCodes returns the number of unused recovery codes
+func (e *Entity) CountRecoveryCodes(ctx context.Context, userId int64) (int, error) {
+	var count int
+	q := squirrel.Select("count(*)").
+		PlaceholderFormat(squirrel.Dollar).
+		From("user_recovery_codes").
+		Where(
+			squirrel.And{
+				squirrel.Eq{"user_id": userId},
+				squirrel.Eq{"used_at": nil},
+			},
+		)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.GetContext(ctx, &count, raw, args...)
+	if err != nil {
+		return 0, fmt.Errorf("unable to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+func replaceCodes(ctx context.Context, tx *sqlx.Tx, userId int64, codeHashes []string) error {
+	raw, args, err := squirrel.Delete("user_recovery_codes").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"user_id": userId}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, raw, args...); err != nil {
+		return fmt.Errorf("unable to remove recovery codes: %w", err)
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	q := squirrel.Insert("user_recovery_codes").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("user_id", "code_hash")
+	for _, h := range codeHashes {
+		q = q.Values(userId, h)
+	}
+	raw, args, err = q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, raw, args...); err != nil {
+		return fmt.Errorf("unable to create recovery codes: %w", err)
+	}
+	return nil
+}
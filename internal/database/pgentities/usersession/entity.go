@@ -0,0 +1,27 @@
+package usersession
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type UserSession interface {
+	CreateSession(ctx context.Context, s model.UserSession) error
+	GetSession(ctx context.Context, userId, id int64) (model.UserSession, error)
+	GetActiveSessions(ctx context.Context, userId int64) ([]model.UserSession, error)
+	RotateSession(ctx context.Context, userId, id int64, oldJti, newJti, device, ip string, expires time.Time) (bool, error)
+	RevokeSession(ctx context.Context, userId, id int64) (bool, error)
+	RevokeUserSessions(ctx context.Context, userId int64) ([]int64, error)
+}
+
+type Entity struct {
+	c *sqlx.DB
+}
+
+func New(c *sqlx.DB) UserSession {
+	return &Entity{c: c}
+}
@@ -0,0 +1,163 @@
+package usersession
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+func (e *Entity) CreateSession(ctx context.Context, s model.UserSession) error {
+	q := squirrel.Insert("user_sessions").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("id", "user_id", "refresh_jti", "device", "ip", "expires_at").
+		Values(s.Id, s.UserId, s.RefreshJti, s.Device, s.IP, s.ExpiresAt)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to create user session: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) GetSession(ctx context.Context, userId, id int64) (model.UserSession, error) {
+	var s model.UserSession
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("user_sessions").
+		Where(
+			squirrel.And{
+				squirrel.Eq{"user_id": userId},
+				squirrel.Eq{"id": id},
+			},
+		)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return s, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.GetContext(ctx, &s, raw, args...)
+	if err != nil {
+		return s, fmt.Errorf("unable to get user session: %w", err)
+	}
+	return s, nil
+}
+
+// GetActiveSessions returns sessions that are not revoked or expired, most recently used first
+func (e *Entity) GetActiveSessions(ctx context.Context, userId int64) ([]model.UserSession, error) {
+	var sessions []model.UserSession
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("user_sessions").
+		Where(
+			squirrel.And{
+				squirrel.Eq{"user_id": userId},
+				squirrel.Eq{"revoked_at": nil},
+				squirrel.Expr("expires_at > now()"),
+			},
+		).
+		OrderBy("last_used_at DESC")
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return sessions, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &sessions, raw, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sessions, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get user sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RotateSession replaces the refresh token ID of an active session.
+// Returns false if the session was revoked, expired or oldJti is no longer the current token.
+func (e *Entity) RotateSession(ctx context.Context, userId, id int64, oldJti, newJti, device, ip string, expires time.Time) (bool, error) {
+	q := squirrel.Update("user_sessions").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("refresh_jti", newJti).
+		Set("device", device).
+		Set("ip", ip).
+		Set("last_used_at", squirrel.Expr("now()")).
+		Set("expires_at", expires).
+		Where(
+			squirrel.And{
+				squirrel.Eq{"user_id": userId},
+				squirrel.Eq{"id": id},
+				squirrel.Eq{"refresh_jti": oldJti},
+				squirrel.Eq{"revoked_at": nil},
+				squirrel.Expr("expires_at > now()"),
+			},
+		)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	res, err := e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return false, fmt.Errorf("unable to rotate user session: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("unable to rotate user session: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RevokeSession revokes the session, returns false if it was not active
+func (e *Entity) RevokeSession(ctx context.Context, userId, id int64) (bool, error) {
+	q := squirrel.Update("user_sessions").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("revoked_at", squirrel.Expr("now()")).
+		Where(
+			squirrel.And{
+				squirrel.Eq{"user_id": userId},
+				squirrel.Eq{"id": id},
+				squirrel.Eq{"revoked_at": nil},
+			},
+		)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	res, err := e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return false, fmt.Errorf("unable to revoke user session: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("unable to revoke user session: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RevokeUserSessions revokes all active sessions of the user and returns their IDs
+func (e *Entity) RevokeUserSessions(ctx context.Context, userId int64) ([]int64, error) {
+	var ids []int64
+	q := squirrel.Update("user_sessions").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("revoked_at", squirrel.Expr("now()")).
+		Where(
+			squirrel.And{
+				squirrel.Eq{"user_id": userId},
+				squirrel.Eq{"revoked_at": nil},
+			},
+		).
+		Suffix("RETURNING id")
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &ids, raw, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to revoke user sessions: %w", err)
+	}
+	return ids, nil
+}
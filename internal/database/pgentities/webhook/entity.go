@@ -0,0 +1,26 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type Webhook interface {
+	CreateWebhook(ctx context.Context, webhook model.Webhook) error
+	GetWebhook(ctx context.Context, id int64) (model.Webhook, error)
+	GetChannelWebhooks(ctx context.Context, channelId int64) ([]model.Webhook, error)
+	SetTokenHash(ctx context.Context, id int64, tokenHash string) error
+	DeleteWebhook(ctx context.Context, id int64) error
+	DeleteChannelWebhooks(ctx context.Context, channelId int64) error
+}
+
+type Entity struct {
+	c *sqlx.DB
+}
+
+func New(c *sqlx.DB) Webhook {
+	return &Entity{c: c}
+}
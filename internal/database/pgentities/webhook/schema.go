@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+func (e *Entity) CreateWebhook(ctx context.Context, webhook model.Webhook) error {
+	q := squirrel.Insert("webhooks").
+		PlaceholderFormat(squirrel.Dollar).
+		Columns("id", "guild_id", "channel_id", "creator_id", "name", "avatar_url", "token_hash").
+		Values(webhook.Id, webhook.GuildId, webhook.ChannelId, webhook.CreatorId, webhook.Name, webhook.AvatarURL, webhook.TokenHash)
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to create webhook: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) GetWebhook(ctx context.Context, id int64) (model.Webhook, error) {
+	var webhook model.Webhook
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("webhooks").
+		Where(squirrel.Eq{"id": id})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return webhook, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.GetContext(ctx, &webhook, raw, args...)
+	if err != nil {
+		return webhook, fmt.Errorf("unable to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (e *Entity) GetChannelWebhooks(ctx context.Context, channelId int64) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	q := squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		From("webhooks").
+		Where(squirrel.Eq{"channel_id": channelId}).
+		OrderBy("id ASC")
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	err = e.c.SelectContext(ctx, &webhooks, raw, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhooks, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get channel webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// SetTokenHash replaces the webhook token, the previous URL stops working
+func (e *Entity) SetTokenHash(ctx context.Context, id int64, tokenHash string) error {
+	q := squirrel.Update("webhooks").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("token_hash", tokenHash).
+		Where(squirrel.Eq{"id": id})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to set webhook token: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) DeleteWebhook(ctx context.Context, id int64) error {
+	q := squirrel.Delete("webhooks").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"id": id})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to delete webhook: %w", err)
+	}
+	return nil
+}
+
+func (e *Entity) DeleteChannelWebhooks(ctx context.Context, channelId int64) error {
+	q := squirrel.Delete("webhooks").
+		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"channel_id": channelId})
+	raw, args, err := q.ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to create SQL query: %w", err)
+	}
+	_, err = e.c.ExecContext(ctx, raw, args...)
+	if err != nil {
+		return fmt.Errorf("unable to delete channel webhooks: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+type AuditLogChange struct {
+	Key string `json:"key" example:"name"`          // Changed property
+	Old any    `json:"old,omitempty" example:"old"` // Value before the change
+	New any    `json:"new,omitempty" example:"new"` // Value after the change
+}
+
+type AuditLogEntry struct {
+	Id        int64            `json:"id" example:"2230469276416868352"`                  // Audit record ID
+	GuildId   int64            `json:"guild_id" example:"2230469276416868352"`            // Guild ID
+	ActorId   int64            `json:"actor_id" example:"2230469276416868352"`            // User who made the change
+	Action    int              `json:"action" example:"22"`                               // Action type. Check the audit log documentation for the list of actions.
+	TargetId  *int64           `json:"target_id,omitempty" example:"2230469276416868352"` // Affected entity ID: member, role, channel, invite or emoji
+	Changes   []AuditLogChange `json:"changes,omitempty"`                                 // Before and after values of changed properties
+	Reason    *string          `json:"reason,omitempty" example:"spam"`                   // Reason provided by the actor
+	CreatedAt time.Time        `json:"created_at"`                                        // Time of the change
+}
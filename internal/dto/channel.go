@@ -20,7 +20,56 @@ type Channel struct {
 	Roles         []int64           `json:"roles,omitempty" example:"2230469276416868352"`          // Roles IDs
 	LastMessageId int64             `json:"last_message_id" example:"2230469276416868352"`          // ID of the last message in the channel
 	VoiceRegion   *string           `json:"voice_region,omitempty" example:"us-east"`               // Voice channel region
+	UserLimit     int               `json:"user_limit,omitempty" example:"10"`                      // Voice channel user limit, 0 means unlimited
+	Bitrate       int               `json:"bitrate,omitempty" example:"64"`                         // Voice channel audio bitrate cap in kbps, 0 means the server default
+	Stage         bool              `json:"stage,omitempty" default:"false"`                        // Voice channel in stage mode, only approved speakers may speak
 	CreatedAt     time.Time         `json:"created_at"`                                             // Timestamp of channel creation
+	Thread        *ThreadMetadata   `json:"thread,omitempty"`                                       // Thread state. Only set for thread channels
+	OwnerId       *int64            `json:"owner_id,omitempty" example:"2230469276416868352"`       // For group DM channels: the owner's user ID
+	Icon          *Icon             `json:"icon,omitempty"`                                         // For group DM channels: icon metadata
+	Recipients    []int64           `json:"recipients,omitempty" example:"2230469276416868352"`     // For group DM channels: user IDs of all recipients
+}
+
+type ThreadMetadata struct {
+	OwnerId             int64      `json:"owner_id" example:"2230469276416868352"`             // ID of the user who started the thread
+	MessageId           *int64     `json:"message_id,omitempty" example:"2230469276416868352"` // ID of the parent channel message the thread was started from
+	Archived            bool       `json:"archived"`                                           // Whether the thread is archived
+	AutoArchiveDuration int        `json:"auto_archive_duration" example:"1440"`               // Minutes of inactivity before the thread is archived
+	ArchivedAt          *time.Time `json:"archived_at,omitempty"`                              // Timestamp of the last archive state change
+	LastActivityAt      time.Time  `json:"last_activity_at"`                                   // Timestamp of the last message in the thread
+}
+
+// NewThreadChannel builds the thread channel sent in thread events from the channel row and its thread metadata
+func NewThreadChannel(c *model.Channel, t *model.Thread) Channel {
+	return Channel{
+		Id:            c.Id,
+		Type:          c.Type,
+		GuildId:       &t.GuildId,
+		Name:          c.Name,
+		ParentId:      c.ParentID,
+		Topic:         c.Topic,
+		LastMessageId: c.LastMessage,
+		CreatedAt:     c.CreatedAt,
+		Thread:        NewThreadMetadata(t),
+	}
+}
+
+// NewThreadMetadata builds the thread state of a thread channel
+func NewThreadMetadata(t *model.Thread) *ThreadMetadata {
+	return &ThreadMetadata{
+		OwnerId:             t.OwnerId,
+		MessageId:           t.MessageId,
+		Archived:            t.Archived,
+		AutoArchiveDuration: t.AutoArchiveMinutes,
+		ArchivedAt:          t.ArchivedAt,
+		LastActivityAt:      t.LastActivityAt,
+	}
+}
+
+type ThreadMember struct {
+	ThreadId int64     `json:"thread_id" example:"2230469276416868352"` // Thread ID
+	UserId   int64     `json:"user_id" example:"2230469276416868352"`   // Member user ID
+	JoinedAt time.Time `json:"joined_at"`                               // Timestamp of joining the thread
 }
 
 type ChannelOrder struct {
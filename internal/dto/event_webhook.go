@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+type EventWebhook struct {
+	Id           int64      `json:"id" example:"2230469276416868352"`                // Event webhook ID
+	GuildId      int64      `json:"guild_id" example:"2230469276416868352"`          // Guild ID
+	CreatorId    int64      `json:"creator_id" example:"2230469276416868352"`        // User who created the webhook
+	URL          string     `json:"url" example:"https://example.com/gochat/events"` // HTTPS endpoint receiving the events
+	Events       []int64    `json:"events" example:"100,200"`                        // Subscribed event types
+	Enabled      bool       `json:"enabled" example:"true"`                          // Disabled webhooks receive no events
+	FailureCount int        `json:"failure_count" example:"0"`                       // Failed deliveries in a row
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`                           // When the webhook was disabled
+	Secret       string     `json:"secret,omitempty"`                                // Signing secret, returned only on creation and secret rotation
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type EventWebhookDelivery struct {
+	Id         int64     `json:"id" example:"2230469276416868352"`       // Delivery attempt ID
+	EventId    int64     `json:"event_id" example:"2230469276416868352"` // Event ID, the same for all attempts of an event
+	EventType  int       `json:"event_type" example:"100"`               // Event type
+	Attempt    int       `json:"attempt" example:"1"`                    // Attempt number, starting at 1
+	StatusCode int       `json:"status_code" example:"204"`              // Response status, 0 when no response was received
+	Error      *string   `json:"error,omitempty"`                        // Failure reason
+	DurationMs int       `json:"duration_ms" example:"120"`              // Request duration
+	CreatedAt  time.Time `json:"created_at"`
+}
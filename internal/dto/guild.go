@@ -1,10 +1,29 @@
 package dto
 
 type Guild struct {
-	Id          int64  `json:"id" example:"2230469276416868352"`    // Guild ID
-	Name        string `json:"name" example:"My Guild"`             // Guild Name
-	Icon        *Icon  `json:"icon,omitempty"`                      // Icon metadata
-	Owner       int64  `json:"owner" example:"2230469276416868352"` // Owner ID
-	Public      bool   `json:"public" default:"false"`              // Whether the guild is public
-	Permissions int64  `json:"permissions" default:"7927905"`       // Default guild Permissions. Check the permissions documentation for more info.
+	Id                int64   `json:"id" example:"2230469276416868352"`                    // Guild ID
+	Name              string  `json:"name" example:"My Guild"`                             // Guild Name
+	Icon              *Icon   `json:"icon,omitempty"`                                      // Icon metadata
+	Owner             int64   `json:"owner" example:"2230469276416868352"`                 // Owner ID
+	Public            bool    `json:"public" default:"false"`                              // Whether the guild is public
+	Permissions       int64   `json:"permissions" default:"7927905"`                       // Default guild Permissions. Check the permissions documentation for more info.
+	MFARequired       bool    `json:"mfa_required" default:"false"`                        // Whether moderation permissions require two-factor authentication
+	Description       *string `json:"description,omitempty" example:"A place to hang out"` // Description shown in discovery
+	Category          int     `json:"category" example:"1"`                                // Discovery category
+	VanityCode        *string `json:"vanity_code,omitempty" example:"my-guild"`            // Vanity invite code
+	VerificationLevel int     `json:"verification_level" example:"1"`                      // What members without roles need before they can post
+	Rules             *string `json:"rules,omitempty" example:"Be nice"`                   // Rules new members accept when screening is enabled
+	ScreeningEnabled  bool    `json:"screening_enabled" default:"false"`                   // Whether new members have to accept the rules
+}
+
+// DiscoverableGuild is a public guild listed in discovery
+type DiscoverableGuild struct {
+	Id           int64   `json:"id" example:"2230469276416868352"`                    // Guild ID
+	Name         string  `json:"name" example:"My Guild"`                             // Guild Name
+	Icon         *Icon   `json:"icon,omitempty"`                                      // Icon metadata
+	Description  *string `json:"description,omitempty" example:"A place to hang out"` // Guild description
+	Category     int     `json:"category" example:"1"`                                // Discovery category
+	VanityCode   *string `json:"vanity_code,omitempty" example:"my-guild"`            // Vanity invite code
+	MembersCount int64   `json:"members_count" example:"1520"`                        // Number of members
+	OnlineCount  int64   `json:"online_count" example:"230"`                          // Approximate number of online members
 }
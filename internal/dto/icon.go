@@ -1,12 +1,13 @@
 package dto
 
-// IconUpload describes newly created guild icon placeholder
+// IconUpload describes newly created guild or group DM icon placeholder
 type IconUpload struct {
-	Id      int64 `json:"id" example:"2230469276416868352"`
-	GuildId int64 `json:"guild_id" example:"2230469276416868352"`
+	Id        int64 `json:"id" example:"2230469276416868352"`
+	GuildId   int64 `json:"guild_id,omitempty" example:"2230469276416868352"`
+	ChannelId int64 `json:"channel_id,omitempty" example:"2230469276416868352"`
 }
 
-// Icon is a full guild icon description returned in guild payloads
+// Icon is a full guild or group DM icon description
 type Icon struct {
 	Id       int64  `json:"id" example:"2230469276416868352"`
 	URL      string `json:"url" example:"https://cdn.example.com/icons/2230/2231.webp"`
@@ -7,8 +7,12 @@ type GuildInvite struct {
 	Code      string    `json:"code"`
 	GuildId   int64     `json:"guild_id"`
 	AuthorId  int64     `json:"author_id"`
+	Author    *User     `json:"author,omitempty"` // Inviter, set in the guild invites list
 	CreatedAt time.Time `json:"created_at"`
 	ExpiresAt time.Time `json:"expires_at"`
+	MaxUses   int       `json:"max_uses" example:"10"` // Zero means unlimited
+	Uses      int       `json:"uses" example:"3"`
+	Temporary bool      `json:"temporary"` // Members joined with the invite are removed when they disconnect
 }
 
 type InvitePreview struct {
@@ -19,4 +23,6 @@ type InvitePreview struct {
 	CreatedAt    time.Time `json:"created_at"`
 	ExpiresAt    time.Time `json:"expires_at"`
 	MembersCount int       `json:"members_count"`
+	Temporary    bool      `json:"temporary"`
+	Vanity       bool      `json:"vanity"` // The code is the vanity code of the guild, it has no ID, author or expiration
 }
@@ -3,9 +3,13 @@ package dto
 import "time"
 
 type Member struct {
-	User     User      `json:"user"`                                          // Guild member data
-	Username *string   `json:"username" example:"FancyUserName"`              // Username in this guild
-	Avatar   *int64    `json:"avatar" example:"2230469276416868352"`          // Avatar ID
-	JoinAt   time.Time `json:"join_at"`                                       // Join date
-	Roles    []int64   `json:"roles,omitempty" example:"2230469276416868352"` // List of assigned role IDs
+	User       User       `json:"user"`                                          // Guild member data
+	Username   *string    `json:"username" example:"FancyUserName"`              // Username in this guild
+	Avatar     *int64     `json:"avatar" example:"2230469276416868352"`          // Avatar ID
+	JoinAt     time.Time  `json:"join_at"`                                       // Join date
+	Roles      []int64    `json:"roles,omitempty" example:"2230469276416868352"` // List of assigned role IDs
+	Timeout    *time.Time `json:"timeout,omitempty"`                             // Time until the member is timed out, omitted when not timed out
+	InviteCode *string    `json:"invite_code,omitempty" example:"PWBJ124G"`      // Code of the invite the member joined with, only shown to members who can manage invites
+	Temporary  bool       `json:"temporary,omitempty"`                           // Joined with a temporary invite, removed on disconnect unless a role is assigned
+	Pending    bool       `json:"pending,omitempty"`                             // Has not accepted the guild rules yet
 }
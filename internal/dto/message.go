@@ -7,15 +7,31 @@ import (
 )
 
 type Message struct {
-	Id          int64         `json:"id" example:"2230469276416868352"`         // Message ID
-	ChannelId   int64         `json:"channel_id" example:"2230469276416868352"` // Channel id the message was sent to
-	Author      User          `json:"author"`
-	Content     string        `json:"content" example:"Hello world!"`
-	Attachments []Attachment  `json:"attachments,omitempty"`
-	Embeds      []embed.Embed `json:"embeds,omitempty"`
-	Flags       int           `json:"flags,omitempty"` // Bitmask. Includes suppress-embeds and banned-author markers in API responses.
-	Type        int           `json:"type" example:"0"`
-	UpdatedAt   *time.Time    `json:"updated_at,omitempty"` // Timestamp of the last message edit
+	Id          int64             `json:"id" example:"2230469276416868352"`         // Message ID
+	ChannelId   int64             `json:"channel_id" example:"2230469276416868352"` // Channel id the message was sent to
+	Author      User              `json:"author"`
+	Content     string            `json:"content" example:"Hello world!"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Embeds      []embed.Embed     `json:"embeds,omitempty"`
+	Reactions   []MessageReaction `json:"reactions,omitempty"` // Reactions grouped by emoji
+	Flags       int               `json:"flags,omitempty"`     // Bitmask. Includes suppress-embeds and banned-author markers in API responses.
+	Type        int               `json:"type" example:"0"`
+	ThreadId    *int64            `json:"thread_id,omitempty" example:"2230469276416868352"` // ID of the thread started from this message
+	UpdatedAt   *time.Time        `json:"updated_at,omitempty"`                              // Timestamp of the last message edit
+
+	MessageReference  *int64             `json:"message_reference,omitempty" example:"2230469276416868352"` // ID of the message this message replies to
+	ReferencedMessage *ReferencedMessage `json:"referenced_message,omitempty"`                              // Compact copy of the replied message
+}
+
+// ReferencedMessage is a compact copy of the message a reply points to.
+// Deleted messages are returned as a tombstone with only IDs and the deleted marker set.
+type ReferencedMessage struct {
+	Id        int64  `json:"id" example:"2230469276416868352"`         // Referenced message ID
+	ChannelId int64  `json:"channel_id" example:"2230469276416868352"` // Channel id of the referenced message
+	Author    *User  `json:"author,omitempty"`                         // Author of the referenced message
+	Content   string `json:"content,omitempty" example:"Hello world!"` // Content of the referenced message
+	Flags     int    `json:"flags,omitempty"`                          // Message flags of the referenced message
+	Deleted   bool   `json:"deleted,omitempty" example:"false"`        // True if the referenced message was deleted
 }
 
 type Attachment struct {
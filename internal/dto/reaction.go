@@ -0,0 +1,12 @@
+package dto
+
+type ReactionEmoji struct {
+	Id   *int64 `json:"id,string,omitempty" example:"2230469276416868352"` // Custom guild emoji ID, empty for unicode emoji
+	Name string `json:"name" example:"👍"`                                  // Unicode emoji or custom emoji name
+}
+
+type MessageReaction struct {
+	Emoji ReactionEmoji `json:"emoji"`
+	Count int           `json:"count" example:"3"` // Number of users reacted with this emoji
+	Me    bool          `json:"me"`                // Whether the current user reacted with this emoji
+}
@@ -7,4 +7,5 @@ type Role struct {
 	Color       int    `json:"color"`                                  // Role color. Will change username color. Represent RGB color in one Integer value.
 	Permissions int64  `json:"permissions"`                            // Role permissions. Check the permissions documentation for more info.
 	Position    int    `json:"position" example:"0"`                   // Role position. Lower values are shown first in guild role lists.
+	Managed     bool   `json:"managed,omitempty"`                      // Role of a bot, can not be assigned to members
 }
@@ -5,4 +5,5 @@ type User struct {
 	Name          string      `json:"name" example:"FancyUserName"`
 	Discriminator string      `json:"discriminator" example:"uniquename"`
 	Avatar        *AvatarData `json:"avatar,omitempty"`
+	Bot           bool        `json:"bot,omitempty"` // Account is an application bot
 }
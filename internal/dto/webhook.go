@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+type Webhook struct {
+	Id        int64     `json:"id" example:"2230469276416868352"`                              // Webhook ID, used as the author ID of its messages
+	GuildId   int64     `json:"guild_id" example:"2230469276416868352"`                        // Guild ID
+	ChannelId int64     `json:"channel_id" example:"2230469276416868352"`                      // Channel the webhook posts to
+	CreatorId int64     `json:"creator_id" example:"2230469276416868352"`                      // User who created the webhook
+	Name      string    `json:"name" example:"CI"`                                             // Default author name of the messages
+	AvatarURL *string   `json:"avatar_url,omitempty" example:"https://example.com/avatar.png"` // Default author avatar of the messages
+	Token     string    `json:"token,omitempty"`                                               // Secret token, returned only on creation and token rotation
+	URL       string    `json:"url,omitempty"`                                                 // Execution URL with the token, returned only on creation and token rotation
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -4,26 +4,32 @@ import (
 	"encoding/json"
 
 	"github.com/FlameInTheDark/gochat/internal/dto"
-	"github.com/nats-io/nats.go"
+	"github.com/FlameInTheDark/gochat/internal/mq/durable"
 )
 
 const MakeEmbedSubject = "embed.make"
 
+// Stream keeps embed requests until the embedder acknowledges them
+var Stream = durable.Stream{
+	Name:     "EMBEDDER",
+	Subjects: []string{MakeEmbedSubject},
+}
+
 type MakeEmbedRequest struct {
 	GuildId *int64      `json:"guild_id,omitempty"`
 	Message dto.Message `json:"message"`
 }
 
 type Queue struct {
-	conn *nats.Conn
+	pub *durable.Publisher
 }
 
 func New(conn string) (*Queue, error) {
-	nc, err := nats.Connect(conn, nats.Compression(true))
+	pub, err := durable.NewPublisher(conn, Stream)
 	if err != nil {
 		return nil, err
 	}
-	return &Queue{conn: nc}, nil
+	return &Queue{pub: pub}, nil
 }
 
 func (q *Queue) MakeEmbed(msg MakeEmbedRequest) error {
@@ -31,10 +37,9 @@ func (q *Queue) MakeEmbed(msg MakeEmbedRequest) error {
 	if err != nil {
 		return err
 	}
-	return q.conn.Publish(MakeEmbedSubject, data)
+	return q.pub.Publish(MakeEmbedSubject, data)
 }
 
 func (q *Queue) Close() error {
-	q.conn.Close()
-	return nil
+	return q.pub.Close()
 }
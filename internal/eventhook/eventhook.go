@@ -0,0 +1,118 @@
+// Package eventhook describes outgoing event webhooks: the guild events an endpoint can subscribe to,
+// the delivery jobs passed between the dispatcher and the delivery worker, and the signed request body.
+package eventhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/FlameInTheDark/gochat/internal/mq/durable"
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+const (
+	DeliverySubject = "eventhook.delivery"
+
+	// Headers of a delivery request
+	HeaderSignature = "X-Gochat-Signature"
+	HeaderTimestamp = "X-Gochat-Timestamp"
+	HeaderEvent     = "X-Gochat-Event"
+	HeaderDelivery  = "X-Gochat-Delivery"
+
+	signaturePrefix = "sha256="
+)
+
+// Stream keeps delivery jobs until the endpoint accepts them or the retries run out
+var Stream = durable.Stream{
+	Name:     "EVENT_WEBHOOKS",
+	Subjects: []string{DeliverySubject},
+	MaxAge:   time.Hour * 24,
+}
+
+// Backoff is the delay before each retry of a failed delivery
+var Backoff = []time.Duration{time.Second * 10, time.Minute, time.Minute * 5, time.Minute * 30, time.Hour * 2}
+
+// events are the guild events an event webhook can subscribe to
+var events = map[mqmsg.EventType]bool{
+	mqmsg.EventTypeMessageCreate:          true,
+	mqmsg.EventTypeMessageUpdate:          true,
+	mqmsg.EventTypeMessageDelete:          true,
+	mqmsg.EventTypeMessageReactionAdd:     true,
+	mqmsg.EventTypeMessageReactionRemove:  true,
+	mqmsg.EventTypeChannelPinsUpdate:      true,
+	mqmsg.EventTypeGuildUpdate:            true,
+	mqmsg.EventTypeChannelCreate:          true,
+	mqmsg.EventTypeChannelUpdate:          true,
+	mqmsg.EventTypeChannelDelete:          true,
+	mqmsg.EventTypeGuildRoleCreate:        true,
+	mqmsg.EventTypeGuildRoleUpdate:        true,
+	mqmsg.EventTypeGuildRoleDelete:        true,
+	mqmsg.EventTypeThreadCreate:           true,
+	mqmsg.EventTypeThreadUpdate:           true,
+	mqmsg.EventTypeThreadDelete:           true,
+	mqmsg.EventTypeGuildEmojiCreate:       true,
+	mqmsg.EventTypeGuildEmojiUpdate:       true,
+	mqmsg.EventTypeGuildEmojiDelete:       true,
+	mqmsg.EventTypeGuildMemberAdd:         true,
+	mqmsg.EventTypeGuildMemberUpdate:      true,
+	mqmsg.EventTypeGuildMemberRemove:      true,
+	mqmsg.EventTypeGuildMemberAddRole:     true,
+	mqmsg.EventTypeGuildMemberRemoveRole:  true,
+	mqmsg.EventTypeGuildMemberModeration:  true,
+	mqmsg.EventTypeGuildMemberJoinVoice:   true,
+	mqmsg.EventTypeGuildMemberLeaveVoice:  true,
+	mqmsg.EventTypeGuildMemberStreamStart: true,
+	mqmsg.EventTypeGuildMemberStreamStop:  true,
+}
+
+// Allowed reports whether an event webhook can subscribe to the event type
+func Allowed(t mqmsg.EventType) bool {
+	return events[t]
+}
+
+// Job is a single event to deliver to a webhook. EventId stays the same across retries.
+type Job struct {
+	GuildId   int64           `json:"guild_id"`
+	WebhookId int64           `json:"webhook_id"`
+	EventId   int64           `json:"event_id"`
+	EventType mqmsg.EventType `json:"event_type"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Payload is the JSON body posted to the webhook endpoint
+type Payload struct {
+	Id        int64           `json:"id"`
+	WebhookId int64           `json:"webhook_id"`
+	GuildId   int64           `json:"guild_id"`
+	EventType mqmsg.EventType `json:"t"`
+	Data      json.RawMessage `json:"d"`
+}
+
+// Sign returns the signature header value: the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivery, receivers should also reject old timestamps
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// GuildID returns the guild_id field of the event data
+func GuildID(data json.RawMessage) (int64, bool) {
+	var aux struct {
+		GuildId *int64 `json:"guild_id"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil || aux.GuildId == nil {
+		return 0, false
+	}
+	return *aux.GuildId, true
+}
@@ -0,0 +1,41 @@
+package eventhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/FlameInTheDark/gochat/internal/mq/mqmsg"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	signature := Sign("secret", 1700000000, body)
+
+	if !Verify("secret", 1700000000, body, signature) {
+		t.Fatal("expected signature to verify")
+	}
+	if Verify("other", 1700000000, body, signature) {
+		t.Fatal("expected signature with another secret to fail")
+	}
+	if Verify("secret", 1700000001, body, signature) {
+		t.Fatal("expected signature with another timestamp to fail")
+	}
+}
+
+func TestGuildID(t *testing.T) {
+	if id, ok := GuildID(json.RawMessage(`{"guild_id":2230469276416868352,"message":{}}`)); !ok || id != 2230469276416868352 {
+		t.Fatalf("expected guild id, got %d, %t", id, ok)
+	}
+	if _, ok := GuildID(json.RawMessage(`{"guild_id":null}`)); ok {
+		t.Fatal("expected DM event without guild")
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	if !Allowed(mqmsg.EventTypeMessageCreate) || !Allowed(mqmsg.EventTypeGuildMemberModeration) {
+		t.Fatal("expected guild events to be allowed")
+	}
+	if Allowed(mqmsg.EventTypeChannelUserTyping) || Allowed(mqmsg.EventTypeRTCJoin) {
+		t.Fatal("expected typing and RTC events to be rejected")
+	}
+}
@@ -14,8 +14,15 @@ import (
 
 type JWTUser struct {
 	Id int64
+	// Login session the token was issued for, zero for tokens issued before sessions were tracked
+	SessionId int64
+	// Request is authenticated with a bot token
+	Bot bool
 }
 
+// RefreshTokenLifetime is how long a refresh token and its login session stay valid without use
+const RefreshTokenLifetime = 30 * 24 * time.Hour
+
 func GetUser(c *fiber.Ctx) (*JWTUser, error) {
 	user, ok := c.Locals("user").(*jwt.Token)
 	if !ok {
@@ -30,27 +37,46 @@ func GetUserFromToken(token *jwt.Token) (*JWTUser, error) {
 		return nil, fmt.Errorf("could not get claims")
 	}
 
-	return &JWTUser{Id: claims.UserID}, nil
+	return &JWTUser{Id: claims.UserID, SessionId: claims.SessionID, Bot: claims.Bot}, nil
+}
+
+// GetClaims returns claims of the token from the context
+func GetClaims(c *fiber.Ctx) (*Claims, error) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return nil, fmt.Errorf("could not find user in context")
+	}
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return nil, fmt.Errorf("could not get claims")
+	}
+	return claims, nil
 }
 
 type Claims struct {
 	UserID    int64  `json:"user_id"`
 	TokenType string `json:"typ"`
+	SessionID int64  `json:"sid,omitempty"`
+	Bot       bool   `json:"bot,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func generateJTI() string {
+// NewJTI generates a random refresh token ID
+func NewJTI() string {
 	b := make([]byte, 16)
 	rand.Read(b)
 	return hex.EncodeToString(b)
 }
 
-func IssueTokens(userID int64, secret string) (access, refresh string, err error) {
+// IssueTokens signs an access and a refresh token of the login session. The refresh token gets the jti ID,
+// the session stores it to reject refresh tokens that were already rotated.
+func IssueTokens(userID, sessionID int64, jti, secret string) (access, refresh string, err error) {
 	now := time.Now()
 
 	accessTok := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
 		UserID:    userID,
 		TokenType: "access",
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "gochat",
 			Audience:  []string{"api"},
@@ -65,25 +91,45 @@ func IssueTokens(userID int64, secret string) (access, refresh string, err error
 	refreshTok := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
 		UserID:    userID,
 		TokenType: "refresh",
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "gochat",
 			Audience:  []string{"refresh"},
 			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(30 * 24 * time.Hour)),
-			ID:        generateJTI(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(RefreshTokenLifetime)),
+			ID:        jti,
 		},
 	})
 	refresh, err = refreshTok.SignedString([]byte(secret))
 	return
 }
 
+// BotToken returns the unsigned access token the bot token of the request is resolved to.
+// It passes the same token type and audience checks as a user access token.
+func BotToken(botID int64) *jwt.Token {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		UserID:    botID,
+		TokenType: "access",
+		Bot:       true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   "gochat",
+			Audience: []string{"api"},
+		},
+	})
+}
+
 func isPublicEmojiRoute(path string) bool {
 	return stringsstd.HasPrefix(path, "/emoji/")
 }
 
+// IsWebhookRoute reports whether the path executes a webhook, those requests are authenticated by the webhook token
+func IsWebhookRoute(path string) bool {
+	return stringsstd.HasPrefix(path, "/api/v1/message/webhook/")
+}
+
 func RequireTokenType(expect string, audience ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		if isPublicEmojiRoute(c.Path()) {
+		if isPublicEmojiRoute(c.Path()) || IsWebhookRoute(c.Path()) {
 			return c.Next()
 		}
 
@@ -0,0 +1,39 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	ErrUnableToGetMemberTimeout = "unable to get member"
+	ErrMemberTimedOut           = "member is timed out"
+)
+
+// MemberTimeouts is the part of the member entity the timeout check needs
+type MemberTimeouts interface {
+	GetMemberTimeout(ctx context.Context, userId, guildId int64) (time.Time, error)
+}
+
+// CheckMemberTimeout returns 403 if the user has an active timeout in the guild.
+// Users without a member record are left to the permission checks.
+func CheckMemberTimeout(ctx context.Context, members MemberTimeouts, guildId, userId int64) error {
+	if members == nil {
+		return nil
+	}
+	until, err := members.GetMemberTimeout(ctx, userId, guildId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, ErrUnableToGetMemberTimeout)
+	}
+	if until.After(time.Now()) {
+		return fiber.NewError(fiber.StatusForbidden, ErrMemberTimedOut)
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package helper
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"io"
 )
 
@@ -22,3 +24,9 @@ func RandomToken(n int) (string, error) {
 	// Encode to base64 and return the first n characters
 	return base64.RawURLEncoding.EncodeToString(randomBytes)[:n], nil
 }
+
+// HashToken returns the hex encoded SHA-256 hash of the token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
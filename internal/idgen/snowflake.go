@@ -8,6 +8,9 @@ import (
 
 const bucket_size = 1000 * 60 * 60 * 24 * 4
 
+// epoch is the default snowflake start time, IDs are generated relative to it
+var epoch = time.Date(2008, 11, 10, 23, 0, 0, 0, time.UTC)
+
 func New(nodeId uint16) {
 	snowflake.SetMachineID(nodeId)
 }
@@ -24,3 +27,13 @@ func GetTime(id int64) time.Time {
 	sid := snowflake.ParseID(uint64(id))
 	return sid.GenerateTime()
 }
+
+// FromTime returns the smallest ID that could be generated at the given time.
+// Useful as an exclusive upper bound when selecting records created before t.
+func FromTime(t time.Time) int64 {
+	ms := t.UTC().UnixMilli() - epoch.UnixMilli()
+	if ms < 0 {
+		return 0
+	}
+	return ms << (snowflake.MachineIDLength + snowflake.SequenceLength)
+}
@@ -3,28 +3,33 @@ package indexmq
 import (
 	"encoding/json"
 
-	"github.com/nats-io/nats.go"
-
 	"github.com/FlameInTheDark/gochat/internal/dto"
+	"github.com/FlameInTheDark/gochat/internal/mq/durable"
 )
 
 const (
-	indexerQueue       = "indexer.message"
-	indexerDeleteQueue = "indexer.delete"
-	indexerUpdateQueue = "indexer.update"
+	IndexSubject  = "indexer.message"
+	DeleteSubject = "indexer.delete"
+	UpdateSubject = "indexer.update"
 )
 
+// Stream keeps indexer messages until the indexer acknowledges them
+var Stream = durable.Stream{
+	Name:     "INDEXER",
+	Subjects: []string{IndexSubject, DeleteSubject, UpdateSubject},
+}
+
 type IndexMQ struct {
-	conn *nats.Conn
+	pub *durable.Publisher
 }
 
 func NewIndexMQ(conn string) (*IndexMQ, error) {
-	c, err := nats.Connect(conn, nats.Compression(true))
+	pub, err := durable.NewPublisher(conn, Stream)
 	if err != nil {
 		return nil, err
 	}
 	return &IndexMQ{
-		conn: c,
+		pub: pub,
 	}, nil
 }
 
@@ -33,7 +38,7 @@ func (i *IndexMQ) IndexMessage(msg dto.IndexMessage) error {
 	if err != nil {
 		return err
 	}
-	err = i.conn.Publish(indexerQueue, data)
+	err = i.pub.Publish(IndexSubject, data)
 	if err != nil {
 		return err
 	}
@@ -45,7 +50,7 @@ func (i *IndexMQ) IndexDeleteMessage(msg dto.IndexDeleteMessage) error {
 	if err != nil {
 		return err
 	}
-	err = i.conn.Publish(indexerDeleteQueue, data)
+	err = i.pub.Publish(DeleteSubject, data)
 	if err != nil {
 		return err
 	}
@@ -57,7 +62,7 @@ func (i *IndexMQ) UpdateMessage(msg dto.IndexMessage) error {
 	if err != nil {
 		return err
 	}
-	err = i.conn.Publish(indexerUpdateQueue, data)
+	err = i.pub.Publish(UpdateSubject, data)
 	if err != nil {
 		return err
 	}
@@ -65,6 +70,5 @@ func (i *IndexMQ) UpdateMessage(msg dto.IndexMessage) error {
 }
 
 func (i *IndexMQ) Close() error {
-	i.conn.Close()
-	return nil
+	return i.pub.Close()
 }
@@ -0,0 +1,284 @@
+// Package durable moves work queues onto NATS JetStream: messages are stored until a consumer acknowledges them,
+// failed messages are retried with backoff and end up in the dead letter stream when the retries run out.
+package durable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	// DeadLetterStream keeps messages that could not be processed, under the original subject prefixed with "dlq."
+	DeadLetterStream  = "DEAD_LETTERS"
+	deadLetterPrefix  = "dlq."
+	deadLetterMaxAge  = time.Hour * 24 * 14
+	defaultAckWait    = time.Second * 30
+	defaultHandleTime = time.Second * 10
+
+	// Headers set on dead letters
+	HeaderSubject    = "Gochat-Original-Subject"
+	HeaderError      = "Gochat-Error"
+	HeaderDeliveries = "Gochat-Deliveries"
+)
+
+// DefaultBackoff is the delay before each retry of a failed message
+var DefaultBackoff = []time.Duration{time.Second, time.Second * 5, time.Second * 30, time.Minute * 2, time.Minute * 10}
+
+// Stream is a work queue stream: a message is removed once a consumer acknowledges it
+type Stream struct {
+	Name     string
+	Subjects []string
+	MaxAge   time.Duration
+}
+
+// EnsureStreams creates or updates the streams and the dead letter stream
+func EnsureStreams(ctx context.Context, js jetstream.JetStream, streams ...Stream) error {
+	for _, s := range streams {
+		_, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:      s.Name,
+			Subjects:  s.Subjects,
+			Retention: jetstream.WorkQueuePolicy,
+			Storage:   jetstream.FileStorage,
+			MaxAge:    s.MaxAge,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to create stream %s: %w", s.Name, err)
+		}
+	}
+	_, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      DeadLetterStream,
+		Subjects:  []string{deadLetterPrefix + ">"},
+		Retention: jetstream.LimitsPolicy,
+		Storage:   jetstream.FileStorage,
+		MaxAge:    deadLetterMaxAge,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create dead letter stream: %w", err)
+	}
+	return nil
+}
+
+// Publisher publishes messages and waits until the stream stores them
+type Publisher struct {
+	nc *nats.Conn
+	js jetstream.JetStream
+}
+
+// NewPublisher connects to NATS and makes sure the streams exist
+func NewPublisher(conn string, streams ...Stream) (*Publisher, error) {
+	nc, err := nats.Connect(conn, nats.Compression(true))
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	if err := EnsureStreams(ctx, js, streams...); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return &Publisher{nc: nc, js: js}, nil
+}
+
+func (p *Publisher) Publish(subject string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	_, err := p.js.Publish(ctx, subject, data)
+	return err
+}
+
+func (p *Publisher) Close() error {
+	p.nc.Close()
+	return nil
+}
+
+// Handler processes a message. Returned errors are retried unless wrapped with Permanent.
+type Handler func(ctx context.Context, data []byte) error
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error that a retry can not fix, the message goes to the dead letter stream right away
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether the error was marked with Permanent
+func IsPermanent(err error) bool {
+	var perm permanentError
+	return errors.As(err, &perm)
+}
+
+type attemptKey struct{}
+
+type attempt struct {
+	delivered uint64
+	last      bool
+}
+
+// Attempt returns the delivery number of the message being handled, starting at 1,
+// and whether it is the last one, so a failure moves the message to the dead letters
+func Attempt(ctx context.Context) (uint64, bool) {
+	a, ok := ctx.Value(attemptKey{}).(attempt)
+	if !ok {
+		return 1, false
+	}
+	return a.delivered, a.last
+}
+
+// ConsumerConfig describes a durable consumer shared by all replicas of a service
+type ConsumerConfig struct {
+	Stream  string
+	Durable string
+	// Handlers by subject
+	Handlers map[string]Handler
+	// Delays between retries, DefaultBackoff if empty
+	Backoff []time.Duration
+	// Time limit for a single handler call
+	HandleTimeout time.Duration
+}
+
+// Consumer delivers stream messages to the handlers
+type Consumer struct {
+	cc  jetstream.ConsumeContext
+	js  jetstream.JetStream
+	cfg ConsumerConfig
+	log *slog.Logger
+}
+
+// Consume creates or updates the durable consumer and starts processing messages.
+// Replicas using the same durable name share the messages, each message is processed by one of them.
+func Consume(ctx context.Context, js jetstream.JetStream, cfg ConsumerConfig, logger *slog.Logger) (*Consumer, error) {
+	if len(cfg.Backoff) == 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	if cfg.HandleTimeout == 0 {
+		cfg.HandleTimeout = defaultHandleTime
+	}
+
+	cons, err := js.CreateOrUpdateConsumer(ctx, cfg.Stream, consumerConfig(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create consumer %s: %w", cfg.Durable, err)
+	}
+
+	c := &Consumer{js: js, cfg: cfg, log: logger}
+	cc, err := cons.Consume(c.handle)
+	if err != nil {
+		return nil, fmt.Errorf("unable to start consumer %s: %w", cfg.Durable, err)
+	}
+	c.cc = cc
+	return c, nil
+}
+
+// consumerConfig builds the JetStream consumer. BackOff is left empty: JetStream uses it as the ack deadline
+// of each delivery instead of AckWait, so a handler slower than the first delay would get the message redelivered
+// to another replica while it still runs. Retries are spaced with NakWithDelay instead.
+func consumerConfig(cfg ConsumerConfig) jetstream.ConsumerConfig {
+	subjects := make([]string, 0, len(cfg.Handlers))
+	for subject := range cfg.Handlers {
+		subjects = append(subjects, subject)
+	}
+	return jetstream.ConsumerConfig{
+		Durable:        cfg.Durable,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		AckWait:        max(defaultAckWait, cfg.HandleTimeout*2),
+		MaxDeliver:     len(cfg.Backoff) + 1,
+		FilterSubjects: subjects,
+	}
+}
+
+func (c *Consumer) handle(msg jetstream.Msg) {
+	var delivered uint64 = 1
+	if meta, merr := msg.Metadata(); merr == nil {
+		delivered = meta.NumDelivered
+	}
+
+	handler, ok := c.cfg.Handlers[msg.Subject()]
+	var err error
+	if !ok {
+		err = Permanent(fmt.Errorf("no handler for subject %s", msg.Subject()))
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), c.cfg.HandleTimeout)
+		ctx = context.WithValue(ctx, attemptKey{}, attempt{delivered: delivered, last: delivered > uint64(len(c.cfg.Backoff))})
+		err = handler(ctx, msg.Data())
+		cancel()
+	}
+	if err == nil {
+		if aerr := msg.Ack(); aerr != nil {
+			c.log.Warn("unable to ack message", slog.String("subject", msg.Subject()), slog.String("error", aerr.Error()))
+		}
+		return
+	}
+
+	delay, dead := retryDelay(delivered, c.cfg.Backoff, err)
+	if !dead {
+		c.log.Warn("message processing failed, retrying",
+			slog.String("subject", msg.Subject()),
+			slog.Uint64("delivered", delivered),
+			slog.Duration("delay", delay),
+			slog.String("error", err.Error()))
+		if nerr := msg.NakWithDelay(delay); nerr != nil {
+			c.log.Warn("unable to nak message", slog.String("subject", msg.Subject()), slog.String("error", nerr.Error()))
+		}
+		return
+	}
+
+	c.log.Error("message processing failed, moving to dead letters",
+		slog.String("subject", msg.Subject()),
+		slog.Uint64("delivered", delivered),
+		slog.String("error", err.Error()))
+	if derr := c.deadLetter(msg, delivered, err); derr != nil {
+		// Leave the message in the stream, it is redelivered after the ack wait
+		c.log.Error("unable to publish dead letter", slog.String("subject", msg.Subject()), slog.String("error", derr.Error()))
+		return
+	}
+	if terr := msg.Term(); terr != nil {
+		c.log.Warn("unable to terminate message", slog.String("subject", msg.Subject()), slog.String("error", terr.Error()))
+	}
+}
+
+func (c *Consumer) deadLetter(msg jetstream.Msg, delivered uint64, cause error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	dl := nats.NewMsg(deadLetterPrefix + msg.Subject())
+	dl.Data = msg.Data()
+	dl.Header.Set(HeaderSubject, msg.Subject())
+	dl.Header.Set(HeaderError, cause.Error())
+	dl.Header.Set(HeaderDeliveries, strconv.FormatUint(delivered, 10))
+	_, err := c.js.PublishMsg(ctx, dl)
+	return err
+}
+
+// Stop stops receiving new messages
+func (c *Consumer) Stop() {
+	c.cc.Stop()
+}
+
+// retryDelay returns the delay before the next delivery, or dead if the message should not be retried
+func retryDelay(delivered uint64, backoff []time.Duration, err error) (time.Duration, bool) {
+	if IsPermanent(err) {
+		return 0, true
+	}
+	if delivered == 0 {
+		delivered = 1
+	}
+	if delivered > uint64(len(backoff)) {
+		return 0, true
+	}
+	return backoff[delivered-1], false
+}
@@ -0,0 +1,44 @@
+package durable
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	backoff := []time.Duration{time.Second, time.Minute}
+	failure := errors.New("opensearch is down")
+
+	if delay, dead := retryDelay(1, backoff, failure); dead || delay != time.Second {
+		t.Fatalf("expected first retry after 1s, got %s, dead %t", delay, dead)
+	}
+	if delay, dead := retryDelay(2, backoff, failure); dead || delay != time.Minute {
+		t.Fatalf("expected second retry after 1m, got %s, dead %t", delay, dead)
+	}
+	if _, dead := retryDelay(3, backoff, failure); !dead {
+		t.Fatal("expected message to be dead lettered after the last retry")
+	}
+	if _, dead := retryDelay(1, backoff, fmt.Errorf("decode: %w", Permanent(failure))); !dead {
+		t.Fatal("expected permanent error to be dead lettered right away")
+	}
+}
+
+func TestConsumerConfigWaitsForHandler(t *testing.T) {
+	cfg := consumerConfig(ConsumerConfig{
+		Durable:       "webhooks",
+		Handlers:      map[string]Handler{"event.deliver": nil},
+		Backoff:       []time.Duration{time.Second * 10, time.Minute},
+		HandleTimeout: time.Minute,
+	})
+	if len(cfg.BackOff) != 0 {
+		t.Fatalf("expected no consumer backoff, it replaces the ack wait, got %v", cfg.BackOff)
+	}
+	if cfg.AckWait <= time.Minute {
+		t.Fatalf("expected ack wait longer than the handle timeout, got %s", cfg.AckWait)
+	}
+	if cfg.MaxDeliver != 3 {
+		t.Fatalf("expected first delivery and two retries, got %d", cfg.MaxDeliver)
+	}
+}
@@ -20,6 +20,10 @@ const (
 	OPCodePresenceSubscription
 	// WebRTC signaling over existing WS (single opcode)
 	OPCodeRTC
+	// Resume a dropped gateway session and replay missed events
+	OPCodeResume
+	// Session can not be resumed, client must send a fresh hello
+	OPCodeInvalidSession
 )
 
 type EventType int
@@ -44,6 +48,9 @@ const (
 	EventTypeGuildEmojiCreate
 	EventTypeGuildEmojiUpdate
 	EventTypeGuildEmojiDelete
+	EventTypeMessageReactionAdd
+	EventTypeMessageReactionRemove
+	EventTypeChannelPinsUpdate
 )
 
 const (
@@ -69,6 +76,13 @@ const (
 	EventTypeGuildVoiceRegionChanging EventType = 208
 )
 
+const (
+	// EventTypeGuildMemberStreamStart and EventTypeGuildMemberStreamStop are sent to guild members
+	// when a user in a voice channel goes live with a screen share and when the share ends.
+	EventTypeGuildMemberStreamStart EventType = 211 + iota
+	EventTypeGuildMemberStreamStop
+)
+
 const (
 	EventTypeGuildChannelMessage EventType = 300 + iota
 	EventTypeChannelUserTyping
@@ -83,6 +97,10 @@ const (
 	EventTypeUserFriendRemoved
 	EventTypeUserDMMessage
 	EventTypeUserUpdate
+	EventTypeUserGroupDMRecipientAdd
+	EventTypeUserGroupDMRecipientRemove
+	EventTypeUserBlockAdd
+	EventTypeUserBlockRemove
 )
 
 // RTC signaling event types (client <-> SFU via WS)
@@ -116,12 +134,24 @@ const (
 	EventTypeRTCServerRebind
 	// Client -> SFU and SFU -> clients: speaking state notification
 	EventTypeRTCSpeaking
+	// Client -> SFU: preferred simulcast video quality
+	EventTypeRTCVideoQuality
+	// SFU -> clients: a user started or stopped sharing their screen
+	EventTypeRTCStream
+	// SFU -> clients: the channel recording started or stopped
+	EventTypeRTCRecording
+	// Client -> SFU and SFU -> clients: raise or lower the hand to speak in a stage channel
+	EventTypeRTCRaiseHand
+	// Privileged client -> SFU and SFU -> clients: approve or revoke a stage speaker
+	EventTypeRTCStageSpeaker
 )
 
 type Message struct {
 	Operation OPCodeType      `json:"op"`
 	Data      json.RawMessage `json:"d"`
 	EventType *EventType      `json:"t,omitempty"`
+	// Sequence number of a dispatched event within the gateway session
+	Sequence int64 `json:"s,omitempty"`
 }
 
 func BuildEventMessage(data EventDataMessage) (msg Message, err error) {
@@ -138,12 +168,14 @@ func (m *Message) UnmarshalJSON(b []byte) error {
 		D         json.RawMessage `json:"d"`
 		Data      json.RawMessage `json:"data"`
 		T         *EventType      `json:"t"`
+		S         int64           `json:"s"`
 	}
 	if err := json.Unmarshal(b, &aux); err != nil {
 		return err
 	}
 	m.Operation = aux.Operation
 	m.EventType = aux.T
+	m.Sequence = aux.S
 	if len(aux.D) > 0 {
 		m.Data = aux.D
 	} else {
@@ -0,0 +1,26 @@
+package mqmsg
+
+import (
+	"encoding/json"
+)
+
+type ChannelPinsUpdate struct {
+	GuildId   *int64 `json:"guild_id"`
+	ChannelId int64  `json:"channel_id"`
+	MessageId int64  `json:"message_id"`
+	UserId    int64  `json:"user_id"` // User who pinned or unpinned the message
+	Pinned    bool   `json:"pinned"`
+}
+
+func (m *ChannelPinsUpdate) EventType() *EventType {
+	e := EventTypeChannelPinsUpdate
+	return &e
+}
+
+func (m *ChannelPinsUpdate) Operation() OPCodeType {
+	return OpCodeDispatch
+}
+
+func (m *ChannelPinsUpdate) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
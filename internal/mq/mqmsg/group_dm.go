@@ -0,0 +1,41 @@
+package mqmsg
+
+import "encoding/json"
+
+// GroupDMRecipientAdd notifies group DM recipients that a user was added to the channel
+type GroupDMRecipientAdd struct {
+	ChannelId int64     `json:"channel_id"`
+	User      UserBrief `json:"user"`
+}
+
+func (m *GroupDMRecipientAdd) EventType() *EventType {
+	e := EventTypeUserGroupDMRecipientAdd
+	return &e
+}
+
+func (m *GroupDMRecipientAdd) Operation() OPCodeType {
+	return OpCodeDispatch
+}
+
+func (m *GroupDMRecipientAdd) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// GroupDMRecipientRemove notifies group DM recipients that a user left or was removed from the channel
+type GroupDMRecipientRemove struct {
+	ChannelId int64     `json:"channel_id"`
+	User      UserBrief `json:"user"`
+}
+
+func (m *GroupDMRecipientRemove) EventType() *EventType {
+	e := EventTypeUserGroupDMRecipientRemove
+	return &e
+}
+
+func (m *GroupDMRecipientRemove) Operation() OPCodeType {
+	return OpCodeDispatch
+}
+
+func (m *GroupDMRecipientRemove) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
@@ -1,13 +1,17 @@
 package mqmsg
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 type GuildMemberModerationAction string
 
 const (
-	GuildMemberModerationKick  GuildMemberModerationAction = "kick"
-	GuildMemberModerationBan   GuildMemberModerationAction = "ban"
-	GuildMemberModerationUnban GuildMemberModerationAction = "unban"
+	GuildMemberModerationKick    GuildMemberModerationAction = "kick"
+	GuildMemberModerationBan     GuildMemberModerationAction = "ban"
+	GuildMemberModerationUnban   GuildMemberModerationAction = "unban"
+	GuildMemberModerationTimeout GuildMemberModerationAction = "timeout"
 )
 
 type GuildMemberModeration struct {
@@ -16,6 +20,8 @@ type GuildMemberModeration struct {
 	ActorId int64                       `json:"actor_id"`
 	Action  GuildMemberModerationAction `json:"action"`
 	Reason  *string                     `json:"reason,omitempty"`
+	// Until is set for the timeout action while the timeout is active, it is omitted when the timeout is removed
+	Until *time.Time `json:"until,omitempty"`
 }
 
 func (m *GuildMemberModeration) EventType() *EventType {
@@ -0,0 +1,43 @@
+package mqmsg
+
+import "encoding/json"
+
+// GuildMemberStreamStart is sent when a member in a voice channel starts sharing their screen.
+type GuildMemberStreamStart struct {
+	GuildId   int64 `json:"guild_id"`
+	UserId    int64 `json:"user_id"`
+	ChannelId int64 `json:"channel_id"`
+}
+
+func (m *GuildMemberStreamStart) EventType() *EventType {
+	e := EventTypeGuildMemberStreamStart
+	return &e
+}
+
+func (m *GuildMemberStreamStart) Operation() OPCodeType {
+	return OpCodeDispatch
+}
+
+func (m *GuildMemberStreamStart) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// GuildMemberStreamStop is sent when a member stops sharing their screen or leaves the voice channel while sharing.
+type GuildMemberStreamStop struct {
+	GuildId   int64 `json:"guild_id"`
+	UserId    int64 `json:"user_id"`
+	ChannelId int64 `json:"channel_id"`
+}
+
+func (m *GuildMemberStreamStop) EventType() *EventType {
+	e := EventTypeGuildMemberStreamStop
+	return &e
+}
+
+func (m *GuildMemberStreamStop) Operation() OPCodeType {
+	return OpCodeDispatch
+}
+
+func (m *GuildMemberStreamStop) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
@@ -0,0 +1,49 @@
+package mqmsg
+
+import (
+	"encoding/json"
+
+	"github.com/FlameInTheDark/gochat/internal/dto"
+)
+
+type MessageReactionAdd struct {
+	GuildId   *int64            `json:"guild_id"`
+	ChannelId int64             `json:"channel_id"`
+	MessageId int64             `json:"message_id"`
+	UserId    int64             `json:"user_id"`
+	Emoji     dto.ReactionEmoji `json:"emoji"`
+}
+
+func (m *MessageReactionAdd) EventType() *EventType {
+	e := EventTypeMessageReactionAdd
+	return &e
+}
+
+func (m *MessageReactionAdd) Operation() OPCodeType {
+	return OpCodeDispatch
+}
+
+func (m *MessageReactionAdd) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+type MessageReactionRemove struct {
+	GuildId   *int64            `json:"guild_id"`
+	ChannelId int64             `json:"channel_id"`
+	MessageId int64             `json:"message_id"`
+	UserId    int64             `json:"user_id"`
+	Emoji     dto.ReactionEmoji `json:"emoji"`
+}
+
+func (m *MessageReactionRemove) EventType() *EventType {
+	e := EventTypeMessageReactionRemove
+	return &e
+}
+
+func (m *MessageReactionRemove) Operation() OPCodeType {
+	return OpCodeDispatch
+}
+
+func (m *MessageReactionRemove) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
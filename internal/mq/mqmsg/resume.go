@@ -0,0 +1,48 @@
+package mqmsg
+
+import (
+	"encoding/json"
+)
+
+// Resume is sent by the client to continue a dropped gateway session
+type Resume struct {
+	Token     string `json:"token"`
+	SessionID string `json:"session_id"`
+	Seq       int64  `json:"seq"` // Sequence number of the last event the client received
+}
+
+// Resumed is sent after the missed events were replayed
+type Resumed struct {
+	HeartbeatInterval int64  `json:"heartbeat_interval"`
+	SessionID         string `json:"session_id"`
+	Replayed          int    `json:"replayed"` // Number of replayed events
+}
+
+func (m *Resumed) EventType() *EventType {
+	return nil
+}
+
+func (m *Resumed) Operation() OPCodeType {
+	return OPCodeResume
+}
+
+func (m *Resumed) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// InvalidSession is sent when the session can not be resumed
+type InvalidSession struct {
+	Resumable bool `json:"resumable"`
+}
+
+func (m *InvalidSession) EventType() *EventType {
+	return nil
+}
+
+func (m *InvalidSession) Operation() OPCodeType {
+	return OPCodeInvalidSession
+}
+
+func (m *InvalidSession) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
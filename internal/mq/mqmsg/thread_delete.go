@@ -0,0 +1,27 @@
+package mqmsg
+
+import (
+	"encoding/json"
+
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type DeleteThread struct {
+	GuildId     int64             `json:"guild_id"`
+	ChannelType model.ChannelType `json:"channel_type"`
+	ChannelId   int64             `json:"channel_id"`
+	ParentId    int64             `json:"parent_id"`
+}
+
+func (m *DeleteThread) EventType() *EventType {
+	e := EventTypeThreadDelete
+	return &e
+}
+
+func (m *DeleteThread) Operation() OPCodeType {
+	return OpCodeDispatch
+}
+
+func (m *DeleteThread) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
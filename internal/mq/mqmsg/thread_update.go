@@ -0,0 +1,25 @@
+package mqmsg
+
+import (
+	"encoding/json"
+
+	"github.com/FlameInTheDark/gochat/internal/dto"
+)
+
+type UpdateThread struct {
+	GuildId int64       `json:"guild_id"`
+	Channel dto.Channel `json:"channel"`
+}
+
+func (m *UpdateThread) EventType() *EventType {
+	e := EventTypeThreadUpdate
+	return &e
+}
+
+func (m *UpdateThread) Operation() OPCodeType {
+	return OpCodeDispatch
+}
+
+func (m *UpdateThread) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
@@ -0,0 +1,39 @@
+package mqmsg
+
+import "encoding/json"
+
+// UserBlockAdd notifies the user sessions that the user blocked someone
+type UserBlockAdd struct {
+	User UserBrief `json:"user"`
+}
+
+func (m *UserBlockAdd) EventType() *EventType {
+	e := EventTypeUserBlockAdd
+	return &e
+}
+
+func (m *UserBlockAdd) Operation() OPCodeType {
+	return OpCodeDispatch
+}
+
+func (m *UserBlockAdd) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// UserBlockRemove notifies the user sessions that the user unblocked someone
+type UserBlockRemove struct {
+	User UserBrief `json:"user"`
+}
+
+func (m *UserBlockRemove) EventType() *EventType {
+	e := EventTypeUserBlockRemove
+	return &e
+}
+
+func (m *UserBlockRemove) Operation() OPCodeType {
+	return OpCodeDispatch
+}
+
+func (m *UserBlockRemove) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
@@ -0,0 +1,149 @@
+package msgsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// ChangesAlias points to the change log of a running rebuild. Edits and deletions go through the messages alias
+// to the old index until the switch, so the indexer also records them here and the rebuild applies them to the new index.
+// The alias exists only while a rebuild runs.
+const ChangesAlias = MessagesAlias + "_changes"
+
+// ChangesIndexName returns the name of the change log of the index being built
+func ChangesIndexName(index string) string {
+	return index + "_changes"
+}
+
+var changesIndex = map[string]any{
+	"settings": map[string]any{
+		"index": map[string]any{"number_of_shards": 1, "number_of_replicas": 1},
+	},
+	"mappings": map[string]any{
+		"properties": map[string]any{
+			"channel_id": map[string]string{"type": "long"},
+			"message_id": map[string]string{"type": "long"},
+		},
+	},
+	"aliases": map[string]any{ChangesAlias: map[string]any{}},
+}
+
+// CreateChangesIndex creates the change log of the index being built and points the changes alias to it
+func (s *Search) CreateChangesIndex(ctx context.Context, index string) error {
+	data, err := json.Marshal(changesIndex)
+	if err != nil {
+		return err
+	}
+	res, err := s.osc.Indices.Create(
+		ChangesIndexName(index),
+		s.osc.Indices.Create.WithBody(bytes.NewReader(data)),
+		s.osc.Indices.Create.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer closeQuietly(res.Body)
+	if res.IsError() {
+		b, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("create changes index response error: %s", string(b))
+	}
+	return nil
+}
+
+// RecordChange adds the edited or deleted message to the change log. Does nothing when no rebuild runs.
+func (s *Search) RecordChange(ctx context.Context, m ChangedMessage) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	// require_alias keeps OpenSearch from creating a concrete index when the alias is missing
+	res, err := s.osc.Index(
+		ChangesAlias,
+		bytes.NewReader(data),
+		s.osc.Index.WithDocumentID(strconv.FormatInt(m.MessageId, 10)),
+		s.osc.Index.WithRequireAlias(true),
+		s.osc.Index.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer closeQuietly(res.Body)
+	if res.IsError() {
+		if res.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		b, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("record change response error: %s", string(b))
+	}
+	return nil
+}
+
+// RefreshChanges makes all recorded changes visible to Changes
+func (s *Search) RefreshChanges(ctx context.Context, index string) error {
+	res, err := s.osc.Indices.Refresh(
+		s.osc.Indices.Refresh.WithIndex(ChangesIndexName(index)),
+		s.osc.Indices.Refresh.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer closeQuietly(res.Body)
+	if res.IsError() {
+		b, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("refresh changes response error: %s", string(b))
+	}
+	return nil
+}
+
+type osChangesResponse struct {
+	Hits struct {
+		Hits []struct {
+			Source ChangedMessage `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// Changes returns records of the change log with message ID greater than after, ordered by message ID
+func (s *Search) Changes(ctx context.Context, index string, after int64, size int) ([]ChangedMessage, error) {
+	q, err := buildChangesQuery(after, size)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.osc.Search(
+		s.osc.Search.WithContext(ctx),
+		s.osc.Search.WithIndex(ChangesIndexName(index)),
+		s.osc.Search.WithBody(bytes.NewReader(q)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer closeQuietly(res.Body)
+	if res.IsError() {
+		b, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("changes response error: %s", string(b))
+	}
+
+	var cr osChangesResponse
+	if err := json.NewDecoder(res.Body).Decode(&cr); err != nil {
+		return nil, err
+	}
+	changes := make([]ChangedMessage, 0, len(cr.Hits.Hits))
+	for _, h := range cr.Hits.Hits {
+		changes = append(changes, h.Source)
+	}
+	return changes, nil
+}
+
+func buildChangesQuery(after int64, size int) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"size":         size,
+		"query":        map[string]any{"match_all": map[string]any{}},
+		"sort":         []map[string]any{{"message_id": "asc"}},
+		"search_after": []int64{after},
+	})
+}
@@ -0,0 +1,76 @@
+package msgsearch
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	urlRegex         = regexp.MustCompile(`(?i)\bhttps?://[^\s]+`)
+	userMentionRegex = regexp.MustCompile(`<@(\d+)>`)
+)
+
+func GetAttachmentType(contentType string) string {
+	if idx := strings.Index(contentType, "/"); idx != -1 {
+		prefix := strings.ToLower(contentType[:idx])
+		switch prefix {
+		case "image":
+			return "image"
+		case "video":
+			return "video"
+		case "audio":
+			return "audio"
+		}
+	}
+	return "file"
+}
+
+func UniqueAttachmentTypes(types []string) []string {
+	seen := make(map[string]struct{})
+	var unique []string
+
+	for _, t := range types {
+		t = strings.ToLower(t)
+		if _, ok := seen[t]; !ok {
+			seen[t] = struct{}{}
+			unique = append(unique, t)
+		}
+	}
+
+	return unique
+}
+
+// HasURL returns true if the input string contains at least one URL.
+func HasURL(text string) bool {
+	return urlRegex.MatchString(text)
+}
+
+// HasTypes returns values of the "has" field for the message content and attachment content types
+func HasTypes(content string, contentTypes []string) []string {
+	var types []string
+	if HasURL(content) {
+		types = append(types, "url")
+	}
+	for _, ct := range contentTypes {
+		types = append(types, GetAttachmentType(ct))
+	}
+	return UniqueAttachmentTypes(types)
+}
+
+// MentionedUsers returns unique IDs of users mentioned in the content as <@id>
+func MentionedUsers(content string) []int64 {
+	var users []int64
+	seen := make(map[int64]struct{})
+	for _, match := range userMentionRegex.FindAllStringSubmatch(content, -1) {
+		id, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			users = append(users, id)
+		}
+	}
+	return users
+}
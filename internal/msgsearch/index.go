@@ -0,0 +1,293 @@
+package msgsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MessagesAlias is used for all reads and writes. It points to a versioned index (messages_v1, messages_v2, ...),
+// so the index can be rebuilt with a new mapping and switched without downtime.
+const MessagesAlias = "messages"
+
+// IndexName returns the name of the versioned messages index
+func IndexName(version int) string {
+	return fmt.Sprintf("%s_v%d", MessagesAlias, version)
+}
+
+// IndexVersion returns the version of the versioned messages index name
+func IndexVersion(name string) (int, bool) {
+	v, ok := strings.CutPrefix(name, MessagesAlias+"_v")
+	if !ok {
+		return 0, false
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// NextIndexName returns the versioned index name following the highest version of the indices
+func NextIndexName(indices []string) string {
+	var last int
+	for _, name := range indices {
+		if v, ok := IndexVersion(name); ok && v > last {
+			last = v
+		}
+	}
+	return IndexName(last + 1)
+}
+
+// IndexExists checks if the index or alias exists
+func (s *Search) IndexExists(ctx context.Context, name string) (bool, error) {
+	res, err := s.osc.Indices.Exists([]string{name}, s.osc.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to check if index exists: %w", err)
+	}
+	defer closeQuietly(res.Body)
+	return res.StatusCode == http.StatusOK, nil
+}
+
+// CreateIndex creates a messages index with the default mapping without adding it to the alias
+func (s *Search) CreateIndex(ctx context.Context, name string) error {
+	return s.createIndex(ctx, name, false)
+}
+
+func (s *Search) createIndex(ctx context.Context, name string, alias bool) error {
+	req := defaultMessagesIndex
+	if alias {
+		req.Aliases = map[string]struct{}{MessagesAlias: {}}
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.osc.Indices.Create(
+		name,
+		s.osc.Indices.Create.WithBody(bytes.NewReader(data)),
+		s.osc.Indices.Create.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer closeQuietly(res.Body)
+	if res.IsError() {
+		b, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("create index response error: %s", string(b))
+	}
+	return nil
+}
+
+// AliasIndices returns indices the messages alias points to.
+// Deployments created before the alias have a concrete "messages" index, it is returned with legacy set.
+func (s *Search) AliasIndices(ctx context.Context) (indices []string, legacy bool, err error) {
+	res, err := s.osc.Indices.GetAlias(
+		s.osc.Indices.GetAlias.WithName(MessagesAlias),
+		s.osc.Indices.GetAlias.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	defer closeQuietly(res.Body)
+
+	if res.StatusCode == http.StatusNotFound {
+		exists, err := s.IndexExists(ctx, MessagesAlias)
+		if err != nil || !exists {
+			return nil, false, err
+		}
+		return []string{MessagesAlias}, true, nil
+	}
+	if res.IsError() {
+		b, _ := io.ReadAll(res.Body)
+		return nil, false, fmt.Errorf("get alias response error: %s", string(b))
+	}
+
+	var aliases map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&aliases); err != nil {
+		return nil, false, err
+	}
+	for name := range aliases {
+		indices = append(indices, name)
+	}
+	slices.Sort(indices)
+	return indices, false, nil
+}
+
+// SwitchAlias atomically moves the messages alias to the index and returns indices it pointed to before.
+// A legacy concrete "messages" index is deleted in the same request, because the alias can not share its name.
+func (s *Search) SwitchAlias(ctx context.Context, index string) ([]string, error) {
+	current, legacy, err := s.AliasIndices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []map[string]any{
+		{"add": map[string]any{"index": index, "alias": MessagesAlias}},
+	}
+	var old []string
+	if legacy {
+		actions = append(actions, map[string]any{"remove_index": map[string]any{"index": MessagesAlias}})
+	} else {
+		for _, name := range current {
+			if name == index {
+				continue
+			}
+			actions = append(actions, map[string]any{"remove": map[string]any{"index": name, "alias": MessagesAlias}})
+			old = append(old, name)
+		}
+	}
+	data, err := json.Marshal(map[string]any{"actions": actions})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.osc.Indices.UpdateAliases(
+		bytes.NewReader(data),
+		s.osc.Indices.UpdateAliases.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer closeQuietly(res.Body)
+	if res.IsError() {
+		b, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("update aliases response error: %s", string(b))
+	}
+	return old, nil
+}
+
+// DeleteIndices removes indices
+func (s *Search) DeleteIndices(ctx context.Context, indices []string) error {
+	if len(indices) == 0 {
+		return nil
+	}
+	res, err := s.osc.Indices.Delete(indices, s.osc.Indices.Delete.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer closeQuietly(res.Body)
+	if res.IsError() && res.StatusCode != http.StatusNotFound {
+		b, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("delete index response error: %s", string(b))
+	}
+	return nil
+}
+
+type osBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Id     string `json:"_id"`
+		Status int    `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// BulkIndex indexes messages into the index with a single request
+func (s *Search) BulkIndex(ctx context.Context, index string, msgs []Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	body, err := buildBulkBody(index, msgs)
+	if err != nil {
+		return err
+	}
+	return s.bulk(ctx, body, len(msgs))
+}
+
+// BulkDelete removes messages from the index with a single request, missing documents are skipped
+func (s *Search) BulkDelete(ctx context.Context, index string, msgs []ChangedMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	body, err := buildBulkDeleteBody(index, msgs)
+	if err != nil {
+		return err
+	}
+	return s.bulk(ctx, body, len(msgs))
+}
+
+func (s *Search) bulk(ctx context.Context, body []byte, n int) error {
+	res, err := s.osc.Bulk(bytes.NewReader(body), s.osc.Bulk.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer closeQuietly(res.Body)
+	if res.IsError() {
+		b, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("bulk response error: %s", string(b))
+	}
+
+	var br osBulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&br); err != nil {
+		return err
+	}
+	if !br.Errors {
+		return nil
+	}
+	var failed int
+	var first string
+	for _, item := range br.Items {
+		for _, r := range item {
+			if r.Error == nil {
+				continue
+			}
+			if failed == 0 {
+				first = fmt.Sprintf("%s: %s: %s", r.Id, r.Error.Type, r.Error.Reason)
+			}
+			failed++
+		}
+	}
+	return fmt.Errorf("unable to process %d of %d messages, first error: %s", failed, n, first)
+}
+
+// buildBulkBody builds the newline delimited bulk request, documents are routed by channel like in IndexMessage
+func buildBulkBody(index string, msgs []Message) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, m := range msgs {
+		meta := map[string]any{
+			"index": map[string]any{
+				"_index":  index,
+				"_id":     strconv.FormatInt(m.MessageId, 10),
+				"routing": strconv.FormatInt(m.ChannelId, 10),
+			},
+		}
+		if err := enc.Encode(meta); err != nil {
+			return nil, err
+		}
+		if err := enc.Encode(m); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// buildBulkDeleteBody builds the newline delimited bulk request removing the messages
+func buildBulkDeleteBody(index string, msgs []ChangedMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, m := range msgs {
+		meta := map[string]any{
+			"delete": map[string]any{
+				"_index":  index,
+				"_id":     strconv.FormatInt(m.MessageId, 10),
+				"routing": strconv.FormatInt(m.ChannelId, 10),
+			},
+		}
+		if err := enc.Encode(meta); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,91 @@
+package msgsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestNextIndexName(t *testing.T) {
+	cases := []struct {
+		indices []string
+		want    string
+	}{
+		{nil, "messages_v1"},
+		{[]string{"messages"}, "messages_v1"},
+		{[]string{"messages_v1"}, "messages_v2"},
+		{[]string{"messages_v3", "messages_v10", "messages_vx"}, "messages_v11"},
+	}
+	for _, c := range cases {
+		if got := NextIndexName(c.indices); got != c.want {
+			t.Fatalf("NextIndexName(%v) = %s, want %s", c.indices, got, c.want)
+		}
+	}
+}
+
+func TestBuildBulkBodyRoutesByChannel(t *testing.T) {
+	guildID := int64(5)
+	body, err := buildBulkBody("messages_v2", []Message{
+		{GuildId: &guildID, ChannelId: 42, UserId: 7, MessageId: 100, Content: "hello"},
+		{ChannelId: 43, UserId: 8, MessageId: 101, Content: "world"},
+	})
+	if err != nil {
+		t.Fatalf("buildBulkBody returned error: %v", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(body, []byte("\n")), []byte("\n"))
+	if len(lines) != 4 {
+		t.Fatalf("expected action and document lines for each message, got %d lines", len(lines))
+	}
+	var meta struct {
+		Index struct {
+			Index   string `json:"_index"`
+			Id      string `json:"_id"`
+			Routing string `json:"routing"`
+		} `json:"index"`
+	}
+	if err := json.Unmarshal(lines[2], &meta); err != nil {
+		t.Fatalf("unable to decode action: %v", err)
+	}
+	if meta.Index.Index != "messages_v2" || meta.Index.Id != "101" || meta.Index.Routing != "43" {
+		t.Fatalf("unexpected action: %+v", meta.Index)
+	}
+	var doc Message
+	if err := json.Unmarshal(lines[1], &doc); err != nil {
+		t.Fatalf("unable to decode document: %v", err)
+	}
+	if doc.MessageId != 100 || doc.GuildId == nil || *doc.GuildId != guildID {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+}
+
+func TestHasTypesAndMentions(t *testing.T) {
+	has := HasTypes("see https://example.com <@10> and <@10> <@&3>", []string{"image/png", "IMAGE/jpeg", "application/pdf"})
+	if len(has) != 3 || has[0] != "url" || has[1] != "image" || has[2] != "file" {
+		t.Fatalf("unexpected has types: %v", has)
+	}
+	mentions := MentionedUsers("see https://example.com <@10> and <@10> <@&3>")
+	if len(mentions) != 1 || mentions[0] != 10 {
+		t.Fatalf("unexpected mentions: %v", mentions)
+	}
+}
+
+func TestBuildBulkDeleteBody(t *testing.T) {
+	body, err := buildBulkDeleteBody("messages_v2", []ChangedMessage{{ChannelId: 42, MessageId: 100}})
+	if err != nil {
+		t.Fatalf("buildBulkDeleteBody returned error: %v", err)
+	}
+	var meta struct {
+		Delete struct {
+			Index   string `json:"_index"`
+			Id      string `json:"_id"`
+			Routing string `json:"routing"`
+		} `json:"delete"`
+	}
+	if err := json.Unmarshal(bytes.TrimSuffix(body, []byte("\n")), &meta); err != nil {
+		t.Fatalf("expected a single delete action, got %q: %v", body, err)
+	}
+	if meta.Delete.Index != "messages_v2" || meta.Delete.Id != "100" || meta.Delete.Routing != "42" {
+		t.Fatalf("unexpected action: %+v", meta.Delete)
+	}
+}
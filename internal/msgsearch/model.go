@@ -15,6 +15,12 @@ type DeleteMessage struct {
 	MessageId int64 `json:"message_id"`
 }
 
+// ChangedMessage is a record of the rebuild change log
+type ChangedMessage struct {
+	ChannelId int64 `json:"channel_id"`
+	MessageId int64 `json:"message_id"`
+}
+
 type UpdateMessage struct {
 	ChannelId int64  `json:"channel_id"`
 	MessageId int64  `json:"message_id"`
@@ -112,8 +118,9 @@ type osMessagesProperties struct {
 }
 
 type osCreateMessagesIndexRequest struct {
-	Settings osSettings        `json:"settings"`
-	Mappings osMessagesMapping `json:"mappings"`
+	Settings osSettings          `json:"settings"`
+	Mappings osMessagesMapping   `json:"mappings"`
+	Aliases  map[string]struct{} `json:"aliases,omitempty"`
 }
 
 var defaultMessagesIndex = osCreateMessagesIndexRequest{
@@ -35,32 +35,24 @@ func NewSearch(addresses []string, tlsSkip bool, username, password string) (*Se
 		return nil, err
 	}
 
-	// Init indices if not exist
-	res, err := c.Indices.Exists([]string{"messages"})
+	s := &Search{osc: c}
+
+	// Init indices if not exist. The first versioned index gets the alias, rebuilds create the next versions.
+	ctx := context.Background()
+	exists, err := s.IndexExists(ctx, MessagesAlias)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if index exists: %w", err)
+		return nil, err
 	}
-	defer closeQuietly(res.Body)
-
-	if res.StatusCode != 200 {
-		ctx := context.Background()
-
-		data, err := json.Marshal(defaultMessagesIndex)
-		if err != nil {
-			return nil, err
-		}
-
-		_, err = c.Indices.Create(
-			"messages",
-			c.Indices.Create.WithBody(bytes.NewReader(data)),
-			c.Indices.Create.WithContext(ctx),
-		)
-		if err != nil {
-			return nil, err
+	if !exists {
+		if err := s.createIndex(ctx, IndexName(1), true); err != nil {
+			// Another service could create it at the same time
+			if exists, eerr := s.IndexExists(ctx, MessagesAlias); eerr != nil || !exists {
+				return nil, err
+			}
 		}
 	}
 
-	return &Search{osc: c}, nil
+	return s, nil
 }
 
 func (s *Search) IndexMessage(ctx context.Context, m Message) error {
@@ -69,7 +61,7 @@ func (s *Search) IndexMessage(ctx context.Context, m Message) error {
 		return err
 	}
 	index, err := s.osc.Index(
-		"messages",
+		MessagesAlias,
 		bytes.NewReader(data),
 		s.osc.Index.WithDocumentID(fmt.Sprintf("%d", m.MessageId)),
 		s.osc.Index.WithRouting(fmt.Sprintf("%d", m.ChannelId)),
@@ -102,7 +94,7 @@ func (s *Search) Search(ctx context.Context, req SearchRequest) (results *Result
 
 	opts := []func(*opensearchapi.SearchRequest){
 		s.osc.Search.WithContext(ctx),
-		s.osc.Search.WithIndex("messages"),
+		s.osc.Search.WithIndex(MessagesAlias),
 		s.osc.Search.WithBody(bytes.NewReader(q)),
 		s.osc.Search.WithTrackTotalHits(true),
 		s.osc.Search.WithRouting(fmt.Sprintf("%d", req.ChannelId)),
@@ -203,9 +195,12 @@ func (s *Search) DeleteMessage(ctx context.Context, m DeleteMessage) error {
 	if s.osc == nil {
 		return fmt.Errorf("opensearch client is not initialized")
 	}
+	if err := s.RecordChange(ctx, ChangedMessage{ChannelId: m.ChannelId, MessageId: m.MessageId}); err != nil {
+		return err
+	}
 
 	res, err := s.osc.Delete(
-		"messages",
+		MessagesAlias,
 		fmt.Sprintf("%d", m.MessageId),
 		s.osc.Delete.WithRouting(fmt.Sprintf("%d", m.ChannelId)),
 		s.osc.Delete.WithContext(ctx),
@@ -229,6 +224,9 @@ func (s *Search) UpdateMessage(ctx context.Context, m Message) error {
 	if s.osc == nil {
 		return fmt.Errorf("opensearch client is not initialized")
 	}
+	if err := s.RecordChange(ctx, ChangedMessage{ChannelId: m.ChannelId, MessageId: m.MessageId}); err != nil {
+		return err
+	}
 
 	payload := map[string]any{"doc": m}
 	data, err := json.Marshal(payload)
@@ -237,7 +235,7 @@ func (s *Search) UpdateMessage(ctx context.Context, m Message) error {
 	}
 
 	res, err := opensearchapi.UpdateRequest{
-		Index:      "messages",
+		Index:      MessagesAlias,
 		DocumentID: fmt.Sprintf("%d", m.MessageId),
 		Routing:    fmt.Sprintf("%d", m.ChannelId),
 		Body:       bytes.NewReader(data),
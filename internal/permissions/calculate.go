@@ -54,6 +54,22 @@ func SubtractRoles(perm int64, remove int64) int64 {
 	return perm &^ remove
 }
 
+// RequiresMFA returns true if any of the permissions is a moderation permission
+func RequiresMFA(permissions ...RolePermission) bool {
+	return HasOverlap(CreatePermissions(permissions...), ModerationPermissions)
+}
+
+// Split returns the known permissions set in the bitmask
+func Split(perm int64) []RolePermission {
+	var result []RolePermission
+	for p := PermServerViewChannels; int64(p)&AllPermissions != 0; p <<= 1 {
+		if perm&int64(p) != 0 {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
 func AddRoles(perm int64, add ...int64) int64 {
 	for _, p := range add {
 		perm |= p
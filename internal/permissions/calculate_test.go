@@ -406,3 +406,45 @@ func TestSubtractRoles(t *testing.T) {
 		})
 	}
 }
+
+func TestRequiresMFA(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions []RolePermission
+		want        bool
+	}{
+		{name: "ban members", permissions: []RolePermission{PermMembershipBanMembers}, want: true},
+		{name: "administrator", permissions: []RolePermission{PermAdministrator}, want: true},
+		{name: "view and manage messages", permissions: []RolePermission{PermServerViewChannels, PermTextManageMessages}, want: true},
+		{name: "send message", permissions: []RolePermission{PermTextSendMessage}, want: false},
+		{name: "create invite", permissions: []RolePermission{PermMembershipCreateInvite}, want: false},
+		{name: "none", permissions: []RolePermission{}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RequiresMFA(tt.permissions...); got != tt.want {
+				t.Errorf("RequiresMFA() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	perm := CreatePermissions(PermServerViewChannels, PermTextSendMessage, PermManageExpressions)
+	got := Split(perm | 1<<40)
+	want := []RolePermission{PermServerViewChannels, PermTextSendMessage, PermManageExpressions}
+	if len(got) != len(want) {
+		t.Fatalf("Split() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Split() = %v, want %v", got, want)
+		}
+	}
+	if len(Split(0)) != 0 {
+		t.Fatal("expected no permissions in empty bitmask")
+	}
+	if CreatePermissions(Split(AllPermissions)...) != AllPermissions {
+		t.Fatal("expected all permissions to be split")
+	}
+}
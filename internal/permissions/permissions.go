@@ -32,8 +32,15 @@ const (
 	PermAdministrator
 	PermCreateExpressions
 	PermManageExpressions
+	PermManageWebhooks
+	PermVoiceStream
+	PermVoiceRecord
+	PermVoicePrioritySpeaker
 )
 
+// AllPermissions has every known permission bit set
+var AllPermissions = int64(PermVoicePrioritySpeaker)<<1 - 1
+
 var DefaultPermissions = CreatePermissions(
 	PermServerViewChannels,
 	PermMembershipCreateInvite,
@@ -46,4 +53,28 @@ var DefaultPermissions = CreatePermissions(
 	PermTextReadMessageHistory,
 	PermVoiceConnect,
 	PermVoiceSpeak,
-	PermVoiceVideo)
+	PermVoiceVideo,
+	PermVoiceStream)
+
+// PendingPermissions are the only permissions of members who haven't passed the guild screening
+var PendingPermissions = CreatePermissions(
+	PermServerViewChannels,
+	PermTextReadMessageHistory)
+
+// ModerationPermissions are honored only for users with two-factor authentication in guilds that require it
+var ModerationPermissions = CreatePermissions(
+	PermServerManageChannels,
+	PermServerManageRoles,
+	PermServerManage,
+	PermMembershipManageNickname,
+	PermMembershipKickMembers,
+	PermMembershipBanMembers,
+	PermMembershipTimeoutMembers,
+	PermTextManageMessages,
+	PermTextManageThreads,
+	PermVoiceMuteMembers,
+	PermVoiceDeafenMembers,
+	PermVoiceMoveMembers,
+	PermAdministrator,
+	PermManageExpressions,
+	PermManageWebhooks)
@@ -115,6 +115,27 @@ func (s *Store) RemoveSession(ctx context.Context, userID int64, sessionID strin
 	return nil
 }
 
+// HasSessions reports if the user has any not expired session, overrides are ignored.
+func (s *Store) HasSessions(ctx context.Context, userID int64, nowUnix int64) (bool, error) {
+	m, err := s.c.HGetAll(ctx, sessionsKey(userID))
+	if err != nil {
+		return false, err
+	}
+	for _, v := range m {
+		if v == "" {
+			continue
+		}
+		var sp SessionPresence
+		if json.Unmarshal([]byte(v), &sp) != nil {
+			continue
+		}
+		if sp.ExpiresAt > nowUnix {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // Aggregate reads all valid sessions and returns aggregated presence and if any sessions present.
 func (s *Store) Aggregate(ctx context.Context, userID int64, nowUnix int64) (Presence, bool, error) {
 	// Check global override first (e.g., manual offline/invisible)
@@ -194,6 +215,21 @@ func (s *Store) Get(ctx context.Context, userID int64) (Presence, bool, error) {
 	return s.Aggregate(ctx, userID, time.Now().Unix())
 }
 
+// CountOnline returns how many of the users are not offline
+func (s *Store) CountOnline(ctx context.Context, userIDs []int64) (int, error) {
+	online := 0
+	for _, id := range userIDs {
+		p, _, err := s.Get(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		if p.Status != "" && p.Status != StatusOffline {
+			online++
+		}
+	}
+	return online, nil
+}
+
 // SetAggregated stores aggregated presence with TTL.
 func (s *Store) SetAggregated(ctx context.Context, p Presence, ttlSeconds int64) error {
 	return s.c.SetTimedJSON(ctx, aggKey(p.UserID), p, ttlSeconds)
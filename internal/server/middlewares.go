@@ -1,31 +1,62 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/FlameInTheDark/gochat/internal/bottoken"
 	"github.com/FlameInTheDark/gochat/internal/cache/kvcpiped"
 	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/idempotency"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
 
 	"github.com/FlameInTheDark/gochat/internal/helper"
 )
 
+// BotAuthenticator resolves a bot token to the ID of the bot user
+type BotAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (int64, error)
+}
+
+// BotAuthMiddleware accepts "Authorization: Bot <token>" headers. Must be registered before AuthMiddleware,
+// which skips requests that are already authenticated.
+func (s *Server) BotAuthMiddleware(bots BotAuthenticator) {
+	s.Use(func(c *fiber.Ctx) error {
+		token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bot ")
+		if !ok {
+			return c.Next()
+		}
+		botId, err := bots.Authenticate(c.UserContext(), strings.TrimSpace(token))
+		if errors.Is(err, bottoken.ErrInvalidToken) {
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid bot token")
+		} else if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "unable to check bot token")
+		}
+		c.Locals("user", helper.BotToken(botId))
+		return c.Next()
+	})
+}
+
 func (s *Server) AuthMiddleware(secret string) {
 	s.Use(jwtware.New(jwtware.Config{
 		SigningKey: jwtware.SigningKey{Key: []byte(secret)},
 		Claims:     &helper.Claims{},
 		Filter: func(c *fiber.Ctx) bool {
+			if _, ok := c.Locals("user").(*jwt.Token); ok {
+				return true
+			}
 			path := c.Path()
 			switch path {
-			case "/docs/swagger", "/api/v1/auth/login", "/api/v1/auth/registration", "/api/v1/auth/confirmation", "/api/v1/auth/recovery", "/api/v1/auth/reset", "/healthz", "/metrics":
+			case "/docs/swagger", "/api/v1/auth/login", "/api/v1/auth/login/mfa", "/api/v1/auth/registration", "/api/v1/auth/confirmation", "/api/v1/auth/recovery", "/api/v1/auth/reset", "/healthz", "/metrics":
 				return true
 			}
-			return strings.HasPrefix(path, "/emoji/")
+			return strings.HasPrefix(path, "/emoji/") || helper.IsWebhookRoute(path)
 		},
 	}))
 }
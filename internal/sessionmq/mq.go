@@ -0,0 +1,58 @@
+// Package sessionmq notifies gateway instances about revoked login sessions, so they can drop connections using them.
+package sessionmq
+
+import (
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+)
+
+const RevokeSubject = "auth.session.revoke"
+
+// Revoke lists revoked login sessions of the user. Empty Sessions means all sessions of the user.
+type Revoke struct {
+	UserId   int64   `json:"user_id"`
+	Sessions []int64 `json:"sessions,omitempty"`
+}
+
+// Matches returns true if the login session is revoked by the message
+func (r Revoke) Matches(userId, sessionId int64) bool {
+	if r.UserId != userId {
+		return false
+	}
+	if len(r.Sessions) == 0 {
+		return true
+	}
+	for _, id := range r.Sessions {
+		if id == sessionId {
+			return true
+		}
+	}
+	return false
+}
+
+type Queue struct {
+	nc *nats.Conn
+}
+
+func New(conn string) (*Queue, error) {
+	nc, err := nats.Connect(conn, nats.Compression(true))
+	if err != nil {
+		return nil, err
+	}
+	return &Queue{nc: nc}, nil
+}
+
+// Revoke publishes revoked sessions of the user, no sessions means all of them
+func (q *Queue) Revoke(userId int64, sessions ...int64) error {
+	data, err := json.Marshal(Revoke{UserId: userId, Sessions: sessions})
+	if err != nil {
+		return err
+	}
+	return q.nc.Publish(RevokeSubject, data)
+}
+
+func (q *Queue) Close() error {
+	q.nc.Close()
+	return nil
+}
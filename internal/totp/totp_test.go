@@ -0,0 +1,69 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Secret of the RFC 6238 SHA1 test vectors, "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeMatchesRFCVectors(t *testing.T) {
+	// The RFC lists 8 digit values, 6 digit codes are their last digits
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range cases {
+		got, err := Code(rfcSecret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("Code at %d = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidateAcceptsAdjacentSteps(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	prev, _ := Code(rfcSecret, now.Add(-Period))
+	next, _ := Code(rfcSecret, now.Add(Period))
+	old, _ := Code(rfcSecret, now.Add(-2*Period))
+
+	if step, ok := Validate(rfcSecret, prev, now); !ok || step != Step(now)-1 {
+		t.Fatalf("expected previous step to be accepted, got %d %v", step, ok)
+	}
+	if step, ok := Validate(rfcSecret, next, now); !ok || step != Step(now)+1 {
+		t.Fatalf("expected next step to be accepted, got %d %v", step, ok)
+	}
+	if _, ok := Validate(rfcSecret, old, now); ok {
+		t.Fatal("expected code two steps old to be rejected")
+	}
+	if _, ok := Validate(rfcSecret, "12345", now); ok {
+		t.Fatal("expected short code to be rejected")
+	}
+	if _, ok := Validate("not a secret!", "005924", now); ok {
+		t.Fatal("expected invalid secret to be rejected")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := decodeSecret(secret)
+	if err != nil || len(key) != secretSize {
+		t.Fatalf("expected %d byte secret, got %d, %v", secretSize, len(key), err)
+	}
+	uri := URI("GoChat", "user@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/GoChat:user@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+}
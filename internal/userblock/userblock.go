@@ -0,0 +1,73 @@
+// Package userblock answers whether users blocked each other. The blocked users of a user are cached,
+// since they are checked on every DM message, friend request and mention.
+package userblock
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/FlameInTheDark/gochat/internal/cache"
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+// Seconds the blocked users of a user stay in the cache
+const cacheTTL = 600
+
+type blocks interface {
+	GetBlockedUsers(ctx context.Context, userId int64) ([]model.BlockedUser, error)
+}
+
+// Checker reads blocked users through the cache
+type Checker struct {
+	blocks blocks
+	cache  cache.Cache
+}
+
+func NewChecker(blocks blocks, cache cache.Cache) *Checker {
+	return &Checker{blocks: blocks, cache: cache}
+}
+
+// BlockedIds returns IDs of the users blocked by the user
+func (c *Checker) BlockedIds(ctx context.Context, userId int64) ([]int64, error) {
+	var ids []int64
+	if err := c.cache.GetJSON(ctx, cacheKey(userId), &ids); err == nil {
+		return ids, nil
+	}
+	blocked, err := c.blocks.GetBlockedUsers(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	ids = make([]int64, 0, len(blocked))
+	for _, b := range blocked {
+		ids = append(ids, b.BlockedUserId)
+	}
+	_ = c.cache.SetTimedJSON(ctx, cacheKey(userId), ids, cacheTTL)
+	return ids, nil
+}
+
+// Blocked reports whether the user blocked the target
+func (c *Checker) Blocked(ctx context.Context, userId, targetId int64) (bool, error) {
+	ids, err := c.BlockedIds(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(ids, targetId), nil
+}
+
+// BlockedEither reports whether one of the users blocked the other
+func (c *Checker) BlockedEither(ctx context.Context, userId, targetId int64) (bool, error) {
+	if ok, err := c.Blocked(ctx, userId, targetId); err != nil || ok {
+		return ok, err
+	}
+	return c.Blocked(ctx, targetId, userId)
+}
+
+// Forget drops the cached blocked users, must be called after the user blocked or unblocked someone
+func (c *Checker) Forget(ctx context.Context, userId int64) error {
+	return c.cache.Delete(ctx, cacheKey(userId))
+}
+
+func cacheKey(userId int64) string {
+	return fmt.Sprintf("user:%d:blocked", userId)
+}
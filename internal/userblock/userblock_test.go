@@ -0,0 +1,103 @@
+package userblock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/FlameInTheDark/gochat/internal/cache"
+	"github.com/FlameInTheDark/gochat/internal/database/model"
+)
+
+type fakeBlocks struct {
+	blocked map[int64][]int64
+	calls   int
+}
+
+func (f *fakeBlocks) GetBlockedUsers(_ context.Context, userId int64) ([]model.BlockedUser, error) {
+	f.calls++
+	var out []model.BlockedUser
+	for _, id := range f.blocked[userId] {
+		out = append(out, model.BlockedUser{UserId: userId, BlockedUserId: id})
+	}
+	return out, nil
+}
+
+type fakeCache struct {
+	cache.Cache
+	values map[string][]byte
+}
+
+func (f *fakeCache) GetJSON(_ context.Context, key string, v interface{}) error {
+	data, ok := f.values[key]
+	if !ok {
+		return errors.New("not found")
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (f *fakeCache) SetTimedJSON(_ context.Context, key string, val interface{}, _ int64) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	f.values[key] = data
+	return nil
+}
+
+func (f *fakeCache) Delete(_ context.Context, key string) error {
+	delete(f.values, key)
+	return nil
+}
+
+func TestBlockedEither(t *testing.T) {
+	blocks := &fakeBlocks{blocked: map[int64][]int64{1: {2}}}
+	c := NewChecker(blocks, &fakeCache{values: map[string][]byte{}})
+	ctx := context.Background()
+
+	if ok, err := c.Blocked(ctx, 2, 1); err != nil || ok {
+		t.Fatalf("expected user 2 not to block user 1, got %v, %v", ok, err)
+	}
+	for _, pair := range [][2]int64{{1, 2}, {2, 1}} {
+		if ok, err := c.BlockedEither(ctx, pair[0], pair[1]); err != nil || !ok {
+			t.Fatalf("expected block between %d and %d, got %v, %v", pair[0], pair[1], ok, err)
+		}
+	}
+	if ok, _ := c.BlockedEither(ctx, 1, 3); ok {
+		t.Fatal("expected no block between 1 and 3")
+	}
+}
+
+func TestBlockedIdsCached(t *testing.T) {
+	blocks := &fakeBlocks{blocked: map[int64][]int64{1: {2}}}
+	c := NewChecker(blocks, &fakeCache{values: map[string][]byte{}})
+	ctx := context.Background()
+
+	for range 2 {
+		if ok, err := c.Blocked(ctx, 1, 2); err != nil || !ok {
+			t.Fatalf("expected user 2 to be blocked, got %v, %v", ok, err)
+		}
+	}
+	if blocks.calls != 1 {
+		t.Fatalf("expected blocked users to be cached, got %d lookups", blocks.calls)
+	}
+
+	// Users without blocks are cached too
+	for range 2 {
+		if ok, _ := c.Blocked(ctx, 5, 2); ok {
+			t.Fatal("expected no block")
+		}
+	}
+	if blocks.calls != 2 {
+		t.Fatalf("expected empty block list to be cached, got %d lookups", blocks.calls)
+	}
+
+	blocks.blocked[1] = nil
+	if err := c.Forget(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := c.Blocked(ctx, 1, 2); ok {
+		t.Fatal("expected block to be gone after forget")
+	}
+}